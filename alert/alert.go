@@ -0,0 +1,106 @@
+package alert
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A Severity of the alert
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// An Alert is a notification about the condition
+type Alert struct {
+	Name     string            `json:"name"`
+	Severity Severity          `json:"severity"`
+	Message  string            `json:"message"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Time     time.Time         `json:"time"`
+}
+
+// FuncCondition returns the alert if the condition is triggered or nil
+type FuncCondition func(ctx context.Context) *Alert
+
+// Dead alerts when the component (e.g. consumer) is not alive
+func Dead(name string, alive func() bool) FuncCondition {
+
+	return func(context.Context) *Alert {
+		if alive() {
+			return nil
+		}
+
+		return &Alert{
+			Name:     name,
+			Severity: SeverityCritical,
+			Message:  name + " is dead",
+		}
+	}
+}
+
+// LagAbove alerts when the lag (e.g. consumer.Heartbeat.TotalLag) is greater than the threshold
+func LagAbove(name string, threshold int64, lag func() int64) FuncCondition {
+
+	return func(context.Context) *Alert {
+		val := lag()
+		if val <= threshold {
+			return nil
+		}
+
+		return &Alert{
+			Name:     name,
+			Severity: SeverityWarning,
+			Message:  name + " lag is above the threshold",
+			Labels: map[string]string{
+				"lag":       formatInt(val),
+				"threshold": formatInt(threshold),
+			},
+		}
+	}
+}
+
+// Flapping alerts when the readiness changed more than maxChanges times in the window.
+// The state is checked on every call of the condition (see Notifier.Watch).
+func Flapping(name string, ready func() bool, maxChanges int, window time.Duration) FuncCondition {
+
+	var (
+		changes []time.Time
+		last    *bool
+		mu      sync.Mutex
+	)
+
+	return func(context.Context) *Alert {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		val := ready()
+		if last != nil && *last != val {
+			changes = append(changes, now)
+		}
+		last = &val
+
+		for len(changes) > 0 && now.Sub(changes[0]) > window {
+			changes = changes[1:]
+		}
+
+		if len(changes) <= maxChanges {
+			return nil
+		}
+
+		return &Alert{
+			Name:     name,
+			Severity: SeverityWarning,
+			Message:  name + " readiness is flapping",
+			Labels: map[string]string{
+				"changes": formatInt(int64(len(changes))),
+				"window":  window.String(),
+			},
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDead(t *testing.T) {
+
+	alive := true
+	condition := Dead("consumer", func() bool { return alive })
+
+	require.Nil(t, condition(context.Background()))
+
+	alive = false
+	a := condition(context.Background())
+	require.NotNil(t, a)
+	require.Equal(t, "consumer", a.Name)
+	require.Equal(t, SeverityCritical, a.Severity)
+}
+
+func TestLagAbove(t *testing.T) {
+
+	lag := int64(10)
+	condition := LagAbove("consumer", 10, func() int64 { return lag })
+
+	require.Nil(t, condition(context.Background()))
+
+	lag = 11
+	a := condition(context.Background())
+	require.NotNil(t, a)
+	require.Equal(t, map[string]string{"lag": "11", "threshold": "10"}, a.Labels)
+}
+
+func TestFlapping(t *testing.T) {
+
+	ready := true
+	condition := Flapping("service", func() bool { return ready }, 2, time.Minute)
+
+	require.Nil(t, condition(context.Background()))
+	for i := 0; i < 2; i++ {
+		ready = !ready
+		require.Nil(t, condition(context.Background()))
+	}
+
+	ready = !ready
+	a := condition(context.Background())
+	require.NotNil(t, a)
+	require.Equal(t, "3", a.Labels["changes"])
+
+	// the state isn't changed
+	require.NotNil(t, condition(context.Background()))
+}
+
+func TestFlappingWindow(t *testing.T) {
+
+	ready := true
+	condition := Flapping("service", func() bool { return ready }, 0, time.Millisecond*10)
+
+	require.Nil(t, condition(context.Background()))
+
+	ready = false
+	require.NotNil(t, condition(context.Background()))
+
+	time.Sleep(time.Millisecond * 20)
+	require.Nil(t, condition(context.Background()))
+}
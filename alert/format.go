@@ -0,0 +1,120 @@
+package alert
+
+import (
+	"sort"
+	"strconv"
+)
+
+// FuncFormat returns the webhook payload of the alert (encoded as JSON)
+type FuncFormat func(a *Alert) interface{}
+
+// JSONFormat sends the alert as is
+func JSONFormat(a *Alert) interface{} {
+	return a
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Title  string       `json:"title"`
+	Text   string       `json:"text"`
+	Fields []slackField `json:"fields,omitempty"`
+	TS     int64        `json:"ts"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// SlackFormat formats the alert as a Slack incoming webhook message
+func SlackFormat(a *Alert) interface{} {
+
+	var fields []slackField
+	for _, k := range sortedKeys(a.Labels) {
+		fields = append(fields, slackField{Title: k, Value: a.Labels[k], Short: true})
+	}
+
+	return &slackMessage{
+		Text: "[" + string(a.Severity) + "] " + a.Name,
+		Attachments: []slackAttachment{{
+			Color:  "#" + severityColor(a.Severity),
+			Title:  a.Name,
+			Text:   a.Message,
+			Fields: fields,
+			TS:     a.Time.Unix(),
+		}},
+	}
+}
+
+type teamsMessage struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	ThemeColor string         `json:"themeColor"`
+	Summary    string         `json:"summary"`
+	Title      string         `json:"title"`
+	Text       string         `json:"text"`
+	Sections   []teamsSection `json:"sections,omitempty"`
+}
+
+type teamsSection struct {
+	Facts []teamsFact `json:"facts"`
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// TeamsFormat formats the alert as a Microsoft Teams connector card
+func TeamsFormat(a *Alert) interface{} {
+
+	retval := &teamsMessage{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		ThemeColor: severityColor(a.Severity),
+		Summary:    a.Name,
+		Title:      "[" + string(a.Severity) + "] " + a.Name,
+		Text:       a.Message,
+	}
+
+	if len(a.Labels) > 0 {
+		section := teamsSection{}
+		for _, k := range sortedKeys(a.Labels) {
+			section.Facts = append(section.Facts, teamsFact{Name: k, Value: a.Labels[k]})
+		}
+		retval.Sections = []teamsSection{section}
+	}
+
+	return retval
+}
+
+func severityColor(s Severity) string {
+	switch s {
+	case SeverityCritical:
+		return "D00000"
+	case SeverityWarning:
+		return "FFA500"
+	default:
+		return "2EB886"
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	retval := make([]string, 0, len(m))
+	for k := range m {
+		retval = append(retval, k)
+	}
+	sort.Strings(retval)
+
+	return retval
+}
+
+func formatInt(val int64) string {
+	return strconv.FormatInt(val, 10)
+}
@@ -0,0 +1,43 @@
+package alert
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+
+	a := &Alert{
+		Name:     "lag",
+		Severity: SeverityWarning,
+		Message:  "message",
+		Labels:   map[string]string{"b": "2", "a": "1"},
+		Time:     time.Unix(100, 0),
+	}
+
+	for _, testData := range []struct {
+		Format FuncFormat
+		Expect string
+	}{
+		{
+			Format: SlackFormat,
+			Expect: `{"text":"[warning] lag","attachments":[{"color":"#FFA500","title":"lag","text":"message",` +
+				`"fields":[{"title":"a","value":"1","short":true},{"title":"b","value":"2","short":true}],"ts":100}]}`,
+		},
+		{
+			Format: TeamsFormat,
+			Expect: `{"@type":"MessageCard","@context":"http://schema.org/extensions","themeColor":"FFA500",` +
+				`"summary":"lag","title":"[warning] lag","text":"message",` +
+				`"sections":[{"facts":[{"name":"a","value":"1"},{"name":"b","value":"2"}]}]}`,
+		},
+	} {
+		data, err := json.Marshal(testData.Format(a))
+		require.NoError(t, err)
+		require.JSONEq(t, testData.Expect, string(data))
+	}
+
+	require.Equal(t, a, JSONFormat(a))
+}
@@ -0,0 +1,206 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ErrSuppressed is returned if the alert with the same name was sent during the cooldown
+var ErrSuppressed = errors.New("alert is suppressed")
+
+// NotifierConfig is a configuration of the notifier
+type NotifierConfig struct {
+	// URL of the webhook
+	URL string
+	// Format is JSONFormat by default (see SlackFormat, TeamsFormat)
+	Format FuncFormat
+	Client *http.Client
+	// Retries is a count of the retries of the failed request (3 by default)
+	Retries int
+	// RetryDelay is an initial delay between the retries, it's doubled on every retry (1s by default)
+	RetryDelay time.Duration
+	// Cooldown is a min interval between the alerts with the same name (5m by default)
+	Cooldown time.Duration
+	Logger   *zap.Logger
+}
+
+// A Notifier posts the alerts to the webhook
+type Notifier struct {
+	url        string
+	format     FuncFormat
+	client     *http.Client
+	retries    int
+	retryDelay time.Duration
+	cooldown   time.Duration
+	logger     *zap.Logger
+	sent       map[string]time.Time
+	mu         sync.Mutex
+}
+
+// NewNotifier creates a notifier
+func NewNotifier(cfg NotifierConfig) (*Notifier, error) {
+
+	if cfg.URL == "" {
+		return nil, errors.New("webhook url is empty")
+	}
+
+	n := &Notifier{
+		url:        cfg.URL,
+		format:     cfg.Format,
+		client:     cfg.Client,
+		retries:    cfg.Retries,
+		retryDelay: cfg.RetryDelay,
+		cooldown:   cfg.Cooldown,
+		logger:     cfg.Logger,
+		sent:       make(map[string]time.Time),
+	}
+
+	if n.format == nil {
+		n.format = JSONFormat
+	}
+	if n.client == nil {
+		n.client = &http.Client{Timeout: time.Second * 10}
+	}
+	if n.retries <= 0 {
+		n.retries = 3
+	}
+	if n.retryDelay <= 0 {
+		n.retryDelay = time.Second
+	}
+	if n.cooldown <= 0 {
+		n.cooldown = time.Minute * 5
+	}
+	if n.logger == nil {
+		n.logger = zap.NewNop()
+	}
+
+	return n, nil
+}
+
+// Notify posts the alert. The alert is suppressed if the alert
+// with the same name was sent during the cooldown.
+func (n *Notifier) Notify(ctx context.Context, a *Alert) error {
+
+	if a.Time.IsZero() {
+		a.Time = time.Now()
+	}
+
+	n.mu.Lock()
+	if last, ok := n.sent[a.Name]; ok && a.Time.Sub(last) < n.cooldown {
+		n.mu.Unlock()
+		return ErrSuppressed
+	}
+	n.sent[a.Name] = a.Time
+	n.mu.Unlock()
+
+	body, err := json.Marshal(n.format(a))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode alert")
+	}
+
+	delay := n.retryDelay
+	for i := 0; ; i++ {
+		var retry bool
+		retry, err = n.post(ctx, body)
+		if err == nil || !retry || i >= n.retries {
+			break
+		}
+
+		n.logger.Warn("failed to send alert, retry...", zap.String("alert", a.Name), zap.Error(err))
+
+		tm := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			tm.Stop()
+			return ctx.Err()
+		case <-tm.C:
+		}
+		delay *= 2
+	}
+
+	if err != nil {
+		// the alert can be sent again without the cooldown
+		n.mu.Lock()
+		delete(n.sent, a.Name)
+		n.mu.Unlock()
+
+		return errors.Wrapf(err, "failed to send alert %s", a.Name)
+	}
+
+	return nil
+}
+
+// Resolve resets the cooldown of the alert
+func (n *Notifier) Resolve(name string) {
+	n.mu.Lock()
+	delete(n.sent, name)
+	n.mu.Unlock()
+}
+
+// Watch checks the conditions with the interval until the context is done.
+// The alert is resolved when the condition isn't triggered.
+func (n *Notifier) Watch(ctx context.Context, interval time.Duration, conditions ...FuncCondition) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	active := make(map[int]string)
+
+	for {
+		for i, condition := range conditions {
+			a := condition(ctx)
+			if a == nil {
+				if name, ok := active[i]; ok {
+					n.Resolve(name)
+					delete(active, i)
+				}
+				continue
+			}
+
+			active[i] = a.Name
+			if err := n.Notify(ctx, a); err != nil && err != ErrSuppressed {
+				n.logger.Error("failed to notify", zap.String("alert", a.Name), zap.Error(err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// post sends the request and reports that the failed request can be retried
+func (n *Notifier) post(ctx context.Context, body []byte) (retry bool, _ error) {
+
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return retry, errors.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return false, nil
+}
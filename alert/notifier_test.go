@@ -0,0 +1,138 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testWebhook struct {
+	statuses []int
+	alerts   []Alert
+	mu       sync.Mutex
+}
+
+func (h *testWebhook) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := http.StatusOK
+	if len(h.statuses) > 0 {
+		status, h.statuses = h.statuses[0], h.statuses[1:]
+	}
+
+	if status == http.StatusOK {
+		var a Alert
+		if err := json.NewDecoder(req.Body).Decode(&a); err == nil {
+			h.alerts = append(h.alerts, a)
+		}
+	}
+
+	w.WriteHeader(status)
+}
+
+func (h *testWebhook) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.alerts)
+}
+
+func TestNotifier(t *testing.T) {
+
+	_, err := NewNotifier(NotifierConfig{})
+	require.Error(t, err)
+
+	webhook := &testWebhook{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	srv := httptest.NewServer(webhook)
+	defer srv.Close()
+
+	n, err := NewNotifier(NotifierConfig{
+		URL:        srv.URL,
+		RetryDelay: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, n.Notify(ctx, &Alert{Name: "a1"}))
+	require.Equal(t, 1, webhook.count())
+	require.Equal(t, "a1", webhook.alerts[0].Name)
+
+	// cooldown
+	require.Equal(t, ErrSuppressed, n.Notify(ctx, &Alert{Name: "a1"}))
+	require.NoError(t, n.Notify(ctx, &Alert{Name: "a2"}))
+
+	n.Resolve("a1")
+	require.NoError(t, n.Notify(ctx, &Alert{Name: "a1"}))
+	require.Equal(t, 3, webhook.count())
+}
+
+func TestNotifierError(t *testing.T) {
+
+	webhook := &testWebhook{statuses: []int{http.StatusBadRequest, http.StatusOK}}
+	srv := httptest.NewServer(webhook)
+	defer srv.Close()
+
+	n, err := NewNotifier(NotifierConfig{
+		URL:        srv.URL,
+		RetryDelay: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	// the client error isn't retried
+	require.Error(t, n.Notify(context.Background(), &Alert{Name: "a1"}))
+	require.Equal(t, 0, webhook.count())
+
+	// the failed alert isn't suppressed
+	require.NoError(t, n.Notify(context.Background(), &Alert{Name: "a1"}))
+	require.Equal(t, 1, webhook.count())
+}
+
+func TestNotifierWatch(t *testing.T) {
+
+	webhook := &testWebhook{}
+	srv := httptest.NewServer(webhook)
+	defer srv.Close()
+
+	n, err := NewNotifier(NotifierConfig{URL: srv.URL})
+	require.NoError(t, err)
+
+	var (
+		alive = false
+		mu    sync.Mutex
+	)
+	isAlive := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return alive
+	}
+	setAlive := func(val bool) {
+		mu.Lock()
+		alive = val
+		mu.Unlock()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.Watch(ctx, time.Millisecond, Dead("consumer", isAlive))
+	}()
+
+	require.Eventually(t, func() bool { return webhook.count() == 1 }, time.Second, time.Millisecond)
+
+	// resolved
+	setAlive(true)
+	time.Sleep(time.Millisecond * 20)
+	setAlive(false)
+
+	require.Eventually(t, func() bool { return webhook.count() == 2 }, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
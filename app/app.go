@@ -0,0 +1,302 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/config"
+	"github.com/dialogs/dialog-go-lib/logger"
+	"github.com/dialogs/dialog-go-lib/reporter"
+	"github.com/dialogs/dialog-go-lib/service"
+	"github.com/dialogs/dialog-go-lib/service/info"
+	"github.com/dialogs/dialog-go-lib/service/router"
+	"github.com/dialogs/dialog-go-lib/trace"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	_ConfigAdminAddr = "admin_addr"
+	_CloseTimeout    = time.Second * 30
+)
+
+// IComponent is a long-running component of the application (e.g. consumer.Group)
+type IComponent interface {
+	// Start blocks until the component is stopped
+	Start() error
+	StopContext(ctx context.Context) error
+}
+
+// IServer is a network service of the application (e.g. service.HTTP, service.GRPC)
+type IServer interface {
+	ListenAndServeAddr(l *zap.Logger, addr string) error
+	SetReporter(r reporter.IReporter)
+	Close() error
+}
+
+type server struct {
+	name string
+	addr string
+	svc  IServer
+}
+
+type httpHandler struct {
+	addr    string
+	handler http.Handler
+}
+
+type component struct {
+	name string
+	c    IComponent
+}
+
+// An App ties together the configuration, logging, admin router, tracing, services and components
+type App struct {
+	info       *info.Info
+	logger     *zap.Logger
+	config     *viper.Viper
+	admin      *router.AdminRouter
+	adminAddr  string
+	adminAuth  router.FuncMiddleware
+	tracer     *trace.Tracer
+	reporter   reporter.IReporter
+	handlers   []httpHandler
+	servers    []server
+	components []component
+	tasks      []service.GroupTask
+	closers    []closer
+	stopAfter  map[string][]string
+	restarts   map[string]restartPolicy
+	metrics    *Metrics
+	states     map[string]string
+	statesMu   sync.Mutex
+	clock      clock.Clock
+	ctx        context.Context
+	ctxCancel  context.CancelFunc
+}
+
+// New creates an application
+func New(opts ...Option) (*App, error) {
+
+	a := &App{}
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.info == nil {
+		a.info = &info.Info{}
+	}
+
+	if a.clock == nil {
+		a.clock = clock.Real
+	}
+
+	if a.logger == nil {
+		l, err := logger.New()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create logger")
+		}
+		a.logger = l
+	}
+	a.logger = a.logger.With(zap.String("app", a.info.Name))
+
+	if a.config == nil {
+		a.config = config.New(strings.Replace(a.info.Name, "-", "_", -1), true)
+	}
+
+	if a.adminAddr == "" {
+		a.adminAddr = a.config.GetString(_ConfigAdminAddr)
+	}
+	if a.adminAddr == "" {
+		return nil, errors.New("admin address is empty")
+	}
+
+	a.admin = router.NewAdminRouterWithOptions(a.info, router.WithAuth(a.adminAuth))
+
+	for _, item := range a.components {
+		if ctrl, ok := item.c.(router.IConsumerControl); ok {
+			a.admin.RegisterConsumer(item.name, ctrl)
+		}
+	}
+
+	servers := []server{{name: _AdminName, addr: a.adminAddr, svc: service.NewHTTPWithOptions(a.admin, service.WithTimeout(_CloseTimeout))}}
+	for _, item := range a.handlers {
+		handler := item.handler
+		if mux, ok := handler.(*http.ServeMux); ok && a.tracer != nil {
+			// the spans are named by the routes of the mux
+			handler = trace.MuxRoutes(mux)
+		}
+
+		handler = logger.Middleware(a.logger)(handler)
+		if a.tracer != nil {
+			handler = trace.Middleware(a.tracer)(handler)
+		}
+		if a.reporter != nil {
+			handler = reporter.Recover(a.reporter, a.logger)(handler)
+		}
+
+		servers = append(servers, server{name: "http", addr: item.addr, svc: service.NewHTTPWithOptions(handler, service.WithTimeout(_CloseTimeout))})
+	}
+	a.servers = append(servers, a.servers...)
+
+	if a.reporter != nil {
+		for _, item := range a.servers {
+			item.svc.SetReporter(a.reporter)
+		}
+	}
+
+	units := a.units()
+	if err := checkStopOrder(units); err != nil {
+		return nil, err
+	}
+	if err := checkRestarts(units, a.restarts); err != nil {
+		return nil, err
+	}
+	a.states = make(map[string]string, len(units))
+
+	a.ctx, a.ctxCancel = context.WithCancel(context.Background())
+
+	return a, nil
+}
+
+// Info returns the application info
+func (a *App) Info() *info.Info {
+	return a.info
+}
+
+// Logger returns the logger of the application
+func (a *App) Logger() *zap.Logger {
+	return a.logger
+}
+
+// Config returns the configuration of the application
+func (a *App) Config() *viper.Viper {
+	return a.config
+}
+
+// AdminRouter returns the admin router (custom handlers can be registered before Run)
+func (a *App) AdminRouter() *router.AdminRouter {
+	return a.admin
+}
+
+// Run starts the services, components and tasks and blocks until
+// one of them is stopped, the application is closed or SIGINT/SIGTERM is received.
+// All the others are stopped after that in the order of WithStopAfter (the timeline is logged).
+func (a *App) Run() error {
+
+	a.logger.Info("starting...")
+
+	units := a.units()
+
+	tasks := make([]service.GroupTask, 0, len(units)+1)
+	tasks = append(tasks, a.waitStop)
+	for _, u := range units {
+		tasks = append(tasks, a.task(u))
+	}
+
+	chErr, cancel := service.RunGroup(tasks...)
+
+	retval := <-chErr
+	cancel()
+
+	a.logger.Info("stopping...")
+	a.shutdown(units)
+
+	for err := range chErr {
+		if retval == nil {
+			retval = err
+		}
+	}
+
+	if a.tracer != nil {
+		if err := a.tracer.Close(); err != nil {
+			a.logger.Error("failed to close tracer", zap.Error(err))
+		}
+	}
+
+	if retval != nil {
+		a.logger.Error("stopped with error", zap.Error(retval))
+	} else {
+		a.logger.Info("stopped")
+	}
+
+	return retval
+}
+
+// Close stops the application
+func (a *App) Close() error {
+	a.ctxCancel()
+	return nil
+}
+
+func (a *App) waitStop(ctx context.Context) error {
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	select {
+	case <-ctx.Done():
+	case <-a.ctx.Done():
+	case sig := <-interrupt:
+		a.logger.Info("got signal", zap.String("signal", sig.String()))
+	}
+
+	return nil
+}
+
+func (a *App) serverTask(s server) service.GroupTask {
+
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = s.svc.Close()
+			case <-done:
+			}
+		}()
+
+		err := s.svc.ListenAndServeAddr(a.logger, s.addr)
+		if err == http.ErrServerClosed {
+			return nil
+		}
+
+		return errors.Wrapf(err, "%s service %s", s.name, s.addr)
+	}
+}
+
+func (a *App) componentTask(c component) service.GroupTask {
+
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			select {
+			case <-ctx.Done():
+				stopCtx, cancel := context.WithTimeout(context.Background(), _CloseTimeout)
+				defer cancel()
+
+				if err := c.c.StopContext(stopCtx); err != nil {
+					a.logger.Error("failed to stop component", zap.String("component", c.name), zap.Error(err))
+				}
+			case <-done:
+			}
+		}()
+
+		return errors.Wrapf(c.c.Start(), "component %s", c.name)
+	}
+}
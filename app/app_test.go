@@ -0,0 +1,435 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	clockmock "github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/dialogs/dialog-go-lib/metric"
+	"github.com/dialogs/dialog-go-lib/metric/mock"
+	"github.com/dialogs/dialog-go-lib/service"
+	"github.com/dialogs/dialog-go-lib/service/info"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testComponent struct {
+	started chan struct{}
+	stop    chan struct{}
+	err     error
+	once    sync.Once
+}
+
+func newTestComponent(err error) *testComponent {
+	return &testComponent{
+		started: make(chan struct{}),
+		stop:    make(chan struct{}),
+		err:     err,
+	}
+}
+
+func (c *testComponent) Start() error {
+	close(c.started)
+	if c.err != nil {
+		return c.err
+	}
+
+	<-c.stop
+	return nil
+}
+
+func (c *testComponent) StopContext(context.Context) error {
+	c.once.Do(func() { close(c.stop) })
+	return nil
+}
+
+type testConsumerComponent struct {
+	*testComponent
+}
+
+func (testConsumerComponent) SleepAll(time.Duration) error { return nil }
+func (testConsumerComponent) ResumeAll() error             { return nil }
+func (testConsumerComponent) Commit() error                { return nil }
+func (testConsumerComponent) Resubscribe() error           { return nil }
+
+func TestApp(t *testing.T) {
+
+	adminAddr := tempAddress(t)
+	httpAddr := tempAddress(t)
+
+	c := testConsumerComponent{newTestComponent(nil)}
+	taskDone := make(chan struct{})
+
+	a, err := New(
+		WithInfo(&info.Info{Name: "test-app"}),
+		WithLogger(zap.NewNop()),
+		WithAdminAddr(adminAddr),
+		WithHTTP(httpAddr, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})),
+		WithComponent("consumer", c),
+		WithTask(func(ctx context.Context) error {
+			<-ctx.Done()
+			close(taskDone)
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "test-app", a.Info().Name)
+	require.NotNil(t, a.Config())
+	require.NotNil(t, a.Logger())
+	require.NotNil(t, a.AdminRouter())
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- a.Run() }()
+
+	<-c.started
+	require.NoError(t, service.PingConn(adminAddr, 3, time.Second, nil))
+	require.NoError(t, service.PingConn(httpAddr, 3, time.Second, nil))
+
+	res, err := http.Get("http://" + httpAddr)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	// the consumer endpoints are registered (forbidden without the auth middleware)
+	res, err = http.Post("http://"+adminAddr+"/consumers/consumer/commit", "", nil)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	require.NoError(t, a.Close())
+	require.NoError(t, <-runErr)
+	<-c.stop
+	<-taskDone
+}
+
+func TestAppComponentError(t *testing.T) {
+
+	c := newTestComponent(errors.New("failed"))
+
+	a, err := New(
+		WithLogger(zap.NewNop()),
+		WithAdminAddr(tempAddress(t)),
+		WithComponent("consumer", c),
+	)
+	require.NoError(t, err)
+
+	require.EqualError(t, a.Run(), "component consumer: failed")
+}
+
+func TestAppOptions(t *testing.T) {
+
+	_, err := New(WithLogger(zap.NewNop()))
+	require.EqualError(t, err, "admin address is empty")
+
+	_, err = New(func(*App) error { return errors.New("invalid option") })
+	require.EqualError(t, err, "invalid option")
+}
+
+type testCloser struct {
+	name  string
+	order *[]string
+	mu    *sync.Mutex
+}
+
+func (c testCloser) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.order = append(*c.order, c.name)
+	return nil
+}
+
+func TestAppStopOrder(t *testing.T) {
+
+	var (
+		order []string
+		mu    sync.Mutex
+	)
+
+	c := newTestComponent(nil)
+	done := make(chan struct{})
+
+	a, err := New(
+		WithLogger(zap.NewNop()),
+		WithAdminAddr(tempAddress(t)),
+		WithComponent("consumer", c),
+		WithTask(func(ctx context.Context) error {
+			<-ctx.Done()
+			<-c.stop
+			mu.Lock()
+			order = append(order, "consumer")
+			mu.Unlock()
+			return nil
+		}),
+		WithCloser("db", testCloser{name: "db", order: &order, mu: &mu}),
+		WithStopAfter("db", "consumer", "task"),
+		WithTask(func(ctx context.Context) error {
+			<-ctx.Done()
+			close(done)
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+
+	units := a.units()
+	names := make([]string, 0, len(units))
+	for _, u := range units {
+		names = append(names, u.name)
+	}
+	require.Equal(t, []string{"admin", "consumer", "task", "task-2", "db"}, names)
+	require.Equal(t, []string{"consumer", "task", "task-2", "db"}, units[0].after)
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- a.Run() }()
+
+	<-c.started
+	require.NoError(t, a.Close())
+	require.NoError(t, <-runErr)
+	<-done
+
+	require.Equal(t, []string{"consumer", "db"}, order)
+}
+
+type testStoppingComponent struct {
+	*testComponent
+	onStop func()
+}
+
+func (c testStoppingComponent) StopContext(ctx context.Context) error {
+	c.onStop()
+	return c.testComponent.StopContext(ctx)
+}
+
+func TestAppStopOrderState(t *testing.T) {
+
+	addr := tempAddress(t)
+	get := func() error {
+		res, err := http.Get("http://" + addr)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}
+
+	// the http service is requested while the consumer is stopping
+	var stoppingErr error
+	c := testStoppingComponent{
+		testComponent: newTestComponent(nil),
+		onStop:        func() { stoppingErr = get() },
+	}
+
+	a, err := New(
+		WithLogger(zap.NewNop()),
+		WithAdminAddr(tempAddress(t)),
+		WithHTTP(addr, http.NotFoundHandler()),
+		WithComponent("consumer", c),
+		WithStopAfter("http", "consumer"),
+	)
+	require.NoError(t, err)
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- a.Run() }()
+
+	<-c.started
+	require.Eventually(t, func() bool { return get() == nil }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, a.Close())
+	require.NoError(t, <-runErr)
+
+	require.NoError(t, stoppingErr)
+	require.Error(t, get())
+}
+
+func TestAppShutdownTimeline(t *testing.T) {
+
+	clk := clockmock.NewClock(time.Unix(100, 0))
+
+	a, err := New(
+		WithLogger(zap.NewNop()),
+		WithAdminAddr(tempAddress(t)),
+		WithClock(clk),
+	)
+	require.NoError(t, err)
+
+	newUnit := func(name string, d time.Duration, after ...string) *unit {
+		u := &unit{name: name, after: after, done: make(chan struct{})}
+		u.cancel = func() {
+			clk.Add(d)
+			close(u.done)
+		}
+		return u
+	}
+
+	timeline := a.shutdown([]*unit{
+		newUnit("consumer", time.Second),
+		newUnit("db", 2*time.Second, "consumer"),
+	})
+
+	require.Equal(t, []timelineEntry{
+		{name: "consumer", start: 0, end: time.Second},
+		{name: "db", start: time.Second, end: 3 * time.Second},
+	}, timeline)
+	require.Equal(t, "+1s db (2s)", timeline[1].String())
+}
+
+func TestAppStopOrderInvalid(t *testing.T) {
+
+	_, err := New(
+		WithLogger(zap.NewNop()),
+		WithAdminAddr(tempAddress(t)),
+		WithComponent("consumer", newTestComponent(nil)),
+		WithStopAfter("consumer", "db"),
+	)
+	require.EqualError(t, err, "unknown name db in stop order of consumer")
+
+	_, err = New(
+		WithLogger(zap.NewNop()),
+		WithAdminAddr(tempAddress(t)),
+		WithComponent("consumer", newTestComponent(nil)),
+		WithCloser("db", testCloser{}),
+		WithStopAfter("consumer", "db"),
+		WithStopAfter("db", "consumer"),
+	)
+	require.EqualError(t, err, "stop order cycle: admin -> consumer -> db -> consumer")
+}
+
+type testMetrics struct {
+	gauges    map[string]*mock.Gauge
+	observers map[string]*mock.Observer
+	counters  map[string]*mock.Counter
+	mu        sync.Mutex
+}
+
+func newTestMetrics() *testMetrics {
+	return &testMetrics{
+		gauges:    make(map[string]*mock.Gauge),
+		observers: make(map[string]*mock.Observer),
+		counters:  make(map[string]*mock.Counter),
+	}
+}
+
+func (m *testMetrics) metrics() *Metrics {
+	return &Metrics{
+		State: func(unit, state string) metric.IGauge {
+			return m.gauge(unit + "/" + state)
+		},
+		StartDuration: func(unit string) metric.IObserver {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if m.observers[unit] == nil {
+				m.observers[unit] = mock.NewObserver()
+			}
+			return m.observers[unit]
+		},
+		Restarts: func(unit string) metric.ICounter {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if m.counters[unit] == nil {
+				m.counters[unit] = mock.NewCounter()
+			}
+			return m.counters[unit]
+		},
+	}
+}
+
+func (m *testMetrics) gauge(key string) *mock.Gauge {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.gauges[key] == nil {
+		m.gauges[key] = mock.NewGauge()
+	}
+	return m.gauges[key]
+}
+
+func TestAppMetrics(t *testing.T) {
+
+	m := newTestMetrics()
+	c := newTestComponent(nil)
+	failures := 0
+
+	a, err := New(
+		WithLogger(zap.NewNop()),
+		WithAdminAddr(tempAddress(t)),
+		WithMetrics(m.metrics()),
+		WithComponent("consumer", c),
+		WithTask(func(ctx context.Context) error {
+			if failures < 2 {
+				failures++
+				return errors.New("failed")
+			}
+			<-ctx.Done()
+			return nil
+		}),
+		WithRestart("task", 2, time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- a.Run() }()
+
+	<-c.started
+	require.Eventually(t, func() bool {
+		return m.gauge("admin/running").Get() == 1 &&
+			m.gauge("consumer/running").Get() == 1 &&
+			m.gauge("task/running").Get() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Equal(t, float64(0), m.gauge("admin/starting").Get())
+	require.Equal(t, float64(0), m.gauge("task/failed").Get())
+	require.Equal(t, uint64(2), m.counters["task"].Get())
+	require.Len(t, m.observers["admin"].GetSlice(), 1)
+	// the task is started three times
+	require.Len(t, m.observers["task"].GetSlice(), 3)
+
+	require.NoError(t, a.Close())
+	require.NoError(t, <-runErr)
+
+	for _, name := range []string{"admin", "consumer", "task"} {
+		require.Equal(t, float64(1), m.gauge(name+"/stopped").Get(), name)
+		require.Equal(t, float64(0), m.gauge(name+"/running").Get(), name)
+		require.Equal(t, float64(0), m.gauge(name+"/stopping").Get(), name)
+	}
+}
+
+func TestAppRestartFailed(t *testing.T) {
+
+	m := newTestMetrics()
+
+	a, err := New(
+		WithLogger(zap.NewNop()),
+		WithAdminAddr(tempAddress(t)),
+		WithMetrics(m.metrics()),
+		WithTask(func(context.Context) error { return errors.New("failed") }),
+		WithRestart("task", 1, time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	require.EqualError(t, a.Run(), "failed")
+	require.Equal(t, uint64(1), m.counters["task"].Get())
+	require.Equal(t, float64(1), m.gauge("task/failed").Get())
+
+	_, err = New(
+		WithLogger(zap.NewNop()),
+		WithAdminAddr(tempAddress(t)),
+		WithRestart("consumer", 1, time.Second),
+	)
+	require.EqualError(t, err, "unknown name consumer in restart policy")
+
+	_, err = New(WithRestart("task", 0, time.Second))
+	require.EqualError(t, err, "restart attempts must be positive")
+}
+
+func tempAddress(t *testing.T) string {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	return l.Addr().String()
+}
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/metric"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// The states of the units (see Metrics.State)
+const (
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StateStopping = "stopping"
+	StateStopped  = "stopped"
+	StateFailed   = "failed"
+)
+
+const _ReadyCheckInterval = 100 * time.Millisecond
+
+// FuncStateGauge returns the gauge of the state of the unit (e.g. prometheus.GaugeVec.WithLabelValues)
+type FuncStateGauge func(unit, state string) metric.IGauge
+
+// FuncUnitObserver returns the observer of the unit (e.g. prometheus.HistogramVec.WithLabelValues)
+type FuncUnitObserver func(unit string) metric.IObserver
+
+// FuncUnitCounter returns the counter of the unit (e.g. prometheus.CounterVec.WithLabelValues)
+type FuncUnitCounter func(unit string) metric.ICounter
+
+// Metrics of the application. The units are the services, the components, the tasks and the closers
+// with the names of WithStopAfter. All fields are optional.
+type Metrics struct {
+	// State is 1 for the current state of the unit and 0 for the previous one
+	State FuncStateGauge
+	// StartDuration is a time (seconds) from the start of the unit until it's running:
+	// the service accepts the connections, the component implementing router.IHealthChecker is healthy
+	StartDuration FuncUnitObserver
+	// Restarts is a count of the restarts of the failed unit (see WithRestart)
+	Restarts FuncUnitCounter
+}
+
+type restartPolicy struct {
+	attempts int
+	delay    time.Duration
+}
+
+// checkRestarts checks the names of WithRestart
+func checkRestarts(units []*unit, restarts map[string]restartPolicy) error {
+
+	index := make(map[string]struct{}, len(units))
+	for _, u := range units {
+		index[u.name] = struct{}{}
+	}
+
+	for name := range restarts {
+		if _, ok := index[name]; !ok {
+			return errors.Errorf("unknown name %s in restart policy", name)
+		}
+	}
+
+	return nil
+}
+
+// setState updates the state of the unit (the running state is ignored after the stopping one,
+// the stopping state is ignored after the unit is stopped)
+func (a *App) setState(name, state string) {
+
+	a.statesMu.Lock()
+	defer a.statesMu.Unlock()
+
+	prev, ok := a.states[name]
+	switch {
+	case prev == state,
+		prev == StateStopping && state == StateRunning,
+		(prev == StateStopped || prev == StateFailed) && state == StateStopping:
+		return
+	}
+	a.states[name] = state
+
+	if a.metrics == nil || a.metrics.State == nil {
+		return
+	}
+	if ok {
+		a.metrics.State(name, prev).Set(0)
+	}
+	a.metrics.State(name, state).Set(1)
+}
+
+// runUnit runs the unit until it's stopped by the shutdown, the failed unit is restarted by the restart policy
+func (a *App) runUnit(u *unit) func(context.Context) error {
+
+	return func(context.Context) error {
+		defer close(u.done)
+
+		for attempt := 1; ; attempt++ {
+			err := a.startUnit(u)
+			if err == nil {
+				a.setState(u.name, StateStopped)
+				return nil
+			}
+			a.setState(u.name, StateFailed)
+
+			if u.ctx.Err() != nil || attempt > u.restart.attempts {
+				return err
+			}
+
+			a.logger.Error("unit failed, restarting...",
+				zap.String("unit", u.name),
+				zap.Int("attempt", attempt),
+				zap.Error(err))
+
+			if a.metrics != nil && a.metrics.Restarts != nil {
+				a.metrics.Restarts(u.name).Inc()
+			}
+
+			timer := a.clock.NewTimer(u.restart.delay)
+			select {
+			case <-u.ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C():
+			}
+		}
+	}
+}
+
+func (a *App) startUnit(u *unit) error {
+
+	a.setState(u.name, StateStarting)
+
+	ctx, cancel := context.WithCancel(u.ctx)
+	readyDone := make(chan struct{})
+
+	go func() {
+		defer close(readyDone)
+
+		start := a.clock.Now()
+		if u.ready != nil && waitReady(ctx, u.ready) != nil {
+			return
+		}
+
+		a.setState(u.name, StateRunning)
+		if a.metrics != nil && a.metrics.StartDuration != nil {
+			a.metrics.StartDuration(u.name).Observe(a.clock.Since(start).Seconds())
+		}
+	}()
+
+	err := u.run(u.ctx)
+
+	cancel()
+	<-readyDone
+
+	return err
+}
+
+// waitReady checks the unit with the interval until it's ready or the context is done
+func waitReady(ctx context.Context, check func() error) error {
+
+	ticker := time.NewTicker(_ReadyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := check(); err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func dialCheck(addr string) func() error {
+
+	return func() error {
+		conn, err := net.DialTimeout("tcp", addr, _ReadyCheckInterval)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/reporter"
+	"github.com/dialogs/dialog-go-lib/service"
+	"github.com/dialogs/dialog-go-lib/service/info"
+	"github.com/dialogs/dialog-go-lib/service/router"
+	"github.com/dialogs/dialog-go-lib/trace"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// An Option of the application
+type Option func(a *App) error
+
+// WithInfo sets the application info (the name is a prefix of the environment variables)
+func WithInfo(appinfo *info.Info) Option {
+	return func(a *App) error {
+		a.info = appinfo
+		return nil
+	}
+}
+
+// WithLogger sets the logger. By default the logger is created by logger.New.
+func WithLogger(l *zap.Logger) Option {
+	return func(a *App) error {
+		a.logger = l
+		return nil
+	}
+}
+
+// WithConfig sets the configuration. By default the configuration is read
+// from the environment variables with the prefix of the application name (see config.New).
+func WithConfig(v *viper.Viper) Option {
+	return func(a *App) error {
+		a.config = v
+		return nil
+	}
+}
+
+// WithAdminAddr sets the address of the admin router (the config key 'admin_addr' by default)
+func WithAdminAddr(addr string) Option {
+	return func(a *App) error {
+		a.adminAddr = addr
+		return nil
+	}
+}
+
+// WithAdminAuth sets the middleware which protects the admin endpoints of the components
+func WithAdminAuth(auth router.FuncMiddleware) Option {
+	return func(a *App) error {
+		a.adminAuth = auth
+		return nil
+	}
+}
+
+// WithTracer sets the tracer of the http requests. The tracer is closed by the application.
+func WithTracer(t *trace.Tracer) Option {
+	return func(a *App) error {
+		a.tracer = t
+		return nil
+	}
+}
+
+// WithReporter sets the reporter of the panics of the http requests and the fatal errors of the services
+func WithReporter(r reporter.IReporter) Option {
+	return func(a *App) error {
+		a.reporter = r
+		return nil
+	}
+}
+
+// WithHTTP adds the http service (the handler is wrapped by the logging, tracing and recovery middlewares)
+func WithHTTP(addr string, handler http.Handler) Option {
+	return func(a *App) error {
+		a.handlers = append(a.handlers, httpHandler{addr: addr, handler: handler})
+		return nil
+	}
+}
+
+// WithGRPC adds the grpc service
+func WithGRPC(addr string, svc *service.GRPC) Option {
+	return func(a *App) error {
+		a.servers = append(a.servers, server{name: "grpc", addr: addr, svc: svc})
+		return nil
+	}
+}
+
+// WithComponent adds the component (e.g. consumer.Group). The component which implements
+// router.IConsumerControl is registered in the admin router with the name.
+func WithComponent(name string, c IComponent) Option {
+	return func(a *App) error {
+		a.components = append(a.components, component{name: name, c: c})
+		return nil
+	}
+}
+
+// WithTask adds the task which runs until the context is done
+func WithTask(task service.GroupTask) Option {
+	return func(a *App) error {
+		a.tasks = append(a.tasks, task)
+		return nil
+	}
+}
+
+// WithCloser adds the resource closed on the shutdown (e.g. the pool of the database connections)
+func WithCloser(name string, c io.Closer) Option {
+	return func(a *App) error {
+		a.closers = append(a.closers, closer{name: name, c: c})
+		return nil
+	}
+}
+
+// WithStopAfter declares the shutdown order: the service, the component or the closer with the name
+// is stopped after the ones with the names 'after' are stopped (e.g. the database is closed after
+// the consumers). The names of the services are 'admin', 'http' and 'grpc' ('http-2' for the second one),
+// the names of the tasks are 'task', 'task-2' etc. The admin service is stopped the last by default.
+func WithStopAfter(name string, after ...string) Option {
+	return func(a *App) error {
+		if a.stopAfter == nil {
+			a.stopAfter = make(map[string][]string)
+		}
+		a.stopAfter[name] = append(a.stopAfter[name], after...)
+		return nil
+	}
+}
+
+// WithMetrics sets the metrics of the states of the services, the components, the tasks and the closers
+func WithMetrics(m *Metrics) Option {
+	return func(a *App) error {
+		a.metrics = m
+		return nil
+	}
+}
+
+// WithRestart restarts the failed component or task with the name (see WithStopAfter)
+// after the delay (at most 'attempts' times). The restarted component must support
+// the start after the failure.
+func WithRestart(name string, attempts int, delay time.Duration) Option {
+	return func(a *App) error {
+		if attempts <= 0 {
+			return errors.New("restart attempts must be positive")
+		}
+		if a.restarts == nil {
+			a.restarts = make(map[string]restartPolicy)
+		}
+		a.restarts[name] = restartPolicy{attempts: attempts, delay: delay}
+		return nil
+	}
+}
+
+// WithClock sets the source of the time of the shutdown timeline and the restarts (clock.Real by default)
+func WithClock(clk clock.Clock) Option {
+	return func(a *App) error {
+		a.clock = clk
+		return nil
+	}
+}
@@ -0,0 +1,223 @@
+package app
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/service"
+	"github.com/dialogs/dialog-go-lib/service/router"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const _AdminName = "admin"
+
+type closer struct {
+	name string
+	c    io.Closer
+}
+
+// A unit is a service, a component, a task or a closer stopped in the shutdown order
+type unit struct {
+	name    string
+	run     service.GroupTask
+	ready   func() error
+	after   []string
+	restart restartPolicy
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// A timelineEntry is a step of the shutdown sequence: start and end are the offsets
+// from the beginning of the shutdown
+type timelineEntry struct {
+	name  string
+	start time.Duration
+	end   time.Duration
+}
+
+func (e timelineEntry) String() string {
+	return "+" + e.start.String() + " " + e.name + " (" + (e.end - e.start).String() + ")"
+}
+
+// units returns the units of the services, the components, the tasks and the closers
+func (a *App) units() []*unit {
+
+	var (
+		retval []*unit
+		names  = make(map[string]int)
+	)
+
+	add := func(name string, run service.GroupTask, ready func() error) {
+		names[name]++
+		if n := names[name]; n > 1 {
+			name += "-" + strconv.Itoa(n)
+		}
+
+		retval = append(retval, &unit{
+			name:    name,
+			run:     run,
+			ready:   ready,
+			after:   a.stopAfter[name],
+			restart: a.restarts[name],
+			done:    make(chan struct{}),
+		})
+	}
+
+	for _, item := range a.servers {
+		add(item.name, a.serverTask(item), dialCheck(item.addr))
+	}
+	for _, item := range a.components {
+		var ready func() error
+		if checker, ok := item.c.(router.IHealthChecker); ok {
+			ready = checker.HealthCheck
+		}
+		add(item.name, a.componentTask(item), ready)
+	}
+	for _, task := range a.tasks {
+		add("task", task, nil)
+	}
+	for _, item := range a.closers {
+		add(item.name, a.closerTask(item), nil)
+	}
+
+	// the admin service is available until the end of the shutdown
+	for _, u := range retval {
+		if u.name == _AdminName && len(u.after) == 0 {
+			for _, other := range retval {
+				if other != u {
+					u.after = append(u.after, other.name)
+				}
+			}
+		}
+	}
+
+	return retval
+}
+
+// checkStopOrder checks the names of WithStopAfter and the cycles of the shutdown order
+func checkStopOrder(units []*unit) error {
+
+	index := make(map[string]*unit, len(units))
+	for _, u := range units {
+		index[u.name] = u
+	}
+
+	for _, u := range units {
+		for _, name := range u.after {
+			if _, ok := index[name]; !ok {
+				return errors.Errorf("unknown name %s in stop order of %s", name, u.name)
+			}
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[string]int, len(units))
+
+	var visit func(u *unit, path []string) error
+	visit = func(u *unit, path []string) error {
+
+		switch state[u.name] {
+		case visiting:
+			return errors.Errorf("stop order cycle: %s", strings.Join(append(path, u.name), " -> "))
+		case visited:
+			return nil
+		}
+
+		state[u.name] = visiting
+		for _, name := range u.after {
+			if err := visit(index[name], append(path, u.name)); err != nil {
+				return err
+			}
+		}
+		state[u.name] = visited
+
+		return nil
+	}
+
+	for _, u := range units {
+		if err := visit(u, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shutdown stops the units in the order and returns the timeline of the shutdown
+func (a *App) shutdown(units []*unit) []timelineEntry {
+
+	index := make(map[string]*unit, len(units))
+	for _, u := range units {
+		index[u.name] = u
+	}
+
+	var (
+		begin    = a.clock.Now()
+		timeline []timelineEntry
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+	)
+
+	wg.Add(len(units))
+	for _, u := range units {
+		go func(u *unit) {
+			defer wg.Done()
+
+			for _, name := range u.after {
+				<-index[name].done
+			}
+
+			entry := timelineEntry{name: u.name, start: a.clock.Since(begin)}
+			a.logger.Info("stopping", zap.String("unit", u.name), zap.Duration("elapsed", entry.start))
+
+			a.setState(u.name, StateStopping)
+			u.cancel()
+			<-u.done
+
+			entry.end = a.clock.Since(begin)
+			a.logger.Info("stopped", zap.String("unit", u.name), zap.Duration("elapsed", entry.end))
+
+			mu.Lock()
+			timeline = append(timeline, entry)
+			mu.Unlock()
+		}(u)
+	}
+	wg.Wait()
+
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].start < timeline[j].start })
+
+	lines := make([]string, 0, len(timeline))
+	for _, entry := range timeline {
+		lines = append(lines, entry.String())
+	}
+	a.logger.Info("shutdown timeline", zap.Strings("timeline", lines))
+
+	return timeline
+}
+
+// task runs the unit until it's stopped by the shutdown
+func (a *App) task(u *unit) service.GroupTask {
+
+	u.ctx, u.cancel = context.WithCancel(context.Background())
+
+	return a.runUnit(u)
+}
+
+func (a *App) closerTask(c closer) service.GroupTask {
+
+	return func(ctx context.Context) error {
+		<-ctx.Done()
+		return errors.Wrapf(c.c.Close(), "closer %s", c.name)
+	}
+}
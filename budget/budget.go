@@ -0,0 +1,79 @@
+package budget
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/trace"
+)
+
+// Header is the deadline of the request (unix milliseconds): the latency budget is propagated
+// from the HTTP request to the processing of the messages produced by the request
+const Header = "x-deadline"
+
+// Format returns the value of the header
+func Format(deadline time.Time) string {
+	return strconv.FormatInt(deadline.UnixNano()/int64(time.Millisecond), 10)
+}
+
+// Parse parses the value of the header
+func Parse(val string) (time.Time, bool) {
+
+	ms, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, ms*int64(time.Millisecond)), true
+}
+
+// Inject writes the deadline of the context to the headers (e.g. the headers of the message)
+func Inject(ctx context.Context, c trace.ICarrier) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.Set(Header, Format(deadline))
+	}
+}
+
+// Extract returns the context with the deadline of the headers.
+// The context is done at once if the budget is spent.
+func Extract(ctx context.Context, clk clock.Clock, c trace.ICarrier) (context.Context, context.CancelFunc) {
+
+	deadline, ok := Parse(c.Get(Header))
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	if current, ok := ctx.Deadline(); ok && current.Before(deadline) {
+		return context.WithCancel(ctx)
+	}
+
+	if !deadline.After(clk.Now()) {
+		// the context is done synchronously
+		return context.WithDeadline(ctx, deadline)
+	}
+
+	return clock.WithDeadline(ctx, clk, deadline)
+}
+
+// Middleware sets the deadline of the request: the deadline of the header of the request
+// (e.g. the request of the other service) or the timeout (if it isn't zero)
+func Middleware(timeout time.Duration) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+			ctx, cancel := Extract(req.Context(), clock.Real, req.Header)
+			defer cancel()
+
+			if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
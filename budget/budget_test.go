@@ -0,0 +1,104 @@
+package budget
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatParse(t *testing.T) {
+
+	deadline := time.Unix(1000, 250*int64(time.Millisecond))
+	require.Equal(t, "1000250", Format(deadline))
+
+	res, ok := Parse("1000250")
+	require.True(t, ok)
+	require.True(t, deadline.Equal(res))
+
+	_, ok = Parse("")
+	require.False(t, ok)
+	_, ok = Parse("abc")
+	require.False(t, ok)
+	_, ok = Parse("-1")
+	require.False(t, ok)
+}
+
+func TestInjectExtract(t *testing.T) {
+
+	clk := mock.NewClock(time.Unix(1000, 0))
+
+	h := http.Header{}
+	Inject(context.Background(), h)
+	require.Empty(t, h)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Unix(1005, 0))
+	defer cancel()
+	Inject(ctx, h)
+	require.Equal(t, "1005000", h.Get(Header))
+
+	// the context without the header
+	res, cancel := Extract(context.Background(), clk, http.Header{})
+	defer cancel()
+	_, ok := res.Deadline()
+	require.False(t, ok)
+
+	res, cancel = Extract(context.Background(), clk, h)
+	defer cancel()
+	deadline, ok := res.Deadline()
+	require.True(t, ok)
+	require.True(t, time.Unix(1005, 0).Equal(deadline))
+	require.NoError(t, res.Err())
+
+	clk.Add(time.Second * 5)
+	<-res.Done()
+	require.Equal(t, context.DeadlineExceeded, res.Err())
+
+	// the budget is spent
+	res, cancel = Extract(context.Background(), clk, h)
+	defer cancel()
+	<-res.Done()
+	require.Equal(t, context.DeadlineExceeded, res.Err())
+
+	// the earlier deadline of the context is kept
+	parent, cancelParent := context.WithDeadline(context.Background(), time.Unix(900, 0))
+	defer cancelParent()
+	res, cancel = Extract(parent, clk, h)
+	defer cancel()
+	deadline, _ = res.Deadline()
+	require.True(t, time.Unix(900, 0).Equal(deadline))
+}
+
+func TestMiddleware(t *testing.T) {
+
+	var (
+		deadline time.Time
+		ok       bool
+	)
+	handler := Middleware(time.Minute)(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		deadline, ok = req.Context().Deadline()
+	}))
+
+	// the timeout
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	// the deadline of the header
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	expected := time.Now().Add(time.Second * 10).Truncate(time.Millisecond)
+	req.Header.Set(Header, Format(expected))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.True(t, ok)
+	require.True(t, expected.Equal(deadline))
+
+	// without the timeout
+	Middleware(0)(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		_, ok = req.Context().Deadline()
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	require.False(t, ok)
+}
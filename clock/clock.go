@@ -0,0 +1,111 @@
+package clock
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is a source of the time and the timers (Real or mock.Clock in the tests)
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer sends the time to the channel once after the duration (see time.Timer)
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Ticker sends the time to the channel periodically (see time.Ticker)
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real is the clock of the time package
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// WithDeadline returns the context done at the deadline of the clock (see context.WithDeadline)
+func WithDeadline(parent context.Context, c Clock, deadline time.Time) (context.Context, context.CancelFunc) {
+
+	if _, ok := c.(realClock); ok {
+		return context.WithDeadline(parent, deadline)
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	retval := &deadlineContext{Context: ctx, deadline: deadline}
+	timer := c.NewTimer(deadline.Sub(c.Now()))
+
+	go func() {
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+		case <-timer.C():
+			atomic.StoreInt32(&retval.exceeded, 1)
+			cancel()
+		}
+	}()
+
+	return retval, cancel
+}
+
+// WithTimeout returns the context done after the timeout of the clock (see context.WithTimeout)
+func WithTimeout(parent context.Context, c Clock, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return WithDeadline(parent, c, c.Now().Add(timeout))
+}
+
+type deadlineContext struct {
+	context.Context
+	deadline time.Time
+	exceeded int32
+}
+
+func (c *deadlineContext) Err() error {
+	if err := c.Context.Err(); err != nil && atomic.LoadInt32(&c.exceeded) == 1 {
+		return context.DeadlineExceeded
+	}
+	return c.Context.Err()
+}
+
+func (c *deadlineContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
@@ -0,0 +1,145 @@
+package mock
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock"
+)
+
+// Clock is a manual clock: the time is changed by Add and Set only,
+// the timers and the tickers are fired when their time has come
+type Clock struct {
+	now     time.Time
+	waiters []*waiter
+	mu      sync.Mutex
+}
+
+type waiter struct {
+	until  time.Time
+	period time.Duration
+	c      chan time.Time
+	clock  *Clock
+}
+
+// NewClock returns the clock with the time
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *Clock) NewTimer(d time.Duration) clock.Timer {
+	return c.add(d, 0)
+}
+
+func (c *Clock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	return ticker{c.add(d, d)}
+}
+
+// Add moves the time forward and fires the timers and the tickers
+func (c *Clock) Add(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set changes the time and fires the timers and the tickers
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+
+	sort.Slice(c.waiters, func(i, j int) bool {
+		return c.waiters[i].until.Before(c.waiters[j].until)
+	})
+
+	active := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.until.After(now) {
+			active = append(active, w)
+			continue
+		}
+
+		// the channel is buffered like the channel of time.Ticker: the ticks are dropped for a slow reader
+		select {
+		case w.c <- now:
+		default:
+		}
+
+		if w.period > 0 {
+			for !w.until.After(now) {
+				w.until = w.until.Add(w.period)
+			}
+			active = append(active, w)
+		}
+	}
+	c.waiters = active
+}
+
+// Waiters returns a count of the active timers and tickers
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.waiters)
+}
+
+func (c *Clock) add(d, period time.Duration) *waiter {
+
+	w := &waiter{
+		period: period,
+		c:      make(chan time.Time, 1),
+		clock:  c,
+	}
+
+	c.mu.Lock()
+	w.until = c.now.Add(d)
+	c.waiters = append(c.waiters, w)
+	c.mu.Unlock()
+
+	if d <= 0 {
+		c.Set(c.Now())
+	}
+
+	return w
+}
+
+func (w *waiter) C() <-chan time.Time {
+	return w.c
+}
+
+func (w *waiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	for i, item := range w.clock.waiters {
+		if item == w {
+			w.clock.waiters = append(w.clock.waiters[:i], w.clock.waiters[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+type ticker struct {
+	*waiter
+}
+
+func (t ticker) Stop() {
+	t.waiter.Stop()
+}
@@ -0,0 +1,87 @@
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockTimer(t *testing.T) {
+
+	start := time.Unix(1000, 0)
+	c := NewClock(start)
+	require.Equal(t, start, c.Now())
+
+	timer := c.NewTimer(time.Minute)
+	require.Equal(t, 1, c.Waiters())
+
+	c.Add(time.Second * 59)
+	require.Len(t, timer.C(), 0)
+	require.Equal(t, time.Second*59, c.Since(start))
+
+	c.Add(time.Second)
+	require.Equal(t, start.Add(time.Minute), <-timer.C())
+	require.Equal(t, 0, c.Waiters())
+	require.False(t, timer.Stop())
+
+	// stopped
+	timer = c.NewTimer(time.Minute)
+	require.True(t, timer.Stop())
+	c.Add(time.Hour)
+	require.Len(t, timer.C(), 0)
+
+	// expired
+	timer = c.NewTimer(0)
+	require.Len(t, timer.C(), 1)
+}
+
+func TestClockTicker(t *testing.T) {
+
+	start := time.Unix(1000, 0)
+	c := NewClock(start)
+
+	ticker := c.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	c.Add(time.Second)
+	require.Equal(t, start.Add(time.Second), <-ticker.C())
+
+	// the ticks are dropped for a slow reader
+	c.Add(time.Second)
+	c.Add(time.Second)
+	require.Len(t, ticker.C(), 1)
+	require.Equal(t, start.Add(time.Second*2), <-ticker.C())
+
+	// the next tick is aligned to the period
+	c.Add(time.Millisecond * 1500)
+	require.Equal(t, start.Add(time.Millisecond*4500), <-ticker.C())
+	c.Add(time.Millisecond * 500)
+	require.Equal(t, start.Add(time.Second*5), <-ticker.C())
+
+	ticker.Stop()
+	require.Equal(t, 0, c.Waiters())
+
+	require.Panics(t, func() { c.NewTicker(0) })
+}
+
+func TestClockWithTimeout(t *testing.T) {
+
+	c := NewClock(time.Unix(1000, 0))
+
+	ctx, cancel := clock.WithTimeout(context.Background(), c, time.Minute)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.Equal(t, time.Unix(1060, 0), deadline)
+
+	require.Eventually(t, func() bool { return c.Waiters() == 1 }, time.Second, time.Millisecond)
+	require.NoError(t, ctx.Err())
+
+	c.Add(time.Minute)
+	<-ctx.Done()
+	require.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
@@ -0,0 +1,96 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// _TableNameRegexp is a pattern of the table name (with an optional schema)
+var _TableNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
+// A Record is a row of the outbox table:
+//
+//	CREATE TABLE outbox (
+//		id         BIGSERIAL PRIMARY KEY,
+//		topic      TEXT NOT NULL,
+//		key        BYTEA,
+//		value      BYTEA,
+//		headers    JSONB,
+//		created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+//	);
+type Record struct {
+	ID        int64
+	Topic     string
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	CreatedAt time.Time
+}
+
+// IFetcher returns the records of the outbox in the order of the ids
+type IFetcher interface {
+	Fetch(ctx context.Context, afterID int64, limit int) ([]Record, error)
+}
+
+// SQLFetcher reads the outbox table by the database connection (e.g. pgx/stdlib)
+type SQLFetcher struct {
+	db    *sql.DB
+	query string
+}
+
+// NewSQLFetcher returns the fetcher of the table
+func NewSQLFetcher(db *sql.DB, table string) (*SQLFetcher, error) {
+
+	if !_TableNameRegexp.MatchString(table) {
+		return nil, errors.Errorf("invalid outbox table name: %s", table)
+	}
+
+	return &SQLFetcher{
+		db: db,
+		query: fmt.Sprintf(
+			"SELECT id, topic, key, value, headers, created_at FROM %s WHERE id > $1 ORDER BY id LIMIT $2",
+			table),
+	}, nil
+}
+
+// Fetch returns the records after the id
+func (f *SQLFetcher) Fetch(ctx context.Context, afterID int64, limit int) ([]Record, error) {
+
+	rows, err := f.db.QueryContext(ctx, f.query, afterID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query outbox")
+	}
+	defer rows.Close()
+
+	var retval []Record
+	for rows.Next() {
+		var (
+			rec     Record
+			headers []byte
+		)
+
+		if err := rows.Scan(&rec.ID, &rec.Topic, &rec.Key, &rec.Value, &headers, &rec.CreatedAt); err != nil {
+			return nil, errors.Wrap(err, "failed to read outbox")
+		}
+
+		if len(headers) > 0 {
+			if err := json.Unmarshal(headers, &rec.Headers); err != nil {
+				return nil, errors.Wrapf(err, "invalid headers of outbox record %d", rec.ID)
+			}
+		}
+
+		retval = append(retval, rec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read outbox")
+	}
+
+	return retval, nil
+}
@@ -0,0 +1,148 @@
+package outbox
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/connect"
+	"github.com/pkg/errors"
+)
+
+const (
+	_DefaultBatchSize   = 100
+	_DefaultPartition   = "outbox"
+	_DefaultWaitTimeout = 5 * time.Second
+)
+
+// IWaiter waits for the signal of the new records (see pgnotify.Listener)
+type IWaiter interface {
+	Wait(ctx context.Context) error
+}
+
+// TaskConfig is a configuration of the outbox task
+type TaskConfig struct {
+	// BatchSize is a max count of the records of the poll (100 by default)
+	BatchSize int
+	// Partition is a name of the offset in the offset store ('outbox' by default)
+	Partition string
+	// Waiter wakes up the polling by the notifications (optional)
+	Waiter IWaiter
+	// WaitTimeout is a max wait time of the notification (5 seconds by default)
+	WaitTimeout time.Duration
+}
+
+// A Task is a source task (see connect.NewSource) producing the outbox records to Kafka.
+// The id of the last delivered record is saved to the offset store, so the polling
+// is resumed after the restart (at-least-once).
+//
+// The records must be committed in the order of the ids (e.g. by one writer or with a lock):
+// a record committed after a record with a greater id is skipped.
+type Task struct {
+	fetcher IFetcher
+	cfg     TaskConfig
+	last    int64
+}
+
+var _ connect.SourceTask = (*Task)(nil)
+
+// NewTask returns the outbox task
+func NewTask(fetcher IFetcher, cfg TaskConfig) *Task {
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = _DefaultBatchSize
+	}
+	if cfg.Partition == "" {
+		cfg.Partition = _DefaultPartition
+	}
+	if cfg.WaitTimeout <= 0 {
+		cfg.WaitTimeout = _DefaultWaitTimeout
+	}
+
+	return &Task{
+		fetcher: fetcher,
+		cfg:     cfg,
+	}
+}
+
+// Start restores the id of the last delivered record
+func (t *Task) Start(_ context.Context, offsets map[string]string) error {
+
+	val, ok := offsets[t.cfg.Partition]
+	if !ok {
+		return nil
+	}
+
+	last, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "invalid outbox offset %s", val)
+	}
+	t.last = last
+
+	return nil
+}
+
+// Poll returns the next records. If the outbox is empty, the notification is awaited.
+func (t *Task) Poll(ctx context.Context) ([]connect.SourceRecord, error) {
+
+	list, err := t.fetcher.Fetch(ctx, t.last, t.cfg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 && t.cfg.Waiter != nil {
+		waitCtx, cancel := context.WithTimeout(ctx, t.cfg.WaitTimeout)
+		err := t.cfg.Waiter.Wait(waitCtx)
+		cancel()
+
+		if err != nil && waitCtx.Err() == nil {
+			return nil, errors.Wrap(err, "failed to wait for notification")
+		}
+
+		if list, err = t.fetcher.Fetch(ctx, t.last, t.cfg.BatchSize); err != nil {
+			return nil, err
+		}
+	}
+
+	retval := make([]connect.SourceRecord, 0, len(list))
+	for i := range list {
+		retval = append(retval, connect.SourceRecord{
+			Message:   recordMessage(&list[i]),
+			Partition: t.cfg.Partition,
+			Offset:    strconv.FormatInt(list[i].ID, 10),
+		})
+		t.last = list[i].ID
+	}
+
+	return retval, nil
+}
+
+// Stop does nothing
+func (t *Task) Stop() error {
+	return nil
+}
+
+func recordMessage(rec *Record) *kafka.Message {
+
+	topic := rec.Topic
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            rec.Key,
+		Value:          rec.Value,
+		Timestamp:      rec.CreatedAt,
+	}
+
+	keys := make([]string, 0, len(rec.Headers))
+	for k := range rec.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(rec.Headers[k])})
+	}
+
+	return msg
+}
@@ -0,0 +1,153 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testFetcher struct {
+	mu      sync.Mutex
+	records []Record
+	err     error
+}
+
+func (f *testFetcher) Add(list ...Record) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records = append(f.records, list...)
+}
+
+func (f *testFetcher) Fetch(_ context.Context, afterID int64, limit int) ([]Record, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	var retval []Record
+	for _, rec := range f.records {
+		if rec.ID > afterID && len(retval) < limit {
+			retval = append(retval, rec)
+		}
+	}
+
+	return retval, nil
+}
+
+type testWaiter struct {
+	ch chan struct{}
+}
+
+func (w *testWaiter) Wait(ctx context.Context) error {
+	select {
+	case <-w.ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestUnitTaskPoll(t *testing.T) {
+
+	fetcher := &testFetcher{}
+	fetcher.Add(
+		Record{ID: 1, Topic: "a", Key: []byte("k1"), Value: []byte("v1"), Headers: map[string]string{"b": "2", "a": "1"}},
+		Record{ID: 2, Topic: "b", Value: []byte("v2")},
+		Record{ID: 3, Topic: "a", Value: []byte("v3")},
+	)
+
+	task := NewTask(fetcher, TaskConfig{BatchSize: 2})
+	require.NoError(t, task.Start(context.Background(), map[string]string{}))
+
+	list, err := task.Poll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	require.Equal(t, "outbox", list[0].Partition)
+	require.Equal(t, "1", list[0].Offset)
+	require.Equal(t, "a", *list[0].Message.TopicPartition.Topic)
+	require.Equal(t, []byte("k1"), list[0].Message.Key)
+	require.Equal(t, []byte("v1"), list[0].Message.Value)
+	require.Len(t, list[0].Message.Headers, 2)
+	require.Equal(t, "a", list[0].Message.Headers[0].Key)
+	require.Equal(t, "b", list[0].Message.Headers[1].Key)
+	require.Equal(t, "2", list[1].Offset)
+
+	list, err = task.Poll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "3", list[0].Offset)
+
+	list, err = task.Poll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, list, 0)
+}
+
+func TestUnitTaskStart(t *testing.T) {
+
+	fetcher := &testFetcher{}
+	fetcher.Add(Record{ID: 1, Topic: "a"}, Record{ID: 2, Topic: "a"})
+
+	task := NewTask(fetcher, TaskConfig{})
+	require.NoError(t, task.Start(context.Background(), map[string]string{"outbox": "1"}))
+
+	list, err := task.Poll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "2", list[0].Offset)
+
+	task = NewTask(fetcher, TaskConfig{})
+	require.EqualError(t,
+		task.Start(context.Background(), map[string]string{"outbox": "x"}),
+		`invalid outbox offset x: strconv.ParseInt: parsing "x": invalid syntax`)
+}
+
+func TestUnitTaskWait(t *testing.T) {
+
+	fetcher := &testFetcher{}
+	waiter := &testWaiter{ch: make(chan struct{})}
+
+	task := NewTask(fetcher, TaskConfig{Waiter: waiter, WaitTimeout: 10 * time.Millisecond})
+	require.NoError(t, task.Start(context.Background(), nil))
+
+	// timeout of the notification
+	list, err := task.Poll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, list, 0)
+
+	task.cfg.WaitTimeout = time.Minute
+	go func() {
+		fetcher.Add(Record{ID: 1, Topic: "a"})
+		waiter.ch <- struct{}{}
+	}()
+
+	list, err = task.Poll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "1", list[0].Offset)
+}
+
+func TestUnitTaskFetchError(t *testing.T) {
+
+	fetcher := &testFetcher{err: errors.New("fetch error")}
+
+	task := NewTask(fetcher, TaskConfig{})
+	_, err := task.Poll(context.Background())
+	require.EqualError(t, err, "fetch error")
+}
+
+func TestUnitNewSQLFetcher(t *testing.T) {
+
+	_, err := NewSQLFetcher(nil, "public.outbox")
+	require.NoError(t, err)
+
+	_, err = NewSQLFetcher(nil, "outbox; DROP TABLE users")
+	require.EqualError(t, err, "invalid outbox table name: outbox; DROP TABLE users")
+}
@@ -0,0 +1,93 @@
+package pgnotify
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+)
+
+// A Listener waits for the notifications of the PostgreSQL channel (LISTEN/NOTIFY).
+// It implements outbox.IWaiter: the trigger of the outbox table sends NOTIFY
+// and the outbox task polls the table without delay.
+type Listener struct {
+	connString string
+	channel    string
+	mu         sync.Mutex
+	conn       *pgx.Conn
+}
+
+// NewListener connects to the database and subscribes to the channel
+func NewListener(ctx context.Context, connString, channel string) (*Listener, error) {
+
+	l := &Listener{
+		connString: connString,
+		channel:    channel,
+	}
+
+	if err := l.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Wait waits for the next notification. The connection is restored after the failure.
+func (l *Listener) Wait(ctx context.Context) error {
+	_, err := l.WaitForNotification(ctx)
+	return err
+}
+
+// WaitForNotification waits for the next notification and returns its payload
+func (l *Listener) WaitForNotification(ctx context.Context) (string, error) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil || l.conn.IsClosed() {
+		if err := l.connect(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	n, err := l.conn.WaitForNotification(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to wait for notification")
+	}
+
+	return n.Payload, nil
+}
+
+// Close closes the connection
+func (l *Listener) Close() error {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	err := l.conn.Close(context.Background())
+	l.conn = nil
+
+	return err
+}
+
+func (l *Listener) connect(ctx context.Context) error {
+
+	conn, err := pgx.Connect(ctx, l.connString)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect")
+	}
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		conn.Close(ctx)
+		return errors.Wrapf(err, "failed to listen %s", l.channel)
+	}
+
+	l.conn = conn
+
+	return nil
+}
@@ -0,0 +1,323 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/metric"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Actions of the bulk items
+const (
+	ActionIndex  = "index"
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// An Item is a document operation of the bulk request
+type Item struct {
+	// Action is ActionIndex by default
+	Action string
+	Index  string
+	ID     string
+	// Body is a document (a partial document for the update, it's sent as "doc"), it's empty for the delete
+	Body []byte
+}
+
+// BulkMetrics of the bulk indexer. All fields are optional.
+type BulkMetrics struct {
+	// Indexed is a count of the successful items
+	Indexed metric.ICounter
+	// Failed is a count of the failed items
+	Failed metric.ICounter
+	// Retried is a count of the retried items (429, 5xx)
+	Retried metric.ICounter
+	// Latency is a duration (seconds) of the bulk requests
+	Latency metric.IObserver
+}
+
+// BulkConfig is a configuration of the bulk indexer
+type BulkConfig struct {
+	// FlushItems is a count of the items to flush (1000 by default)
+	FlushItems int
+	// FlushBytes is a size of the items to flush (5 MB by default)
+	FlushBytes int
+	// Retries is a count of the retries of the rejected items (3 by default)
+	Retries int
+	// RetryDelay is an initial delay between the retries, it's doubled on every retry (1s by default)
+	RetryDelay time.Duration
+	Metrics    *BulkMetrics
+	// Logger is a nop logger by default
+	Logger *zap.Logger
+}
+
+// A BulkIndexer sends the items by the bulk requests.
+// The full batch is flushed by Add synchronously, so the callers are slowed down
+// while the cluster is overloaded (backpressure).
+type BulkIndexer struct {
+	client *Client
+	cfg    BulkConfig
+	items  []Item
+	size   int
+	mu     sync.Mutex
+}
+
+type bulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkResponseItem `json:"items"`
+}
+
+type bulkResponseItem struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// NewBulkIndexer returns the bulk indexer of the client
+func NewBulkIndexer(c *Client, cfg BulkConfig) *BulkIndexer {
+
+	if cfg.FlushItems <= 0 {
+		cfg.FlushItems = 1000
+	}
+	if cfg.FlushBytes <= 0 {
+		cfg.FlushBytes = 5 << 20
+	}
+	if cfg.Retries <= 0 {
+		cfg.Retries = 3
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = time.Second
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = &BulkMetrics{}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+
+	return &BulkIndexer{
+		client: c,
+		cfg:    cfg,
+	}
+}
+
+// Add adds the item to the batch and flushes the full batch
+func (b *BulkIndexer) Add(ctx context.Context, item Item) error {
+
+	if item.Action == "" {
+		item.Action = ActionIndex
+	}
+	if item.Index == "" {
+		return errors.New("item index is empty")
+	}
+	if item.Action != ActionDelete && len(item.Body) == 0 {
+		return errors.New("item body is empty")
+	}
+	if item.Action != ActionIndex && item.ID == "" {
+		return errors.Errorf("item id is empty for %s", item.Action)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.items = append(b.items, item)
+	b.size += len(item.Body)
+
+	if len(b.items) < b.cfg.FlushItems && b.size < b.cfg.FlushBytes {
+		return nil
+	}
+
+	return b.flush(ctx)
+}
+
+// Flush sends the batch. BulkError is returned if any item isn't indexed after the retries.
+// The items which aren't indexed are kept in the batch and sent again by the next flush
+// until the caller drops them by Reset (e.g. after they're sent to the dead letter queue).
+func (b *BulkIndexer) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.flush(ctx)
+}
+
+func (b *BulkIndexer) flush(ctx context.Context) error {
+
+	pending := b.items
+	b.items = nil
+	b.size = 0
+
+	var failed []ItemError
+
+	delay := b.cfg.RetryDelay
+	for attempt := 0; len(pending) > 0; attempt++ {
+		retry, itemErrors, err := b.send(ctx, pending)
+		failed = append(failed, itemErrors...)
+
+		if err != nil {
+			if statusErr, ok := err.(*StatusError); ctx.Err() != nil || (ok && !statusErr.Temporary()) {
+				b.count(b.cfg.Metrics.Failed, len(pending)+len(failed))
+				b.keep(pending, failed)
+				return errors.Wrap(err, "bulk request")
+			}
+			retry = pending
+		}
+
+		if len(retry) == 0 {
+			break
+		}
+
+		if attempt >= b.cfg.Retries {
+			for _, item := range retry {
+				failed = append(failed, ItemError{Item: item, Status: http.StatusTooManyRequests, Type: "retries_exceeded", Reason: "bulk retries exceeded"})
+			}
+			break
+		}
+
+		b.cfg.Logger.Warn("bulk items rejected, retry...", zap.Int("items", len(retry)), zap.Error(err))
+		b.count(b.cfg.Metrics.Retried, len(retry))
+
+		tm := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			tm.Stop()
+			b.count(b.cfg.Metrics.Failed, len(retry)+len(failed))
+			b.keep(retry, failed)
+			return ctx.Err()
+		case <-tm.C:
+		}
+		delay *= 2
+
+		pending = retry
+	}
+
+	if len(failed) > 0 {
+		b.count(b.cfg.Metrics.Failed, len(failed))
+		b.keep(nil, failed)
+		return &BulkError{Items: failed}
+	}
+
+	return nil
+}
+
+// keep returns the items which aren't indexed to the batch (see Reset)
+func (b *BulkIndexer) keep(items []Item, failed []ItemError) {
+
+	for i := range failed {
+		items = append(items, failed[i].Item)
+	}
+
+	b.items = append(items, b.items...)
+	b.size = 0
+	for i := range b.items {
+		b.size += len(b.items[i].Body)
+	}
+}
+
+// Reset drops the items of the batch (e.g. the items kept after the failed flush)
+func (b *BulkIndexer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.items = nil
+	b.size = 0
+}
+
+// send returns the items to retry and the failed items
+func (b *BulkIndexer) send(ctx context.Context, items []Item) (retry []Item, failed []ItemError, _ error) {
+
+	body, err := encodeBulk(items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := time.Now()
+	status, data, err := b.client.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", body)
+	if b.cfg.Metrics.Latency != nil {
+		b.cfg.Metrics.Latency.Observe(time.Since(start).Seconds())
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+	if status != http.StatusOK {
+		return nil, nil, &StatusError{Status: status, Body: string(data)}
+	}
+
+	var resp bulkResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid bulk response")
+	}
+	if len(resp.Items) != len(items) {
+		return nil, nil, errors.Errorf("invalid bulk response: %d items of %d", len(resp.Items), len(items))
+	}
+
+	var indexed int
+	for i, res := range resp.Items {
+		for _, item := range res {
+			switch {
+			case item.Status/100 == 2:
+				indexed++
+			case isTemporary(item.Status):
+				retry = append(retry, items[i])
+			default:
+				itemErr := ItemError{Item: items[i], Status: item.Status}
+				if item.Error != nil {
+					itemErr.Type = item.Error.Type
+					itemErr.Reason = item.Error.Reason
+				}
+				failed = append(failed, itemErr)
+			}
+		}
+	}
+
+	b.count(b.cfg.Metrics.Indexed, indexed)
+
+	return retry, failed, nil
+}
+
+func (b *BulkIndexer) count(c metric.ICounter, val int) {
+	if c != nil && val > 0 {
+		c.Add(float64(val))
+	}
+}
+
+// encodeBulk returns the body of the bulk request (NDJSON)
+func encodeBulk(items []Item) ([]byte, error) {
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for _, item := range items {
+		meta := map[string]string{"_index": item.Index}
+		if item.ID != "" {
+			meta["_id"] = item.ID
+		}
+
+		if err := enc.Encode(map[string]interface{}{item.Action: meta}); err != nil {
+			return nil, errors.Wrap(err, "failed to encode bulk item")
+		}
+
+		switch item.Action {
+		case ActionDelete:
+		case ActionUpdate:
+			// the partial document
+			buf.WriteString(`{"doc":`)
+			buf.Write(bytes.TrimSpace(item.Body))
+			buf.WriteString("}\n")
+		default:
+			buf.Write(bytes.TrimSpace(item.Body))
+			buf.WriteByte('\n')
+		}
+	}
+
+	return buf.Bytes(), nil
+}
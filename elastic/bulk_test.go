@@ -0,0 +1,221 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/metric/mock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// testBulkServer responds with the statuses of the items by the document ids
+type testBulkServer struct {
+	statuses map[string][]int
+	requests []string
+	mu       sync.Mutex
+}
+
+func (s *testBulkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	s.requests = append(s.requests, string(body))
+
+	if code, ok := s.statuses["*"]; ok && len(code) > 0 {
+		s.statuses["*"] = code[1:]
+		w.WriteHeader(code[0])
+		return
+	}
+
+	var items []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		var meta map[string]map[string]string
+		if err := json.Unmarshal([]byte(line), &meta); err != nil {
+			continue
+		}
+
+		for action, m := range meta {
+			if _, ok := m["_index"]; !ok {
+				continue
+			}
+
+			status := http.StatusOK
+			if list := s.statuses[m["_id"]]; len(list) > 0 {
+				status = list[0]
+				s.statuses[m["_id"]] = list[1:]
+			}
+
+			item := map[string]interface{}{"status": status}
+			if status != http.StatusOK {
+				item["error"] = map[string]string{"type": "error_type", "reason": "error reason"}
+			}
+			items = append(items, map[string]interface{}{action: item})
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": true, "items": items})
+}
+
+func newTestIndexer(t *testing.T, statuses map[string][]int, cfg BulkConfig) (*BulkIndexer, *testBulkServer, func()) {
+	t.Helper()
+
+	handler := &testBulkServer{statuses: statuses}
+	srv := httptest.NewServer(handler)
+
+	c, err := NewClient(Config{URL: srv.URL})
+	require.NoError(t, err)
+
+	cfg.RetryDelay = time.Millisecond
+	return NewBulkIndexer(c, cfg), handler, srv.Close
+}
+
+func TestEncodeBulk(t *testing.T) {
+
+	data, err := encodeBulk([]Item{
+		{Action: ActionIndex, Index: "i1", ID: "1", Body: []byte(`{"a":1}`)},
+		{Action: ActionDelete, Index: "i1", ID: "2"},
+		{Action: ActionIndex, Index: "i1", Body: []byte("{\"b\":2}\n")},
+		{Action: ActionUpdate, Index: "i1", ID: "3", Body: []byte(`{"c":3}`)},
+	})
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"index":{"_id":"1","_index":"i1"}}`+"\n"+
+			`{"a":1}`+"\n"+
+			`{"delete":{"_id":"2","_index":"i1"}}`+"\n"+
+			`{"index":{"_index":"i1"}}`+"\n"+
+			`{"b":2}`+"\n"+
+			`{"update":{"_id":"3","_index":"i1"}}`+"\n"+
+			`{"doc":{"c":3}}`+"\n",
+		string(data))
+}
+
+func TestBulkIndexer(t *testing.T) {
+
+	indexed := mock.NewCounter()
+	latency := mock.NewObserver()
+
+	b, srv, stop := newTestIndexer(t, nil, BulkConfig{
+		FlushItems: 2,
+		Metrics:    &BulkMetrics{Indexed: indexed, Latency: latency},
+	})
+	defer stop()
+
+	ctx := context.Background()
+	require.NoError(t, b.Add(ctx, Item{Index: "i1", ID: "1", Body: []byte(`{}`)}))
+	require.Empty(t, srv.requests)
+
+	// the batch is full
+	require.NoError(t, b.Add(ctx, Item{Action: ActionDelete, Index: "i1", ID: "2"}))
+	require.Len(t, srv.requests, 1)
+	require.Equal(t, uint64(2), indexed.Get())
+	require.Len(t, latency.GetSlice(), 1)
+
+	// the empty batch isn't sent
+	require.NoError(t, b.Flush(ctx))
+	require.Len(t, srv.requests, 1)
+}
+
+func TestBulkIndexerRetry(t *testing.T) {
+
+	indexed := mock.NewCounter()
+	retried := mock.NewCounter()
+	failed := mock.NewCounter()
+
+	b, srv, stop := newTestIndexer(t,
+		map[string][]int{
+			"*": {http.StatusTooManyRequests},
+			"1": {http.StatusTooManyRequests, http.StatusServiceUnavailable},
+			"2": {http.StatusBadRequest},
+		},
+		BulkConfig{Metrics: &BulkMetrics{Indexed: indexed, Retried: retried, Failed: failed}})
+	defer stop()
+
+	ctx := context.Background()
+	require.NoError(t, b.Add(ctx, Item{Index: "i1", ID: "1", Body: []byte(`{}`)}))
+	require.NoError(t, b.Add(ctx, Item{Index: "i1", ID: "2", Body: []byte(`{}`)}))
+	require.NoError(t, b.Add(ctx, Item{Index: "i1", ID: "3", Body: []byte(`{}`)}))
+
+	err := b.Flush(ctx)
+	require.True(t, errors.Is(err, ErrBulkFailed))
+	require.EqualError(t, err, "bulk request failed: 1 items failed, first: error_type: error reason")
+
+	var bulkErr *BulkError
+	require.True(t, errors.As(err, &bulkErr))
+	require.Equal(t, "2", bulkErr.Items[0].Item.ID)
+	require.Equal(t, http.StatusBadRequest, bulkErr.Items[0].Status)
+
+	// the whole request (429), the item 1 (429, 503)
+	require.Len(t, srv.requests, 4)
+	require.Equal(t, uint64(2), indexed.Get())
+	require.Equal(t, uint64(5), retried.Get())
+	require.Equal(t, uint64(1), failed.Get())
+
+	// the failed item is kept and sent again by the next flush
+	require.NoError(t, b.Flush(ctx))
+	require.Len(t, srv.requests, 5)
+	require.Contains(t, srv.requests[4], `"_id":"2"`)
+	require.Equal(t, uint64(3), indexed.Get())
+}
+
+func TestBulkIndexerReset(t *testing.T) {
+
+	b, srv, stop := newTestIndexer(t, map[string][]int{"1": {http.StatusBadRequest}}, BulkConfig{})
+	defer stop()
+
+	ctx := context.Background()
+	require.NoError(t, b.Add(ctx, Item{Index: "i1", ID: "1", Body: []byte(`{}`)}))
+	require.True(t, errors.Is(b.Flush(ctx), ErrBulkFailed))
+
+	// the failed item is dropped by the caller
+	b.Reset()
+	require.NoError(t, b.Flush(ctx))
+	require.Len(t, srv.requests, 1)
+}
+
+func TestBulkIndexerRetriesExceeded(t *testing.T) {
+
+	b, _, stop := newTestIndexer(t,
+		map[string][]int{"1": {429, 429, 429}},
+		BulkConfig{Retries: 2})
+	defer stop()
+
+	require.NoError(t, b.Add(context.Background(), Item{Index: "i1", ID: "1", Body: []byte(`{}`)}))
+
+	var bulkErr *BulkError
+	require.True(t, errors.As(b.Flush(context.Background()), &bulkErr))
+	require.Equal(t, []ItemError{{
+		Item:   Item{Action: ActionIndex, Index: "i1", ID: "1", Body: []byte(`{}`)},
+		Status: http.StatusTooManyRequests,
+		Type:   "retries_exceeded",
+		Reason: "bulk retries exceeded",
+	}}, bulkErr.Items)
+}
+
+func TestBulkIndexerStatusError(t *testing.T) {
+
+	b, srv, stop := newTestIndexer(t, map[string][]int{"*": {http.StatusBadRequest}}, BulkConfig{})
+	defer stop()
+
+	require.NoError(t, b.Add(context.Background(), Item{Index: "i1", Body: []byte(`{}`)}))
+	require.EqualError(t, b.Flush(context.Background()), "bulk request: unexpected status: 400 Bad Request: ")
+	require.Len(t, srv.requests, 1)
+}
+
+func TestBulkIndexerAddErrors(t *testing.T) {
+
+	b := NewBulkIndexer(&Client{}, BulkConfig{})
+	ctx := context.Background()
+
+	require.EqualError(t, b.Add(ctx, Item{}), "item index is empty")
+	require.EqualError(t, b.Add(ctx, Item{Index: "i1"}), "item body is empty")
+	require.EqualError(t, b.Add(ctx, Item{Action: ActionDelete, Index: "i1"}), "item id is empty for delete")
+}
@@ -0,0 +1,134 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Statuses of the cluster health
+const (
+	HealthGreen  = "green"
+	HealthYellow = "yellow"
+	HealthRed    = "red"
+)
+
+// Config is a configuration of the client
+type Config struct {
+	// URL of the cluster, e.g. http://localhost:9200
+	URL      string
+	Username string
+	Password string
+	// Client is a client with 30 seconds timeout by default
+	Client *http.Client
+}
+
+// A Health is a state of the cluster (GET /_cluster/health)
+type Health struct {
+	ClusterName         string `json:"cluster_name"`
+	Status              string `json:"status"`
+	NumberOfNodes       int    `json:"number_of_nodes"`
+	ActiveShards        int    `json:"active_shards"`
+	UnassignedShards    int    `json:"unassigned_shards"`
+	RelocatingShards    int    `json:"relocating_shards"`
+	InitializingShards  int    `json:"initializing_shards"`
+	NumberOfPendingTask int    `json:"number_of_pending_tasks"`
+}
+
+// A Client is a client of the Elasticsearch REST API
+type Client struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewClient creates a client
+func NewClient(cfg Config) (*Client, error) {
+
+	if cfg.URL == "" {
+		return nil, errors.New("elasticsearch url is empty")
+	}
+
+	c := &Client{
+		url:      strings.TrimSuffix(cfg.URL, "/"),
+		username: cfg.Username,
+		password: cfg.Password,
+		client:   cfg.Client,
+	}
+
+	if c.client == nil {
+		c.client = &http.Client{Timeout: time.Second * 30}
+	}
+
+	return c, nil
+}
+
+// Health returns the state of the cluster
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+
+	status, body, err := c.do(ctx, http.MethodGet, "/_cluster/health", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, &StatusError{Status: status, Body: string(body)}
+	}
+
+	retval := &Health{}
+	if err := json.Unmarshal(body, retval); err != nil {
+		return nil, errors.Wrap(err, "invalid cluster health")
+	}
+
+	return retval, nil
+}
+
+// Check returns an error if the cluster is unavailable or its status is red (e.g. for the health checks)
+func (c *Client) Check(ctx context.Context) error {
+
+	h, err := c.Health(ctx)
+	if err != nil {
+		return err
+	}
+
+	if h.Status == HealthRed {
+		return errors.Errorf("cluster %s status is %s", h.ClusterName, h.Status)
+	}
+
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path, contentType string, body []byte) (int, []byte, error) {
+
+	req, err := http.NewRequest(method, c.url+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return resp.StatusCode, data, nil
+}
@@ -0,0 +1,49 @@
+package elastic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientHealth(t *testing.T) {
+
+	status := HealthGreen
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		require.Equal(t, "/_cluster/health", r.URL.Path)
+		_, _ = w.Write([]byte(`{"cluster_name":"test","status":"` + status + `","number_of_nodes":3}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Config{URL: srv.URL + "/", Username: "user", Password: "pass"})
+	require.NoError(t, err)
+
+	h, err := c.Health(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &Health{ClusterName: "test", Status: HealthGreen, NumberOfNodes: 3}, h)
+	require.NoError(t, c.Check(context.Background()))
+
+	status = HealthRed
+	require.EqualError(t, c.Check(context.Background()), "cluster test status is red")
+
+	c, err = NewClient(Config{URL: srv.URL})
+	require.NoError(t, err)
+
+	_, err = c.Health(context.Background())
+	require.EqualError(t, err, "unexpected status: 401 Unauthorized: ")
+}
+
+func TestNewClientErrors(t *testing.T) {
+
+	_, err := NewClient(Config{})
+	require.EqualError(t, err, "elasticsearch url is empty")
+}
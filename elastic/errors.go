@@ -0,0 +1,58 @@
+package elastic
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// ErrBulkFailed is returned if any item of the bulk request isn't indexed
+var ErrBulkFailed = errors.New("bulk request failed")
+
+// A StatusError is an unexpected status of the response
+type StatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return "unexpected status: " + strconv.Itoa(e.Status) + " " + http.StatusText(e.Status) + ": " + e.Body
+}
+
+// Temporary reports that the request can be retried (429 or 5xx)
+func (e *StatusError) Temporary() bool {
+	return isTemporary(e.Status)
+}
+
+// An ItemError is an error of the bulk item
+type ItemError struct {
+	Item   Item
+	Status int
+	Type   string
+	Reason string
+}
+
+// A BulkError is returned if the items of the bulk request aren't indexed.
+// It's matched by ErrBulkFailed (errors.Is).
+type BulkError struct {
+	Items []ItemError
+}
+
+func (e *BulkError) Error() string {
+	retval := ErrBulkFailed.Error() + ": " + strconv.Itoa(len(e.Items)) + " items failed"
+	if len(e.Items) > 0 {
+		retval += ", first: " + e.Items[0].Type + ": " + e.Items[0].Reason
+	}
+
+	return retval
+}
+
+// Is reports that the error is ErrBulkFailed (errors.Is implementation)
+func (e *BulkError) Is(target error) bool {
+	return target == ErrBulkFailed
+}
+
+func isTemporary(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
@@ -0,0 +1,87 @@
+package elastic
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// FuncDocument returns the bulk item of the message (the message is skipped if the item is nil)
+type FuncDocument func(msg *kafka.Message) (*Item, error)
+
+// KeyDocument indexes the message value as the document with the message key as the id.
+// The message without value (tombstone) deletes the document.
+func KeyDocument(index string) FuncDocument {
+	return func(msg *kafka.Message) (*Item, error) {
+		item := &Item{Index: index, ID: string(msg.Key), Body: msg.Value}
+		if msg.Value == nil {
+			item.Action = ActionDelete
+		}
+
+		return item, nil
+	}
+}
+
+// A SinkTask writes the messages to Elasticsearch by the bulk indexer
+// (see connect.NewSinkConsumer: the batches of the consumer are flushed before the offsets commit)
+type SinkTask struct {
+	indexer  *BulkIndexer
+	document FuncDocument
+}
+
+// NewSinkTask returns the sink task
+func NewSinkTask(indexer *BulkIndexer, document FuncDocument) *SinkTask {
+	return &SinkTask{
+		indexer:  indexer,
+		document: document,
+	}
+}
+
+// Open does nothing: the task has no state of the partitions
+func (t *SinkTask) Open(context.Context, []kafka.TopicPartition) error {
+	return nil
+}
+
+// Put sends the documents of the messages by the bulk indexer: the errors of the documents
+// are returned by Put, so the batch is retried or sent to the dead letter queue by the sink
+// (the sink buffers the messages before Put)
+func (t *SinkTask) Put(ctx context.Context, msgs []*kafka.Message) error {
+
+	err := t.put(ctx, msgs)
+	if err != nil {
+		// the whole batch is retried by the sink
+		t.indexer.Reset()
+	}
+
+	return err
+}
+
+func (t *SinkTask) put(ctx context.Context, msgs []*kafka.Message) error {
+
+	for _, msg := range msgs {
+		item, err := t.document(msg)
+		if err != nil {
+			return err
+		}
+
+		if item == nil {
+			continue
+		}
+
+		if err := t.indexer.Add(ctx, *item); err != nil {
+			return err
+		}
+	}
+
+	return t.indexer.Flush(ctx)
+}
+
+// Flush sends the pending documents
+func (t *SinkTask) Flush(ctx context.Context) error {
+	return t.indexer.Flush(ctx)
+}
+
+// Close does nothing: the task has no state of the partitions
+func (t *SinkTask) Close(context.Context, []kafka.TopicPartition) error {
+	return nil
+}
@@ -0,0 +1,72 @@
+package elastic
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/connect"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyDocument(t *testing.T) {
+
+	fn := KeyDocument("users")
+
+	item, err := fn(&kafka.Message{Key: []byte("1"), Value: []byte(`{"name":"a"}`)})
+	require.NoError(t, err)
+	require.Equal(t, &Item{Index: "users", ID: "1", Body: []byte(`{"name":"a"}`)}, item)
+
+	item, err = fn(&kafka.Message{Key: []byte("1")})
+	require.NoError(t, err)
+	require.Equal(t, &Item{Action: ActionDelete, Index: "users", ID: "1"}, item)
+}
+
+func TestSinkTask(t *testing.T) {
+
+	b, srv, stop := newTestIndexer(t, nil, BulkConfig{})
+	defer stop()
+
+	var task connect.SinkTask = NewSinkTask(b, func(msg *kafka.Message) (*Item, error) {
+		if string(msg.Key) == "skip" {
+			return nil, nil
+		}
+		return KeyDocument("users")(msg)
+	})
+
+	ctx := context.Background()
+	require.NoError(t, task.Open(ctx, nil))
+	require.NoError(t, task.Put(ctx, []*kafka.Message{
+		{Key: []byte("1"), Value: []byte(`{}`)},
+		{Key: []byte("skip"), Value: []byte(`{}`)},
+		{Key: []byte("2")},
+	}))
+	require.Equal(t, []string{
+		`{"index":{"_id":"1","_index":"users"}}` + "\n" +
+			`{}` + "\n" +
+			`{"delete":{"_id":"2","_index":"users"}}` + "\n",
+	}, srv.requests)
+
+	require.NoError(t, task.Flush(ctx))
+	require.Len(t, srv.requests, 1)
+	require.NoError(t, task.Close(ctx, nil))
+}
+
+func TestSinkTaskFailed(t *testing.T) {
+
+	b, srv, stop := newTestIndexer(t, map[string][]int{"1": {http.StatusBadRequest}}, BulkConfig{})
+	defer stop()
+
+	task := NewSinkTask(b, KeyDocument("users"))
+
+	// the error of the document is returned by Put (the sink retries the batch or sends it to DLQ)
+	ctx := context.Background()
+	require.True(t, errors.Is(task.Put(ctx, []*kafka.Message{{Key: []byte("1"), Value: []byte(`{}`)}}), ErrBulkFailed))
+	require.Len(t, srv.requests, 1)
+
+	// the failed batch isn't kept by the task
+	require.NoError(t, task.Flush(ctx))
+	require.Len(t, srv.requests, 1)
+}
@@ -0,0 +1,102 @@
+// Package fault injects latency and errors into the calls of the downstream
+// dependencies (HTTP clients, Kafka producers) to test the timeouts and retries.
+// The injection must be enabled explicitly and is intended for non-prod environments.
+package fault
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/rand"
+	"github.com/pkg/errors"
+)
+
+// ErrInjected is returned by the injected failures
+var ErrInjected = errors.New("injected failure")
+
+// Config is a configuration of the injector
+type Config struct {
+	Enabled bool
+	// Latency is a delay of every call
+	Latency time.Duration
+	// Jitter is a max random addition to the latency
+	Jitter time.Duration
+	// ErrorRate is a share of the failed calls (0..1)
+	ErrorRate float64
+}
+
+// An Injector delays the calls and fails the share of them
+type Injector struct {
+	cfg    Config
+	random func() float64
+}
+
+// NewInjector creates the injector
+func NewInjector(cfg Config) *Injector {
+	return newInjector(cfg, func() float64 {
+		return float64(rand.Int63()) / math.MaxInt64
+	})
+}
+
+func newInjector(cfg Config, random func() float64) *Injector {
+	return &Injector{
+		cfg:    cfg,
+		random: random,
+	}
+}
+
+// Inject waits for the latency and returns ErrInjected with the probability of the error rate.
+// The disabled (or nil) injector does nothing.
+func (i *Injector) Inject(ctx context.Context) error {
+
+	if i == nil || !i.cfg.Enabled {
+		return nil
+	}
+
+	delay := i.cfg.Latency
+	if i.cfg.Jitter > 0 {
+		delay += time.Duration(i.random() * float64(i.cfg.Jitter))
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if i.cfg.ErrorRate > 0 && i.random() < i.cfg.ErrorRate {
+		return ErrInjected
+	}
+
+	return nil
+}
+
+// RoundTripper returns the transport of the HTTP client with the injected faults
+// (http.DefaultTransport is used if next is nil)
+func RoundTripper(next http.RoundTripper, i *Injector) http.RoundTripper {
+
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		if err := i.Inject(req.Context()); err != nil {
+			return nil, err
+		}
+
+		return next.RoundTrip(req)
+	})
+}
+
+type roundTripper func(req *http.Request) (*http.Response, error)
+
+func (fn roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
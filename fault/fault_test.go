@@ -0,0 +1,61 @@
+package fault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjector(t *testing.T) {
+
+	// disabled
+	var i *Injector
+	require.NoError(t, i.Inject(context.Background()))
+	require.NoError(t, NewInjector(Config{ErrorRate: 1}).Inject(context.Background()))
+
+	// errors
+	random := 0.3
+	i = newInjector(Config{Enabled: true, ErrorRate: 0.5}, func() float64 { return random })
+	require.Equal(t, ErrInjected, i.Inject(context.Background()))
+
+	random = 0.7
+	require.NoError(t, i.Inject(context.Background()))
+
+	// latency
+	random = 0.5
+	i = newInjector(Config{Enabled: true, Latency: 20 * time.Millisecond, Jitter: 20 * time.Millisecond}, func() float64 { return random })
+
+	start := time.Now()
+	require.NoError(t, i.Inject(context.Background()))
+	require.True(t, time.Since(start) >= 30*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, i.Inject(ctx))
+}
+
+func TestRoundTripper(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	random := 0.0
+	i := newInjector(Config{Enabled: true, ErrorRate: 0.5}, func() float64 { return random })
+	client := &http.Client{Transport: RoundTripper(nil, i)}
+
+	_, err := client.Get(srv.URL)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrInjected.Error())
+
+	random = 1
+	res, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
@@ -5,7 +5,7 @@ go 1.13
 require (
 	github.com/actgardner/gogen-avro/v7 v7.1.0
 	github.com/bluele/gcache v0.0.0-20190518031135-bc40bd653833
-	github.com/confluentinc/confluent-kafka-go v1.4.2
+	github.com/confluentinc/confluent-kafka-go v1.6.1
 	github.com/gogo/protobuf v1.3.1
 	github.com/golang-migrate/migrate/v4 v4.11.0
 	github.com/google/uuid v1.1.1
@@ -16,6 +16,7 @@ require (
 	github.com/segmentio/kafka-go v0.2.2
 	github.com/spf13/viper v1.7.0
 	github.com/stretchr/testify v1.6.0
+	go.etcd.io/bbolt v1.3.5
 	go.uber.org/zap v1.15.0
 	golang.org/x/crypto v0.0.0-20200510223506-06a226fb4e37
 	google.golang.org/grpc v1.29.1
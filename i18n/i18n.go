@@ -0,0 +1,235 @@
+// Package i18n translates the messages of the API errors to the languages of the clients
+// (the catalogs of the messages, the negotiation by the 'Accept-Language' header and the fallbacks).
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Messages are the formats of the messages by the codes (see fmt.Sprintf)
+type Messages map[string]string
+
+// An Error is the error with the message translated by the catalog (see Catalog.Translate)
+type Error struct {
+	Code string
+	Args []interface{}
+}
+
+// NewError returns the error with the code of the message and the arguments of the format
+func NewError(code string, args ...interface{}) *Error {
+	return &Error{Code: code, Args: args}
+}
+
+func (e *Error) Error() string {
+	return e.Code
+}
+
+// A Catalog is the messages of the languages. The message of the language is searched
+// in the language ('pt-br'), in the base language ('pt') and in the fallback language,
+// the code is returned if the message isn't found.
+type Catalog struct {
+	fallback string
+	messages map[string]Messages
+	mu       sync.RWMutex
+}
+
+// NewCatalog returns the empty catalog with the fallback language (e.g. 'en')
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{
+		fallback: normalize(fallback),
+		messages: make(map[string]Messages),
+	}
+}
+
+// Add adds the messages of the language, the existing messages of the codes are replaced
+func (c *Catalog) Add(lang string, messages Messages) {
+
+	lang = normalize(lang)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list, ok := c.messages[lang]
+	if !ok {
+		list = make(Messages, len(messages))
+		c.messages[lang] = list
+	}
+
+	for code, msg := range messages {
+		list[code] = msg
+	}
+}
+
+// Languages returns the sorted languages of the catalog
+func (c *Catalog) Languages() []string {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	retval := make([]string, 0, len(c.messages))
+	for lang := range c.messages {
+		retval = append(retval, lang)
+	}
+	sort.Strings(retval)
+
+	return retval
+}
+
+// Match returns the language of the catalog preferred by the client (the value of the 'Accept-Language' header)
+// or the fallback language
+func (c *Catalog) Match(acceptLanguage string) string {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, lang := range ParseAcceptLanguage(acceptLanguage) {
+		if lang == "*" {
+			break
+		}
+
+		for _, item := range chain(lang, "") {
+			if _, ok := c.messages[item]; ok {
+				return item
+			}
+		}
+	}
+
+	return c.fallback
+}
+
+// Message returns the message of the code in the language
+func (c *Catalog) Message(lang, code string, args ...interface{}) string {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, item := range chain(normalize(lang), c.fallback) {
+		if msg, ok := c.messages[item][code]; ok {
+			if len(args) == 0 {
+				return msg
+			}
+			return fmt.Sprintf(msg, args...)
+		}
+	}
+
+	return code
+}
+
+// Translate returns the message of the error (see Error) in the language,
+// the text of the other errors isn't translated
+func (c *Catalog) Translate(lang string, err error) string {
+
+	if err == nil {
+		return ""
+	}
+
+	if e, ok := errors.Cause(err).(*Error); ok {
+		return c.Message(lang, e.Code, e.Args...)
+	}
+
+	return err.Error()
+}
+
+// Middleware saves the language matched by the 'Accept-Language' header to the context of the request
+// (see FromContext) and sets the 'Content-Language' header of the response
+func (c *Catalog) Middleware(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+		lang := c.Match(req.Header.Get("Accept-Language"))
+
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language")
+
+		next.ServeHTTP(w, req.WithContext(WithLanguage(req.Context(), lang)))
+	})
+}
+
+type contextKey struct{}
+
+// WithLanguage returns the context with the language
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// FromContext returns the language of the context (empty if it isn't set)
+func FromContext(ctx context.Context) string {
+	lang, _ := ctx.Value(contextKey{}).(string)
+	return lang
+}
+
+// ParseAcceptLanguage returns the languages of the 'Accept-Language' header sorted by the weights
+// (the languages with 'q=0' are skipped)
+func ParseAcceptLanguage(header string) []string {
+
+	type item struct {
+		lang string
+		q    float64
+	}
+
+	var list []item
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+
+		lang := normalize(params[0])
+		if lang == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if val, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = val
+				}
+			}
+		}
+
+		if q > 0 {
+			list = append(list, item{lang: lang, q: q})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool { return list[i].q > list[j].q })
+
+	retval := make([]string, len(list))
+	for i := range list {
+		retval[i] = list[i].lang
+	}
+
+	return retval
+}
+
+// chain returns the language, the base languages and the fallback language ('pt-br', 'pt', 'en')
+func chain(lang, fallback string) []string {
+
+	var retval []string
+	for lang != "" {
+		retval = append(retval, lang)
+
+		pos := strings.LastIndex(lang, "-")
+		if pos < 0 {
+			break
+		}
+		lang = lang[:pos]
+	}
+
+	if fallback != "" {
+		retval = append(retval, fallback)
+	}
+
+	return retval
+}
+
+func normalize(lang string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(lang), "_", "-", -1))
+}
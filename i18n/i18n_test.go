@@ -0,0 +1,99 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCatalog() *Catalog {
+
+	c := NewCatalog("en")
+	c.Add("en", Messages{
+		"not_found": "%s not found",
+		"forbidden": "access denied",
+		"internal":  "internal error",
+	})
+	c.Add("pt", Messages{
+		"not_found": "%s não encontrado",
+		"forbidden": "acesso negado",
+	})
+	c.Add("pt_BR", Messages{
+		"forbidden": "acesso proibido",
+	})
+
+	return c
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	require.Equal(t, []string{"pt-br", "pt", "en", "*"}, ParseAcceptLanguage("en;q=0.5, pt-BR, *;q=0.1, pt;q=0.8, de;q=0"))
+	require.Equal(t, []string{"fr", "de"}, ParseAcceptLanguage("fr, de"))
+	require.Empty(t, ParseAcceptLanguage(""))
+}
+
+func TestCatalogMatch(t *testing.T) {
+
+	c := newTestCatalog()
+	require.Equal(t, []string{"en", "pt", "pt-br"}, c.Languages())
+
+	for header, expected := range map[string]string{
+		"":                   "en",
+		"pt-BR,en;q=0.5":     "pt-br",
+		"pt-PT,en;q=0.5":     "pt",
+		"de,en;q=0.5":        "en",
+		"de, fr":             "en",
+		"*":                  "en",
+		"en;q=0.1, pt;q=0.9": "pt",
+	} {
+		require.Equal(t, expected, c.Match(header), header)
+	}
+}
+
+func TestCatalogMessage(t *testing.T) {
+
+	c := newTestCatalog()
+
+	// the language, the base language and the fallback language
+	require.Equal(t, "acesso proibido", c.Message("pt-BR", "forbidden"))
+	require.Equal(t, "user não encontrado", c.Message("pt-BR", "not_found", "user"))
+	require.Equal(t, "internal error", c.Message("pt-BR", "internal"))
+	require.Equal(t, "access denied", c.Message("de", "forbidden"))
+	require.Equal(t, "unknown", c.Message("pt", "unknown"))
+
+	// the messages are replaced
+	c.Add("en", Messages{"internal": "server error"})
+	require.Equal(t, "server error", c.Message("en", "internal"))
+	require.Equal(t, "access denied", c.Message("en", "forbidden"))
+
+	err := errors.Wrap(NewError("not_found", "order"), "get order")
+	require.Equal(t, "get order: not_found", err.Error())
+	require.Equal(t, "order não encontrado", c.Translate("pt", err))
+	require.Equal(t, "failed", c.Translate("pt", errors.New("failed")))
+	require.Equal(t, "", c.Translate("pt", nil))
+}
+
+func TestCatalogMiddleware(t *testing.T) {
+
+	c := newTestCatalog()
+
+	var lang string
+	handler := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lang = FromContext(req.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "pt-PT, en;q=0.5")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, "pt", lang)
+	require.Equal(t, "pt", w.Header().Get("Content-Language"))
+	require.Equal(t, "Accept-Language", w.Header().Get("Vary"))
+
+	require.Equal(t, "", FromContext(context.Background()))
+}
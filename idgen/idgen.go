@@ -0,0 +1,98 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// IDGenerator returns the unique identifiers (e.g. the client.id of the kafka consumers)
+type IDGenerator interface {
+	NewID() (string, error)
+}
+
+// Func is an adapter of the function to IDGenerator
+type Func func() (string, error)
+
+func (fn Func) NewID() (string, error) {
+	return fn()
+}
+
+var (
+	// UUIDv4 generates the random UUIDs
+	UUIDv4 IDGenerator = Func(newUUIDv4)
+	// UUIDv7 generates the UUIDs ordered by the creation time (milliseconds)
+	UUIDv7 IDGenerator = Func(func() (string, error) { return newUUIDv7(time.Now(), rand.Reader) })
+	// ULID generates the lexicographically sortable identifiers (https://github.com/ulid/spec)
+	ULID IDGenerator = Func(func() (string, error) { return newULID(time.Now(), rand.Reader) })
+)
+
+// Default is the generator of the library identifiers
+var Default = UUIDv4
+
+func newUUIDv4() (string, error) {
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate uuid")
+	}
+
+	return id.String(), nil
+}
+
+// newUUIDv7 returns the UUID version 7: the unix time in milliseconds (48 bits), the version,
+// the random bits and the variant (draft-ietf-uuidrev-rfc4122bis)
+func newUUIDv7(now time.Time, random io.Reader) (string, error) {
+
+	var id uuid.UUID
+	if _, err := io.ReadFull(random, id[6:]); err != nil {
+		return "", errors.Wrap(err, "failed to generate uuid")
+	}
+
+	putMilliseconds(id[:6], now)
+	id[6] = 0x70 | id[6]&0x0f // version 7
+	id[8] = 0x80 | id[8]&0x3f // variant RFC 4122
+
+	return id.String(), nil
+}
+
+// crockford is the alphabet of the ULID (the Crockford's base32)
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns the ULID: the unix time in milliseconds (48 bits) and the random bits (80 bits)
+// encoded by the Crockford's base32 (26 characters)
+func newULID(now time.Time, random io.Reader) (string, error) {
+
+	var id [16]byte
+	if _, err := io.ReadFull(random, id[6:]); err != nil {
+		return "", errors.Wrap(err, "failed to generate ulid")
+	}
+
+	putMilliseconds(id[:6], now)
+
+	// 128 bits are encoded by 26 characters of 5 bits (the first character has 3 bits)
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+
+	var retval [26]byte
+	for i := len(retval) - 1; i >= 0; i-- {
+		retval[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(retval[:]), nil
+}
+
+func putMilliseconds(dst []byte, now time.Time) {
+
+	ms := uint64(now.UnixNano() / int64(time.Millisecond))
+	for i := 5; i >= 0; i-- {
+		dst[i] = byte(ms)
+		ms >>= 8
+	}
+}
@@ -0,0 +1,71 @@
+package idgen
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUUIDv4(t *testing.T) {
+
+	val, err := UUIDv4.NewID()
+	require.NoError(t, err)
+
+	id, err := uuid.Parse(val)
+	require.NoError(t, err)
+	require.Equal(t, uuid.Version(4), id.Version())
+	require.Equal(t, uuid.RFC4122, id.Variant())
+}
+
+func TestUUIDv7(t *testing.T) {
+
+	now := time.Unix(1600000000, 123000000)
+
+	val, err := newUUIDv7(now, bytes.NewReader(bytes.Repeat([]byte{0xff}, 10)))
+	require.NoError(t, err)
+	require.Equal(t, "0174876e-807b-7fff-bfff-ffffffffffff", val)
+
+	id, err := uuid.Parse(val)
+	require.NoError(t, err)
+	require.Equal(t, uuid.Version(7), id.Version())
+	require.Equal(t, uuid.RFC4122, id.Variant())
+
+	// ordered by the time
+	next, err := newUUIDv7(now.Add(time.Millisecond), bytes.NewReader(make([]byte, 10)))
+	require.NoError(t, err)
+	require.True(t, val < next)
+
+	_, err = newUUIDv7(now, bytes.NewReader(nil))
+	require.Error(t, err)
+}
+
+func TestULID(t *testing.T) {
+
+	// the example of the specification: the time part of 01ARYZ6S41
+	val, err := newULID(time.Unix(0, 1469918176385*int64(time.Millisecond)), bytes.NewReader(make([]byte, 10)))
+	require.NoError(t, err)
+	require.Equal(t, "01ARYZ6S410000000000000000", val)
+
+	val, err = newULID(time.Unix(0, 0), bytes.NewReader(bytes.Repeat([]byte{0xff}, 10)))
+	require.NoError(t, err)
+	require.Equal(t, "0000000000ZZZZZZZZZZZZZZZZ", val)
+
+	// ordered by the time
+	next, err := newULID(time.Unix(0, int64(time.Millisecond)), bytes.NewReader(make([]byte, 10)))
+	require.NoError(t, err)
+	require.True(t, val < next)
+
+	val, err = ULID.NewID()
+	require.NoError(t, err)
+	require.Len(t, val, 26)
+}
+
+func TestFunc(t *testing.T) {
+
+	_, err := Func(func() (string, error) { return "", errors.New("failed") }).NewID()
+	require.EqualError(t, err, "failed")
+}
@@ -0,0 +1,118 @@
+package connect
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// IOffsetStore keeps the offsets (positions) of the source partitions (see Source)
+type IOffsetStore interface {
+	Load(ctx context.Context) (map[string]string, error)
+	// Save merges the offsets into the stored offsets
+	Save(ctx context.Context, offsets map[string]string) error
+}
+
+// MemoryOffsetStore keeps the offsets in the memory (the offsets are lost after the restart)
+type MemoryOffsetStore struct {
+	offsets map[string]string
+	mu      sync.Mutex
+}
+
+// NewMemoryOffsetStore returns the empty store
+func NewMemoryOffsetStore() *MemoryOffsetStore {
+	return &MemoryOffsetStore{
+		offsets: make(map[string]string),
+	}
+}
+
+func (s *MemoryOffsetStore) Load(context.Context) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	retval := make(map[string]string, len(s.offsets))
+	for k, v := range s.offsets {
+		retval[k] = v
+	}
+
+	return retval, nil
+}
+
+func (s *MemoryOffsetStore) Save(_ context.Context, offsets map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, v := range offsets {
+		s.offsets[k] = v
+	}
+
+	return nil
+}
+
+// FileOffsetStore keeps the offsets in the JSON file
+type FileOffsetStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileOffsetStore returns the store of the file (the file is created by the first save)
+func NewFileOffsetStore(path string) *FileOffsetStore {
+	return &FileOffsetStore{path: path}
+}
+
+func (s *FileOffsetStore) Load(context.Context) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load()
+}
+
+func (s *FileOffsetStore) Save(_ context.Context, offsets map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	for k, v := range offsets {
+		stored[k] = v
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode offsets")
+	}
+
+	// write and rename: the file isn't corrupted by a crash
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write offsets")
+	}
+
+	return errors.Wrap(os.Rename(tmp, s.path), "failed to write offsets")
+}
+
+func (s *FileOffsetStore) load() (map[string]string, error) {
+
+	retval := make(map[string]string)
+
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return retval, nil
+		}
+		return nil, errors.Wrap(err, "failed to read offsets")
+	}
+
+	if err := json.Unmarshal(data, &retval); err != nil {
+		return nil, errors.Wrap(err, "failed to decode offsets")
+	}
+
+	return retval, nil
+}
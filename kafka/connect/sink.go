@@ -0,0 +1,297 @@
+package connect
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	_DefaultBatchSize  = 100
+	_DefaultRetryDelay = time.Second
+)
+
+// Headers of the messages of the dead letter queue
+const (
+	HeaderDLQError     = "dlq-error"
+	HeaderDLQTopic     = "dlq-topic"
+	HeaderDLQPartition = "dlq-partition"
+	HeaderDLQOffset    = "dlq-offset"
+)
+
+// A SinkTask writes the messages to the external system (database, search index, etc.)
+type SinkTask interface {
+	// Open is called after the partitions assignment
+	Open(ctx context.Context, partitions []kafka.TopicPartition) error
+	// Put writes the messages (the writes can be buffered until Flush)
+	Put(ctx context.Context, msgs []*kafka.Message) error
+	// Flush completes the writes. It's called before the offsets commit.
+	Flush(ctx context.Context) error
+	// Close is called after the partitions revoke
+	Close(ctx context.Context, partitions []kafka.TopicPartition) error
+}
+
+// IOffsetSink is implemented by the task which stores the offsets of the messages with the data
+// (in the same transaction of Flush). The messages before the stored offsets are skipped,
+// so the messages redelivered after a failure aren't written twice (exactly-once).
+type IOffsetSink interface {
+	// Offsets returns the next offsets of the partitions (kafka.OffsetInvalid if the partition is unknown)
+	Offsets(ctx context.Context, partitions []kafka.TopicPartition) ([]kafka.TopicPartition, error)
+}
+
+// SinkConfig is a configuration of the sink
+type SinkConfig struct {
+	// BatchSize is a max count of the messages of Put (100 by default)
+	BatchSize int
+	// Retries is a count of the retries of Put and Flush
+	Retries int
+	// RetryDelay is a delay between the retries (1 second by default)
+	RetryDelay time.Duration
+	// DLQ receives the messages failed by Put after the retries (optional), the offsets of the messages
+	// are committed after the delivery reports (e.g. producer.SyncProducer).
+	// The consumer is stopped by the failed messages without DLQ.
+	DLQ      IProducer
+	DLQTopic string
+}
+
+// A Sink runs the task in the consumer: the messages are passed to the task in batches and
+// the task is flushed before the offsets commit (see NewSinkConsumer)
+type Sink struct {
+	task    SinkTask
+	cfg     SinkConfig
+	buffer  []*kafka.Message
+	offsets map[string]kafka.Offset
+	openErr error
+	mu      sync.Mutex
+}
+
+// NewSink returns the sink of the task
+func NewSink(task SinkTask, cfg SinkConfig) (*Sink, error) {
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = _DefaultBatchSize
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = _DefaultRetryDelay
+	}
+	if cfg.DLQ != nil && cfg.DLQTopic == "" {
+		return nil, errors.New("dlq topic is empty")
+	}
+
+	return &Sink{
+		task:    task,
+		cfg:     cfg,
+		offsets: make(map[string]kafka.Offset),
+	}, nil
+}
+
+// NewSinkConsumer returns the consumer running the sink.
+// The callbacks of the processing, rebalance, revoke and pre-commit are replaced by the sink.
+func NewSinkConsumer(task SinkTask, cfg SinkConfig, consumerCfg *consumer.Config, opts ...consumer.Option) (*consumer.Consumer, error) {
+
+	s, err := NewSink(task, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append(opts, consumer.WithConfig(func(cfg *consumer.Config) {
+		cfg.OnProcess = s.Process
+		cfg.OnRebalance = s.Open
+		cfg.OnRevoke = s.Close
+		cfg.OnPreCommit = s.PreCommit
+	}))
+
+	return consumer.NewWithOptions(consumerCfg, opts...)
+}
+
+// Open opens the task of the assigned partitions (see consumer.Config.OnRebalance)
+func (s *Sink) Open(ctx context.Context, logger *zap.Logger, partitions []kafka.TopicPartition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.openErr = s.open(ctx, partitions)
+	if s.openErr != nil {
+		logger.Error("failed to open sink task", zap.Error(s.openErr))
+	}
+}
+
+func (s *Sink) open(ctx context.Context, partitions []kafka.TopicPartition) error {
+
+	if err := s.task.Open(ctx, partitions); err != nil {
+		return errors.Wrap(err, "open sink task")
+	}
+
+	offsetSink, ok := s.task.(IOffsetSink)
+	if !ok {
+		return nil
+	}
+
+	list, err := offsetSink.Offsets(ctx, partitions)
+	if err != nil {
+		return errors.Wrap(err, "get sink offsets")
+	}
+
+	for _, tp := range list {
+		if tp.Offset >= 0 {
+			s.offsets[partitionKey(tp)] = tp.Offset
+		}
+	}
+
+	return nil
+}
+
+// Close closes the task of the revoked partitions (see consumer.Config.OnRevoke).
+// The task is flushed before by the offsets commit of the consumer. The consumer revokes the partitions
+// even if PreCommit is failed: the offsets aren't committed and the buffered messages of the partitions
+// are dropped, so they're consumed again by the new owner of the partitions.
+func (s *Sink) Close(ctx context.Context, logger *zap.Logger, partitions []kafka.TopicPartition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revoked := make(map[string]struct{}, len(partitions))
+	for _, tp := range partitions {
+		delete(s.offsets, partitionKey(tp))
+		revoked[partitionKey(tp)] = struct{}{}
+	}
+
+	buffer := s.buffer[:0]
+	for _, msg := range s.buffer {
+		if _, ok := revoked[partitionKey(msg.TopicPartition)]; !ok {
+			buffer = append(buffer, msg)
+		}
+	}
+	s.buffer = buffer
+
+	if err := s.task.Close(ctx, partitions); err != nil {
+		logger.Error("failed to close sink task", zap.Error(err))
+	}
+}
+
+// Process adds the message to the batch (see consumer.Config.OnProcess)
+func (s *Sink) Process(ctx context.Context, logger *zap.Logger, msg *kafka.Message, _ consumer.ISleeper, _ consumer.ICommitter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.openErr != nil {
+		return s.openErr
+	}
+
+	if next, ok := s.offsets[partitionKey(msg.TopicPartition)]; ok && msg.TopicPartition.Offset < next {
+		logger.Debug("skipped message written by sink")
+		return nil
+	}
+
+	s.buffer = append(s.buffer, msg)
+	if len(s.buffer) < s.cfg.BatchSize {
+		return nil
+	}
+
+	return s.put(ctx, logger)
+}
+
+// PreCommit writes the batch and flushes the task (see consumer.Config.OnPreCommit)
+func (s *Sink) PreCommit(ctx context.Context, logger *zap.Logger, _ []kafka.TopicPartition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.put(ctx, logger); err != nil {
+		return err
+	}
+
+	return s.retry(ctx, logger, "flush", s.task.Flush)
+}
+
+func (s *Sink) put(ctx context.Context, logger *zap.Logger) error {
+
+	if len(s.buffer) == 0 {
+		return nil
+	}
+
+	batch := s.buffer
+	err := s.retry(ctx, logger, "put", func(ctx context.Context) error {
+		return s.task.Put(ctx, batch)
+	})
+
+	if err != nil {
+		if s.cfg.DLQ == nil {
+			return err
+		}
+
+		msgs := make([]*kafka.Message, len(batch))
+		for i, msg := range batch {
+			msgs[i] = dlqMessage(msg, s.cfg.DLQTopic, err)
+		}
+
+		// the batch is kept until the messages are delivered to the dlq
+		if _, errDLQ := s.cfg.DLQ.ProduceSync(ctx, msgs...); errDLQ != nil {
+			return errors.Wrap(errDLQ, "produce to dlq")
+		}
+
+		logger.Warn("messages sent to dlq", zap.Int("count", len(batch)), zap.Error(err))
+	}
+
+	s.buffer = nil
+	return nil
+}
+
+func (s *Sink) retry(ctx context.Context, logger *zap.Logger, operation string, fn func(ctx context.Context) error) error {
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+
+		if attempt >= s.cfg.Retries {
+			return errors.Wrap(err, operation)
+		}
+
+		logger.Warn("sink task failed, retry", zap.String("sink operation", operation), zap.Int("attempt", attempt+1), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(err, operation)
+		case <-time.After(s.cfg.RetryDelay):
+		}
+	}
+}
+
+// dlqMessage returns the copy of the message to the dead letter queue with the error and the source in the headers
+func dlqMessage(msg *kafka.Message, topic string, err error) *kafka.Message {
+
+	var sourceTopic string
+	if msg.TopicPartition.Topic != nil {
+		sourceTopic = *msg.TopicPartition.Topic
+	}
+
+	headers := append(make([]kafka.Header, 0, len(msg.Headers)+4), msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderDLQError, Value: []byte(err.Error())},
+		kafka.Header{Key: HeaderDLQTopic, Value: []byte(sourceTopic)},
+		kafka.Header{Key: HeaderDLQPartition, Value: []byte(strconv.Itoa(int(msg.TopicPartition.Partition)))},
+		kafka.Header{Key: HeaderDLQOffset, Value: []byte(strconv.FormatInt(int64(msg.TopicPartition.Offset), 10))},
+	)
+
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        headers,
+		Timestamp:      msg.Timestamp,
+	}
+}
+
+func partitionKey(tp kafka.TopicPartition) string {
+	var topic string
+	if tp.Topic != nil {
+		topic = *tp.Topic
+	}
+
+	return topic + ":" + strconv.Itoa(int(tp.Partition))
+}
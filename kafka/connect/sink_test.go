@@ -0,0 +1,266 @@
+package connect
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/dialogs/dialog-go-lib/kafka/producer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testTask struct {
+	calls   []string
+	written []string
+	putErr  error
+	offsets []kafka.TopicPartition
+}
+
+func (t *testTask) Open(_ context.Context, partitions []kafka.TopicPartition) error {
+	t.calls = append(t.calls, "open")
+	return nil
+}
+
+func (t *testTask) Put(_ context.Context, msgs []*kafka.Message) error {
+	t.calls = append(t.calls, "put")
+	if t.putErr != nil {
+		return t.putErr
+	}
+
+	for _, msg := range msgs {
+		t.written = append(t.written, string(msg.Value))
+	}
+	return nil
+}
+
+func (t *testTask) Flush(context.Context) error {
+	t.calls = append(t.calls, "flush")
+	return nil
+}
+
+func (t *testTask) Close(context.Context, []kafka.TopicPartition) error {
+	t.calls = append(t.calls, "close")
+	return nil
+}
+
+type testOffsetTask struct {
+	testTask
+}
+
+func (t *testOffsetTask) Offsets(context.Context, []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+	return t.offsets, nil
+}
+
+type testDLQ struct {
+	err  error
+	msgs []*kafka.Message
+}
+
+func (p *testDLQ) ProduceSync(_ context.Context, msgs ...*kafka.Message) ([]producer.Result, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	p.msgs = append(p.msgs, msgs...)
+	return make([]producer.Result, len(msgs)), nil
+}
+
+var testTopic = "in"
+
+func newMessage(offset int, value string) *kafka.Message {
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &testTopic, Partition: 1, Offset: kafka.Offset(offset)},
+		Value:          []byte(value),
+	}
+}
+
+func TestSink(t *testing.T) {
+
+	task := &testTask{}
+	s, err := NewSink(task, SinkConfig{BatchSize: 2})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	logger := zap.NewNop()
+	partitions := []kafka.TopicPartition{{Topic: &testTopic, Partition: 1}}
+
+	s.Open(ctx, logger, partitions)
+	require.NoError(t, s.Process(ctx, logger, newMessage(0, "a"), nil, nil))
+	require.Equal(t, []string{"open"}, task.calls)
+
+	// the batch is full
+	require.NoError(t, s.Process(ctx, logger, newMessage(1, "b"), nil, nil))
+	require.NoError(t, s.Process(ctx, logger, newMessage(2, "c"), nil, nil))
+	require.Equal(t, []string{"open", "put"}, task.calls)
+	require.Equal(t, []string{"a", "b"}, task.written)
+
+	require.NoError(t, s.PreCommit(ctx, logger, nil))
+	require.Equal(t, []string{"open", "put", "put", "flush"}, task.calls)
+	require.Equal(t, []string{"a", "b", "c"}, task.written)
+
+	// the empty batch isn't written
+	require.NoError(t, s.PreCommit(ctx, logger, nil))
+	require.Equal(t, []string{"open", "put", "put", "flush", "flush"}, task.calls)
+
+	s.Close(ctx, logger, partitions)
+	require.Equal(t, "close", task.calls[len(task.calls)-1])
+}
+
+func TestSinkCloseDropsBuffer(t *testing.T) {
+
+	task := &testTask{}
+	s, err := NewSink(task, SinkConfig{BatchSize: 10})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	logger := zap.NewNop()
+	other := "other"
+
+	require.NoError(t, s.Process(ctx, logger, newMessage(0, "a"), nil, nil))
+	require.NoError(t, s.Process(ctx, logger, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &other, Partition: 1},
+		Value:          []byte("b"),
+	}, nil, nil))
+
+	// the messages of the revoked partition aren't written
+	s.Close(ctx, logger, []kafka.TopicPartition{{Topic: &testTopic, Partition: 1}})
+	require.NoError(t, s.PreCommit(ctx, logger, nil))
+	require.Equal(t, []string{"b"}, task.written)
+}
+
+func TestSinkRevokeAfterFailedPreCommit(t *testing.T) {
+
+	task := &testTask{putErr: errors.New("put failed")}
+	s, err := NewSink(task, SinkConfig{BatchSize: 10})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	logger := zap.NewNop()
+	partitions := []kafka.TopicPartition{{Topic: &testTopic, Partition: 1}}
+
+	s.Open(ctx, logger, partitions)
+	require.NoError(t, s.Process(ctx, logger, newMessage(0, "a"), nil, nil))
+	require.Error(t, s.PreCommit(ctx, logger, nil))
+
+	// the partitions are revoked without the commit: the messages are redelivered after the assignment
+	s.Close(ctx, logger, partitions)
+	task.putErr = nil
+
+	s.Open(ctx, logger, partitions)
+	require.NoError(t, s.Process(ctx, logger, newMessage(0, "a"), nil, nil))
+	require.NoError(t, s.PreCommit(ctx, logger, nil))
+	require.Equal(t, []string{"a"}, task.written)
+}
+
+func TestSinkOffsets(t *testing.T) {
+
+	task := &testOffsetTask{}
+	task.offsets = []kafka.TopicPartition{
+		{Topic: &testTopic, Partition: 1, Offset: 2},
+		{Topic: &testTopic, Partition: 2, Offset: kafka.OffsetInvalid},
+	}
+
+	s, err := NewSink(task, SinkConfig{BatchSize: 1})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	s.Open(ctx, logger, []kafka.TopicPartition{{Topic: &testTopic, Partition: 1}, {Topic: &testTopic, Partition: 2}})
+	for i, val := range []string{"a", "b", "c"} {
+		require.NoError(t, s.Process(ctx, logger, newMessage(i, val), nil, nil))
+	}
+
+	// the messages written before the restart are skipped
+	require.Equal(t, []string{"c"}, task.written)
+}
+
+func TestSinkRetryAndDLQ(t *testing.T) {
+
+	task := &testTask{putErr: errors.New("unavailable")}
+	dlq := &testDLQ{}
+
+	s, err := NewSink(task, SinkConfig{
+		BatchSize:  10,
+		Retries:    2,
+		RetryDelay: time.Millisecond,
+		DLQ:        dlq,
+		DLQTopic:   "dlq",
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	require.NoError(t, s.Process(ctx, logger, newMessage(5, "a"), nil, nil))
+	require.NoError(t, s.PreCommit(ctx, logger, nil))
+	require.Equal(t, []string{"put", "put", "put", "flush"}, task.calls)
+
+	require.Len(t, dlq.msgs, 1)
+	msg := dlq.msgs[0]
+	require.Equal(t, "dlq", *msg.TopicPartition.Topic)
+	require.Equal(t, []byte("a"), msg.Value)
+	require.Equal(t, []kafka.Header{
+		{Key: HeaderDLQError, Value: []byte("put: unavailable")},
+		{Key: HeaderDLQTopic, Value: []byte("in")},
+		{Key: HeaderDLQPartition, Value: []byte("1")},
+		{Key: HeaderDLQOffset, Value: []byte("5")},
+	}, msg.Headers)
+}
+
+func TestSinkDLQFailed(t *testing.T) {
+
+	task := &testTask{putErr: errors.New("unavailable")}
+	dlq := &testDLQ{err: errors.New("not delivered")}
+
+	s, err := NewSink(task, SinkConfig{DLQ: dlq, DLQTopic: "dlq"})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	// the offsets aren't committed until the messages are delivered to the dlq
+	require.NoError(t, s.Process(ctx, logger, newMessage(0, "a"), nil, nil))
+	require.EqualError(t, s.PreCommit(ctx, logger, nil), "produce to dlq: not delivered")
+
+	dlq.err = nil
+	require.NoError(t, s.PreCommit(ctx, logger, nil))
+	require.Len(t, dlq.msgs, 1)
+}
+
+func TestSinkFailed(t *testing.T) {
+
+	task := &testTask{putErr: errors.New("unavailable")}
+	s, err := NewSink(task, SinkConfig{BatchSize: 1})
+	require.NoError(t, err)
+
+	err = s.Process(context.Background(), zap.NewNop(), newMessage(0, "a"), nil, nil)
+	require.EqualError(t, err, "put: unavailable")
+}
+
+func TestNewSinkErrors(t *testing.T) {
+
+	_, err := NewSink(&testTask{}, SinkConfig{DLQ: &testDLQ{}})
+	require.EqualError(t, err, "dlq topic is empty")
+}
+
+func TestNewSinkConsumer(t *testing.T) {
+
+	cfg := &consumer.Config{
+		OnError: func(context.Context, *zap.Logger, error) {},
+		Topics:  []string{"in"},
+		ConfigMap: &kafka.ConfigMap{
+			"group.id":          "test",
+			"bootstrap.servers": "localhost:1",
+		},
+	}
+
+	c, err := NewSinkConsumer(&testTask{}, SinkConfig{}, cfg)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	require.Nil(t, cfg.OnPreCommit)
+}
@@ -0,0 +1,204 @@
+package connect
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/producer"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	_DefaultPollInterval = time.Second
+)
+
+// ErrSourceAlreadyStarted is returned if the source is started twice
+var ErrSourceAlreadyStarted = errors.New("source already started")
+
+// IProducer produces the messages and waits for the delivery reports (see producer.SyncProducer)
+type IProducer = producer.ISyncProducer
+
+// A SourceRecord is a message of the external system with its position
+type SourceRecord struct {
+	Message *kafka.Message
+	// Partition of the source (e.g. a table name or an API endpoint)
+	Partition string
+	// Offset is a position in the source partition to continue polling after the record
+	Offset string
+}
+
+// A SourceTask reads the records of the external system (database, API, etc.)
+type SourceTask interface {
+	// Start is called with the stored offsets of the source partitions
+	Start(ctx context.Context, offsets map[string]string) error
+	// Poll returns the next records (the source waits for the poll interval if the list is empty)
+	Poll(ctx context.Context) ([]SourceRecord, error)
+	Stop() error
+}
+
+// SourceConfig is a configuration of the source
+type SourceConfig struct {
+	Producer IProducer
+	// Store is a store of the source offsets (memory store by default)
+	Store IOffsetStore
+	// PollInterval is a delay of the poll after the empty list or the error (1 second by default)
+	PollInterval time.Duration
+	// Logger is a nop logger by default
+	Logger *zap.Logger
+}
+
+// A Source polls the task and produces the records to Kafka.
+// The offsets of the records are saved after the delivery (at-least-once),
+// the failed records are produced again.
+type Source struct {
+	task   SourceTask
+	cfg    SourceConfig
+	logger *zap.Logger
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewSource returns the source of the task
+func NewSource(task SourceTask, cfg SourceConfig) (*Source, error) {
+
+	if cfg.Producer == nil {
+		return nil, errors.New("source producer is nil")
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryOffsetStore()
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = _DefaultPollInterval
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Source{
+		task:   task,
+		cfg:    cfg,
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Start polls the task until the source is stopped
+func (s *Source) Start() error {
+
+	err := ErrSourceAlreadyStarted
+	s.once.Do(func() {
+		defer close(s.done)
+		err = s.run(s.ctx)
+	})
+
+	return err
+}
+
+// StopContext stops the source and waits for the completion of the current batch
+func (s *Source) StopContext(ctx context.Context) error {
+	s.cancel()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "failed to wait for source closing")
+	}
+}
+
+func (s *Source) run(ctx context.Context) error {
+
+	offsets, err := s.cfg.Store.Load(ctx)
+	if err != nil {
+		return errors.Wrap(err, "load source offsets")
+	}
+
+	if err := s.task.Start(ctx, offsets); err != nil {
+		return errors.Wrap(err, "start source task")
+	}
+	defer func() {
+		if err := s.task.Stop(); err != nil {
+			s.logger.Error("failed to stop source task", zap.Error(err))
+		}
+	}()
+
+	s.logger.Info("start")
+
+	for ctx.Err() == nil {
+		records, err := s.task.Poll(ctx)
+		if err != nil {
+			s.logger.Error("failed to poll source task", zap.Error(err))
+		}
+
+		if err != nil || len(records) == 0 {
+			s.wait(ctx)
+			continue
+		}
+
+		s.send(ctx, records)
+	}
+
+	s.logger.Info("stop")
+	return nil
+}
+
+// send produces the records until the delivery or the source stop and saves the offsets
+func (s *Source) send(ctx context.Context, records []SourceRecord) {
+
+	msgs := make([]*kafka.Message, len(records))
+	offsets := make(map[string]string)
+	for i := range records {
+		msgs[i] = records[i].Message
+		offsets[records[i].Partition] = records[i].Offset
+	}
+
+	for len(msgs) > 0 {
+		results, err := s.cfg.Producer.ProduceSync(ctx, msgs...)
+		if err == nil {
+			break
+		}
+
+		s.logger.Error("failed to produce source records", zap.Error(err))
+
+		// the messages before the first failed one aren't produced again, the delivered messages
+		// after it are produced again to keep the order of the partitions (at least once)
+		if len(results) == len(msgs) {
+			for i := range results {
+				if results[i].Err != nil {
+					msgs = msgs[i:]
+					break
+				}
+			}
+		}
+
+		if !s.wait(ctx) {
+			return
+		}
+	}
+
+	// the offsets are saved regardless of the source stop: the records are delivered
+	if err := s.cfg.Store.Save(context.Background(), offsets); err != nil {
+		s.logger.Error("failed to save source offsets", zap.Error(err))
+	}
+}
+
+// wait returns false if the source is stopped
+func (s *Source) wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(s.cfg.PollInterval):
+		return true
+	}
+}
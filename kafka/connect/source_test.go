@@ -0,0 +1,158 @@
+package connect
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/producer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type testSourceTask struct {
+	started map[string]string
+	next    int
+	stopped bool
+	mu      sync.Mutex
+}
+
+func (t *testSourceTask) Start(_ context.Context, offsets map[string]string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.started = offsets
+	if val, ok := offsets["table"]; ok {
+		t.next, _ = strconv.Atoi(val)
+	}
+	return nil
+}
+
+func (t *testSourceTask) Poll(context.Context) ([]SourceRecord, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.next >= 3 {
+		return nil, nil
+	}
+
+	// the records are polled by one batch
+	topic := "out"
+	var retval []SourceRecord
+	for t.next < 3 {
+		t.next++
+		retval = append(retval, SourceRecord{
+			Message: &kafka.Message{
+				TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+				Value:          []byte(strconv.Itoa(t.next)),
+			},
+			Partition: "table",
+			Offset:    strconv.Itoa(t.next),
+		})
+	}
+	return retval, nil
+}
+
+func (t *testSourceTask) Stop() error {
+	t.mu.Lock()
+	t.stopped = true
+	t.mu.Unlock()
+	return nil
+}
+
+type testSourceProducer struct {
+	fails  int
+	values []string
+	mu     sync.Mutex
+}
+
+func (p *testSourceProducer) ProduceSync(_ context.Context, msgs ...*kafka.Message) ([]producer.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// the first message fails, the next ones are delivered
+	results := make([]producer.Result, len(msgs))
+	for i, msg := range msgs {
+		if i == 0 && p.fails > 0 {
+			p.fails--
+			results[i].Err = errors.New("failed")
+			continue
+		}
+		p.values = append(p.values, string(msg.Value))
+	}
+
+	if results[0].Err != nil {
+		return results, errors.New("failed")
+	}
+	return results, nil
+}
+
+func (p *testSourceProducer) Values() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string(nil), p.values...)
+}
+
+func TestSource(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "source")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := NewFileOffsetStore(filepath.Join(dir, "offsets.json"))
+	require.NoError(t, store.Save(context.Background(), map[string]string{"table": "1"}))
+
+	task := &testSourceTask{}
+	p := &testSourceProducer{fails: 1}
+
+	s, err := NewSource(task, SourceConfig{
+		Producer:     p,
+		Store:        store,
+		PollInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	chErr := make(chan error, 1)
+	go func() { chErr <- s.Start() }()
+
+	// the delivered message after the failed one is produced again to keep the order
+	require.Eventually(t, func() bool { return len(p.Values()) == 3 }, time.Second, time.Millisecond)
+	require.Equal(t, []string{"3", "2", "3"}, p.Values())
+
+	require.NoError(t, s.StopContext(context.Background()))
+	require.NoError(t, <-chErr)
+	require.Equal(t, ErrSourceAlreadyStarted, s.Start())
+
+	task.mu.Lock()
+	require.Equal(t, map[string]string{"table": "1"}, task.started)
+	require.True(t, task.stopped)
+	task.mu.Unlock()
+
+	offsets, err := store.Load(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"table": "3"}, offsets)
+}
+
+func TestMemoryOffsetStore(t *testing.T) {
+
+	s := NewMemoryOffsetStore()
+	require.NoError(t, s.Save(context.Background(), map[string]string{"a": "1", "b": "1"}))
+	require.NoError(t, s.Save(context.Background(), map[string]string{"b": "2"}))
+
+	offsets, err := s.Load(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"a": "1", "b": "2"}, offsets)
+}
+
+func TestNewSourceErrors(t *testing.T) {
+
+	_, err := NewSource(&testSourceTask{}, SourceConfig{})
+	require.EqualError(t, err, "source producer is nil")
+}
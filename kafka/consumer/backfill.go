@@ -0,0 +1,339 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	_BackfillQueryTimeout = 10 * time.Second
+	_BackfillReadTimeout  = 100 * time.Millisecond
+)
+
+// FuncOnBackfill processes the message of the backfill
+type FuncOnBackfill func(ctx context.Context, logger *zap.Logger, msg *kafka.Message) error
+
+// FuncOnBackfillProgress is called (sequentially) when the percent of the processed messages is increased
+type FuncOnBackfillProgress func(percent int)
+
+// BackfillConfig is a configuration of the backfill consumer
+type BackfillConfig struct {
+	ConfigMap *kafka.ConfigMap
+	Topic     string
+	// Start and End are the bounds of the message timestamps [Start, End)
+	Start time.Time
+	End   time.Time
+	// Concurrency is a max count of the concurrent handlers (1 by default, the order isn't preserved above 1)
+	Concurrency int
+	OnProcess   FuncOnBackfill
+	OnProgress  FuncOnBackfillProgress
+}
+
+type backfillReader interface {
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error)
+	OffsetsForTimes(times []kafka.TopicPartition, timeoutMs int) ([]kafka.TopicPartition, error)
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error)
+	Assign(partitions []kafka.TopicPartition) error
+	Poll(timeoutMs int) kafka.Event
+}
+
+// A BackfillConsumer reads the messages of the topic between the timestamps without the consumer group
+type BackfillConsumer struct {
+	cfg       BackfillConfig
+	logger    *zap.Logger
+	total     int64
+	processed int64
+	percent   int
+	mu        sync.Mutex
+}
+
+// NewBackfillConsumer creates the backfill consumer
+func NewBackfillConsumer(cfg BackfillConfig, logger *zap.Logger) (*BackfillConsumer, error) {
+
+	if cfg.ConfigMap == nil {
+		return nil, configError("reader config is nil")
+	}
+	if cfg.Topic == "" {
+		return nil, configError("topic is empty")
+	}
+	if cfg.OnProcess == nil {
+		return nil, configError("on process callback is nil")
+	}
+	if !cfg.End.After(cfg.Start) {
+		return nil, configError("end of backfill must be after start")
+	}
+
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &BackfillConsumer{
+		cfg:    cfg,
+		logger: logger.With(zap.String("topic", cfg.Topic)),
+	}, nil
+}
+
+// Run reads the messages until the end timestamp is reached in all partitions or the context is done
+func (b *BackfillConsumer) Run(ctx context.Context) error {
+
+	configMap := kafka.ConfigMap{
+		"group.id": "backfill",
+	}
+	for k, v := range *b.cfg.ConfigMap {
+		configMap[k] = v
+	}
+	configMap["enable.auto.commit"] = false
+	configMap["enable.auto.offset.store"] = false
+	configMap["go.events.channel.enable"] = false
+	// the end of the partition is detected by the event: the last offsets can be
+	// the transaction markers or the compacted messages
+	configMap["enable.partition.eof"] = true
+
+	reader, err := kafka.NewConsumer(&configMap)
+	if err != nil {
+		return errors.Wrap(err, "create reader failed")
+	}
+	defer reader.Close()
+
+	return b.run(ctx, reader)
+}
+
+// Progress returns the percent of the processed messages
+func (b *BackfillConsumer) Progress() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.percent
+}
+
+func (b *BackfillConsumer) run(ctx context.Context, r backfillReader) error {
+
+	end, assignment, err := b.bounds(r)
+	if err != nil {
+		return err
+	}
+
+	if len(assignment) == 0 {
+		b.logger.Info("nothing to backfill")
+		b.setProgress(100)
+		return nil
+	}
+
+	if err := r.Assign(assignment); err != nil {
+		return errors.Wrap(err, "failed to assign partitions")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, b.cfg.Concurrency)
+	)
+
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+loop:
+	for len(end) > 0 && ctx.Err() == nil {
+		var msg *kafka.Message
+		switch e := r.Poll(int(_BackfillReadTimeout / time.Millisecond)).(type) {
+		case *kafka.Message:
+			if e.TopicPartition.Error != nil {
+				fail(errors.Wrap(e.TopicPartition.Error, "failed to read message"))
+				break loop
+			}
+			msg = e
+		case kafka.PartitionEOF:
+			// the partition is read to the end
+			if last, ok := end[e.Partition]; ok && int64(e.Offset) >= last {
+				delete(end, e.Partition)
+			}
+			continue
+		case kafka.Error:
+			if e.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			fail(errors.Wrap(e, "failed to read message"))
+			break loop
+		default:
+			continue
+		}
+
+		p := msg.TopicPartition.Partition
+		last, ok := end[p]
+		if !ok {
+			continue
+		}
+
+		if int64(msg.TopicPartition.Offset) >= last {
+			delete(end, p)
+			continue
+		}
+		if int64(msg.TopicPartition.Offset) == last-1 {
+			delete(end, p)
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+
+		wg.Add(1)
+		go func(msg *kafka.Message) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := b.cfg.OnProcess(ctx, b.logger, msg); err != nil {
+				fail(err)
+				return
+			}
+
+			b.done()
+		}(msg)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil && len(end) > 0 {
+		return err
+	}
+
+	b.setProgress(100)
+	b.logger.Info("backfill completed", zap.Int64("processed", b.processedCount()))
+
+	return nil
+}
+
+func (b *BackfillConsumer) bounds(r backfillReader) (map[int32]int64, []kafka.TopicPartition, error) {
+
+	const timeoutMs = int(_BackfillQueryTimeout / time.Millisecond)
+
+	topic := b.cfg.Topic
+
+	md, err := r.GetMetadata(&topic, false, timeoutMs)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get metadata")
+	}
+
+	tm, ok := md.Topics[topic]
+	if !ok || tm.Error.Code() != kafka.ErrNoError {
+		return nil, nil, errors.Errorf("topic %s not found", topic)
+	}
+
+	startTimes := make([]kafka.TopicPartition, 0, len(tm.Partitions))
+	endTimes := make([]kafka.TopicPartition, 0, len(tm.Partitions))
+	for _, p := range tm.Partitions {
+		startTimes = append(startTimes, kafka.TopicPartition{Topic: &topic, Partition: p.ID, Offset: kafka.Offset(timeMs(b.cfg.Start))})
+		endTimes = append(endTimes, kafka.TopicPartition{Topic: &topic, Partition: p.ID, Offset: kafka.Offset(timeMs(b.cfg.End))})
+	}
+
+	startOffsets, err := r.OffsetsForTimes(startTimes, timeoutMs)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get start offsets")
+	}
+
+	endOffsets, err := r.OffsetsForTimes(endTimes, timeoutMs)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get end offsets")
+	}
+
+	endByPartition := make(map[int32]int64, len(endOffsets))
+	for _, tp := range endOffsets {
+		endByPartition[tp.Partition] = int64(tp.Offset)
+	}
+
+	end := make(map[int32]int64)
+	assignment := make([]kafka.TopicPartition, 0, len(startOffsets))
+
+	for _, tp := range startOffsets {
+		if tp.Offset < 0 {
+			// no messages after the start
+			continue
+		}
+
+		_, high, err := r.QueryWatermarkOffsets(topic, tp.Partition, timeoutMs)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to query watermark offsets of partition %d", tp.Partition)
+		}
+
+		last, ok := endByPartition[tp.Partition]
+		if !ok || last < 0 || last > high {
+			last = high
+		}
+
+		if int64(tp.Offset) >= last {
+			continue
+		}
+
+		end[tp.Partition] = last
+		assignment = append(assignment, kafka.TopicPartition{Topic: &topic, Partition: tp.Partition, Offset: tp.Offset})
+		b.total += last - int64(tp.Offset)
+	}
+
+	return end, assignment, nil
+}
+
+func (b *BackfillConsumer) done() {
+
+	b.mu.Lock()
+	b.processed++
+
+	var percent int
+	if b.total > 0 {
+		percent = int(b.processed * 100 / b.total)
+	}
+	if percent > 99 {
+		// 100% after all partitions are read (the compacted topics have gaps)
+		percent = 99
+	}
+	b.mu.Unlock()
+
+	b.setProgress(percent)
+}
+
+func (b *BackfillConsumer) setProgress(percent int) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if percent <= b.percent {
+		return
+	}
+	b.percent = percent
+
+	if b.cfg.OnProgress != nil {
+		b.cfg.OnProgress(percent)
+	}
+}
+
+func (b *BackfillConsumer) processedCount() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.processed
+}
+
+func timeMs(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
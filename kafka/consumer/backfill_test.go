@@ -0,0 +1,206 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testBackfillReader has the partitions with the messages (the timestamp of the message is offset*1000ms),
+// the last offsets of the partitions can be the transaction markers (see markers)
+type testBackfillReader struct {
+	topic    string
+	messages map[int32]int
+	markers  map[int32]int
+	assigned []kafka.TopicPartition
+	queue    []kafka.Event
+}
+
+func (r *testBackfillReader) GetMetadata(*string, bool, int) (*kafka.Metadata, error) {
+
+	tm := kafka.TopicMetadata{Topic: r.topic}
+	for p := range r.messages {
+		tm.Partitions = append(tm.Partitions, kafka.PartitionMetadata{ID: p})
+	}
+	sort.Slice(tm.Partitions, func(i, j int) bool { return tm.Partitions[i].ID < tm.Partitions[j].ID })
+
+	return &kafka.Metadata{Topics: map[string]kafka.TopicMetadata{r.topic: tm}}, nil
+}
+
+func (r *testBackfillReader) OffsetsForTimes(times []kafka.TopicPartition, _ int) ([]kafka.TopicPartition, error) {
+
+	retval := make([]kafka.TopicPartition, len(times))
+	for i, tp := range times {
+		offset := (int64(tp.Offset) + 999) / 1000
+		if offset >= int64(r.messages[tp.Partition]) {
+			offset = int64(kafka.OffsetEnd)
+		}
+
+		retval[i] = tp
+		retval[i].Offset = kafka.Offset(offset)
+	}
+
+	return retval, nil
+}
+
+func (r *testBackfillReader) QueryWatermarkOffsets(_ string, partition int32, _ int) (int64, int64, error) {
+	return 0, int64(r.messages[partition]), nil
+}
+
+func (r *testBackfillReader) Assign(partitions []kafka.TopicPartition) error {
+
+	r.assigned = partitions
+	for _, tp := range partitions {
+		high := r.messages[tp.Partition]
+		for offset := int(tp.Offset); offset < high-r.markers[tp.Partition]; offset++ {
+			r.queue = append(r.queue, &kafka.Message{
+				TopicPartition: kafka.TopicPartition{Topic: &r.topic, Partition: tp.Partition, Offset: kafka.Offset(offset)},
+			})
+		}
+		r.queue = append(r.queue, kafka.PartitionEOF{Topic: &r.topic, Partition: tp.Partition, Offset: kafka.Offset(high)})
+	}
+
+	return nil
+}
+
+func (r *testBackfillReader) Poll(int) kafka.Event {
+
+	if len(r.queue) == 0 {
+		return nil
+	}
+
+	ev := r.queue[0]
+	r.queue = r.queue[1:]
+
+	return ev
+}
+
+func TestBackfillConfig(t *testing.T) {
+
+	now := time.Now()
+	fn := func(context.Context, *zap.Logger, *kafka.Message) error { return nil }
+
+	for _, testData := range []struct {
+		Config BackfillConfig
+		Err    string
+	}{
+		{BackfillConfig{}, "reader config is nil"},
+		{BackfillConfig{ConfigMap: &kafka.ConfigMap{}}, "topic is empty"},
+		{BackfillConfig{ConfigMap: &kafka.ConfigMap{}, Topic: "a"}, "on process callback is nil"},
+		{BackfillConfig{ConfigMap: &kafka.ConfigMap{}, Topic: "a", OnProcess: fn, Start: now, End: now}, "end of backfill must be after start"},
+	} {
+		_, err := NewBackfillConsumer(testData.Config, nil)
+		require.True(t, errors.Is(err, ErrInvalidConfig))
+		require.EqualError(t, err, testData.Err)
+	}
+}
+
+func TestBackfillRun(t *testing.T) {
+
+	var (
+		mu        sync.Mutex
+		processed []kafka.TopicPartition
+		progress  []int
+	)
+
+	b, err := NewBackfillConsumer(BackfillConfig{
+		ConfigMap:   &kafka.ConfigMap{},
+		Topic:       "a",
+		Start:       time.Unix(2, 0),
+		End:         time.Unix(6, 0),
+		Concurrency: 3,
+		OnProcess: func(_ context.Context, _ *zap.Logger, msg *kafka.Message) error {
+			mu.Lock()
+			processed = append(processed, msg.TopicPartition)
+			mu.Unlock()
+			return nil
+		},
+		OnProgress: func(percent int) { progress = append(progress, percent) },
+	}, nil)
+	require.NoError(t, err)
+
+	// partition 0: offsets 2..5; partition 1: offsets 2..3 (end of the partition); partition 2: empty
+	reader := &testBackfillReader{topic: "a", messages: map[int32]int{0: 10, 1: 4, 2: 1}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, b.run(ctx, reader))
+	require.Len(t, reader.assigned, 2)
+	require.Len(t, processed, 6)
+	for _, tp := range processed {
+		require.True(t, tp.Offset >= 2 && tp.Offset < 6, tp.String())
+	}
+
+	require.Equal(t, 100, b.Progress())
+	require.Equal(t, 100, progress[len(progress)-1])
+	require.True(t, sort.IntsAreSorted(progress))
+}
+
+func TestBackfillMarkers(t *testing.T) {
+
+	var processed []kafka.TopicPartition
+	b, err := NewBackfillConsumer(BackfillConfig{
+		ConfigMap: &kafka.ConfigMap{},
+		Topic:     "a",
+		Start:     time.Unix(0, 0),
+		End:       time.Unix(100, 0),
+		OnProcess: func(_ context.Context, _ *zap.Logger, msg *kafka.Message) error {
+			processed = append(processed, msg.TopicPartition)
+			return nil
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	// the last offsets before the high watermark are the transaction markers
+	reader := &testBackfillReader{topic: "a", messages: map[int32]int{0: 5}, markers: map[int32]int{0: 2}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, b.run(ctx, reader))
+	require.Len(t, processed, 3)
+	require.Equal(t, 100, b.Progress())
+}
+
+func TestBackfillError(t *testing.T) {
+
+	b, err := NewBackfillConsumer(BackfillConfig{
+		ConfigMap: &kafka.ConfigMap{},
+		Topic:     "a",
+		Start:     time.Unix(0, 0),
+		End:       time.Unix(100, 0),
+		OnProcess: func(context.Context, *zap.Logger, *kafka.Message) error {
+			return errors.New("failed")
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	reader := &testBackfillReader{topic: "a", messages: map[int32]int{0: 10}}
+	require.EqualError(t, b.run(context.Background(), reader), "failed")
+	require.Equal(t, 0, b.Progress())
+}
+
+func TestBackfillEmpty(t *testing.T) {
+
+	b, err := NewBackfillConsumer(BackfillConfig{
+		ConfigMap: &kafka.ConfigMap{},
+		Topic:     "a",
+		Start:     time.Unix(100, 0),
+		End:       time.Unix(200, 0),
+		OnProcess: func(context.Context, *zap.Logger, *kafka.Message) error { return nil },
+	}, nil)
+	require.NoError(t, err)
+
+	reader := &testBackfillReader{topic: "a", messages: map[int32]int{0: 10}}
+	require.NoError(t, b.run(context.Background(), reader))
+	require.Len(t, reader.assigned, 0)
+	require.Equal(t, 100, b.Progress())
+}
@@ -0,0 +1,164 @@
+package consumer
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/logger"
+	"github.com/dialogs/dialog-go-lib/trace"
+	"go.uber.org/zap"
+)
+
+// FuncOnProcessBatch processes the batch of the messages (see Config.OnProcessBatch)
+type FuncOnProcessBatch func(ctx context.Context, logger *zap.Logger, msgs []*kafka.Message, s ISleeper) error
+
+type batch struct {
+	size    int
+	timeout time.Duration
+	msgs    []*kafka.Message
+	// partitions are the offsets of the batch messages and the skipped messages
+	partitions []kafka.TopicPartition
+	// keys are the keys of the deduplication of the batch messages (see dedupeKey)
+	keys []string
+}
+
+func newBatch(cfg *Config) *batch {
+	if cfg.OnProcessBatch == nil {
+		return nil
+	}
+
+	b := &batch{
+		size:    cfg.BatchSize,
+		timeout: cfg.BatchTimeout,
+	}
+
+	if b.size <= 0 {
+		b.size = 100
+	}
+	if b.timeout <= 0 {
+		b.timeout = time.Second
+	}
+
+	return b
+}
+
+func (b *batch) tickDuration() time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	return b.timeout
+}
+
+func (c *Consumer) addToBatch(tp kafka.TopicPartition, msg *kafka.Message, key string, consumerOffsets *offset) error {
+
+	if msg != nil {
+		c.batch.msgs = append(c.batch.msgs, msg)
+	}
+	if key != "" {
+		c.batch.keys = append(c.batch.keys, key)
+	}
+	c.batch.partitions = append(c.batch.partitions, tp)
+
+	if len(c.batch.msgs) >= c.batch.size {
+		return c.flushBatch(consumerOffsets)
+	}
+
+	return nil
+}
+
+func (c *Consumer) flushBatch(consumerOffsets *offset) error {
+
+	if c.batch == nil || len(c.batch.partitions) == 0 {
+		return nil
+	}
+
+	msgs, partitions, keys := c.batch.msgs, c.batch.partitions, c.batch.keys
+	c.batch.msgs, c.batch.partitions, c.batch.keys = nil, nil, nil
+
+	if len(msgs) > 0 {
+		opLog := c.logger.With(zap.String("operation", "batch"), zap.Int("size", len(msgs)))
+
+		start := time.Now()
+		err := c.processBatchInflight(opLog, partitions, msgs)
+		for _, msg := range msgs {
+			c.inflight.Done(msg.TopicPartition, time.Since(start), err)
+		}
+
+		if err != nil {
+			if progressErr, ok := err.(*ProgressError); ok {
+				opLog = opLog.With(zap.Any("progress", progressErr.Tags()))
+			}
+			opLog.Error("failed to process batch", zap.Error(err))
+			return err
+		}
+
+		opLog.Debug("success")
+	}
+
+	for _, key := range keys {
+		c.dedupe.Add(key)
+	}
+
+	consumerOffsets.Add(partitions...)
+	c.commitScheduled(consumerOffsets)
+
+	return nil
+}
+
+func (c *Consumer) processBatchInflight(opLog *zap.Logger, partitions []kafka.TopicPartition, msgs []*kafka.Message) error {
+
+	for _, tp := range partitions {
+		c.inflight.Begin(tp)
+	}
+	defer func() {
+		for _, tp := range partitions {
+			c.inflight.End(tp)
+		}
+	}()
+
+	return c.retrier.Do(c.ctx, opLog, func() error { return c.processBatch(opLog, msgs) })
+}
+
+func (c *Consumer) processBatch(opLog *zap.Logger, msgs []*kafka.Message) error {
+
+	defer repanic(func() map[string]string { return batchDetails(msgs) })
+
+	ctx, progress := ContextWithProgress(logger.ContextWithLogger(c.ctx, opLog))
+
+	topics := ""
+	if c.labels != nil {
+		topics = batchTopics(msgs)
+	}
+	onProcessBatch := func(ctx context.Context) error { return c.onProcessBatch(ctx, opLog, msgs, c) }
+
+	if c.tracer == nil {
+		return progressError(progress, c.labels.Do(ctx, topics, onProcessBatch))
+	}
+
+	ctx, span := c.tracer.Start(ctx, "batch process", trace.KindConsumer)
+	defer span.End()
+
+	span.SetAttribute("messaging.system", "kafka")
+	span.SetAttribute("messaging.batch.message_count", strconv.Itoa(len(msgs)))
+	span.SetAttribute("messaging.kafka.consumer_id", c.id)
+
+	err := c.labels.Do(ctx, topics, onProcessBatch)
+	if stages := progress.String(); stages != "" {
+		span.SetAttribute("messaging.kafka.progress", stages)
+	}
+	span.SetError(err)
+
+	return progressError(progress, err)
+}
+
+func (c *Consumer) handleTick(consumerOffsets *offset) error {
+
+	if c.pool != nil {
+		return c.pool.Err()
+	}
+
+	return c.flushBatch(consumerOffsets)
+}
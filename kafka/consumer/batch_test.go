@@ -0,0 +1,86 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBatchDisabled(t *testing.T) {
+
+	require.Nil(t, newBatch(&Config{}))
+	require.Equal(t, time.Duration(0), newBatch(&Config{}).tickDuration())
+
+	b := newBatch(&Config{OnProcessBatch: func(context.Context, *zap.Logger, []*kafka.Message, ISleeper) error { return nil }})
+	require.Equal(t, 100, b.size)
+	require.Equal(t, time.Second, b.tickDuration())
+}
+
+func TestBatchFlush(t *testing.T) {
+
+	var batches [][]string
+
+	c, reader := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.BatchSize = 2
+		cfg.OnProcess = nil
+		cfg.OnProcessBatch = func(_ context.Context, _ *zap.Logger, msgs []*kafka.Message, _ ISleeper) error {
+			values := make([]string, len(msgs))
+			for i := range msgs {
+				values[i] = string(msgs[i].Value)
+			}
+			batches = append(batches, values)
+			return nil
+		}
+	}))
+
+	topic := "t1"
+	tp := func(offset int) kafka.TopicPartition {
+		return kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(offset)}
+	}
+
+	offsets := newOffset()
+	require.NoError(t, c.handleMessage(&kafka.Message{TopicPartition: tp(1), Value: []byte("1")}, offsets))
+	require.Len(t, batches, 0)
+
+	// the full batch
+	require.NoError(t, c.handleMessage(&kafka.Message{TopicPartition: tp(2), Value: []byte("2")}, offsets))
+	require.Equal(t, [][]string{{"1", "2"}}, batches)
+	commits := reader.Commits()
+	require.Len(t, commits, 1)
+	require.Equal(t, kafka.Offset(2), commits[0][0].Offset)
+
+	// the timeout
+	require.NoError(t, c.handleMessage(&kafka.Message{TopicPartition: tp(3), Value: []byte("3")}, offsets))
+	require.NoError(t, c.handleTick(offsets))
+	require.Equal(t, [][]string{{"1", "2"}, {"3"}}, batches)
+	require.Equal(t, kafka.Offset(3), reader.Commits()[1][0].Offset)
+
+	// nothing to flush
+	require.NoError(t, c.handleTick(offsets))
+	require.Len(t, batches, 2)
+}
+
+func TestBatchError(t *testing.T) {
+
+	c, reader := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.BatchSize = 1
+		cfg.OnProcess = nil
+		cfg.OnProcessBatch = func(ctx context.Context, _ *zap.Logger, _ []*kafka.Message, _ ISleeper) error {
+			Checkpoint(ctx, "insert")
+			return errors.New("failed")
+		}
+	}))
+
+	topic := "t1"
+	offsets := newOffset()
+	err := c.handleMessage(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: 1}}, offsets)
+	require.EqualError(t, err, "failed")
+	require.IsType(t, &ProgressError{}, err)
+	require.Empty(t, reader.Commits())
+	require.Equal(t, 0, offsets.Counter())
+}
@@ -0,0 +1,264 @@
+package consumer
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/kafka/producer"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	_DefaultChunkTimeout = time.Minute
+	_DefaultMaxChunks    = 16
+)
+
+// ReassembleConfig is a configuration of the reassembly of the chunks (see producer.ChunkingProducer)
+type ReassembleConfig struct {
+	// Timeout is a max wait time of the remaining chunks (1m by default)
+	Timeout time.Duration
+	// MaxChunks is a max count of the chunks of the message (16 by default)
+	MaxChunks int
+}
+
+type assembly struct {
+	topic     string
+	partition int32
+	// first is the offset of the first received chunk, last is the offset of the chunk
+	// completing the message (kafka.OffsetInvalid until the message is reassembled)
+	first    kafka.Offset
+	last     kafka.Offset
+	chunks   [][]byte
+	received int
+	created  time.Time
+}
+
+// reassembler collects the chunks, the offsets aren't committed past the first chunk of an incomplete message
+type reassembler struct {
+	cfg     ReassembleConfig
+	clock   clock.Clock
+	pending map[string]*assembly
+	done    []*assembly
+	mu      sync.Mutex
+}
+
+func newReassembler(cfg *ReassembleConfig, clk clock.Clock) *reassembler {
+	if cfg == nil {
+		return nil
+	}
+
+	retval := &reassembler{
+		cfg:     *cfg,
+		clock:   clk,
+		pending: make(map[string]*assembly),
+	}
+
+	if retval.cfg.Timeout <= 0 {
+		retval.cfg.Timeout = _DefaultChunkTimeout
+	}
+	if retval.cfg.MaxChunks <= 0 {
+		retval.cfg.MaxChunks = _DefaultMaxChunks
+	}
+
+	return retval
+}
+
+// Add returns the whole or the reassembled message, pending is set for the chunk waiting for the others
+func (r *reassembler) Add(logger *zap.Logger, msg *kafka.Message) (retval *kafka.Message, pending bool) {
+	if r == nil {
+		return msg, false
+	}
+
+	id, index, count, ok, err := chunkHeaders(msg)
+	if !ok {
+		return msg, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	for k, a := range r.pending {
+		if now.Sub(a.created) > r.cfg.Timeout {
+			logger.Warn("chunked message expired",
+				zap.String("chunk_id", k),
+				zap.Int("received", a.received),
+				zap.Int("count", len(a.chunks)))
+			delete(r.pending, k)
+		}
+	}
+
+	if err == nil && count > r.cfg.MaxChunks {
+		err = errors.Errorf("%d chunks (max %d)", count, r.cfg.MaxChunks)
+	}
+	if err != nil {
+		logger.Warn("invalid chunk", zap.String("chunk_id", id), zap.Error(err))
+		delete(r.pending, id)
+		return nil, false
+	}
+
+	tp := msg.TopicPartition
+	a, ok := r.pending[id]
+	if !ok {
+		a = &assembly{
+			topic:     stringValue(tp.Topic),
+			partition: tp.Partition,
+			first:     tp.Offset,
+			last:      kafka.OffsetInvalid,
+			chunks:    make([][]byte, count),
+			created:   now,
+		}
+		r.pending[id] = a
+	}
+
+	if len(a.chunks) != count {
+		logger.Warn("invalid chunk", zap.String("chunk_id", id), zap.Error(errors.New("count mismatch")))
+		delete(r.pending, id)
+		return nil, false
+	}
+
+	if a.chunks[index] == nil {
+		a.received++
+	}
+	a.chunks[index] = append([]byte{}, msg.Value...)
+
+	if a.received < count {
+		return nil, true
+	}
+
+	delete(r.pending, id)
+	a.last = tp.Offset
+	r.done = append(r.done, a)
+
+	reassembled := *msg
+	reassembled.Value = bytes.Join(a.chunks, nil)
+	reassembled.Headers = make([]kafka.Header, 0, len(msg.Headers))
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case producer.HeaderChunkID, producer.HeaderChunkIndex, producer.HeaderChunkCount:
+		default:
+			reassembled.Headers = append(reassembled.Headers, h)
+		}
+	}
+
+	if string(reassembled.Key) == id {
+		// the key was generated by the producer
+		reassembled.Key = nil
+	}
+
+	return &reassembled, false
+}
+
+// Limit caps the offsets to commit before the first chunks of the incomplete messages
+func (r *reassembler) Limit(list []kafka.TopicPartition) []kafka.TopicPartition {
+	if r == nil {
+		return list
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	retval := make([]kafka.TopicPartition, 0, len(list))
+	for _, tp := range list {
+		topic, limit := stringValue(tp.Topic), tp.Offset
+
+		done := r.done[:0]
+		for _, a := range r.done {
+			if a.topic == topic && a.partition == tp.Partition && tp.Offset >= a.last {
+				// the reassembled message is processed
+				continue
+			}
+			done = append(done, a)
+		}
+		r.done = done
+
+		for _, a := range append(r.pendingList(), r.done...) {
+			if a.topic == topic && a.partition == tp.Partition && a.first-1 < limit {
+				limit = a.first - 1
+			}
+		}
+
+		if limit >= 0 {
+			tp.Offset = limit
+			retval = append(retval, tp)
+		}
+	}
+
+	return retval
+}
+
+// Drop removes the chunks of the revoked partitions: they're consumed again by the next owner
+func (r *reassembler) Drop(partitions []kafka.TopicPartition) {
+	if r == nil {
+		return
+	}
+
+	revoked := make(map[string]struct{}, len(partitions))
+	for _, tp := range partitions {
+		revoked[getPartitionKey(tp.Topic, tp.Partition)] = struct{}{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, a := range r.pending {
+		if _, ok := revoked[getPartitionKey(&a.topic, a.partition)]; ok {
+			delete(r.pending, id)
+		}
+	}
+
+	done := r.done[:0]
+	for _, a := range r.done {
+		if _, ok := revoked[getPartitionKey(&a.topic, a.partition)]; !ok {
+			done = append(done, a)
+		}
+	}
+	r.done = done
+}
+
+func (r *reassembler) pendingList() []*assembly {
+	retval := make([]*assembly, 0, len(r.pending))
+	for _, a := range r.pending {
+		retval = append(retval, a)
+	}
+
+	return retval
+}
+
+func chunkHeaders(msg *kafka.Message) (id string, index, count int, ok bool, err error) {
+
+	var indexVal, countVal string
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case producer.HeaderChunkID:
+			id, ok = string(h.Value), true
+		case producer.HeaderChunkIndex:
+			indexVal = string(h.Value)
+		case producer.HeaderChunkCount:
+			countVal = string(h.Value)
+		}
+	}
+
+	if !ok {
+		return
+	}
+
+	if index, err = strconv.Atoi(indexVal); err != nil {
+		err = errors.Wrap(err, "invalid chunk index")
+		return
+	}
+	if count, err = strconv.Atoi(countVal); err != nil {
+		err = errors.Wrap(err, "invalid chunk count")
+		return
+	}
+	if count <= 0 || index < 0 || index >= count {
+		err = errors.Errorf("invalid chunk %d of %d", index, count)
+	}
+
+	return
+}
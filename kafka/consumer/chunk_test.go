@@ -0,0 +1,173 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/dialogs/dialog-go-lib/kafka/producer"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestChunks(t *testing.T, topic string, key, value string, size int, offset kafka.Offset) []*kafka.Message {
+	t.Helper()
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic},
+		Value:          []byte(value),
+		Headers:        []kafka.Header{{Key: "h", Value: []byte("1")}},
+	}
+	if key != "" {
+		msg.Key = []byte(key)
+	}
+
+	chunks, err := producer.Chunk(msg, size, 16)
+	require.NoError(t, err)
+
+	for i := range chunks {
+		chunks[i].TopicPartition = kafka.TopicPartition{Topic: &topic, Offset: offset + kafka.Offset(i)}
+	}
+
+	return chunks
+}
+
+func TestReassemble(t *testing.T) {
+
+	topic := "a"
+	chunks := newTestChunks(t, topic, "", "0123456789", 4, 10)
+	require.Len(t, chunks, 3)
+
+	r := newReassembler(&ReassembleConfig{}, clock.Real)
+	logger := zap.NewNop()
+
+	// the whole message
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}, Value: []byte("1")}
+	res, pending := r.Add(logger, msg)
+	require.False(t, pending)
+	require.Equal(t, msg, res)
+
+	// out of order
+	for _, i := range []int{2, 0} {
+		res, pending = r.Add(logger, chunks[i])
+		require.True(t, pending)
+		require.Nil(t, res)
+	}
+
+	res, pending = r.Add(logger, chunks[1])
+	require.False(t, pending)
+	require.NotNil(t, res)
+	require.Equal(t, "0123456789", string(res.Value))
+	require.Nil(t, res.Key)
+	require.Equal(t, []kafka.Header{{Key: "h", Value: []byte("1")}}, res.Headers)
+	require.Equal(t, kafka.Offset(11), res.TopicPartition.Offset)
+
+	// too many chunks
+	r = newReassembler(&ReassembleConfig{MaxChunks: 2}, clock.Real)
+	res, pending = r.Add(logger, chunks[0])
+	require.False(t, pending)
+	require.Nil(t, res)
+
+	// disabled
+	r = newReassembler(nil, clock.Real)
+	res, pending = r.Add(logger, chunks[0])
+	require.False(t, pending)
+	require.Equal(t, chunks[0], res)
+}
+
+func TestReassembleTimeout(t *testing.T) {
+
+	chunks := newTestChunks(t, "a", "k", "0123", 2, 0)
+
+	clk := mock.NewClock(time.Unix(100, 0))
+	r := newReassembler(&ReassembleConfig{Timeout: time.Minute}, clk)
+	logger := zap.NewNop()
+
+	_, pending := r.Add(logger, chunks[0])
+	require.True(t, pending)
+
+	clk.Add(2 * time.Minute)
+
+	// the first chunk is expired
+	_, pending = r.Add(logger, chunks[1])
+	require.True(t, pending)
+
+	res, pending := r.Add(logger, chunks[0])
+	require.False(t, pending)
+	require.Equal(t, "0123", string(res.Value))
+	require.Equal(t, []byte("k"), res.Key)
+}
+
+func TestReassembleLimit(t *testing.T) {
+
+	topic := "a"
+	chunks := newTestChunks(t, topic, "", "0123", 2, 10)
+
+	r := newReassembler(&ReassembleConfig{}, clock.Real)
+	logger := zap.NewNop()
+
+	offsets := func(values ...kafka.Offset) []kafka.TopicPartition {
+		retval := make([]kafka.TopicPartition, len(values))
+		for i, val := range values {
+			retval[i] = kafka.TopicPartition{Topic: &topic, Partition: int32(i), Offset: val}
+		}
+		return retval
+	}
+
+	_, pending := r.Add(logger, chunks[0])
+	require.True(t, pending)
+
+	// the offsets of the partition aren't committed after the first chunk
+	require.Equal(t, offsets(9, 20), r.Limit(offsets(15, 20)))
+	require.Equal(t, offsets(5, 20), r.Limit(offsets(5, 20)))
+
+	res, pending := r.Add(logger, chunks[1])
+	require.False(t, pending)
+	require.NotNil(t, res)
+
+	// the reassembled message isn't processed
+	require.Equal(t, offsets(9), r.Limit(offsets(10)))
+	// the reassembled message is processed
+	require.Equal(t, offsets(11), r.Limit(offsets(11)))
+	require.Equal(t, offsets(12), r.Limit(offsets(12)))
+
+	// nothing to commit before the first chunk
+	chunks = newTestChunks(t, topic, "", "0123", 2, 0)
+	_, pending = r.Add(logger, chunks[0])
+	require.True(t, pending)
+	require.Empty(t, r.Limit(offsets(3)))
+
+	// the chunks of the revoked partition are consumed again
+	r.Drop(offsets(0))
+	require.Equal(t, offsets(3), r.Limit(offsets(3)))
+}
+
+func TestReassembleOffsets(t *testing.T) {
+
+	var processed []string
+	c, _ := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.Reassemble = &ReassembleConfig{}
+		cfg.OnProcess = func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
+			processed = append(processed, string(msg.Value))
+			return nil
+		}
+	}))
+
+	topic := "a"
+	chunks := newTestChunks(t, topic, "", "0123", 2, 0)
+	offsets := newOffset()
+
+	// the offset of the pending chunk isn't stored
+	require.NoError(t, c.handleMessage(chunks[0], offsets))
+	list, _ := offsets.Get()
+	require.Empty(t, list)
+
+	require.NoError(t, c.handleMessage(chunks[1], offsets))
+	require.Equal(t, []string{"0123"}, processed)
+
+	list, _ = offsets.Get()
+	require.Equal(t, []kafka.TopicPartition{{Topic: &topic, Offset: 1}}, c.reassembler.Limit(list))
+}
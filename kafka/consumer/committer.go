@@ -0,0 +1,18 @@
+package consumer
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// MarkOffset marks the message as processed (ICommitter implementation)
+func (c *Consumer) MarkOffset(tp kafka.TopicPartition) {
+	c.marked.Add(tp)
+}
+
+func (c *Consumer) storeOffset(consumerOffsets *offset, tp kafka.TopicPartition) {
+	if c.manualCommit {
+		return
+	}
+
+	consumerOffsets.Add(tp)
+}
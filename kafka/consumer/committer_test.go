@@ -0,0 +1,101 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestManualCommit(t *testing.T) {
+
+	var pending []kafka.TopicPartition
+
+	c, _ := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.ManualCommit = true
+		cfg.OnProcess = func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
+			// the message is processed asynchronously: the offset is marked later
+			pending = append(pending, msg.TopicPartition)
+			return nil
+		}
+	}))
+
+	topic := "a"
+	offsets := newOffset()
+	for i := 1; i <= 3; i++ {
+		msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(i)}}
+		require.NoError(t, c.handleMessage(msg, offsets))
+	}
+
+	// the offsets of the processed messages aren't stored
+	list, _ := offsets.Get()
+	require.Empty(t, list)
+	require.Len(t, pending, 3)
+
+	c.MarkOffset(pending[1])
+	c.MarkOffset(pending[0])
+
+	list = c.marked.Take()
+	require.Len(t, list, 1)
+	require.Equal(t, kafka.Offset(2), list[0].Offset)
+	require.Zero(t, c.marked.Counter())
+
+	// the marks of the revoked partitions are dropped
+	c.MarkOffset(pending[2])
+	c.clearOffsets(offsets, []kafka.TopicPartition{pending[2]})
+	require.Empty(t, c.marked.Take())
+}
+
+func TestManualCommitDisabled(t *testing.T) {
+
+	c, _ := newTestConsumer(t)
+
+	topic := "a"
+	offsets := newOffset()
+	c.storeOffset(offsets, kafka.TopicPartition{Topic: &topic, Offset: 1})
+
+	list, _ := offsets.Get()
+	require.Len(t, list, 1)
+
+	c.manualCommit = true
+	c.storeOffset(offsets, kafka.TopicPartition{Topic: &topic, Offset: 2})
+
+	list, _ = offsets.Get()
+	require.Len(t, list, 1)
+	require.Equal(t, kafka.Offset(1), list[0].Offset)
+}
+
+func TestRevokePreCommitError(t *testing.T) {
+
+	failure := errors.New("pre-commit failed")
+
+	var (
+		reported error
+		revoked  []kafka.TopicPartition
+	)
+
+	c, reader := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.OnError = func(_ context.Context, _ *zap.Logger, err error) { reported = err }
+		cfg.OnPreCommit = func(context.Context, *zap.Logger, []kafka.TopicPartition) error {
+			return failure
+		}
+		cfg.OnRevoke = func(_ context.Context, _ *zap.Logger, partitions []kafka.TopicPartition) {
+			revoked = partitions
+		}
+	}))
+
+	topic := "a"
+	offsets := newOffset()
+	offsets.Add(kafka.TopicPartition{Topic: &topic, Offset: 1})
+
+	// the failed commit is reported, the partitions are revoked and the offsets are dropped
+	partitions := []kafka.TopicPartition{{Topic: &topic}}
+	require.NoError(t, c.handleRevoke(&kafka.RevokedPartitions{Partitions: partitions}, offsets))
+	require.Equal(t, failure, reported)
+	require.Equal(t, partitions, revoked)
+	require.Zero(t, offsets.Counter())
+	require.Empty(t, reader.Commits())
+}
@@ -1,22 +1,110 @@
 package consumer
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
-	"github.com/pkg/errors"
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/idgen"
+	"github.com/dialogs/dialog-go-lib/trace"
 )
 
 type Config struct {
-	ConfigMap            *kafka.ConfigMap
-	CommitOffsetCount    int
-	CommitOffsetDuration time.Duration
-	OnCommit             FuncOnCommit
-	OnError              FuncOnError
-	OnProcess            FuncOnProcess
-	OnRevoke             FuncOnRevoke
-	OnRebalance          FuncOnRebalance
-	Topics               []string
+	// ConfigMap is the configuration of the reader
+	ConfigMap                 *kafka.ConfigMap
+	CommitOffsetCount         int
+	CommitOffsetDuration      time.Duration
+	CommitTimeout             time.Duration
+	Dedupe                    *DedupeConfig
+	HeartbeatInterval         time.Duration
+	LagInterval               time.Duration
+	Metrics                   *Metrics
+	OnCommit                  FuncOnCommit
+	OnError                   FuncOnError
+	OnEvent                   FuncOnEvent
+	OnHeartbeat               FuncOnHeartbeat
+	OnLag                     FuncOnLag
+	OnOAuthBearerTokenRefresh FuncOnOAuthBearerTokenRefresh
+	// TokenProvider returns the token on the OAUTHBEARER token refresh instead of OnOAuthBearerTokenRefresh
+	TokenProvider FuncTokenProvider
+	// OnPreCommit flushes the results of the processed messages before the commit
+	OnPreCommit FuncOnPreCommit
+	OnProcess   FuncOnProcess
+	// OnProcessBatch enables the batch mode: the messages are processed by BatchSize (100) or BatchTimeout (1s)
+	OnProcessBatch FuncOnProcessBatch
+	BatchSize      int
+	BatchTimeout   time.Duration
+	OnRevoke       FuncOnRevoke
+	OnRebalance    FuncOnRebalance
+	// OnAssign replaces the start offsets of the assigned partitions (e.g. the offsets of the state snapshot)
+	OnAssign FuncOnAssign
+	OnStats  FuncOnStats
+	// StatsInterval sets 'statistics.interval.ms' (1m by default if the statistics are required)
+	StatsInterval time.Duration
+	// OnThrottle receives the broker throttle times from the statistics (see StatsInterval)
+	OnThrottle FuncOnThrottle
+	// OnTopicChange is called when the subscribed topic appears or disappears (see TopicWaitConfig.WatchInterval)
+	OnTopicChange FuncOnTopicChange
+	// OnPartitionsChange is called when the partitions count of the subscribed topic is changed (see PartitionsWatch)
+	OnPartitionsChange FuncOnPartitionsChange
+	// PartitionsWatch enables the checks of the partitions count of the subscribed topics
+	PartitionsWatch *PartitionsWatchConfig
+	// ManualCommit commits the offsets marked by ICommitter only (the batch mode isn't supported)
+	ManualCommit bool
+	// CommitStrategy is CommitSync (by default), CommitAsync or CommitPerPartition
+	CommitStrategy CommitStrategy
+	// Poison enables the skip of the message failed consecutively (the batch mode isn't supported)
+	Poison *PoisonConfig
+	// Quotas limit the consumption rate of the topics
+	Quotas map[string]QuotaConfig
+	// CooperativeRebalance enables the incremental rebalance (partition.assignment.strategy=cooperative-sticky)
+	CooperativeRebalance bool
+	// MaxMessagesPerSecond limits the processing rate of the consumer, MaxMessagesBurst is a max burst
+	MaxMessagesPerSecond float64
+	MaxMessagesBurst     int
+	// Retry enables the retries of OnProcess with the exponential backoff
+	Retry              *RetryConfig
+	SleepCheckInterval time.Duration
+	// SleepStore keeps the sleeps of the partitions between the rebalances
+	SleepStore ISleepStore
+	// ThrottleBackoffFactor enables a pause of consumption for the broker throttle time multiplied by the factor
+	ThrottleBackoffFactor float64
+	ThrottleBackoffMax    time.Duration
+	// Workers enables the concurrent processing of the partitions (of the keys if KeyOrdering is set)
+	Workers         int
+	WorkerQueueSize int
+	KeyOrdering     bool
+	// TopicWait enables the waiting for the subscribed topics instead of consuming nothing
+	TopicWait *TopicWaitConfig
+	// Reassemble enables the reassembly of the chunked messages before the transformers
+	Reassemble *ReassembleConfig
+	// Transformers modify messages before OnProcess
+	Transformers []FuncTransform
+	// Filter skips the messages before the deduplication and the transformers
+	Filter FuncFilter
+	// Topics are the names or the regular expressions starting with '^'
+	Topics []string
+	// OnTopicMatched is called when the topics matched by the regular expressions of Topics are assigned for the first time
+	OnTopicMatched FuncOnTopicMatched
+	// Tracer creates a consume span of every message (the context of OnProcess contains the span)
+	Tracer *trace.Tracer
+	// Propagator extracts the span context from the message headers (trace.TraceContext by default)
+	Propagator trace.IPropagator
+	// TraceSampler decides the sampling of the consume span by the message (e.g. SampleByHeader)
+	TraceSampler FuncTraceSampler
+	// EnforceDeadline sets the deadline of the budget.Header header to the context of OnProcess
+	EnforceDeadline bool
+	// IDGenerator generates the identifiers of the consumers and the groups (idgen.Default by default)
+	IDGenerator idgen.IDGenerator
+	// ProfileLabels sets the pprof labels 'topic' and 'handler' (HandlerName or the function name)
+	ProfileLabels bool
+	HandlerName   string
+	// HealthTimeout is a max time without the activity of the event loop (5m by default, see Consumer.HealthCheck)
+	HealthTimeout time.Duration
+	// Clock is a source of the time of the tickers, the sleeps and the retries (clock.Real by default)
+	Clock clock.Clock
 }
 
 func NewConfig() *Config {
@@ -26,20 +114,134 @@ func NewConfig() *Config {
 func (c *Config) Check() error {
 
 	if c.OnError == nil {
-		return errors.New("on error callback is nil")
+		return configError("on error callback is nil")
 	}
 
-	if c.OnProcess == nil {
-		return errors.New("on process callback is nil")
+	if c.OnProcess == nil && c.OnProcessBatch == nil {
+		return configError("on process callback is nil")
+	}
+
+	if c.Workers > 1 && c.OnProcessBatch != nil {
+		return configError("workers can't be used in batch mode")
+	}
+
+	if c.KeyOrdering && c.Workers < 2 {
+		return configError("key ordering requires workers")
 	}
 
 	if len(c.Topics) == 0 {
-		return errors.New("topics is empty")
+		return configError("topics is empty")
+	}
+
+	if _, err := newTopicMatcher(c.Topics); err != nil {
+		return configError("invalid topic pattern: " + err.Error())
+	}
+
+	if c.ManualCommit && c.OnProcessBatch != nil {
+		return configError("manual commit can't be used in batch mode")
+	}
+
+	if c.EnforceDeadline && c.OnProcessBatch != nil {
+		return configError("deadline can't be enforced in batch mode")
+	}
+
+	if c.CommitStrategy < CommitSync || c.CommitStrategy > CommitPerPartition {
+		return configError("unknown commit strategy")
+	}
+
+	if c.CommitStrategy == CommitPerPartition && c.OnProcessBatch != nil {
+		return configError("per partition commit can't be used in batch mode")
+	}
+
+	if c.TokenProvider != nil && c.OnOAuthBearerTokenRefresh != nil {
+		return configError("token provider can't be used with token refresh callback")
+	}
+
+	if c.Poison != nil && c.Poison.MaxFailures <= 0 {
+		return configError("poison max failures must be positive")
+	}
+
+	if c.Poison != nil && c.OnProcessBatch != nil {
+		return configError("poison messages can't be skipped in batch mode")
+	}
+
+	if c.MaxMessagesPerSecond < 0 {
+		return configError("max messages per second is negative")
 	}
 
 	if c.ConfigMap == nil {
-		return errors.New("reader config is nil")
+		return configError("reader config is nil")
+	}
+
+	if strategy, _ := c.ConfigMap.Get("partition.assignment.strategy", ""); !c.CooperativeRebalance && strings.Contains(fmt.Sprint(strategy), "cooperative") {
+		return configError("cooperative assignment strategy requires cooperative rebalance")
 	}
 
 	return nil
 }
+
+// Clone returns a deep copy of the configuration
+func (c *Config) Clone() *Config {
+
+	retval := *c
+
+	if c.ConfigMap != nil {
+		configMap := make(kafka.ConfigMap, len(*c.ConfigMap))
+		for k, v := range *c.ConfigMap {
+			configMap[k] = v
+		}
+		retval.ConfigMap = &configMap
+	}
+
+	if c.Dedupe != nil {
+		dedupe := *c.Dedupe
+		retval.Dedupe = &dedupe
+	}
+
+	if c.Poison != nil {
+		poison := *c.Poison
+		retval.Poison = &poison
+	}
+
+	if c.Reassemble != nil {
+		reassemble := *c.Reassemble
+		retval.Reassemble = &reassemble
+	}
+
+	if c.Quotas != nil {
+		retval.Quotas = make(map[string]QuotaConfig, len(c.Quotas))
+		for k, v := range c.Quotas {
+			retval.Quotas[k] = v
+		}
+	}
+
+	if c.Retry != nil {
+		retry := *c.Retry
+		retval.Retry = &retry
+	}
+
+	if c.TopicWait != nil {
+		topicWait := *c.TopicWait
+		retval.TopicWait = &topicWait
+	}
+
+	if c.PartitionsWatch != nil {
+		partitionsWatch := *c.PartitionsWatch
+		retval.PartitionsWatch = &partitionsWatch
+	}
+
+	if c.Metrics != nil {
+		metrics := *c.Metrics
+		retval.Metrics = &metrics
+	}
+
+	if c.Transformers != nil {
+		retval.Transformers = append(make([]FuncTransform, 0, len(c.Transformers)), c.Transformers...)
+	}
+
+	if c.Topics != nil {
+		retval.Topics = append(make([]string, 0, len(c.Topics)), c.Topics...)
+	}
+
+	return &retval
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/metric/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
@@ -28,24 +29,162 @@ func TestConfigCheck(t *testing.T) {
 	require.EqualError(t,
 		(&Config{
 			OnError:   func(context.Context, *zap.Logger, error) {},
-			OnProcess: func(context.Context, *zap.Logger, *kafka.Message, ISleeper) error { return nil },
+			OnProcess: func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
 		}).Check(),
 		"topics is empty")
 
 	require.EqualError(t,
 		(&Config{
 			OnError:   func(context.Context, *zap.Logger, error) {},
-			OnProcess: func(context.Context, *zap.Logger, *kafka.Message, ISleeper) error { return nil },
+			OnProcess: func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
 			Topics:    []string{"a"},
 		}).Check(),
 		"reader config is nil")
 
+	require.EqualError(t,
+		(&Config{
+			OnError:   func(context.Context, *zap.Logger, error) {},
+			OnProcess: func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
+			Topics:    []string{"a", "^b-("},
+		}).Check(),
+		"invalid topic pattern: error parsing regexp: missing closing ): `^b-(`")
+
+	require.EqualError(t,
+		(&Config{
+			OnError:              func(context.Context, *zap.Logger, error) {},
+			OnProcess:            func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
+			Topics:               []string{"a"},
+			MaxMessagesPerSecond: -1,
+		}).Check(),
+		"max messages per second is negative")
+
+	require.EqualError(t,
+		(&Config{
+			OnError:   func(context.Context, *zap.Logger, error) {},
+			OnProcess: func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
+			Topics:    []string{"a"},
+			Poison:    &PoisonConfig{},
+		}).Check(),
+		"poison max failures must be positive")
+
+	require.EqualError(t,
+		(&Config{
+			OnError:        func(context.Context, *zap.Logger, error) {},
+			OnProcessBatch: func(context.Context, *zap.Logger, []*kafka.Message, ISleeper) error { return nil },
+			Topics:         []string{"a"},
+			Poison:         &PoisonConfig{MaxFailures: 1},
+		}).Check(),
+		"poison messages can't be skipped in batch mode")
+
+	require.EqualError(t,
+		(&Config{
+			OnError:        func(context.Context, *zap.Logger, error) {},
+			OnProcessBatch: func(context.Context, *zap.Logger, []*kafka.Message, ISleeper) error { return nil },
+			Topics:         []string{"a"},
+			ManualCommit:   true,
+		}).Check(),
+		"manual commit can't be used in batch mode")
+
+	require.EqualError(t,
+		(&Config{
+			OnError:         func(context.Context, *zap.Logger, error) {},
+			OnProcessBatch:  func(context.Context, *zap.Logger, []*kafka.Message, ISleeper) error { return nil },
+			Topics:          []string{"a"},
+			EnforceDeadline: true,
+		}).Check(),
+		"deadline can't be enforced in batch mode")
+
+	require.EqualError(t,
+		(&Config{
+			OnError:        func(context.Context, *zap.Logger, error) {},
+			OnProcess:      func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
+			Topics:         []string{"a"},
+			CommitStrategy: CommitStrategy(10),
+		}).Check(),
+		"unknown commit strategy")
+
+	require.EqualError(t,
+		(&Config{
+			OnError:        func(context.Context, *zap.Logger, error) {},
+			OnProcessBatch: func(context.Context, *zap.Logger, []*kafka.Message, ISleeper) error { return nil },
+			Topics:         []string{"a"},
+			CommitStrategy: CommitPerPartition,
+		}).Check(),
+		"per partition commit can't be used in batch mode")
+
+	require.EqualError(t,
+		(&Config{
+			OnError:     func(context.Context, *zap.Logger, error) {},
+			OnProcess:   func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
+			Topics:      []string{"a"},
+			Workers:     1,
+			KeyOrdering: true,
+		}).Check(),
+		"key ordering requires workers")
+
+	require.EqualError(t,
+		(&Config{
+			OnError:                   func(context.Context, *zap.Logger, error) {},
+			OnProcess:                 func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
+			Topics:                    []string{"a"},
+			TokenProvider:             func(context.Context) (kafka.OAuthBearerToken, error) { return kafka.OAuthBearerToken{}, nil },
+			OnOAuthBearerTokenRefresh: func(context.Context, *zap.Logger, *kafka.OAuthBearerTokenRefresh, kafka.Handle) {},
+		}).Check(),
+		"token provider can't be used with token refresh callback")
+
 	require.NoError(t,
 		(&Config{
 			OnError:   func(context.Context, *zap.Logger, error) {},
-			OnProcess: func(context.Context, *zap.Logger, *kafka.Message, ISleeper) error { return nil },
+			OnProcess: func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
 			Topics:    []string{"a"},
 			ConfigMap: &kafka.ConfigMap{"bootstrap.servers": "b1,b2,b3"},
 		}).Check())
 
+	require.EqualError(t,
+		(&Config{
+			OnError:   func(context.Context, *zap.Logger, error) {},
+			OnProcess: func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
+			Topics:    []string{"a"},
+			ConfigMap: &kafka.ConfigMap{"partition.assignment.strategy": "cooperative-sticky"},
+		}).Check(),
+		"cooperative assignment strategy requires cooperative rebalance")
+
+	require.NoError(t,
+		(&Config{
+			OnError:              func(context.Context, *zap.Logger, error) {},
+			OnProcess:            func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
+			Topics:               []string{"a"},
+			ConfigMap:            &kafka.ConfigMap{"partition.assignment.strategy": "cooperative-sticky"},
+			CooperativeRebalance: true,
+		}).Check())
+
+}
+
+func TestConfigClone(t *testing.T) {
+
+	src := &Config{
+		ConfigMap:         &kafka.ConfigMap{"bootstrap.servers": "b1,b2,b3"},
+		CommitOffsetCount: 10,
+		Metrics:           &Metrics{},
+		Poison:            &PoisonConfig{MaxFailures: 3},
+		Reassemble:        &ReassembleConfig{MaxChunks: 3},
+		Topics:            []string{"a"},
+	}
+
+	dst := src.Clone()
+	require.Equal(t, src, dst)
+
+	require.NoError(t, dst.ConfigMap.SetKey("client.id", "id"))
+	dst.Topics[0] = "b"
+	dst.Metrics.QueueDepth = mock.NewGauge()
+	dst.Poison.MaxFailures = 1
+	dst.Reassemble.MaxChunks = 1
+
+	require.Equal(t, &kafka.ConfigMap{"bootstrap.servers": "b1,b2,b3"}, src.ConfigMap)
+	require.Equal(t, []string{"a"}, src.Topics)
+	require.Nil(t, src.Metrics.QueueDepth)
+	require.Equal(t, 3, src.Poison.MaxFailures)
+	require.Equal(t, 3, src.Reassemble.MaxChunks)
+
+	require.Equal(t, &Config{}, (&Config{}).Clone())
 }
@@ -3,21 +3,28 @@ package consumer
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
-	"github.com/google/uuid"
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/reporter"
+	"github.com/dialogs/dialog-go-lib/trace"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
 
 type FuncOnError func(ctx context.Context, logger *zap.Logger, err error)
-type FuncOnProcess func(ctx context.Context, logger *zap.Logger, msg *kafka.Message, s ISleeper) error
+type FuncOnProcess func(ctx context.Context, logger *zap.Logger, msg *kafka.Message, s ISleeper, c ICommitter) error
+type FuncOnPreCommit func(ctx context.Context, logger *zap.Logger, offsets []kafka.TopicPartition) error
 type FuncOnCommit func(ctx context.Context, logger *zap.Logger, topic string, partition int32, offset kafka.Offset, committed int)
 type FuncOnRevoke func(ctx context.Context, logger *zap.Logger, topic []kafka.TopicPartition)
 type FuncOnRebalance func(ctx context.Context, logger *zap.Logger, topic []kafka.TopicPartition)
 
+// FuncOnAssign returns the start offsets of the assigned partitions (see Config.OnAssign)
+type FuncOnAssign func(ctx context.Context, logger *zap.Logger, partitions []kafka.TopicPartition) []kafka.TopicPartition
+
 var (
 	nopCommitFunc = func(ctx context.Context, logger *zap.Logger, topic string, partition int32, offset kafka.Offset, committed int) {
 	}
@@ -25,32 +32,138 @@ var (
 	nopOnRebalanceFunc = func(ctx context.Context, logger *zap.Logger, topic []kafka.TopicPartition) {}
 )
 
+// reader is the part of kafka.Consumer used by the Consumer
+type reader interface {
+	kafka.Handle
+	SubscribeTopics(topics []string, rebalanceCb kafka.RebalanceCb) error
+	Unsubscribe() error
+	Assign(partitions []kafka.TopicPartition) error
+	Unassign() error
+	IncrementalAssign(partitions []kafka.TopicPartition) error
+	IncrementalUnassign(partitions []kafka.TopicPartition) error
+	Assignment() ([]kafka.TopicPartition, error)
+	CommitOffsets(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error)
+	Committed(partitions []kafka.TopicPartition, timeoutMs int) ([]kafka.TopicPartition, error)
+	Position(partitions []kafka.TopicPartition) ([]kafka.TopicPartition, error)
+	Seek(partition kafka.TopicPartition, timeoutMs int) error
+	Pause(partitions []kafka.TopicPartition) error
+	Resume(partitions []kafka.TopicPartition) error
+	Events() chan kafka.Event
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error)
+	GetWatermarkOffsets(topic string, partition int32) (low, high int64, err error)
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error)
+	OffsetsForTimes(times []kafka.TopicPartition, timeoutMs int) ([]kafka.TopicPartition, error)
+	Close() error
+}
+
+type funcNewReader func(cfg *kafka.ConfigMap) (reader, error)
+
+func newKafkaReader(cfg *kafka.ConfigMap) (reader, error) {
+	return kafka.NewConsumer(cfg)
+}
+
 type Consumer struct {
 	observable
 
-	id                   uuid.UUID
-	commitOffsetCount    int
-	commitOffsetDuration time.Duration
-	ctx                  context.Context
-	ctxCancel            context.CancelFunc
-	logger               *zap.Logger
-	onCommit             FuncOnCommit
-	onError              FuncOnError
-	onProcess            FuncOnProcess
-	onRevoke             FuncOnRevoke
-	onRebalance          FuncOnRebalance
-	reader               *kafka.Consumer
-	topics               []string
-	wg                   sync.WaitGroup
-	mu                   sync.RWMutex
+	id                        string
+	cfg                       *Config
+	userCfg                   *Config
+	baseLogger                *zap.Logger
+	commitOffsetCount         int
+	commitStrategy            CommitStrategy
+	asyncCommit               chan struct{}
+	commitOffsetDuration      time.Duration
+	commitTimeout             time.Duration
+	commitRequests            chan chan error
+	requests                  chan loopRequest
+	seeks                     []kafka.TopicPartition
+	seeksMu                   sync.Mutex
+	throttleBackoffFactor     float64
+	throttleBackoffMax        time.Duration
+	stopErr                   error
+	finalCommit               chan struct{}
+	ctx                       context.Context
+	ctxCancel                 context.CancelFunc
+	loopCtx                   context.Context
+	loopCancel                context.CancelFunc
+	logger                    *zap.Logger
+	onCommit                  FuncOnCommit
+	onError                   FuncOnError
+	onEvent                   FuncOnEvent
+	onHeartbeat               FuncOnHeartbeat
+	heartbeatInterval         time.Duration
+	onLag                     FuncOnLag
+	lagInterval               time.Duration
+	health                    health
+	healthTimeout             time.Duration
+	onStats                   FuncOnStats
+	onThrottle                FuncOnThrottle
+	onOAuthBearerTokenRefresh FuncOnOAuthBearerTokenRefresh
+	onPreCommit               FuncOnPreCommit
+	onProcess                 FuncOnProcess
+	onProcessBatch            FuncOnProcessBatch
+	batch                     *batch
+	workers                   int
+	workerQueueSize           int
+	keyOrdering               bool
+	cooperative               bool
+	pool                      *workerPool
+	onRevoke                  FuncOnRevoke
+	onRebalance               FuncOnRebalance
+	onAssign                  FuncOnAssign
+	reader                    reader
+	newReader                 funcNewReader
+	sleeps                    *sleeps
+	pauses                    *pauses
+	inflight                  *inflight
+	sleepStore                ISleepStore
+	sleepCheckInterval        time.Duration
+	sleepAllUntil             time.Time
+	sleepAllMu                sync.RWMutex
+	reassembler               *reassembler
+	transformers              []FuncTransform
+	filter                    FuncFilter
+	dedupe                    *dedupe
+	retrier                   *retrier
+	poison                    *poison
+	manualCommit              bool
+	marked                    *offset
+	quotas                    *quotas
+	rateLimiter               *rateLimiter
+	clock                     clock.Clock
+	topics                    []string
+	matcher                   *topicMatcher
+	onTopicMatched            FuncOnTopicMatched
+	topicWait                 *TopicWaitConfig
+	onTopicChange             FuncOnTopicChange
+	onPartitionsChange        FuncOnPartitionsChange
+	partitionsWatch           *PartitionsWatchConfig
+	tracer                    *trace.Tracer
+	propagator                trace.IPropagator
+	labels                    *labels
+	traceSampler              FuncTraceSampler
+	enforceDeadline           bool
+	history                   *history
+	readerClosed              bool
+	readerMu                  sync.RWMutex
+	wg                        sync.WaitGroup
+	mu                        sync.RWMutex
 }
 
 func New(cfg *Config, logger *zap.Logger) (*Consumer, error) {
+	return newWithReader(cfg, logger, newKafkaReader)
+}
+
+func newWithReader(cfg *Config, logger *zap.Logger, newReader funcNewReader) (*Consumer, error) {
 
 	if err := cfg.Check(); err != nil {
 		return nil, err
 	}
 
+	// the configuration is modified below and can be shared between consumers (see Group)
+	cfg = cfg.Clone()
+	userCfg := cfg.Clone()
+
 	onCommit := nopCommitFunc
 	if cfg.OnCommit != nil {
 		onCommit = cfg.OnCommit
@@ -66,7 +179,61 @@ func New(cfg *Config, logger *zap.Logger) (*Consumer, error) {
 		onRebalance = cfg.OnRebalance
 	}
 
-	id, err := uuid.NewUUID()
+	// the recent commits and errors are kept for the snapshot
+	history := newHistory()
+	onCommit = history.wrapCommit(onCommit)
+
+	onOAuthBearerTokenRefresh := cfg.OnOAuthBearerTokenRefresh
+	if cfg.TokenProvider != nil {
+		onOAuthBearerTokenRefresh = newTokenRefreshHandler(cfg.TokenProvider)
+	}
+
+	commitTimeout := cfg.CommitTimeout
+	if commitTimeout <= 0 {
+		commitTimeout = time.Second * 10
+	}
+
+	throttleBackoffMax := cfg.ThrottleBackoffMax
+	if throttleBackoffMax <= 0 {
+		throttleBackoffMax = time.Second * 30
+	}
+
+	heartbeatInterval := cfg.HeartbeatInterval
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = time.Second * 30
+	}
+
+	lagInterval := cfg.LagInterval
+	if lagInterval <= 0 {
+		lagInterval = time.Minute
+	}
+
+	healthTimeout := cfg.HealthTimeout
+	if healthTimeout <= 0 {
+		healthTimeout = time.Minute * 5
+	}
+
+	sleepCheckInterval := cfg.SleepCheckInterval
+	if sleepCheckInterval <= 0 {
+		sleepCheckInterval = time.Second
+	}
+
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.Real
+	}
+
+	matcher, err := newTopicMatcher(cfg.Topics)
+	if err != nil {
+		return nil, err
+	}
+
+	propagator := cfg.Propagator
+	if propagator == nil && cfg.Tracer != nil {
+		propagator = trace.TraceContext{}
+	}
+
+	id, err := newID(cfg.IDGenerator)
 	if err != nil {
 		return nil, err
 	}
@@ -81,7 +248,9 @@ func New(cfg *Config, logger *zap.Logger) (*Consumer, error) {
 		"go.application.rebalance.enable": true,
 		// https://docs.confluent.io/3.3.1/clients/librdkafka/CONFIGURATION_8md.html
 		"api.version.request": "true",
-		"client.id":           id.String(),
+	}
+	if cfg.CooperativeRebalance {
+		requiredProps["partition.assignment.strategy"] = "cooperative-sticky"
 	}
 	for k, v := range requiredProps {
 		if err := cfg.ConfigMap.SetKey(k, v); err != nil {
@@ -89,37 +258,118 @@ func New(cfg *Config, logger *zap.Logger) (*Consumer, error) {
 		}
 	}
 
-	logger = logger.With(zap.String("consumer", id.String()))
+	if err := setStatsInterval(cfg); err != nil {
+		return nil, err
+	}
+
+	if _, ok := (*cfg.ConfigMap)["client.id"]; !ok {
+		if err := cfg.ConfigMap.SetKey("client.id", id); err != nil {
+			return nil, errors.Wrapf(err, "set config client.id to %v failed", id)
+		}
+	}
 
-	ctx, ctxCancel := context.WithCancel(context.Background())
+	baseLogger := logger
+	logger = logger.With(zap.String("consumer", id))
 
-	reader, err := kafka.NewConsumer(cfg.ConfigMap)
+	groupID, _ := cfg.ConfigMap.Get("group.id", "")
+
+	// the tags of the errors reported by OnError (see reporter.OnError)
+	ctx, ctxCancel := context.WithCancel(reporter.ContextWithTags(context.Background(), map[string]string{
+		"kafka.consumer": id,
+		"kafka.group":    fmt.Sprint(groupID),
+		"kafka.topics":   strings.Join(cfg.Topics, ","),
+	}))
+
+	reader, err := newReader(cfg.ConfigMap)
 	if err != nil {
 		defer ctxCancel()
 		return nil, errors.Wrap(err, "create reader failed")
 	}
 
+	// the event loop is stopped before the processing by StopWithTimeout
+	loopCtx, loopCancel := context.WithCancel(ctx)
+
 	return &Consumer{
-		id:                   id,
-		ctx:                  ctx,
-		ctxCancel:            ctxCancel,
-		logger:               logger,
-		onCommit:             onCommit,
-		onRevoke:             onRevoke,
-		onRebalance:          onRebalance,
-		onError:              cfg.OnError,
-		onProcess:            cfg.OnProcess,
-		reader:               reader,
-		topics:               cfg.Topics,
-		commitOffsetCount:    cfg.CommitOffsetCount,
-		commitOffsetDuration: cfg.CommitOffsetDuration,
-		observable:           *newObservable(),
+		id:                        id,
+		cfg:                       cfg,
+		userCfg:                   userCfg,
+		baseLogger:                baseLogger,
+		ctx:                       ctx,
+		ctxCancel:                 ctxCancel,
+		loopCtx:                   loopCtx,
+		loopCancel:                loopCancel,
+		logger:                    logger,
+		onCommit:                  onCommit,
+		onRevoke:                  onRevoke,
+		onRebalance:               onRebalance,
+		onAssign:                  cfg.OnAssign,
+		onError:                   history.wrapError(cfg.OnError),
+		history:                   history,
+		onEvent:                   cfg.OnEvent,
+		onHeartbeat:               cfg.OnHeartbeat,
+		heartbeatInterval:         heartbeatInterval,
+		onLag:                     cfg.OnLag,
+		lagInterval:               lagInterval,
+		healthTimeout:             healthTimeout,
+		onStats:                   cfg.OnStats,
+		onThrottle:                cfg.OnThrottle,
+		onOAuthBearerTokenRefresh: onOAuthBearerTokenRefresh,
+		onPreCommit:               cfg.OnPreCommit,
+		onProcess:                 cfg.OnProcess,
+		onProcessBatch:            cfg.OnProcessBatch,
+		batch:                     newBatch(cfg),
+		workers:                   cfg.Workers,
+		workerQueueSize:           cfg.WorkerQueueSize,
+		keyOrdering:               cfg.KeyOrdering,
+		cooperative:               cfg.CooperativeRebalance,
+		reader:                    reader,
+		newReader:                 newReader,
+		sleeps:                    newSleeps(),
+		pauses:                    newPauses(),
+		inflight:                  newInflight(cfg.Metrics),
+		sleepStore:                cfg.SleepStore,
+		sleepCheckInterval:        sleepCheckInterval,
+		topics:                    cfg.Topics,
+		matcher:                   matcher,
+		onTopicMatched:            cfg.OnTopicMatched,
+		topicWait:                 cfg.TopicWait,
+		onTopicChange:             cfg.OnTopicChange,
+		onPartitionsChange:        cfg.OnPartitionsChange,
+		partitionsWatch:           cfg.PartitionsWatch,
+		tracer:                    cfg.Tracer,
+		propagator:                propagator,
+		labels:                    newLabels(cfg),
+		traceSampler:              cfg.TraceSampler,
+		enforceDeadline:           cfg.EnforceDeadline,
+		reassembler:               newReassembler(cfg.Reassemble, clk),
+		transformers:              cfg.Transformers,
+		filter:                    cfg.Filter,
+		dedupe:                    newDedupe(cfg.Dedupe, clk),
+		retrier:                   newRetrier(cfg.Retry, clk),
+		poison:                    newPoison(cfg.Poison),
+		manualCommit:              cfg.ManualCommit,
+		marked:                    newOffset(),
+		quotas:                    newQuotas(cfg.Quotas, clk),
+		rateLimiter:               newRateLimiter(cfg.MaxMessagesPerSecond, cfg.MaxMessagesBurst, clk),
+		clock:                     clk,
+		commitOffsetCount:         cfg.CommitOffsetCount,
+		commitStrategy:            cfg.CommitStrategy,
+		commitOffsetDuration:      cfg.CommitOffsetDuration,
+		commitTimeout:             commitTimeout,
+		commitRequests:            make(chan chan error),
+		requests:                  make(chan loopRequest, 1),
+		throttleBackoffFactor:     cfg.ThrottleBackoffFactor,
+		throttleBackoffMax:        throttleBackoffMax,
+		observable:                *newObservable(),
 	}, nil
 }
 
 func (c *Consumer) Start() error {
 
-	defer func() { c.observable.notify(StateClosed) }()
+	defer func() {
+		c.health.SetState(healthClosed, c.clock.Now())
+		c.observable.notify(StateClosed)
+	}()
 
 	c.mu.Lock()
 	defer func() {
@@ -132,82 +382,450 @@ func (c *Consumer) Start() error {
 
 	c.logger.Info("start")
 	defer func() {
-		if err := recover(); err != nil {
-			c.logger.Error("panic:" + fmt.Sprintln(err))
+		if val := recover(); val != nil {
+			c.reportPanic(val)
 		}
 	}()
 
 	select {
 	case <-c.ctx.Done():
 		// protection for error of double closing: 'fatal error: unexpected signal during runtime execution'
-		err := errors.New("consumer already closed")
+		err := ErrAlreadyClosed
 		c.logger.Error("failed to start consumer", zap.Error(err))
 		return err
 	default:
 		// ok
 	}
 
+	c.health.SetState(healthRunning, c.clock.Now())
 	c.observable.notify(StateRun)
 
 	return c.listen()
 }
 
-func (c *Consumer) Stop() {
+// Restart stops the consumer and creates a new one with the same configuration and observers
+func (c *Consumer) Restart() (*Consumer, error) {
+
+	if err := c.Stop(); err != nil {
+		c.logger.Warn("failed to stop before restart", zap.Error(err))
+	}
+
+	// the properties set by New (e.g. client.id) are generated again
+	retval, err := newWithReader(c.userCfg, c.baseLogger, c.newReader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to restart consumer")
+	}
+
+	c.observable.mu.RLock()
+	retval.AddStateObserver(c.observable.observers...)
+	c.observable.mu.RUnlock()
+
+	// the failures of the messages are counted after the restart
+	if retval.poison != nil && c.poison != nil {
+		retval.poison = c.poison
+	}
+
+	c.logger.Info("restarted", zap.String("new consumer", retval.id))
+
+	return retval, nil
+}
+
+// Stop closes the consumer and returns the result of the last offsets commit
+func (c *Consumer) Stop() error {
+	return c.StopContext(context.Background())
+}
+
+// StopContext is Stop until the context is done (the closing continues in background)
+func (c *Consumer) StopContext(ctx context.Context) error {
 
 	c.ctxCancel()
 
-	c.mu.Lock() // protection for WaitGroup data race
-	defer c.mu.Unlock()
+	select {
+	case err := <-c.waitClosed():
+		return err
+	case <-ctx.Done():
+		c.logger.Warn("stop is not completed", zap.Error(ctx.Err()))
+		return errors.Wrap(ctx.Err(), "failed to wait for consumer closing")
+	}
+}
+
+// StopWithTimeout commits the in-flight messages before closing (ErrDrainTimeout after the timeout)
+func (c *Consumer) StopWithTimeout(timeout time.Duration) error {
+
+	ctx, cancel := clock.WithTimeout(context.Background(), c.clock, timeout)
+	defer cancel()
 
-	c.wg.Wait()
+	return c.Drain(ctx)
 }
 
+// Drain is StopWithTimeout until the context is done
+func (c *Consumer) Drain(ctx context.Context) error {
+
+	c.loopCancel()
+
+	done := c.waitClosed()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	c.logger.Warn("drain is not completed, processing is cancelled", zap.Error(ctx.Err()))
+	c.ctxCancel()
+
+	if err := <-done; err != nil {
+		return wrapSentinel(ErrDrainTimeout, err)
+	}
+
+	return ErrDrainTimeout
+}
+
+func (c *Consumer) waitClosed() <-chan error {
+
+	done := make(chan error, 1)
+	go func() {
+		c.mu.Lock() // protection for WaitGroup data race
+		defer c.mu.Unlock()
+
+		c.wg.Wait()
+		done <- c.stopErr
+	}()
+
+	return done
+}
+
+func (c *Consumer) closeReader() {
+
+	c.readerMu.Lock()
+	c.readerClosed = true
+	c.readerMu.Unlock()
+
+	if err := c.reader.Close(); err != nil {
+		c.logger.Error("failed to close reader", zap.Error(err))
+	}
+}
+
+// Sleep pauses the partitions and resumes them after the delay
 func (c *Consumer) Sleep(delay time.Duration, partitions []kafka.TopicPartition) error {
+	ctx, cancel := clock.WithTimeout(c.ctx, c.clock, delay)
+	return c.sleep(ctx, cancel, nil, partitions)
+}
+
+// SleepContext pauses the partitions for the delay or until the context is done, the returned function resumes them
+func (c *Consumer) SleepContext(ctx context.Context, delay time.Duration, partitions []kafka.TopicPartition) (context.CancelFunc, error) {
+
+	ctx, cancel := clock.WithTimeout(ctx, c.clock, delay)
+	return cancel, c.sleep(ctx, cancel, nil, partitions)
+}
+
+// SleepUntil pauses the partitions until the condition is satisfied or the context is done
+func (c *Consumer) SleepUntil(ctx context.Context, condition FuncSleepCondition, partitions []kafka.TopicPartition) error {
+	ctx, cancel := context.WithCancel(ctx)
+	return c.sleep(ctx, cancel, condition, partitions)
+}
+
+// SleepAll pauses all assigned partitions for the delay (including the ones assigned during the delay)
+func (c *Consumer) SleepAll(delay time.Duration) error {
+
+	until := c.clock.Now().Add(delay)
+
+	c.sleepAllMu.Lock()
+	c.sleepAllUntil = until
+	c.sleepAllMu.Unlock()
+
+	partitions, err := c.reader.Assignment()
+	if err != nil {
+		c.logger.Warn("failed to get assignment", zap.Error(err))
+		return err
+	}
+
+	ctx, cancel := clock.WithDeadline(c.ctx, c.clock, until)
+	return c.sleep(ctx, cancel, nil, partitions)
+}
+
+// SleepStatus returns the end of the sleep started by SleepAll
+func (c *Consumer) SleepStatus() (until time.Time, sleeping bool) {
+
+	c.sleepAllMu.RLock()
+	until = c.sleepAllUntil
+	c.sleepAllMu.RUnlock()
+
+	return until, until.After(c.clock.Now())
+}
+
+// InFlight returns a snapshot of the partitions with messages in processing or paused
+func (c *Consumer) InFlight() []PartitionState {
+
+	retval := c.inflight.Snapshot()
+
+	index := make(map[string]int, len(retval))
+	for i := range retval {
+		index[getPartitionKey(&retval[i].Topic, retval[i].Partition)] = i
+	}
+
+	for _, p := range c.sleeps.List() {
+		if i, ok := index[getPartitionKey(p.Topic, p.Partition)]; ok {
+			retval[i].Paused = true
+			continue
+		}
+
+		var topic string
+		if p.Topic != nil {
+			topic = *p.Topic
+		}
+
+		retval = append(retval, PartitionState{Topic: topic, Partition: p.Partition, Paused: true})
+	}
+
+	return retval
+}
+
+// SleepingPartitions returns the partitions paused by the sleeps and by Pause
+func (c *Consumer) SleepingPartitions() []SleepingPartition {
+	return c.sleeps.Sleeping()
+}
+
+// ResumeAll cancels all sleeps including the one started by SleepAll and the pauses (see Pause)
+func (c *Consumer) ResumeAll() error {
+
+	c.sleepAllMu.Lock()
+	c.sleepAllUntil = time.Time{}
+	c.sleepAllMu.Unlock()
+
+	c.pauses.Clear()
+
+	return c.CancelSleep(c.sleeps.List())
+}
+
+// Commit commits offsets of the processed messages
+func (c *Consumer) Commit() error {
+
+	res := make(chan error, 1)
+
+	select {
+	case <-c.ctx.Done():
+		return ErrAlreadyClosed
+	case c.commitRequests <- res:
+	}
+
+	select {
+	case <-c.ctx.Done():
+		return ErrAlreadyClosed
+	case err := <-res:
+		return err
+	}
+}
+
+// Resubscribe unsubscribes and subscribes to the topics again to trigger a rebalance
+func (c *Consumer) Resubscribe() error {
+
+	c.logger.Info("resubscribe")
+
+	if err := c.reader.Unsubscribe(); err != nil {
+		return wrapSentinel(ErrUnsubscribeFailed, err)
+	}
+
+	if err := c.reader.SubscribeTopics(c.topics, nil); err != nil {
+		return wrapSentinel(ErrSubscribeFailed, err)
+	}
+
+	return nil
+}
+
+// CancelSleep resumes the paused partitions before the end of sleep
+func (c *Consumer) CancelSleep(partitions []kafka.TopicPartition) error {
+	list := c.sleeps.Cancel(partitions...)
+	if len(list) == 0 {
+		return nil
+	}
+
+	c.deleteSleepState(list)
+	c.inflight.SetPaused(false, list...)
+
+	return c.resume(list)
+}
+
+func (c *Consumer) sleep(ctx context.Context, cancel context.CancelFunc, condition FuncSleepCondition, partitions []kafka.TopicPartition) error {
 	if len(partitions) == 0 {
+		cancel()
 		return nil
 	}
+
 	err := c.reader.Pause(partitions)
 	if err != nil {
+		cancel()
 		c.logger.With(
 			zap.Any("partitions", partitions),
 		).Warn("failed to pause consumer", zap.Error(err))
 		return err
 	}
 
+	entry := &sleepEntry{cancel: cancel}
+	if until, ok := ctx.Deadline(); ok {
+		entry.until = until
+	}
+
+	// the partitions of the longer sleeps are resumed by them
+	added := c.sleeps.Add(entry, partitions...)
+	if len(added) == 0 {
+		cancel()
+		return nil
+	}
+	c.inflight.SetPaused(true, added...)
+
+	if !entry.until.IsZero() {
+		c.saveSleepState(entry.until, added)
+	}
+
 	go func() {
-		time.Sleep(delay)
-		select {
-		case <-c.ctx.Done():
-			c.logger.Warn("service already stopped")
-		default:
-			err := c.reader.Resume(partitions)
-			if err != nil {
-				c.logger.With(
-					zap.Any("partitions", partitions),
-				).Warn("failed to resume consumer", zap.Error(err))
-			}
+		defer cancel()
+
+		var check <-chan time.Time
+		if condition != nil {
+			ticker := c.clock.NewTicker(c.sleepCheckInterval)
+			defer ticker.Stop()
+			check = ticker.C()
+		}
 
-			//Resume doesn't return error if broker is unavailable, that's why we try to get metadata
-			for _, partition := range partitions {
-				_, err = c.reader.GetMetadata(partition.Topic, false, 2000)
-				if err != nil {
-					c.logger.With(
-						zap.String("topic", *partition.Topic),
-						zap.Int32("partition", partition.Partition),
-					).Warn("may be partition haven't been resumed", zap.Error(err))
+	loop:
+		for {
+			select {
+			case <-c.ctx.Done():
+				c.logger.Warn("service already stopped")
+				return
+			case <-ctx.Done():
+				break loop
+			case <-check:
+				if condition(ctx) {
+					break loop
 				}
 			}
 		}
+
+		c.readerMu.RLock()
+		defer c.readerMu.RUnlock()
+
+		if c.readerClosed || c.ctx.Err() != nil {
+			// the consumer is stopped: the sleep state is kept for the restart
+			return
+		}
+
+		if list := c.keepPaused(c.sleeps.Remove(entry, partitions...)); len(list) > 0 {
+			c.deleteSleepState(list)
+			c.inflight.SetPaused(false, list...)
+			_ = c.resume(list)
+		}
 	}()
 
 	return nil
 }
 
+func (c *Consumer) restoreSleep(partitions []kafka.TopicPartition) {
+	if c.sleepStore == nil {
+		return
+	}
+
+	opLog := c.logger.With(zap.String("operation", "restore sleep"))
+
+	states, err := c.sleepStore.Load(c.ctx, partitions)
+	if err != nil {
+		opLog.Error("failed to load sleep state", zap.Error(err))
+		c.onError(c.ctx, opLog, err)
+		return
+	}
+
+	now := c.clock.Now()
+	expired := make([]kafka.TopicPartition, 0)
+	for i := range states {
+		item := &states[i]
+		if !item.Until.After(now) {
+			expired = append(expired, item.Partition)
+			continue
+		}
+
+		ctx, cancel := clock.WithDeadline(context.Background(), c.clock, item.Until)
+		if err := c.sleep(ctx, cancel, nil, []kafka.TopicPartition{item.Partition}); err != nil {
+			opLog.Error("failed to restore sleep", zap.Any("partition", item.Partition), zap.Error(err))
+			c.onError(c.ctx, opLog, err)
+			continue
+		}
+
+		opLog.Info("success", zap.Any("partition", item.Partition), zap.Time("until", item.Until))
+	}
+
+	c.deleteSleepState(expired)
+}
+
+func (c *Consumer) saveSleepState(until time.Time, partitions []kafka.TopicPartition) {
+	if c.sleepStore == nil || len(partitions) == 0 {
+		return
+	}
+
+	if err := c.sleepStore.Save(c.ctx, until, partitions); err != nil {
+		opLog := c.logger.With(zap.String("operation", "save sleep state"), zap.Any("partitions", partitions))
+		opLog.Error("failed to save", zap.Error(err))
+		c.onError(c.ctx, opLog, err)
+	}
+}
+
+func (c *Consumer) deleteSleepState(partitions []kafka.TopicPartition) {
+	if c.sleepStore == nil || len(partitions) == 0 {
+		return
+	}
+
+	if err := c.sleepStore.Delete(c.ctx, partitions); err != nil {
+		opLog := c.logger.With(zap.String("operation", "delete sleep state"), zap.Any("partitions", partitions))
+		opLog.Error("failed to delete", zap.Error(err))
+		c.onError(c.ctx, opLog, err)
+	}
+}
+
+func (c *Consumer) resume(partitions []kafka.TopicPartition) error {
+
+	select {
+	case <-c.ctx.Done():
+		c.logger.Warn("service already stopped")
+		return nil
+	default:
+		// ok
+	}
+
+	err := c.reader.Resume(partitions)
+	if err != nil {
+		c.logger.With(
+			zap.Any("partitions", partitions),
+		).Warn("failed to resume consumer", zap.Error(err))
+		return err
+	}
+
+	//Resume doesn't return error if broker is unavailable, that's why we try to get metadata
+	for _, partition := range partitions {
+		_, err = c.reader.GetMetadata(partition.Topic, false, 2000)
+		if err != nil {
+			c.logger.With(
+				zap.String("topic", *partition.Topic),
+				zap.Int32("partition", partition.Partition),
+			).Warn("may be partition haven't been resumed", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
 func (c *Consumer) listen() error {
 	c.logger.Info("start listener")
+
+	watcher := c.newTopicWatcher()
+	if c.topicWait != nil {
+		if err := watcher.wait(c.ctx, c.logger, c.topicWait); err != nil {
+			return err
+		}
+	}
+
 	err := c.reader.SubscribeTopics(c.topics, nil)
 	if err != nil {
-		return errors.Wrap(err, "subscribe to topics failed")
+		return wrapSentinel(ErrSubscribeFailed, err)
 	}
 
 	defer func() {
@@ -219,89 +837,70 @@ func (c *Consumer) listen() error {
 		// https://github.com/confluentinc/confluent-kafka-go/issues/189
 		defer c.logger.Info("success closed")
 
+		c.waitFinalCommit()
+
 		if errUnsubscribe := c.reader.Unsubscribe(); errUnsubscribe != nil {
 			c.logger.Error("failed to unsubscribe", zap.Error(errUnsubscribe))
-		} else if errUnassign := c.reader.Unassign(); errUnassign != nil {
+		} else if errUnassign := c.unassignAll(); errUnassign != nil {
 			c.logger.Error("failed to unassign", zap.Error(errUnassign))
 		}
 
-		if err := c.reader.Close(); err != nil {
-			c.logger.Error("failed to close reader", zap.Error(err))
-		}
+		c.closeReader()
 	}()
 
-	consumerOffsets := newOffset()
-	defer c.commitOffsets(consumerOffsets)
-
 	commitOffsetDuration := c.commitOffsetDuration
 	if commitOffsetDuration <= 0 {
 		commitOffsetDuration = time.Second * 5
 	}
 
-	offsetsTicker := time.NewTicker(commitOffsetDuration)
-	defer offsetsTicker.Stop()
-
-	for {
-		select {
-		case <-c.ctx.Done():
-			return nil
+	stopHeartbeat := c.startHeartbeat()
+	defer stopHeartbeat()
 
-		case <-offsetsTicker.C:
-			c.commitOffsets(consumerOffsets)
+	stopLagMonitor := c.startLagMonitor()
+	defer stopLagMonitor()
 
-		case ev := <-c.reader.Events():
+	stopTopicWatch := c.startTopicWatch(watcher)
+	defer stopTopicWatch()
 
-			switch e := ev.(type) {
-			case kafka.AssignedPartitions:
-				// consumer group rebalance event: assigned partition set
-				if err := c.handleRebalance(&e, consumerOffsets); err != nil {
-					return err
-				}
-
-			case kafka.RevokedPartitions:
-				// consumer group rebalance event: revoked partition set
-				if err := c.handleRevoke(&e, consumerOffsets); err != nil {
-					return err
-				}
+	if c.workers > 1 {
+		if c.keyOrdering {
+			c.pool = newKeyedWorkerPool(c.workers, c.workerQueueSize, c.processItem)
+		} else {
+			c.pool = newWorkerPool(c.workers, c.workerQueueSize, c.processItem)
+		}
+		c.pool.onPanic = c.reportPanic
+		// the workers are stopped after the final commit
+		defer c.pool.Close()
+	}
 
-			case *kafka.Message:
-				if err := c.handleMessage(e, consumerOffsets); err != nil {
-					return err
-				}
+	return runEventLoop(c.loopCtx, c.clock, c.reader.Events(), c.commitRequests, c.requests, commitOffsetDuration, c.tickDuration(), c)
+}
 
-			case kafka.PartitionEOF:
-				// consumer reached end of partition
-				// Needs to be explicitly enabled by setting the `enable.partition.eof`
-				// configuration property to true.
-				if err := c.handlePartitionEOF(&e, consumerOffsets); err != nil {
-					return err
-				}
+func (c *Consumer) handleEvent(ev kafka.Event) {
 
-			case kafka.OffsetsCommitted:
-				// reports committed offsets
-				// https://godoc.org/github.com/confluentinc/confluent-kafka-go/kafka#hdr-Consumer_events
-				// Offset commit results (when `enable.auto.commit` is enabled)
-				if err := c.handleOffsetCommitted(&e, consumerOffsets); err != nil {
-					return err
-				}
+	c.health.Touch(c.clock.Now())
 
-			case kafka.Error:
-				// Errors should generally be considered as informational, the client will try to automatically recover
-				c.logger.Error("error event", zap.Error(e))
-				c.onError(c.ctx, c.logger, e)
+	if c.onEvent != nil {
+		c.onEvent(c.ctx, c.logger, ev)
+	}
+}
 
-			default:
-				c.logger.Error("unknown event", zap.Any("payload", e))
-			}
+func (c *Consumer) handleError(e kafka.Error) {
+	// Errors should generally be considered as informational, the client will try to automatically recover
+	c.logger.Error("error event", zap.Error(e))
+	c.onError(c.ctx, c.logger, e)
+}
 
-		}
+func (c *Consumer) handleUnknown(e kafka.Event) {
+	if c.onEvent == nil {
+		c.logger.Error("unknown event", zap.Any("payload", e))
 	}
 }
 
 func (c *Consumer) handleRebalance(e *kafka.AssignedPartitions, consumerOffsets *offset) error {
 
-	c.commitOffsets(consumerOffsets)
-	consumerOffsets.Clear()
+	_ = c.commitOffsets(consumerOffsets)
+	c.clearOffsets(consumerOffsets, e.Partitions)
 
 	opLog := c.logger.With(zap.String("operation", "rebalance"), zap.Any("event", e))
 
@@ -329,12 +928,34 @@ func (c *Consumer) handleRebalance(e *kafka.AssignedPartitions, consumerOffsets
 		}
 	}
 
-	if err := c.reader.Assign(committedOffsets); err != nil {
+	if c.onAssign != nil {
+		committedOffsets = c.onAssign(c.ctx, opLog, committedOffsets)
+	}
+
+	if err := c.assign(committedOffsets); err != nil {
 		opLog.Error("failed to set assigned", zap.Error(err))
 		c.onError(c.ctx, opLog, err)
 		return err
 	}
 
+	c.restoreSleep(committedOffsets)
+
+	if err := c.restorePauses(committedOffsets); err != nil {
+		opLog.Error("failed to pause assigned partitions", zap.Error(err))
+		c.onError(c.ctx, opLog, err)
+	}
+
+	if until, sleeping := c.SleepStatus(); sleeping {
+		ctx, cancel := clock.WithDeadline(context.Background(), c.clock, until)
+		if err := c.sleep(ctx, cancel, nil, committedOffsets); err != nil {
+			opLog.Error("failed to pause assigned partitions", zap.Error(err))
+			c.onError(c.ctx, opLog, err)
+		}
+	}
+
+	c.handleMatchedTopics(opLog, e.Partitions)
+
+	c.inflight.Rebalanced(true)
 	c.onRebalance(c.ctx, opLog, e.Partitions)
 	opLog.Info("success")
 
@@ -343,8 +964,23 @@ func (c *Consumer) handleRebalance(e *kafka.AssignedPartitions, consumerOffsets
 
 func (c *Consumer) handleRevoke(e *kafka.RevokedPartitions, consumerOffsets *offset) error {
 
-	c.commitOffsets(consumerOffsets)
-	consumerOffsets.Clear()
+	if err := c.flushBatch(consumerOffsets); err != nil {
+		return err
+	}
+
+	if c.pool != nil {
+		c.pool.Drain()
+		if err := c.pool.Err(); err != nil {
+			return err
+		}
+		c.pool.Reset(e.Partitions)
+	}
+
+	// the failed commit is reported by OnError and isn't fatal: the partitions are unassigned anyway
+	// and the processed messages aren't committed, so they're redelivered to the next owner
+	_ = c.commitOffsets(consumerOffsets)
+	c.clearOffsets(consumerOffsets, e.Partitions)
+	c.reassembler.Drop(e.Partitions)
 
 	opLog := c.logger.With(zap.String("operation", "revoked"), zap.Any("event", e))
 
@@ -354,12 +990,16 @@ func (c *Consumer) handleRevoke(e *kafka.RevokedPartitions, consumerOffsets *off
 		return err
 	}
 
-	if err := c.reader.Unassign(); err != nil {
+	if err := c.unassign(e.Partitions); err != nil {
 		opLog.Error("failed to unassign", zap.Error(err))
 		c.onError(c.ctx, opLog, err)
 		return err
 	}
 
+	// pause intents are kept in the sleep store and restored after the next assignment
+	c.inflight.SetPaused(false, c.sleeps.Cancel(e.Partitions...)...)
+
+	c.inflight.Rebalanced(false)
 	c.onRevoke(c.ctx, opLog, e.Partitions)
 	opLog.Info("success")
 
@@ -376,26 +1016,112 @@ func (c *Consumer) handleMessage(e *kafka.Message, consumerOffsets *offset) erro
 		return err
 	}
 
-	if err := c.onProcess(c.ctx, opLog, e, c); err != nil {
-		opLog.Error("failed to process message", zap.Error(err))
+	if c.pool != nil {
+		if err := c.pool.Err(); err != nil {
+			return err
+		}
+	}
+
+	if !c.limitRate(opLog) {
+		// the offset isn't stored: the message is consumed again after the restart
+		return nil
+	}
+
+	c.throttleTopic(opLog, e.TopicPartition)
+
+	if c.filter != nil && !c.filter(e) {
+		opLog.Debug("skipped by filter")
+		return c.skip(opLog, e.TopicPartition, consumerOffsets)
+	}
+
+	var key string
+	if c.dedupe != nil {
+		key = dedupeKey(e)
+		if c.dedupe.Seen(key) {
+			opLog.Debug("skipped duplicate")
+			return c.skip(opLog, e.TopicPartition, consumerOffsets)
+		}
+	}
+
+	msg, pending := c.reassembler.Add(opLog, e)
+	if pending {
+		// the offset isn't stored until the reassembled message is processed
+		opLog.Debug("pending chunk")
+		return nil
+	}
+	if msg == nil {
+		opLog.Debug("skipped invalid chunk")
+		return c.skip(opLog, e.TopicPartition, consumerOffsets)
+	}
+
+	msg, err := Transform(c.ctx, opLog, msg, c.transformers...)
+	if err != nil {
+		opLog.Error("failed to transform message", zap.Error(err))
 		return err
 	}
 
-	consumerOffsets.Add(e.TopicPartition)
+	if c.batch != nil {
+		// the offsets are committed after the batch is processed
+		return c.addToBatch(e.TopicPartition, msg, key, consumerOffsets)
+	}
 
-	if c.commitOffsetCount > 0 {
-		if consumerOffsets.Counter() >= c.commitOffsetCount {
-			c.commitOffsets(consumerOffsets)
+	if c.pool != nil {
+		// the offset is added by the worker after the message is processed
+		return c.dispatch(opLog, e.TopicPartition, msg, key, consumerOffsets)
+	}
+
+	if msg != nil {
+		start := time.Now()
+		err = c.processInflight(opLog, e.TopicPartition, msg)
+		if errors.Is(err, ErrRetryLater) {
+			c.inflight.Done(e.TopicPartition, time.Since(start), nil)
+			return c.retryLater(opLog, c.reader, e.TopicPartition)
+		}
+		c.inflight.Done(e.TopicPartition, time.Since(start), err)
+		if err != nil {
+			if progressErr, ok := err.(*ProgressError); ok {
+				opLog = opLog.With(zap.Any("progress", progressErr.Tags()))
+			}
+			opLog.Error("failed to process message", zap.Error(err))
+			return err
 		}
+	} else {
+		opLog.Debug("skipped by transformer")
 	}
 
+	c.dedupe.Add(key)
+	c.storeOffset(consumerOffsets, e.TopicPartition)
+	c.commitProcessed(consumerOffsets)
+
 	opLog.Debug("success")
 	return nil
 }
 
+func (c *Consumer) processInflight(opLog *zap.Logger, tp kafka.TopicPartition, msg *kafka.Message) error {
+
+	c.inflight.Begin(tp)
+	defer c.inflight.End(tp)
+
+	return c.processMessage(opLog, msg)
+}
+
+func (c *Consumer) skip(opLog *zap.Logger, tp kafka.TopicPartition, consumerOffsets *offset) error {
+
+	if c.batch != nil {
+		return c.addToBatch(tp, nil, "", consumerOffsets)
+	}
+
+	if c.pool != nil {
+		return c.dispatch(opLog, tp, nil, "", consumerOffsets)
+	}
+
+	c.storeOffset(consumerOffsets, tp)
+	return nil
+}
+
 func (c *Consumer) handlePartitionEOF(e *kafka.PartitionEOF, consumerOffsets *offset) error {
 
-	c.commitOffsets(consumerOffsets)
+	_ = c.commitOffsets(consumerOffsets)
 	consumerOffsets.Clear()
 
 	opLog := c.logger.With(zap.String("operation", "partition EOF"), zap.Any("event", e))
@@ -432,25 +1158,86 @@ func (c *Consumer) handleOffsetCommitted(e *kafka.OffsetsCommitted, consumerOffs
 	return nil
 }
 
-func (c *Consumer) commitOffsets(consumerOffsets *offset) {
+func (c *Consumer) handleFinalCommit(consumerOffsets *offset) {
+
+	if c.pool != nil {
+		c.pool.Drain()
+	}
+
+	// the reader isn't closed until the commit is completed (see waitFinalCommit)
+	c.finalCommit = make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		defer close(c.finalCommit)
+		done <- c.commitOffsets(consumerOffsets)
+	}()
+
+	timer := c.clock.NewTimer(c.commitTimeout)
+	defer timer.Stop()
+
+	select {
+	case c.stopErr = <-done:
+	case <-timer.C():
+		c.stopErr = errors.Errorf("final commit of offsets timed out after %s", c.commitTimeout)
+		c.logger.Error("failed to commit offsets before closing", zap.Error(c.stopErr))
+	}
+}
+
+func (c *Consumer) waitFinalCommit() {
+	if c.finalCommit == nil {
+		return
+	}
+
+	select {
+	case <-c.finalCommit:
+	default:
+		c.logger.Warn("waiting for the final commit of offsets before closing")
+		<-c.finalCommit
+	}
+}
+
+func (c *Consumer) commitOffsets(consumerOffsets *offset) error {
+
+	c.waitAsyncCommit()
+
+	if c.marked != nil {
+		consumerOffsets.Add(c.marked.Take()...)
+	}
 
 	list, count := consumerOffsets.Get()
+	return c.commitList(consumerOffsets, list, count)
+}
+
+func (c *Consumer) commitList(consumerOffsets *offset, list []kafka.TopicPartition, count map[string]int) error {
+
+	c.health.Touch(c.clock.Now())
+
+	list = c.reassembler.Limit(list)
 	if len(list) > 0 {
-		opLog := c.logger.WithOptions(zap.AddCallerSkip(1)).With(
+		opLog := c.logger.WithOptions(zap.AddCallerSkip(2)).With(
 			zap.String("operation", "commit offsets"),
 			zap.Any("event", list))
 
+		if c.onPreCommit != nil {
+			if err := c.onPreCommit(c.ctx, opLog, list); err != nil {
+				opLog.Error("failed to pre-commit", zap.Error(err))
+				c.inflight.CommitFailed()
+				c.onError(c.ctx, opLog, err)
+				return err
+			}
+		}
+
 		success, err := c.reader.CommitOffsets(list)
-		if err != nil {
-			opLog.Error("failed to commit", zap.Error(err))
-			c.onError(c.ctx, opLog, err)
-			return
+		if err == nil {
+			err = checkPartitions(success)
 		}
 
-		if err := checkPartitions(success); err != nil {
+		if err != nil {
+			err = &CommitError{Partitions: list, Err: err}
 			opLog.Error("failed to commit", zap.Error(err))
+			c.inflight.CommitFailed()
 			c.onError(c.ctx, opLog, err)
-			return
+			return err
 		}
 
 		opLog.Debug("success", zap.Any("result", success))
@@ -458,14 +1245,16 @@ func (c *Consumer) commitOffsets(consumerOffsets *offset) {
 		var topic string
 		for i := range success {
 			item := &success[i]
-			consumerOffsets.Remove(*item)
+			countCommitted := count[getPartitionKey(item.Topic, item.Partition)]
+			consumerOffsets.RemoveCommitted(*item, countCommitted)
 
 			if item.Topic != nil {
 				topic = *item.Topic
 			}
 
-			countCommitted := count[getPartitionKey(item.Topic, item.Partition)]
 			c.onCommit(c.ctx, opLog, topic, item.Partition, item.Offset, countCommitted)
 		}
 	}
+
+	return nil
 }
@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/dialogs/dialog-go-lib/reporter"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -26,7 +28,7 @@ func TestConsumerNew(t *testing.T) {
 		CommitOffsetCount:    1,
 		CommitOffsetDuration: time.Hour,
 		OnError:              func(context.Context, *zap.Logger, error) {},
-		OnProcess:            func(context.Context, *zap.Logger, *kafka.Message, ISleeper) error { return nil },
+		OnProcess:            func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
 		Topics:               []string{"a"},
 		ConfigMap: &kafka.ConfigMap{
 			"group.id":          "group-id",
@@ -34,12 +36,13 @@ func TestConsumerNew(t *testing.T) {
 		},
 	}
 
-	ctxDone, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	c, err := New(cfg, zap.L())
 	require.NoError(t, err)
-	require.Equal(t, ctxDone, c.ctx)
+	require.NoError(t, c.ctx.Err())
+	// the tags of the reported errors
+	require.Equal(t,
+		map[string]string{"kafka.consumer": c.id, "kafka.group": "group-id", "kafka.topics": "a"},
+		reporter.TagsFromContext(c.ctx))
 	require.Equal(t, zap.L(), c.logger)
 	require.NotEmpty(t, c.onCommit, c.onCommit)
 	require.NotEmpty(t, func(context.Context, error) {}, c.onError)
@@ -61,7 +64,7 @@ func TestConsumerDoubleStartClose(t *testing.T) {
 		require.NoError(t, err)
 	}
 
-	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper) error {
+	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
 		if msg == nil {
 			return errors.New("invalid message")
 		}
@@ -96,7 +99,7 @@ func TestConsumerReadMessageSuccess(t *testing.T) {
 	}
 
 	chMsg := make(chan *kafka.Message, 2)
-	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper) error {
+	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
 		if msg == nil {
 			return errors.New("invalid message")
 		}
@@ -159,7 +162,7 @@ func TestConsumerReadMessagesWithDelay(t *testing.T) {
 	chMsg := make(chan *kafka.Message, 2)
 	delayDuration := 3 * time.Second
 	processedMessages := 0
-	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, c ISleeper) error {
+	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, c ISleeper, _ ICommitter) error {
 		processedMessages++
 		if msg == nil {
 			return errors.New("invalid message")
@@ -248,7 +251,7 @@ func TestConsumerRebalance(t *testing.T) {
 	}
 
 	chMsg := make(chan *kafka.Message)
-	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper) error {
+	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
 		if msg == nil {
 			return errors.New("invalid message")
 		}
@@ -296,7 +299,7 @@ func TestConsumerFailedSubscribe(t *testing.T) {
 		require.NoError(t, err)
 	}
 
-	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper) error {
+	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
 		if msg == nil {
 			return errors.New("invalid message")
 		}
@@ -329,7 +332,7 @@ func TestConsumerRevokePartition(t *testing.T) {
 		chErrors <- err
 	}
 
-	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper) error {
+	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
 		if msg == nil {
 			return errors.New("invalid message")
 		}
@@ -403,7 +406,7 @@ func TestConsumerCommit(t *testing.T) {
 		}
 
 		chMsg := make(chan *kafka.Message, countMessages)
-		onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper) error {
+		onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
 			if msg == nil {
 				return errors.New("invalid message")
 			}
@@ -736,3 +739,182 @@ func waitRebalance(chRebalance <-chan int) {
 
 	wg.Wait()
 }
+
+func TestConsumerStopContext(t *testing.T) {
+
+	cfg := newConsumerConfig([]string{"test-stop-context"}, nil,
+		func(context.Context, *zap.Logger, error) {},
+		func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
+		nil, nil, nil)
+
+	c, err := New(cfg, newLogger(t))
+	require.NoError(t, err)
+
+	// imitation of the running consumer
+	c.mu.Lock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	err = c.StopContext(ctx)
+	require.Error(t, err)
+	require.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+
+	c.mu.Unlock()
+	require.NoError(t, c.Stop())
+}
+
+func TestConsumerRestart(t *testing.T) {
+
+	cfg := newConsumerConfig([]string{"test-restart"}, nil,
+		func(context.Context, *zap.Logger, error) {},
+		func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
+		nil, nil, nil)
+
+	c, err := New(cfg, newLogger(t))
+	require.NoError(t, err)
+
+	observer := &TestObserver{}
+	c.AddStateObserver(observer)
+
+	restarted, err := c.Restart()
+	require.NoError(t, err)
+	defer restarted.Stop()
+
+	require.NotEqual(t, c.id, restarted.id)
+	require.Equal(t, kafka.ConfigValue(restarted.id), (*restarted.cfg.ConfigMap)["client.id"])
+	require.Equal(t, c.topics, restarted.topics)
+	require.Equal(t, []IStateObserver{observer}, restarted.observers)
+	require.Error(t, c.ctx.Err())
+	require.NoError(t, restarted.ctx.Err())
+}
+
+func TestConsumerNewDoesNotModifyConfig(t *testing.T) {
+
+	cfg := newConsumerConfig([]string{"test-config"}, nil,
+		func(context.Context, *zap.Logger, error) {},
+		func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
+		nil, nil, nil)
+	before := cfg.Clone()
+
+	c1, err := New(cfg, newLogger(t))
+	require.NoError(t, err)
+	defer c1.Stop()
+
+	c2, err := New(cfg, newLogger(t))
+	require.NoError(t, err)
+	defer c2.Stop()
+
+	require.Equal(t, before.ConfigMap, cfg.ConfigMap)
+	require.NotEqual(t, c1.cfg.ConfigMap, c2.cfg.ConfigMap)
+}
+
+func TestStopWithTimeout(t *testing.T) {
+
+	newConsumer := func() (*Consumer, *mock.Clock) {
+		clk := mock.NewClock(time.Now())
+		c, _ := newTestConsumer(t, WithClock(clk))
+		return c, clk
+	}
+
+	// start emulates the event loop with the in-flight message: the processing
+	// is continued after the end of the loop and the offsets are committed
+	start := func(c *Consumer, process func(ctx context.Context) error) {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			defer c.ctxCancel()
+
+			<-c.loopCtx.Done()
+			c.stopErr = process(c.ctx)
+		}()
+	}
+
+	t.Run("drained", func(t *testing.T) {
+		c, _ := newConsumer()
+
+		release := make(chan struct{})
+		start(c, func(ctx context.Context) error {
+			<-release
+			return ctx.Err()
+		})
+
+		done := make(chan error, 1)
+		go func() { done <- c.StopWithTimeout(time.Minute) }()
+
+		// the processing isn't cancelled
+		close(release)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		c, clk := newConsumer()
+
+		start(c, func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+
+		done := make(chan error, 1)
+		go func() { done <- c.StopWithTimeout(time.Minute) }()
+
+		require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+		clk.Add(time.Minute)
+
+		require.Equal(t, ErrDrainTimeout, <-done)
+	})
+
+	t.Run("timeout with commit error", func(t *testing.T) {
+		c, clk := newConsumer()
+
+		start(c, func(ctx context.Context) error {
+			<-ctx.Done()
+			return errors.New("commit failed")
+		})
+
+		done := make(chan error, 1)
+		go func() { done <- c.StopWithTimeout(time.Minute) }()
+
+		require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+		clk.Add(time.Minute)
+
+		err := <-done
+		require.True(t, errors.Is(err, ErrDrainTimeout))
+		require.EqualError(t, err, "drain timed out: commit failed")
+	})
+}
+
+func TestFinalCommitTimeout(t *testing.T) {
+
+	clk := mock.NewClock(time.Now())
+	c, _ := newTestConsumer(t, WithClock(clk), WithTimeout(time.Minute))
+	// the commit in the background isn't completed
+	c.asyncCommit = make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.handleFinalCommit(newOffset())
+	}()
+
+	require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+	clk.Add(time.Minute)
+	<-done
+	require.EqualError(t, c.stopErr, "final commit of offsets timed out after 1m0s")
+
+	// the reader isn't closed while the commit is in flight
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		c.waitFinalCommit()
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("the reader is closed before the end of the commit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(c.asyncCommit)
+	<-closed
+}
@@ -0,0 +1,467 @@
+package consumertest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// A Rebalance is the recorded assignment or revocation of the partitions
+type Rebalance struct {
+	Assigned   bool
+	Partitions []kafka.TopicPartition
+}
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// A Consumer is the in-memory fake of consumer.Consumer for the tests of the handlers:
+// the messages pushed by Push are processed by the callbacks of the config synchronously
+// (OnProcess, OnError, OnPreCommit, OnCommit, OnRebalance, OnRevoke, Transformers),
+// the commits, the rebalances and the pauses are recorded.
+// The committed offset is the offset of the last processed message like consumer.Consumer.
+type Consumer struct {
+	cfg        *consumer.Config
+	logger     *zap.Logger
+	ctx        context.Context
+	ctxCancel  context.CancelFunc
+	assigned   map[partitionKey]struct{}
+	pending    map[partitionKey]kafka.Offset
+	counts     map[partitionKey]int
+	committed  map[partitionKey]kafka.Offset
+	commits    []kafka.TopicPartition
+	rebalances []Rebalance
+	paused     map[partitionKey]struct{}
+	seeks      []kafka.TopicPartition
+	mu         sync.Mutex
+}
+
+var _ consumer.IConsumer = (*Consumer)(nil)
+
+// New creates the fake consumer (the batch mode isn't supported)
+func New(cfg *consumer.Config, logger *zap.Logger) (*Consumer, error) {
+
+	if cfg == nil || cfg.OnProcess == nil {
+		return nil, errors.New("on process callback is nil")
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	ctx, ctxCancel := context.WithCancel(context.Background())
+
+	return &Consumer{
+		cfg:       cfg,
+		logger:    logger,
+		ctx:       ctx,
+		ctxCancel: ctxCancel,
+		assigned:  make(map[partitionKey]struct{}),
+		pending:   make(map[partitionKey]kafka.Offset),
+		counts:    make(map[partitionKey]int),
+		committed: make(map[partitionKey]kafka.Offset),
+		paused:    make(map[partitionKey]struct{}),
+	}, nil
+}
+
+// Push processes the messages one by one: the partition of the message is assigned
+// (without OnRebalance) if it isn't, the offset of the processed message is stored
+// to commit (see Config.ManualCommit, Config.CommitOffsetCount).
+// The error of OnProcess is passed to OnError and returned, consumer.ErrRetryLater is recorded as the seek
+// to the message (see Seeks).
+func (c *Consumer) Push(msgs ...*kafka.Message) error {
+
+	for _, msg := range msgs {
+		if err := c.push(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Consumer) push(msg *kafka.Message) error {
+
+	if err := c.ctx.Err(); err != nil {
+		return consumer.ErrAlreadyClosed
+	}
+
+	tp := msg.TopicPartition
+	key := newPartitionKey(tp)
+
+	c.mu.Lock()
+	c.assigned[key] = struct{}{}
+	c.mu.Unlock()
+
+	logger := c.logger.With(zap.Any("event", msg))
+
+	res, err := consumer.Transform(c.ctx, logger, msg, c.cfg.Transformers...)
+	if err == nil && res != nil {
+		err = c.cfg.OnProcess(c.ctx, logger, res, c, c)
+	}
+	if errors.Is(err, consumer.ErrRetryLater) {
+		// the offset isn't stored, the partition is moved back to the message
+		c.mu.Lock()
+		c.seeks = append(c.seeks, tp)
+		c.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		if c.cfg.OnError != nil {
+			c.cfg.OnError(c.ctx, logger, err)
+		}
+		return err
+	}
+
+	if !c.cfg.ManualCommit {
+		c.MarkOffset(tp)
+	}
+
+	c.mu.Lock()
+	count := 0
+	for _, n := range c.counts {
+		count += n
+	}
+	c.mu.Unlock()
+
+	if c.cfg.CommitOffsetCount > 0 && count >= c.cfg.CommitOffsetCount {
+		return c.Commit()
+	}
+
+	return nil
+}
+
+// MarkOffset stores the offset to commit (consumer.ICommitter implementation)
+func (c *Consumer) MarkOffset(tp kafka.TopicPartition) {
+
+	key := newPartitionKey(tp)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if offset, ok := c.pending[key]; !ok || offset < tp.Offset {
+		c.pending[key] = tp.Offset
+		c.counts[key]++
+	}
+}
+
+// Commit commits the stored offsets: OnPreCommit is called before the commit, OnCommit after it
+func (c *Consumer) Commit() error {
+
+	c.mu.Lock()
+	list := make([]kafka.TopicPartition, 0, len(c.pending))
+	for key, offset := range c.pending {
+		list = append(list, key.topicPartition(offset))
+	}
+	c.mu.Unlock()
+
+	if len(list) == 0 {
+		return nil
+	}
+	sortPartitions(list)
+
+	if c.cfg.OnPreCommit != nil {
+		if err := c.cfg.OnPreCommit(c.ctx, c.logger, list); err != nil {
+			if c.cfg.OnError != nil {
+				c.cfg.OnError(c.ctx, c.logger, err)
+			}
+			return err
+		}
+	}
+
+	for _, tp := range list {
+		key := newPartitionKey(tp)
+
+		c.mu.Lock()
+		count := c.counts[key]
+		c.committed[key] = tp.Offset
+		c.commits = append(c.commits, tp)
+		delete(c.pending, key)
+		delete(c.counts, key)
+		c.mu.Unlock()
+
+		if c.cfg.OnCommit != nil {
+			c.cfg.OnCommit(c.ctx, c.logger, key.topic, key.partition, tp.Offset, count)
+		}
+	}
+
+	return nil
+}
+
+// Assign assigns the partitions and calls OnRebalance (the partitions are replaced by OnAssign)
+func (c *Consumer) Assign(partitions ...kafka.TopicPartition) {
+
+	if c.cfg.OnAssign != nil {
+		partitions = c.cfg.OnAssign(c.ctx, c.logger, partitions)
+	}
+
+	c.mu.Lock()
+	for _, tp := range partitions {
+		c.assigned[newPartitionKey(tp)] = struct{}{}
+	}
+	c.rebalances = append(c.rebalances, Rebalance{Assigned: true, Partitions: partitions})
+	c.mu.Unlock()
+
+	if c.cfg.OnRebalance != nil {
+		c.cfg.OnRebalance(c.ctx, c.logger, partitions)
+	}
+}
+
+// Revoke commits the stored offsets, revokes the partitions and calls OnRevoke.
+// The offsets of the revoked partitions aren't committed if the commit is failed.
+func (c *Consumer) Revoke(partitions ...kafka.TopicPartition) error {
+
+	err := c.Commit()
+
+	c.mu.Lock()
+	for _, tp := range partitions {
+		key := newPartitionKey(tp)
+		delete(c.assigned, key)
+		delete(c.pending, key)
+		delete(c.counts, key)
+		delete(c.paused, key)
+	}
+	c.rebalances = append(c.rebalances, Rebalance{Partitions: partitions})
+	c.mu.Unlock()
+
+	if c.cfg.OnRevoke != nil {
+		c.cfg.OnRevoke(c.ctx, c.logger, partitions)
+	}
+
+	return err
+}
+
+// Assignment returns the assigned partitions
+func (c *Consumer) Assignment() []kafka.TopicPartition {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return partitions(c.assigned)
+}
+
+// Committed returns the committed offset of the partition
+func (c *Consumer) Committed(topic string, partition int32) (kafka.Offset, bool) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	offset, ok := c.committed[partitionKey{topic: topic, partition: partition}]
+	return offset, ok
+}
+
+// Commits returns the committed offsets in order of the commits
+func (c *Consumer) Commits() []kafka.TopicPartition {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]kafka.TopicPartition(nil), c.commits...)
+}
+
+// Rebalances returns the assignments and the revocations in order
+func (c *Consumer) Rebalances() []Rebalance {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Rebalance(nil), c.rebalances...)
+}
+
+// Seeks returns the positions of the Seek calls in order
+func (c *Consumer) Seeks() []kafka.TopicPartition {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]kafka.TopicPartition(nil), c.seeks...)
+}
+
+// Start blocks until the consumer is stopped
+func (c *Consumer) Start() error {
+
+	select {
+	case <-c.ctx.Done():
+		return consumer.ErrAlreadyClosed
+	default:
+	}
+
+	<-c.ctx.Done()
+	return nil
+}
+
+// Stop commits the stored offsets and closes the consumer
+func (c *Consumer) Stop() error {
+
+	if c.ctx.Err() != nil {
+		return nil
+	}
+
+	err := c.Commit()
+	c.ctxCancel()
+
+	return err
+}
+
+func (c *Consumer) StopContext(context.Context) error   { return c.Stop() }
+func (c *Consumer) StopWithTimeout(time.Duration) error { return c.Stop() }
+func (c *Consumer) Drain(context.Context) error         { return c.Stop() }
+func (c *Consumer) SeekToTime(time.Time) error          { return nil }
+func (c *Consumer) InFlight() []consumer.PartitionState { return nil }
+func (c *Consumer) MatchedTopics() []string             { return nil }
+func (c *Consumer) CheckBrokers(context.Context) error  { return nil }
+func (c *Consumer) SleepStatus() (time.Time, bool)      { return time.Time{}, false }
+func (c *Consumer) SleepAll(time.Duration) error        { return c.Pause(c.Assignment()) }
+func (c *Consumer) ResumeAll() error                    { return c.Resume(c.PausedPartitions()) }
+
+// Sleep pauses the partitions (the delay is ignored, see CancelSleep)
+func (c *Consumer) Sleep(_ time.Duration, partitions []kafka.TopicPartition) error {
+	return c.Pause(partitions)
+}
+
+// SleepUntil pauses the partitions (the condition isn't checked, see CancelSleep)
+func (c *Consumer) SleepUntil(_ context.Context, _ consumer.FuncSleepCondition, partitions []kafka.TopicPartition) error {
+	return c.Pause(partitions)
+}
+
+// SleepContext pauses the partitions, the returned function (or the done context) resumes them
+func (c *Consumer) SleepContext(ctx context.Context, _ time.Duration, partitions []kafka.TopicPartition) (context.CancelFunc, error) {
+
+	if err := c.Pause(partitions); err != nil {
+		return func() {}, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		<-ctx.Done()
+		_ = c.Resume(partitions)
+	}()
+
+	return cancel, nil
+}
+
+func (c *Consumer) CancelSleep(partitions []kafka.TopicPartition) error {
+	return c.Resume(partitions)
+}
+
+// HealthCheck returns consumer.ErrAlreadyClosed after Stop
+func (c *Consumer) HealthCheck() error {
+	if c.ctx.Err() != nil {
+		return consumer.ErrAlreadyClosed
+	}
+	return nil
+}
+
+// Resubscribe revokes and assigns the assigned partitions again
+func (c *Consumer) Resubscribe() error {
+
+	partitions := c.Assignment()
+	if err := c.Revoke(partitions...); err != nil {
+		return err
+	}
+	c.Assign(partitions...)
+
+	return nil
+}
+
+func (c *Consumer) Pause(partitions []kafka.TopicPartition) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tp := range partitions {
+		c.paused[newPartitionKey(tp)] = struct{}{}
+	}
+
+	return nil
+}
+
+func (c *Consumer) Resume(partitions []kafka.TopicPartition) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tp := range partitions {
+		delete(c.paused, newPartitionKey(tp))
+	}
+
+	return nil
+}
+
+func (c *Consumer) PausedPartitions() []kafka.TopicPartition {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return partitions(c.paused)
+}
+
+// SleepingPartitions returns the paused partitions without the ends of the sleeps
+func (c *Consumer) SleepingPartitions() []consumer.SleepingPartition {
+
+	list := c.PausedPartitions()
+	retval := make([]consumer.SleepingPartition, len(list))
+	for i := range list {
+		retval[i] = consumer.SleepingPartition{Topic: *list[i].Topic, Partition: list[i].Partition}
+	}
+
+	return retval
+}
+
+// Seek drops the stored offsets of the partition and records the position (see Seeks)
+func (c *Consumer) Seek(topic string, partition int32, offset kafka.Offset) error {
+
+	key := partitionKey{topic: topic, partition: partition}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pending, key)
+	delete(c.counts, key)
+	c.seeks = append(c.seeks, key.topicPartition(offset))
+
+	return nil
+}
+
+func newPartitionKey(tp kafka.TopicPartition) partitionKey {
+
+	key := partitionKey{partition: tp.Partition}
+	if tp.Topic != nil {
+		key.topic = *tp.Topic
+	}
+
+	return key
+}
+
+func (k partitionKey) topicPartition(offset kafka.Offset) kafka.TopicPartition {
+
+	topic := k.topic
+	return kafka.TopicPartition{Topic: &topic, Partition: k.partition, Offset: offset}
+}
+
+func partitions(set map[partitionKey]struct{}) []kafka.TopicPartition {
+
+	retval := make([]kafka.TopicPartition, 0, len(set))
+	for key := range set {
+		retval = append(retval, key.topicPartition(kafka.OffsetInvalid))
+	}
+	sortPartitions(retval)
+
+	return retval
+}
+
+func sortPartitions(list []kafka.TopicPartition) {
+	sort.Slice(list, func(i, j int) bool {
+		if *list[i].Topic != *list[j].Topic {
+			return *list[i].Topic < *list[j].Topic
+		}
+		return list[i].Partition < list[j].Partition
+	})
+}
@@ -0,0 +1,146 @@
+package consumertest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newMessage(topic string, partition int32, offset kafka.Offset, value string) *kafka.Message {
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: offset},
+		Value:          []byte(value),
+	}
+}
+
+func TestConsumer(t *testing.T) {
+
+	_, err := New(&consumer.Config{}, nil)
+	require.EqualError(t, err, "on process callback is nil")
+
+	var (
+		processed []string
+		errs      []error
+		commits   []kafka.Offset
+		revoked   []kafka.TopicPartition
+	)
+
+	c, err := New(&consumer.Config{
+		OnProcess: func(_ context.Context, _ *zap.Logger, msg *kafka.Message, s consumer.ISleeper, _ consumer.ICommitter) error {
+			if string(msg.Value) == "fail" {
+				_ = s.Sleep(0, []kafka.TopicPartition{msg.TopicPartition})
+				return errors.New("failed")
+			}
+			processed = append(processed, string(msg.Value))
+			return nil
+		},
+		OnError: func(_ context.Context, _ *zap.Logger, err error) {
+			errs = append(errs, err)
+		},
+		OnCommit: func(_ context.Context, _ *zap.Logger, _ string, _ int32, offset kafka.Offset, _ int) {
+			commits = append(commits, offset)
+		},
+		OnRevoke: func(_ context.Context, _ *zap.Logger, partitions []kafka.TopicPartition) {
+			revoked = append(revoked, partitions...)
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Push(newMessage("a", 0, 1, "x"), newMessage("a", 0, 2, "y"), newMessage("a", 1, 5, "z")))
+	require.Equal(t, []string{"x", "y", "z"}, processed)
+	require.Len(t, c.Assignment(), 2)
+
+	_, ok := c.Committed("a", 0)
+	require.False(t, ok)
+
+	require.NoError(t, c.Commit())
+	offset, ok := c.Committed("a", 0)
+	require.True(t, ok)
+	require.Equal(t, kafka.Offset(2), offset)
+	require.Equal(t, []kafka.Offset{2, 5}, commits)
+	require.Len(t, c.Commits(), 2)
+
+	// the failed message isn't committed, the partition is paused by the handler
+	require.EqualError(t, c.Push(newMessage("a", 0, 3, "fail")), "failed")
+	require.Len(t, errs, 1)
+	require.Len(t, c.PausedPartitions(), 1)
+	require.NoError(t, c.Commit())
+	require.Len(t, c.Commits(), 2)
+
+	// the revoke commits the processed messages
+	require.NoError(t, c.Push(newMessage("a", 1, 6, "w")))
+	require.NoError(t, c.Revoke(kafka.TopicPartition{Topic: stringPointer("a"), Partition: 1}))
+	require.Equal(t, []kafka.Offset{2, 5, 6}, commits)
+	require.Len(t, revoked, 1)
+	require.Len(t, c.Assignment(), 1)
+
+	c.Assign(kafka.TopicPartition{Topic: stringPointer("a"), Partition: 1})
+	require.Equal(t,
+		[]Rebalance{
+			{Partitions: []kafka.TopicPartition{{Topic: stringPointer("a"), Partition: 1}}},
+			{Assigned: true, Partitions: []kafka.TopicPartition{{Topic: stringPointer("a"), Partition: 1}}},
+		},
+		c.Rebalances())
+
+	// the consumer is stopped after the final commit
+	require.NoError(t, c.Push(newMessage("a", 0, 4, "v")))
+	require.NoError(t, c.Stop())
+	require.Equal(t, []kafka.Offset{2, 5, 6, 4}, commits)
+
+	require.Equal(t, consumer.ErrAlreadyClosed, c.Start())
+
+	require.Equal(t, consumer.ErrAlreadyClosed, c.Push(newMessage("a", 0, 5, "u")))
+	require.Equal(t, consumer.ErrAlreadyClosed, c.HealthCheck())
+}
+
+func TestConsumerManualCommit(t *testing.T) {
+
+	var pending []kafka.TopicPartition
+
+	c, err := New(&consumer.Config{
+		ManualCommit:      true,
+		CommitOffsetCount: 2,
+		OnProcess: func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ consumer.ISleeper, _ consumer.ICommitter) error {
+			pending = append(pending, msg.TopicPartition)
+			return nil
+		},
+		OnPreCommit: func(_ context.Context, _ *zap.Logger, offsets []kafka.TopicPartition) error {
+			if len(offsets) > 1 {
+				return errors.New("flush failed")
+			}
+			return nil
+		},
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, c.Push(newMessage("a", 0, 1, "x"), newMessage("a", 0, 2, "y")))
+	require.NoError(t, c.Commit())
+	require.Empty(t, c.Commits())
+
+	// the offsets are committed by the count of the marked messages
+	c.MarkOffset(pending[0])
+	c.MarkOffset(pending[1])
+	require.NoError(t, c.Push(newMessage("a", 0, 3, "z")))
+	require.Equal(t, []kafka.TopicPartition{pending[1]}, c.Commits())
+
+	// the offsets aren't committed if the pre-commit is failed
+	c.MarkOffset(pending[2])
+	c.MarkOffset(kafka.TopicPartition{Topic: stringPointer("b"), Partition: 0, Offset: 1})
+	require.EqualError(t, c.Commit(), "flush failed")
+	require.Len(t, c.Commits(), 1)
+
+	// the seek drops the marked offsets
+	require.NoError(t, c.Seek("b", 0, 0))
+	require.NoError(t, c.Commit())
+	require.Len(t, c.Commits(), 2)
+	require.Equal(t, []kafka.TopicPartition{{Topic: stringPointer("b"), Partition: 0, Offset: 0}}, c.Seeks())
+}
+
+func stringPointer(s string) *string {
+	return &s
+}
@@ -0,0 +1,52 @@
+package consumer
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+func (c *Consumer) assign(partitions []kafka.TopicPartition) error {
+	if c.cooperative {
+		return c.reader.IncrementalAssign(partitions)
+	}
+
+	return c.reader.Assign(partitions)
+}
+
+func (c *Consumer) unassign(partitions []kafka.TopicPartition) error {
+	if c.cooperative {
+		return c.reader.IncrementalUnassign(partitions)
+	}
+
+	return c.reader.Unassign()
+}
+
+func (c *Consumer) unassignAll() error {
+	if !c.cooperative {
+		return c.reader.Unassign()
+	}
+
+	partitions, err := c.reader.Assignment()
+	if err != nil {
+		return err
+	}
+
+	return c.reader.IncrementalUnassign(partitions)
+}
+
+// clearOffsets drops the pending offsets of the partitions, the other partitions are kept assigned in the cooperative mode
+func (c *Consumer) clearOffsets(consumerOffsets *offset, partitions []kafka.TopicPartition) {
+	if !c.cooperative {
+		consumerOffsets.Clear()
+		if c.marked != nil {
+			c.marked.Clear()
+		}
+		return
+	}
+
+	for _, tp := range partitions {
+		consumerOffsets.Remove(tp)
+		if c.marked != nil {
+			c.marked.Remove(tp)
+		}
+	}
+}
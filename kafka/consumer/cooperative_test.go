@@ -0,0 +1,87 @@
+package consumer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCooperativeRebalance(t *testing.T) {
+
+	c, r := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.CooperativeRebalance = true
+	}))
+
+	strategy, err := c.cfg.ConfigMap.Get("partition.assignment.strategy", "")
+	require.NoError(t, err)
+	require.Equal(t, "cooperative-sticky", strategy)
+
+	topic := "test"
+	p0 := kafka.TopicPartition{Topic: &topic, Partition: 0}
+	p1 := kafka.TopicPartition{Topic: &topic, Partition: 1}
+	p2 := kafka.TopicPartition{Topic: &topic, Partition: 2}
+
+	partitions := func() (retval []int32) {
+		assignment, err := r.Assignment()
+		require.NoError(t, err)
+		for _, tp := range assignment {
+			retval = append(retval, tp.Partition)
+		}
+		return
+	}
+
+	offsets := newOffset()
+
+	// the partitions are added to the assignment
+	require.NoError(t, c.handleRebalance(&kafka.AssignedPartitions{Partitions: []kafka.TopicPartition{p0, p1}}, offsets))
+	require.NoError(t, c.handleRebalance(&kafka.AssignedPartitions{Partitions: []kafka.TopicPartition{p2}}, offsets))
+	require.Equal(t, []int32{0, 1, 2}, partitions())
+
+	// the offsets of the kept partitions aren't dropped after the failed commit
+	r.commitErr = errors.New("fail")
+	offsets.Add(kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 10}, kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 20})
+
+	require.NoError(t, c.handleRevoke(&kafka.RevokedPartitions{Partitions: []kafka.TopicPartition{p2}}, offsets))
+	require.Equal(t, []int32{0, 1}, partitions())
+
+	list, _ := offsets.Get()
+	require.Equal(t, []kafka.TopicPartition{{Topic: &topic, Partition: 0, Offset: 10}}, list)
+
+	require.NoError(t, c.unassignAll())
+	require.Empty(t, partitions())
+}
+
+func TestEagerRebalance(t *testing.T) {
+
+	c, r := newTestConsumer(t)
+
+	topic := "test"
+	p0 := kafka.TopicPartition{Topic: &topic, Partition: 0}
+	p1 := kafka.TopicPartition{Topic: &topic, Partition: 1}
+
+	offsets := newOffset()
+
+	// the assignment is replaced
+	require.NoError(t, c.handleRebalance(&kafka.AssignedPartitions{Partitions: []kafka.TopicPartition{p0}}, offsets))
+	require.NoError(t, c.handleRebalance(&kafka.AssignedPartitions{Partitions: []kafka.TopicPartition{p1}}, offsets))
+
+	assignment, err := r.Assignment()
+	require.NoError(t, err)
+	require.Len(t, assignment, 1)
+	require.Equal(t, int32(1), assignment[0].Partition)
+
+	// all offsets are dropped
+	r.commitErr = errors.New("fail")
+	offsets.Add(kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 10})
+
+	require.NoError(t, c.handleRevoke(&kafka.RevokedPartitions{Partitions: []kafka.TopicPartition{p1}}, offsets))
+
+	assignment, err = r.Assignment()
+	require.NoError(t, err)
+	require.Empty(t, assignment)
+
+	list, _ := offsets.Get()
+	require.Empty(t, list)
+}
@@ -0,0 +1,125 @@
+package consumer
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock"
+)
+
+// DedupeConfig enables dropping of the duplicates processed successfully within the window
+type DedupeConfig struct {
+	Window time.Duration `mapstructure:"window"`
+	// Size is the max count of the remembered messages (the oldest ones are evicted)
+	Size int `mapstructure:"size"`
+}
+
+type dedupeEntry struct {
+	key     string
+	expires time.Time
+}
+
+// dedupe is a LRU/TTL filter of the messages processed successfully
+type dedupe struct {
+	window time.Duration
+	size   int
+	items  map[string]*list.Element
+	order  *list.List
+	clock  clock.Clock
+	mu     sync.Mutex
+}
+
+func newDedupe(cfg *DedupeConfig, clk clock.Clock) *dedupe {
+	if cfg == nil || cfg.Window <= 0 {
+		return nil
+	}
+
+	size := cfg.Size
+	if size <= 0 {
+		size = 10000
+	}
+
+	return &dedupe{
+		window: cfg.Window,
+		size:   size,
+		items:  make(map[string]*list.Element),
+		order:  list.New(),
+		clock:  clk,
+	}
+}
+
+// Seen checks that the message with the key (see dedupeKey) was processed within the window
+func (d *dedupe) Seen(key string) bool {
+	if d == nil {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.expire()
+	_, ok := d.items[key]
+
+	return ok
+}
+
+// Add remembers the processed message with the key (see dedupeKey), the empty key is ignored
+func (d *dedupe) Add(key string) {
+	if d == nil || key == "" {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.expire()
+	if e, ok := d.items[key]; ok {
+		d.order.Remove(e)
+	}
+
+	d.items[key] = d.order.PushBack(&dedupeEntry{key: key, expires: d.clock.Now().Add(d.window)})
+
+	for d.order.Len() > d.size {
+		e := d.order.Front()
+		d.order.Remove(e)
+		delete(d.items, e.Value.(*dedupeEntry).key)
+	}
+}
+
+func (d *dedupe) expire() {
+
+	now := d.clock.Now()
+
+	// the list is sorted by expiration time
+	for e := d.order.Front(); e != nil; e = d.order.Front() {
+		entry := e.Value.(*dedupeEntry)
+		if entry.expires.After(now) {
+			break
+		}
+
+		d.order.Remove(e)
+		delete(d.items, entry.key)
+	}
+}
+
+func dedupeKey(msg *kafka.Message) string {
+
+	var topic string
+	if msg.TopicPartition.Topic != nil {
+		topic = *msg.TopicPartition.Topic
+	}
+
+	h := sha256.New()
+	for _, field := range [][]byte{[]byte(topic), msg.Key, msg.Value} {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(field)))
+		_, _ = h.Write(size[:])
+		_, _ = h.Write(field)
+	}
+
+	return string(h.Sum(nil))
+}
@@ -0,0 +1,89 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDedupeDisabled(t *testing.T) {
+	require.Nil(t, newDedupe(nil, clock.Real))
+	require.Nil(t, newDedupe(&DedupeConfig{}, clock.Real))
+
+	var d *dedupe
+	d.Add("k")
+	require.False(t, d.Seen("k"))
+}
+
+func TestDedupeWindow(t *testing.T) {
+
+	clk := mock.NewClock(time.Now())
+	d := newDedupe(&DedupeConfig{Window: time.Minute}, clk)
+
+	key := func(key, value string) string {
+		return dedupeKey(&kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: stringPointer("t1")},
+			Key:            []byte(key),
+			Value:          []byte(value),
+		})
+	}
+
+	require.False(t, d.Seen(key("k1", "v1")))
+	d.Add(key("k1", "v1"))
+	require.True(t, d.Seen(key("k1", "v1")))
+	require.False(t, d.Seen(key("k1", "v2")))
+	require.False(t, d.Seen(key("k2", "v1")))
+
+	clk.Add(time.Minute)
+	require.False(t, d.Seen(key("k1", "v1")))
+	require.Zero(t, d.order.Len())
+
+	// the bytes of the fields don't move between the fields
+	require.NotEqual(t, key("k1", "v1"), key("k", "1v1"))
+}
+
+func TestDedupeSize(t *testing.T) {
+
+	d := newDedupe(&DedupeConfig{Window: time.Minute, Size: 2}, clock.Real)
+
+	d.Add("1")
+	d.Add("2")
+	d.Add("3")
+	require.Len(t, d.items, 2)
+
+	// the oldest message is evicted
+	require.False(t, d.Seen("1"))
+	require.True(t, d.Seen("3"))
+}
+
+func TestDedupeAfterSuccess(t *testing.T) {
+
+	var processed int
+	failure := errors.New("failed")
+	c, _ := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.Dedupe = &DedupeConfig{Window: time.Minute}
+		cfg.OnProcess = func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error {
+			processed++
+			if processed == 1 {
+				return failure
+			}
+			return nil
+		}
+	}))
+
+	topic := "a"
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: 1}, Value: []byte("v")}
+
+	// the failed message isn't remembered: it's processed again after the redelivery
+	require.Equal(t, failure, c.handleMessage(msg, newOffset()))
+	require.NoError(t, c.handleMessage(msg, newOffset()))
+	require.NoError(t, c.handleMessage(msg, newOffset()))
+	require.Equal(t, 2, processed)
+}
@@ -0,0 +1,35 @@
+package consumer
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CheckBrokers fetches the metadata of the brokers (e.g. the self-test of the service)
+func (c *Consumer) CheckBrokers(ctx context.Context) error {
+	return checkBrokers(ctx, c.reader)
+}
+
+func checkBrokers(ctx context.Context, r metadataReader) error {
+
+	timeout := time.Second * 5
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	if timeout <= 0 {
+		return errors.Wrap(context.DeadlineExceeded, "failed to get metadata")
+	}
+
+	metadata, err := r.GetMetadata(nil, false, int(timeout/time.Millisecond))
+	if err != nil {
+		return errors.Wrap(err, "failed to get metadata")
+	}
+
+	if len(metadata.Brokers) == 0 {
+		return errors.New("no brokers in metadata")
+	}
+
+	return nil
+}
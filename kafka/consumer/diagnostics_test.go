@@ -0,0 +1,37 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type brokersReader struct {
+	brokers []kafka.BrokerMetadata
+	err     error
+}
+
+func (r *brokersReader) GetMetadata(_ *string, _ bool, _ int) (*kafka.Metadata, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return &kafka.Metadata{Brokers: r.brokers}, nil
+}
+
+func TestCheckBrokers(t *testing.T) {
+
+	ctx := context.Background()
+
+	require.NoError(t, checkBrokers(ctx, &brokersReader{brokers: []kafka.BrokerMetadata{{ID: 1}}}))
+	require.EqualError(t, checkBrokers(ctx, &brokersReader{}), "no brokers in metadata")
+	require.EqualError(t, checkBrokers(ctx, &brokersReader{err: errors.New("down")}), "failed to get metadata: down")
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	require.True(t, errors.Is(checkBrokers(ctx, &brokersReader{}), context.DeadlineExceeded))
+}
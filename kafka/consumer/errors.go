@@ -0,0 +1,99 @@
+package consumer
+
+import (
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrAlreadyClosed is returned by the closed consumer
+	ErrAlreadyClosed = errors.New("consumer already closed")
+	// ErrGroupAlreadyClosed is returned by the closed consumers group
+	ErrGroupAlreadyClosed = errors.New("consumers group already closed")
+	// ErrSubscribeFailed is returned if the consumer can't subscribe to the topics
+	ErrSubscribeFailed = errors.New("subscribe to topics failed")
+	// ErrUnsubscribeFailed is returned if the consumer can't unsubscribe from the topics
+	ErrUnsubscribeFailed = errors.New("unsubscribe failed")
+	// ErrTopicsNotFound is returned if the topics don't exist after the waiting (see Config.TopicWait)
+	ErrTopicsNotFound = errors.New("topics not found")
+	// ErrDrainTimeout is returned by StopWithTimeout if the in-flight messages aren't processed in the timeout
+	ErrDrainTimeout = errors.New("drain timed out")
+	// ErrNotRunning is returned by the health check of the consumer that isn't started
+	ErrNotRunning = errors.New("consumer is not running")
+	// ErrStuck is returned by the health check if the events aren't handled longer than Config.HealthTimeout
+	ErrStuck = errors.New("consumer is stuck")
+	// ErrInvalidConfig is returned by Config.Check
+	ErrInvalidConfig = errors.New("invalid config")
+	// ErrRetryLater is returned by OnProcess to process the message again after the sleep of its partition
+	ErrRetryLater = errors.New("retry message later")
+)
+
+// A CommitError is an error of the offsets commit
+type CommitError struct {
+	Partitions []kafka.TopicPartition
+	Err        error
+}
+
+func (e *CommitError) Error() string {
+	return "failed to commit offsets: " + e.Err.Error()
+}
+
+// Unwrap returns the cause (errors.Unwrap implementation)
+func (e *CommitError) Unwrap() error {
+	return e.Err
+}
+
+// A GroupError is returned by the operation applied to all workers of the group (see Group.SleepAll)
+type GroupError struct {
+	Operation string
+	// Errors of the failed workers
+	Errors []error
+}
+
+func (e *GroupError) Error() string {
+
+	parts := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		parts[i] = err.Error()
+	}
+
+	return "failed to " + e.Operation + " consumer group: " + strings.Join(parts, "; ")
+}
+
+// Unwrap returns the error of the first failed worker (errors.Unwrap implementation)
+func (e *GroupError) Unwrap() error {
+	return e.Errors[0]
+}
+
+type sentinelError struct {
+	sentinel error
+	cause    error
+}
+
+func wrapSentinel(sentinel, cause error) error {
+	return &sentinelError{sentinel: sentinel, cause: cause}
+}
+
+func (e *sentinelError) Error() string {
+	return e.sentinel.Error() + ": " + e.cause.Error()
+}
+
+func (e *sentinelError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+func (e *sentinelError) Unwrap() error {
+	return e.cause
+}
+
+type configError string
+
+func (e configError) Error() string {
+	return string(e)
+}
+
+func (e configError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
@@ -0,0 +1,40 @@
+package consumer
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrors(t *testing.T) {
+
+	cause := kafka.NewError(kafka.ErrInvalidArg, "invalid", false)
+
+	err := errors.Wrap(wrapSentinel(ErrSubscribeFailed, cause), "start")
+	require.EqualError(t, err, "start: subscribe to topics failed: invalid")
+	require.True(t, errors.Is(err, ErrSubscribeFailed))
+	require.False(t, errors.Is(err, ErrUnsubscribeFailed))
+
+	var kafkaErr kafka.Error
+	require.True(t, errors.As(err, &kafkaErr))
+	require.Equal(t, kafka.ErrInvalidArg, kafkaErr.Code())
+
+	partitions := []kafka.TopicPartition{{Topic: stringPointer("t1"), Partition: 1}}
+	err = errors.Wrap(&CommitError{Partitions: partitions, Err: cause}, "stop")
+	require.EqualError(t, err, "stop: failed to commit offsets: invalid")
+
+	var commitErr *CommitError
+	require.True(t, errors.As(err, &commitErr))
+	require.Equal(t, partitions, commitErr.Partitions)
+	require.True(t, errors.As(err, &kafkaErr))
+
+	err = &GroupError{Operation: "sleep", Errors: []error{cause, ErrAlreadyClosed}}
+	require.EqualError(t, err, "failed to sleep consumer group: invalid; consumer already closed")
+	require.True(t, errors.As(err, &kafkaErr))
+
+	err = (&Config{}).Check()
+	require.True(t, errors.Is(err, ErrInvalidConfig))
+	require.EqualError(t, err, "on error callback is nil")
+}
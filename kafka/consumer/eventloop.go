@@ -0,0 +1,120 @@
+package consumer
+
+import (
+	"context"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock"
+)
+
+type eventHandler interface {
+	// handleEvent is invoked before any event
+	handleEvent(ev kafka.Event)
+	handleRebalance(e *kafka.AssignedPartitions, consumerOffsets *offset) error
+	handleRevoke(e *kafka.RevokedPartitions, consumerOffsets *offset) error
+	handleMessage(e *kafka.Message, consumerOffsets *offset) error
+	handlePartitionEOF(e *kafka.PartitionEOF, consumerOffsets *offset) error
+	handleOffsetCommitted(e *kafka.OffsetsCommitted, consumerOffsets *offset) error
+	handleError(e kafka.Error)
+	handleStats(e *kafka.Stats)
+	handleOAuthBearerTokenRefresh(e *kafka.OAuthBearerTokenRefresh)
+	handleUnknown(e kafka.Event)
+	commitOffsets(consumerOffsets *offset) error
+	// commitScheduled is invoked by the commit interval (see Config.CommitStrategy)
+	commitScheduled(consumerOffsets *offset)
+	// handleTick is invoked periodically (see runEventLoop)
+	handleTick(consumerOffsets *offset) error
+	// handleFinalCommit is invoked once before exit
+	handleFinalCommit(consumerOffsets *offset)
+}
+
+type loopRequest func(consumerOffsets *offset)
+
+func runEventLoop(ctx context.Context, clk clock.Clock, events <-chan kafka.Event, commitRequests <-chan chan error, requests <-chan loopRequest, commitOffsetDuration, tickDuration time.Duration, h eventHandler) error {
+
+	consumerOffsets := newOffset()
+	defer h.handleFinalCommit(consumerOffsets)
+
+	offsetsTicker := clk.NewTicker(commitOffsetDuration)
+	defer offsetsTicker.Stop()
+
+	var ticks <-chan time.Time
+	if tickDuration > 0 {
+		ticker := clk.NewTicker(tickDuration)
+		defer ticker.Stop()
+		ticks = ticker.C()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-offsetsTicker.C():
+			h.commitScheduled(consumerOffsets)
+
+		case <-ticks:
+			if err := h.handleTick(consumerOffsets); err != nil {
+				return err
+			}
+
+		case res := <-commitRequests:
+			res <- h.commitOffsets(consumerOffsets)
+
+		case fn := <-requests:
+			fn(consumerOffsets)
+
+		case ev := <-events:
+			if err := dispatchEvent(ev, consumerOffsets, h); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func dispatchEvent(ev kafka.Event, consumerOffsets *offset, h eventHandler) error {
+
+	h.handleEvent(ev)
+
+	switch e := ev.(type) {
+	case kafka.AssignedPartitions:
+		// consumer group rebalance event: assigned partition set
+		return h.handleRebalance(&e, consumerOffsets)
+
+	case kafka.RevokedPartitions:
+		// consumer group rebalance event: revoked partition set
+		return h.handleRevoke(&e, consumerOffsets)
+
+	case *kafka.Message:
+		return h.handleMessage(e, consumerOffsets)
+
+	case kafka.PartitionEOF:
+		// consumer reached end of partition
+		// Needs to be explicitly enabled by setting the `enable.partition.eof`
+		// configuration property to true.
+		return h.handlePartitionEOF(&e, consumerOffsets)
+
+	case kafka.OffsetsCommitted:
+		// reports committed offsets
+		// https://godoc.org/github.com/confluentinc/confluent-kafka-go/kafka#hdr-Consumer_events
+		// Offset commit results (when `enable.auto.commit` is enabled)
+		return h.handleOffsetCommitted(&e, consumerOffsets)
+
+	case kafka.Error:
+		h.handleError(e)
+
+	case *kafka.Stats:
+		// statistics (the 'statistics.interval.ms' property must be set)
+		h.handleStats(e)
+
+	case kafka.OAuthBearerTokenRefresh:
+		// retrieval of a new SASL/OAUTHBEARER token is required
+		h.handleOAuthBearerTokenRefresh(&e)
+
+	default:
+		h.handleUnknown(e)
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type testEventHandler struct {
+	calls      []string
+	commits    int
+	errMessage error
+	errTick    error
+	mu         sync.Mutex
+}
+
+func (h *testEventHandler) add(name string) {
+	h.mu.Lock()
+	h.calls = append(h.calls, name)
+	h.mu.Unlock()
+}
+
+func (h *testEventHandler) getCalls() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]string{}, h.calls...)
+}
+
+func (h *testEventHandler) getCommits() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.commits
+}
+
+func (h *testEventHandler) handleEvent(kafka.Event) {}
+
+func (h *testEventHandler) handleRebalance(*kafka.AssignedPartitions, *offset) error {
+	h.add("rebalance")
+	return nil
+}
+
+func (h *testEventHandler) handleRevoke(*kafka.RevokedPartitions, *offset) error {
+	h.add("revoke")
+	return nil
+}
+
+func (h *testEventHandler) handleMessage(e *kafka.Message, o *offset) error {
+	h.add("message")
+	o.Add(e.TopicPartition)
+	return h.errMessage
+}
+
+func (h *testEventHandler) handlePartitionEOF(*kafka.PartitionEOF, *offset) error {
+	h.add("eof")
+	return nil
+}
+
+func (h *testEventHandler) handleOffsetCommitted(*kafka.OffsetsCommitted, *offset) error {
+	h.add("committed")
+	return nil
+}
+
+func (h *testEventHandler) handleError(kafka.Error) { h.add("error") }
+
+func (h *testEventHandler) handleStats(*kafka.Stats) { h.add("stats") }
+
+func (h *testEventHandler) handleOAuthBearerTokenRefresh(*kafka.OAuthBearerTokenRefresh) {
+	h.add("oauth")
+}
+
+func (h *testEventHandler) handleUnknown(kafka.Event) { h.add("unknown") }
+
+func (h *testEventHandler) commitOffsets(o *offset) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if o.Counter() > 0 {
+		h.commits++
+		o.Clear()
+	}
+
+	return nil
+}
+
+func (h *testEventHandler) commitScheduled(o *offset) {
+	_ = h.commitOffsets(o)
+}
+
+func (h *testEventHandler) handleTick(*offset) error {
+	h.add("tick")
+	return h.errTick
+}
+
+func (h *testEventHandler) handleFinalCommit(o *offset) {
+	h.add("final commit")
+	_ = h.commitOffsets(o)
+}
+
+func TestEventLoopDispatch(t *testing.T) {
+
+	h := &testEventHandler{}
+	events := make(chan kafka.Event, 10)
+
+	events <- kafka.AssignedPartitions{}
+	events <- &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: stringPointer("t1"), Offset: 1}}
+	events <- kafka.PartitionEOF{}
+	events <- kafka.OffsetsCommitted{}
+	events <- kafka.NewError(kafka.ErrAllBrokersDown, "down", false)
+	events <- &kafka.Stats{}
+	events <- kafka.OAuthBearerTokenRefresh{}
+	events <- kafka.PartitionEOF{}
+	events <- kafka.RevokedPartitions{}
+	events <- kafka.OffsetsCommitted{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- runEventLoop(ctx, clock.Real, events, nil, nil, time.Hour, 0, h) }()
+
+	require.Eventually(t, func() bool { return len(events) == 0 && len(h.getCalls()) == 10 }, time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	require.Equal(t,
+		[]string{"rebalance", "message", "eof", "committed", "error", "stats", "oauth", "eof", "revoke", "committed", "final commit"},
+		h.getCalls())
+	// the final commit
+	require.Equal(t, 1, h.getCommits())
+}
+
+func TestEventLoopUnknownEvent(t *testing.T) {
+
+	h := &testEventHandler{}
+	require.NoError(t, dispatchEvent(kafka.OffsetsCommitted{}, newOffset(), h))
+	require.NoError(t, dispatchEvent(nil, newOffset(), h))
+	require.Equal(t, []string{"committed", "unknown"}, h.getCalls())
+}
+
+func TestEventLoopError(t *testing.T) {
+
+	h := &testEventHandler{errMessage: errors.New("fail")}
+	events := make(chan kafka.Event, 1)
+	events <- &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: stringPointer("t1"), Offset: 1}}
+
+	require.EqualError(t, runEventLoop(context.Background(), clock.Real, events, nil, nil, time.Hour, 0, h), "fail")
+	require.Equal(t, 1, h.getCommits())
+}
+
+func TestEventLoopTicker(t *testing.T) {
+
+	h := &testEventHandler{}
+	events := make(chan kafka.Event, 1)
+	events <- &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: stringPointer("t1"), Offset: 1}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() { _ = runEventLoop(ctx, clock.Real, events, nil, nil, time.Millisecond, 0, h) }()
+
+	require.Eventually(t, func() bool { return h.getCommits() == 1 }, time.Second, time.Millisecond)
+}
+
+func TestEventLoopClock(t *testing.T) {
+
+	h := &testEventHandler{}
+	events := make(chan kafka.Event, 1)
+	events <- &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: stringPointer("t1"), Offset: 1}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clk := mock.NewClock(time.Now())
+	go func() { _ = runEventLoop(ctx, clk, events, nil, nil, time.Minute, 0, h) }()
+
+	require.Eventually(t, func() bool { return len(h.getCalls()) == 1 && clk.Waiters() == 1 }, time.Second, time.Millisecond)
+
+	clk.Add(time.Second * 59)
+	require.Equal(t, 0, h.getCommits())
+
+	clk.Add(time.Second)
+	require.Eventually(t, func() bool { return h.getCommits() == 1 }, time.Second, time.Millisecond)
+}
+
+func TestEventLoopCommitRequest(t *testing.T) {
+
+	h := &testEventHandler{}
+	events := make(chan kafka.Event, 1)
+	events <- &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: stringPointer("t1"), Offset: 1}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	commitRequests := make(chan chan error)
+	go func() { _ = runEventLoop(ctx, clock.Real, events, commitRequests, nil, time.Hour, 0, h) }()
+
+	require.Eventually(t, func() bool { return len(h.getCalls()) == 1 }, time.Second, time.Millisecond)
+
+	res := make(chan error, 1)
+	commitRequests <- res
+	require.NoError(t, <-res)
+	require.Equal(t, 1, h.getCommits())
+}
+
+func TestEventLoopRequest(t *testing.T) {
+
+	h := &testEventHandler{}
+	events := make(chan kafka.Event)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	requests := make(chan loopRequest)
+	go func() { _ = runEventLoop(ctx, clock.Real, events, nil, requests, time.Hour, 0, h) }()
+
+	res := make(chan *offset, 1)
+	requests <- func(consumerOffsets *offset) { res <- consumerOffsets }
+	require.NotNil(t, <-res)
+}
+
+func TestEventLoopTick(t *testing.T) {
+
+	h := &testEventHandler{errTick: errors.New("tick failed")}
+	events := make(chan kafka.Event)
+
+	require.EqualError(t, runEventLoop(context.Background(), clock.Real, events, nil, nil, time.Hour, time.Millisecond, h), "tick failed")
+	require.Equal(t, []string{"tick", "final commit"}, h.getCalls())
+}
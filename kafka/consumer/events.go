@@ -0,0 +1,253 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type FuncOnEvent func(ctx context.Context, logger *zap.Logger, e kafka.Event)
+
+// FuncOnStats receives the JSON of the statistics of librdkafka (see ParseStats and Config.StatsInterval)
+type FuncOnStats func(ctx context.Context, logger *zap.Logger, statsJSON string)
+type FuncOnThrottle func(ctx context.Context, logger *zap.Logger, e []Throttle)
+type FuncOnOAuthBearerTokenRefresh func(ctx context.Context, logger *zap.Logger, e *kafka.OAuthBearerTokenRefresh, h kafka.Handle)
+
+// FuncTokenProvider returns the token of the SASL/OAUTHBEARER authentication (see Config.TokenProvider)
+type FuncTokenProvider func(ctx context.Context) (kafka.OAuthBearerToken, error)
+
+// A Throttle is a throttle time of the broker (read from the statistics, see Config.StatsInterval)
+type Throttle struct {
+	Broker   string
+	NodeID   int32
+	Duration time.Duration
+}
+
+// Stats is a part of the statistics of librdkafka (https://github.com/edenhill/librdkafka/blob/master/STATISTICS.md)
+type Stats struct {
+	Name string `json:"name"`
+	// ReplyQueue is a count of the ops waiting in the queue for the application
+	ReplyQueue int64                  `json:"replyq"`
+	Brokers    map[string]BrokerStats `json:"brokers"`
+	Topics     map[string]TopicStats  `json:"topics"`
+}
+
+// A StatsWindow is a rolling window of the values (the microseconds of the latencies, the milliseconds of the throttle)
+type StatsWindow struct {
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+	Avg int64 `json:"avg"`
+	P99 int64 `json:"p99"`
+}
+
+// BrokerStats are the statistics of the broker connection
+type BrokerStats struct {
+	Name   string `json:"name"`
+	NodeID int32  `json:"nodeid"`
+	State  string `json:"state"`
+	// OutbufCount is a count of the requests waiting to be sent, WaitrespCount is a count of the requests in flight
+	OutbufCount   int64       `json:"outbuf_cnt"`
+	WaitrespCount int64       `json:"waitresp_cnt"`
+	RTT           StatsWindow `json:"rtt"`
+	Throttle      StatsWindow `json:"throttle"`
+}
+
+// TopicStats are the statistics of the partitions of the topic
+type TopicStats struct {
+	Topic      string                    `json:"topic"`
+	Partitions map[string]PartitionStats `json:"partitions"`
+}
+
+// PartitionStats are the statistics of the partition (the internal partition -1 is included)
+type PartitionStats struct {
+	Partition int32 `json:"partition"`
+	// FetchQueueCount is a count of the prefetched messages
+	FetchQueueCount int64 `json:"fetchq_cnt"`
+	ConsumerLag     int64 `json:"consumer_lag"`
+}
+
+// ParseStats parses the JSON of the statistics
+func ParseStats(statsJSON string) (*Stats, error) {
+
+	retval := &Stats{}
+	if err := json.Unmarshal([]byte(statsJSON), retval); err != nil {
+		return nil, err
+	}
+
+	return retval, nil
+}
+
+// ParseThrottle returns the brokers with non-zero throttle time from the statistics
+func ParseThrottle(e *kafka.Stats) ([]Throttle, error) {
+	return parseThrottle(e.String())
+}
+
+func parseThrottle(statsJSON string) ([]Throttle, error) {
+
+	payload, err := ParseStats(statsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return throttleOf(payload), nil
+}
+
+func throttleOf(payload *Stats) []Throttle {
+
+	retval := make([]Throttle, 0)
+	for _, b := range payload.Brokers {
+		if b.Throttle.Max > 0 {
+			retval = append(retval, Throttle{
+				Broker:   b.Name,
+				NodeID:   b.NodeID,
+				Duration: time.Duration(b.Throttle.Max) * time.Millisecond,
+			})
+		}
+	}
+
+	sort.Slice(retval, func(i, j int) bool {
+		return retval[i].Broker < retval[j].Broker
+	})
+
+	return retval
+}
+
+func (c *Consumer) handleStats(e *kafka.Stats) {
+
+	opLog := c.logger.With(zap.String("operation", "stats"))
+	statsJSON := e.String()
+
+	if c.onStats != nil {
+		c.onStats(c.ctx, opLog, statsJSON)
+	}
+
+	throttle := c.onThrottle != nil || c.inflight.metrics.Throttle != nil || c.throttleBackoffFactor > 0
+	if !throttle && c.inflight.metrics.QueueDepth == nil {
+		return
+	}
+
+	payload, err := ParseStats(statsJSON)
+	if err != nil {
+		opLog.Error("failed to parse statistics", zap.Error(err))
+		c.onError(c.ctx, opLog, err)
+		return
+	}
+
+	c.inflight.SetQueueDepth(int(payload.ReplyQueue))
+	if !throttle {
+		return
+	}
+
+	list := throttleOf(payload)
+	if len(list) == 0 {
+		return
+	}
+
+	opLog.Warn("throttled", zap.Any("brokers", list))
+
+	if observe := c.inflight.metrics.Throttle; observe != nil {
+		for i := range list {
+			observe(list[i].Broker).Observe(list[i].Duration.Seconds())
+		}
+	}
+
+	if c.onThrottle != nil {
+		c.onThrottle(c.ctx, opLog, list)
+	}
+
+	if delay := throttleDelay(list, c.throttleBackoffFactor, c.throttleBackoffMax); delay > 0 {
+		partitions, err := c.reader.Assignment()
+		if err != nil {
+			opLog.Error("failed to get assignment", zap.Error(err))
+			c.onError(c.ctx, opLog, err)
+			return
+		}
+
+		opLog.Info("backoff", zap.Duration("delay", delay))
+		if err := c.Sleep(delay, partitions); err != nil {
+			c.onError(c.ctx, opLog, err)
+		}
+	}
+}
+
+func throttleDelay(list []Throttle, factor float64, max time.Duration) time.Duration {
+
+	if factor <= 0 {
+		return 0
+	}
+
+	var retval time.Duration
+	for i := range list {
+		if list[i].Duration > retval {
+			retval = list[i].Duration
+		}
+	}
+
+	retval = time.Duration(float64(retval) * factor)
+	if max > 0 && retval > max {
+		retval = max
+	}
+
+	return retval
+}
+
+func (c *Consumer) handleOAuthBearerTokenRefresh(e *kafka.OAuthBearerTokenRefresh) {
+
+	opLog := c.logger.With(zap.String("operation", "oauthbearer token refresh"))
+
+	if c.onOAuthBearerTokenRefresh == nil {
+		opLog.Warn("token refresh handler is not set")
+		return
+	}
+
+	c.onOAuthBearerTokenRefresh(c.ctx, opLog, e, c.reader)
+}
+
+func newTokenRefreshHandler(provider FuncTokenProvider) FuncOnOAuthBearerTokenRefresh {
+
+	return func(ctx context.Context, logger *zap.Logger, _ *kafka.OAuthBearerTokenRefresh, h kafka.Handle) {
+		token, err := provider(ctx)
+		if err == nil {
+			err = h.SetOAuthBearerToken(token)
+		}
+
+		if err != nil {
+			logger.Error("failed to refresh token", zap.Error(err))
+			if errFailure := h.SetOAuthBearerTokenFailure(err.Error()); errFailure != nil {
+				logger.Error("failed to set token failure", zap.Error(errFailure))
+			}
+		}
+	}
+}
+
+func setStatsInterval(cfg *Config) error {
+
+	interval := cfg.StatsInterval
+	if interval <= 0 {
+		if _, ok := (*cfg.ConfigMap)["statistics.interval.ms"]; ok || !statsRequired(cfg) {
+			return nil
+		}
+		interval = time.Minute
+	}
+
+	ms := int(interval / time.Millisecond)
+	if err := cfg.ConfigMap.SetKey("statistics.interval.ms", ms); err != nil {
+		return errors.Wrapf(err, "set config statistics.interval.ms to %d failed", ms)
+	}
+
+	return nil
+}
+
+func statsRequired(cfg *Config) bool {
+
+	if cfg.OnStats != nil || cfg.OnThrottle != nil || cfg.ThrottleBackoffFactor > 0 {
+		return true
+	}
+
+	return cfg.Metrics != nil && (cfg.Metrics.QueueDepth != nil || cfg.Metrics.Throttle != nil)
+}
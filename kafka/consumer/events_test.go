@@ -0,0 +1,161 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/metric"
+	"github.com/dialogs/dialog-go-lib/metric/mock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseThrottle(t *testing.T) {
+
+	list, err := parseThrottle(`{
+		"name": "rdkafka#consumer-1",
+		"brokers": {
+			"b2:9092/2": {"name": "b2:9092/2", "nodeid": 2, "throttle": {"min": 0, "max": 150, "avg": 20}},
+			"b1:9092/1": {"name": "b1:9092/1", "nodeid": 1, "throttle": {"min": 0, "max": 0, "avg": 0}},
+			"b3:9092/3": {"name": "b3:9092/3", "nodeid": 3, "throttle": {"min": 0, "max": 1000, "avg": 500}}
+		}
+	}`)
+	require.NoError(t, err)
+	require.Equal(t,
+		[]Throttle{
+			{Broker: "b2:9092/2", NodeID: 2, Duration: 150 * time.Millisecond},
+			{Broker: "b3:9092/3", NodeID: 3, Duration: time.Second},
+		},
+		list)
+
+	list, err = parseThrottle(`{}`)
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	_, err = parseThrottle(`{`)
+	require.Error(t, err)
+}
+
+func TestParseStats(t *testing.T) {
+
+	stats, err := ParseStats(`{
+		"name": "rdkafka#consumer-1",
+		"replyq": 3,
+		"brokers": {
+			"b1:9092/1": {"name": "b1:9092/1", "nodeid": 1, "state": "UP", "outbuf_cnt": 2, "waitresp_cnt": 1,
+				"rtt": {"min": 100, "max": 900, "avg": 300, "p99": 850}}
+		},
+		"topics": {
+			"t1": {"topic": "t1", "partitions": {"0": {"partition": 0, "fetchq_cnt": 10, "consumer_lag": 42}}}
+		}
+	}`)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), stats.ReplyQueue)
+	require.Equal(t, BrokerStats{
+		Name:          "b1:9092/1",
+		NodeID:        1,
+		State:         "UP",
+		OutbufCount:   2,
+		WaitrespCount: 1,
+		RTT:           StatsWindow{Min: 100, Max: 900, Avg: 300, P99: 850},
+	}, stats.Brokers["b1:9092/1"])
+	require.Equal(t, PartitionStats{FetchQueueCount: 10, ConsumerLag: 42}, stats.Topics["t1"].Partitions["0"])
+
+	_, err = ParseStats(`{`)
+	require.Error(t, err)
+}
+
+func TestSetStatsInterval(t *testing.T) {
+
+	onStats := func(context.Context, *zap.Logger, string) {}
+
+	// disabled
+	cfg := &Config{ConfigMap: &kafka.ConfigMap{}}
+	require.NoError(t, setStatsInterval(cfg))
+	require.Empty(t, *cfg.ConfigMap)
+
+	// by default for OnStats
+	cfg = &Config{ConfigMap: &kafka.ConfigMap{}, OnStats: onStats}
+	require.NoError(t, setStatsInterval(cfg))
+	require.Equal(t, kafka.ConfigMap{"statistics.interval.ms": 60000}, *cfg.ConfigMap)
+
+	// the property is kept
+	cfg = &Config{ConfigMap: &kafka.ConfigMap{"statistics.interval.ms": 5000}, OnStats: onStats}
+	require.NoError(t, setStatsInterval(cfg))
+	require.Equal(t, kafka.ConfigMap{"statistics.interval.ms": 5000}, *cfg.ConfigMap)
+
+	// by default for the queue depth metric
+	cfg = &Config{ConfigMap: &kafka.ConfigMap{}, Metrics: &Metrics{QueueDepth: mock.NewGauge()}}
+	require.NoError(t, setStatsInterval(cfg))
+	require.Equal(t, kafka.ConfigMap{"statistics.interval.ms": 60000}, *cfg.ConfigMap)
+
+	// by default for the throttle options
+	cfg = &Config{ConfigMap: &kafka.ConfigMap{}, ThrottleBackoffFactor: 2}
+	require.NoError(t, setStatsInterval(cfg))
+	require.Equal(t, kafka.ConfigMap{"statistics.interval.ms": 60000}, *cfg.ConfigMap)
+
+	cfg = &Config{ConfigMap: &kafka.ConfigMap{}, OnThrottle: func(context.Context, *zap.Logger, []Throttle) {}}
+	require.NoError(t, setStatsInterval(cfg))
+	require.Equal(t, kafka.ConfigMap{"statistics.interval.ms": 60000}, *cfg.ConfigMap)
+
+	cfg = &Config{ConfigMap: &kafka.ConfigMap{}, Metrics: &Metrics{Throttle: func(string) metric.IObserver { return mock.NewObserver() }}}
+	require.NoError(t, setStatsInterval(cfg))
+	require.Equal(t, kafka.ConfigMap{"statistics.interval.ms": 60000}, *cfg.ConfigMap)
+
+	cfg = &Config{ConfigMap: &kafka.ConfigMap{"statistics.interval.ms": 5000}, StatsInterval: time.Second}
+	require.NoError(t, setStatsInterval(cfg))
+	require.Equal(t, kafka.ConfigMap{"statistics.interval.ms": 1000}, *cfg.ConfigMap)
+}
+
+func TestThrottleDelay(t *testing.T) {
+
+	list := []Throttle{
+		{Broker: "b1", Duration: time.Second},
+		{Broker: "b2", Duration: 2 * time.Second},
+	}
+
+	require.Equal(t, time.Duration(0), throttleDelay(list, 0, time.Minute))
+	require.Equal(t, time.Duration(0), throttleDelay(nil, 1, time.Minute))
+	require.Equal(t, 2*time.Second, throttleDelay(list, 1, time.Minute))
+	require.Equal(t, time.Second, throttleDelay(list, 0.5, time.Minute))
+	require.Equal(t, 3*time.Second, throttleDelay(list, 10, 3*time.Second))
+	require.Equal(t, 20*time.Second, throttleDelay(list, 10, 0))
+}
+
+type testTokenHandle struct {
+	kafka.Handle
+	token   *kafka.OAuthBearerToken
+	failure string
+}
+
+func (h *testTokenHandle) SetOAuthBearerToken(token kafka.OAuthBearerToken) error {
+	h.token = &token
+	return nil
+}
+
+func (h *testTokenHandle) SetOAuthBearerTokenFailure(errstr string) error {
+	h.failure = errstr
+	return nil
+}
+
+func TestTokenRefreshHandler(t *testing.T) {
+
+	token := kafka.OAuthBearerToken{TokenValue: "value", Principal: "user", Expiration: time.Unix(100, 0)}
+
+	h := &testTokenHandle{}
+	newTokenRefreshHandler(func(context.Context) (kafka.OAuthBearerToken, error) {
+		return token, nil
+	})(context.Background(), zap.NewNop(), &kafka.OAuthBearerTokenRefresh{}, h)
+	require.Equal(t, &token, h.token)
+	require.Empty(t, h.failure)
+
+	h = &testTokenHandle{}
+	newTokenRefreshHandler(func(context.Context) (kafka.OAuthBearerToken, error) {
+		return kafka.OAuthBearerToken{}, errors.New("failed")
+	})(context.Background(), zap.NewNop(), &kafka.OAuthBearerTokenRefresh{}, h)
+	require.Nil(t, h.token)
+	require.Equal(t, "failed", h.failure)
+}
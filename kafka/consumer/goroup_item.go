@@ -24,7 +24,7 @@ func (i *groupItem) HandleStateEvent(e StateEvent) {
 	if e == StateRun {
 		go func() {
 			<-i.closeCtx.Done()
-			i.Stop()
+			_ = i.Stop()
 		}()
 	}
 }
@@ -4,9 +4,12 @@ import (
 	"container/list"
 	"context"
 	"runtime"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
@@ -14,6 +17,10 @@ import (
 type GroupConfig struct {
 	Config  *Config `mapstructure:"config"`
 	Workers int     `mapstructure:"workers"`
+	// ClientID is a prefix of the client.id property of the workers: <ClientID>-<worker number>
+	ClientID string `mapstructure:"client_id"`
+	// Rack is a value of the client.rack property for fetching from the closest replica (KIP-392)
+	Rack string `mapstructure:"rack"`
 }
 
 type Group struct {
@@ -21,18 +28,19 @@ type Group struct {
 	logger    *zap.Logger
 	ctx       context.Context
 	ctxCancel func()
+	draining  int32
 	mu        sync.RWMutex
 	wg        sync.WaitGroup
 }
 
 func NewGroup(cfg GroupConfig, logger *zap.Logger) (*Group, error) {
 
-	id, err := uuid.NewUUID()
+	id, err := newID(cfg.Config.IDGenerator)
 	if err != nil {
 		return nil, err
 	}
 
-	logger = logger.With(zap.String("consumers group", id.String()))
+	logger = logger.With(zap.String("consumers group", id))
 
 	workers := cfg.Workers
 	if workers <= 0 {
@@ -41,7 +49,12 @@ func NewGroup(cfg GroupConfig, logger *zap.Logger) (*Group, error) {
 
 	consumers := list.New()
 	for i := 0; i < workers; i++ {
-		c, err := New(cfg.Config, logger)
+		workerCfg, err := newWorkerConfig(cfg, id, i)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to start consumer group")
+		}
+
+		c, err := New(workerCfg, logger)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to start consumer group")
 		}
@@ -59,6 +72,34 @@ func NewGroup(cfg GroupConfig, logger *zap.Logger) (*Group, error) {
 	}, nil
 }
 
+func newWorkerConfig(cfg GroupConfig, groupID string, number int) (*Config, error) {
+
+	retval := cfg.Config.Clone()
+	if retval.ConfigMap == nil {
+		return retval, nil
+	}
+
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = groupID
+	}
+
+	props := kafka.ConfigMap{
+		"client.id": clientID + "-" + strconv.Itoa(number),
+	}
+	if cfg.Rack != "" {
+		props["client.rack"] = cfg.Rack
+	}
+
+	for k, v := range props {
+		if err := retval.ConfigMap.SetKey(k, v); err != nil {
+			return nil, errors.Wrapf(err, "set config %s to %v failed", k, v)
+		}
+	}
+
+	return retval, nil
+}
+
 func (g *Group) Start() error {
 
 	g.logger.Info("wait for start")
@@ -68,7 +109,7 @@ func (g *Group) Start() error {
 
 	select {
 	case <-g.ctx.Done():
-		return errors.New("consumers group already closed")
+		return ErrGroupAlreadyClosed
 	default:
 		// ok
 	}
@@ -83,7 +124,13 @@ func (g *Group) Start() error {
 		go func(c *Consumer) {
 			defer g.wg.Done()
 
-			retval <- newGroupItem(c, g.ctx).Start()
+			err := newGroupItem(c, g.ctx).Start()
+			if err == nil && atomic.LoadInt32(&g.draining) == 1 {
+				// the other workers are still drained
+				return
+			}
+
+			retval <- err
 		}(item.Value.(*Consumer))
 	}
 
@@ -97,12 +144,126 @@ func (g *Group) Start() error {
 	return <-retval
 }
 
-func (g *Group) Stop() {
+// Stop closes the workers and returns the first error of the last offsets commit
+func (g *Group) Stop() error {
+	return g.StopContext(context.Background())
+}
+
+// StopContext closes the workers and waits for them until the context is done
+func (g *Group) StopContext(ctx context.Context) error {
 
 	g.ctxCancel()
 
-	g.mu.Lock() // protection for WaitGroup data race
-	defer g.mu.Unlock()
+	done := make(chan error, 1)
+	go func() {
+		g.mu.Lock() // protection for WaitGroup data race
+		defer g.mu.Unlock()
+
+		g.wg.Wait()
+
+		var err error
+		for item := g.consumers.Front(); item != nil; item = item.Next() {
+			if errStop := item.Value.(*Consumer).Stop(); errStop != nil && err == nil {
+				err = errStop
+			}
+		}
+
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		g.logger.Warn("stop is not completed", zap.Error(ctx.Err()))
+		return errors.Wrap(ctx.Err(), "failed to wait for consumers group closing")
+	}
+}
+
+// Drain stops all workers like StopWithTimeout, so the partitions are reassigned at once
+func (g *Group) Drain(ctx context.Context) error {
+
+	g.logger.Info("drain")
+	atomic.StoreInt32(&g.draining, 1)
+
+	done := make(chan error, g.consumers.Len())
+	for item := g.consumers.Front(); item != nil; item = item.Next() {
+		go func(c *Consumer) {
+			done <- c.Drain(ctx)
+		}(item.Value.(*Consumer))
+	}
+
+	var err error
+	for i := 0; i < g.consumers.Len(); i++ {
+		if errDrain := <-done; errDrain != nil && err == nil {
+			err = errors.Wrap(errDrain, "failed to drain consumer group")
+		}
+	}
+
+	if errStop := g.StopContext(ctx); errStop != nil && err == nil {
+		err = errStop
+	}
+
+	return err
+}
+
+// SleepAll pauses the assigned partitions of all workers for the delay (see GroupError)
+func (g *Group) SleepAll(delay time.Duration) error {
+
+	g.logger.Info("sleep all", zap.Duration("delay", delay))
+
+	var errs []error
+	for item := g.consumers.Front(); item != nil; item = item.Next() {
+		if err := item.Value.(*Consumer).SleepAll(delay); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &GroupError{Operation: "sleep", Errors: errs}
+	}
+
+	return nil
+}
+
+// SleepStatus returns the end of the sleep started by SleepAll
+func (g *Group) SleepStatus() (until time.Time, sleeping bool) {
+
+	for item := g.consumers.Front(); item != nil; item = item.Next() {
+		u, s := item.Value.(*Consumer).SleepStatus()
+		if u.After(until) {
+			until = u
+		}
+		sleeping = sleeping || s
+	}
+
+	return
+}
+
+// ResumeAll cancels all sleeps of the workers
+func (g *Group) ResumeAll() error {
+	return g.each("resume", (*Consumer).ResumeAll)
+}
+
+// Commit commits offsets of the processed messages of the workers
+func (g *Group) Commit() error {
+	return g.each("commit", (*Consumer).Commit)
+}
+
+// Resubscribe triggers a rebalance of the workers
+func (g *Group) Resubscribe() error {
+	return g.each("resubscribe", (*Consumer).Resubscribe)
+}
+
+func (g *Group) each(operation string, fn func(*Consumer) error) error {
+
+	g.logger.Info(operation)
+
+	for item := g.consumers.Front(); item != nil; item = item.Next() {
+		if err := fn(item.Value.(*Consumer)); err != nil {
+			return errors.Wrapf(err, "failed to %s consumer group", operation)
+		}
+	}
 
-	g.wg.Wait()
+	return nil
 }
@@ -1,6 +1,7 @@
 package consumer
 
 import (
+	"container/list"
 	"context"
 	"sort"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
@@ -29,7 +31,7 @@ func TestGroup(t *testing.T) {
 	}
 
 	chMsg := make(chan *kafka.Message, CountMessages)
-	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper) error {
+	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
 		chMsg <- msg
 		return nil
 	}
@@ -113,7 +115,7 @@ func TestGroupGracefulShutdown(t *testing.T) {
 	}
 
 	chMsg := make(chan *kafka.Message, CountMessages)
-	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper) error {
+	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
 		chMsg <- msg
 		return nil
 	}
@@ -132,3 +134,128 @@ func TestGroupGracefulShutdown(t *testing.T) {
 
 	time.Sleep(time.Millisecond) // protection for error: 'consumers group already closed'
 }
+
+func TestGroupSleepAll(t *testing.T) {
+
+	onError := func(_ context.Context, _ *zap.Logger, err error) {
+		require.NoError(t, err)
+	}
+
+	onProcess := func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
+		return nil
+	}
+
+	cfg := GroupConfig{
+		Workers: 2,
+		Config:  newConsumerConfig([]string{"test-sleep-all"}, nil, onError, onProcess, nil, nil, nil),
+	}
+
+	group, err := NewGroup(cfg, newLogger(t))
+	require.NoError(t, err)
+	defer group.Stop()
+
+	until, sleeping := group.SleepStatus()
+	require.False(t, sleeping)
+	require.True(t, until.IsZero())
+
+	require.NoError(t, group.SleepAll(time.Minute))
+
+	until, sleeping = group.SleepStatus()
+	require.True(t, sleeping)
+	require.WithinDuration(t, time.Now().Add(time.Minute), until, time.Second)
+}
+
+func TestGroupWorkerConfig(t *testing.T) {
+
+	cfg := GroupConfig{
+		Config: &Config{
+			ConfigMap: &kafka.ConfigMap{"bootstrap.servers": "b1"},
+		},
+		ClientID: "service",
+		Rack:     "use1-az1",
+	}
+
+	workerCfg, err := newWorkerConfig(cfg, "group", 2)
+	require.NoError(t, err)
+	require.Equal(t,
+		&kafka.ConfigMap{
+			"bootstrap.servers": "b1",
+			"client.id":         "service-2",
+			"client.rack":       "use1-az1",
+		},
+		workerCfg.ConfigMap)
+	require.Equal(t, &kafka.ConfigMap{"bootstrap.servers": "b1"}, cfg.Config.ConfigMap)
+
+	cfg.ClientID = ""
+	cfg.Rack = ""
+	workerCfg, err = newWorkerConfig(cfg, "group", 0)
+	require.NoError(t, err)
+	require.Equal(t,
+		&kafka.ConfigMap{
+			"bootstrap.servers": "b1",
+			"client.id":         "group-0",
+		},
+		workerCfg.ConfigMap)
+}
+
+func TestGroupDrain(t *testing.T) {
+
+	// newDrained returns the worker with the in-flight message: the processing
+	// is continued after the end of the event loop until the message is released
+	newDrained := func(release <-chan struct{}) *Consumer {
+		c, _ := newTestConsumer(t)
+
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			defer c.ctxCancel()
+
+			<-c.loopCtx.Done()
+			select {
+			case <-release:
+			case <-c.ctx.Done():
+				c.stopErr = c.ctx.Err()
+			}
+		}()
+
+		return c
+	}
+
+	newGroup := func(consumers ...*Consumer) *Group {
+		g := &Group{consumers: list.New(), logger: zap.NewNop()}
+		g.ctx, g.ctxCancel = context.WithCancel(context.Background())
+		for _, c := range consumers {
+			g.consumers.PushBack(c)
+		}
+		return g
+	}
+
+	t.Run("drained", func(t *testing.T) {
+		release := make(chan struct{})
+		c1, c2 := newDrained(release), newDrained(release)
+		g := newGroup(c1, c2)
+
+		done := make(chan error, 1)
+		go func() { done <- g.Drain(context.Background()) }()
+
+		// the fetching is stopped, the processing isn't cancelled
+		<-c1.loopCtx.Done()
+		<-c2.loopCtx.Done()
+		require.NoError(t, c1.ctx.Err())
+
+		close(release)
+		require.NoError(t, <-done)
+		require.Error(t, g.ctx.Err())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		g := newGroup(newDrained(nil), newDrained(nil))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+
+		err := g.Drain(ctx)
+		require.True(t, errors.Is(err, ErrDrainTimeout))
+		require.True(t, errors.Is(err, context.Canceled))
+	})
+}
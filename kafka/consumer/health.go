@@ -0,0 +1,69 @@
+package consumer
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	healthNotStarted int32 = iota
+	healthRunning
+	healthClosed
+)
+
+type health struct {
+	state    int32
+	activity int64
+}
+
+func (h *health) SetState(state int32, now time.Time) {
+	atomic.StoreInt64(&h.activity, now.UnixNano())
+	atomic.StoreInt32(&h.state, state)
+}
+
+func (h *health) Touch(now time.Time) {
+	atomic.StoreInt64(&h.activity, now.UnixNano())
+}
+
+// Check returns an error if the consumer isn't running or the event loop is inactive longer than the timeout
+func (h *health) Check(now time.Time, timeout time.Duration) error {
+
+	switch atomic.LoadInt32(&h.state) {
+	case healthNotStarted:
+		return ErrNotRunning
+	case healthClosed:
+		return ErrAlreadyClosed
+	}
+
+	idle := now.Sub(time.Unix(0, atomic.LoadInt64(&h.activity)))
+	if idle > timeout {
+		return errors.Wrapf(ErrStuck, "no activity for %s", idle.Truncate(time.Second))
+	}
+
+	return nil
+}
+
+// HealthCheck returns an error if the consumer isn't running or it's stuck (ErrStuck)
+func (c *Consumer) HealthCheck() error {
+	return c.health.Check(c.clock.Now(), c.healthTimeout)
+}
+
+// HealthCheck returns the first error of the health checks of the workers
+func (g *Group) HealthCheck() error {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	number := 0
+	for item := g.consumers.Front(); item != nil; item = item.Next() {
+		if err := item.Value.(*Consumer).HealthCheck(); err != nil {
+			return errors.Wrap(err, "worker "+strconv.Itoa(number))
+		}
+		number++
+	}
+
+	return nil
+}
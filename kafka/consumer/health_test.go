@@ -0,0 +1,70 @@
+package consumer
+
+import (
+	"container/list"
+	"testing"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// withHealthTimeout sets the max time of the event loop without activity
+func withHealthTimeout(timeout time.Duration) Option {
+	return WithConfig(func(cfg *Config) { cfg.HealthTimeout = timeout })
+}
+
+func TestHealth(t *testing.T) {
+
+	clk := mock.NewClock(time.Unix(1000, 0))
+	c, _ := newTestConsumer(t, WithClock(clk), withHealthTimeout(time.Minute))
+
+	require.Equal(t, ErrNotRunning, c.HealthCheck())
+
+	c.health.SetState(healthRunning, clk.Now())
+	require.NoError(t, c.HealthCheck())
+
+	clk.Add(time.Second * 50)
+	c.health.Touch(clk.Now())
+	clk.Add(time.Second * 50)
+	require.NoError(t, c.HealthCheck())
+
+	// the event loop is blocked
+	clk.Add(time.Second * 11)
+	err := c.HealthCheck()
+	require.True(t, errors.Is(err, ErrStuck))
+	require.EqualError(t, err, "no activity for 1m1s: consumer is stuck")
+
+	c.health.SetState(healthClosed, clk.Now())
+	require.Equal(t, ErrAlreadyClosed, c.HealthCheck())
+}
+
+func TestGroupHealth(t *testing.T) {
+
+	clk := mock.NewClock(time.Unix(1000, 0))
+
+	g := &Group{consumers: list.New()}
+	for i := 0; i < 2; i++ {
+		c, _ := newTestConsumer(t, WithClock(clk), withHealthTimeout(time.Minute))
+		c.health.SetState(healthRunning, clk.Now())
+		g.consumers.PushBack(c)
+	}
+	require.NoError(t, g.HealthCheck())
+
+	g.consumers.Back().Value.(*Consumer).health.SetState(healthClosed, clk.Now())
+	require.EqualError(t, g.HealthCheck(), "worker 1: consumer already closed")
+}
+
+func TestHealthEvents(t *testing.T) {
+
+	clk := mock.NewClock(time.Unix(1000, 0))
+	c, _ := newTestConsumer(t, WithClock(clk), withHealthTimeout(time.Minute))
+	c.health.SetState(healthRunning, clk.Now())
+
+	clk.Add(time.Minute * 2)
+	require.True(t, errors.Is(c.HealthCheck(), ErrStuck))
+
+	c.handleEvent(nil)
+	require.NoError(t, c.HealthCheck())
+}
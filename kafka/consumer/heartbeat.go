@@ -0,0 +1,184 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// FuncOnHeartbeat reports the liveness of the consumer (see Config.HeartbeatInterval)
+type FuncOnHeartbeat func(ctx context.Context, logger *zap.Logger, hb *Heartbeat)
+
+// A PartitionLag is a consumption progress of the partition
+type PartitionLag struct {
+	Topic         string `json:"topic"`
+	Partition     int32  `json:"partition"`
+	Offset        int64  `json:"offset"`
+	HighWatermark int64  `json:"highWatermark"`
+	// Lag is -1 if the offset or the watermark is unknown
+	Lag int64 `json:"lag"`
+}
+
+// A Heartbeat is a liveness report of the consumer
+type Heartbeat struct {
+	ConsumerID string         `json:"consumerId"`
+	Time       time.Time      `json:"time"`
+	Partitions []PartitionLag `json:"partitions"`
+}
+
+// TotalLag returns the sum of the known lags of the partitions
+func (hb *Heartbeat) TotalLag() (retval int64) {
+	for i := range hb.Partitions {
+		if hb.Partitions[i].Lag > 0 {
+			retval += hb.Partitions[i].Lag
+		}
+	}
+
+	return
+}
+
+// NewHTTPHeartbeatReporter returns the heartbeat handler which posts the report as JSON to the url
+func NewHTTPHeartbeatReporter(url string, client *http.Client) FuncOnHeartbeat {
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return func(ctx context.Context, logger *zap.Logger, hb *Heartbeat) {
+		if err := postHeartbeat(ctx, client, url, hb); err != nil {
+			logger.Warn("failed to report heartbeat", zap.String("url", url), zap.Error(err))
+		}
+	}
+}
+
+func postHeartbeat(ctx context.Context, client *http.Client, url string, hb *Heartbeat) error {
+
+	body, err := json.Marshal(hb)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (c *Consumer) startHeartbeat() (stop func()) {
+
+	lagMetric := c.cfg != nil && c.cfg.Metrics != nil && c.cfg.Metrics.Lag != nil
+	if c.onHeartbeat == nil && !lagMetric {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := c.clock.NewTicker(c.heartbeatInterval)
+		defer ticker.Stop()
+
+		opLog := c.logger.With(zap.String("operation", "heartbeat"))
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				hb, err := c.heartbeat()
+				if err != nil {
+					opLog.Warn("failed to get heartbeat", zap.Error(err))
+					continue
+				}
+
+				c.inflight.SetLag(hb.Partitions)
+				if c.onHeartbeat != nil {
+					c.onHeartbeat(ctx, opLog, hb)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func (c *Consumer) heartbeat() (*Heartbeat, error) {
+
+	assignment, err := c.reader.Assignment()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get assignment")
+	}
+
+	positions, err := c.reader.Position(assignment)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get positions")
+	}
+
+	retval := &Heartbeat{
+		ConsumerID: c.id,
+		Time:       time.Now(),
+		Partitions: make([]PartitionLag, 0, len(positions)),
+	}
+
+	for i := range positions {
+		item := &positions[i]
+
+		var topic string
+		if item.Topic != nil {
+			topic = *item.Topic
+		}
+
+		_, high, err := c.reader.GetWatermarkOffsets(topic, item.Partition)
+		if err != nil {
+			high = -1
+		}
+
+		retval.Partitions = append(retval.Partitions, newPartitionLag(topic, item.Partition, int64(item.Offset), high))
+	}
+
+	return retval, nil
+}
+
+func newPartitionLag(topic string, partition int32, offset, high int64) PartitionLag {
+
+	lag := int64(-1)
+	if offset >= 0 && high >= 0 {
+		lag = high - offset
+		if lag < 0 {
+			lag = 0
+		}
+	}
+
+	return PartitionLag{
+		Topic:         topic,
+		Partition:     partition,
+		Offset:        offset,
+		HighWatermark: high,
+		Lag:           lag,
+	}
+}
@@ -0,0 +1,64 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewPartitionLag(t *testing.T) {
+
+	require.Equal(t,
+		PartitionLag{Topic: "t1", Partition: 1, Offset: 10, HighWatermark: 15, Lag: 5},
+		newPartitionLag("t1", 1, 10, 15))
+
+	require.Equal(t,
+		PartitionLag{Topic: "t1", Partition: 1, Offset: -1001, HighWatermark: 15, Lag: -1},
+		newPartitionLag("t1", 1, -1001, 15))
+
+	require.Equal(t,
+		PartitionLag{Topic: "t1", Partition: 1, Offset: 10, HighWatermark: -1, Lag: -1},
+		newPartitionLag("t1", 1, 10, -1))
+}
+
+func TestHeartbeatTotalLag(t *testing.T) {
+
+	hb := &Heartbeat{Partitions: []PartitionLag{
+		newPartitionLag("t1", 0, 10, 15),
+		newPartitionLag("t1", 1, -1001, 15),
+		newPartitionLag("t1", 2, 1, 3),
+	}}
+
+	require.Equal(t, int64(7), hb.TotalLag())
+}
+
+func TestHTTPHeartbeatReporter(t *testing.T) {
+
+	hb := &Heartbeat{
+		ConsumerID: "id",
+		Time:       time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Partitions: []PartitionLag{newPartitionLag("t1", 1, 10, 15)},
+	}
+
+	chReport := make(chan *Heartbeat, 1)
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		res := &Heartbeat{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(res))
+		chReport <- res
+	}))
+	defer svr.Close()
+
+	NewHTTPHeartbeatReporter(svr.URL, nil)(context.Background(), zap.NewNop(), hb)
+	require.Equal(t, hb, <-chReport)
+
+	require.Error(t, postHeartbeat(context.Background(), http.DefaultClient, svr.URL+"\x00", hb))
+}
@@ -2,6 +2,8 @@ package consumer
 
 import (
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/idgen"
+	"github.com/pkg/errors"
 )
 
 func stringPointer(src string) *string {
@@ -19,3 +21,25 @@ func checkPartitions(list []kafka.TopicPartition) error {
 
 	return nil
 }
+
+func stringValue(val *string) string {
+	if val == nil {
+		return ""
+	}
+
+	return *val
+}
+
+func newID(generator idgen.IDGenerator) (string, error) {
+
+	if generator == nil {
+		generator = idgen.Default
+	}
+
+	id, err := generator.NewID()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate id")
+	}
+
+	return id, nil
+}
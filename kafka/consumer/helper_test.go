@@ -1,11 +1,15 @@
 package consumer
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"testing"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/idgen"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestCheckPartitions(t *testing.T) {
@@ -18,3 +22,217 @@ func TestCheckPartitions(t *testing.T) {
 		errors.New("fail"),
 		checkPartitions([]kafka.TopicPartition{{Error: errors.New("fail")}}))
 }
+
+func TestNewID(t *testing.T) {
+
+	id, err := newID(nil)
+	require.NoError(t, err)
+	require.Len(t, id, 36)
+
+	id, err = newID(idgen.ULID)
+	require.NoError(t, err)
+	require.Len(t, id, 26)
+
+	_, err = newID(idgen.Func(func() (string, error) { return "", errors.New("fail") }))
+	require.EqualError(t, err, "failed to generate id: fail")
+}
+
+// testReader is the reader which isn't connected to the brokers:
+// the assignment, the commits, the pauses and the seeks are recorded
+type testReader struct {
+	kafka.Handle
+	events     chan kafka.Event
+	assignment []kafka.TopicPartition
+	committed  []kafka.TopicPartition
+	commits    [][]kafka.TopicPartition
+	commitErr  error
+	paused     []kafka.TopicPartition
+	seeks      []kafka.TopicPartition
+	closed     bool
+	mu         sync.Mutex
+}
+
+func newTestReader() *testReader {
+	return &testReader{events: make(chan kafka.Event)}
+}
+
+func (r *testReader) SubscribeTopics([]string, kafka.RebalanceCb) error { return nil }
+func (r *testReader) Unsubscribe() error                                { return nil }
+
+func (r *testReader) Assign(partitions []kafka.TopicPartition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.assignment = append([]kafka.TopicPartition{}, partitions...)
+	return nil
+}
+
+func (r *testReader) Unassign() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.assignment = nil
+	return nil
+}
+
+func (r *testReader) IncrementalAssign(partitions []kafka.TopicPartition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.assignment = append(r.assignment, partitions...)
+	return nil
+}
+
+func (r *testReader) IncrementalUnassign(partitions []kafka.TopicPartition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	assignment := r.assignment[:0]
+	for _, item := range r.assignment {
+		revoked := false
+		for _, tp := range partitions {
+			if stringValue(item.Topic) == stringValue(tp.Topic) && item.Partition == tp.Partition {
+				revoked = true
+			}
+		}
+		if !revoked {
+			assignment = append(assignment, item)
+		}
+	}
+	r.assignment = assignment
+
+	return nil
+}
+
+func (r *testReader) Assignment() ([]kafka.TopicPartition, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]kafka.TopicPartition{}, r.assignment...), nil
+}
+
+func (r *testReader) CommitOffsets(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.commitErr != nil {
+		return nil, r.commitErr
+	}
+
+	r.commits = append(r.commits, append([]kafka.TopicPartition{}, offsets...))
+	return offsets, nil
+}
+
+// Commits returns the recorded commits
+func (r *testReader) Commits() [][]kafka.TopicPartition {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([][]kafka.TopicPartition{}, r.commits...)
+}
+
+func (r *testReader) Committed(partitions []kafka.TopicPartition, _ int) ([]kafka.TopicPartition, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	retval := make([]kafka.TopicPartition, len(partitions))
+	for i, tp := range partitions {
+		tp.Offset = kafka.OffsetInvalid
+		for _, item := range r.committed {
+			if stringValue(item.Topic) == stringValue(tp.Topic) && item.Partition == tp.Partition {
+				tp.Offset = item.Offset
+			}
+		}
+		retval[i] = tp
+	}
+
+	return retval, nil
+}
+
+func (r *testReader) Position(partitions []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+	return r.Committed(partitions, 0)
+}
+
+func (r *testReader) Seek(partition kafka.TopicPartition, _ int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seeks = append(r.seeks, partition)
+	return nil
+}
+
+func (r *testReader) Pause(partitions []kafka.TopicPartition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.paused = append(r.paused, partitions...)
+	return nil
+}
+
+func (r *testReader) Resume(partitions []kafka.TopicPartition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	paused := r.paused[:0]
+	for _, item := range r.paused {
+		resumed := false
+		for _, tp := range partitions {
+			if stringValue(item.Topic) == stringValue(tp.Topic) && item.Partition == tp.Partition {
+				resumed = true
+			}
+		}
+		if !resumed {
+			paused = append(paused, item)
+		}
+	}
+	r.paused = paused
+
+	return nil
+}
+
+func (r *testReader) Events() chan kafka.Event {
+	return r.events
+}
+
+func (r *testReader) GetMetadata(*string, bool, int) (*kafka.Metadata, error) {
+	return &kafka.Metadata{}, nil
+}
+
+func (r *testReader) GetWatermarkOffsets(string, int32) (low, high int64, err error) {
+	return 0, 0, nil
+}
+
+func (r *testReader) QueryWatermarkOffsets(string, int32, int) (low, high int64, err error) {
+	return 0, 0, nil
+}
+
+func (r *testReader) OffsetsForTimes(times []kafka.TopicPartition, _ int) ([]kafka.TopicPartition, error) {
+	return times, nil
+}
+
+func (r *testReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	return nil
+}
+
+// newTestConsumer creates the consumer of the topic 'test' by New with the test reader
+func newTestConsumer(t *testing.T, opts ...Option) (*Consumer, *testReader) {
+	t.Helper()
+
+	cfg := &Config{
+		ConfigMap: &kafka.ConfigMap{"group.id": "test"},
+		Topics:    []string{"test"},
+		OnError:   func(context.Context, *zap.Logger, error) {},
+		OnProcess: func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error { return nil },
+	}
+	o := newOptions(cfg, opts)
+
+	r := newTestReader()
+	c, err := newWithReader(o.config, o.logger, func(*kafka.ConfigMap) (reader, error) { return r, nil })
+	require.NoError(t, err)
+
+	return c, r
+}
@@ -0,0 +1,254 @@
+package consumer
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/metric"
+)
+
+// FuncPartitionGauge returns the gauge of the partition (e.g. prometheus.GaugeVec.WithLabelValues)
+type FuncPartitionGauge func(topic string, partition int32) metric.IGauge
+
+// FuncTopicGauge returns the gauge of the topic (see metric.FuncTopicGauge)
+type FuncTopicGauge = metric.FuncTopicGauge
+
+// FuncTopicCounter returns the counter of the topic (see metric.FuncTopicCounter)
+type FuncTopicCounter = metric.FuncTopicCounter
+
+// FuncTopicObserver returns the observer of the topic (see metric.FuncTopicObserver)
+type FuncTopicObserver = metric.FuncTopicObserver
+
+// FuncBrokerObserver returns the observer of the broker (e.g. prometheus.HistogramVec.WithLabelValues)
+type FuncBrokerObserver func(broker string) metric.IObserver
+
+// Metrics of the consumer. All fields are optional.
+type Metrics struct {
+	// InFlight is a count of the messages in processing per partition
+	InFlight FuncPartitionGauge
+	// Paused is a pause state per partition: 1 - paused, 0 - active
+	Paused FuncPartitionGauge
+	// QueueDepth is a count of the ops waiting in the librdkafka queue for the application
+	QueueDepth metric.IGauge
+	// Throttle is a throttle time (seconds) of the broker
+	Throttle FuncBrokerObserver
+	// Partitions is a count of the partitions of the subscribed topic (see PartitionsWatchConfig)
+	Partitions FuncTopicGauge
+	// QuotaThrottle is a pause time (seconds) of the topic by the quota (see Config.Quotas)
+	QuotaThrottle FuncTopicCounter
+	// RateLimitWait is a waiting time (seconds) of the event loop by the rate limit (see Config.MaxMessagesPerSecond)
+	RateLimitWait metric.ICounter
+	// Processed is a count of the processed messages
+	Processed FuncTopicCounter
+	// Failed is a count of the messages failed to process (after the retries)
+	Failed FuncTopicCounter
+	// Poisoned is a count of the skipped poison messages (see Config.Poison)
+	Poisoned FuncTopicCounter
+	// Duration is a processing time (seconds) of the message (of the batch in the batch mode)
+	Duration FuncTopicObserver
+	// CommitFailed is a count of the failed commits of the offsets
+	CommitFailed metric.ICounter
+	// Assigned is a count of the partitions assignments (rebalances)
+	Assigned metric.ICounter
+	// Revoked is a count of the partitions revocations
+	Revoked metric.ICounter
+	// Lag is a count of the messages after the position of the partition (see Config.HeartbeatInterval)
+	Lag FuncPartitionGauge
+	// CommittedLag is a count of the messages after the committed offset of the partition (see Config.LagInterval)
+	CommittedLag FuncPartitionGauge
+}
+
+// A PartitionState is a snapshot of the partition processing
+type PartitionState struct {
+	Topic     string
+	Partition int32
+	InFlight  int
+	Paused    bool
+	// Since is a start time of the oldest message in processing
+	Since time.Time
+}
+
+type inflightEntry struct {
+	topic     string
+	partition int32
+	count     int
+	since     time.Time
+}
+
+type inflight struct {
+	partitions map[string]*inflightEntry
+	metrics    *Metrics
+	mu         sync.RWMutex
+}
+
+func newInflight(m *Metrics) *inflight {
+	if m == nil {
+		m = &Metrics{}
+	}
+
+	return &inflight{
+		partitions: make(map[string]*inflightEntry),
+		metrics:    m,
+	}
+}
+
+func (i *inflight) Begin(tp kafka.TopicPartition) {
+	var topic string
+	if tp.Topic != nil {
+		topic = *tp.Topic
+	}
+
+	i.mu.Lock()
+	key := getPartitionKey(tp.Topic, tp.Partition)
+	entry, ok := i.partitions[key]
+	if !ok {
+		entry = &inflightEntry{topic: topic, partition: tp.Partition}
+		i.partitions[key] = entry
+	}
+	if entry.count == 0 {
+		entry.since = time.Now()
+	}
+	entry.count++
+	i.mu.Unlock()
+
+	if i.metrics.InFlight != nil {
+		i.metrics.InFlight(topic, tp.Partition).Inc()
+	}
+}
+
+func (i *inflight) End(tp kafka.TopicPartition) {
+	var topic string
+	if tp.Topic != nil {
+		topic = *tp.Topic
+	}
+
+	i.mu.Lock()
+	key := getPartitionKey(tp.Topic, tp.Partition)
+	if entry, ok := i.partitions[key]; ok {
+		entry.count--
+		if entry.count <= 0 {
+			delete(i.partitions, key)
+		}
+	}
+	i.mu.Unlock()
+
+	if i.metrics.InFlight != nil {
+		i.metrics.InFlight(topic, tp.Partition).Dec()
+	}
+}
+
+// Done records the result of the message processing
+func (i *inflight) Done(tp kafka.TopicPartition, duration time.Duration, err error) {
+	var topic string
+	if tp.Topic != nil {
+		topic = *tp.Topic
+	}
+
+	if err != nil {
+		if i.metrics.Failed != nil {
+			i.metrics.Failed(topic).Inc()
+		}
+	} else if i.metrics.Processed != nil {
+		i.metrics.Processed(topic).Inc()
+	}
+
+	if i.metrics.Duration != nil {
+		i.metrics.Duration(topic).Observe(duration.Seconds())
+	}
+}
+
+// Poisoned records the skipped poison message of the topic
+func (i *inflight) Poisoned(topic string) {
+	if i.metrics.Poisoned != nil {
+		i.metrics.Poisoned(topic).Inc()
+	}
+}
+
+func (i *inflight) CommitFailed() {
+	if i.metrics.CommitFailed != nil {
+		i.metrics.CommitFailed.Inc()
+	}
+}
+
+func (i *inflight) Rebalanced(assigned bool) {
+	if assigned {
+		if i.metrics.Assigned != nil {
+			i.metrics.Assigned.Inc()
+		}
+	} else if i.metrics.Revoked != nil {
+		i.metrics.Revoked.Inc()
+	}
+}
+
+func (i *inflight) SetLag(partitions []PartitionLag) {
+	if i.metrics.Lag == nil {
+		return
+	}
+
+	for j := range partitions {
+		if partitions[j].Lag >= 0 {
+			i.metrics.Lag(partitions[j].Topic, partitions[j].Partition).Set(float64(partitions[j].Lag))
+		}
+	}
+}
+
+func (i *inflight) SetCommittedLag(lag map[kafka.TopicPartition]int64) {
+	if i.metrics.CommittedLag == nil {
+		return
+	}
+
+	for tp, val := range lag {
+		i.metrics.CommittedLag(*tp.Topic, tp.Partition).Set(float64(val))
+	}
+}
+
+func (i *inflight) SetPaused(paused bool, partitions ...kafka.TopicPartition) {
+	if i.metrics.Paused == nil {
+		return
+	}
+
+	var val float64
+	if paused {
+		val = 1
+	}
+
+	for j := range partitions {
+		var topic string
+		if partitions[j].Topic != nil {
+			topic = *partitions[j].Topic
+		}
+
+		i.metrics.Paused(topic, partitions[j].Partition).Set(val)
+	}
+}
+
+func (i *inflight) SetQueueDepth(val int) {
+	if i.metrics.QueueDepth != nil {
+		i.metrics.QueueDepth.Set(float64(val))
+	}
+}
+
+func (i *inflight) Snapshot() []PartitionState {
+	i.mu.RLock()
+	retval := make([]PartitionState, 0, len(i.partitions))
+	for _, entry := range i.partitions {
+		retval = append(retval, PartitionState{
+			Topic:     entry.topic,
+			Partition: entry.partition,
+			InFlight:  entry.count,
+			Since:     entry.since,
+		})
+	}
+	i.mu.RUnlock()
+
+	sort.Slice(retval, func(a, b int) bool {
+		if retval[a].Topic != retval[b].Topic {
+			return retval[a].Topic < retval[b].Topic
+		}
+		return retval[a].Partition < retval[b].Partition
+	})
+
+	return retval
+}
@@ -0,0 +1,132 @@
+package consumer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/metric"
+	"github.com/dialogs/dialog-go-lib/metric/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInflight(t *testing.T) {
+
+	gauges := make(map[string]*mock.Gauge)
+	getGauge := func(name string) FuncPartitionGauge {
+		return func(topic string, partition int32) metric.IGauge {
+			key := name + getPartitionKey(&topic, partition)
+			if _, ok := gauges[key]; !ok {
+				gauges[key] = mock.NewGauge()
+			}
+			return gauges[key]
+		}
+	}
+
+	queue := mock.NewGauge()
+	i := newInflight(&Metrics{
+		InFlight:   getGauge("inflight"),
+		Paused:     getGauge("paused"),
+		QueueDepth: queue,
+	})
+
+	p1 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1}
+	p2 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 2}
+
+	i.Begin(p1)
+	i.Begin(p1)
+	i.Begin(p2)
+	require.Equal(t, float64(2), gauges["inflightt11"].Get())
+	require.Equal(t, float64(1), gauges["inflightt12"].Get())
+
+	list := i.Snapshot()
+	require.Len(t, list, 2)
+	require.Equal(t, "t1", list[0].Topic)
+	require.Equal(t, int32(1), list[0].Partition)
+	require.Equal(t, 2, list[0].InFlight)
+	require.False(t, list[0].Since.IsZero())
+	require.Equal(t, int32(2), list[1].Partition)
+	require.Equal(t, 1, list[1].InFlight)
+
+	i.End(p1)
+	i.End(p2)
+	require.Equal(t, float64(1), gauges["inflightt11"].Get())
+	require.Equal(t, float64(0), gauges["inflightt12"].Get())
+	require.Len(t, i.Snapshot(), 1)
+
+	i.SetPaused(true, p1)
+	require.Equal(t, float64(1), gauges["pausedt11"].Get())
+	i.SetPaused(false, p1)
+	require.Equal(t, float64(0), gauges["pausedt11"].Get())
+
+	i.SetQueueDepth(3)
+	require.Equal(t, float64(3), queue.Get())
+}
+
+func TestInflightWithoutMetrics(t *testing.T) {
+
+	i := newInflight(nil)
+
+	p := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1}
+	i.Begin(p)
+	i.SetPaused(true, p)
+	i.SetQueueDepth(1)
+	i.End(p)
+
+	require.Empty(t, i.Snapshot())
+}
+
+func TestInflightResultMetrics(t *testing.T) {
+
+	processed, failed, poisoned := mock.NewCounter(), mock.NewCounter(), mock.NewCounter()
+	commitFailed, assigned, revoked := mock.NewCounter(), mock.NewCounter(), mock.NewCounter()
+	duration := mock.NewObserver()
+	lag := mock.NewGauge()
+
+	i := newInflight(&Metrics{
+		Processed:    func(string) metric.ICounter { return processed },
+		Failed:       func(string) metric.ICounter { return failed },
+		Poisoned:     func(string) metric.ICounter { return poisoned },
+		Duration:     func(string) metric.IObserver { return duration },
+		CommitFailed: commitFailed,
+		Assigned:     assigned,
+		Revoked:      revoked,
+		Lag: func(topic string, partition int32) metric.IGauge {
+			require.Equal(t, "t1", topic)
+			require.Equal(t, int32(1), partition)
+			return lag
+		},
+	})
+
+	p1 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1}
+
+	i.Done(p1, time.Second, nil)
+	i.Done(p1, time.Second*3, errors.New("failed"))
+	require.Equal(t, uint64(1), processed.Get())
+	require.Equal(t, uint64(1), failed.Get())
+	require.Equal(t, []float64{1, 3}, duration.GetSlice())
+
+	i.Poisoned("t1")
+	require.Equal(t, uint64(1), poisoned.Get())
+
+	i.CommitFailed()
+	i.Rebalanced(true)
+	i.Rebalanced(true)
+	i.Rebalanced(false)
+	require.Equal(t, uint64(1), commitFailed.Get())
+	require.Equal(t, uint64(2), assigned.Get())
+	require.Equal(t, uint64(1), revoked.Get())
+
+	// the unknown lag is skipped
+	i.SetLag([]PartitionLag{newPartitionLag("t1", 1, 10, 15), newPartitionLag("t2", 1, -1, 15)})
+	require.Equal(t, float64(5), lag.Get())
+
+	// the metrics are optional
+	i = newInflight(nil)
+	i.Done(p1, time.Second, nil)
+	i.Poisoned("t1")
+	i.CommitFailed()
+	i.Rebalanced(true)
+	i.SetLag([]PartitionLag{newPartitionLag("t1", 1, 10, 15)})
+}
@@ -1,11 +1,61 @@
 package consumer
 
 import (
+	"context"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 )
 
 type ISleeper interface {
+	// Sleep pauses the partitions for the delay
 	Sleep(time.Duration, []kafka.TopicPartition) error
+	// SleepUntil pauses the partitions until the condition is satisfied or the context is done
+	SleepUntil(context.Context, FuncSleepCondition, []kafka.TopicPartition) error
+	// SleepContext pauses the partitions for the delay or until the context is done,
+	// the returned function resumes the partitions early
+	SleepContext(context.Context, time.Duration, []kafka.TopicPartition) (context.CancelFunc, error)
+	// CancelSleep resumes the paused partitions immediately
+	CancelSleep([]kafka.TopicPartition) error
 }
+
+// ICommitter marks the offsets of the processed messages (see Config.ManualCommit)
+type ICommitter interface {
+	// MarkOffset marks the message of the partition as processed: the offset is committed
+	// by the next commit of the consumer. The marks of the partition can't move the offset back.
+	MarkOffset(kafka.TopicPartition)
+}
+
+// IConsumer is the interface of the Consumer (see kafka/mocks)
+type IConsumer interface {
+	ISleeper
+	ICommitter
+	// Start consumes the messages until the consumer is stopped
+	Start() error
+	// Stop stops the consumer and waits for the processing messages
+	Stop() error
+	StopContext(context.Context) error
+	StopWithTimeout(time.Duration) error
+	Drain(context.Context) error
+	// SleepAll pauses all assigned partitions for the delay
+	SleepAll(time.Duration) error
+	SleepStatus() (time.Time, bool)
+	ResumeAll() error
+	Pause([]kafka.TopicPartition) error
+	Resume([]kafka.TopicPartition) error
+	PausedPartitions() []kafka.TopicPartition
+	SleepingPartitions() []SleepingPartition
+	// Seek sets the offset of the partition, SeekToTime sets the offsets of all assigned partitions by the time
+	// (the seeks are queued for the event loop, the errors are passed to OnError)
+	Seek(topic string, partition int32, offset kafka.Offset) error
+	SeekToTime(time.Time) error
+	// Commit commits the offsets of the processed messages
+	Commit() error
+	Resubscribe() error
+	InFlight() []PartitionState
+	MatchedTopics() []string
+	CheckBrokers(context.Context) error
+	HealthCheck() error
+}
+
+var _ IConsumer = (*Consumer)(nil)
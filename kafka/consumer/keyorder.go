@@ -0,0 +1,100 @@
+package consumer
+
+import (
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+type pendingOffsets struct {
+	queue []kafka.Offset
+	done  map[kafka.Offset]struct{}
+}
+
+type keyOffsets struct {
+	partitions map[string]*pendingOffsets
+	mu         sync.Mutex
+}
+
+func newKeyOffsets() *keyOffsets {
+	return &keyOffsets{
+		partitions: make(map[string]*pendingOffsets),
+	}
+}
+
+// Begin registers the dispatched message (the messages of a partition are dispatched in order)
+func (k *keyOffsets) Begin(tp kafka.TopicPartition) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key := getPartitionKey(tp.Topic, tp.Partition)
+	p, ok := k.partitions[key]
+	if !ok {
+		p = &pendingOffsets{done: make(map[kafka.Offset]struct{})}
+		k.partitions[key] = p
+	}
+
+	p.queue = append(p.queue, tp.Offset)
+}
+
+// Cancel unregisters the last dispatched message which isn't sent to the worker
+func (k *keyOffsets) Cancel(tp kafka.TopicPartition) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key := getPartitionKey(tp.Topic, tp.Partition)
+	p, ok := k.partitions[key]
+	if !ok || len(p.queue) == 0 || p.queue[len(p.queue)-1] != tp.Offset {
+		return
+	}
+
+	p.queue = p.queue[:len(p.queue)-1]
+	if len(p.queue) == 0 {
+		delete(k.partitions, key)
+	}
+}
+
+// Done returns the last offset of the partition processed without the gaps
+func (k *keyOffsets) Done(tp kafka.TopicPartition) (kafka.TopicPartition, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key := getPartitionKey(tp.Topic, tp.Partition)
+	p, ok := k.partitions[key]
+	if !ok {
+		return tp, true
+	}
+
+	p.done[tp.Offset] = struct{}{}
+
+	completed := -1
+	for i, offset := range p.queue {
+		if _, ok := p.done[offset]; !ok {
+			break
+		}
+		delete(p.done, offset)
+		completed = i
+	}
+
+	if completed < 0 {
+		return tp, false
+	}
+
+	retval := tp
+	retval.Offset = p.queue[completed]
+
+	p.queue = p.queue[completed+1:]
+	if len(p.queue) == 0 {
+		delete(k.partitions, key)
+	}
+
+	return retval, true
+}
+
+// Reset drops the dispatched messages of the partition (the partition is drained and sought or revoked)
+func (k *keyOffsets) Reset(tp kafka.TopicPartition) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	delete(k.partitions, getPartitionKey(tp.Topic, tp.Partition))
+}
@@ -0,0 +1,108 @@
+package consumer
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyOffsets(t *testing.T) {
+
+	k := newKeyOffsets()
+
+	topic := "t1"
+	tp := func(partition int32, offset kafka.Offset) kafka.TopicPartition {
+		return kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: offset}
+	}
+
+	for offset := kafka.Offset(1); offset <= 4; offset++ {
+		k.Begin(tp(0, offset))
+	}
+	k.Begin(tp(1, 7))
+
+	// the offset isn't stored until the previous messages are processed
+	_, ok := k.Done(tp(0, 3))
+	require.False(t, ok)
+	_, ok = k.Done(tp(0, 2))
+	require.False(t, ok)
+
+	res, ok := k.Done(tp(0, 1))
+	require.True(t, ok)
+	require.Equal(t, tp(0, 3), res)
+
+	res, ok = k.Done(tp(1, 7))
+	require.True(t, ok)
+	require.Equal(t, tp(1, 7), res)
+
+	// the message isn't sent to the worker
+	k.Begin(tp(0, 5))
+	k.Cancel(tp(0, 5))
+
+	res, ok = k.Done(tp(0, 4))
+	require.True(t, ok)
+	require.Equal(t, tp(0, 4), res)
+	require.Empty(t, k.partitions)
+
+	// the unknown partition
+	res, ok = k.Done(tp(2, 1))
+	require.True(t, ok)
+	require.Equal(t, tp(2, 1), res)
+}
+
+func TestKeyedWorkerPool(t *testing.T) {
+
+	var (
+		mu     sync.Mutex
+		result = make(map[string][]kafka.Offset)
+		stored []kafka.Offset
+	)
+
+	var p *workerPool
+	p = newKeyedWorkerPool(4, 1, func(item workItem) error {
+		key := string(item.msg.Key)
+		// the slow key doesn't block others
+		if key == "k0" {
+			time.Sleep(time.Millisecond)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		result[key] = append(result[key], item.tp.Offset)
+		if tp, ok := p.Completed(item.tp); ok {
+			stored = append(stored, tp.Offset)
+		}
+		return nil
+	})
+	defer p.Close()
+
+	// the messages of the keys of a partition
+	topic := "t1"
+	for offset := 0; offset < 40; offset++ {
+		tp := kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(offset)}
+		msg := &kafka.Message{TopicPartition: tp, Key: []byte("k" + strconv.Itoa(offset%4))}
+		require.NoError(t, p.Dispatch(context.Background(), workItem{tp: tp, msg: msg}))
+	}
+
+	p.Drain()
+	require.NoError(t, p.Err())
+	require.Len(t, result, 4)
+	for key, offsets := range result {
+		require.Len(t, offsets, 10)
+		for i := 1; i < len(offsets); i++ {
+			require.Equal(t, offsets[i-1]+4, offsets[i], key)
+		}
+	}
+
+	// the stored offsets are increased without the gaps
+	require.NotEmpty(t, stored)
+	require.Equal(t, kafka.Offset(39), stored[len(stored)-1])
+	for i := 1; i < len(stored); i++ {
+		require.True(t, stored[i-1] < stored[i])
+	}
+}
@@ -0,0 +1,111 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const _LagQueryTimeout = time.Second * 10
+
+// FuncOnLag receives the lags of the assigned partitions (see Config.LagInterval)
+type FuncOnLag func(ctx context.Context, logger *zap.Logger, lag map[kafka.TopicPartition]int64)
+
+type lagReader interface {
+	Assignment() ([]kafka.TopicPartition, error)
+	Committed(partitions []kafka.TopicPartition, timeoutMs int) ([]kafka.TopicPartition, error)
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error)
+}
+
+func (c *Consumer) startLagMonitor() (stop func()) {
+
+	lagMetric := c.cfg != nil && c.cfg.Metrics != nil && c.cfg.Metrics.CommittedLag != nil
+	if c.onLag == nil && !lagMetric {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := c.clock.NewTicker(c.lagInterval)
+		defer ticker.Stop()
+
+		opLog := c.logger.With(zap.String("operation", "lag"))
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				lag, err := committedLag(c.reader)
+				if err != nil {
+					opLog.Warn("failed to get lag", zap.Error(err))
+					continue
+				}
+
+				c.inflight.SetCommittedLag(lag)
+				if c.onLag != nil {
+					c.onLag(ctx, opLog, lag)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func committedLag(r lagReader) (map[kafka.TopicPartition]int64, error) {
+
+	const timeoutMs = int(_LagQueryTimeout / time.Millisecond)
+
+	assignment, err := r.Assignment()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get assignment")
+	}
+
+	if len(assignment) == 0 {
+		return map[kafka.TopicPartition]int64{}, nil
+	}
+
+	committed, err := r.Committed(assignment, timeoutMs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get committed offsets")
+	}
+
+	retval := make(map[kafka.TopicPartition]int64, len(committed))
+	for _, tp := range committed {
+		if tp.Topic == nil {
+			continue
+		}
+
+		low, high, err := r.QueryWatermarkOffsets(*tp.Topic, tp.Partition, timeoutMs)
+		if err != nil {
+			continue
+		}
+
+		offset := int64(tp.Offset)
+		if offset < 0 {
+			offset = low
+		}
+
+		lag := high - offset
+		if lag < 0 {
+			lag = 0
+		}
+
+		retval[kafka.TopicPartition{Topic: tp.Topic, Partition: tp.Partition}] = lag
+	}
+
+	return retval, nil
+}
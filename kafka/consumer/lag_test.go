@@ -0,0 +1,106 @@
+package consumer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/metric"
+	"github.com/dialogs/dialog-go-lib/metric/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type testLagReader struct {
+	assignment []kafka.TopicPartition
+	committed  map[int32]kafka.Offset
+	watermarks map[int32][2]int64
+	err        error
+}
+
+func (r *testLagReader) Assignment() ([]kafka.TopicPartition, error) {
+	return r.assignment, r.err
+}
+
+func (r *testLagReader) Committed(partitions []kafka.TopicPartition, _ int) ([]kafka.TopicPartition, error) {
+
+	retval := make([]kafka.TopicPartition, len(partitions))
+	for i, tp := range partitions {
+		tp.Offset = r.committed[tp.Partition]
+		retval[i] = tp
+	}
+
+	return retval, nil
+}
+
+func (r *testLagReader) QueryWatermarkOffsets(_ string, partition int32, _ int) (int64, int64, error) {
+
+	w, ok := r.watermarks[partition]
+	if !ok {
+		return 0, 0, errors.New("unknown partition")
+	}
+
+	return w[0], w[1], nil
+}
+
+func TestCommittedLag(t *testing.T) {
+
+	topic := "t1"
+	r := &testLagReader{
+		assignment: []kafka.TopicPartition{
+			{Topic: &topic, Partition: 0},
+			{Topic: &topic, Partition: 1},
+			{Topic: &topic, Partition: 2},
+			{Topic: &topic, Partition: 3},
+		},
+		committed: map[int32]kafka.Offset{
+			0: 10,
+			1: kafka.OffsetInvalid,
+			2: 30,
+			3: 5,
+		},
+		watermarks: map[int32][2]int64{
+			0: {0, 15},
+			1: {4, 20},
+			2: {0, 25},
+		},
+	}
+
+	lag, err := committedLag(r)
+	require.NoError(t, err)
+
+	// the partition with the failed query of the watermarks is skipped
+	values := make(map[int32]int64, len(lag))
+	for tp, val := range lag {
+		require.Equal(t, topic, *tp.Topic)
+		require.Equal(t, kafka.Offset(0), tp.Offset)
+		values[tp.Partition] = val
+	}
+	require.Equal(t, map[int32]int64{0: 5, 1: 16, 2: 0}, values)
+
+	r.assignment = nil
+	lag, err = committedLag(r)
+	require.NoError(t, err)
+	require.Empty(t, lag)
+
+	r.err = errors.New("failed")
+	_, err = committedLag(r)
+	require.Error(t, err)
+}
+
+func TestInflightCommittedLag(t *testing.T) {
+
+	gauge := mock.NewGauge()
+	i := newInflight(&Metrics{
+		CommittedLag: func(topic string, partition int32) metric.IGauge {
+			require.Equal(t, "t1", topic)
+			require.Equal(t, int32(1), partition)
+			return gauge
+		},
+	})
+
+	i.SetCommittedLag(map[kafka.TopicPartition]int64{{Topic: stringPointer("t1"), Partition: 1}: 7})
+	require.Equal(t, float64(7), gauge.Get())
+
+	// the metric is optional
+	newInflight(nil).SetCommittedLag(map[kafka.TopicPartition]int64{{Topic: stringPointer("t1"), Partition: 1}: 7})
+}
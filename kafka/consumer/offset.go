@@ -69,6 +69,28 @@ func (o *offset) Clear() {
 	o.counter = 0
 }
 
+// Take returns the offsets and clears them
+func (o *offset) Take() []kafka.TopicPartition {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var retval []kafka.TopicPartition
+	for topic, partition := range o.topics {
+		for p, po := range partition {
+			retval = append(retval, kafka.TopicPartition{
+				Topic:     stringPointer(topic),
+				Partition: p,
+				Offset:    po.Offset,
+			})
+		}
+	}
+
+	o.topics = make(map[string]map[int32]*offsetEntry)
+	o.counter = 0
+
+	return retval
+}
+
 func (o *offset) Counter() (counter int) {
 	o.mu.RLock()
 	counter = o.counter
@@ -87,8 +109,7 @@ func (o *offset) Remove(in kafka.TopicPartition) {
 	o.mu.Lock()
 
 	partitions, ok := o.topics[topic]
-	if ok {
-		entry := partitions[in.Partition]
+	if entry, exists := partitions[in.Partition]; ok && exists {
 		delete(partitions, in.Partition)
 		if len(partitions) == 0 {
 			delete(o.topics, topic)
@@ -104,6 +125,43 @@ func (o *offset) Remove(in kafka.TopicPartition) {
 	o.mu.Unlock()
 }
 
+// RemoveCommitted removes the offset of the partition if it isn't newer than the committed one
+func (o *offset) RemoveCommitted(in kafka.TopicPartition, count int) {
+
+	var topic string
+	if in.Topic != nil {
+		topic = *in.Topic
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	partitions, ok := o.topics[topic]
+	entry, exists := partitions[in.Partition]
+	if !ok || !exists {
+		return
+	}
+
+	if entry.Offset > in.Offset {
+		if count > entry.Count {
+			count = entry.Count
+		}
+		entry.Count -= count
+		o.counter -= count
+		return
+	}
+
+	delete(partitions, in.Partition)
+	if len(partitions) == 0 {
+		delete(o.topics, topic)
+	}
+
+	o.counter -= entry.Count
+	if len(o.topics) == 0 {
+		o.counter = 0
+	}
+}
+
 func (o *offset) Get() (retval []kafka.TopicPartition, count map[string]int) {
 
 	count = make(map[string]int)
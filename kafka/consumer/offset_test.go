@@ -103,3 +103,54 @@ func TestOffsetGetRemove(t *testing.T) {
 	require.Equal(t, 0, o.Counter())
 	require.Equal(t, map[string]map[int32]*offsetEntry{}, o.topics)
 }
+
+func TestOffsetTake(t *testing.T) {
+
+	o := newOffset()
+	o.Add(
+		kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1, Offset: 1},
+		kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1, Offset: 3},
+		kafka.TopicPartition{Topic: stringPointer("t2"), Partition: 0, Offset: 2},
+	)
+
+	require.ElementsMatch(t,
+		[]kafka.TopicPartition{
+			{Topic: stringPointer("t1"), Partition: 1, Offset: 3},
+			{Topic: stringPointer("t2"), Partition: 0, Offset: 2},
+		},
+		o.Take())
+
+	require.Equal(t, 0, o.Counter())
+	require.Empty(t, o.Take())
+}
+
+func TestOffsetRemoveCommitted(t *testing.T) {
+
+	o := newOffset()
+	o.Add(
+		kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1, Offset: 1},
+		kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1, Offset: 2},
+		kafka.TopicPartition{Topic: stringPointer("t2"), Partition: 0, Offset: 5},
+	)
+
+	list, count := o.Get()
+	require.Len(t, list, 2)
+
+	// the worker adds the newer offset while the offsets are committed
+	o.Add(kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1, Offset: 3})
+
+	for _, tp := range list {
+		o.RemoveCommitted(tp, count[getPartitionKey(tp.Topic, tp.Partition)])
+	}
+
+	require.Equal(t,
+		map[string]map[int32]*offsetEntry{
+			"t1": map[int32]*offsetEntry{1: &offsetEntry{Offset: 3, Count: 1}},
+		},
+		o.topics)
+	require.Equal(t, 1, o.Counter())
+
+	o.RemoveCommitted(kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1, Offset: 3}, 1)
+	require.Equal(t, 0, o.Counter())
+	require.Equal(t, map[string]map[int32]*offsetEntry{}, o.topics)
+}
@@ -0,0 +1,118 @@
+package consumer
+
+import (
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/idgen"
+	"github.com/dialogs/dialog-go-lib/trace"
+	"go.uber.org/zap"
+)
+
+type options struct {
+	config *Config
+	logger *zap.Logger
+}
+
+// An Option modifies the consumer settings (see NewWithOptions, NewGroupWithOptions)
+type Option func(o *options)
+
+// WithLogger sets the logger (nop logger by default)
+func WithLogger(l *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithMetrics sets the metrics of the consumer
+func WithMetrics(m *Metrics) Option {
+	return func(o *options) {
+		o.config.Metrics = m
+	}
+}
+
+// WithTimeout sets the timeout of the offsets commit
+func WithTimeout(val time.Duration) Option {
+	return func(o *options) {
+		o.config.CommitTimeout = val
+	}
+}
+
+// WithTracer sets the tracer of the messages processing
+func WithTracer(t *trace.Tracer) Option {
+	return func(o *options) {
+		o.config.Tracer = t
+	}
+}
+
+// WithPropagator sets the propagator extracting the span context from the message headers
+func WithPropagator(p trace.IPropagator) Option {
+	return func(o *options) {
+		o.config.Propagator = p
+	}
+}
+
+// WithClock sets the source of the time (e.g. mock.Clock in the tests)
+func WithClock(clk clock.Clock) Option {
+	return func(o *options) {
+		o.config.Clock = clk
+	}
+}
+
+// WithIDGenerator sets the generator of the consumer identifiers (e.g. idgen.ULID)
+func WithIDGenerator(g idgen.IDGenerator) Option {
+	return func(o *options) {
+		o.config.IDGenerator = g
+	}
+}
+
+// WithRetry sets the retries of OnProcess
+func WithRetry(cfg RetryConfig) Option {
+	return func(o *options) {
+		o.config.Retry = &cfg
+	}
+}
+
+// WithTopicWait sets the waiting for the subscribed topics
+func WithTopicWait(cfg TopicWaitConfig) Option {
+	return func(o *options) {
+		o.config.TopicWait = &cfg
+	}
+}
+
+// WithConfig modifies the configuration
+func WithConfig(fn func(cfg *Config)) Option {
+	return func(o *options) {
+		fn(o.config)
+	}
+}
+
+func newOptions(cfg *Config, opts []Option) *options {
+
+	o := &options{
+		config: cfg.Clone(),
+		logger: zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// NewWithOptions creates a consumer. The configuration isn't modified by the options.
+func NewWithOptions(cfg *Config, opts ...Option) (*Consumer, error) {
+
+	o := newOptions(cfg, opts)
+	return New(o.config, o.logger)
+}
+
+// NewGroupWithOptions creates a consumers group. The configuration isn't modified by the options.
+func NewGroupWithOptions(cfg GroupConfig, opts ...Option) (*Group, error) {
+
+	o := newOptions(cfg.Config, opts)
+	cfg.Config = o.config
+
+	return NewGroup(cfg, o.logger)
+}
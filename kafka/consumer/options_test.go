@@ -0,0 +1,50 @@
+package consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/trace"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestOptions(t *testing.T) {
+
+	cfg := &Config{
+		ConfigMap: &kafka.ConfigMap{"bootstrap.servers": "b1"},
+		Topics:    []string{"t1"},
+	}
+
+	l := zap.NewExample()
+	m := &Metrics{}
+	tracer := trace.NewTracer(trace.Config{})
+	defer tracer.Close()
+
+	o := newOptions(cfg, []Option{
+		WithLogger(l),
+		WithMetrics(m),
+		WithTimeout(time.Minute),
+		WithTracer(tracer),
+		WithConfig(func(cfg *Config) {
+			cfg.Topics = append(cfg.Topics, "t2")
+		}),
+	})
+
+	require.Equal(t, l, o.logger)
+	require.Equal(t, time.Minute, o.config.CommitTimeout)
+	require.Equal(t, tracer, o.config.Tracer)
+	require.Equal(t, []string{"t1", "t2"}, o.config.Topics)
+	require.Equal(t, *m, *o.config.Metrics)
+
+	// the source configuration isn't modified
+	require.Equal(t, &Config{
+		ConfigMap: &kafka.ConfigMap{"bootstrap.servers": "b1"},
+		Topics:    []string{"t1"},
+	}, cfg)
+
+	o = newOptions(cfg, nil)
+	require.NotNil(t, o.logger)
+	require.Equal(t, cfg, o.config)
+}
@@ -0,0 +1,134 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"go.uber.org/zap"
+)
+
+// FuncOnOrderViolation is called when the message of the key is delivered out of order or with a gap
+type FuncOnOrderViolation func(ctx context.Context, logger *zap.Logger, v *OrderViolation)
+
+// OrderingConfig is a configuration of the ordering checks (the test mode)
+type OrderingConfig struct {
+	// Header is the header of the sequence number of the key ("x-sequence" by default, see producer.SequenceProducer)
+	Header string
+	// AllowRedelivery allows the repeated delivery of the processed messages (e.g. after the rebalance)
+	AllowRedelivery bool
+	// OnViolation reports the violation (the checker panics if it isn't set)
+	OnViolation FuncOnOrderViolation
+}
+
+// An OrderViolation is the delivery of the message out of order or with a gap
+type OrderViolation struct {
+	Key       string
+	Partition kafka.TopicPartition
+	Expected  uint64
+	Actual    uint64
+}
+
+// Gap reports whether the messages between Expected and Actual are skipped
+func (v *OrderViolation) Gap() bool {
+	return v.Actual > v.Expected
+}
+
+func (v *OrderViolation) Error() string {
+
+	kind := "out of order delivery"
+	if v.Gap() {
+		kind = "delivery gap"
+	}
+
+	return fmt.Sprintf("%s of key %q in %s[%d]: expected sequence %d, got %d",
+		kind, v.Key, stringValue(v.Partition.Topic), v.Partition.Partition, v.Expected, v.Actual)
+}
+
+// OrderingChecker checks the sequence numbers of the keys set by producer.SequenceProducer
+type OrderingChecker struct {
+	header          string
+	allowRedelivery bool
+	onViolation     FuncOnOrderViolation
+	sequences       map[string]uint64
+	violations      []OrderViolation
+	mu              sync.Mutex
+}
+
+// NewOrderingChecker creates the checker
+func NewOrderingChecker(cfg OrderingConfig) *OrderingChecker {
+
+	header := cfg.Header
+	if header == "" {
+		header = "x-sequence"
+	}
+
+	return &OrderingChecker{
+		header:          header,
+		allowRedelivery: cfg.AllowRedelivery,
+		onViolation:     cfg.OnViolation,
+		sequences:       make(map[string]uint64),
+	}
+}
+
+// Check returns the violation of the sequence number of the message key
+func (c *OrderingChecker) Check(ctx context.Context, logger *zap.Logger, msg *kafka.Message) *OrderViolation {
+
+	val := NewHeadersCarrier(msg).Get(c.header)
+	if val == "" {
+		return nil
+	}
+
+	seq, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		logger.Warn("invalid sequence number", zap.String("header", c.header), zap.Error(err))
+		return nil
+	}
+
+	key := stringValue(msg.TopicPartition.Topic) + "/" + string(msg.Key)
+
+	c.mu.Lock()
+	last, exists := c.sequences[key]
+	c.sequences[key] = seq
+
+	if !exists || seq == last+1 || (c.allowRedelivery && seq <= last) {
+		c.mu.Unlock()
+		return nil
+	}
+
+	v := OrderViolation{
+		Key:       string(msg.Key),
+		Partition: msg.TopicPartition,
+		Expected:  last + 1,
+		Actual:    seq,
+	}
+	c.violations = append(c.violations, v)
+	c.mu.Unlock()
+
+	logger.Error("ordering is violated", zap.Error(&v))
+
+	if c.onViolation == nil {
+		panic(v.Error())
+	}
+	c.onViolation(ctx, logger, &v)
+
+	return &v
+}
+
+// Wrap returns OnProcess checking the ordering of the processed messages
+func (c *OrderingChecker) Wrap(fn FuncOnProcess) FuncOnProcess {
+	return func(ctx context.Context, logger *zap.Logger, msg *kafka.Message, s ISleeper, committer ICommitter) error {
+		c.Check(ctx, logger, msg)
+		return fn(ctx, logger, msg, s, committer)
+	}
+}
+
+// Violations returns the found violations
+func (c *OrderingChecker) Violations() []OrderViolation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]OrderViolation(nil), c.violations...)
+}
@@ -0,0 +1,86 @@
+package consumer
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newSequenceMessage(key string, partition int32, seq uint64) *kafka.Message {
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: stringPointer("t1"), Partition: partition},
+		Key:            []byte(key),
+		Headers:        []kafka.Header{{Key: "x-sequence", Value: []byte(strconv.FormatUint(seq, 10))}},
+	}
+}
+
+func TestOrderingChecker(t *testing.T) {
+
+	var reported []string
+	c := NewOrderingChecker(OrderingConfig{
+		OnViolation: func(_ context.Context, _ *zap.Logger, v *OrderViolation) {
+			reported = append(reported, v.Error())
+		},
+	})
+
+	ctx := context.Background()
+	l := zap.NewNop()
+
+	// the first number of the key is accepted
+	require.Nil(t, c.Check(ctx, l, newSequenceMessage("k1", 0, 5)))
+	require.Nil(t, c.Check(ctx, l, newSequenceMessage("k1", 0, 6)))
+	require.Nil(t, c.Check(ctx, l, newSequenceMessage("k2", 1, 1)))
+	// without the header
+	require.Nil(t, c.Check(ctx, l, &kafka.Message{Key: []byte("k1")}))
+
+	v := c.Check(ctx, l, newSequenceMessage("k1", 0, 8))
+	require.NotNil(t, v)
+	require.True(t, v.Gap())
+
+	v = c.Check(ctx, l, newSequenceMessage("k1", 0, 7))
+	require.NotNil(t, v)
+	require.False(t, v.Gap())
+
+	require.Equal(t, []string{
+		`delivery gap of key "k1" in t1[0]: expected sequence 7, got 8`,
+		`out of order delivery of key "k1" in t1[0]: expected sequence 9, got 7`,
+	}, reported)
+	require.Len(t, c.Violations(), 2)
+}
+
+func TestOrderingCheckerRedelivery(t *testing.T) {
+
+	c := NewOrderingChecker(OrderingConfig{AllowRedelivery: true})
+	ctx := context.Background()
+	l := zap.NewNop()
+
+	for _, seq := range []uint64{1, 2, 3, 2, 3, 4} {
+		require.Nil(t, c.Check(ctx, l, newSequenceMessage("k1", 0, seq)))
+	}
+
+	// the violation panics without OnViolation
+	require.Panics(t, func() { c.Check(ctx, l, newSequenceMessage("k1", 0, 6)) })
+	require.Len(t, c.Violations(), 1)
+}
+
+func TestOrderingCheckerWrap(t *testing.T) {
+
+	var processed int
+	c := NewOrderingChecker(OrderingConfig{Header: "seq"})
+	fn := c.Wrap(func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error {
+		processed++
+		return nil
+	})
+
+	msg := &kafka.Message{Headers: []kafka.Header{{Key: "seq", Value: []byte("1")}}}
+	require.NoError(t, fn(context.Background(), zap.NewNop(), msg, nil, nil))
+	require.Equal(t, 1, processed)
+
+	msg.Headers[0].Value = []byte("3")
+	require.Panics(t, func() { _ = fn(context.Background(), zap.NewNop(), msg, nil, nil) })
+	require.Equal(t, 1, processed)
+}
@@ -0,0 +1,67 @@
+package consumer
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/reporter"
+	"go.uber.org/zap"
+)
+
+func repanic(details func() map[string]string) {
+
+	val := recover()
+	if val == nil {
+		return
+	}
+
+	if _, ok := val.(*reporter.PanicError); ok {
+		panic(val)
+	}
+
+	panic(reporter.NewPanicError(val, details()))
+}
+
+func (c *Consumer) reportPanic(val interface{}) {
+
+	err, ok := val.(*reporter.PanicError)
+	if !ok {
+		err = reporter.NewPanicError(val, nil)
+	}
+
+	c.logger.Error("recovered panic",
+		zap.Error(err),
+		zap.Any("details", err.Details),
+		zap.ByteString("stack", err.Stack))
+
+	if c.onError != nil {
+		c.onError(c.ctx, c.logger, err)
+	}
+}
+
+func messageDetails(msg *kafka.Message) map[string]string {
+	return map[string]string{
+		"kafka.topic":     stringValue(msg.TopicPartition.Topic),
+		"kafka.partition": strconv.Itoa(int(msg.TopicPartition.Partition)),
+		"kafka.offset":    msg.TopicPartition.Offset.String(),
+	}
+}
+
+func batchDetails(msgs []*kafka.Message) map[string]string {
+
+	partitions := make([]string, 0, len(msgs))
+	seen := make(map[string]bool, len(msgs))
+	for _, msg := range msgs {
+		key := stringValue(msg.TopicPartition.Topic) + "[" + strconv.Itoa(int(msg.TopicPartition.Partition)) + "]"
+		if !seen[key] {
+			seen[key] = true
+			partitions = append(partitions, key)
+		}
+	}
+
+	return map[string]string{
+		"kafka.batch_size": strconv.Itoa(len(msgs)),
+		"kafka.partitions": strings.Join(partitions, ","),
+	}
+}
@@ -0,0 +1,86 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/reporter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// withPanics sets the handlers of the messages and the batches which panic
+func withPanics() Option {
+	return WithConfig(func(cfg *Config) {
+		cfg.OnProcess = func(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error {
+			panic("failed")
+		}
+		cfg.OnProcessBatch = func(context.Context, *zap.Logger, []*kafka.Message, ISleeper) error {
+			panic("failed batch")
+		}
+	})
+}
+
+func TestReportPanic(t *testing.T) {
+
+	var reported []error
+	c, _ := newTestConsumer(t, withPanics(), WithConfig(func(cfg *Config) {
+		cfg.OnError = func(_ context.Context, _ *zap.Logger, err error) {
+			reported = append(reported, err)
+		}
+	}))
+
+	topic := "a"
+	msgs := []*kafka.Message{
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 10}},
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 11}},
+		{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 5}},
+	}
+
+	// the panic of the handler is recovered by Start
+	recovered := func(fn func()) {
+		defer func() {
+			if val := recover(); val != nil {
+				c.reportPanic(val)
+			}
+		}()
+		fn()
+	}
+
+	recovered(func() { _ = c.process(zap.NewNop(), msgs[0]) })
+	recovered(func() { _ = c.processBatch(zap.NewNop(), msgs) })
+	recovered(func() { panic("loop") })
+
+	require.Len(t, reported, 3)
+
+	err := reported[0].(*reporter.PanicError)
+	require.EqualError(t, err, "panic: failed")
+	require.Equal(t, map[string]string{"kafka.topic": "a", "kafka.partition": "1", "kafka.offset": "10"}, err.Details)
+	require.Contains(t, string(err.Stack), "TestReportPanic")
+	require.NotEmpty(t, err.Goroutines)
+
+	err = reported[1].(*reporter.PanicError)
+	require.EqualError(t, err, "panic: failed batch")
+	require.Equal(t, map[string]string{"kafka.batch_size": "3", "kafka.partitions": "a[1],a[2]"}, err.Details)
+
+	err = reported[2].(*reporter.PanicError)
+	require.EqualError(t, err, "panic: loop")
+	require.Nil(t, err.Details)
+}
+
+func TestPanicEndsInflight(t *testing.T) {
+
+	c, _ := newTestConsumer(t, withPanics())
+
+	topic := "a"
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 10}}
+
+	require.Panics(t, func() { _ = c.processInflight(zap.NewNop(), msg.TopicPartition, msg) })
+	require.Empty(t, c.inflight.Snapshot())
+
+	require.Panics(t, func() {
+		_ = c.processBatchInflight(zap.NewNop(), []kafka.TopicPartition{msg.TopicPartition}, []*kafka.Message{msg})
+	})
+	require.Empty(t, c.inflight.Snapshot())
+}
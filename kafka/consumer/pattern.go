@@ -0,0 +1,122 @@
+package consumer
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"go.uber.org/zap"
+)
+
+// FuncOnTopicMatched is called when the topics matched by the patterns of Config.Topics are assigned first
+type FuncOnTopicMatched func(ctx context.Context, logger *zap.Logger, topics []string)
+
+func isTopicPattern(topic string) bool {
+	return strings.HasPrefix(topic, "^")
+}
+
+type topicMatcher struct {
+	patterns []*regexp.Regexp
+	matched  map[string]struct{}
+	mu       sync.Mutex
+}
+
+func newTopicMatcher(topics []string) (*topicMatcher, error) {
+
+	var patterns []*regexp.Regexp
+	for _, topic := range topics {
+		if !isTopicPattern(topic) {
+			continue
+		}
+
+		re, err := regexp.Compile(topic)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, re)
+	}
+
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	return &topicMatcher{
+		patterns: patterns,
+		matched:  make(map[string]struct{}),
+	}, nil
+}
+
+// Match returns the sorted topics of the partitions matched for the first time
+func (m *topicMatcher) Match(partitions []kafka.TopicPartition) []string {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var retval []string
+	for i := range partitions {
+		topic := stringValue(partitions[i].Topic)
+		if _, ok := m.matched[topic]; ok || !m.match(topic) {
+			continue
+		}
+
+		m.matched[topic] = struct{}{}
+		retval = append(retval, topic)
+	}
+
+	sort.Strings(retval)
+
+	return retval
+}
+
+// Topics returns the sorted topics matched by the regular expressions
+func (m *topicMatcher) Topics() []string {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	retval := make([]string, 0, len(m.matched))
+	for topic := range m.matched {
+		retval = append(retval, topic)
+	}
+	m.mu.Unlock()
+
+	sort.Strings(retval)
+
+	return retval
+}
+
+func (m *topicMatcher) match(topic string) bool {
+	for _, re := range m.patterns {
+		if re.MatchString(topic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchedTopics returns the assigned topics matched by the regular expressions of the subscription
+func (c *Consumer) MatchedTopics() []string {
+	return c.matcher.Topics()
+}
+
+func (c *Consumer) handleMatchedTopics(logger *zap.Logger, partitions []kafka.TopicPartition) {
+
+	topics := c.matcher.Match(partitions)
+	if len(topics) == 0 {
+		return
+	}
+
+	logger.Info("new topics are matched", zap.Strings("topics", topics))
+
+	if c.onTopicMatched != nil {
+		c.onTopicMatched(c.ctx, logger, topics)
+	}
+}
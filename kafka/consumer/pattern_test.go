@@ -0,0 +1,57 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTopicMatcher(t *testing.T) {
+
+	m, err := newTopicMatcher([]string{"a", "b"})
+	require.NoError(t, err)
+	require.Nil(t, m)
+	require.Nil(t, m.Match([]kafka.TopicPartition{{Topic: stringPointer("a")}}))
+
+	_, err = newTopicMatcher([]string{"^tenant-("})
+	require.Error(t, err)
+
+	m, err = newTopicMatcher([]string{"a", "^tenant-.*", "^other$"})
+	require.NoError(t, err)
+
+	partitions := []kafka.TopicPartition{
+		{Topic: stringPointer("tenant-2"), Partition: 0},
+		{Topic: stringPointer("tenant-2"), Partition: 1},
+		{Topic: stringPointer("tenant-1"), Partition: 0},
+		{Topic: stringPointer("a"), Partition: 0},
+	}
+	require.Equal(t, []string{"tenant-1", "tenant-2"}, m.Match(partitions))
+
+	// the known topics aren't reported again
+	partitions = append(partitions, kafka.TopicPartition{Topic: stringPointer("other"), Partition: 0})
+	require.Equal(t, []string{"other"}, m.Match(partitions))
+	require.Empty(t, m.Match(partitions))
+
+	require.Equal(t, []string{"other", "tenant-1", "tenant-2"}, m.Topics())
+}
+
+func TestHandleMatchedTopics(t *testing.T) {
+
+	var reported [][]string
+	c, _ := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.Topics = []string{"^t.*"}
+		cfg.OnTopicMatched = func(_ context.Context, _ *zap.Logger, topics []string) {
+			reported = append(reported, topics)
+		}
+	}))
+
+	partitions := []kafka.TopicPartition{{Topic: stringPointer("t1")}}
+	c.handleMatchedTopics(zap.NewNop(), partitions)
+	c.handleMatchedTopics(zap.NewNop(), partitions)
+
+	require.Equal(t, [][]string{{"t1"}}, reported)
+	require.Equal(t, []string{"t1"}, c.MatchedTopics())
+}
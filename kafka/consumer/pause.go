@@ -0,0 +1,126 @@
+package consumer
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+type pauses struct {
+	partitions map[string]kafka.TopicPartition
+	mu         sync.Mutex
+}
+
+func newPauses() *pauses {
+	return &pauses{
+		partitions: make(map[string]kafka.TopicPartition),
+	}
+}
+
+func (p *pauses) Add(in ...kafka.TopicPartition) {
+	p.mu.Lock()
+	for i := range in {
+		p.partitions[getPartitionKey(in[i].Topic, in[i].Partition)] = kafka.TopicPartition{Topic: in[i].Topic, Partition: in[i].Partition}
+	}
+	p.mu.Unlock()
+}
+
+func (p *pauses) Remove(in ...kafka.TopicPartition) {
+	p.mu.Lock()
+	for i := range in {
+		delete(p.partitions, getPartitionKey(in[i].Topic, in[i].Partition))
+	}
+	p.mu.Unlock()
+}
+
+func (p *pauses) Clear() {
+	p.mu.Lock()
+	p.partitions = make(map[string]kafka.TopicPartition)
+	p.mu.Unlock()
+}
+
+// Filter splits the partitions to the paused and the other ones
+func (p *pauses) Filter(in []kafka.TopicPartition) (paused, other []kafka.TopicPartition) {
+	if p == nil {
+		return nil, in
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range in {
+		if _, ok := p.partitions[getPartitionKey(in[i].Topic, in[i].Partition)]; ok {
+			paused = append(paused, in[i])
+		} else {
+			other = append(other, in[i])
+		}
+	}
+
+	return
+}
+
+func (p *pauses) List() []kafka.TopicPartition {
+	p.mu.Lock()
+	retval := make([]kafka.TopicPartition, 0, len(p.partitions))
+	for _, tp := range p.partitions {
+		retval = append(retval, tp)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(retval, func(i, j int) bool {
+		if a, b := stringValue(retval[i].Topic), stringValue(retval[j].Topic); a != b {
+			return a < b
+		}
+		return retval[i].Partition < retval[j].Partition
+	})
+
+	return retval
+}
+
+// Pause pauses the partitions until Resume (the pause is kept after the rebalance)
+func (c *Consumer) Pause(partitions []kafka.TopicPartition) error {
+
+	c.pauses.Add(partitions...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := c.sleep(ctx, cancel, nil, partitions); err != nil {
+		c.pauses.Remove(partitions...)
+		return err
+	}
+
+	return nil
+}
+
+// Resume resumes the partitions paused by Pause (or by a sleep)
+func (c *Consumer) Resume(partitions []kafka.TopicPartition) error {
+	c.pauses.Remove(partitions...)
+	return c.CancelSleep(partitions)
+}
+
+// PausedPartitions returns the partitions paused by Pause
+func (c *Consumer) PausedPartitions() []kafka.TopicPartition {
+	return c.pauses.List()
+}
+
+func (c *Consumer) restorePauses(partitions []kafka.TopicPartition) error {
+
+	paused, _ := c.pauses.Filter(partitions)
+	if len(paused) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return c.sleep(ctx, cancel, nil, paused)
+}
+
+func (c *Consumer) keepPaused(partitions []kafka.TopicPartition) []kafka.TopicPartition {
+
+	paused, other := c.pauses.Filter(partitions)
+	if len(paused) > 0 {
+		c.sleeps.Add(&sleepEntry{}, paused...)
+	}
+
+	return other
+}
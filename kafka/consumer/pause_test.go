@@ -0,0 +1,55 @@
+package consumer
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauses(t *testing.T) {
+
+	p := newPauses()
+
+	p1 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1, Offset: 10}
+	p2 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 2}
+	p3 := kafka.TopicPartition{Topic: stringPointer("t0"), Partition: 3}
+
+	p.Add(p2, p1, p3)
+	list := p.List()
+	require.Len(t, list, 3)
+	require.Equal(t, "t0", *list[0].Topic)
+	require.Equal(t, int32(1), list[1].Partition)
+	// the offset isn't kept
+	require.Equal(t, kafka.Offset(0), list[1].Offset)
+
+	p.Remove(p2)
+	paused, other := p.Filter([]kafka.TopicPartition{p1, p2})
+	require.Equal(t, []kafka.TopicPartition{p1}, paused)
+	require.Equal(t, []kafka.TopicPartition{p2}, other)
+
+	p.Clear()
+	require.Empty(t, p.List())
+
+	// nil is allowed
+	var empty *pauses
+	paused, other = empty.Filter([]kafka.TopicPartition{p1})
+	require.Empty(t, paused)
+	require.Equal(t, []kafka.TopicPartition{p1}, other)
+}
+
+func TestKeepPaused(t *testing.T) {
+
+	c, _ := newTestConsumer(t)
+
+	p1 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1}
+	p2 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 2}
+
+	c.pauses.Add(p1)
+	require.Equal(t, []kafka.TopicPartition{p1}, c.PausedPartitions())
+
+	// the sleep is over: the paused partition isn't resumed
+	require.Equal(t, []kafka.TopicPartition{p2}, c.keepPaused([]kafka.TopicPartition{p1, p2}))
+	require.True(t, c.sleeps.Contains(p1))
+	require.False(t, c.sleeps.Contains(p2))
+}
@@ -0,0 +1,45 @@
+package consumer
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/serde/compress"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Decompress decodes the values compressed by producer.CompressingProducer (see Config.Transformers)
+func Decompress() FuncTransform {
+	return func(_ context.Context, _ *zap.Logger, msg *kafka.Message) (*kafka.Message, error) {
+
+		name, ok := "", false
+		headers := make([]kafka.Header, 0, len(msg.Headers))
+		for _, h := range msg.Headers {
+			if h.Key == compress.HeaderCodec {
+				name, ok = string(h.Value), true
+			} else {
+				headers = append(headers, h)
+			}
+		}
+
+		if !ok {
+			return msg, nil
+		}
+
+		codec, err := compress.Get(name)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := codec.Decode(msg.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode value by %s", name)
+		}
+
+		msg.Value = value
+		msg.Headers = headers
+
+		return msg, nil
+	}
+}
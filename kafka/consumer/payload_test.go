@@ -0,0 +1,55 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/serde/compress"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDecompress(t *testing.T) {
+
+	fn := Decompress()
+	ctx, logger := context.Background(), zap.NewNop()
+
+	// the message without the header
+	msg := &kafka.Message{Value: []byte("value")}
+	res, err := fn(ctx, logger, msg)
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), res.Value)
+
+	codec, err := compress.Get(compress.Gzip)
+	require.NoError(t, err)
+
+	src := bytes.Repeat([]byte("value"), 10)
+	enc, err := codec.Encode(src)
+	require.NoError(t, err)
+
+	msg = &kafka.Message{
+		Value: enc,
+		Headers: []kafka.Header{
+			{Key: "h1", Value: []byte("1")},
+			{Key: compress.HeaderCodec, Value: []byte(compress.Gzip)},
+		},
+	}
+	res, err = fn(ctx, logger, msg)
+	require.NoError(t, err)
+	require.Equal(t, src, res.Value)
+	require.Equal(t, []kafka.Header{{Key: "h1", Value: []byte("1")}}, res.Headers)
+
+	_, err = fn(ctx, logger, &kafka.Message{
+		Value:   []byte("value"),
+		Headers: []kafka.Header{{Key: compress.HeaderCodec, Value: []byte("brotli")}},
+	})
+	require.EqualError(t, err, "brotli: unknown codec")
+
+	_, err = fn(ctx, logger, &kafka.Message{
+		Value:   []byte("value"),
+		Headers: []kafka.Header{{Key: compress.HeaderCodec, Value: []byte(compress.Gzip)}},
+	})
+	require.EqualError(t, err, "failed to decode value by gzip: failed to decompress: unexpected EOF")
+}
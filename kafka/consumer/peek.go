@@ -0,0 +1,189 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+)
+
+const (
+	_PeekGroupID     = "peek"
+	_PeekReadTimeout = 100 * time.Millisecond
+)
+
+// ErrMessageNotFound is returned if the partition has no message with the offset
+var ErrMessageNotFound = errors.New("message not found")
+
+// A PeekedMessage is a decoded message (see PeekHandler)
+type PeekedMessage struct {
+	Topic     string            `json:"topic"`
+	Partition int32             `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Timestamp time.Time         `json:"timestamp"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Key       interface{}       `json:"key"`
+	Value     interface{}       `json:"value"`
+}
+
+type peekReader interface {
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error)
+	Assign(partitions []kafka.TopicPartition) error
+	ReadMessage(timeout time.Duration) (*kafka.Message, error)
+}
+
+// Peek reads the message at the offset without joining the group
+func Peek(ctx context.Context, cfg *kafka.ConfigMap, topic string, partition int32, offset int64) (*kafka.Message, error) {
+
+	configMap := kafka.ConfigMap{
+		"group.id": _PeekGroupID,
+	}
+	for k, v := range *cfg {
+		configMap[k] = v
+	}
+	configMap["enable.auto.commit"] = false
+	configMap["enable.auto.offset.store"] = false
+	configMap["go.events.channel.enable"] = false
+
+	reader, err := kafka.NewConsumer(&configMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "create reader failed")
+	}
+	defer reader.Close()
+
+	return peek(ctx, reader, topic, partition, offset)
+}
+
+func peek(ctx context.Context, r peekReader, topic string, partition int32, offset int64) (*kafka.Message, error) {
+
+	timeout := 10 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	low, high, err := r.QueryWatermarkOffsets(topic, partition, int(timeout/time.Millisecond))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query watermark offsets")
+	}
+
+	if offset < low || offset >= high {
+		return nil, errors.Wrapf(ErrMessageNotFound, "offset %d is out of range [%d, %d)", offset, low, high)
+	}
+
+	err = r.Assign([]kafka.TopicPartition{{Topic: &topic, Partition: partition, Offset: kafka.Offset(offset)}})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to assign partition")
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		msg, err := r.ReadMessage(_PeekReadTimeout)
+		if err != nil {
+			if kafkaErr, ok := err.(kafka.Error); ok && kafkaErr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			return nil, errors.Wrap(err, "failed to read message")
+		}
+
+		if int64(msg.TopicPartition.Offset) != offset {
+			// the message was removed by the compaction
+			return nil, errors.Wrapf(ErrMessageNotFound, "offset %d", offset)
+		}
+
+		return msg, nil
+	}
+}
+
+// NewPeekedMessage decodes the message
+func NewPeekedMessage(msg *kafka.Message) *PeekedMessage {
+
+	retval := &PeekedMessage{
+		Partition: msg.TopicPartition.Partition,
+		Offset:    int64(msg.TopicPartition.Offset),
+		Timestamp: msg.Timestamp,
+		Key:       decodePayload(msg.Key),
+		Value:     decodePayload(msg.Value),
+	}
+
+	if msg.TopicPartition.Topic != nil {
+		retval.Topic = *msg.TopicPartition.Topic
+	}
+
+	if len(msg.Headers) > 0 {
+		retval.Headers = make(map[string]string, len(msg.Headers))
+		for _, h := range msg.Headers {
+			retval.Headers[h.Key] = string(h.Value)
+		}
+	}
+
+	return retval
+}
+
+func decodePayload(data []byte) interface{} {
+
+	switch {
+	case data == nil:
+		return nil
+	case json.Valid(data):
+		return json.RawMessage(data)
+	case utf8.Valid(data):
+		return string(data)
+	default:
+		return data
+	}
+}
+
+// PeekHandler returns the handler of 'GET ?topic=name&partition=0&offset=1' (see PeekedMessage)
+func PeekHandler(cfg *kafka.ConfigMap, timeout time.Duration) http.Handler {
+	return peekHandler(timeout, func(ctx context.Context, topic string, partition int32, offset int64) (*kafka.Message, error) {
+		return Peek(ctx, cfg, topic, partition, offset)
+	})
+}
+
+func peekHandler(timeout time.Duration, fn func(ctx context.Context, topic string, partition int32, offset int64) (*kafka.Message, error)) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := req.URL.Query()
+
+		topic := query.Get("topic")
+		partition, errPartition := strconv.ParseInt(query.Get("partition"), 10, 32)
+		offset, errOffset := strconv.ParseInt(query.Get("offset"), 10, 64)
+		if topic == "" || errPartition != nil || errOffset != nil {
+			http.Error(w, "invalid topic, partition or offset", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+
+		msg, err := fn(ctx, topic, int32(partition), offset)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, ErrMessageNotFound) {
+				code = http.StatusNotFound
+			}
+
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(NewPeekedMessage(msg)); err != nil {
+			_, _ = w.Write([]byte("unknown"))
+		}
+	})
+}
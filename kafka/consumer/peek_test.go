@@ -0,0 +1,134 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+)
+
+type testPeekReader struct {
+	low, high int64
+	assigned  []kafka.TopicPartition
+	msgs      []*kafka.Message
+}
+
+func (r *testPeekReader) QueryWatermarkOffsets(string, int32, int) (int64, int64, error) {
+	return r.low, r.high, nil
+}
+
+func (r *testPeekReader) Assign(partitions []kafka.TopicPartition) error {
+	r.assigned = partitions
+	return nil
+}
+
+func (r *testPeekReader) ReadMessage(time.Duration) (*kafka.Message, error) {
+	if len(r.msgs) == 0 {
+		return nil, kafka.NewError(kafka.ErrTimedOut, "timed out", false)
+	}
+
+	msg := r.msgs[0]
+	r.msgs = r.msgs[1:]
+
+	return msg, nil
+}
+
+func TestPeek(t *testing.T) {
+
+	topic := "a"
+	reader := &testPeekReader{
+		low:  2,
+		high: 10,
+		msgs: []*kafka.Message{{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 5}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	msg, err := peek(ctx, reader, topic, 1, 5)
+	require.NoError(t, err)
+	require.Equal(t, kafka.Offset(5), msg.TopicPartition.Offset)
+	require.Equal(t, []kafka.TopicPartition{{Topic: &topic, Partition: 1, Offset: 5}}, reader.assigned)
+
+	// out of range
+	_, err = peek(ctx, reader, topic, 1, 10)
+	require.True(t, errors.Is(err, ErrMessageNotFound))
+	require.EqualError(t, err, "offset 10 is out of range [2, 10): message not found")
+
+	// compacted
+	reader.msgs = []*kafka.Message{{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 4}}}
+	_, err = peek(ctx, reader, topic, 1, 3)
+	require.True(t, errors.Is(err, ErrMessageNotFound))
+
+	// timeout
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = peek(ctx, reader, topic, 1, 3)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestNewPeekedMessage(t *testing.T) {
+
+	topic := "a"
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	data, err := json.Marshal(NewPeekedMessage(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 5},
+		Timestamp:      ts,
+		Headers:        []kafka.Header{{Key: "h", Value: []byte("1")}},
+		Key:            []byte("key"),
+		Value:          []byte(`{"id":1}`),
+	}))
+	require.NoError(t, err)
+	require.JSONEq(t,
+		`{"topic":"a","partition":1,"offset":5,"timestamp":"2020-01-02T03:04:05Z","headers":{"h":"1"},"key":"key","value":{"id":1}}`,
+		string(data))
+
+	data, err = json.Marshal(NewPeekedMessage(&kafka.Message{Value: []byte{0xff, 0x00}}))
+	require.NoError(t, err)
+	require.JSONEq(t,
+		`{"topic":"","partition":0,"offset":0,"timestamp":"0001-01-01T00:00:00Z","key":null,"value":"/wA="}`,
+		string(data))
+}
+
+func TestPeekHandler(t *testing.T) {
+
+	handler := peekHandler(time.Second, func(_ context.Context, topic string, partition int32, offset int64) (*kafka.Message, error) {
+		switch offset {
+		case 1:
+			return &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: 1}}, nil
+		case 2:
+			return nil, ErrMessageNotFound
+		default:
+			return nil, errors.New("failed")
+		}
+	})
+
+	request := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	require.Equal(t, http.StatusMethodNotAllowed, request(http.MethodPost, "/?topic=a&partition=0&offset=1").Code)
+	require.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/?partition=0&offset=1").Code)
+	require.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/?topic=a&partition=x&offset=1").Code)
+	require.Equal(t, http.StatusNotFound, request(http.MethodGet, "/?topic=a&partition=0&offset=2").Code)
+	require.Equal(t, http.StatusInternalServerError, request(http.MethodGet, "/?topic=a&partition=0&offset=3").Code)
+
+	w := request(http.MethodGet, "/?topic=a&partition=3&offset=1")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res PeekedMessage
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+	require.Equal(t, "a", res.Topic)
+	require.Equal(t, int32(3), res.Partition)
+	require.Equal(t, int64(1), res.Offset)
+}
@@ -0,0 +1,102 @@
+package consumer
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// FuncOnPoison is called before the poison message is skipped (err is the error of the last failure)
+type FuncOnPoison func(ctx context.Context, logger *zap.Logger, msg *kafka.Message, err error)
+
+// PoisonConfig enables the skip of the message after MaxFailures consecutive failures
+type PoisonConfig struct {
+	// MaxFailures is a count of the failures of the message (every failure is after the retries, see RetryConfig)
+	MaxFailures int `mapstructure:"max-failures"`
+	// OnPoison is called before the skip (e.g. to save the message to the dead letter queue), it's optional
+	OnPoison FuncOnPoison `mapstructure:"-"`
+}
+
+type poison struct {
+	maxFailures int
+	onPoison    FuncOnPoison
+	failures    map[string]int
+	mu          sync.Mutex
+}
+
+func newPoison(cfg *PoisonConfig) *poison {
+
+	if cfg == nil || cfg.MaxFailures <= 0 {
+		return nil
+	}
+
+	return &poison{
+		maxFailures: cfg.MaxFailures,
+		onPoison:    cfg.OnPoison,
+		failures:    make(map[string]int),
+	}
+}
+
+// Fail counts the failure of the message and reports whether the message must be skipped
+func (p *poison) Fail(tp kafka.TopicPartition) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := poisonKey(tp)
+	p.failures[key]++
+	if p.failures[key] < p.maxFailures {
+		return false
+	}
+
+	delete(p.failures, key)
+	return true
+}
+
+// Reset drops the failures of the processed message
+func (p *poison) Reset(tp kafka.TopicPartition) {
+	p.mu.Lock()
+	delete(p.failures, poisonKey(tp))
+	p.mu.Unlock()
+}
+
+func poisonKey(tp kafka.TopicPartition) string {
+	return stringValue(tp.Topic) + ":" + strconv.Itoa(int(tp.Partition)) + ":" + strconv.FormatInt(int64(tp.Offset), 10)
+}
+
+func (c *Consumer) processMessage(opLog *zap.Logger, msg *kafka.Message) error {
+
+	for {
+		err := c.retrier.Do(c.ctx, opLog, func() error { return c.process(opLog, msg) })
+		if c.poison == nil {
+			return err
+		}
+
+		if err == nil {
+			c.poison.Reset(msg.TopicPartition)
+			return nil
+		}
+
+		if c.ctx.Err() != nil || errors.Is(err, ErrRetryLater) {
+			return err
+		}
+
+		if !c.poison.Fail(msg.TopicPartition) {
+			opLog.Warn("failed to process message, process again", zap.Error(err))
+			continue
+		}
+
+		opLog.Error("skipped poison message", zap.Int("failures", c.poison.maxFailures), zap.Error(err))
+
+		c.inflight.Poisoned(stringValue(msg.TopicPartition.Topic))
+
+		if c.poison.onPoison != nil {
+			c.poison.onPoison(c.ctx, opLog, msg, err)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,85 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPoison(t *testing.T) {
+
+	require.Nil(t, newPoison(nil))
+	require.Nil(t, newPoison(&PoisonConfig{}))
+
+	p := newPoison(&PoisonConfig{MaxFailures: 2})
+
+	topic := "a"
+	tp := kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 10}
+	other := kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 11}
+
+	require.False(t, p.Fail(tp))
+	require.False(t, p.Fail(other))
+	require.True(t, p.Fail(tp))
+
+	// the counter is dropped after the skip
+	require.False(t, p.Fail(tp))
+
+	// the counter is dropped after the success
+	p.Reset(other)
+	require.False(t, p.Fail(other))
+}
+
+func TestPoisonSkip(t *testing.T) {
+
+	var (
+		calls    int
+		poisoned []kafka.Offset
+	)
+
+	failed := kafka.Offset(2)
+
+	c, _ := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.OnProcess = func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
+			calls++
+			if msg.TopicPartition.Offset == failed {
+				return errors.New("malformed")
+			}
+			return nil
+		}
+		cfg.Poison = &PoisonConfig{
+			MaxFailures: 3,
+			OnPoison: func(_ context.Context, _ *zap.Logger, msg *kafka.Message, err error) {
+				require.EqualError(t, err, "malformed")
+				poisoned = append(poisoned, msg.TopicPartition.Offset)
+			},
+		}
+	}))
+
+	topic := "a"
+	offsets := newOffset()
+	for i := 1; i <= 3; i++ {
+		msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(i)}}
+		require.NoError(t, c.handleMessage(msg, offsets))
+	}
+
+	// the message is processed 3 times and skipped, the offsets of all messages are stored
+	require.Equal(t, 5, calls)
+	require.Equal(t, []kafka.Offset{2}, poisoned)
+
+	list, _ := offsets.Get()
+	require.Len(t, list, 1)
+	require.Equal(t, kafka.Offset(3), list[0].Offset)
+
+	// the consumer is stopped: the error is returned
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.ctx = ctx
+
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: failed}}
+	require.EqualError(t, c.handleMessage(msg, newOffset()), "malformed")
+	require.Equal(t, []kafka.Offset{2}, poisoned)
+}
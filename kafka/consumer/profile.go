@@ -0,0 +1,71 @@
+package consumer
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+type labels struct {
+	handler string
+}
+
+func newLabels(cfg *Config) *labels {
+	if !cfg.ProfileLabels {
+		return nil
+	}
+
+	handler := cfg.HandlerName
+	if handler == "" {
+		if cfg.OnProcessBatch != nil {
+			handler = funcName(cfg.OnProcessBatch)
+		} else {
+			handler = funcName(cfg.OnProcess)
+		}
+	}
+
+	return &labels{handler: handler}
+}
+
+// Do calls the function with the pprof labels of the topics and the handler
+func (l *labels) Do(ctx context.Context, topics string, fn func(ctx context.Context) error) error {
+	if l == nil {
+		return fn(ctx)
+	}
+
+	var err error
+	pprof.Do(ctx, pprof.Labels("topic", topics, "handler", l.handler), func(ctx context.Context) {
+		err = fn(ctx)
+	})
+
+	return err
+}
+
+func batchTopics(msgs []*kafka.Message) string {
+
+	set := make(map[string]struct{})
+	for _, msg := range msgs {
+		set[stringValue(msg.TopicPartition.Topic)] = struct{}{}
+	}
+
+	list := make([]string, 0, len(set))
+	for topic := range set {
+		list = append(list, topic)
+	}
+	sort.Strings(list)
+
+	return strings.Join(list, ",")
+}
+
+func funcName(fn interface{}) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+
+	return ""
+}
@@ -0,0 +1,61 @@
+package consumer
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func testProfiledHandler(context.Context, *zap.Logger, *kafka.Message, ISleeper, ICommitter) error {
+	return nil
+}
+
+func TestNewLabels(t *testing.T) {
+
+	require.Nil(t, newLabels(&Config{OnProcess: testProfiledHandler}))
+
+	l := newLabels(&Config{OnProcess: testProfiledHandler, ProfileLabels: true})
+	require.Equal(t, "github.com/dialogs/dialog-go-lib/kafka/consumer.testProfiledHandler", l.handler)
+
+	l = newLabels(&Config{OnProcess: testProfiledHandler, ProfileLabels: true, HandlerName: "orders"})
+	require.Equal(t, "orders", l.handler)
+}
+
+func TestProcessLabels(t *testing.T) {
+
+	var topic, handler string
+	c, _ := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.OnProcess = func(ctx context.Context, _ *zap.Logger, _ *kafka.Message, _ ISleeper, _ ICommitter) error {
+			topic, _ = pprof.Label(ctx, "topic")
+			handler, _ = pprof.Label(ctx, "handler")
+			return nil
+		}
+		cfg.ProfileLabels = true
+		cfg.HandlerName = "h1"
+	}))
+
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: stringPointer("t1")}}
+	require.NoError(t, c.process(zap.NewNop(), msg))
+	require.Equal(t, "t1", topic)
+	require.Equal(t, "h1", handler)
+
+	// disabled
+	c.labels = nil
+	require.NoError(t, c.process(zap.NewNop(), msg))
+	require.Empty(t, topic)
+	require.Empty(t, handler)
+}
+
+func TestBatchTopics(t *testing.T) {
+
+	msgs := []*kafka.Message{
+		{TopicPartition: kafka.TopicPartition{Topic: stringPointer("t2")}},
+		{TopicPartition: kafka.TopicPartition{Topic: stringPointer("t1")}},
+		{TopicPartition: kafka.TopicPartition{Topic: stringPointer("t2")}},
+	}
+	require.Equal(t, "t1,t2", batchTopics(msgs))
+}
@@ -0,0 +1,113 @@
+package consumer
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A ProgressStage is a checkpoint of the message processing
+type ProgressStage struct {
+	Name string
+	Time time.Time
+}
+
+// A Progress records the stages of the message processing (see Checkpoint)
+type Progress struct {
+	mu     sync.Mutex
+	stages []ProgressStage
+}
+
+type progressKey struct{}
+
+// ContextWithProgress returns the context with the new progress recorder
+func ContextWithProgress(ctx context.Context) (context.Context, *Progress) {
+	p := &Progress{}
+	return context.WithValue(ctx, progressKey{}, p), p
+}
+
+// ProgressFromContext returns the progress recorder of the context (nil if not found)
+func ProgressFromContext(ctx context.Context) *Progress {
+	p, _ := ctx.Value(progressKey{}).(*Progress)
+	return p
+}
+
+// Checkpoint records the stage of the message processing
+func Checkpoint(ctx context.Context, stage string) {
+	ProgressFromContext(ctx).Record(stage)
+}
+
+// Record adds the stage (the nil recorder does nothing)
+func (p *Progress) Record(stage string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.stages = append(p.stages, ProgressStage{Name: stage, Time: time.Now()})
+	p.mu.Unlock()
+}
+
+// Stages returns the recorded stages
+func (p *Progress) Stages() []ProgressStage {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]ProgressStage{}, p.stages...)
+}
+
+// String returns the names of the stages ('decode > save > notify')
+func (p *Progress) String() string {
+
+	stages := p.Stages()
+
+	names := make([]string, len(stages))
+	for i := range stages {
+		names[i] = stages[i].Name
+	}
+
+	return strings.Join(names, " > ")
+}
+
+// A ProgressError is an error of OnProcess with the completed stages
+type ProgressError struct {
+	Stages []ProgressStage
+	Err    error
+}
+
+func (e *ProgressError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the cause (errors.Unwrap implementation)
+func (e *ProgressError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the cause (errors.Cause implementation)
+func (e *ProgressError) Cause() error {
+	return e.Err
+}
+
+// Tags returns the stages as the tags of the error report (reporter.ITaggedError implementation)
+func (e *ProgressError) Tags() map[string]string {
+
+	if len(e.Stages) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(e.Stages))
+	for i := range e.Stages {
+		names[i] = e.Stages[i].Name
+	}
+
+	return map[string]string{
+		"kafka.progress":       strings.Join(names, " > "),
+		"kafka.progress.stage": names[len(names)-1],
+	}
+}
@@ -0,0 +1,63 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestProgress(t *testing.T) {
+
+	// without the recorder
+	Checkpoint(context.Background(), "skipped")
+	require.Nil(t, ProgressFromContext(context.Background()))
+
+	ctx, p := ContextWithProgress(context.Background())
+	require.Equal(t, p, ProgressFromContext(ctx))
+	require.Equal(t, "", p.String())
+
+	Checkpoint(ctx, "decode")
+	Checkpoint(ctx, "save")
+
+	stages := p.Stages()
+	require.Len(t, stages, 2)
+	require.Equal(t, "decode", stages[0].Name)
+	require.False(t, stages[1].Time.Before(stages[0].Time))
+	require.Equal(t, "decode > save", p.String())
+}
+
+func TestProcessProgressError(t *testing.T) {
+
+	cause := errors.New("failed")
+
+	c, _ := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.OnProcess = func(ctx context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
+			if string(msg.Value) == "plain" {
+				return cause
+			}
+
+			Checkpoint(ctx, "decode")
+			Checkpoint(ctx, "save")
+			return cause
+		}
+	}))
+
+	err := c.process(zap.NewNop(), &kafka.Message{Value: []byte("plain")})
+	require.Equal(t, cause, err)
+
+	err = c.process(zap.NewNop(), &kafka.Message{})
+	require.EqualError(t, err, "failed")
+	require.True(t, errors.Is(err, cause))
+	require.Equal(t, cause, errors.Cause(err))
+
+	progressErr, ok := err.(*ProgressError)
+	require.True(t, ok)
+	require.Len(t, progressErr.Stages, 2)
+	require.Equal(t,
+		map[string]string{"kafka.progress": "decode > save", "kafka.progress.stage": "save"},
+		progressErr.Tags())
+}
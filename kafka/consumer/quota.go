@@ -0,0 +1,136 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock"
+	"go.uber.org/zap"
+)
+
+// QuotaConfig limits the consumption rate of the topic
+type QuotaConfig struct {
+	// Rate is a max count of the messages per second
+	Rate float64 `mapstructure:"rate"`
+	// Burst is a max count of the messages consumed without the pause (Rate by default)
+	Burst int `mapstructure:"burst"`
+}
+
+type quota struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	until  time.Time
+}
+
+type quotas struct {
+	topics map[string]*quota
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+func newQuotas(cfg map[string]QuotaConfig, clk clock.Clock) *quotas {
+
+	q := &quotas{
+		topics: make(map[string]*quota),
+		now:    clk.Now,
+	}
+
+	for topic, item := range cfg {
+		if item.Rate <= 0 {
+			continue
+		}
+
+		burst := float64(item.Burst)
+		if burst <= 0 {
+			burst = item.Rate
+		}
+
+		q.topics[topic] = &quota{
+			rate:   item.Rate,
+			burst:  burst,
+			tokens: burst,
+		}
+	}
+
+	if len(q.topics) == 0 {
+		return nil
+	}
+
+	return q
+}
+
+// Take counts the message of the topic and returns the pause of the topic
+func (q *quotas) Take(topic string) time.Duration {
+
+	if q == nil {
+		return 0
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item, ok := q.topics[topic]
+	if !ok {
+		return 0
+	}
+
+	now := q.now()
+	if !item.last.IsZero() {
+		item.tokens += now.Sub(item.last).Seconds() * item.rate
+		if item.tokens > item.burst {
+			item.tokens = item.burst
+		}
+	}
+	item.last = now
+	item.tokens--
+
+	if item.tokens >= 0 || now.Before(item.until) {
+		return 0
+	}
+
+	delay := time.Duration(-item.tokens / item.rate * float64(time.Second))
+	item.until = now.Add(delay)
+
+	return delay
+}
+
+func (c *Consumer) throttleTopic(logger *zap.Logger, tp kafka.TopicPartition) {
+
+	if c.quotas == nil || tp.Topic == nil {
+		return
+	}
+
+	topic := *tp.Topic
+	delay := c.quotas.Take(topic)
+	if delay <= 0 {
+		return
+	}
+
+	if c.cfg.Metrics != nil && c.cfg.Metrics.QuotaThrottle != nil {
+		c.cfg.Metrics.QuotaThrottle(topic).Add(delay.Seconds())
+	}
+
+	assignment, err := c.reader.Assignment()
+	if err != nil {
+		logger.Warn("failed to get assignment", zap.Error(err))
+		return
+	}
+
+	partitions := make([]kafka.TopicPartition, 0, len(assignment))
+	for i := range assignment {
+		if assignment[i].Topic != nil && *assignment[i].Topic == topic {
+			partitions = append(partitions, assignment[i])
+		}
+	}
+
+	logger.Debug("quota exceeded", zap.String("topic", topic), zap.Duration("delay", delay))
+
+	ctx, cancel := context.WithTimeout(context.Background(), delay)
+	if err := c.sleep(ctx, cancel, nil, partitions); err != nil {
+		logger.Warn("failed to pause topic", zap.String("topic", topic), zap.Error(err))
+	}
+}
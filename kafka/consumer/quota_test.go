@@ -0,0 +1,38 @@
+package consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotas(t *testing.T) {
+
+	require.Nil(t, newQuotas(nil, clock.Real))
+	require.Nil(t, newQuotas(map[string]QuotaConfig{"a": {}}, clock.Real))
+
+	clk := mock.NewClock(time.Unix(1000, 0))
+	q := newQuotas(map[string]QuotaConfig{"a": {Rate: 10, Burst: 2}}, clk)
+
+	// burst
+	require.Equal(t, time.Duration(0), q.Take("a"))
+	require.Equal(t, time.Duration(0), q.Take("a"))
+	// the topic without quota
+	require.Equal(t, time.Duration(0), q.Take("b"))
+
+	// exceeded: the pause for one message
+	require.Equal(t, time.Millisecond*100, q.Take("a"))
+	// the topic is already paused
+	require.Equal(t, time.Duration(0), q.Take("a"))
+
+	// the tokens are restored
+	clk.Add(time.Second)
+	require.Equal(t, time.Duration(0), q.Take("a"))
+
+	// nil is allowed
+	var empty *quotas
+	require.Equal(t, time.Duration(0), empty.Take("a"))
+}
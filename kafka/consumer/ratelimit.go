@@ -0,0 +1,90 @@
+package consumer
+
+import (
+	"context"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock"
+	"go.uber.org/zap"
+)
+
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	clock  clock.Clock
+}
+
+func newRateLimiter(rate float64, burst int, clk clock.Clock) *rateLimiter {
+
+	if rate <= 0 {
+		return nil
+	}
+
+	b := float64(burst)
+	if b <= 0 {
+		b = rate
+	}
+	if b < 1 {
+		b = 1
+	}
+
+	return &rateLimiter{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+		clock:  clk,
+	}
+}
+
+// Wait takes the token and returns the waiting time
+func (r *rateLimiter) Wait(ctx context.Context) (time.Duration, error) {
+
+	if r == nil {
+		return 0, nil
+	}
+
+	now := r.clock.Now()
+	if !r.last.IsZero() {
+		r.tokens += now.Sub(r.last).Seconds() * r.rate
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+	}
+	r.last = now
+	r.tokens--
+
+	if r.tokens >= 0 {
+		return 0, nil
+	}
+
+	delay := time.Duration(-r.tokens / r.rate * float64(time.Second))
+
+	timer := r.clock.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return delay, nil
+	case <-ctx.Done():
+		// the token isn't used
+		r.tokens++
+		return 0, ctx.Err()
+	}
+}
+
+func (c *Consumer) limitRate(logger *zap.Logger) bool {
+
+	delay, err := c.rateLimiter.Wait(c.loopCtx)
+	if err != nil {
+		logger.Debug("rate limit wait is cancelled", zap.Error(err))
+		return false
+	}
+
+	if delay > 0 && c.cfg.Metrics != nil && c.cfg.Metrics.RateLimitWait != nil {
+		c.cfg.Metrics.RateLimitWait.Add(delay.Seconds())
+	}
+
+	return true
+}
@@ -0,0 +1,68 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+
+	require.Nil(t, newRateLimiter(0, 10, clock.Real))
+
+	// nil is allowed
+	var empty *rateLimiter
+	delay, err := empty.Wait(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), delay)
+
+	clk := mock.NewClock(time.Unix(1000, 0))
+	r := newRateLimiter(10, 2, clk)
+
+	// burst
+	for i := 0; i < 2; i++ {
+		delay, err := r.Wait(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, time.Duration(0), delay)
+	}
+
+	// exceeded: the loop waits for one token
+	done := make(chan time.Duration, 1)
+	go func() {
+		delay, _ := r.Wait(context.Background())
+		done <- delay
+	}()
+
+	require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+	require.Len(t, done, 0)
+	clk.Add(time.Millisecond * 100)
+	require.Equal(t, time.Millisecond*100, <-done)
+
+	// the waiting is cancelled: the token is returned
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, err := r.Wait(ctx)
+		require.Equal(t, context.Canceled, err)
+		done <- 0
+	}()
+
+	require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	clk.Add(time.Millisecond * 100)
+	delay, err = r.Wait(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), delay)
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+
+	// the burst is the rate by default and one message at least
+	require.Equal(t, float64(5), newRateLimiter(5, 0, clock.Real).burst)
+	require.Equal(t, float64(1), newRateLimiter(0.5, 0, clock.Real).burst)
+}
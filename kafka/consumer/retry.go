@@ -0,0 +1,99 @@
+package consumer
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// RetryConfig enables the retries of OnProcess before the error is returned
+type RetryConfig struct {
+	// MaxAttempts is a max count of the calls of OnProcess (including the first one)
+	MaxAttempts int `mapstructure:"max-attempts"`
+	// InitialBackoff is a delay before the first retry (100ms by default), it's doubled on every retry
+	InitialBackoff time.Duration `mapstructure:"initial-backoff"`
+	// MaxBackoff is a max delay between the retries (10s by default)
+	MaxBackoff time.Duration `mapstructure:"max-backoff"`
+	// Jitter is a max random share of the delay added to it (0..1)
+	Jitter float64 `mapstructure:"jitter"`
+}
+
+type retrier struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	jitter         float64
+	random         func() float64
+	clock          clock.Clock
+}
+
+func newRetrier(cfg *RetryConfig, clk clock.Clock) *retrier {
+	if cfg == nil || cfg.MaxAttempts <= 1 {
+		return nil
+	}
+
+	r := &retrier{
+		maxAttempts:    cfg.MaxAttempts,
+		initialBackoff: cfg.InitialBackoff,
+		maxBackoff:     cfg.MaxBackoff,
+		jitter:         cfg.Jitter,
+		random:         rand.Float64,
+		clock:          clk,
+	}
+
+	if r.initialBackoff <= 0 {
+		r.initialBackoff = time.Millisecond * 100
+	}
+	if r.maxBackoff <= 0 {
+		r.maxBackoff = time.Second * 10
+	}
+
+	return r
+}
+
+func (r *retrier) backoff(attempt int) time.Duration {
+
+	delay := r.initialBackoff
+	for i := 1; i < attempt && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.maxBackoff {
+		delay = r.maxBackoff
+	}
+
+	if r.jitter > 0 {
+		delay += time.Duration(float64(delay) * r.jitter * r.random())
+	}
+
+	return delay
+}
+
+// Do calls the function until success, the max attempts or the end of the context
+func (r *retrier) Do(ctx context.Context, logger *zap.Logger, fn func() error) error {
+
+	err := fn()
+	if r == nil {
+		return err
+	}
+
+	for attempt := 1; err != nil && !errors.Is(err, ErrRetryLater) && attempt < r.maxAttempts; attempt++ {
+		delay := r.backoff(attempt)
+		logger.Warn("retry processing", zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Error(err))
+
+		timer := r.clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C():
+		}
+
+		err = fn()
+	}
+
+	return err
+}
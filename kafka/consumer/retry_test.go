@@ -0,0 +1,123 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRetryDisabled(t *testing.T) {
+	require.Nil(t, newRetrier(nil, clock.Real))
+	require.Nil(t, newRetrier(&RetryConfig{MaxAttempts: 1}, clock.Real))
+
+	var r *retrier
+	var calls int
+	err := r.Do(context.Background(), zap.NewNop(), func() error {
+		calls++
+		return errors.New("failed")
+	})
+	require.EqualError(t, err, "failed")
+	require.Equal(t, 1, calls)
+}
+
+func TestRetryBackoff(t *testing.T) {
+
+	r := newRetrier(&RetryConfig{MaxAttempts: 10, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, clock.Real)
+	require.Equal(t, time.Second, r.backoff(1))
+	require.Equal(t, 2*time.Second, r.backoff(2))
+	require.Equal(t, 4*time.Second, r.backoff(3))
+	require.Equal(t, 5*time.Second, r.backoff(4))
+	require.Equal(t, 5*time.Second, r.backoff(100))
+
+	r = newRetrier(&RetryConfig{MaxAttempts: 10, InitialBackoff: time.Second, Jitter: 0.5}, clock.Real)
+	r.random = func() float64 { return 0.5 }
+	require.Equal(t, 1250*time.Millisecond, r.backoff(1))
+
+	r = newRetrier(&RetryConfig{MaxAttempts: 2}, clock.Real)
+	require.Equal(t, 100*time.Millisecond, r.backoff(1))
+	require.Equal(t, 10*time.Second, r.maxBackoff)
+}
+
+func TestRetryDo(t *testing.T) {
+
+	r := newRetrier(&RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond}, clock.Real)
+
+	var calls int
+	err := r.Do(context.Background(), zap.NewNop(), func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	calls = 0
+	err = r.Do(context.Background(), zap.NewNop(), func() error {
+		calls++
+		return errors.New("failed")
+	})
+	require.EqualError(t, err, "failed")
+	require.Equal(t, 3, calls)
+
+	// the message is processed after the sleep
+	calls = 0
+	err = r.Do(context.Background(), zap.NewNop(), func() error {
+		calls++
+		return ErrRetryLater
+	})
+	require.Equal(t, ErrRetryLater, err)
+	require.Equal(t, 1, calls)
+
+	// the consumer is stopped
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls = 0
+	r = newRetrier(&RetryConfig{MaxAttempts: 3, InitialBackoff: time.Minute}, clock.Real)
+	err = r.Do(ctx, zap.NewNop(), func() error {
+		calls++
+		return errors.New("failed")
+	})
+	require.EqualError(t, err, "failed")
+	require.Equal(t, 1, calls)
+}
+
+func TestRetryDoClock(t *testing.T) {
+
+	clk := mock.NewClock(time.Now())
+	r := newRetrier(&RetryConfig{MaxAttempts: 3, InitialBackoff: time.Minute}, clk)
+
+	calls := make(chan struct{}, 3)
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Do(context.Background(), zap.NewNop(), func() error {
+			calls <- struct{}{}
+			return errors.New("failed")
+		})
+	}()
+
+	<-calls
+	// the retry waits for the backoff
+	require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+	require.Len(t, calls, 0)
+
+	clk.Add(time.Minute)
+	<-calls
+
+	// the backoff is doubled
+	require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+	clk.Add(time.Minute)
+	require.Len(t, calls, 0)
+
+	clk.Add(time.Minute)
+	<-calls
+	require.EqualError(t, <-done, "failed")
+}
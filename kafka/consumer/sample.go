@@ -0,0 +1,203 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// _RedactedValue replaces the redacted data of the sample
+	_RedactedValue = "***"
+	// _SampleConcurrency is a default max count of the samples saved at the same time
+	_SampleConcurrency = 4
+	// _SampleTimeout is a max time of the saving of the sample
+	_SampleTimeout = 30 * time.Second
+)
+
+// IObjectStorage saves the objects by the keys (e.g. an adapter of S3)
+type IObjectStorage interface {
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// DirStorage saves the objects to the files of the directory
+type DirStorage string
+
+// Put writes the object to the file (the key is a relative path)
+func (d DirStorage) Put(_ context.Context, key string, data []byte) error {
+
+	name := filepath.Join(string(d), filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return errors.Wrap(err, "failed to create directory")
+	}
+
+	return errors.Wrap(ioutil.WriteFile(name, data, 0644), "failed to write object")
+}
+
+// A Sample is a copy of the message saved to the storage (the key and the value are base64 in JSON)
+type Sample struct {
+	Topic     string            `json:"topic"`
+	Partition int32             `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Timestamp time.Time         `json:"timestamp"`
+	Key       []byte            `json:"key"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Value     []byte            `json:"value"`
+}
+
+// FuncRedact removes the sensitive data of the sample
+type FuncRedact func(s *Sample)
+
+// RedactHeaders replaces the values of the headers
+func RedactHeaders(keys ...string) FuncRedact {
+	return func(s *Sample) {
+		for _, k := range keys {
+			if _, ok := s.Headers[k]; ok {
+				s.Headers[k] = _RedactedValue
+			}
+		}
+	}
+}
+
+// RedactValue replaces the value of the message (e.g. masks the fields of the document)
+func RedactValue(fn func(value []byte) []byte) FuncRedact {
+	return func(s *Sample) {
+		s.Value = fn(s.Value)
+	}
+}
+
+// SampleConfig is a configuration of the sampling
+type SampleConfig struct {
+	// Percent is a share of the sampled messages (0..100)
+	Percent float64
+	// Topics overrides the percent of the topics
+	Topics map[string]float64
+	// Storage saves the samples as '<prefix>/<topic>/<partition>/<offset>.json'
+	Storage IObjectStorage
+	Prefix  string
+	Redact  []FuncRedact
+	// Concurrency is a max count of the samples saved at the same time (4 by default)
+	Concurrency int
+}
+
+// SampleTo saves the share of the messages to the storage in the background (see Config.Transformers)
+func SampleTo(cfg SampleConfig) FuncTransform {
+
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = _SampleConcurrency
+	}
+	busy := make(chan struct{}, concurrency)
+
+	return func(_ context.Context, logger *zap.Logger, msg *kafka.Message) (*kafka.Message, error) {
+
+		tp := msg.TopicPartition
+
+		var topic string
+		if tp.Topic != nil {
+			topic = *tp.Topic
+		}
+
+		percent, ok := cfg.Topics[topic]
+		if !ok {
+			percent = cfg.Percent
+		}
+
+		if !sampled(tp, percent) {
+			return msg, nil
+		}
+
+		s := newSample(msg)
+		for _, fn := range cfg.Redact {
+			fn(s)
+		}
+
+		opLog := logger.With(
+			zap.String("topic", topic),
+			zap.Int32("partition", tp.Partition),
+			zap.String("offset", tp.Offset.String()))
+
+		data, err := json.Marshal(s)
+		if err != nil {
+			opLog.Warn("failed to save sample", zap.Error(err))
+			return msg, nil
+		}
+
+		key := fmt.Sprintf("%s/%d/%d.json", s.Topic, s.Partition, s.Offset)
+		if cfg.Prefix != "" {
+			key = cfg.Prefix + "/" + key
+		}
+
+		select {
+		case busy <- struct{}{}:
+		default:
+			opLog.Warn("sample is dropped: storage is busy")
+			return msg, nil
+		}
+
+		go func() {
+			defer func() { <-busy }()
+
+			// the context of the message is done after the processing
+			ctx, cancel := context.WithTimeout(context.Background(), _SampleTimeout)
+			defer cancel()
+
+			if err := cfg.Storage.Put(ctx, key, data); err != nil {
+				opLog.Warn("failed to save sample", zap.Error(err))
+			}
+		}()
+
+		return msg, nil
+	}
+}
+
+func sampled(tp kafka.TopicPartition, percent float64) bool {
+
+	if percent <= 0 {
+		return false
+	}
+	if percent >= 100 {
+		return true
+	}
+
+	h := fnv.New64a()
+	if tp.Topic != nil {
+		h.Write([]byte(*tp.Topic))
+	}
+	fmt.Fprintf(h, "/%d/%d", tp.Partition, tp.Offset)
+
+	return float64(h.Sum64()%10000) < percent*100
+}
+
+func newSample(msg *kafka.Message) *Sample {
+
+	s := &Sample{
+		Partition: msg.TopicPartition.Partition,
+		Offset:    int64(msg.TopicPartition.Offset),
+		Timestamp: msg.Timestamp,
+		Key:       append([]byte(nil), msg.Key...),
+		Value:     append([]byte(nil), msg.Value...),
+	}
+
+	if msg.TopicPartition.Topic != nil {
+		s.Topic = *msg.TopicPartition.Topic
+	}
+
+	if len(msg.Headers) > 0 {
+		s.Headers = make(map[string]string, len(msg.Headers))
+		for _, h := range msg.Headers {
+			s.Headers[h.Key] = string(h.Value)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,171 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	err     error
+}
+
+func (s *testStorage) get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.objects[key]
+	return data, ok
+}
+
+func (s *testStorage) Put(_ context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	if s.objects == nil {
+		s.objects = make(map[string][]byte)
+	}
+	s.objects[key] = data
+
+	return nil
+}
+
+func TestSampleTo(t *testing.T) {
+
+	storage := &testStorage{}
+	fn := SampleTo(SampleConfig{
+		Percent: 100,
+		Topics:  map[string]float64{"skip": 0},
+		Storage: storage,
+		Prefix:  "samples",
+		Redact: []FuncRedact{
+			RedactHeaders("token"),
+			RedactValue(func([]byte) []byte { return []byte("{}") }),
+		},
+	})
+
+	topic := "a"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 10},
+		Key:            []byte("k"),
+		Value:          []byte(`{"name":"x"}`),
+		Headers:        []kafka.Header{{Key: "token", Value: []byte("secret")}, {Key: "h", Value: []byte("1")}},
+	}
+
+	res, err := fn(context.Background(), zap.NewNop(), msg)
+	require.NoError(t, err)
+	require.Equal(t, msg, res)
+	require.Equal(t, `{"name":"x"}`, string(msg.Value))
+
+	// the sample is saved in the background
+	var data []byte
+	require.Eventually(t, func() bool {
+		var ok bool
+		data, ok = storage.get("samples/a/1/10.json")
+		return ok
+	}, time.Second, time.Millisecond)
+
+	var s Sample
+	require.NoError(t, json.Unmarshal(data, &s))
+	require.Equal(t,
+		Sample{
+			Topic:     "a",
+			Partition: 1,
+			Offset:    10,
+			Timestamp: s.Timestamp,
+			Key:       []byte("k"),
+			Headers:   map[string]string{"token": "***", "h": "1"},
+			Value:     []byte("{}"),
+		},
+		s)
+
+	skip := "skip"
+	msg.TopicPartition.Topic = &skip
+	_, err = fn(context.Background(), zap.NewNop(), msg)
+	require.NoError(t, err)
+	_, ok := storage.get("samples/skip/1/10.json")
+	require.False(t, ok)
+
+	// storage errors don't stop processing
+	storage.err = errors.New("storage error")
+	msg.TopicPartition.Topic = &topic
+	res, err = fn(context.Background(), zap.NewNop(), msg)
+	require.NoError(t, err)
+	require.Equal(t, msg, res)
+}
+
+type blockingStorage struct {
+	release chan struct{}
+	calls   chan string
+}
+
+func (s *blockingStorage) Put(_ context.Context, key string, _ []byte) error {
+	s.calls <- key
+	<-s.release
+	return nil
+}
+
+func TestSampleBusy(t *testing.T) {
+
+	storage := &blockingStorage{release: make(chan struct{}), calls: make(chan string, 10)}
+	fn := SampleTo(SampleConfig{Percent: 100, Storage: storage, Concurrency: 1})
+
+	topic := "a"
+	for i := 0; i < 3; i++ {
+		msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(i)}}
+
+		// the processing doesn't wait for the storage
+		_, err := fn(context.Background(), zap.NewNop(), msg)
+		require.NoError(t, err)
+	}
+
+	// the samples are dropped while the storage is busy
+	require.Equal(t, "a/0/0.json", <-storage.calls)
+	close(storage.release)
+	require.Empty(t, storage.calls)
+}
+
+func TestSampleRate(t *testing.T) {
+
+	topic := "a"
+	var count int
+	for i := 0; i < 10000; i++ {
+		if sampled(kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(i)}, 10) {
+			count++
+		}
+	}
+	require.InDelta(t, 1000, count, 150)
+
+	tp := kafka.TopicPartition{Topic: &topic, Offset: 1}
+	require.Equal(t, sampled(tp, 50), sampled(tp, 50))
+	require.False(t, sampled(tp, 0))
+	require.True(t, sampled(tp, 100))
+}
+
+func TestDirStorage(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "samples")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, DirStorage(dir).Put(context.Background(), "a/1/10.json", []byte("{}")))
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "a", "1", "10.json"))
+	require.NoError(t, err)
+	require.Equal(t, "{}", string(data))
+}
@@ -0,0 +1,146 @@
+package consumer
+
+import (
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type seekReader interface {
+	Assignment() ([]kafka.TopicPartition, error)
+	OffsetsForTimes(times []kafka.TopicPartition, timeoutMs int) ([]kafka.TopicPartition, error)
+	Seek(partition kafka.TopicPartition, timeoutMs int) error
+}
+
+// Seek moves the assigned partition to the offset (the seek is applied by the event loop)
+func (c *Consumer) Seek(topic string, partition int32, offset kafka.Offset) error {
+	return c.seek([]kafka.TopicPartition{{Topic: &topic, Partition: partition, Offset: offset}})
+}
+
+// SeekToTime moves all assigned partitions to the first messages after the time
+func (c *Consumer) SeekToTime(t time.Time) error {
+
+	partitions, err := offsetsForTime(c.reader, t)
+	if err != nil {
+		return err
+	}
+
+	return c.seek(partitions)
+}
+
+func offsetsForTime(r seekReader, t time.Time) ([]kafka.TopicPartition, error) {
+
+	assignment, err := r.Assignment()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get assignment")
+	}
+	if len(assignment) == 0 {
+		return nil, nil
+	}
+
+	ts := kafka.Offset(t.UnixNano() / int64(time.Millisecond))
+	times := make([]kafka.TopicPartition, len(assignment))
+	for i := range assignment {
+		times[i] = kafka.TopicPartition{Topic: assignment[i].Topic, Partition: assignment[i].Partition, Offset: ts}
+	}
+
+	offsets, err := r.OffsetsForTimes(times, 5000)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get offsets for time")
+	}
+
+	for i := range offsets {
+		if offsets[i].Offset < 0 {
+			offsets[i].Offset = kafka.OffsetEnd
+		}
+	}
+
+	return offsets, nil
+}
+
+// seek queues the partitions for the event loop without waiting for it
+func (c *Consumer) seek(partitions []kafka.TopicPartition) error {
+
+	if len(partitions) == 0 {
+		return nil
+	}
+
+	if c.ctx.Err() != nil {
+		return ErrAlreadyClosed
+	}
+
+	c.seeksMu.Lock()
+	c.seeks = append(c.seeks, partitions...)
+	c.seeksMu.Unlock()
+
+	req := func(consumerOffsets *offset) { c.applySeeks(c.reader, consumerOffsets) }
+	select {
+	case c.requests <- req:
+	default:
+		// the request is queued already, it applies all queued seeks
+	}
+
+	return nil
+}
+
+func (c *Consumer) applySeeks(r seekReader, consumerOffsets *offset) {
+
+	c.seeksMu.Lock()
+	partitions := c.seeks
+	c.seeks = nil
+	c.seeksMu.Unlock()
+
+	if len(partitions) == 0 {
+		return
+	}
+
+	if err := c.seekPartitions(r, partitions, consumerOffsets); err != nil {
+		c.onError(c.ctx, c.logger.With(zap.String("operation", "seek")), err)
+	}
+}
+
+func (c *Consumer) seekPartitions(r seekReader, partitions []kafka.TopicPartition, consumerOffsets *offset) error {
+
+	opLog := c.logger.With(zap.String("operation", "seek"), zap.Any("partitions", partitions))
+
+	if err := c.flushBatch(consumerOffsets); err != nil {
+		return err
+	}
+
+	if c.pool != nil {
+		c.pool.Drain()
+		if err := c.pool.Err(); err != nil {
+			return err
+		}
+		c.pool.Reset(partitions)
+	}
+
+	for _, tp := range partitions {
+		consumerOffsets.Remove(tp)
+		if c.marked != nil {
+			c.marked.Remove(tp)
+		}
+
+		if err := r.Seek(tp, 5000); err != nil {
+			opLog.Error("failed to seek", zap.Error(err))
+			return errors.Wrapf(err, "failed to seek %s[%d] to %s", stringValue(tp.Topic), tp.Partition, tp.Offset)
+		}
+	}
+
+	opLog.Info("success")
+
+	return nil
+}
+
+func (c *Consumer) retryLater(opLog *zap.Logger, r seekReader, tp kafka.TopicPartition) error {
+
+	if err := r.Seek(tp, 5000); err != nil {
+		opLog.Error("failed to seek back", zap.Error(err))
+		return errors.Wrapf(err, "failed to seek %s[%d] to %s", stringValue(tp.Topic), tp.Partition, tp.Offset)
+	}
+
+	opLog.Debug("retry later")
+	return nil
+}
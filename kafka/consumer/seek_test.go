@@ -0,0 +1,142 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeSeekReader struct {
+	assignment []kafka.TopicPartition
+	times      []kafka.TopicPartition
+	seeks      []kafka.TopicPartition
+	err        error
+}
+
+func (r *fakeSeekReader) Assignment() ([]kafka.TopicPartition, error) {
+	return r.assignment, nil
+}
+
+func (r *fakeSeekReader) OffsetsForTimes(times []kafka.TopicPartition, _ int) ([]kafka.TopicPartition, error) {
+	r.times = times
+
+	retval := make([]kafka.TopicPartition, len(times))
+	copy(retval, times)
+	retval[0].Offset = 5
+	// no messages after the time
+	retval[1].Offset = -1
+
+	return retval, nil
+}
+
+func (r *fakeSeekReader) Seek(tp kafka.TopicPartition, _ int) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	r.seeks = append(r.seeks, tp)
+	return nil
+}
+
+func TestOffsetsForTime(t *testing.T) {
+
+	r := &fakeSeekReader{assignment: []kafka.TopicPartition{
+		{Topic: stringPointer("t1"), Partition: 0, Offset: 10},
+		{Topic: stringPointer("t1"), Partition: 1, Offset: 20},
+	}}
+
+	at := time.Unix(100, 0)
+	offsets, err := offsetsForTime(r, at)
+	require.NoError(t, err)
+
+	require.Equal(t, kafka.Offset(100000), r.times[0].Offset)
+	require.Equal(t, kafka.Offset(5), offsets[0].Offset)
+	require.Equal(t, kafka.OffsetEnd, offsets[1].Offset)
+
+	offsets, err = offsetsForTime(&fakeSeekReader{}, at)
+	require.NoError(t, err)
+	require.Empty(t, offsets)
+}
+
+func TestSeekPartitions(t *testing.T) {
+
+	c, _ := newTestConsumer(t)
+	r := &fakeSeekReader{}
+
+	consumerOffsets := newOffset()
+	consumerOffsets.Add(
+		kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 0, Offset: 10},
+		kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1, Offset: 20},
+	)
+
+	target := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 0, Offset: 3}
+	require.NoError(t, c.seekPartitions(r, []kafka.TopicPartition{target}, consumerOffsets))
+	require.Equal(t, []kafka.TopicPartition{target}, r.seeks)
+
+	// the offsets of the partition before the seek aren't committed
+	list, _ := consumerOffsets.Get()
+	require.Len(t, list, 1)
+	require.Equal(t, int32(1), list[0].Partition)
+
+	// the replayed messages are committed
+	consumerOffsets.Add(kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 0, Offset: 3})
+	list, _ = consumerOffsets.Get()
+	require.Len(t, list, 2)
+
+	// the partition without offsets
+	r.err = errors.New("not assigned")
+	target = kafka.TopicPartition{Topic: stringPointer("t2"), Partition: 0, Offset: 1}
+	require.EqualError(t, c.seekPartitions(r, []kafka.TopicPartition{target}, consumerOffsets),
+		"failed to seek t2[0] to 1: not assigned")
+}
+
+func TestRetryLater(t *testing.T) {
+
+	c, _ := newTestConsumer(t)
+	r := &fakeSeekReader{}
+
+	tp := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 0, Offset: 7}
+	require.NoError(t, c.retryLater(c.logger, r, tp))
+	require.Equal(t, []kafka.TopicPartition{tp}, r.seeks)
+
+	r.err = errors.New("failed")
+	require.EqualError(t, c.retryLater(c.logger, r, tp), "failed to seek t1[0] to 7: failed")
+}
+
+func TestSeekQueued(t *testing.T) {
+
+	var reported []error
+	c, _ := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.OnError = func(_ context.Context, _ *zap.Logger, err error) {
+			reported = append(reported, err)
+		}
+	}))
+
+	// the seeks don't wait for the event loop (e.g. the call from the handler)
+	require.NoError(t, c.Seek("t1", 0, 3))
+	require.NoError(t, c.Seek("t1", 1, 5))
+	require.Len(t, c.requests, 1)
+
+	r := &fakeSeekReader{}
+	c.applySeeks(r, newOffset())
+	require.Equal(t, []kafka.TopicPartition{
+		{Topic: stringPointer("t1"), Partition: 0, Offset: 3},
+		{Topic: stringPointer("t1"), Partition: 1, Offset: 5},
+	}, r.seeks)
+	require.Empty(t, reported)
+
+	// the error of the seek is reported
+	require.NoError(t, c.Seek("t1", 0, 1))
+	r.err = errors.New("not assigned")
+	c.applySeeks(r, newOffset())
+	require.Len(t, reported, 1)
+	require.EqualError(t, reported[0], "failed to seek t1[0] to 1: not assigned")
+
+	c.ctxCancel()
+	require.Equal(t, ErrAlreadyClosed, c.Seek("t1", 0, 1))
+}
@@ -0,0 +1,159 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// FuncSleepCondition reports that paused partitions can be resumed
+type FuncSleepCondition func(ctx context.Context) bool
+
+// A SleepingPartition is a partition paused by a sleep or by Pause
+type SleepingPartition struct {
+	Topic     string
+	Partition int32
+	// Until is the end of the sleep (zero for the pauses and the sleeps by the condition)
+	Until time.Time
+}
+
+type sleepEntry struct {
+	cancel context.CancelFunc
+	until  time.Time
+	count  int
+}
+
+func (e *sleepEntry) outlives(other *sleepEntry) bool {
+	return !e.until.IsZero() && !other.until.IsZero() && e.until.After(other.until)
+}
+
+type sleepItem struct {
+	entry     *sleepEntry
+	partition kafka.TopicPartition
+}
+
+type sleeps struct {
+	partitions map[string]sleepItem
+	mu         sync.Mutex
+}
+
+func newSleeps() *sleeps {
+	return &sleeps{
+		partitions: make(map[string]sleepItem),
+	}
+}
+
+// Add registers the partitions as paused by the entry unless the previous entry ends later
+func (s *sleeps) Add(entry *sleepEntry, in ...kafka.TopicPartition) (retval []kafka.TopicPartition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range in {
+		key := getPartitionKey(in[i].Topic, in[i].Partition)
+		prev, ok := s.partitions[key]
+		if ok && prev.entry != entry {
+			if prev.entry.outlives(entry) {
+				continue
+			}
+			s.release(prev.entry)
+		}
+
+		if prev.entry != entry {
+			entry.count++
+		}
+		s.partitions[key] = sleepItem{
+			entry:     entry,
+			partition: kafka.TopicPartition{Topic: in[i].Topic, Partition: in[i].Partition},
+		}
+		retval = append(retval, in[i])
+	}
+
+	return
+}
+
+// Remove unregisters the partitions paused by the entry and returns the ones to resume
+func (s *sleeps) Remove(entry *sleepEntry, in ...kafka.TopicPartition) (retval []kafka.TopicPartition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range in {
+		key := getPartitionKey(in[i].Topic, in[i].Partition)
+		if s.partitions[key].entry == entry {
+			delete(s.partitions, key)
+			s.release(entry)
+			retval = append(retval, in[i])
+		}
+	}
+
+	return
+}
+
+// Cancel unregisters the partitions and returns the ones to resume
+func (s *sleeps) Cancel(in ...kafka.TopicPartition) (retval []kafka.TopicPartition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range in {
+		key := getPartitionKey(in[i].Topic, in[i].Partition)
+		if item, ok := s.partitions[key]; ok {
+			delete(s.partitions, key)
+			s.release(item.entry)
+			retval = append(retval, in[i])
+		}
+	}
+
+	return
+}
+
+// Contains checks that the partition is paused
+func (s *sleeps) Contains(in kafka.TopicPartition) (ok bool) {
+	s.mu.Lock()
+	_, ok = s.partitions[getPartitionKey(in.Topic, in.Partition)]
+	s.mu.Unlock()
+
+	return
+}
+
+// List returns the paused partitions
+func (s *sleeps) List() []kafka.TopicPartition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	retval := make([]kafka.TopicPartition, 0, len(s.partitions))
+	for _, item := range s.partitions {
+		retval = append(retval, item.partition)
+	}
+
+	return retval
+}
+
+// Sleeping returns the paused partitions with the ends of the sleeps
+func (s *sleeps) Sleeping() []SleepingPartition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	retval := make([]SleepingPartition, 0, len(s.partitions))
+	for _, item := range s.partitions {
+		var topic string
+		if item.partition.Topic != nil {
+			topic = *item.partition.Topic
+		}
+
+		retval = append(retval, SleepingPartition{
+			Topic:     topic,
+			Partition: item.partition.Partition,
+			Until:     item.entry.until,
+		})
+	}
+
+	return retval
+}
+
+func (s *sleeps) release(entry *sleepEntry) {
+	entry.count--
+	if entry.count <= 0 && entry.cancel != nil {
+		entry.cancel()
+	}
+}
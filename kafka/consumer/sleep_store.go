@@ -0,0 +1,298 @@
+package consumer
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+)
+
+const (
+	_SleepStoreGroupID      = "sleep-store"
+	_SleepStoreReadTimeout  = 100 * time.Millisecond
+	_SleepStoreQueryTimeout = 10 * time.Second
+)
+
+// A SleepState is a pause intent of the partition
+type SleepState struct {
+	Partition kafka.TopicPartition
+	Until     time.Time
+}
+
+// ISleepStore keeps the pause intents between the rebalances
+type ISleepStore interface {
+	// Save stores the pause intent of the partitions
+	Save(ctx context.Context, until time.Time, partitions []kafka.TopicPartition) error
+	// Load returns the pause intents of the partitions
+	Load(ctx context.Context, partitions []kafka.TopicPartition) ([]SleepState, error)
+	// Delete removes the pause intents of the partitions
+	Delete(ctx context.Context, partitions []kafka.TopicPartition) error
+}
+
+// MemorySleepStore keeps the pause intents in memory (see TopicSleepStore)
+type MemorySleepStore struct {
+	items map[string]SleepState
+	mu    sync.RWMutex
+}
+
+func NewMemorySleepStore() *MemorySleepStore {
+	return &MemorySleepStore{
+		items: make(map[string]SleepState),
+	}
+}
+
+func (m *MemorySleepStore) Save(_ context.Context, until time.Time, partitions []kafka.TopicPartition) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range partitions {
+		item := &partitions[i]
+		m.items[getPartitionKey(item.Topic, item.Partition)] = SleepState{
+			Partition: kafka.TopicPartition{Topic: item.Topic, Partition: item.Partition},
+			Until:     until,
+		}
+	}
+
+	return nil
+}
+
+func (m *MemorySleepStore) Load(_ context.Context, partitions []kafka.TopicPartition) (retval []SleepState, _ error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i := range partitions {
+		item := &partitions[i]
+		if state, ok := m.items[getPartitionKey(item.Topic, item.Partition)]; ok {
+			retval = append(retval, state)
+		}
+	}
+
+	return retval, nil
+}
+
+func (m *MemorySleepStore) Delete(_ context.Context, partitions []kafka.TopicPartition) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range partitions {
+		item := &partitions[i]
+		delete(m.items, getPartitionKey(item.Topic, item.Partition))
+	}
+
+	return nil
+}
+
+// ISleepStoreProducer produces the pause intents and waits for the delivery reports (see producer.SyncProducer)
+type ISleepStoreProducer interface {
+	Produce(ctx context.Context, msg *kafka.Message) error
+}
+
+// TopicSleepStoreConfig is a configuration of TopicSleepStore
+type TopicSleepStoreConfig struct {
+	// Topic keeps the pause intents, it should be compacted ('cleanup.policy=compact')
+	Topic string
+	// Namespace separates the pause intents of the groups sharing the topic (e.g. the group id)
+	Namespace string
+	// ConfigMap is a configuration of the reader of the topic (servers, security, etc.)
+	ConfigMap *kafka.ConfigMap
+	Producer  ISleepStoreProducer
+}
+
+type sleepStoreReader interface {
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error)
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error)
+	Assign(partitions []kafka.TopicPartition) error
+	Poll(timeoutMs int) kafka.Event
+}
+
+// TopicSleepStore keeps the pause intents in the compacted topic to survive the restarts and the reassignment
+type TopicSleepStore struct {
+	cfg       TopicSleepStoreConfig
+	newReader func() (sleepStoreReader, func(), error)
+}
+
+// NewTopicSleepStore returns the store in the topic
+func NewTopicSleepStore(cfg TopicSleepStoreConfig) (*TopicSleepStore, error) {
+
+	if cfg.Topic == "" {
+		return nil, configError("sleep store topic is empty")
+	}
+	if cfg.ConfigMap == nil {
+		return nil, configError("sleep store reader config is nil")
+	}
+	if cfg.Producer == nil {
+		return nil, configError("sleep store producer is nil")
+	}
+
+	s := &TopicSleepStore{cfg: cfg}
+	s.newReader = s.createReader
+
+	return s, nil
+}
+
+func (s *TopicSleepStore) Save(ctx context.Context, until time.Time, partitions []kafka.TopicPartition) error {
+	value := []byte(strconv.FormatInt(timeMs(until), 10))
+	return s.produce(ctx, value, partitions)
+}
+
+func (s *TopicSleepStore) Load(ctx context.Context, partitions []kafka.TopicPartition) ([]SleepState, error) {
+
+	reader, closeReader, err := s.newReader()
+	if err != nil {
+		return nil, err
+	}
+	defer closeReader()
+
+	intents, err := s.read(ctx, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var retval []SleepState
+	for i := range partitions {
+		item := &partitions[i]
+		if until, ok := intents[s.key(item)]; ok {
+			retval = append(retval, SleepState{
+				Partition: kafka.TopicPartition{Topic: item.Topic, Partition: item.Partition},
+				Until:     until,
+			})
+		}
+	}
+
+	return retval, nil
+}
+
+func (s *TopicSleepStore) Delete(ctx context.Context, partitions []kafka.TopicPartition) error {
+	return s.produce(ctx, nil, partitions)
+}
+
+func (s *TopicSleepStore) produce(ctx context.Context, value []byte, partitions []kafka.TopicPartition) error {
+
+	for i := range partitions {
+		err := s.cfg.Producer.Produce(ctx, &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &s.cfg.Topic, Partition: kafka.PartitionAny},
+			Key:            []byte(s.key(&partitions[i])),
+			Value:          value,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to produce sleep state to %s", s.cfg.Topic)
+		}
+	}
+
+	return nil
+}
+
+func (s *TopicSleepStore) read(ctx context.Context, r sleepStoreReader) (map[string]time.Time, error) {
+
+	const timeoutMs = int(_SleepStoreQueryTimeout / time.Millisecond)
+
+	topic := s.cfg.Topic
+
+	md, err := r.GetMetadata(&topic, false, timeoutMs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get metadata")
+	}
+
+	tm, ok := md.Topics[topic]
+	if !ok || tm.Error.Code() != kafka.ErrNoError {
+		return nil, errors.Errorf("topic %s not found", topic)
+	}
+
+	end := make(map[int32]int64)
+	assignment := make([]kafka.TopicPartition, 0, len(tm.Partitions))
+	for _, p := range tm.Partitions {
+		low, high, err := r.QueryWatermarkOffsets(topic, p.ID, timeoutMs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to query watermark offsets of partition %d", p.ID)
+		}
+
+		if high > low {
+			end[p.ID] = high
+			assignment = append(assignment, kafka.TopicPartition{Topic: &topic, Partition: p.ID, Offset: kafka.OffsetBeginning})
+		}
+	}
+
+	intents := make(map[string]time.Time)
+	if len(assignment) == 0 {
+		return intents, nil
+	}
+
+	if err := r.Assign(assignment); err != nil {
+		return nil, errors.Wrap(err, "failed to assign partitions")
+	}
+
+	for len(end) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		switch e := r.Poll(int(_SleepStoreReadTimeout / time.Millisecond)).(type) {
+		case *kafka.Message:
+			if e.TopicPartition.Error != nil {
+				return nil, errors.Wrap(e.TopicPartition.Error, "failed to read sleep state")
+			}
+
+			applySleepIntent(intents, e)
+			if last, ok := end[e.TopicPartition.Partition]; ok && int64(e.TopicPartition.Offset) >= last-1 {
+				delete(end, e.TopicPartition.Partition)
+			}
+		case kafka.PartitionEOF:
+			// the last offsets can be removed by the compaction
+			if last, ok := end[e.Partition]; ok && int64(e.Offset) >= last {
+				delete(end, e.Partition)
+			}
+		case kafka.Error:
+			if e.Code() != kafka.ErrTimedOut {
+				return nil, errors.Wrap(e, "failed to read sleep state")
+			}
+		}
+	}
+
+	return intents, nil
+}
+
+func (s *TopicSleepStore) key(tp *kafka.TopicPartition) string {
+	return s.cfg.Namespace + "/" + stringValue(tp.Topic) + "/" + strconv.Itoa(int(tp.Partition))
+}
+
+func (s *TopicSleepStore) createReader() (sleepStoreReader, func(), error) {
+
+	configMap := kafka.ConfigMap{
+		"group.id": _SleepStoreGroupID,
+	}
+	for k, v := range *s.cfg.ConfigMap {
+		configMap[k] = v
+	}
+	configMap["enable.auto.commit"] = false
+	configMap["enable.auto.offset.store"] = false
+	configMap["go.events.channel.enable"] = false
+	configMap["enable.partition.eof"] = true
+
+	reader, err := kafka.NewConsumer(&configMap)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "create reader failed")
+	}
+
+	return reader, func() { _ = reader.Close() }, nil
+}
+
+func applySleepIntent(intents map[string]time.Time, msg *kafka.Message) {
+
+	key := string(msg.Key)
+	if msg.Value == nil {
+		delete(intents, key)
+		return
+	}
+
+	ms, err := strconv.ParseInt(string(msg.Value), 10, 64)
+	if err != nil {
+		// the invalid record isn't restored
+		delete(intents, key)
+		return
+	}
+
+	intents[key] = time.Unix(0, ms*int64(time.Millisecond))
+}
@@ -0,0 +1,136 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemorySleepStore(t *testing.T) {
+
+	ctx := context.Background()
+	s := NewMemorySleepStore()
+
+	p1 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1, Offset: 10}
+	p2 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 2}
+	until := time.Now().Add(time.Minute)
+
+	list, err := s.Load(ctx, []kafka.TopicPartition{p1, p2})
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	require.NoError(t, s.Save(ctx, until, []kafka.TopicPartition{p1}))
+
+	list, err = s.Load(ctx, []kafka.TopicPartition{p1, p2})
+	require.NoError(t, err)
+	require.Equal(t,
+		[]SleepState{{Partition: kafka.TopicPartition{Topic: p1.Topic, Partition: 1}, Until: until}},
+		list)
+
+	require.NoError(t, s.Delete(ctx, []kafka.TopicPartition{p1, p2}))
+
+	list, err = s.Load(ctx, []kafka.TopicPartition{p1, p2})
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+// testSleepLog is the topic of the sleep store: the produced records are read by the reader
+type testSleepLog struct {
+	topic   string
+	records []*kafka.Message
+	queue   []kafka.Event
+}
+
+func (l *testSleepLog) Produce(_ context.Context, msg *kafka.Message) error {
+	l.records = append(l.records, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &l.topic, Offset: kafka.Offset(len(l.records))},
+		Key:            msg.Key,
+		Value:          msg.Value,
+	})
+	return nil
+}
+
+func (l *testSleepLog) GetMetadata(*string, bool, int) (*kafka.Metadata, error) {
+	return &kafka.Metadata{Topics: map[string]kafka.TopicMetadata{
+		l.topic: {Topic: l.topic, Partitions: []kafka.PartitionMetadata{{ID: 0}}},
+	}}, nil
+}
+
+func (l *testSleepLog) QueryWatermarkOffsets(string, int32, int) (int64, int64, error) {
+	return 0, int64(len(l.records)), nil
+}
+
+func (l *testSleepLog) Assign([]kafka.TopicPartition) error {
+	l.queue = nil
+	for _, msg := range l.records {
+		l.queue = append(l.queue, msg)
+	}
+	return nil
+}
+
+func (l *testSleepLog) Poll(int) kafka.Event {
+	if len(l.queue) == 0 {
+		return kafka.PartitionEOF{Topic: &l.topic, Offset: kafka.Offset(len(l.records))}
+	}
+
+	ev := l.queue[0]
+	l.queue = l.queue[1:]
+	return ev
+}
+
+func TestTopicSleepStore(t *testing.T) {
+
+	log := &testSleepLog{topic: "sleeps"}
+
+	_, err := NewTopicSleepStore(TopicSleepStoreConfig{Topic: "sleeps", ConfigMap: &kafka.ConfigMap{}})
+	require.True(t, errors.Is(err, ErrInvalidConfig))
+
+	newStore := func(namespace string) *TopicSleepStore {
+		s, err := NewTopicSleepStore(TopicSleepStoreConfig{
+			Topic:     "sleeps",
+			Namespace: namespace,
+			ConfigMap: &kafka.ConfigMap{},
+			Producer:  log,
+		})
+		require.NoError(t, err)
+		s.newReader = func() (sleepStoreReader, func(), error) { return log, func() {}, nil }
+		return s
+	}
+
+	ctx := context.Background()
+	s := newStore("g1")
+
+	p1 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1, Offset: 10}
+	p2 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 2}
+	until := time.Unix(100, 0)
+
+	list, err := s.Load(ctx, []kafka.TopicPartition{p1, p2})
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	require.NoError(t, s.Save(ctx, time.Unix(50, 0), []kafka.TopicPartition{p1}))
+	require.NoError(t, s.Save(ctx, until, []kafka.TopicPartition{p1}))
+
+	// the intent is read by another instance, the intents of the other groups are separated
+	list, err = newStore("g1").Load(ctx, []kafka.TopicPartition{p1, p2})
+	require.NoError(t, err)
+	require.Equal(t,
+		[]SleepState{{Partition: kafka.TopicPartition{Topic: p1.Topic, Partition: 1}, Until: until}},
+		list)
+
+	list, err = newStore("g2").Load(ctx, []kafka.TopicPartition{p1, p2})
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	// the tombstone removes the intent
+	require.NoError(t, s.Delete(ctx, []kafka.TopicPartition{p1, p2}))
+	require.Nil(t, log.records[len(log.records)-1].Value)
+
+	list, err = s.Load(ctx, []kafka.TopicPartition{p1, p2})
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
@@ -0,0 +1,170 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSleepsAddRemove(t *testing.T) {
+
+	s := newSleeps()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := &sleepEntry{cancel: cancel}
+
+	p1 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1}
+	p2 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 2}
+
+	s.Add(entry, p1, p2)
+	require.Equal(t, 2, entry.count)
+	require.True(t, s.Contains(p1))
+	require.True(t, s.Contains(p2))
+
+	require.Equal(t, []kafka.TopicPartition{p1}, s.Remove(entry, p1))
+	require.Nil(t, s.Remove(entry, p1))
+	require.NoError(t, ctx.Err())
+
+	require.Equal(t, []kafka.TopicPartition{p2}, s.Cancel(p2))
+	require.Error(t, ctx.Err())
+	require.False(t, s.Contains(p1))
+	require.False(t, s.Contains(p2))
+}
+
+func TestSleepsReplace(t *testing.T) {
+
+	s := newSleeps()
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	entry1 := &sleepEntry{cancel: cancel1}
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	entry2 := &sleepEntry{cancel: cancel2}
+
+	p := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1}
+
+	s.Add(entry1, p)
+	s.Add(entry2, p)
+	require.Error(t, ctx1.Err())
+	require.NoError(t, ctx2.Err())
+
+	// the first sleep must not resume the partition paused by the second one
+	require.Nil(t, s.Remove(entry1, p))
+	require.True(t, s.Contains(p))
+
+	require.Equal(t, []kafka.TopicPartition{p}, s.Remove(entry2, p))
+	require.Error(t, ctx2.Err())
+}
+
+func TestSleepsOverlap(t *testing.T) {
+
+	s := newSleeps()
+	now := time.Now()
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	entry1 := &sleepEntry{cancel: cancel1, until: now.Add(time.Hour)}
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	entry2 := &sleepEntry{cancel: cancel2, until: now.Add(time.Minute)}
+
+	p1 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 1}
+	p2 := kafka.TopicPartition{Topic: stringPointer("t1"), Partition: 2}
+
+	require.Equal(t, []kafka.TopicPartition{p1}, s.Add(entry1, p1))
+
+	// the shorter sleep doesn't replace the longer one
+	require.Equal(t, []kafka.TopicPartition{p2}, s.Add(entry2, p1, p2))
+	require.NoError(t, ctx1.Err())
+	require.Equal(t, 1, entry2.count)
+
+	require.Nil(t, s.Remove(entry2, p1))
+	require.Equal(t, []kafka.TopicPartition{p2}, s.Remove(entry2, p2))
+	require.Error(t, ctx2.Err())
+	require.True(t, s.Contains(p1))
+
+	require.Equal(t, []SleepingPartition{{Topic: "t1", Partition: 1, Until: entry1.until}}, s.Sleeping())
+
+	// the longer sleep replaces the shorter one
+	ctx3, cancel3 := context.WithCancel(context.Background())
+	entry3 := &sleepEntry{cancel: cancel3, until: now.Add(2 * time.Hour)}
+	require.Equal(t, []kafka.TopicPartition{p1}, s.Add(entry3, p1))
+	require.Error(t, ctx1.Err())
+	require.NoError(t, ctx3.Err())
+	require.Equal(t, []SleepingPartition{{Topic: "t1", Partition: 1, Until: entry3.until}}, s.Sleeping())
+}
+
+func TestSleepContext(t *testing.T) {
+
+	c, _ := newTestConsumer(t)
+	defer c.closeReader()
+	defer c.ctxCancel()
+
+	topic := "a"
+	partitions := []kafka.TopicPartition{{Topic: &topic, Partition: 1}}
+
+	resume, err := c.SleepContext(context.Background(), time.Hour, partitions)
+	require.NoError(t, err)
+	require.True(t, c.sleeps.Contains(partitions[0]))
+
+	// the partitions are resumed before the end of the sleep
+	resume()
+	require.Eventually(t, func() bool { return !c.sleeps.Contains(partitions[0]) }, time.Second, time.Millisecond)
+
+	// the context ends the sleep
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = c.SleepContext(ctx, time.Hour, partitions)
+	require.NoError(t, err)
+	require.True(t, c.sleeps.Contains(partitions[0]))
+
+	cancel()
+	require.Eventually(t, func() bool { return !c.sleeps.Contains(partitions[0]) }, time.Second, time.Millisecond)
+}
+
+func TestSleepStopped(t *testing.T) {
+
+	c, _ := newTestConsumer(t)
+	defer c.closeReader()
+
+	topic := "a"
+	partitions := []kafka.TopicPartition{{Topic: &topic, Partition: 1}}
+
+	require.NoError(t, c.Sleep(time.Hour, partitions))
+	require.True(t, c.sleeps.Contains(partitions[0]))
+
+	// the sleep is finished by the stop without the resume of the partitions
+	c.ctxCancel()
+	time.Sleep(50 * time.Millisecond)
+	require.True(t, c.sleeps.Contains(partitions[0]))
+}
+
+func TestSleepOverlap(t *testing.T) {
+
+	c, _ := newTestConsumer(t)
+	defer c.closeReader()
+	defer c.ctxCancel()
+
+	topic := "a"
+	partitions := []kafka.TopicPartition{{Topic: &topic, Partition: 1}}
+
+	require.NoError(t, c.Sleep(time.Hour, partitions))
+
+	// the second sleep ends before the first one and doesn't resume the partition
+	resume, err := c.SleepContext(context.Background(), 10*time.Millisecond, partitions)
+	require.NoError(t, err)
+	defer resume()
+
+	time.Sleep(50 * time.Millisecond)
+	require.True(t, c.sleeps.Contains(partitions[0]))
+
+	list := c.SleepingPartitions()
+	require.Len(t, list, 1)
+	require.Equal(t, topic, list[0].Topic)
+	require.Equal(t, int32(1), list[0].Partition)
+	require.WithinDuration(t, time.Now().Add(time.Hour), list[0].Until, time.Minute)
+
+	require.NoError(t, c.CancelSleep(partitions))
+	require.Empty(t, c.SleepingPartitions())
+}
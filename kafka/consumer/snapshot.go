@@ -0,0 +1,182 @@
+package consumer
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const _HistorySize = 50
+
+// A CommitRecord is a commit of the offset of the partition
+type CommitRecord struct {
+	Time      time.Time `json:"time"`
+	Topic     string    `json:"topic"`
+	Partition int32     `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Count     int       `json:"count"`
+}
+
+// An ErrorRecord is an error passed to OnError
+type ErrorRecord struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+// A Snapshot is the state of the consumer for the diagnostics (e.g. the dashboard of the admin router)
+type Snapshot struct {
+	ConsumerID string    `json:"consumerId"`
+	Time       time.Time `json:"time"`
+	// Partitions are the assigned partitions with the lag
+	Partitions []PartitionLag   `json:"partitions"`
+	InFlight   []PartitionState `json:"inFlight"`
+	Paused     []PartitionLag   `json:"paused"`
+	SleepUntil *time.Time       `json:"sleepUntil,omitempty"`
+	Topics     []string         `json:"topics"`
+	Commits    []CommitRecord   `json:"commits"`
+	Errors     []ErrorRecord    `json:"errors"`
+}
+
+type history struct {
+	commits []CommitRecord
+	errors  []ErrorRecord
+	mu      sync.Mutex
+}
+
+func newHistory() *history {
+	return &history{}
+}
+
+func (h *history) wrapCommit(next FuncOnCommit) FuncOnCommit {
+
+	return func(ctx context.Context, logger *zap.Logger, topic string, partition int32, offset kafka.Offset, committed int) {
+		h.mu.Lock()
+		h.commits = appendRecent(h.commits, CommitRecord{
+			Time:      time.Now(),
+			Topic:     topic,
+			Partition: partition,
+			Offset:    int64(offset),
+			Count:     committed,
+		})
+		h.mu.Unlock()
+
+		next(ctx, logger, topic, partition, offset, committed)
+	}
+}
+
+func (h *history) wrapError(next FuncOnError) FuncOnError {
+
+	return func(ctx context.Context, logger *zap.Logger, err error) {
+		if err != nil {
+			h.mu.Lock()
+			h.errors = appendRecentError(h.errors, ErrorRecord{Time: time.Now(), Error: err.Error()})
+			h.mu.Unlock()
+		}
+
+		next(ctx, logger, err)
+	}
+}
+
+func (h *history) recent() ([]CommitRecord, []ErrorRecord) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	commits := make([]CommitRecord, len(h.commits))
+	for i := range h.commits {
+		commits[len(commits)-1-i] = h.commits[i]
+	}
+
+	errs := make([]ErrorRecord, len(h.errors))
+	for i := range h.errors {
+		errs[len(errs)-1-i] = h.errors[i]
+	}
+
+	return commits, errs
+}
+
+func appendRecent(list []CommitRecord, item CommitRecord) []CommitRecord {
+	if len(list) >= _HistorySize {
+		list = append(list[:0], list[1:]...)
+	}
+	return append(list, item)
+}
+
+func appendRecentError(list []ErrorRecord, item ErrorRecord) []ErrorRecord {
+	if len(list) >= _HistorySize {
+		list = append(list[:0], list[1:]...)
+	}
+	return append(list, item)
+}
+
+// Snapshot returns the state of the consumer: the assignment, the processing, the pauses and the recent events
+func (c *Consumer) Snapshot() (*Snapshot, error) {
+
+	hb, err := c.heartbeat()
+	if err != nil {
+		return nil, err
+	}
+
+	retval := &Snapshot{
+		ConsumerID: c.id,
+		Time:       hb.Time,
+		Partitions: hb.Partitions,
+		InFlight:   c.InFlight(),
+		Topics:     c.MatchedTopics(),
+	}
+
+	paused := make(map[string]struct{})
+	for _, tp := range c.PausedPartitions() {
+		paused[getPartitionKey(tp.Topic, tp.Partition)] = struct{}{}
+	}
+	for _, p := range hb.Partitions {
+		if _, ok := paused[getPartitionKey(&p.Topic, p.Partition)]; ok {
+			retval.Paused = append(retval.Paused, p)
+		}
+	}
+
+	if until, ok := c.SleepStatus(); ok {
+		retval.SleepUntil = &until
+	}
+
+	if c.history != nil {
+		retval.Commits, retval.Errors = c.history.recent()
+	}
+
+	return retval, nil
+}
+
+// Inspect returns the snapshot of the consumer (router.IConsumerInspector implementation)
+func (c *Consumer) Inspect() (interface{}, error) {
+	return c.Snapshot()
+}
+
+// Snapshot returns the snapshots of the workers
+func (g *Group) Snapshot() ([]*Snapshot, error) {
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var retval []*Snapshot
+	number := 0
+	for item := g.consumers.Front(); item != nil; item = item.Next() {
+		s, err := item.Value.(*Consumer).Snapshot()
+		if err != nil {
+			return nil, errors.Wrap(err, "worker "+strconv.Itoa(number))
+		}
+		retval = append(retval, s)
+		number++
+	}
+
+	return retval, nil
+}
+
+// Inspect returns the snapshots of the workers (router.IConsumerInspector implementation)
+func (g *Group) Inspect() (interface{}, error) {
+	return g.Snapshot()
+}
@@ -0,0 +1,49 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHistory(t *testing.T) {
+
+	h := newHistory()
+
+	var (
+		commits int
+		errs    []error
+	)
+
+	onCommit := h.wrapCommit(func(context.Context, *zap.Logger, string, int32, kafka.Offset, int) { commits++ })
+	onError := h.wrapError(func(_ context.Context, _ *zap.Logger, err error) { errs = append(errs, err) })
+
+	for i := 1; i <= _HistorySize+5; i++ {
+		onCommit(context.Background(), zap.NewNop(), "a", 1, kafka.Offset(i), 2)
+	}
+	onError(context.Background(), zap.NewNop(), errors.New("first"))
+	onError(context.Background(), zap.NewNop(), errors.New("second"))
+
+	// the callbacks are called
+	require.Equal(t, _HistorySize+5, commits)
+	require.Len(t, errs, 2)
+
+	list, errList := h.recent()
+
+	// the newest records are first, the oldest are dropped
+	require.Len(t, list, _HistorySize)
+	require.Equal(t, int64(_HistorySize+5), list[0].Offset)
+	require.Equal(t, int64(6), list[_HistorySize-1].Offset)
+	require.Equal(t, "a", list[0].Topic)
+	require.Equal(t, int32(1), list[0].Partition)
+	require.Equal(t, 2, list[0].Count)
+	require.False(t, list[0].Time.IsZero())
+
+	require.Len(t, errList, 2)
+	require.Equal(t, "second", errList[0].Error)
+	require.Equal(t, "first", errList[1].Error)
+}
@@ -55,3 +55,11 @@ func (o *observable) notify(e StateEvent) {
 		item.HandleStateEvent(e)
 	}
 }
+
+// FuncStateObserver is an adapter of the function to IStateObserver
+type FuncStateObserver func(StateEvent)
+
+// HandleStateEvent calls the function
+func (fn FuncStateObserver) HandleStateEvent(e StateEvent) {
+	fn(e)
+}
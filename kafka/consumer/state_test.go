@@ -81,3 +81,15 @@ func TestObserverNotify(t *testing.T) {
 		}
 	}
 }
+
+func TestFuncStateObserver(t *testing.T) {
+
+	var events []StateEvent
+
+	o := newObservable()
+	o.AddStateObserver(FuncStateObserver(func(e StateEvent) { events = append(events, e) }))
+	o.notify(StateRun)
+	o.notify(StateClosed)
+
+	require.Equal(t, []StateEvent{StateRun, StateClosed}, events)
+}
@@ -0,0 +1,130 @@
+package consumer
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// A CommitStrategy defines how the offsets of the processed messages are committed (see Config.CommitStrategy)
+type CommitStrategy int
+
+const (
+	// CommitSync commits the offsets of all partitions and waits for the result
+	CommitSync CommitStrategy = iota
+	// CommitAsync commits the offsets in the background without the waiting for the result:
+	// the failed offsets are committed again by the next commit. OnPreCommit and OnCommit are called
+	// in the background too, concurrently with OnProcess (they must be safe for the concurrent use),
+	// the commits aren't concurrent with each other. The rebalances, Commit and Stop wait for the result.
+	CommitAsync
+	// CommitPerPartition commits the offsets of the partition synchronously when CommitOffsetCount
+	// messages of the partition are processed, the other partitions are committed by CommitOffsetDuration
+	CommitPerPartition
+)
+
+func (s CommitStrategy) String() string {
+	switch s {
+	case CommitSync:
+		return "sync"
+	case CommitAsync:
+		return "async"
+	case CommitPerPartition:
+		return "per-partition"
+	}
+
+	return "unknown"
+}
+
+func (c *Consumer) commitProcessed(consumerOffsets *offset) {
+
+	if c.commitOffsetCount <= 0 {
+		return
+	}
+
+	switch c.commitStrategy {
+	case CommitPerPartition:
+		if list, count := consumerOffsets.Ready(c.commitOffsetCount); len(list) > 0 {
+			_ = c.commitList(consumerOffsets, list, count)
+		}
+
+	case CommitAsync:
+		if consumerOffsets.Counter() >= c.commitOffsetCount {
+			c.commitOffsetsAsync(consumerOffsets)
+		}
+
+	default:
+		if consumerOffsets.Counter() >= c.commitOffsetCount {
+			_ = c.commitOffsets(consumerOffsets)
+		}
+	}
+}
+
+func (c *Consumer) commitScheduled(consumerOffsets *offset) {
+
+	if c.commitStrategy == CommitAsync {
+		c.commitOffsetsAsync(consumerOffsets)
+		return
+	}
+
+	_ = c.commitOffsets(consumerOffsets)
+}
+
+// commitOffsetsAsync commits in the background, the offsets are kept if the previous commit is in progress
+func (c *Consumer) commitOffsetsAsync(consumerOffsets *offset) {
+
+	if c.asyncCommit != nil {
+		select {
+		case <-c.asyncCommit:
+		default:
+			return
+		}
+	}
+
+	if c.marked != nil {
+		consumerOffsets.Add(c.marked.Take()...)
+	}
+
+	list, count := consumerOffsets.Get()
+	if len(list) == 0 {
+		return
+	}
+
+	done := make(chan struct{})
+	c.asyncCommit = done
+
+	go func() {
+		defer close(done)
+
+		// the failed offsets are kept for the next commit
+		_ = c.commitList(consumerOffsets, list, count)
+	}()
+}
+
+func (c *Consumer) waitAsyncCommit() {
+	if c.asyncCommit != nil {
+		<-c.asyncCommit
+	}
+}
+
+// Ready returns the offsets of the partitions with 'min' stored offsets at least
+func (o *offset) Ready(min int) (retval []kafka.TopicPartition, count map[string]int) {
+
+	count = make(map[string]int)
+
+	o.mu.RLock()
+	for topic, partition := range o.topics {
+		for p, po := range partition {
+			if po.Count < min {
+				continue
+			}
+
+			retval = append(retval, kafka.TopicPartition{
+				Topic:     stringPointer(topic),
+				Partition: p,
+				Offset:    po.Offset,
+			})
+			count[getPartitionKey(&topic, p)] = po.Count
+		}
+	}
+	o.mu.RUnlock()
+
+	return
+}
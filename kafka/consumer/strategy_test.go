@@ -0,0 +1,127 @@
+package consumer
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// withFailedCommits stops the commits by OnPreCommit which records the offsets
+func withFailedCommits(strategy CommitStrategy, count int, commits *[][]kafka.TopicPartition, mu *sync.Mutex) Option {
+	return WithConfig(func(cfg *Config) {
+		cfg.CommitStrategy = strategy
+		cfg.CommitOffsetCount = count
+		cfg.OnPreCommit = func(_ context.Context, _ *zap.Logger, list []kafka.TopicPartition) error {
+			mu.Lock()
+			defer mu.Unlock()
+			sort.Slice(list, func(i, j int) bool { return list[i].Partition < list[j].Partition })
+			*commits = append(*commits, list)
+			return errors.New("not committed")
+		}
+	})
+}
+
+func TestCommitStrategyString(t *testing.T) {
+	require.Equal(t, "sync", CommitSync.String())
+	require.Equal(t, "async", CommitAsync.String())
+	require.Equal(t, "per-partition", CommitPerPartition.String())
+	require.Equal(t, "unknown", CommitStrategy(10).String())
+}
+
+func TestCommitPerPartition(t *testing.T) {
+
+	var (
+		commits [][]kafka.TopicPartition
+		mu      sync.Mutex
+	)
+
+	c, _ := newTestConsumer(t, withFailedCommits(CommitPerPartition, 2, &commits, &mu))
+
+	topic := "a"
+	offsets := newOffset()
+
+	offsets.Add(kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 1})
+	offsets.Add(kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 1})
+	c.commitProcessed(offsets)
+	require.Empty(t, commits)
+
+	// only the partition with the count of the processed messages is committed
+	offsets.Add(kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 2})
+	c.commitProcessed(offsets)
+	require.Equal(t, [][]kafka.TopicPartition{
+		{{Topic: &topic, Partition: 1, Offset: 2}},
+	}, commits)
+
+	// the scheduled commit commits all partitions
+	c.commitScheduled(offsets)
+	require.Len(t, commits, 2)
+	require.Equal(t, []kafka.TopicPartition{
+		{Topic: &topic, Partition: 0, Offset: 1},
+		{Topic: &topic, Partition: 1, Offset: 2},
+	}, commits[1])
+}
+
+func TestCommitAsync(t *testing.T) {
+
+	var (
+		commits [][]kafka.TopicPartition
+		mu      sync.Mutex
+	)
+
+	c, _ := newTestConsumer(t, withFailedCommits(CommitAsync, 2, &commits, &mu))
+
+	topic := "a"
+	offsets := newOffset()
+
+	offsets.Add(kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 1})
+	c.commitProcessed(offsets)
+	require.Nil(t, c.asyncCommit)
+
+	offsets.Add(kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 2})
+	c.commitProcessed(offsets)
+	require.NotNil(t, c.asyncCommit)
+
+	// the offsets are kept after the failed commit
+	c.waitAsyncCommit()
+	list, _ := offsets.Get()
+	require.Equal(t, []kafka.TopicPartition{{Topic: &topic, Partition: 0, Offset: 2}}, list)
+
+	// the synchronous commit waits for the commit in the background
+	offsets.Add(kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 5})
+	c.commitScheduled(offsets)
+	require.EqualError(t, c.commitOffsets(offsets), "not committed")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, commits, 3)
+	require.Equal(t, []kafka.TopicPartition{{Topic: &topic, Partition: 0, Offset: 2}}, commits[0])
+	require.Equal(t, commits[1], commits[2])
+	require.Equal(t, []kafka.TopicPartition{
+		{Topic: &topic, Partition: 0, Offset: 2},
+		{Topic: &topic, Partition: 1, Offset: 5},
+	}, commits[1])
+}
+
+func TestOffsetReady(t *testing.T) {
+
+	topic := "a"
+	o := newOffset()
+	o.Add(
+		kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 1},
+		kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 2},
+		kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 1},
+	)
+
+	list, count := o.Ready(2)
+	require.Equal(t, []kafka.TopicPartition{{Topic: &topic, Partition: 0, Offset: 2}}, list)
+	require.Equal(t, map[string]int{getPartitionKey(&topic, 0): 2}, count)
+
+	list, _ = o.Ready(1)
+	require.Len(t, list, 2)
+}
@@ -0,0 +1,266 @@
+package consumer
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// FuncOnTopicChange is called when the subscribed topic appears (exists is true) or disappears
+type FuncOnTopicChange func(ctx context.Context, logger *zap.Logger, topic string, exists bool)
+
+// FuncOnPartitionsChange is called when the partitions count of the subscribed topic is changed
+type FuncOnPartitionsChange func(ctx context.Context, logger *zap.Logger, topic string, old, new int)
+
+// PartitionsWatchConfig enables the checks of the partitions count of the subscribed topics
+type PartitionsWatchConfig struct {
+	// Interval of the checks
+	Interval time.Duration `mapstructure:"interval"`
+	// Resubscribe triggers the rebalance after the partitions are added (see Consumer.Resubscribe)
+	Resubscribe bool `mapstructure:"resubscribe"`
+}
+
+// TopicWaitConfig enables the waiting for the topics before the subscription
+type TopicWaitConfig struct {
+	// Timeout is a max duration of the waiting (0 - unlimited), ErrTopicsNotFound is returned after it
+	Timeout time.Duration `mapstructure:"timeout"`
+	// InitialBackoff is a delay before the first check retry (1s by default), it's doubled on every retry
+	InitialBackoff time.Duration `mapstructure:"initial-backoff"`
+	// MaxBackoff is a max delay between the checks (30s by default)
+	MaxBackoff time.Duration `mapstructure:"max-backoff"`
+	// WatchInterval enables the checks of the topics after the subscription (see Config.OnTopicChange)
+	WatchInterval time.Duration `mapstructure:"watch-interval"`
+}
+
+type metadataReader interface {
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error)
+}
+
+type topicWatcher struct {
+	reader             metadataReader
+	topics             []string
+	exists             map[string]bool
+	partitions         map[string]int
+	onChange           FuncOnTopicChange
+	onPartitionsChange FuncOnPartitionsChange
+	partitionsGauge    FuncTopicGauge
+	clock              clock.Clock
+	mu                 sync.Mutex
+}
+
+func newTopicWatcher(reader metadataReader, topics []string, onChange FuncOnTopicChange) *topicWatcher {
+
+	w := &topicWatcher{
+		reader:     reader,
+		exists:     make(map[string]bool),
+		partitions: make(map[string]int),
+		onChange:   onChange,
+		clock:      clock.Real,
+	}
+
+	for _, topic := range topics {
+		// the regular expressions are matched by the broker
+		if !isTopicPattern(topic) {
+			w.topics = append(w.topics, topic)
+		}
+	}
+
+	return w
+}
+
+func (w *topicWatcher) check(ctx context.Context, logger *zap.Logger) ([]string, error) {
+
+	if len(w.topics) == 0 {
+		return nil, nil
+	}
+
+	metadata, err := w.reader.GetMetadata(nil, true, 5000)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get metadata")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var missing []string
+	for _, topic := range w.topics {
+		count := topicPartitions(metadata, topic)
+		exists := count > 0
+		if !exists {
+			missing = append(missing, topic)
+		}
+
+		prev, ok := w.exists[topic]
+		w.exists[topic] = exists
+		if ok && prev != exists && w.onChange != nil {
+			w.onChange(ctx, logger, topic, exists)
+		}
+
+		if !exists {
+			continue
+		}
+
+		if w.partitionsGauge != nil {
+			w.partitionsGauge(topic).Set(float64(count))
+		}
+
+		prevCount := w.partitions[topic]
+		w.partitions[topic] = count
+		if prevCount > 0 && prevCount != count && w.onPartitionsChange != nil {
+			w.onPartitionsChange(ctx, logger, topic, prevCount, count)
+		}
+	}
+
+	sort.Strings(missing)
+	return missing, nil
+}
+
+func topicPartitions(metadata *kafka.Metadata, topic string) int {
+	if metadata == nil {
+		return 0
+	}
+
+	t, ok := metadata.Topics[topic]
+	if !ok || t.Error.Code() != kafka.ErrNoError {
+		return 0
+	}
+
+	return len(t.Partitions)
+}
+
+func (w *topicWatcher) wait(ctx context.Context, logger *zap.Logger, cfg *TopicWaitConfig) error {
+
+	initialBackoff := cfg.InitialBackoff
+	if initialBackoff <= 0 {
+		initialBackoff = time.Second
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = time.Second * 30
+	}
+
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = clock.WithTimeout(ctx, w.clock, cfg.Timeout)
+		defer cancel()
+	}
+
+	delay := initialBackoff
+	for {
+		missing, err := w.check(ctx, logger)
+		if err == nil && len(missing) == 0 {
+			return nil
+		}
+
+		if err != nil {
+			logger.Warn("failed to check topics", zap.Error(err))
+		} else {
+			logger.Warn("wait for topics", zap.Strings("missing", missing), zap.Duration("delay", delay))
+		}
+
+		timer := w.clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if err != nil {
+				return errors.Wrap(err, "failed to wait for topics")
+			}
+			return errors.Wrap(ErrTopicsNotFound, strings.Join(missing, ","))
+		case <-timer.C():
+		}
+
+		delay *= 2
+		if delay > maxBackoff {
+			delay = maxBackoff
+		}
+	}
+}
+
+func (c *Consumer) topicWatchInterval() (retval time.Duration) {
+
+	if c.topicWait != nil && c.topicWait.WatchInterval > 0 {
+		retval = c.topicWait.WatchInterval
+	}
+
+	if c.partitionsWatch != nil && c.partitionsWatch.Interval > 0 &&
+		(retval <= 0 || c.partitionsWatch.Interval < retval) {
+		retval = c.partitionsWatch.Interval
+	}
+
+	return
+}
+
+func (c *Consumer) newTopicWatcher() *topicWatcher {
+
+	w := newTopicWatcher(c.reader, c.topics, c.onTopicChange)
+	w.clock = c.clock
+
+	if c.partitionsWatch != nil {
+		if c.cfg.Metrics != nil {
+			w.partitionsGauge = c.cfg.Metrics.Partitions
+		}
+		w.onPartitionsChange = c.handlePartitionsChange
+	}
+
+	return w
+}
+
+func (c *Consumer) handlePartitionsChange(ctx context.Context, logger *zap.Logger, topic string, old, new int) {
+
+	logger.Warn("partitions count is changed", zap.String("topic", topic), zap.Int("old", old), zap.Int("new", new))
+
+	if c.onPartitionsChange != nil {
+		c.onPartitionsChange(ctx, logger, topic, old, new)
+	}
+
+	if c.partitionsWatch.Resubscribe && new > old {
+		if err := c.Resubscribe(); err != nil {
+			logger.Error("failed to resubscribe", zap.Error(err))
+			c.onError(ctx, logger, err)
+		}
+	}
+}
+
+func (c *Consumer) startTopicWatch(w *topicWatcher) (stop func()) {
+
+	interval := c.topicWatchInterval()
+	if interval <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := c.clock.NewTicker(interval)
+		defer ticker.Stop()
+
+		opLog := c.logger.With(zap.String("operation", "topics watch"))
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				if _, err := w.check(ctx, opLog); err != nil {
+					opLog.Warn("failed to check topics", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
@@ -0,0 +1,131 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/metric"
+	"github.com/dialogs/dialog-go-lib/metric/mock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeMetadataReader struct {
+	topics     map[string]bool
+	partitions int
+	calls      int
+	mu         sync.Mutex
+}
+
+func (r *fakeMetadataReader) set(topic string, exists bool) {
+	r.mu.Lock()
+	r.topics[topic] = exists
+	r.mu.Unlock()
+}
+
+func (r *fakeMetadataReader) GetMetadata(_ *string, _ bool, _ int) (*kafka.Metadata, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls++
+
+	partitions := r.partitions
+	if partitions <= 0 {
+		partitions = 1
+	}
+
+	m := &kafka.Metadata{Topics: make(map[string]kafka.TopicMetadata)}
+	for topic, exists := range r.topics {
+		if exists {
+			m.Topics[topic] = kafka.TopicMetadata{
+				Topic:      topic,
+				Partitions: make([]kafka.PartitionMetadata, partitions),
+			}
+		}
+	}
+
+	return m, nil
+}
+
+func TestTopicWatcherWait(t *testing.T) {
+
+	reader := &fakeMetadataReader{topics: map[string]bool{"a": true}}
+
+	type change struct {
+		topic  string
+		exists bool
+	}
+	var changes []change
+	onChange := func(_ context.Context, _ *zap.Logger, topic string, exists bool) {
+		changes = append(changes, change{topic, exists})
+	}
+
+	// the regular expressions aren't checked
+	w := newTopicWatcher(reader, []string{"a", "b", "^c.*"}, onChange)
+
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		reader.set("b", true)
+	}()
+
+	cfg := &TopicWaitConfig{InitialBackoff: time.Millisecond * 5, MaxBackoff: time.Millisecond * 10, Timeout: time.Second}
+	require.NoError(t, w.wait(context.Background(), zap.NewNop(), cfg))
+	require.Equal(t, []change{{"b", true}}, changes)
+
+	reader.set("a", false)
+	missing, err := w.check(context.Background(), zap.NewNop())
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, missing)
+	require.Equal(t, []change{{"b", true}, {"a", false}}, changes)
+}
+
+func TestTopicWatcherWaitTimeout(t *testing.T) {
+
+	reader := &fakeMetadataReader{topics: map[string]bool{"a": true}}
+	w := newTopicWatcher(reader, []string{"b", "a"}, nil)
+
+	cfg := &TopicWaitConfig{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond * 2, Timeout: time.Millisecond * 20}
+	err := w.wait(context.Background(), zap.NewNop(), cfg)
+	require.True(t, errors.Is(err, ErrTopicsNotFound), err)
+	require.Contains(t, err.Error(), "b")
+
+	reader.mu.Lock()
+	require.True(t, reader.calls > 1)
+	reader.mu.Unlock()
+}
+
+func TestTopicWatcherPartitions(t *testing.T) {
+
+	reader := &fakeMetadataReader{topics: map[string]bool{"a": true}}
+	gauge := mock.NewGauge()
+
+	type change struct {
+		topic    string
+		old, new int
+	}
+	var changes []change
+
+	w := newTopicWatcher(reader, []string{"a"}, nil)
+	w.partitionsGauge = func(topic string) metric.IGauge { return gauge }
+	w.onPartitionsChange = func(_ context.Context, _ *zap.Logger, topic string, old, new int) {
+		changes = append(changes, change{topic, old, new})
+	}
+
+	_, err := w.check(context.Background(), zap.NewNop())
+	require.NoError(t, err)
+	require.Empty(t, changes)
+	require.Equal(t, float64(1), gauge.Get())
+
+	reader.mu.Lock()
+	reader.partitions = 3
+	reader.mu.Unlock()
+
+	_, err = w.check(context.Background(), zap.NewNop())
+	require.NoError(t, err)
+	require.Equal(t, []change{{"a", 1, 3}}, changes)
+	require.Equal(t, float64(3), gauge.Get())
+}
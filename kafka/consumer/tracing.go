@@ -0,0 +1,137 @@
+package consumer
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/budget"
+	"github.com/dialogs/dialog-go-lib/logger"
+	"github.com/dialogs/dialog-go-lib/trace"
+	"go.uber.org/zap"
+)
+
+// HeadersCarrier is the trace carrier of the message headers (see trace.IPropagator)
+type HeadersCarrier struct {
+	msg *kafka.Message
+}
+
+// NewHeadersCarrier returns the carrier of the message headers
+func NewHeadersCarrier(msg *kafka.Message) HeadersCarrier {
+	return HeadersCarrier{msg: msg}
+}
+
+// Get returns the value of the last header with the key
+func (c HeadersCarrier) Get(key string) string {
+	for i := len(c.msg.Headers) - 1; i >= 0; i-- {
+		if c.msg.Headers[i].Key == key {
+			return string(c.msg.Headers[i].Value)
+		}
+	}
+
+	return ""
+}
+
+// Set replaces the values of the headers with the key
+func (c HeadersCarrier) Set(key, val string) {
+
+	headers := c.msg.Headers[:0]
+	for _, h := range c.msg.Headers {
+		if h.Key != key {
+			headers = append(headers, h)
+		}
+	}
+
+	c.msg.Headers = append(headers, kafka.Header{Key: key, Value: []byte(val)})
+}
+
+// FuncTraceSampler decides the sampling of the consume span (ok is false to use the sampler of the tracer)
+type FuncTraceSampler func(msg *kafka.Message) (sampled, ok bool)
+
+// SampleByHeader samples the messages with the header value from the list
+func SampleByHeader(key string, values ...string) FuncTraceSampler {
+
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+
+	return func(msg *kafka.Message) (bool, bool) {
+		_, ok := set[NewHeadersCarrier(msg).Get(key)]
+		return true, ok
+	}
+}
+
+// SampleAny returns the decision of the first sampler which decides
+func SampleAny(samplers ...FuncTraceSampler) FuncTraceSampler {
+	return func(msg *kafka.Message) (bool, bool) {
+		for _, fn := range samplers {
+			if sampled, ok := fn(msg); ok {
+				return sampled, true
+			}
+		}
+
+		return false, false
+	}
+}
+
+func (c *Consumer) process(opLog *zap.Logger, msg *kafka.Message) error {
+
+	defer repanic(func() map[string]string { return messageDetails(msg) })
+
+	ctx, progress := ContextWithProgress(logger.ContextWithLogger(c.ctx, opLog))
+
+	if c.propagator != nil {
+		ctx = c.propagator.Extract(ctx, NewHeadersCarrier(msg))
+	}
+
+	if c.enforceDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = budget.Extract(ctx, c.clock, NewHeadersCarrier(msg))
+		defer cancel()
+	}
+
+	topic := stringValue(msg.TopicPartition.Topic)
+	onProcess := func(ctx context.Context) error { return c.onProcess(ctx, opLog, msg, c, c) }
+
+	if c.tracer == nil {
+		return progressError(progress, c.labels.Do(ctx, topic, onProcess))
+	}
+
+	if c.traceSampler != nil {
+		if sampled, ok := c.traceSampler(msg); ok {
+			ctx = trace.ContextWithSampling(ctx, sampled)
+		}
+	}
+
+	ctx, span := c.tracer.Start(ctx, topic+" process", trace.KindConsumer)
+	defer span.End()
+
+	span.SetAttribute("messaging.system", "kafka")
+	span.SetAttribute("messaging.destination", topic)
+	span.SetAttribute("messaging.kafka.partition", strconv.Itoa(int(msg.TopicPartition.Partition)))
+	span.SetAttribute("messaging.kafka.offset", msg.TopicPartition.Offset.String())
+	span.SetAttribute("messaging.kafka.consumer_id", c.id)
+
+	err := c.labels.Do(ctx, topic, onProcess)
+	if stages := progress.String(); stages != "" {
+		span.SetAttribute("messaging.kafka.progress", stages)
+	}
+	span.SetError(err)
+
+	return progressError(progress, err)
+}
+
+func progressError(progress *Progress, err error) error {
+
+	if err == nil {
+		return nil
+	}
+
+	stages := progress.Stages()
+	if len(stages) == 0 {
+		return err
+	}
+
+	return &ProgressError{Stages: stages, Err: err}
+}
@@ -0,0 +1,148 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/budget"
+	"github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/dialogs/dialog-go-lib/trace"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHeadersCarrier(t *testing.T) {
+
+	msg := &kafka.Message{Headers: []kafka.Header{
+		{Key: "a", Value: []byte("1")},
+		{Key: trace.HeaderTraceparent, Value: []byte("old")},
+	}}
+
+	c := NewHeadersCarrier(msg)
+	require.Equal(t, "1", c.Get("a"))
+	require.Equal(t, "", c.Get("b"))
+
+	c.Set(trace.HeaderTraceparent, "new")
+	require.Equal(t, "new", c.Get(trace.HeaderTraceparent))
+	require.Len(t, msg.Headers, 2)
+}
+
+func TestProcessExtractTraceContext(t *testing.T) {
+
+	tracer := trace.NewTracer(trace.Config{})
+	defer tracer.Close()
+
+	parent := trace.SpanContext{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}, Sampled: true}
+
+	var spanCtx trace.SpanContext
+	c, _ := newTestConsumer(t, WithTracer(tracer), WithConfig(func(cfg *Config) {
+		cfg.OnProcess = func(ctx context.Context, _ *zap.Logger, _ *kafka.Message, _ ISleeper, _ ICommitter) error {
+			spanCtx = trace.SpanContextFromContext(ctx)
+			return nil
+		}
+	}))
+
+	topic := "topic"
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}}
+	trace.Inject(trace.ContextWithRemoteSpanContext(context.Background(), parent), NewHeadersCarrier(msg))
+
+	require.NoError(t, c.process(zap.NewNop(), msg))
+	require.Equal(t, parent.TraceID, spanCtx.TraceID)
+	require.NotEqual(t, parent.SpanID, spanCtx.SpanID)
+
+	// the message without the trace context starts a new trace
+	require.NoError(t, c.process(zap.NewNop(), &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}}))
+	require.True(t, spanCtx.IsValid())
+	require.NotEqual(t, parent.TraceID, spanCtx.TraceID)
+}
+
+func TestSampleByHeader(t *testing.T) {
+
+	msg := func(tenant string) *kafka.Message {
+		return &kafka.Message{Headers: []kafka.Header{{Key: "tenant", Value: []byte(tenant)}}}
+	}
+
+	fn := SampleAny(SampleByHeader("tenant", "x", "y"), SampleByHeader("error", "true"))
+
+	sampled, ok := fn(msg("x"))
+	require.True(t, ok)
+	require.True(t, sampled)
+
+	_, ok = fn(msg("z"))
+	require.False(t, ok)
+
+	_, ok = fn(&kafka.Message{Headers: []kafka.Header{{Key: "error", Value: []byte("true")}}})
+	require.True(t, ok)
+}
+
+func TestProcessTraceSampler(t *testing.T) {
+
+	tracer := trace.NewTracer(trace.Config{Sampler: trace.NeverSample()})
+	defer tracer.Close()
+
+	var spanCtx trace.SpanContext
+	c, _ := newTestConsumer(t, WithTracer(tracer), WithConfig(func(cfg *Config) {
+		cfg.TraceSampler = SampleByHeader("tenant", "x")
+		cfg.OnProcess = func(ctx context.Context, _ *zap.Logger, _ *kafka.Message, _ ISleeper, _ ICommitter) error {
+			spanCtx = trace.SpanContextFromContext(ctx)
+			return nil
+		}
+	}))
+
+	topic := "topic"
+
+	require.NoError(t, c.process(zap.NewNop(), &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic},
+		Headers:        []kafka.Header{{Key: "tenant", Value: []byte("x")}},
+	}))
+	require.True(t, spanCtx.Sampled)
+
+	// the sampler of the tracer
+	require.NoError(t, c.process(zap.NewNop(), &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}}))
+	require.False(t, spanCtx.Sampled)
+}
+
+func TestProcessEnforceDeadline(t *testing.T) {
+
+	clk := mock.NewClock(time.Unix(1000, 0))
+
+	var (
+		deadline time.Time
+		ok       bool
+		err      error
+	)
+	c, _ := newTestConsumer(t, WithClock(clk), WithConfig(func(cfg *Config) {
+		cfg.EnforceDeadline = true
+		cfg.OnProcess = func(ctx context.Context, _ *zap.Logger, _ *kafka.Message, _ ISleeper, _ ICommitter) error {
+			deadline, ok = ctx.Deadline()
+			err = ctx.Err()
+			return nil
+		}
+	}))
+
+	topic := "topic"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic},
+		Headers:        []kafka.Header{{Key: budget.Header, Value: []byte(budget.Format(time.Unix(1005, 0)))}},
+	}
+
+	require.NoError(t, c.process(zap.NewNop(), msg))
+	require.True(t, ok)
+	require.True(t, time.Unix(1005, 0).Equal(deadline))
+	require.NoError(t, err)
+
+	// the budget is spent
+	clk.Add(time.Second * 10)
+	require.NoError(t, c.process(zap.NewNop(), msg))
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	// the message without the header
+	require.NoError(t, c.process(zap.NewNop(), &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}}))
+	require.False(t, ok)
+
+	c.enforceDeadline = false
+	require.NoError(t, c.process(zap.NewNop(), msg))
+	require.False(t, ok)
+}
@@ -0,0 +1,101 @@
+package consumer
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"go.uber.org/zap"
+)
+
+// FuncTransform modifies the message before processing, the nil result skips the message
+type FuncTransform func(ctx context.Context, logger *zap.Logger, msg *kafka.Message) (*kafka.Message, error)
+
+// FuncFilter reports whether the message is processed (see Config.Filter)
+type FuncFilter func(msg *kafka.Message) bool
+
+// Transform applies the transformers to the message one by one
+func Transform(ctx context.Context, logger *zap.Logger, msg *kafka.Message, list ...FuncTransform) (*kafka.Message, error) {
+
+	for _, fn := range list {
+		if msg == nil {
+			break
+		}
+
+		var err error
+		if msg, err = fn(ctx, logger, msg); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
+
+// HeaderFilter returns the filter of the messages with the header value
+func HeaderFilter(key string, values ...string) FuncFilter {
+
+	index := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		index[v] = struct{}{}
+	}
+
+	return func(msg *kafka.Message) bool {
+		for _, h := range msg.Headers {
+			if h.Key == key {
+				_, ok := index[string(h.Value)]
+				return ok
+			}
+		}
+
+		return false
+	}
+}
+
+// StripHeaders removes the headers with the keys
+func StripHeaders(keys ...string) FuncTransform {
+
+	index := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		index[k] = struct{}{}
+	}
+
+	return func(_ context.Context, _ *zap.Logger, msg *kafka.Message) (*kafka.Message, error) {
+
+		headers := make([]kafka.Header, 0, len(msg.Headers))
+		for _, h := range msg.Headers {
+			if _, ok := index[h.Key]; !ok {
+				headers = append(headers, h)
+			}
+		}
+		msg.Headers = headers
+
+		return msg, nil
+	}
+}
+
+// TransformKey replaces the key of the message (e.g. normalization)
+func TransformKey(fn func([]byte) ([]byte, error)) FuncTransform {
+	return func(_ context.Context, _ *zap.Logger, msg *kafka.Message) (*kafka.Message, error) {
+
+		key, err := fn(msg.Key)
+		if err != nil {
+			return nil, err
+		}
+		msg.Key = key
+
+		return msg, nil
+	}
+}
+
+// TransformValue replaces the value of the message (e.g. decompression, decryption)
+func TransformValue(fn func([]byte) ([]byte, error)) FuncTransform {
+	return func(_ context.Context, _ *zap.Logger, msg *kafka.Message) (*kafka.Message, error) {
+
+		value, err := fn(msg.Value)
+		if err != nil {
+			return nil, err
+		}
+		msg.Value = value
+
+		return msg, nil
+	}
+}
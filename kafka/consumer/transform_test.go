@@ -0,0 +1,109 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTransform(t *testing.T) {
+
+	msg := &kafka.Message{
+		Key:   []byte(" Key "),
+		Value: []byte("value"),
+		Headers: []kafka.Header{
+			{Key: "h1", Value: []byte("1")},
+			{Key: "secret", Value: []byte("2")},
+		},
+	}
+
+	res, err := Transform(context.Background(), zap.NewNop(), msg,
+		StripHeaders("secret"),
+		TransformKey(func(v []byte) ([]byte, error) { return bytes.ToLower(bytes.TrimSpace(v)), nil }),
+		TransformValue(func(v []byte) ([]byte, error) { return bytes.ToUpper(v), nil }),
+	)
+	require.NoError(t, err)
+	require.Equal(t,
+		&kafka.Message{
+			Key:     []byte("key"),
+			Value:   []byte("VALUE"),
+			Headers: []kafka.Header{{Key: "h1", Value: []byte("1")}},
+		},
+		res)
+}
+
+func TestTransformSkip(t *testing.T) {
+
+	var called bool
+	res, err := Transform(context.Background(), zap.NewNop(), &kafka.Message{},
+		func(context.Context, *zap.Logger, *kafka.Message) (*kafka.Message, error) { return nil, nil },
+		func(_ context.Context, _ *zap.Logger, msg *kafka.Message) (*kafka.Message, error) {
+			called = true
+			return msg, nil
+		},
+	)
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.False(t, called)
+}
+
+func TestTransformError(t *testing.T) {
+
+	res, err := Transform(context.Background(), zap.NewNop(), &kafka.Message{},
+		TransformValue(func([]byte) ([]byte, error) { return nil, errors.New("fail") }),
+	)
+	require.EqualError(t, err, "fail")
+	require.Nil(t, res)
+}
+
+func TestHeaderFilter(t *testing.T) {
+
+	filter := HeaderFilter("type", "created", "deleted")
+
+	require.True(t, filter(&kafka.Message{Headers: []kafka.Header{{Key: "type", Value: []byte("created")}}}))
+	require.False(t, filter(&kafka.Message{Headers: []kafka.Header{{Key: "type", Value: []byte("updated")}}}))
+	require.False(t, filter(&kafka.Message{Headers: []kafka.Header{{Key: "version", Value: []byte("created")}}}))
+	require.False(t, filter(&kafka.Message{}))
+}
+
+func TestConsumerFilter(t *testing.T) {
+
+	var (
+		processed   []kafka.Offset
+		transformed int
+	)
+
+	c, _ := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.OnProcess = func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
+			processed = append(processed, msg.TopicPartition.Offset)
+			return nil
+		}
+		cfg.Filter = func(msg *kafka.Message) bool { return msg.TopicPartition.Offset != 2 }
+		cfg.Transformers = []FuncTransform{
+			func(_ context.Context, _ *zap.Logger, msg *kafka.Message) (*kafka.Message, error) {
+				transformed++
+				return msg, nil
+			},
+		}
+	}))
+
+	topic := "a"
+	offsets := newOffset()
+	for i := 1; i <= 2; i++ {
+		msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(i)}}
+		require.NoError(t, c.handleMessage(msg, offsets))
+	}
+
+	// the filtered message isn't transformed and processed, its offset is stored
+	require.Equal(t, []kafka.Offset{1}, processed)
+	require.Equal(t, 1, transformed)
+
+	list, _ := offsets.Get()
+	require.Len(t, list, 1)
+	require.Equal(t, kafka.Offset(2), list[0].Offset)
+}
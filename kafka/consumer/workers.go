@@ -0,0 +1,289 @@
+package consumer
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/reporter"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const _WorkersTickDuration = time.Second
+
+type workItem struct {
+	tp  kafka.TopicPartition
+	msg *kafka.Message
+	// key is the key of the deduplication (see dedupeKey)
+	key     string
+	logger  *zap.Logger
+	offsets *offset
+}
+
+// workerPool processes the partitions concurrently, the messages of a partition (or a key) in order
+type workerPool struct {
+	queues  []chan workItem
+	process func(item workItem) error
+	onPanic func(val interface{})
+	keys    *keyOffsets
+	// retries are the offsets of the messages processed again after the seek (see ErrRetryLater)
+	retries   map[string]kafka.Offset
+	retriesMu sync.Mutex
+	pending   sync.WaitGroup
+	wg        sync.WaitGroup
+	err       error
+	mu        sync.RWMutex
+}
+
+func newWorkerPool(workers, queueSize int, process func(item workItem) error) *workerPool {
+
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+
+	p := &workerPool{
+		queues:  make([]chan workItem, workers),
+		process: process,
+		retries: make(map[string]kafka.Offset),
+	}
+
+	p.wg.Add(workers)
+	for i := range p.queues {
+		p.queues[i] = make(chan workItem, queueSize)
+		go p.run(p.queues[i])
+	}
+
+	return p
+}
+
+func newKeyedWorkerPool(workers, queueSize int, process func(item workItem) error) *workerPool {
+
+	p := newWorkerPool(workers, queueSize, process)
+	p.keys = newKeyOffsets()
+
+	return p
+}
+
+func (p *workerPool) run(queue <-chan workItem) {
+	defer p.wg.Done()
+
+	for item := range queue {
+		// the messages after the error aren't processed (they aren't committed)
+		if p.Err() == nil {
+			if err := p.call(item); err != nil {
+				p.mu.Lock()
+				if p.err == nil {
+					p.err = err
+				}
+				p.mu.Unlock()
+			}
+		}
+
+		p.pending.Done()
+	}
+}
+
+func (p *workerPool) call(item workItem) (err error) {
+
+	defer func() {
+		if val := recover(); val != nil {
+			panicErr, ok := val.(*reporter.PanicError)
+			if !ok {
+				panicErr = reporter.NewPanicError(val, nil)
+			}
+
+			if p.onPanic != nil {
+				p.onPanic(panicErr)
+			}
+			err = panicErr
+		}
+	}()
+
+	return p.process(item)
+}
+
+// Dispatch sends the message to the worker of the partition or of the key (it's blocked if the queue is full)
+func (p *workerPool) Dispatch(ctx context.Context, item workItem) error {
+
+	h := fnv.New32a()
+	var index uint32
+	if p.keys != nil && item.msg != nil && len(item.msg.Key) > 0 {
+		_, _ = h.Write(item.msg.Key)
+		index = h.Sum32() % uint32(len(p.queues))
+	} else {
+		if item.tp.Topic != nil {
+			_, _ = h.Write([]byte(*item.tp.Topic))
+		}
+		index = (h.Sum32() + uint32(item.tp.Partition)) % uint32(len(p.queues))
+	}
+
+	if p.keys != nil {
+		p.keys.Begin(item.tp)
+	}
+
+	p.pending.Add(1)
+	select {
+	case p.queues[index] <- item:
+		return nil
+	case <-ctx.Done():
+		p.pending.Done()
+		if p.keys != nil {
+			p.keys.Cancel(item.tp)
+		}
+		return ctx.Err()
+	}
+}
+
+// Completed returns the offset of the partition which can be stored after the message is processed
+func (p *workerPool) Completed(tp kafka.TopicPartition) (kafka.TopicPartition, bool) {
+	if p.keys == nil {
+		return tp, true
+	}
+
+	return p.keys.Done(tp)
+}
+
+// RetryLater registers the message processed again after the seek of its partition
+func (p *workerPool) RetryLater(tp kafka.TopicPartition, seek func([]kafka.TopicPartition) error) error {
+	p.retriesMu.Lock()
+	defer p.retriesMu.Unlock()
+
+	key := getPartitionKey(tp.Topic, tp.Partition)
+	if offset, ok := p.retries[key]; ok && offset <= tp.Offset {
+		return nil
+	}
+
+	// the seeks are applied in order, so the last one is the earliest message
+	if err := seek([]kafka.TopicPartition{tp}); err != nil {
+		return err
+	}
+
+	p.retries[key] = tp.Offset
+	return nil
+}
+
+// Retrying reports that the message is skipped until the seek of the retried message of the partition
+func (p *workerPool) Retrying(tp kafka.TopicPartition) bool {
+	p.retriesMu.Lock()
+	defer p.retriesMu.Unlock()
+
+	offset, ok := p.retries[getPartitionKey(tp.Topic, tp.Partition)]
+	return ok && offset <= tp.Offset
+}
+
+// Reset drops the retried messages and the dispatched offsets of the drained partitions
+func (p *workerPool) Reset(partitions []kafka.TopicPartition) {
+	p.retriesMu.Lock()
+	defer p.retriesMu.Unlock()
+
+	for _, tp := range partitions {
+		delete(p.retries, getPartitionKey(tp.Topic, tp.Partition))
+		if p.keys != nil {
+			p.keys.Reset(tp)
+		}
+	}
+}
+
+// Drain waits for the dispatched messages
+func (p *workerPool) Drain() {
+	p.pending.Wait()
+}
+
+// Err returns the first error of the processing
+func (p *workerPool) Err() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.err
+}
+
+// Close stops the workers after the dispatched messages are processed
+func (p *workerPool) Close() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
+
+func (c *Consumer) processItem(item workItem) error {
+
+	if c.pool.Retrying(item.tp) {
+		// the offset isn't stored: the message is consumed again after the seek
+		item.logger.Debug("skipped before retry")
+		return nil
+	}
+
+	if item.msg != nil {
+		if c.ctx.Err() != nil {
+			// the consumer is stopped: the message is processed after the restart
+			return nil
+		}
+
+		start := time.Now()
+		err := c.processInflight(item.logger, item.tp, item.msg)
+		if errors.Is(err, ErrRetryLater) {
+			c.inflight.Done(item.tp, time.Since(start), nil)
+			return c.retryLaterItem(item)
+		}
+		c.inflight.Done(item.tp, time.Since(start), err)
+
+		if err != nil {
+			if progressErr, ok := err.(*ProgressError); ok {
+				item.logger = item.logger.With(zap.Any("progress", progressErr.Tags()))
+			}
+			item.logger.Error("failed to process message", zap.Error(err))
+			return err
+		}
+
+		item.logger.Debug("success")
+	}
+
+	c.dedupe.Add(item.key)
+
+	if tp, ok := c.pool.Completed(item.tp); ok {
+		c.storeOffset(item.offsets, tp)
+	}
+	return nil
+}
+
+func (c *Consumer) retryLaterItem(item workItem) error {
+
+	if err := c.pool.RetryLater(item.tp, c.seek); err != nil {
+		if err == ErrAlreadyClosed {
+			// the consumer is stopped: the message is processed after the restart
+			return nil
+		}
+		item.logger.Error("failed to seek back", zap.Error(err))
+		return err
+	}
+
+	item.logger.Debug("retry later")
+	return nil
+}
+
+func (c *Consumer) tickDuration() time.Duration {
+	if c.workers > 1 {
+		return _WorkersTickDuration
+	}
+
+	return c.batch.tickDuration()
+}
+
+func (c *Consumer) dispatch(opLog *zap.Logger, tp kafka.TopicPartition, msg *kafka.Message, key string, consumerOffsets *offset) error {
+
+	if msg == nil {
+		opLog.Debug("skipped")
+	}
+
+	if err := c.pool.Dispatch(c.ctx, workItem{tp: tp, msg: msg, key: key, logger: opLog, offsets: consumerOffsets}); err != nil {
+		// the consumer is stopped
+		return nil
+	}
+
+	c.commitProcessed(consumerOffsets)
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/reporter"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWorkerPoolOrder(t *testing.T) {
+
+	var (
+		mu     sync.Mutex
+		result = make(map[int32][]kafka.Offset)
+	)
+
+	p := newWorkerPool(3, 1, func(item workItem) error {
+		// the slow partition doesn't block others
+		if item.tp.Partition == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		mu.Lock()
+		result[item.tp.Partition] = append(result[item.tp.Partition], item.tp.Offset)
+		mu.Unlock()
+		return nil
+	})
+
+	topic := "t1"
+	for offset := 0; offset < 10; offset++ {
+		for partition := int32(0); partition < 4; partition++ {
+			tp := kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: kafka.Offset(offset)}
+			require.NoError(t, p.Dispatch(context.Background(), workItem{tp: tp}))
+		}
+	}
+
+	p.Drain()
+	require.NoError(t, p.Err())
+	require.Len(t, result, 4)
+	for partition, offsets := range result {
+		require.Len(t, offsets, 10)
+		for i := range offsets {
+			require.Equal(t, kafka.Offset(i), offsets[i], "partition %d", partition)
+		}
+	}
+
+	p.Close()
+}
+
+func TestWorkerPoolError(t *testing.T) {
+
+	var calls int
+	p := newWorkerPool(1, 10, func(item workItem) error {
+		calls++
+		return errors.New("failed")
+	})
+	defer p.Close()
+
+	topic := "t1"
+	for offset := 0; offset < 3; offset++ {
+		tp := kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(offset)}
+		require.NoError(t, p.Dispatch(context.Background(), workItem{tp: tp}))
+	}
+
+	p.Drain()
+	require.EqualError(t, p.Err(), "failed")
+	require.Equal(t, 1, calls)
+
+	// the queue is full
+	block := make(chan struct{})
+	p = newWorkerPool(1, 1, func(workItem) error {
+		<-block
+		return nil
+	})
+	defer p.Close()
+	defer close(block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := p.Dispatch(ctx, workItem{tp: kafka.TopicPartition{Topic: &topic}})
+	for err == nil {
+		err = p.Dispatch(ctx, workItem{tp: kafka.TopicPartition{Topic: &topic}})
+	}
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestWorkerPoolPanic(t *testing.T) {
+
+	var reported interface{}
+	p := newWorkerPool(1, 10, func(item workItem) error {
+		panic("failed")
+	})
+	p.onPanic = func(val interface{}) { reported = val }
+	defer p.Close()
+
+	topic := "t1"
+	require.NoError(t, p.Dispatch(context.Background(), workItem{tp: kafka.TopicPartition{Topic: &topic}}))
+
+	// the panic doesn't crash the process and doesn't hang the drain
+	p.Drain()
+	require.EqualError(t, p.Err(), "panic: failed")
+	require.IsType(t, &reporter.PanicError{}, reported)
+	require.Equal(t, p.Err(), reported)
+}
+
+func TestConsumerWorkers(t *testing.T) {
+
+	processed := make(chan string, 10)
+
+	c, _ := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.Workers = 2
+		cfg.OnProcess = func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
+			if string(msg.Value) == "fail" {
+				return errors.New("failed")
+			}
+			processed <- string(msg.Value)
+			return nil
+		}
+	}))
+	c.pool = newWorkerPool(c.workers, 0, c.processItem)
+	defer c.pool.Close()
+
+	require.Equal(t, _WorkersTickDuration, c.tickDuration())
+
+	topic := "t1"
+	offsets := newOffset()
+	require.NoError(t, c.handleMessage(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: 1}, Value: []byte("1")}, offsets))
+	require.NoError(t, c.handleMessage(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1, Offset: 5}, Value: []byte("2")}, offsets))
+
+	c.pool.Drain()
+	require.Len(t, processed, 2)
+	require.Equal(t, 2, offsets.Counter())
+	require.NoError(t, c.handleTick(offsets))
+
+	require.NoError(t, c.handleMessage(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: 2}, Value: []byte("fail")}, offsets))
+	c.pool.Drain()
+	require.EqualError(t, c.handleTick(offsets), "failed")
+	require.EqualError(t, c.handleMessage(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: 3}}, offsets), "failed")
+	require.Equal(t, 2, offsets.Counter())
+}
+
+func TestConsumerWorkersRetryLater(t *testing.T) {
+
+	var (
+		processed []kafka.Offset
+		retried   bool
+	)
+
+	c, _ := newTestConsumer(t, WithConfig(func(cfg *Config) {
+		cfg.Workers = 1
+		cfg.OnProcess = func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ ISleeper, _ ICommitter) error {
+			processed = append(processed, msg.TopicPartition.Offset)
+			if msg.TopicPartition.Offset == 1 && !retried {
+				retried = true
+				return ErrRetryLater
+			}
+			return nil
+		}
+	}))
+	c.pool = newWorkerPool(c.workers, 0, c.processItem)
+	defer c.pool.Close()
+
+	topic := "t1"
+	offsets := newOffset()
+	for offset := 1; offset <= 3; offset++ {
+		msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(offset)}}
+		require.NoError(t, c.handleMessage(msg, offsets))
+	}
+
+	// the next messages of the partition are skipped until the seek back
+	c.pool.Drain()
+	require.NoError(t, c.pool.Err())
+	require.Equal(t, []kafka.Offset{1}, processed)
+	require.Zero(t, offsets.Counter())
+
+	r := &fakeSeekReader{}
+	c.applySeeks(r, offsets)
+	require.Len(t, r.seeks, 1)
+	require.Equal(t, kafka.Offset(1), r.seeks[0].Offset)
+
+	// the messages are consumed again after the seek
+	for offset := 1; offset <= 3; offset++ {
+		msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(offset)}}
+		require.NoError(t, c.handleMessage(msg, offsets))
+	}
+
+	c.pool.Drain()
+	require.Equal(t, []kafka.Offset{1, 1, 2, 3}, processed)
+	require.Equal(t, 3, offsets.Counter())
+}
@@ -0,0 +1,82 @@
+package kafka
+
+import (
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/service/info"
+)
+
+// The versions of librdkafka (0xMMmmrrxx) which support the features
+const (
+	_VersionIdempotence          = 0x01000000
+	_VersionZstd                 = 0x01010000
+	_VersionOAuthBearer          = 0x01010000
+	_VersionTransactions         = 0x01040000
+	_VersionStaticMembership     = 0x01040000
+	_VersionCooperativeRebalance = 0x01060000
+)
+
+// A FeatureSet is the capabilities of the linked librdkafka
+type FeatureSet struct {
+	Version    string
+	VersionInt int
+	// Idempotence is the idempotent producer (KIP-98)
+	Idempotence bool
+	// Zstd is the zstd compression (KIP-110), librdkafka must be built with libzstd
+	Zstd bool
+	// OAuthBearer is the SASL/OAUTHBEARER authentication (KIP-255)
+	OAuthBearer bool
+	// Transactions is the transactional producer (KIP-98)
+	Transactions bool
+	// StaticMembership is the static group membership, 'group.instance.id' (KIP-345)
+	StaticMembership bool
+	// CooperativeRebalance is the incremental rebalance protocol (KIP-429),
+	// it also requires support by the Go client
+	CooperativeRebalance bool
+}
+
+// Features detects the capabilities by the version of librdkafka
+func Features() FeatureSet {
+	version, str := confluent.LibraryVersion()
+	return newFeatures(version, str)
+}
+
+func newFeatures(version int, str string) FeatureSet {
+	return FeatureSet{
+		Version:              str,
+		VersionInt:           version,
+		Idempotence:          version >= _VersionIdempotence,
+		Zstd:                 version >= _VersionZstd,
+		OAuthBearer:          version >= _VersionOAuthBearer,
+		Transactions:         version >= _VersionTransactions,
+		StaticMembership:     version >= _VersionStaticMembership,
+		CooperativeRebalance: version >= _VersionCooperativeRebalance,
+	}
+}
+
+// Map returns the features by the names
+func (f FeatureSet) Map() map[string]bool {
+	return map[string]bool{
+		"librdkafka.idempotence":           f.Idempotence,
+		"librdkafka.zstd":                  f.Zstd,
+		"librdkafka.oauthbearer":           f.OAuthBearer,
+		"librdkafka.transactions":          f.Transactions,
+		"librdkafka.static-membership":     f.StaticMembership,
+		"librdkafka.cooperative-rebalance": f.CooperativeRebalance,
+	}
+}
+
+// AddTo adds the version and features to the service info (see router.AdminRouter /info)
+func (f FeatureSet) AddTo(i *info.Info) {
+
+	if i.Libraries == nil {
+		i.Libraries = make(map[string]string)
+	}
+	i.Libraries["librdkafka"] = f.Version
+
+	if i.Features == nil {
+		i.Features = make(map[string]bool)
+	}
+	for k, v := range f.Map() {
+		i.Features[k] = v
+	}
+}
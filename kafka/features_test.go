@@ -0,0 +1,38 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/dialogs/dialog-go-lib/service/info"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitFeatures(t *testing.T) {
+
+	f := Features()
+	require.NotEmpty(t, f.Version)
+	require.True(t, f.Idempotence)
+
+	f = newFeatures(0x010402ff, "1.4.2")
+	require.Equal(t,
+		FeatureSet{
+			Version:          "1.4.2",
+			VersionInt:       0x010402ff,
+			Idempotence:      true,
+			Zstd:             true,
+			OAuthBearer:      true,
+			Transactions:     true,
+			StaticMembership: true,
+		},
+		f)
+
+	f = newFeatures(0x010600ff, "1.6.0")
+	require.True(t, f.CooperativeRebalance)
+
+	i := &info.Info{Name: "svc"}
+	newFeatures(0x010000ff, "1.0.0").AddTo(i)
+	require.Equal(t, map[string]string{"librdkafka": "1.0.0"}, i.Libraries)
+	require.True(t, i.Features["librdkafka.idempotence"])
+	require.False(t, i.Features["librdkafka.zstd"])
+	require.Len(t, i.Features, 6)
+}
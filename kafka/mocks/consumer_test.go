@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockConsumer(t *testing.T) {
+
+	var c consumer.IConsumer = &IConsumer{}
+
+	m := c.(*IConsumer)
+	m.On("Start").Return(nil)
+	m.On("Sleep", time.Second, mock.Anything).Return(errors.New("failed"))
+	m.On("PausedPartitions").Return(nil)
+	m.On("SleepStatus").Return(time.Unix(10, 0), true)
+
+	require.NoError(t, c.Start())
+	require.EqualError(t, c.Sleep(time.Second, []kafka.TopicPartition{{Partition: 1}}), "failed")
+	require.Nil(t, c.PausedPartitions())
+
+	until, sleeping := c.SleepStatus()
+	require.Equal(t, time.Unix(10, 0), until)
+	require.True(t, sleeping)
+
+	m.AssertExpectations(t)
+}
@@ -0,0 +1,176 @@
+package msk
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	_EnvAccessKeyID        = "AWS_ACCESS_KEY_ID"
+	_EnvSecretAccessKey    = "AWS_SECRET_ACCESS_KEY"
+	_EnvSessionToken       = "AWS_SESSION_TOKEN"
+	_EnvRegion             = "AWS_REGION"
+	_EnvRoleARN            = "AWS_ROLE_ARN"
+	_EnvRoleSessionName    = "AWS_ROLE_SESSION_NAME"
+	_EnvWebIdentityTokenFn = "AWS_WEB_IDENTITY_TOKEN_FILE"
+)
+
+// ErrNoCredentials is returned if the provider has no credentials
+var ErrNoCredentials = errors.New("aws credentials not found")
+
+// Credentials of AWS
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Expires is zero for the static credentials
+	Expires time.Time
+}
+
+// ICredentialsProvider returns credentials of AWS
+// (an adapter for aws-sdk credentials can be used)
+type ICredentialsProvider interface {
+	Retrieve(ctx context.Context) (Credentials, error)
+}
+
+// EnvProvider reads credentials from the environment variables:
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN
+type EnvProvider struct{}
+
+func (EnvProvider) Retrieve(context.Context) (Credentials, error) {
+
+	retval := Credentials{
+		AccessKeyID:     os.Getenv(_EnvAccessKeyID),
+		SecretAccessKey: os.Getenv(_EnvSecretAccessKey),
+		SessionToken:    os.Getenv(_EnvSessionToken),
+	}
+
+	if retval.AccessKeyID == "" || retval.SecretAccessKey == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+
+	return retval, nil
+}
+
+// WebIdentityProvider exchanges a web identity token for credentials (IAM roles for service accounts on EKS).
+// Parameters are read from the environment variables:
+// AWS_ROLE_ARN, AWS_WEB_IDENTITY_TOKEN_FILE, AWS_ROLE_SESSION_NAME, AWS_REGION
+type WebIdentityProvider struct {
+	Client *http.Client
+	// Endpoint of STS. By default it's the regional endpoint.
+	Endpoint string
+}
+
+type assumeRoleWithWebIdentityResponse struct {
+	Credentials struct {
+		AccessKeyID     string    `xml:"AccessKeyId"`
+		SecretAccessKey string    `xml:"SecretAccessKey"`
+		SessionToken    string    `xml:"SessionToken"`
+		Expiration      time.Time `xml:"Expiration"`
+	} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+}
+
+func (p *WebIdentityProvider) Retrieve(ctx context.Context) (Credentials, error) {
+
+	roleARN := os.Getenv(_EnvRoleARN)
+	tokenFile := os.Getenv(_EnvWebIdentityTokenFn)
+	if roleARN == "" || tokenFile == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, "read web identity token failed")
+	}
+
+	sessionName := os.Getenv(_EnvRoleSessionName)
+	if sessionName == "" {
+		sessionName = "dialog-go-lib-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = "https://sts.amazonaws.com"
+		if region := os.Getenv(_EnvRegion); region != "" {
+			endpoint = "https://sts." + region + ".amazonaws.com"
+		}
+	}
+
+	query := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {sessionName},
+		"WebIdentityToken": {string(token)},
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint+"/?"+query.Encode(), nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, "assume role with web identity failed")
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Credentials{}, errors.Errorf("assume role with web identity failed: %s: %s", resp.Status, body)
+	}
+
+	var res assumeRoleWithWebIdentityResponse
+	if err := xml.Unmarshal(body, &res); err != nil {
+		return Credentials{}, errors.Wrap(err, "invalid response of sts")
+	}
+
+	return Credentials{
+		AccessKeyID:     res.Credentials.AccessKeyID,
+		SecretAccessKey: res.Credentials.SecretAccessKey,
+		SessionToken:    res.Credentials.SessionToken,
+		Expires:         res.Credentials.Expiration,
+	}, nil
+}
+
+// ChainProvider returns credentials of the first provider without ErrNoCredentials
+type ChainProvider []ICredentialsProvider
+
+func (c ChainProvider) Retrieve(ctx context.Context) (Credentials, error) {
+
+	for _, p := range c {
+		retval, err := p.Retrieve(ctx)
+		if err == ErrNoCredentials {
+			continue
+		}
+
+		return retval, err
+	}
+
+	return Credentials{}, ErrNoCredentials
+}
+
+// NewDefaultProvider returns the chain of the environment and web identity providers
+func NewDefaultProvider() ICredentialsProvider {
+	return ChainProvider{
+		EnvProvider{},
+		&WebIdentityProvider{},
+	}
+}
@@ -0,0 +1,137 @@
+package msk
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvProvider(t *testing.T) {
+
+	defer setEnv(t, map[string]string{
+		_EnvAccessKeyID:     "",
+		_EnvSecretAccessKey: "",
+		_EnvSessionToken:    "",
+	})()
+
+	_, err := EnvProvider{}.Retrieve(context.Background())
+	require.Equal(t, ErrNoCredentials, err)
+
+	defer setEnv(t, map[string]string{
+		_EnvAccessKeyID:     "key",
+		_EnvSecretAccessKey: "secret",
+		_EnvSessionToken:    "session",
+	})()
+
+	cred, err := EnvProvider{}.Retrieve(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, Credentials{AccessKeyID: "key", SecretAccessKey: "secret", SessionToken: "session"}, cred)
+}
+
+func TestWebIdentityProvider(t *testing.T) {
+
+	f, err := ioutil.TempFile("", "token")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString("web-token")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	tokenFile := f.Name()
+
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "AssumeRoleWithWebIdentity", r.URL.Query().Get("Action"))
+		require.Equal(t, "arn:aws:iam::1:role/test", r.URL.Query().Get("RoleArn"))
+		require.Equal(t, "session-name", r.URL.Query().Get("RoleSessionName"))
+		require.Equal(t, "web-token", r.URL.Query().Get("WebIdentityToken"))
+
+		_, _ = w.Write([]byte(`<AssumeRoleWithWebIdentityResponse>
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>key</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>session</SessionToken>
+      <Expiration>2020-01-02T03:04:05Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`))
+	}))
+	defer svr.Close()
+
+	defer setEnv(t, map[string]string{
+		_EnvRoleARN:            "arn:aws:iam::1:role/test",
+		_EnvRoleSessionName:    "session-name",
+		_EnvWebIdentityTokenFn: tokenFile,
+	})()
+
+	p := &WebIdentityProvider{Endpoint: svr.URL}
+	cred, err := p.Retrieve(context.Background())
+	require.NoError(t, err)
+	require.Equal(t,
+		Credentials{
+			AccessKeyID:     "key",
+			SecretAccessKey: "secret",
+			SessionToken:    "session",
+			Expires:         time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		cred)
+
+	defer setEnv(t, map[string]string{_EnvRoleARN: ""})()
+	_, err = p.Retrieve(context.Background())
+	require.Equal(t, ErrNoCredentials, err)
+}
+
+func TestChainProvider(t *testing.T) {
+
+	defer setEnv(t, map[string]string{
+		_EnvAccessKeyID:        "",
+		_EnvSecretAccessKey:    "",
+		_EnvRoleARN:            "",
+		_EnvWebIdentityTokenFn: "",
+	})()
+
+	_, err := NewDefaultProvider().Retrieve(context.Background())
+	require.Equal(t, ErrNoCredentials, err)
+
+	defer setEnv(t, map[string]string{
+		_EnvAccessKeyID:     "key",
+		_EnvSecretAccessKey: "secret",
+	})()
+
+	cred, err := NewDefaultProvider().Retrieve(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "key", cred.AccessKeyID)
+}
+
+// setEnv sets the environment variables and returns a function to restore them
+func setEnv(t *testing.T, values map[string]string) func() {
+	t.Helper()
+
+	restore := make([]func(), 0, len(values))
+	for k, v := range values {
+		prev, ok := os.LookupEnv(k)
+		require.NoError(t, os.Setenv(k, v))
+
+		restore = append(restore, func(k, prev string, ok bool) func() {
+			return func() {
+				if ok {
+					_ = os.Setenv(k, prev)
+				} else {
+					_ = os.Unsetenv(k)
+				}
+			}
+		}(k, prev, ok))
+	}
+
+	return func() {
+		for _, fn := range restore {
+			fn()
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package msk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Source: https://github.com/aws/aws-msk-iam-sasl-signer-go
+
+const (
+	_Service    = "kafka-cluster"
+	_Action     = "kafka-cluster:Connect"
+	_Algorithm  = "AWS4-HMAC-SHA256"
+	_UserAgent  = "dialog-go-lib"
+	_TokenTTL   = 15 * time.Minute
+	_DateFormat = "20060102"
+	_TimeFormat = "20060102T150405Z"
+)
+
+// SetConfig sets the properties of the SASL/OAUTHBEARER authentication for the MSK IAM
+func SetConfig(cfg *kafka.ConfigMap) error {
+
+	props := kafka.ConfigMap{
+		"security.protocol": "SASL_SSL",
+		"sasl.mechanisms":   "OAUTHBEARER",
+	}
+	for k, v := range props {
+		if err := cfg.SetKey(k, v); err != nil {
+			return errors.Wrapf(err, "set config %s to %v failed", k, v)
+		}
+	}
+
+	return nil
+}
+
+// GenerateAuthToken returns a token of the MSK IAM authentication
+func GenerateAuthToken(ctx context.Context, region string, provider ICredentialsProvider) (kafka.OAuthBearerToken, error) {
+
+	cred, err := provider.Retrieve(ctx)
+	if err != nil {
+		return kafka.OAuthBearerToken{}, errors.Wrap(err, "retrieve aws credentials failed")
+	}
+
+	return newAuthToken(region, cred, time.Now().UTC())
+}
+
+// NewTokenProvider returns the provider of the tokens (see consumer.Config.TokenProvider)
+func NewTokenProvider(region string, provider ICredentialsProvider) func(context.Context) (kafka.OAuthBearerToken, error) {
+
+	return func(ctx context.Context) (kafka.OAuthBearerToken, error) {
+		return GenerateAuthToken(ctx, region, provider)
+	}
+}
+
+// NewTokenRefreshHandler returns the handler of the OAuthBearerTokenRefresh event
+// (see consumer.Config.OnOAuthBearerTokenRefresh)
+func NewTokenRefreshHandler(region string, provider ICredentialsProvider) func(context.Context, *zap.Logger, *kafka.OAuthBearerTokenRefresh, kafka.Handle) {
+
+	return func(ctx context.Context, logger *zap.Logger, _ *kafka.OAuthBearerTokenRefresh, h kafka.Handle) {
+		token, err := GenerateAuthToken(ctx, region, provider)
+		if err == nil {
+			err = h.SetOAuthBearerToken(token)
+		}
+
+		if err != nil {
+			logger.Error("failed to refresh msk iam token", zap.Error(err))
+			if errFailure := h.SetOAuthBearerTokenFailure(err.Error()); errFailure != nil {
+				logger.Error("failed to set token failure", zap.Error(errFailure))
+			}
+		}
+	}
+}
+
+func newAuthToken(region string, cred Credentials, now time.Time) (kafka.OAuthBearerToken, error) {
+
+	if region == "" {
+		return kafka.OAuthBearerToken{}, errors.New("aws region is empty")
+	}
+
+	host := "kafka." + region + ".amazonaws.com"
+	date := now.Format(_DateFormat)
+	scope := strings.Join([]string{date, region, _Service, "aws4_request"}, "/")
+
+	query := url.Values{
+		"Action":              {_Action},
+		"X-Amz-Algorithm":     {_Algorithm},
+		"X-Amz-Credential":    {cred.AccessKeyID + "/" + scope},
+		"X-Amz-Date":          {now.Format(_TimeFormat)},
+		"X-Amz-Expires":       {strconv.Itoa(int(_TokenTTL.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	if cred.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", cred.SessionToken)
+	}
+
+	canonicalQuery := encodeQuery(query)
+	emptyPayloadHash := sha256.Sum256(nil)
+
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		"/",
+		canonicalQuery,
+		"host:" + host + "\n",
+		"host",
+		hex.EncodeToString(emptyPayloadHash[:]),
+	}, "\n")
+
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		_Algorithm,
+		now.Format(_TimeFormat),
+		scope,
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+cred.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, _Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	signedURL := "https://" + host + "/?" + canonicalQuery +
+		"&X-Amz-Signature=" + signature +
+		"&User-Agent=" + url.QueryEscape(_UserAgent)
+
+	return kafka.OAuthBearerToken{
+		TokenValue: base64.RawURLEncoding.EncodeToString([]byte(signedURL)),
+		Expiration: now.Add(_TokenTTL),
+		Principal:  cred.AccessKeyID,
+	}, nil
+}
+
+// encodeQuery encodes the query with sorted keys (RFC 3986)
+func encodeQuery(v url.Values) string {
+
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, val := range v[k] {
+			parts = append(parts, escape(k)+"="+escape(val))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+func escape(val string) string {
+	return strings.Replace(url.QueryEscape(val), "+", "%20", -1)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
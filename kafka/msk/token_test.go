@@ -0,0 +1,66 @@
+package msk
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetConfig(t *testing.T) {
+
+	cfg := &kafka.ConfigMap{"bootstrap.servers": "b1"}
+	require.NoError(t, SetConfig(cfg))
+	require.Equal(t,
+		&kafka.ConfigMap{
+			"bootstrap.servers": "b1",
+			"security.protocol": "SASL_SSL",
+			"sasl.mechanisms":   "OAUTHBEARER",
+		},
+		cfg)
+}
+
+func TestNewAuthToken(t *testing.T) {
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	cred := Credentials{AccessKeyID: "key", SecretAccessKey: "secret", SessionToken: "session"}
+
+	_, err := newAuthToken("", cred, now)
+	require.EqualError(t, err, "aws region is empty")
+
+	token, err := newAuthToken("us-east-1", cred, now)
+	require.NoError(t, err)
+	require.Equal(t, "key", token.Principal)
+	require.Equal(t, now.Add(15*time.Minute), token.Expiration)
+
+	rawURL, err := base64.RawURLEncoding.DecodeString(token.TokenValue)
+	require.NoError(t, err)
+
+	u, err := url.Parse(string(rawURL))
+	require.NoError(t, err)
+	require.Equal(t, "kafka.us-east-1.amazonaws.com", u.Host)
+
+	q := u.Query()
+	require.Equal(t, "kafka-cluster:Connect", q.Get("Action"))
+	require.Equal(t, "AWS4-HMAC-SHA256", q.Get("X-Amz-Algorithm"))
+	require.Equal(t, "key/20200102/us-east-1/kafka-cluster/aws4_request", q.Get("X-Amz-Credential"))
+	require.Equal(t, "20200102T030405Z", q.Get("X-Amz-Date"))
+	require.Equal(t, "900", q.Get("X-Amz-Expires"))
+	require.Equal(t, "host", q.Get("X-Amz-SignedHeaders"))
+	require.Equal(t, "session", q.Get("X-Amz-Security-Token"))
+	require.Len(t, q.Get("X-Amz-Signature"), 64)
+
+	// the signature is deterministic
+	other, err := newAuthToken("us-east-1", cred, now)
+	require.NoError(t, err)
+	require.Equal(t, token, other)
+}
+
+func TestEncodeQuery(t *testing.T) {
+	require.Equal(t,
+		"a=1%202&b=%2F~-_.",
+		encodeQuery(url.Values{"b": {"/~-_."}, "a": {"1 2"}}))
+}
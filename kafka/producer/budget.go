@@ -0,0 +1,38 @@
+package producer
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/budget"
+)
+
+// A BudgetProducer sets the deadline of the context to the budget.Header header of the message
+type BudgetProducer struct {
+	Producer
+}
+
+// NewBudgetProducer wraps the producer
+func NewBudgetProducer(p Producer) *BudgetProducer {
+	return &BudgetProducer{Producer: p}
+}
+
+// Produce produces the message with the deadline header
+func (p *BudgetProducer) Produce(ctx context.Context, msg *kafka.Message) error {
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return p.Producer.Produce(ctx, msg)
+	}
+
+	retval := *msg
+	retval.Headers = make([]kafka.Header, 0, len(msg.Headers)+1)
+	for _, h := range msg.Headers {
+		if h.Key != budget.Header {
+			retval.Headers = append(retval.Headers, h)
+		}
+	}
+	retval.Headers = append(retval.Headers, kafka.Header{Key: budget.Header, Value: []byte(budget.Format(deadline))})
+
+	return p.Producer.Produce(ctx, &retval)
+}
@@ -0,0 +1,46 @@
+package producer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/budget"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitBudgetProducer(t *testing.T) {
+
+	p := &testProducer{}
+	budgeted := NewBudgetProducer(p)
+
+	topic := "a"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Value:          []byte("value"),
+		Headers: []kafka.Header{
+			{Key: "h", Value: []byte("1")},
+			{Key: budget.Header, Value: []byte("1")},
+		},
+	}
+
+	// the context without the deadline
+	require.NoError(t, budgeted.Produce(context.Background(), msg))
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Unix(1005, 0))
+	defer cancel()
+	require.NoError(t, budgeted.Produce(ctx, msg))
+
+	require.Len(t, p.msgs, 2)
+	require.Equal(t, msg, p.msgs[0])
+	require.Equal(t,
+		[]kafka.Header{
+			{Key: "h", Value: []byte("1")},
+			{Key: budget.Header, Value: []byte("1005000")},
+		},
+		p.msgs[1].Headers)
+
+	// the source message isn't modified
+	require.Equal(t, []byte("1"), msg.Headers[1].Value)
+}
@@ -0,0 +1,205 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+)
+
+const _TopicConfigMaxMessageBytes = "max.message.bytes"
+
+var (
+	// ErrInvalidMessage is returned by Builder.Build if the message is invalid
+	ErrInvalidMessage = errors.New("invalid message")
+	// ErrMessageTooLarge is returned by Builder.Build if the message size exceeds the topic limit
+	ErrMessageTooLarge = errors.New("message too large")
+)
+
+// A MessageTooLargeError is returned if the message size exceeds the topic limit (see ErrMessageTooLarge)
+type MessageTooLargeError struct {
+	Topic string
+	Size  int
+	Limit int
+}
+
+func (e *MessageTooLargeError) Error() string {
+	return ErrMessageTooLarge.Error() + ": topic " + e.Topic +
+		": size " + strconv.Itoa(e.Size) + " > " + strconv.Itoa(e.Limit)
+}
+
+// Is reports that the error is ErrMessageTooLarge (errors.Is implementation)
+func (e *MessageTooLargeError) Is(target error) bool {
+	return target == ErrMessageTooLarge
+}
+
+// ICodec encodes the value of the message
+type ICodec interface {
+	Encode(v interface{}) ([]byte, error)
+}
+
+// FuncCodec is an adapter of the function to ICodec
+type FuncCodec func(v interface{}) ([]byte, error)
+
+// Encode calls the function
+func (fn FuncCodec) Encode(v interface{}) ([]byte, error) {
+	return fn(v)
+}
+
+// JSONCodec encodes the value as JSON
+var JSONCodec ICodec = FuncCodec(json.Marshal)
+
+// Limits are the max sizes of the messages per topic (see FetchLimits)
+type Limits map[string]int
+
+// FetchLimits reads the 'max.message.bytes' property of the topics
+func FetchLimits(ctx context.Context, admin *kafka.AdminClient, topics ...string) (Limits, error) {
+
+	resources := make([]kafka.ConfigResource, 0, len(topics))
+	for _, topic := range topics {
+		resources = append(resources, kafka.ConfigResource{Type: kafka.ResourceTopic, Name: topic})
+	}
+
+	results, err := admin.DescribeConfigs(ctx, resources)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to describe topics config")
+	}
+
+	retval := make(Limits, len(results))
+	for _, res := range results {
+		if res.Error.Code() != kafka.ErrNoError {
+			return nil, errors.Wrapf(res.Error, "failed to describe topic %s config", res.Name)
+		}
+
+		entry, ok := res.Config[_TopicConfigMaxMessageBytes]
+		if !ok {
+			continue
+		}
+
+		limit, err := strconv.Atoi(entry.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s of topic %s", _TopicConfigMaxMessageBytes, res.Name)
+		}
+
+		retval[res.Name] = limit
+	}
+
+	return retval, nil
+}
+
+// A Builder creates the message and validates it before producing
+type Builder struct {
+	msg    kafka.Message
+	limits Limits
+	err    error
+}
+
+// NewMessage returns the builder of the message to the topic (any partition by default)
+func NewMessage(topic string) *Builder {
+	return &Builder{
+		msg: kafka.Message{
+			TopicPartition: kafka.TopicPartition{
+				Topic:     &topic,
+				Partition: kafka.PartitionAny,
+			},
+		},
+	}
+}
+
+// Key sets the key of the message
+func (b *Builder) Key(val []byte) *Builder {
+	b.msg.Key = val
+	return b
+}
+
+// KeyString sets the key of the message
+func (b *Builder) KeyString(val string) *Builder {
+	b.msg.Key = []byte(val)
+	return b
+}
+
+// Value sets the value of the message
+func (b *Builder) Value(val []byte) *Builder {
+	b.msg.Value = val
+	return b
+}
+
+// Encode sets the value of the message encoded by the codec
+func (b *Builder) Encode(codec ICodec, v interface{}) *Builder {
+	val, err := codec.Encode(v)
+	if err != nil && b.err == nil {
+		b.err = errors.Wrap(err, "failed to encode value")
+	}
+
+	b.msg.Value = val
+	return b
+}
+
+// Header adds the header of the message
+func (b *Builder) Header(key string, val []byte) *Builder {
+	b.msg.Headers = append(b.msg.Headers, kafka.Header{Key: key, Value: val})
+	return b
+}
+
+// Timestamp sets the time of the message (the producer time by default)
+func (b *Builder) Timestamp(val time.Time) *Builder {
+	b.msg.Timestamp = val
+	return b
+}
+
+// Partition sets the partition of the message
+func (b *Builder) Partition(val int32) *Builder {
+	b.msg.TopicPartition.Partition = val
+	return b
+}
+
+// Limits sets the limits of the message size (see FetchLimits)
+func (b *Builder) Limits(val Limits) *Builder {
+	b.limits = val
+	return b
+}
+
+// Build validates and returns the message
+func (b *Builder) Build() (*kafka.Message, error) {
+
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	topic := *b.msg.TopicPartition.Topic
+	if topic == "" {
+		return nil, errors.Wrap(ErrInvalidMessage, "topic is empty")
+	}
+
+	if p := b.msg.TopicPartition.Partition; p < 0 && p != kafka.PartitionAny {
+		return nil, errors.Wrapf(ErrInvalidMessage, "invalid partition %d", p)
+	}
+
+	for _, h := range b.msg.Headers {
+		if h.Key == "" {
+			return nil, errors.Wrap(ErrInvalidMessage, "header key is empty")
+		}
+	}
+
+	if limit, ok := b.limits[topic]; ok {
+		if size := MessageSize(&b.msg); size > limit {
+			return nil, &MessageTooLargeError{Topic: topic, Size: size, Limit: limit}
+		}
+	}
+
+	msg := b.msg
+	return &msg, nil
+}
+
+// MessageSize returns the size of the key, value and headers of the message
+func MessageSize(msg *kafka.Message) int {
+	size := len(msg.Key) + len(msg.Value)
+	for _, h := range msg.Headers {
+		size += len(h.Key) + len(h.Value)
+	}
+
+	return size
+}
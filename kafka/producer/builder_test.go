@@ -0,0 +1,65 @@
+package producer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+
+	topic := "t1"
+	now := time.Now()
+
+	msg, err := NewMessage(topic).
+		KeyString("key").
+		Encode(JSONCodec, map[string]int{"a": 1}).
+		Header("h1", []byte("v1")).
+		Timestamp(now).
+		Partition(2).
+		Limits(Limits{topic: 100}).
+		Build()
+	require.NoError(t, err)
+	require.Equal(t, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2},
+		Key:            []byte("key"),
+		Value:          []byte(`{"a":1}`),
+		Headers:        []kafka.Header{{Key: "h1", Value: []byte("v1")}},
+		Timestamp:      now,
+	}, msg)
+	require.Equal(t, 14, MessageSize(msg))
+
+	msg, err = NewMessage(topic).Key([]byte("key")).Value([]byte("value")).Build()
+	require.NoError(t, err)
+	require.Equal(t, kafka.PartitionAny, msg.TopicPartition.Partition)
+}
+
+func TestBuilderErrors(t *testing.T) {
+
+	_, err := NewMessage("").Build()
+	require.True(t, errors.Is(err, ErrInvalidMessage))
+
+	_, err = NewMessage("t1").Partition(-2).Build()
+	require.True(t, errors.Is(err, ErrInvalidMessage))
+
+	_, err = NewMessage("t1").Header("", nil).Build()
+	require.True(t, errors.Is(err, ErrInvalidMessage))
+
+	_, err = NewMessage("t1").Encode(JSONCodec, func() {}).Build()
+	require.Error(t, err)
+
+	_, err = NewMessage("t1").Value(make([]byte, 11)).Limits(Limits{"t1": 10}).Build()
+	require.True(t, errors.Is(err, ErrMessageTooLarge))
+	require.EqualError(t, err, "message too large: topic t1: size 11 > 10")
+
+	var tooLarge *MessageTooLargeError
+	require.True(t, errors.As(err, &tooLarge))
+	require.Equal(t, &MessageTooLargeError{Topic: "t1", Size: 11, Limit: 10}, tooLarge)
+
+	// the topic without limit
+	_, err = NewMessage("t2").Value(make([]byte, 11)).Limits(Limits{"t1": 10}).Build()
+	require.NoError(t, err)
+}
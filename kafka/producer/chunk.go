@@ -0,0 +1,112 @@
+package producer
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// The headers of the chunks (see consumer.Config.Reassemble)
+const (
+	HeaderChunkID    = "chunk-id"
+	HeaderChunkIndex = "chunk-index"
+	HeaderChunkCount = "chunk-count"
+)
+
+const _DefaultMaxChunks = 16
+
+// ErrTooManyChunks is returned if the message requires more chunks than the limit
+var ErrTooManyChunks = errors.New("too many chunks")
+
+// ChunkConfig is a configuration of the chunking
+type ChunkConfig struct {
+	// Size is a max size of the value of the chunk
+	Size int
+	// MaxChunks is a max count of the chunks of the message (16 by default)
+	MaxChunks int
+}
+
+// A ChunkingProducer splits the values larger than the chunk size into several messages
+type ChunkingProducer struct {
+	Producer
+	size      int
+	maxChunks int
+}
+
+// NewChunkingProducer wraps the producer
+func NewChunkingProducer(p Producer, cfg ChunkConfig) (*ChunkingProducer, error) {
+
+	if cfg.Size <= 0 {
+		return nil, errors.New("chunk size must be positive")
+	}
+	if cfg.MaxChunks <= 0 {
+		cfg.MaxChunks = _DefaultMaxChunks
+	}
+
+	return &ChunkingProducer{
+		Producer:  p,
+		size:      cfg.Size,
+		maxChunks: cfg.MaxChunks,
+	}, nil
+}
+
+// Produce produces the chunks of the message one by one
+func (p *ChunkingProducer) Produce(ctx context.Context, msg *kafka.Message) error {
+
+	chunks, err := Chunk(msg, p.size, p.maxChunks)
+	if err != nil {
+		return err
+	}
+
+	for i, chunk := range chunks {
+		if err := p.Producer.Produce(ctx, chunk); err != nil {
+			return errors.Wrapf(err, "failed to produce chunk %d of %d", i+1, len(chunks))
+		}
+	}
+
+	return nil
+}
+
+// Chunk splits the value of the message into the chunks of the size
+func Chunk(msg *kafka.Message, size, maxChunks int) ([]*kafka.Message, error) {
+
+	if len(msg.Value) <= size {
+		return []*kafka.Message{msg}, nil
+	}
+
+	count := (len(msg.Value) + size - 1) / size
+	if count > maxChunks {
+		return nil, errors.Wrapf(ErrTooManyChunks, "%d bytes require %d chunks (max %d)", len(msg.Value), count, maxChunks)
+	}
+
+	id := uuid.New().String()
+
+	key := msg.Key
+	if len(key) == 0 {
+		key = []byte(id)
+	}
+
+	retval := make([]*kafka.Message, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(msg.Value) {
+			end = len(msg.Value)
+		}
+
+		chunk := *msg
+		chunk.Key = key
+		chunk.Value = msg.Value[i*size : end]
+		chunk.Headers = append(append(make([]kafka.Header, 0, len(msg.Headers)+3), msg.Headers...),
+			kafka.Header{Key: HeaderChunkID, Value: []byte(id)},
+			kafka.Header{Key: HeaderChunkIndex, Value: []byte(strconv.Itoa(i))},
+			kafka.Header{Key: HeaderChunkCount, Value: []byte(strconv.Itoa(count))},
+		)
+
+		retval = append(retval, &chunk)
+	}
+
+	return retval, nil
+}
@@ -0,0 +1,77 @@
+package producer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitChunk(t *testing.T) {
+
+	topic := "a"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Value:          []byte("0123456789"),
+		Headers:        []kafka.Header{{Key: "h", Value: []byte("1")}},
+	}
+
+	chunks, err := Chunk(msg, 10, 2)
+	require.NoError(t, err)
+	require.Equal(t, []*kafka.Message{msg}, chunks)
+
+	chunks, err = Chunk(msg, 4, 3)
+	require.NoError(t, err)
+	require.Len(t, chunks, 3)
+
+	id := string(chunks[0].Headers[1].Value)
+	for i, chunk := range chunks {
+		require.Equal(t, []byte(id), chunk.Key)
+		require.Equal(t,
+			[]kafka.Header{
+				{Key: "h", Value: []byte("1")},
+				{Key: HeaderChunkID, Value: []byte(id)},
+				{Key: HeaderChunkIndex, Value: []byte(string(rune('0' + i)))},
+				{Key: HeaderChunkCount, Value: []byte("3")},
+			},
+			chunk.Headers)
+	}
+	require.Equal(t, "0123", string(chunks[0].Value))
+	require.Equal(t, "4567", string(chunks[1].Value))
+	require.Equal(t, "89", string(chunks[2].Value))
+	require.Len(t, msg.Headers, 1)
+
+	_, err = Chunk(msg, 4, 2)
+	require.True(t, errors.Is(err, ErrTooManyChunks))
+	require.EqualError(t, err, "10 bytes require 3 chunks (max 2): too many chunks")
+}
+
+func TestUnitChunkingProducer(t *testing.T) {
+
+	_, err := NewChunkingProducer(&testProducer{}, ChunkConfig{})
+	require.EqualError(t, err, "chunk size must be positive")
+
+	p := &testProducer{}
+	chunking, err := NewChunkingProducer(p, ChunkConfig{Size: 3})
+	require.NoError(t, err)
+
+	topic := "a"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte("k"),
+		Value:          []byte("abcdefg"),
+	}
+	require.NoError(t, chunking.Produce(context.Background(), msg))
+	require.Equal(t, []string{"abc", "def", "g"}, p.Values())
+
+	p.SetError(errors.New("failed"))
+	err = chunking.Produce(context.Background(), msg)
+	require.EqualError(t, err, "failed to produce chunk 1 of 3: failed")
+
+	msg.Value = []byte(strings.Repeat("x", 3*17))
+	err = chunking.Produce(context.Background(), msg)
+	require.True(t, errors.Is(err, ErrTooManyChunks))
+}
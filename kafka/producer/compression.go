@@ -0,0 +1,76 @@
+package producer
+
+import (
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	libkafka "github.com/dialogs/dialog-go-lib/kafka"
+	"github.com/pkg/errors"
+)
+
+// Compression is a codec of the messages ('compression.codec' property)
+type Compression string
+
+// The codecs of librdkafka
+const (
+	CompressionNone   Compression = "none"
+	CompressionGzip   Compression = "gzip"
+	CompressionSnappy Compression = "snappy"
+	CompressionLZ4    Compression = "lz4"
+	CompressionZstd   Compression = "zstd"
+)
+
+// ErrUnsupportedCompression is returned if the codec isn't supported by librdkafka
+var ErrUnsupportedCompression = errors.New("unsupported compression codec")
+
+var _CompressionProperties = []string{"compression.codec", "compression.type"}
+
+// SetCompression validates and sets the codec of the producer config
+func SetCompression(config *kafka.ConfigMap, codec Compression) error {
+
+	if err := checkCompression(codec, libkafka.Features()); err != nil {
+		return err
+	}
+
+	return config.SetKey(_CompressionProperties[0], string(codec))
+}
+
+func validateCompression(config *kafka.ConfigMap, features libkafka.FeatureSet) error {
+
+	for _, name := range _CompressionProperties {
+		val, err := config.Get(name, "")
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s", name)
+		}
+
+		str, ok := val.(string)
+		if !ok {
+			return errors.Errorf("invalid %s: %v", name, val)
+		}
+
+		if str != "" {
+			if err := checkCompression(Compression(str), features); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkCompression(codec Compression, features libkafka.FeatureSet) error {
+
+	switch Compression(strings.ToLower(string(codec))) {
+	case CompressionNone, CompressionGzip, CompressionSnappy, CompressionLZ4:
+		return nil
+
+	case CompressionZstd:
+		if !features.Zstd {
+			return errors.Wrapf(ErrUnsupportedCompression, "%s requires librdkafka 1.1.0+ (linked %s)", codec, features.Version)
+		}
+		return nil
+
+	default:
+		return errors.Wrap(ErrUnsupportedCompression, string(codec))
+	}
+}
@@ -0,0 +1,40 @@
+package producer
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	libkafka "github.com/dialogs/dialog-go-lib/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitCompression(t *testing.T) {
+
+	features := libkafka.FeatureSet{Version: "1.0.0"}
+
+	require.NoError(t, checkCompression(CompressionGzip, features))
+	require.NoError(t, checkCompression("LZ4", features))
+
+	err := checkCompression(CompressionZstd, features)
+	require.True(t, errors.Is(err, ErrUnsupportedCompression))
+	require.EqualError(t, err, "zstd requires librdkafka 1.1.0+ (linked 1.0.0): unsupported compression codec")
+
+	features.Zstd = true
+	require.NoError(t, checkCompression(CompressionZstd, features))
+
+	require.EqualError(t, checkCompression("brotli", features), "brotli: unsupported compression codec")
+
+	require.NoError(t, validateCompression(&kafka.ConfigMap{}, features))
+	require.NoError(t, validateCompression(&kafka.ConfigMap{"compression.type": "zstd"}, features))
+	require.EqualError(t,
+		validateCompression(&kafka.ConfigMap{"compression.codec": "brotli"}, features),
+		"brotli: unsupported compression codec")
+
+	config := &kafka.ConfigMap{}
+	require.NoError(t, SetCompression(config, CompressionSnappy))
+	require.Equal(t, kafka.ConfigValue("snappy"), (*config)["compression.codec"])
+
+	_, err = NewSyncProducerWithOptions(&kafka.ConfigMap{"compression.codec": "brotli"})
+	require.EqualError(t, err, "brotli: unsupported compression codec")
+}
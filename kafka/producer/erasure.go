@@ -0,0 +1,231 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+)
+
+const (
+	_TopicConfigCleanupPolicy = "cleanup.policy"
+	_CleanupPolicyCompact     = "compact"
+	_ErasureReadTimeout       = 100 * time.Millisecond
+)
+
+// ErrNotCompacted is returned if the topic has no 'compact' cleanup policy
+var ErrNotCompacted = errors.New("topic is not compacted")
+
+// BatchProducer produces the messages and returns the delivery reports (see SyncProducer)
+type BatchProducer interface {
+	ProduceSync(ctx context.Context, msgs ...*kafka.Message) ([]Result, error)
+}
+
+// An ErasureRecord is an audit record of the tombstone
+type ErasureRecord struct {
+	Topic     string    `json:"topic"`
+	Partition int32     `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Key       []byte    `json:"key"`
+	Time      time.Time `json:"time"`
+	Err       string    `json:"error,omitempty"`
+}
+
+// FuncAudit saves the audit record of the erasure
+type FuncAudit func(ctx context.Context, rec ErasureRecord) error
+
+// JSONAudit writes the audit records to the writer (one JSON object per line)
+func JSONAudit(w io.Writer) FuncAudit {
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+
+	return func(_ context.Context, rec ErasureRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return enc.Encode(rec)
+	}
+}
+
+// CheckCompacted returns ErrNotCompacted if any of the topics has no 'compact' cleanup policy
+func CheckCompacted(ctx context.Context, admin *kafka.AdminClient, topics ...string) error {
+
+	resources := make([]kafka.ConfigResource, 0, len(topics))
+	for _, topic := range topics {
+		resources = append(resources, kafka.ConfigResource{Type: kafka.ResourceTopic, Name: topic})
+	}
+
+	results, err := admin.DescribeConfigs(ctx, resources)
+	if err != nil {
+		return errors.Wrap(err, "failed to describe topics config")
+	}
+
+	for _, res := range results {
+		if res.Error.Code() != kafka.ErrNoError {
+			return errors.Wrapf(res.Error, "failed to describe topic %s config", res.Name)
+		}
+
+		entry := res.Config[_TopicConfigCleanupPolicy]
+		if !strings.Contains(entry.Value, _CleanupPolicyCompact) {
+			return errors.Wrap(ErrNotCompacted, res.Name)
+		}
+	}
+
+	return nil
+}
+
+// An Eraser produces the tombstones of the keys to the compacted topics and writes them to the audit trail
+type Eraser struct {
+	producer BatchProducer
+	audit    FuncAudit
+}
+
+// NewEraser returns the eraser (audit is optional)
+func NewEraser(p BatchProducer, audit FuncAudit) *Eraser {
+	return &Eraser{
+		producer: p,
+		audit:    audit,
+	}
+}
+
+// Erase produces the tombstones of the keys to every topic and returns the audit records
+func (e *Eraser) Erase(ctx context.Context, topics []string, keys ...[]byte) ([]ErasureRecord, error) {
+
+	msgs := make([]*kafka.Message, 0, len(topics)*len(keys))
+	for i := range topics {
+		for _, key := range keys {
+			msgs = append(msgs, &kafka.Message{
+				TopicPartition: kafka.TopicPartition{Topic: &topics[i], Partition: kafka.PartitionAny},
+				Key:            key,
+			})
+		}
+	}
+
+	results, produceErr := e.producer.ProduceSync(ctx, msgs...)
+
+	retval := make([]ErasureRecord, 0, len(results))
+	for i, res := range results {
+		rec := ErasureRecord{
+			Topic:     *msgs[i].TopicPartition.Topic,
+			Partition: res.TopicPartition.Partition,
+			Offset:    int64(res.TopicPartition.Offset),
+			Key:       msgs[i].Key,
+			Time:      time.Now(),
+		}
+		if res.Err != nil {
+			rec.Err = res.Err.Error()
+		}
+
+		if e.audit != nil {
+			if err := e.audit(ctx, rec); err != nil {
+				return nil, errors.Wrap(err, "failed to save audit record")
+			}
+		}
+
+		if res.Err == nil {
+			retval = append(retval, rec)
+		}
+	}
+
+	if produceErr != nil {
+		return retval, errors.Wrap(produceErr, "failed to produce tombstones")
+	}
+
+	return retval, nil
+}
+
+// ErasureReader reads the partitions of the topics (see kafka.Consumer)
+type ErasureReader interface {
+	Assign(partitions []kafka.TopicPartition) error
+	ReadMessage(timeout time.Duration) (*kafka.Message, error)
+}
+
+// An ErasureStatus is a progress of the compaction of the erased key
+type ErasureStatus struct {
+	ErasureRecord
+	// Remaining is a count of the values of the key before the tombstone
+	Remaining int
+}
+
+// Compacted returns true if the values of the key were removed by the compaction
+func (s ErasureStatus) Compacted() bool {
+	return s.Remaining == 0
+}
+
+// Verify counts the values of the erased keys which aren't removed by the compaction yet
+func Verify(ctx context.Context, r ErasureReader, records []ErasureRecord) ([]ErasureStatus, error) {
+
+	type partition struct {
+		topic string
+		id    int32
+	}
+
+	retval := make([]ErasureStatus, len(records))
+	index := make(map[partition]map[string][]int)
+	end := make(map[partition]int64)
+
+	for i, rec := range records {
+		retval[i].ErasureRecord = rec
+
+		p := partition{topic: rec.Topic, id: rec.Partition}
+		if index[p] == nil {
+			index[p] = make(map[string][]int)
+		}
+		index[p][string(rec.Key)] = append(index[p][string(rec.Key)], i)
+
+		if last, ok := end[p]; !ok || rec.Offset > last {
+			end[p] = rec.Offset
+		}
+	}
+
+	assignment := make([]kafka.TopicPartition, 0, len(index))
+	for p := range index {
+		topic := p.topic
+		assignment = append(assignment, kafka.TopicPartition{
+			Topic:     &topic,
+			Partition: p.id,
+			Offset:    kafka.OffsetBeginning,
+		})
+	}
+
+	if err := r.Assign(assignment); err != nil {
+		return nil, errors.Wrap(err, "failed to assign partitions")
+	}
+
+	for len(end) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		msg, err := r.ReadMessage(_ErasureReadTimeout)
+		if err != nil {
+			if isErrorCode(err, kafka.ErrTimedOut) {
+				continue
+			}
+			return nil, errors.Wrap(err, "failed to read message")
+		}
+
+		p := partition{topic: *msg.TopicPartition.Topic, id: msg.TopicPartition.Partition}
+		offset := int64(msg.TopicPartition.Offset)
+
+		if msg.Value != nil {
+			for _, i := range index[p][string(msg.Key)] {
+				if offset < retval[i].Offset {
+					retval[i].Remaining++
+				}
+			}
+		}
+
+		if last, ok := end[p]; ok && offset >= last {
+			delete(end, p)
+		}
+	}
+
+	return retval, nil
+}
@@ -0,0 +1,158 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+)
+
+type testBatchProducer struct {
+	offset kafka.Offset
+	fail   string
+}
+
+func (p *testBatchProducer) ProduceSync(_ context.Context, msgs ...*kafka.Message) ([]Result, error) {
+
+	retval := make([]Result, len(msgs))
+
+	var err error
+	for i, msg := range msgs {
+		retval[i].TopicPartition = msg.TopicPartition
+		retval[i].TopicPartition.Partition = 0
+		if string(msg.Key) == p.fail {
+			retval[i].Err = errors.New("delivery error")
+			err = retval[i].Err
+			continue
+		}
+
+		retval[i].TopicPartition.Offset = p.offset
+		p.offset++
+	}
+
+	return retval, err
+}
+
+type testErasureReader struct {
+	assigned []kafka.TopicPartition
+	msgs     []*kafka.Message
+}
+
+func (r *testErasureReader) Assign(partitions []kafka.TopicPartition) error {
+	r.assigned = partitions
+	return nil
+}
+
+func (r *testErasureReader) ReadMessage(time.Duration) (*kafka.Message, error) {
+	if len(r.msgs) == 0 {
+		return nil, kafka.NewError(kafka.ErrTimedOut, "timed out", false)
+	}
+
+	msg := r.msgs[0]
+	r.msgs = r.msgs[1:]
+
+	return msg, nil
+}
+
+func TestUnitEraserErase(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	eraser := NewEraser(&testBatchProducer{offset: 10, fail: "k2"}, JSONAudit(buf))
+
+	records, err := eraser.Erase(context.Background(), []string{"a", "b"}, []byte("k1"), []byte("k2"))
+	require.EqualError(t, err, "failed to produce tombstones: delivery error")
+	require.Len(t, records, 2)
+
+	require.Equal(t, "a", records[0].Topic)
+	require.Equal(t, []byte("k1"), records[0].Key)
+	require.Equal(t, int64(10), records[0].Offset)
+	require.Equal(t, "b", records[1].Topic)
+	require.Equal(t, int64(11), records[1].Offset)
+
+	// audit trail contains the failed tombstones too
+	dec := json.NewDecoder(buf)
+	var audit []ErasureRecord
+	for dec.More() {
+		var rec ErasureRecord
+		require.NoError(t, dec.Decode(&rec))
+		audit = append(audit, rec)
+	}
+	require.Len(t, audit, 4)
+	require.Equal(t, []byte("k2"), audit[1].Key)
+	require.Equal(t, "delivery error", audit[1].Err)
+	require.Empty(t, audit[0].Err)
+}
+
+func TestUnitEraserAuditError(t *testing.T) {
+
+	eraser := NewEraser(&testBatchProducer{}, func(context.Context, ErasureRecord) error {
+		return errors.New("audit error")
+	})
+
+	_, err := eraser.Erase(context.Background(), []string{"a"}, []byte("k1"))
+	require.EqualError(t, err, "failed to save audit record: audit error")
+}
+
+func TestUnitVerify(t *testing.T) {
+
+	topic := "a"
+	message := func(offset int, key, value string) *kafka.Message {
+		msg := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(offset)},
+			Key:            []byte(key),
+		}
+		if value != "" {
+			msg.Value = []byte(value)
+		}
+		return msg
+	}
+
+	reader := &testErasureReader{
+		msgs: []*kafka.Message{
+			message(0, "k1", "v1"),
+			message(1, "k2", "v2"),
+			message(2, "k1", "v1"),
+			message(3, "k2", ""),
+			message(4, "k1", ""),
+			// after the tombstone
+			message(5, "k1", "v1"),
+		},
+	}
+
+	records := []ErasureRecord{
+		{Topic: "a", Partition: 0, Offset: 4, Key: []byte("k1")},
+		{Topic: "a", Partition: 0, Offset: 3, Key: []byte("k2")},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	status, err := Verify(ctx, reader, records)
+	require.NoError(t, err)
+	require.Len(t, status, 2)
+	require.Equal(t, 2, status[0].Remaining)
+	require.False(t, status[0].Compacted())
+	require.Equal(t, 1, status[1].Remaining)
+
+	require.Len(t, reader.assigned, 1)
+	require.Equal(t, kafka.OffsetBeginning, reader.assigned[0].Offset)
+
+	// compacted
+	reader.msgs = []*kafka.Message{message(3, "k2", ""), message(4, "k1", "")}
+	status, err = Verify(ctx, reader, records)
+	require.NoError(t, err)
+	require.True(t, status[0].Compacted())
+	require.True(t, status[1].Compacted())
+
+	// the tombstone is not reached
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = Verify(ctx, reader, records)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
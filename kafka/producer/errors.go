@@ -0,0 +1,68 @@
+package producer
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrProduceFailed is returned if the message isn't produced
+	ErrProduceFailed = errors.New("produce message failed")
+	// ErrUnknownEvent is returned if the delivery report has an unexpected type
+	ErrUnknownEvent = errors.New("unknown produce event")
+	// ErrTimeout is returned if the context is done before the delivery report
+	ErrTimeout = errors.New("produce message timeout")
+)
+
+// A DeliveryError is an error of the delivery report of the message (see ErrProduceFailed)
+type DeliveryError struct {
+	TopicPartition kafka.TopicPartition
+	Err            error
+}
+
+func (e *DeliveryError) Error() string {
+	return ErrProduceFailed.Error() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause (errors.Unwrap implementation)
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports that the error is ErrProduceFailed (errors.Is implementation)
+func (e *DeliveryError) Is(target error) bool {
+	return target == ErrProduceFailed
+}
+
+// A TimeoutError is returned if the context is done before the delivery report (see MayBeDelivered)
+type TimeoutError struct {
+	TopicPartition kafka.TopicPartition
+	// NotQueued reports that the message isn't queued because the local queue is full
+	NotQueued bool
+	Err       error
+}
+
+func (e *TimeoutError) Error() string {
+	return ErrTimeout.Error() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the context error (errors.Unwrap implementation)
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports that the error is ErrTimeout (errors.Is implementation)
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// MayBeDelivered reports that the message of the failed produce can still be delivered
+func MayBeDelivered(err error) bool {
+	var timeoutErr *TimeoutError
+	return errors.As(err, &timeoutErr) && !timeoutErr.NotQueued
+}
+
+func isErrorCode(err error, code kafka.ErrorCode) bool {
+	kafkaErr, ok := err.(kafka.Error)
+	return ok && kafkaErr.Code() == code
+}
@@ -0,0 +1,53 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryError(t *testing.T) {
+
+	topic := "t1"
+	cause := kafka.NewError(kafka.ErrMsgSizeTooLarge, "too large", false)
+
+	err := errors.Wrap(&DeliveryError{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 1},
+		Err:            cause,
+	}, "send")
+	require.EqualError(t, err, "send: produce message failed: too large")
+	require.True(t, errors.Is(err, ErrProduceFailed))
+	require.False(t, errors.Is(err, ErrUnknownEvent))
+
+	var deliveryErr *DeliveryError
+	require.True(t, errors.As(err, &deliveryErr))
+	require.Equal(t, int32(1), deliveryErr.TopicPartition.Partition)
+
+	var kafkaErr kafka.Error
+	require.True(t, errors.As(err, &kafkaErr))
+	require.Equal(t, kafka.ErrMsgSizeTooLarge, kafkaErr.Code())
+}
+
+func TestTimeoutError(t *testing.T) {
+
+	topic := "t1"
+	var err error = &TimeoutError{
+		TopicPartition: kafka.TopicPartition{Topic: &topic},
+		NotQueued:      true,
+		Err:            context.DeadlineExceeded,
+	}
+
+	require.EqualError(t, err, "produce message timeout: context deadline exceeded")
+	require.True(t, errors.Is(err, ErrTimeout))
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.False(t, errors.Is(err, ErrProduceFailed))
+
+	// the message isn't queued: it's never delivered
+	require.False(t, MayBeDelivered(err))
+	require.True(t, MayBeDelivered(errors.Wrap(&TimeoutError{Err: context.Canceled}, "send")))
+	require.False(t, MayBeDelivered(context.Canceled))
+	require.False(t, MayBeDelivered(&DeliveryError{Err: kafka.NewError(kafka.ErrMsgSizeTooLarge, "", false)}))
+}
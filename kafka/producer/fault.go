@@ -0,0 +1,36 @@
+package producer
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// IFaultInjector delays the call or returns the error (see fault.Injector)
+type IFaultInjector interface {
+	Inject(ctx context.Context) error
+}
+
+// FaultyProducer injects the faults before producing (non-prod environments only)
+type FaultyProducer struct {
+	Producer
+	injector IFaultInjector
+}
+
+// NewFaultyProducer wraps the producer
+func NewFaultyProducer(p Producer, injector IFaultInjector) *FaultyProducer {
+	return &FaultyProducer{
+		Producer: p,
+		injector: injector,
+	}
+}
+
+// Produce injects the fault and produces the message
+func (p *FaultyProducer) Produce(ctx context.Context, msg *kafka.Message) error {
+
+	if err := p.injector.Inject(ctx); err != nil {
+		return err
+	}
+
+	return p.Producer.Produce(ctx, msg)
+}
@@ -0,0 +1,25 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/fault"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitFaultyProducer(t *testing.T) {
+
+	p := &testProducer{}
+	topic := "a"
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}, Value: []byte("1")}
+
+	faulty := NewFaultyProducer(p, fault.NewInjector(fault.Config{Enabled: true, ErrorRate: 1}))
+	require.Equal(t, fault.ErrInjected, faulty.Produce(context.Background(), msg))
+	require.Len(t, p.Values(), 0)
+
+	faulty = NewFaultyProducer(p, fault.NewInjector(fault.Config{}))
+	require.NoError(t, faulty.Produce(context.Background(), msg))
+	require.Equal(t, []string{"1"}, p.Values())
+}
@@ -0,0 +1,243 @@
+package producer
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/metric"
+	"github.com/pkg/errors"
+)
+
+// A FanOutPolicy defines the result of the produce to the several clusters
+type FanOutPolicy int
+
+const (
+	// PolicyBestEffort requires the delivery to at least one cluster and to all required clusters
+	PolicyBestEffort FanOutPolicy = iota
+	// PolicyAllRequired requires the delivery to all clusters
+	PolicyAllRequired
+)
+
+// FuncClusterCounter returns the counter of the cluster (e.g. prometheus.CounterVec.WithLabelValues)
+type FuncClusterCounter func(cluster string) metric.ICounter
+
+// MultiMetrics of the multi-cluster producer. All fields are optional.
+type MultiMetrics struct {
+	// Delivered is a count of the delivered messages per cluster
+	Delivered FuncClusterCounter
+	// Failed is a count of the not delivered messages per cluster
+	Failed FuncClusterCounter
+}
+
+// ClusterConfig is a configuration of the cluster
+type ClusterConfig struct {
+	// Name of the cluster (e.g. primary, dr)
+	Name      string
+	ConfigMap *kafka.ConfigMap
+	// Required cluster must receive the message regardless of the policy
+	Required bool
+}
+
+// MultiConfig is a configuration of the multi-cluster producer
+type MultiConfig struct {
+	Clusters []ClusterConfig
+	Policy   FanOutPolicy
+	Metrics  *MultiMetrics
+}
+
+// A Cluster is a named producer of the cluster
+type Cluster struct {
+	Name     string
+	Producer Producer
+	Required bool
+}
+
+// A ClusterStats is a snapshot of the delivery statistics of the cluster
+type ClusterStats struct {
+	Name      string
+	Delivered int64
+	Failed    int64
+}
+
+// A FanOutError is returned if the message isn't delivered according to the policy
+type FanOutError struct {
+	// Errors per cluster name
+	Errors map[string]error
+}
+
+func (e *FanOutError) Error() string {
+
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+": "+e.Errors[name].Error())
+	}
+
+	return ErrProduceFailed.Error() + ": " + strings.Join(parts, "; ")
+}
+
+// Is reports that the error is ErrProduceFailed (errors.Is implementation)
+func (e *FanOutError) Is(target error) bool {
+	return target == ErrProduceFailed
+}
+
+type clusterEntry struct {
+	Cluster
+	delivered int64
+	failed    int64
+}
+
+// A MultiProducer produces the message to the several clusters (e.g. primary and DR)
+type MultiProducer struct {
+	clusters []*clusterEntry
+	policy   FanOutPolicy
+	metrics  *MultiMetrics
+	mu       sync.Mutex
+}
+
+// NewMultiProducer creates the producers of the clusters
+func NewMultiProducer(cfg MultiConfig) (*MultiProducer, error) {
+
+	clusters := make([]Cluster, 0, len(cfg.Clusters))
+	for _, c := range cfg.Clusters {
+		p, err := NewSyncProducer(c.ConfigMap)
+		if err != nil {
+			for i := range clusters {
+				clusters[i].Producer.Close()
+			}
+			return nil, errors.Wrapf(err, "cluster %s", c.Name)
+		}
+
+		clusters = append(clusters, Cluster{Name: c.Name, Producer: p, Required: c.Required})
+	}
+
+	return NewMultiProducerFrom(cfg.Policy, cfg.Metrics, clusters...)
+}
+
+// NewMultiProducerFrom returns the multi-cluster producer of the existing producers
+func NewMultiProducerFrom(policy FanOutPolicy, metrics *MultiMetrics, clusters ...Cluster) (*MultiProducer, error) {
+
+	if len(clusters) == 0 {
+		return nil, errors.New("clusters are empty")
+	}
+
+	names := make(map[string]struct{}, len(clusters))
+	entries := make([]*clusterEntry, 0, len(clusters))
+	for _, c := range clusters {
+		if _, ok := names[c.Name]; ok {
+			return nil, errors.Errorf("duplicate cluster %s", c.Name)
+		}
+		names[c.Name] = struct{}{}
+
+		entries = append(entries, &clusterEntry{Cluster: c})
+	}
+
+	if metrics == nil {
+		metrics = &MultiMetrics{}
+	}
+
+	return &MultiProducer{
+		clusters: entries,
+		policy:   policy,
+		metrics:  metrics,
+	}, nil
+}
+
+// Produce produces the message to all clusters concurrently
+func (m *MultiProducer) Produce(ctx context.Context, msg *kafka.Message) error {
+
+	errs := make([]error, len(m.clusters))
+
+	var wg sync.WaitGroup
+	wg.Add(len(m.clusters))
+	for i := range m.clusters {
+		go func(i int) {
+			defer wg.Done()
+
+			// each producer gets its own copy of the message (the producers run concurrently)
+			cp := *msg
+			errs[i] = m.clusters[i].Producer.Produce(ctx, &cp)
+		}(i)
+	}
+	wg.Wait()
+
+	var (
+		delivered int
+		failed    map[string]error
+		violated  bool
+	)
+
+	for i, c := range m.clusters {
+		m.track(c, errs[i])
+		if errs[i] == nil {
+			delivered++
+			continue
+		}
+
+		if failed == nil {
+			failed = make(map[string]error)
+		}
+		failed[c.Name] = errs[i]
+
+		if c.Required || m.policy == PolicyAllRequired {
+			violated = true
+		}
+	}
+
+	if violated || delivered == 0 {
+		return &FanOutError{Errors: failed}
+	}
+
+	return nil
+}
+
+// Stats returns the delivery statistics per cluster (in the order of the clusters)
+func (m *MultiProducer) Stats() []ClusterStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	retval := make([]ClusterStats, 0, len(m.clusters))
+	for _, c := range m.clusters {
+		retval = append(retval, ClusterStats{
+			Name:      c.Name,
+			Delivered: c.delivered,
+			Failed:    c.failed,
+		})
+	}
+
+	return retval
+}
+
+// Close closes the producers of all clusters
+func (m *MultiProducer) Close() {
+	for _, c := range m.clusters {
+		c.Producer.Close()
+	}
+}
+
+func (m *MultiProducer) track(c *clusterEntry, err error) {
+
+	m.mu.Lock()
+	if err == nil {
+		c.delivered++
+	} else {
+		c.failed++
+	}
+	m.mu.Unlock()
+
+	if err == nil {
+		if m.metrics.Delivered != nil {
+			m.metrics.Delivered(c.Name).Inc()
+		}
+	} else if m.metrics.Failed != nil {
+		m.metrics.Failed(c.Name).Inc()
+	}
+}
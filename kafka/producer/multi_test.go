@@ -0,0 +1,113 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/metric"
+	"github.com/dialogs/dialog-go-lib/metric/mock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiProducer(t *testing.T) {
+
+	topic := "t1"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Value:          []byte("val"),
+	}
+	errFailed := errors.New("failed")
+
+	for _, testInfo := range []struct {
+		Name        string
+		Policy      FanOutPolicy
+		DRRequired  bool
+		PrimaryErr  error
+		DRErr       error
+		ExpectedErr string
+	}{
+		{Name: "best effort: success", Policy: PolicyBestEffort},
+		{Name: "best effort: partial", Policy: PolicyBestEffort, DRErr: errFailed},
+		{Name: "best effort: failed", Policy: PolicyBestEffort, PrimaryErr: errFailed, DRErr: errFailed,
+			ExpectedErr: "produce message failed: dr: failed; primary: failed"},
+		{Name: "best effort: required", Policy: PolicyBestEffort, DRRequired: true, DRErr: errFailed,
+			ExpectedErr: "produce message failed: dr: failed"},
+		{Name: "all required: success", Policy: PolicyAllRequired},
+		{Name: "all required: partial", Policy: PolicyAllRequired, PrimaryErr: errFailed,
+			ExpectedErr: "produce message failed: primary: failed"},
+	} {
+		testInfo := testInfo
+		t.Run(testInfo.Name, func(t *testing.T) {
+
+			primary := &testProducer{err: testInfo.PrimaryErr}
+			dr := &testProducer{err: testInfo.DRErr}
+
+			m, err := NewMultiProducerFrom(testInfo.Policy, nil,
+				Cluster{Name: "primary", Producer: primary},
+				Cluster{Name: "dr", Producer: dr, Required: testInfo.DRRequired})
+			require.NoError(t, err)
+			defer m.Close()
+
+			err = m.Produce(context.Background(), msg)
+			if testInfo.ExpectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, testInfo.ExpectedErr)
+				require.True(t, errors.Is(err, ErrProduceFailed))
+			}
+
+			if testInfo.PrimaryErr == nil {
+				require.Equal(t, []string{"val"}, primary.Values())
+			}
+			if testInfo.DRErr == nil {
+				require.Equal(t, []string{"val"}, dr.Values())
+			}
+		})
+	}
+}
+
+func TestMultiProducerStats(t *testing.T) {
+
+	delivered := map[string]*mock.Counter{"primary": mock.NewCounter(), "dr": mock.NewCounter()}
+	failed := map[string]*mock.Counter{"primary": mock.NewCounter(), "dr": mock.NewCounter()}
+
+	dr := &testProducer{}
+	m, err := NewMultiProducerFrom(PolicyBestEffort,
+		&MultiMetrics{
+			Delivered: func(name string) metric.ICounter { return delivered[name] },
+			Failed:    func(name string) metric.ICounter { return failed[name] },
+		},
+		Cluster{Name: "primary", Producer: &testProducer{}},
+		Cluster{Name: "dr", Producer: dr})
+	require.NoError(t, err)
+
+	topic := "t1"
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}}
+
+	require.NoError(t, m.Produce(context.Background(), msg))
+	dr.SetError(errors.New("failed"))
+	require.NoError(t, m.Produce(context.Background(), msg))
+
+	require.Equal(t, []ClusterStats{
+		{Name: "primary", Delivered: 2},
+		{Name: "dr", Delivered: 1, Failed: 1},
+	}, m.Stats())
+
+	require.Equal(t, uint64(2), delivered["primary"].Get())
+	require.Equal(t, uint64(1), delivered["dr"].Get())
+	require.Equal(t, uint64(0), failed["primary"].Get())
+	require.Equal(t, uint64(1), failed["dr"].Get())
+}
+
+func TestNewMultiProducerFromErrors(t *testing.T) {
+
+	_, err := NewMultiProducerFrom(PolicyBestEffort, nil)
+	require.EqualError(t, err, "clusters are empty")
+
+	_, err = NewMultiProducerFrom(PolicyBestEffort, nil,
+		Cluster{Name: "primary", Producer: &testProducer{}},
+		Cluster{Name: "primary", Producer: &testProducer{}})
+	require.EqualError(t, err, "duplicate cluster primary")
+}
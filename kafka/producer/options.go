@@ -0,0 +1,43 @@
+package producer
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	libkafka "github.com/dialogs/dialog-go-lib/kafka"
+	"github.com/pkg/errors"
+)
+
+// FuncOnQueueFull is called if the local queue of the producer is full
+type FuncOnQueueFull func(ctx context.Context, topic string, queueLen int)
+
+// An Option modifies the producer settings (see NewSyncProducerWithOptions)
+type Option func(p *SyncProducer)
+
+// WithMetrics sets the metrics of the producer
+func WithMetrics(m *Metrics) Option {
+	return func(p *SyncProducer) {
+		p.stats = newStats(m)
+	}
+}
+
+// WithOnQueueFull sets the callback of the full local queue
+func WithOnQueueFull(fn FuncOnQueueFull) Option {
+	return func(p *SyncProducer) {
+		p.onQueueFull = fn
+	}
+}
+
+// NewSyncProducerWithOptions returns the producer with the options
+func NewSyncProducerWithOptions(config *kafka.ConfigMap, opts ...Option) (*SyncProducer, error) {
+	if err := validateCompression(config, libkafka.Features()); err != nil {
+		return nil, err
+	}
+
+	producer, err := kafka.NewProducer(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "create producer failed")
+	}
+
+	return newSyncProducer(producer, opts...), nil
+}
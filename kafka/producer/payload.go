@@ -0,0 +1,59 @@
+package producer
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/serde/compress"
+	"github.com/pkg/errors"
+)
+
+// PayloadCompressionConfig is a configuration of the compression of the payloads
+type PayloadCompressionConfig struct {
+	// Codec is a name of the codec (see compress.Register)
+	Codec string
+	// MinSize is a min size of the value to compress (the smaller values are produced as is)
+	MinSize int
+}
+
+// A CompressingProducer compresses the values of the messages (see consumer.Decompress)
+type CompressingProducer struct {
+	Producer
+	codec   compress.ICodec
+	minSize int
+}
+
+// NewCompressingProducer wraps the producer
+func NewCompressingProducer(p Producer, cfg PayloadCompressionConfig) (*CompressingProducer, error) {
+
+	codec, err := compress.Get(cfg.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CompressingProducer{
+		Producer: p,
+		codec:    codec,
+		minSize:  cfg.MinSize,
+	}, nil
+}
+
+// Produce produces the message with the compressed value
+func (p *CompressingProducer) Produce(ctx context.Context, msg *kafka.Message) error {
+
+	if len(msg.Value) == 0 || len(msg.Value) < p.minSize {
+		return p.Producer.Produce(ctx, msg)
+	}
+
+	value, err := p.codec.Encode(msg.Value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode value by %s", p.codec.Name())
+	}
+
+	compressed := *msg
+	compressed.Value = value
+	compressed.Headers = append(append(make([]kafka.Header, 0, len(msg.Headers)+1), msg.Headers...),
+		kafka.Header{Key: compress.HeaderCodec, Value: []byte(p.codec.Name())})
+
+	return p.Producer.Produce(ctx, &compressed)
+}
@@ -0,0 +1,57 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/serde/compress"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitCompressingProducer(t *testing.T) {
+
+	_, err := NewCompressingProducer(&testProducer{}, PayloadCompressionConfig{Codec: "brotli"})
+	require.EqualError(t, err, "brotli: unknown codec")
+
+	p := &testProducer{}
+	compressing, err := NewCompressingProducer(p, PayloadCompressionConfig{Codec: compress.Gzip, MinSize: 10})
+	require.NoError(t, err)
+
+	topic := "a"
+	small := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Value:          []byte("small"),
+	}
+	require.NoError(t, compressing.Produce(context.Background(), small))
+
+	src := bytes.Repeat([]byte("value"), 10)
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Value:          src,
+		Headers:        []kafka.Header{{Key: "h", Value: []byte("1")}},
+	}
+	require.NoError(t, compressing.Produce(context.Background(), msg))
+
+	msgs := p.msgs
+	require.Len(t, msgs, 2)
+	require.Equal(t, small, msgs[0])
+
+	require.Equal(t,
+		[]kafka.Header{
+			{Key: "h", Value: []byte("1")},
+			{Key: compress.HeaderCodec, Value: []byte(compress.Gzip)},
+		},
+		msgs[1].Headers)
+
+	codec, err := compress.Get(compress.Gzip)
+	require.NoError(t, err)
+	dec, err := codec.Decode(msgs[1].Value)
+	require.NoError(t, err)
+	require.Equal(t, src, dec)
+
+	// the source message isn't modified
+	require.Equal(t, src, msg.Value)
+	require.Len(t, msg.Headers, 1)
+}
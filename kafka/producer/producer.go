@@ -10,3 +10,8 @@ type Producer interface {
 	Produce(ctx context.Context, msg *kafka.Message) error
 	Close()
 }
+
+// ISyncProducer produces the messages and waits for the delivery reports (see SyncProducer)
+type ISyncProducer interface {
+	ProduceSync(ctx context.Context, msgs ...*kafka.Message) ([]Result, error)
+}
@@ -0,0 +1,108 @@
+package producer
+
+import (
+	"context"
+	"path"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+)
+
+// ErrNoRoute is returned if the message doesn't match any route
+var ErrNoRoute = errors.New("no route for message")
+
+// FuncRoutingKey returns the routing key of the message (see HeaderRoutingKey)
+type FuncRoutingKey func(msg *kafka.Message) string
+
+// HeaderRoutingKey returns the value of the message header as the routing key
+func HeaderRoutingKey(name string) FuncRoutingKey {
+	return func(msg *kafka.Message) string {
+		for _, h := range msg.Headers {
+			if h.Key == name {
+				return string(h.Value)
+			}
+		}
+
+		return ""
+	}
+}
+
+// A Route binds the routing keys (path.Match patterns) to the topic
+type Route struct {
+	Pattern string
+	Topic   string
+}
+
+// RoutingConfig is a configuration of the routing producer
+type RoutingConfig struct {
+	// Key returns the routing key of the message
+	Key FuncRoutingKey
+	// Routes are checked in the order of declaration, the first matched route is used
+	Routes []Route
+	// DefaultTopic is used if the message doesn't match any route (ErrNoRoute by default)
+	DefaultTopic string
+}
+
+// A RoutingProducer selects the topic of the message by the routing table
+type RoutingProducer struct {
+	producer Producer
+	cfg      RoutingConfig
+}
+
+// NewRoutingProducer returns the routing producer
+func NewRoutingProducer(p Producer, cfg RoutingConfig) (*RoutingProducer, error) {
+
+	if cfg.Key == nil {
+		return nil, errors.New("routing key is not set")
+	}
+
+	for _, r := range cfg.Routes {
+		if r.Topic == "" {
+			return nil, errors.Errorf("empty topic of route %s", r.Pattern)
+		}
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid route pattern %s", r.Pattern)
+		}
+	}
+
+	return &RoutingProducer{
+		producer: p,
+		cfg:      cfg,
+	}, nil
+}
+
+// Resolve returns the topic of the routing key
+func (r *RoutingProducer) Resolve(key string) (string, error) {
+
+	for _, route := range r.cfg.Routes {
+		// the patterns are checked by the constructor
+		if ok, _ := path.Match(route.Pattern, key); ok {
+			return route.Topic, nil
+		}
+	}
+
+	if r.cfg.DefaultTopic != "" {
+		return r.cfg.DefaultTopic, nil
+	}
+
+	return "", errors.Wrapf(ErrNoRoute, "key '%s'", key)
+}
+
+// Produce sets the topic of the message by the routing table and produces it
+func (r *RoutingProducer) Produce(ctx context.Context, msg *kafka.Message) error {
+
+	topic, err := r.Resolve(r.cfg.Key(msg))
+	if err != nil {
+		return err
+	}
+
+	cp := *msg
+	cp.TopicPartition.Topic = &topic
+
+	return r.producer.Produce(ctx, &cp)
+}
+
+// Close closes the producer
+func (r *RoutingProducer) Close() {
+	r.producer.Close()
+}
@@ -0,0 +1,87 @@
+package producer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingProducer(t *testing.T) {
+
+	target := &testProducer{}
+	r, err := NewRoutingProducer(target, RoutingConfig{
+		Key: HeaderRoutingKey("event"),
+		Routes: []Route{
+			{Pattern: "user.deleted", Topic: "users-deleted"},
+			{Pattern: "user.*", Topic: "users"},
+			{Pattern: "order.v?", Topic: "orders"},
+		},
+	})
+	require.NoError(t, err)
+	defer r.Close()
+
+	for key, expected := range map[string]string{
+		"user.deleted": "users-deleted",
+		"user.created": "users",
+		"order.v1":     "orders",
+	} {
+		topic, err := r.Resolve(key)
+		require.NoError(t, err, key)
+		require.Equal(t, expected, topic, key)
+	}
+
+	_, err = r.Resolve("order.v10")
+	require.True(t, errors.Is(err, ErrNoRoute))
+	require.EqualError(t, err, "key 'order.v10': no route for message")
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Partition: kafka.PartitionAny},
+		Headers:        []kafka.Header{{Key: "event", Value: []byte("user.created")}},
+	}
+	require.NoError(t, r.Produce(context.Background(), msg))
+	require.Nil(t, msg.TopicPartition.Topic)
+
+	target.mu.Lock()
+	require.Len(t, target.msgs, 1)
+	require.Equal(t, "users", *target.msgs[0].TopicPartition.Topic)
+	require.Equal(t, kafka.PartitionAny, target.msgs[0].TopicPartition.Partition)
+	target.mu.Unlock()
+
+	err = r.Produce(context.Background(), &kafka.Message{})
+	require.True(t, errors.Is(err, ErrNoRoute))
+}
+
+func TestRoutingProducerDefaultTopic(t *testing.T) {
+
+	r, err := NewRoutingProducer(&testProducer{}, RoutingConfig{
+		Key:          HeaderRoutingKey("event"),
+		Routes:       []Route{{Pattern: "user.*", Topic: "users"}},
+		DefaultTopic: "events",
+	})
+	require.NoError(t, err)
+
+	topic, err := r.Resolve("order.created")
+	require.NoError(t, err)
+	require.Equal(t, "events", topic)
+}
+
+func TestNewRoutingProducerErrors(t *testing.T) {
+
+	_, err := NewRoutingProducer(&testProducer{}, RoutingConfig{})
+	require.EqualError(t, err, "routing key is not set")
+
+	_, err = NewRoutingProducer(&testProducer{}, RoutingConfig{
+		Key:    HeaderRoutingKey("event"),
+		Routes: []Route{{Pattern: "user.*"}},
+	})
+	require.EqualError(t, err, "empty topic of route user.*")
+
+	_, err = NewRoutingProducer(&testProducer{}, RoutingConfig{
+		Key:    HeaderRoutingKey("event"),
+		Routes: []Route{{Pattern: "user.[", Topic: "users"}},
+	})
+	require.EqualError(t, err, "invalid route pattern user.[: syntax error in pattern")
+}
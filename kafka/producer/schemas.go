@@ -0,0 +1,49 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/service/info"
+	"github.com/pkg/errors"
+)
+
+// SchemasMessage is the message of the central topic of the schemas manifests (see PublishSchemas)
+type SchemasMessage struct {
+	Service string    `json:"service"`
+	Version string    `json:"version"`
+	Time    time.Time `json:"time"`
+	info.SchemasManifest
+}
+
+// PublishSchemas sends the manifest of the schemas of the service to the central topic
+func PublishSchemas(ctx context.Context, p Producer, topic string, appinfo *info.Info) error {
+
+	if appinfo == nil || appinfo.Name == "" {
+		return errors.New("service name is empty")
+	}
+
+	value, err := json.Marshal(&SchemasMessage{
+		Service:         appinfo.Name,
+		Version:         appinfo.Version,
+		Time:            time.Now().UTC(),
+		SchemasManifest: appinfo.Schemas.Manifest(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode schemas manifest")
+	}
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            []byte(appinfo.Name),
+		Value:          value,
+	}
+
+	if err := p.Produce(ctx, msg); err != nil {
+		return errors.Wrap(err, "failed to publish schemas manifest")
+	}
+
+	return nil
+}
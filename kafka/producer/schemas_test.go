@@ -0,0 +1,33 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/dialogs/dialog-go-lib/service/info"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitPublishSchemas(t *testing.T) {
+
+	p := &testProducer{}
+
+	require.EqualError(t, PublishSchemas(context.Background(), p, "schemas", &info.Info{}), "service name is empty")
+
+	appinfo := &info.Info{Name: "app", Version: "1.0"}
+	appinfo.Consumes(info.SchemaRef{Subject: "orders-value", Versions: []int{1}})
+
+	require.NoError(t, PublishSchemas(context.Background(), p, "schemas", appinfo))
+	require.Len(t, p.msgs, 1)
+	require.Equal(t, "schemas", *p.msgs[0].TopicPartition.Topic)
+	require.Equal(t, "app", string(p.msgs[0].Key))
+
+	var msg SchemasMessage
+	require.NoError(t, json.Unmarshal(p.msgs[0].Value, &msg))
+	require.Equal(t, "app", msg.Service)
+	require.Equal(t, "1.0", msg.Version)
+	require.False(t, msg.Time.IsZero())
+	require.Equal(t, []info.SchemaRef{{Subject: "orders-value", Versions: []int{1}}}, msg.Consumes)
+	require.Nil(t, msg.Produces)
+}
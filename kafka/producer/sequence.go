@@ -0,0 +1,76 @@
+package producer
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// SequenceHeader is the default header of the sequence number (see consumer.OrderingChecker)
+const SequenceHeader = "x-sequence"
+
+// SequenceProducer sets the sequence number of the message key to the header (see consumer.OrderingChecker)
+type SequenceProducer struct {
+	Producer
+	header    string
+	sequences map[string]uint64
+	mu        sync.Mutex
+}
+
+// NewSequenceProducer wraps the producer (the header is SequenceHeader if it's empty)
+func NewSequenceProducer(p Producer, header string) *SequenceProducer {
+
+	if header == "" {
+		header = SequenceHeader
+	}
+
+	return &SequenceProducer{
+		Producer:  p,
+		header:    header,
+		sequences: make(map[string]uint64),
+	}
+}
+
+// Produce sets the next sequence number of the key and produces the message
+func (p *SequenceProducer) Produce(ctx context.Context, msg *kafka.Message) error {
+
+	key := sequenceKey(msg)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seq := p.sequences[key] + 1
+	setHeader(msg, p.header, strconv.FormatUint(seq, 10))
+
+	if err := p.Producer.Produce(ctx, msg); err != nil {
+		return err
+	}
+
+	p.sequences[key] = seq
+
+	return nil
+}
+
+func sequenceKey(msg *kafka.Message) string {
+
+	var topic string
+	if msg.TopicPartition.Topic != nil {
+		topic = *msg.TopicPartition.Topic
+	}
+
+	return topic + "/" + string(msg.Key)
+}
+
+func setHeader(msg *kafka.Message, key, val string) {
+
+	for i := range msg.Headers {
+		if msg.Headers[i].Key == key {
+			msg.Headers[i].Value = []byte(val)
+			return
+		}
+	}
+
+	msg.Headers = append(msg.Headers, kafka.Header{Key: key, Value: []byte(val)})
+}
@@ -0,0 +1,43 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitSequenceProducer(t *testing.T) {
+
+	p := &testProducer{}
+	seq := NewSequenceProducer(p, "")
+
+	topic := "a"
+	newMessage := func(key string) *kafka.Message {
+		return &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}, Key: []byte(key)}
+	}
+
+	for _, key := range []string{"k1", "k2", "k1"} {
+		require.NoError(t, seq.Produce(context.Background(), newMessage(key)))
+	}
+
+	// the number isn't used by the failed message
+	p.SetError(errors.New("failed"))
+	require.EqualError(t, seq.Produce(context.Background(), newMessage("k1")), "failed")
+	p.SetError(nil)
+
+	// the header is replaced
+	msg := newMessage("k1")
+	msg.Headers = []kafka.Header{{Key: SequenceHeader, Value: []byte("100")}}
+	require.NoError(t, seq.Produce(context.Background(), msg))
+
+	headers := make([]string, 0, len(p.msgs))
+	for _, msg := range p.msgs {
+		require.Len(t, msg.Headers, 1)
+		require.Equal(t, SequenceHeader, msg.Headers[0].Key)
+		headers = append(headers, string(msg.Key)+":"+string(msg.Headers[0].Value))
+	}
+	require.Equal(t, []string{"k1:1", "k2:1", "k1:2", "k1:3"}, headers)
+}
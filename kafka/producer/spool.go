@@ -0,0 +1,369 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/metric"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	_SpoolFileExt               = ".msg"
+	_DefaultSpoolReplayInterval = 5 * time.Second
+	_DefaultSpoolReplayTimeout  = 10 * time.Second
+)
+
+// ErrSpoolFull is returned if the message can't be spooled (the max size of the spool is reached)
+var ErrSpoolFull = errors.New("spool is full")
+
+// SpoolMetrics of the spool. All fields are optional.
+type SpoolMetrics struct {
+	// Spooled is a count of the messages saved to the spool
+	Spooled metric.ICounter
+	// Replayed is a count of the messages produced from the spool
+	Replayed metric.ICounter
+	// Dropped is a count of the messages not saved to the full spool
+	Dropped metric.ICounter
+}
+
+// SpoolConfig is a configuration of the spool producer
+type SpoolConfig struct {
+	// Dir is a directory of the spooled messages
+	Dir string
+	// MaxBytes is a max size of the spooled messages (unlimited by default)
+	MaxBytes int64
+	// ReplayInterval is an interval of the replay attempts (5 seconds by default)
+	ReplayInterval time.Duration
+	// ReplayTimeout is a timeout of the spooled message produce (10 seconds by default)
+	ReplayTimeout time.Duration
+	// IsUnavailable reports that the message must be spooled after the produce error (IsBrokerUnavailable by default)
+	IsUnavailable func(err error) bool
+	Metrics       *SpoolMetrics
+	// Logger is a nop logger by default
+	Logger *zap.Logger
+}
+
+// IsBrokerUnavailable reports that the message isn't queued because the broker is unreachable
+func IsBrokerUnavailable(err error) bool {
+
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr.NotQueued
+	}
+
+	var kafkaErr kafka.Error
+	if !errors.As(err, &kafkaErr) {
+		return false
+	}
+
+	switch kafkaErr.Code() {
+	case kafka.ErrTransport, kafka.ErrAllBrokersDown:
+		return true
+	}
+
+	return false
+}
+
+type spoolRecord struct {
+	Topic     string        `json:"topic"`
+	Partition int32         `json:"partition"`
+	Key       []byte        `json:"key,omitempty"`
+	Value     []byte        `json:"value,omitempty"`
+	Headers   []spoolHeader `json:"headers,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+type spoolHeader struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value,omitempty"`
+}
+
+type spoolFile struct {
+	name string
+	size int64
+}
+
+// A SpoolProducer saves the messages to the disk while the broker is unavailable and replays them in order
+type SpoolProducer struct {
+	producer Producer
+	cfg      SpoolConfig
+	logger   *zap.Logger
+	files    []spoolFile
+	size     int64
+	seq      uint64
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewSpoolProducer returns the producer with the spool (the spooled messages are replayed)
+func NewSpoolProducer(p Producer, cfg SpoolConfig) (*SpoolProducer, error) {
+
+	if cfg.Dir == "" {
+		return nil, errors.New("spool directory is empty")
+	}
+	if cfg.ReplayInterval <= 0 {
+		cfg.ReplayInterval = _DefaultSpoolReplayInterval
+	}
+	if cfg.ReplayTimeout <= 0 {
+		cfg.ReplayTimeout = _DefaultSpoolReplayTimeout
+	}
+	if cfg.IsUnavailable == nil {
+		cfg.IsUnavailable = IsBrokerUnavailable
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = &SpoolMetrics{}
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create spool directory")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &SpoolProducer{
+		producer: p,
+		cfg:      cfg,
+		logger:   logger.With(zap.String("spool", cfg.Dir)),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	if err := s.load(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go s.replayLoop(ctx)
+
+	return s, nil
+}
+
+// Produce produces the message or saves it to the spool if the broker is unavailable
+func (s *SpoolProducer) Produce(ctx context.Context, msg *kafka.Message) error {
+
+	if s.Len() == 0 {
+		err := s.producer.Produce(ctx, msg)
+		if err == nil || !s.cfg.IsUnavailable(err) {
+			return err
+		}
+
+		s.logger.Warn("broker is unavailable, spool message", zap.Error(err))
+	}
+
+	return s.push(msg)
+}
+
+// Len returns a count of the spooled messages
+func (s *SpoolProducer) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.files)
+}
+
+// Close stops the replay and closes the producer (the spooled messages are kept)
+func (s *SpoolProducer) Close() {
+	s.cancel()
+	<-s.done
+
+	s.producer.Close()
+}
+
+func (s *SpoolProducer) load() error {
+
+	list, err := ioutil.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to read spool directory")
+	}
+
+	for _, info := range list {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, _SpoolFileExt) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, _SpoolFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if seq > s.seq {
+			s.seq = seq
+		}
+
+		s.files = append(s.files, spoolFile{name: name, size: info.Size()})
+		s.size += info.Size()
+	}
+
+	sort.Slice(s.files, func(i, j int) bool {
+		return s.files[i].name < s.files[j].name
+	})
+
+	return nil
+}
+
+func (s *SpoolProducer) push(msg *kafka.Message) error {
+
+	data, err := encodeSpoolRecord(msg)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cfg.MaxBytes > 0 && s.size+int64(len(data)) > s.cfg.MaxBytes {
+		if s.cfg.Metrics.Dropped != nil {
+			s.cfg.Metrics.Dropped.Inc()
+		}
+		return ErrSpoolFull
+	}
+
+	s.seq++
+	name := fmt.Sprintf("%020d%s", s.seq, _SpoolFileExt)
+
+	// write and rename: a partially written file isn't loaded after the restart
+	tmp := filepath.Join(s.cfg.Dir, name+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write spool file")
+	}
+	if err := os.Rename(tmp, filepath.Join(s.cfg.Dir, name)); err != nil {
+		return errors.Wrap(err, "failed to write spool file")
+	}
+
+	s.files = append(s.files, spoolFile{name: name, size: int64(len(data))})
+	s.size += int64(len(data))
+
+	if s.cfg.Metrics.Spooled != nil {
+		s.cfg.Metrics.Spooled.Inc()
+	}
+
+	return nil
+}
+
+func (s *SpoolProducer) replayLoop(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.cfg.ReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.replay(ctx)
+		}
+	}
+}
+
+func (s *SpoolProducer) replay(ctx context.Context) {
+
+	for ctx.Err() == nil {
+		s.mu.Lock()
+		if len(s.files) == 0 {
+			s.mu.Unlock()
+			return
+		}
+		file := s.files[0]
+		s.mu.Unlock()
+
+		path := filepath.Join(s.cfg.Dir, file.name)
+		msg, err := readSpoolRecord(path)
+		if err == nil {
+			produceCtx, cancel := context.WithTimeout(ctx, s.cfg.ReplayTimeout)
+			err = s.producer.Produce(produceCtx, msg)
+			cancel()
+
+			if err != nil && s.cfg.IsUnavailable(err) {
+				s.logger.Debug("broker is unavailable, replay postponed", zap.Error(err))
+				return
+			}
+		}
+
+		if MayBeDelivered(err) {
+			// the message isn't produced again to avoid the duplicate
+			s.logger.Warn("spooled message isn't confirmed", zap.String("file", file.name), zap.Error(err))
+		} else if err != nil {
+			// the message can't be produced: it's removed to unblock the spool
+			s.logger.Error("failed to replay spooled message", zap.String("file", file.name), zap.Error(err))
+			if s.cfg.Metrics.Dropped != nil {
+				s.cfg.Metrics.Dropped.Inc()
+			}
+		} else if s.cfg.Metrics.Replayed != nil {
+			s.cfg.Metrics.Replayed.Inc()
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			s.logger.Error("failed to remove spool file", zap.String("file", file.name), zap.Error(err))
+		}
+
+		s.mu.Lock()
+		s.files = s.files[1:]
+		s.size -= file.size
+		s.mu.Unlock()
+	}
+}
+
+func encodeSpoolRecord(msg *kafka.Message) ([]byte, error) {
+
+	rec := spoolRecord{
+		Topic:     getTopic(msg.TopicPartition),
+		Partition: msg.TopicPartition.Partition,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Timestamp: msg.Timestamp,
+	}
+	for _, h := range msg.Headers {
+		rec.Headers = append(rec.Headers, spoolHeader{Key: h.Key, Value: h.Value})
+	}
+
+	data, err := json.Marshal(&rec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode spooled message")
+	}
+
+	return data, nil
+}
+
+func readSpoolRecord(path string) (*kafka.Message, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read spool file")
+	}
+
+	var rec spoolRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, errors.Wrap(err, "failed to decode spooled message")
+	}
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &rec.Topic, Partition: rec.Partition},
+		Key:            rec.Key,
+		Value:          rec.Value,
+		Timestamp:      rec.Timestamp,
+	}
+	for _, h := range rec.Headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: h.Key, Value: h.Value})
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,157 @@
+package producer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/metric/mock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type testProducer struct {
+	err  error
+	msgs []*kafka.Message
+	mu   sync.Mutex
+}
+
+func (p *testProducer) Produce(_ context.Context, msg *kafka.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *testProducer) Close() {}
+
+func (p *testProducer) SetError(err error) {
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+}
+
+func (p *testProducer) Values() (retval []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, msg := range p.msgs {
+		retval = append(retval, string(msg.Value))
+	}
+	return
+}
+
+func TestIsBrokerUnavailable(t *testing.T) {
+
+	require.True(t, IsBrokerUnavailable(&TimeoutError{NotQueued: true, Err: context.DeadlineExceeded}))
+	require.True(t, IsBrokerUnavailable(kafka.NewError(kafka.ErrAllBrokersDown, "", false)))
+	require.True(t, IsBrokerUnavailable(&DeliveryError{Err: kafka.NewError(kafka.ErrTransport, "", false)}))
+	// the queued message may be delivered
+	require.False(t, IsBrokerUnavailable(&TimeoutError{Err: context.DeadlineExceeded}))
+	require.False(t, IsBrokerUnavailable(&DeliveryError{Err: kafka.NewError(kafka.ErrMsgTimedOut, "", false)}))
+	require.False(t, IsBrokerUnavailable(context.Canceled))
+	require.False(t, IsBrokerUnavailable(&DeliveryError{Err: kafka.NewError(kafka.ErrMsgSizeTooLarge, "", false)}))
+	require.False(t, IsBrokerUnavailable(errors.New("failed")))
+}
+
+func TestSpoolProducer(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	unavailable := kafka.NewError(kafka.ErrAllBrokersDown, "down", false)
+	target := &testProducer{err: unavailable}
+
+	spooled := mock.NewCounter()
+	replayed := mock.NewCounter()
+	dropped := mock.NewCounter()
+
+	cfg := SpoolConfig{
+		Dir:            dir,
+		MaxBytes:       300,
+		ReplayInterval: 10 * time.Millisecond,
+		Metrics: &SpoolMetrics{
+			Spooled:  spooled,
+			Replayed: replayed,
+			Dropped:  dropped,
+		},
+	}
+
+	p, err := NewSpoolProducer(target, cfg)
+	require.NoError(t, err)
+
+	topic := "t1"
+	newMessage := func(val string) *kafka.Message {
+		return &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+			Key:            []byte("key"),
+			Value:          []byte(val),
+			Headers:        []kafka.Header{{Key: "h1", Value: []byte("v1")}},
+		}
+	}
+
+	ctx := context.Background()
+	require.NoError(t, p.Produce(ctx, newMessage("1")))
+	require.NoError(t, p.Produce(ctx, newMessage("2")))
+	require.Equal(t, ErrSpoolFull, p.Produce(ctx, newMessage("3")))
+	require.Equal(t, 2, p.Len())
+	require.Equal(t, uint64(2), spooled.Get())
+	require.Equal(t, uint64(1), dropped.Get())
+
+	// the spool is kept after the restart
+	p.Close()
+	p, err = NewSpoolProducer(target, cfg)
+	require.NoError(t, err)
+	defer p.Close()
+	require.Equal(t, 2, p.Len())
+
+	target.SetError(nil)
+	require.Eventually(t, func() bool { return p.Len() == 0 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, uint64(2), replayed.Get())
+	require.Equal(t, []string{"1", "2"}, target.Values())
+
+	target.mu.Lock()
+	require.Equal(t, newMessage("1"), target.msgs[0])
+	target.mu.Unlock()
+
+	// the message is produced directly if the spool is empty
+	require.NoError(t, p.Produce(ctx, newMessage("4")))
+	require.Equal(t, []string{"1", "2", "4"}, target.Values())
+
+	// other errors aren't spooled
+	target.SetError(errors.New("failed"))
+	require.EqualError(t, p.Produce(ctx, newMessage("5")), "failed")
+	require.Equal(t, 0, p.Len())
+
+	// the context errors are returned unchanged, the queued message isn't spooled to avoid the duplicate
+	target.SetError(context.Canceled)
+	require.Equal(t, context.Canceled, p.Produce(ctx, newMessage("6")))
+	timeoutErr := &TimeoutError{Err: context.DeadlineExceeded}
+	target.SetError(timeoutErr)
+	require.Equal(t, timeoutErr, p.Produce(ctx, newMessage("7")))
+	require.Equal(t, 0, p.Len())
+
+	// the message isn't queued by the full local queue
+	target.SetError(&TimeoutError{NotQueued: true, Err: context.DeadlineExceeded})
+	require.NoError(t, p.Produce(ctx, newMessage("8")))
+	require.Equal(t, 1, p.Len())
+
+	// the replayed message isn't confirmed: it isn't replayed again
+	target.SetError(timeoutErr)
+	require.Eventually(t, func() bool { return p.Len() == 0 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, uint64(1), dropped.Get())
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
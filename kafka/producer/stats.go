@@ -0,0 +1,164 @@
+package producer
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/metric"
+)
+
+// FuncTopicGauge returns the gauge of the topic (see metric.FuncTopicGauge)
+type FuncTopicGauge = metric.FuncTopicGauge
+
+// FuncTopicCounter returns the counter of the topic (see metric.FuncTopicCounter)
+type FuncTopicCounter = metric.FuncTopicCounter
+
+// FuncTopicObserver returns the observer of the topic (see metric.FuncTopicObserver)
+type FuncTopicObserver = metric.FuncTopicObserver
+
+// Metrics of the producer. All fields are optional.
+type Metrics struct {
+	// Acked is a count of the delivered messages
+	Acked FuncTopicCounter
+	// Failed is a count of the not delivered messages
+	Failed FuncTopicCounter
+	// Retried is a count of the produce retries (the local queue is full)
+	Retried FuncTopicCounter
+	// Latency is a delivery time (seconds)
+	Latency FuncTopicObserver
+	// QueueDepth is a count of the messages waiting for the delivery report
+	QueueDepth FuncTopicGauge
+}
+
+// A TopicStats is a snapshot of the delivery statistics of the topic
+type TopicStats struct {
+	Topic      string
+	Acked      int64
+	Failed     int64
+	Retried    int64
+	QueueDepth int
+	// AvgLatency is an average delivery time of the acked messages
+	AvgLatency time.Duration
+}
+
+type topicStats struct {
+	acked      int64
+	failed     int64
+	retried    int64
+	queueDepth int
+	latency    time.Duration
+}
+
+type stats struct {
+	topics  map[string]*topicStats
+	metrics *Metrics
+	mu      sync.Mutex
+}
+
+func newStats(m *Metrics) *stats {
+	if m == nil {
+		m = &Metrics{}
+	}
+
+	return &stats{
+		topics:  make(map[string]*topicStats),
+		metrics: m,
+	}
+}
+
+func (s *stats) Begin(topic string) {
+	s.mu.Lock()
+	s.get(topic).queueDepth++
+	s.mu.Unlock()
+
+	if s.metrics.QueueDepth != nil {
+		s.metrics.QueueDepth(topic).Inc()
+	}
+}
+
+func (s *stats) Retry(topic string) {
+	s.mu.Lock()
+	s.get(topic).retried++
+	s.mu.Unlock()
+
+	if s.metrics.Retried != nil {
+		s.metrics.Retried(topic).Inc()
+	}
+}
+
+func (s *stats) End(topic string, latency time.Duration, err error) {
+	s.mu.Lock()
+	entry := s.get(topic)
+	entry.queueDepth--
+	if err == nil {
+		entry.acked++
+		entry.latency += latency
+	} else {
+		entry.failed++
+	}
+	s.mu.Unlock()
+
+	if s.metrics.QueueDepth != nil {
+		s.metrics.QueueDepth(topic).Dec()
+	}
+
+	if err != nil {
+		if s.metrics.Failed != nil {
+			s.metrics.Failed(topic).Inc()
+		}
+		return
+	}
+
+	if s.metrics.Acked != nil {
+		s.metrics.Acked(topic).Inc()
+	}
+	if s.metrics.Latency != nil {
+		s.metrics.Latency(topic).Observe(latency.Seconds())
+	}
+}
+
+func (s *stats) Snapshot() []TopicStats {
+	s.mu.Lock()
+	retval := make([]TopicStats, 0, len(s.topics))
+	for topic, entry := range s.topics {
+		item := TopicStats{
+			Topic:      topic,
+			Acked:      entry.acked,
+			Failed:     entry.failed,
+			Retried:    entry.retried,
+			QueueDepth: entry.queueDepth,
+		}
+		if entry.acked > 0 {
+			item.AvgLatency = entry.latency / time.Duration(entry.acked)
+		}
+
+		retval = append(retval, item)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(retval, func(i, j int) bool {
+		return retval[i].Topic < retval[j].Topic
+	})
+
+	return retval
+}
+
+func (s *stats) get(topic string) *topicStats {
+	entry, ok := s.topics[topic]
+	if !ok {
+		entry = &topicStats{}
+		s.topics[topic] = entry
+	}
+
+	return entry
+}
+
+func getTopic(tp kafka.TopicPartition) string {
+	if tp.Topic != nil {
+		return *tp.Topic
+	}
+
+	return ""
+}
@@ -0,0 +1,48 @@
+package producer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/metric"
+	"github.com/dialogs/dialog-go-lib/metric/mock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+
+	acked := mock.NewCounter()
+	failed := mock.NewCounter()
+	retried := mock.NewCounter()
+	latency := mock.NewObserver()
+	queueDepth := mock.NewGauge()
+
+	s := newStats(&Metrics{
+		Acked:      func(string) metric.ICounter { return acked },
+		Failed:     func(string) metric.ICounter { return failed },
+		Retried:    func(string) metric.ICounter { return retried },
+		Latency:    func(string) metric.IObserver { return latency },
+		QueueDepth: func(string) metric.IGauge { return queueDepth },
+	})
+
+	s.Begin("t2")
+	s.Begin("t1")
+	s.Begin("t1")
+	s.Begin("t1")
+	s.Retry("t1")
+	s.End("t1", time.Second, nil)
+	s.End("t1", 3*time.Second, nil)
+	s.End("t2", time.Second, errors.New("failed"))
+
+	require.Equal(t, []TopicStats{
+		{Topic: "t1", Acked: 2, Retried: 1, QueueDepth: 1, AvgLatency: 2 * time.Second},
+		{Topic: "t2", Failed: 1},
+	}, s.Snapshot())
+
+	require.Equal(t, uint64(2), acked.Get())
+	require.Equal(t, uint64(1), failed.Get())
+	require.Equal(t, uint64(1), retried.Get())
+	require.Equal(t, float64(1), queueDepth.Get())
+	require.Equal(t, []float64{1, 3}, latency.GetSlice())
+}
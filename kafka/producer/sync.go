@@ -2,49 +2,175 @@ package producer
 
 import (
 	"context"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/pkg/errors"
 )
 
+const _QueueFullRetryDelay = 100 * time.Millisecond
+
 type SyncProducer struct {
-	producer *kafka.Producer
+	producer    *kafka.Producer
+	stats       *stats
+	onQueueFull FuncOnQueueFull
 }
 
 func NewSyncProducer(config *kafka.ConfigMap) (*SyncProducer, error) {
-	producer, err := kafka.NewProducer(config)
-	if err != nil {
-		return nil, errors.Wrap(err, "create producer failed")
-	}
+	return NewSyncProducerWithOptions(config)
+}
 
-	return &SyncProducer{
+func newSyncProducer(producer *kafka.Producer, opts ...Option) *SyncProducer {
+	p := &SyncProducer{
 		producer: producer,
-	}, nil
+		stats:    newStats(nil),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
 }
 
+// Produce produces the message and waits for the delivery report until the context is done (see MayBeDelivered)
 func (s *SyncProducer) Produce(ctx context.Context, msg *kafka.Message) error {
+	_, err := s.send(ctx, msg)
+	return err
+}
+
+// A Result is a delivery report of the message (see ProduceSync)
+type Result struct {
+	// TopicPartition is a partition and offset of the delivered message
+	TopicPartition kafka.TopicPartition
+	Err            error
+}
+
+// ProduceSync produces the messages in order and waits for the delivery reports of all of them
+func (s *SyncProducer) ProduceSync(ctx context.Context, msgs ...*kafka.Message) ([]Result, error) {
+
+	retval := make([]Result, len(msgs))
+	deliveries := make([]chan kafka.Event, len(msgs))
+	starts := make([]time.Time, len(msgs))
+
+	for i, msg := range msgs {
+		topic := getTopic(msg.TopicPartition)
+		starts[i] = time.Now()
+		s.stats.Begin(topic)
+
+		delivery := make(chan kafka.Event, 1)
+		if err := s.enqueue(ctx, msg, delivery); err != nil {
+			retval[i] = Result{TopicPartition: msg.TopicPartition, Err: err}
+			s.stats.End(topic, time.Since(starts[i]), err)
+			continue
+		}
+
+		deliveries[i] = delivery
+	}
+
+	for i, msg := range msgs {
+		if deliveries[i] == nil {
+			continue
+		}
+
+		tp, err := s.wait(ctx, msg, deliveries[i])
+		retval[i] = Result{TopicPartition: tp, Err: err}
+		s.stats.End(getTopic(msg.TopicPartition), time.Since(starts[i]), err)
+	}
+
+	var (
+		failed   int
+		firstErr error
+	)
+	for i := range retval {
+		if retval[i].Err != nil {
+			if firstErr == nil {
+				firstErr = retval[i].Err
+			}
+			failed++
+		}
+	}
+
+	if firstErr != nil {
+		return retval, errors.Wrapf(firstErr, "%d of %d messages failed", failed, len(msgs))
+	}
+
+	return retval, nil
+}
+
+// Stats returns the delivery statistics per topic
+func (s *SyncProducer) Stats() []TopicStats {
+	return s.stats.Snapshot()
+}
+
+func (s *SyncProducer) send(ctx context.Context, msg *kafka.Message) (kafka.TopicPartition, error) {
+	topic := getTopic(msg.TopicPartition)
+	start := time.Now()
+
+	s.stats.Begin(topic)
+	tp, err := s.produce(ctx, msg)
+	s.stats.End(topic, time.Since(start), err)
+
+	return tp, err
+}
+
+func (s *SyncProducer) produce(ctx context.Context, msg *kafka.Message) (kafka.TopicPartition, error) {
+
 	delivery := make(chan kafka.Event, 1)
+	if err := s.enqueue(ctx, msg, delivery); err != nil {
+		return msg.TopicPartition, err
+	}
+
+	return s.wait(ctx, msg, delivery)
+}
+
+func (s *SyncProducer) enqueue(ctx context.Context, msg *kafka.Message, delivery chan kafka.Event) error {
+	topic := getTopic(msg.TopicPartition)
+
+	for {
+		err := s.producer.Produce(msg, delivery)
+		if err == nil {
+			return nil
+		}
 
-	err := s.producer.Produce(msg, delivery)
-	if err != nil {
-		return errors.Wrap(err, "produce message failed")
+		if !isErrorCode(err, kafka.ErrQueueFull) {
+			return &DeliveryError{TopicPartition: msg.TopicPartition, Err: err}
+		}
+
+		s.stats.Retry(topic)
+		if s.onQueueFull != nil {
+			s.onQueueFull(ctx, topic, s.producer.Len())
+		}
+
+		select {
+		case <-ctx.Done():
+			return &TimeoutError{TopicPartition: msg.TopicPartition, NotQueued: true, Err: ctx.Err()}
+		case <-time.After(_QueueFullRetryDelay):
+		}
 	}
+}
+
+func (s *SyncProducer) wait(ctx context.Context, msg *kafka.Message, delivery <-chan kafka.Event) (kafka.TopicPartition, error) {
 
 	select {
-	case <-ctx.Done():
-		return ctx.Err()
 	case e := <-delivery:
-		switch ev := e.(type) {
-		case *kafka.Message:
-			if ev.TopicPartition.Error != nil {
-				return errors.Wrap(ev.TopicPartition.Error, "produce message failed")
-			}
-			return nil
-		case kafka.Error:
-			return errors.Wrap(ev, "produce message failed")
-		default:
-			return errors.New("unknown produce event")
+		return deliveryResult(msg, e)
+	case <-ctx.Done():
+		return msg.TopicPartition, &TimeoutError{TopicPartition: msg.TopicPartition, Err: ctx.Err()}
+	}
+}
+
+func deliveryResult(msg *kafka.Message, e kafka.Event) (kafka.TopicPartition, error) {
+	switch ev := e.(type) {
+	case *kafka.Message:
+		if ev.TopicPartition.Error != nil {
+			return ev.TopicPartition, &DeliveryError{TopicPartition: ev.TopicPartition, Err: ev.TopicPartition.Error}
 		}
+		return ev.TopicPartition, nil
+	case kafka.Error:
+		return msg.TopicPartition, &DeliveryError{TopicPartition: msg.TopicPartition, Err: ev}
+	default:
+		return msg.TopicPartition, ErrUnknownEvent
 	}
 }
 
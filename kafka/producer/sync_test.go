@@ -3,11 +3,12 @@ package producer
 import (
 	"context"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
-	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -20,29 +21,15 @@ func TestSync(t *testing.T) {
 	createTopic(t, Topic, 1, 1)
 	defer removeTopic(t, Topic)
 
-	chMessages := make(chan *kafka.Message)
-
-	consumerCfg := &consumer.Config{
-		OnError: func(_ context.Context, _ *zap.Logger, err error) {
-			require.NoError(t, err)
-		},
-		OnProcess: func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ consumer.ISleeper) error {
-			chMessages <- msg
-			return nil
-		},
-		Topics: []string{Topic},
-		ConfigMap: &kafka.ConfigMap{
-			"group.id":           "test",
-			"bootstrap.servers":  getKafkaServers(),
-			"auto.offset.reset":  "earliest",
-			"session.timeout.ms": 6000,
-		},
-	}
-
-	c, err := consumer.New(consumerCfg, newLogger(t))
+	reader, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"group.id":           "test",
+		"bootstrap.servers":  getKafkaServers(),
+		"auto.offset.reset":  "earliest",
+		"session.timeout.ms": 6000,
+	})
 	require.NoError(t, err)
-	defer c.Stop()
-	go func() { require.NoError(t, c.Start()) }()
+	defer reader.Close()
+	require.NoError(t, reader.Subscribe(Topic, nil))
 
 	p, err := NewSyncProducer(&kafka.ConfigMap{
 		"bootstrap.servers": getKafkaServers(),
@@ -57,7 +44,8 @@ func TestSync(t *testing.T) {
 		Value: []byte(Topic),
 	}))
 
-	res := <-chMessages
+	res, err := reader.ReadMessage(time.Minute)
+	require.NoError(t, err)
 
 	require.WithinDuration(t, time.Now(), res.Timestamp, time.Minute)
 	require.Equal(t,
@@ -73,6 +61,174 @@ func TestSync(t *testing.T) {
 		res)
 }
 
+func TestSyncQueueFull(t *testing.T) {
+
+	var (
+		mu       sync.Mutex
+		queueLen []int
+	)
+
+	p, err := NewSyncProducerWithOptions(
+		&kafka.ConfigMap{
+			// the broker is unavailable: messages stay in the local queue
+			"bootstrap.servers":            "localhost:1",
+			"queue.buffering.max.messages": 1,
+		},
+		WithOnQueueFull(func(_ context.Context, topic string, n int) {
+			mu.Lock()
+			queueLen = append(queueLen, n)
+			mu.Unlock()
+		}))
+	require.NoError(t, err)
+	defer p.Close()
+
+	topic := "test-producer-queue-full"
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	chErr := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			chErr <- p.Produce(ctx, &kafka.Message{
+				TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+				Value:          []byte(topic),
+			})
+		}()
+	}
+
+	require.True(t, errors.Is(<-chErr, context.DeadlineExceeded))
+	require.True(t, errors.Is(<-chErr, context.DeadlineExceeded))
+
+	mu.Lock()
+	require.NotEmpty(t, queueLen)
+	require.True(t, queueLen[0] > 0)
+	mu.Unlock()
+
+	stats := p.Stats()
+	require.Len(t, stats, 1)
+	require.Equal(t, int64(2), stats[0].Failed)
+	require.True(t, stats[0].Retried > 0)
+}
+
+func TestSyncProduceTimeout(t *testing.T) {
+
+	p, err := NewSyncProducer(&kafka.ConfigMap{
+		// the broker is unavailable: messages stay in the local queue
+		"bootstrap.servers": "localhost:1",
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	topic := "test-producer-timeout"
+	newMessage := func() *kafka.Message {
+		return &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+			Value:          []byte(topic),
+		}
+	}
+
+	// the message of the long context isn't affected by the timeout of another message
+	longCtx, longCancel := context.WithTimeout(context.Background(), time.Second)
+	defer longCancel()
+
+	chLong := make(chan error, 1)
+	go func() { chLong <- p.Produce(longCtx, newMessage()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err = p.Produce(ctx, newMessage())
+	require.True(t, errors.Is(err, ErrTimeout))
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	var timeoutErr *TimeoutError
+	require.True(t, errors.As(err, &timeoutErr))
+	require.False(t, timeoutErr.NotQueued)
+
+	select {
+	case err := <-chLong:
+		require.Fail(t, "unexpected result", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	err = <-chLong
+	require.True(t, errors.As(err, &timeoutErr))
+	require.False(t, timeoutErr.NotQueued)
+
+	stats := p.Stats()
+	require.Len(t, stats, 1)
+	require.Equal(t, int64(2), stats[0].Failed)
+	require.Equal(t, int64(0), stats[0].Retried)
+	require.Equal(t, 0, stats[0].QueueDepth)
+}
+
+func TestSyncBatch(t *testing.T) {
+
+	var Topic = "test-producer-sync-batch-" + strconv.Itoa(int(time.Now().Unix()))
+
+	createTopic(t, Topic, 1, 1)
+	defer removeTopic(t, Topic)
+
+	p, err := NewSyncProducer(&kafka.ConfigMap{
+		"bootstrap.servers": getKafkaServers(),
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	msgs := make([]*kafka.Message, 2)
+	for i := range msgs {
+		msgs[i] = &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &Topic, Partition: 0},
+			Value:          []byte(strconv.Itoa(i)),
+		}
+	}
+
+	results, err := p.ProduceSync(ctx, msgs...)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	offsets := make([]int, 0, len(results))
+	for _, res := range results {
+		require.NoError(t, res.Err)
+		require.Equal(t, Topic, *res.TopicPartition.Topic)
+		offsets = append(offsets, int(res.TopicPartition.Offset))
+	}
+	require.ElementsMatch(t, []int{0, 1}, offsets)
+}
+
+func TestSyncBatchTimeout(t *testing.T) {
+
+	p, err := NewSyncProducer(&kafka.ConfigMap{
+		// the broker is unavailable: messages stay in the local queue
+		"bootstrap.servers": "localhost:1",
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	topic := "test-producer-batch-timeout"
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	results, err := p.ProduceSync(ctx,
+		&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny}},
+		&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny}})
+	require.True(t, errors.Is(err, ErrTimeout))
+	require.EqualError(t, err, "2 of 2 messages failed: produce message timeout: context deadline exceeded")
+	require.Len(t, results, 2)
+
+	for _, res := range results {
+		require.Equal(t, topic, *res.TopicPartition.Topic)
+		require.True(t, errors.Is(res.Err, ErrTimeout))
+	}
+
+	results, err = p.ProduceSync(ctx)
+	require.NoError(t, err)
+	require.Empty(t, results)
+}
+
 func newLogger(t *testing.T) *zap.Logger {
 	t.Helper()
 
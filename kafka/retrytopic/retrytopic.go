@@ -0,0 +1,340 @@
+package retrytopic
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/dialogs/dialog-go-lib/kafka/producer"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Headers of the retried messages and the messages of the dead letter queue
+const (
+	// HeaderAttempt is a number of the retry (starting from 1)
+	HeaderAttempt = "retry-attempt"
+	// HeaderTopic is the source topic of the message
+	HeaderTopic = "retry-topic"
+	// HeaderPartition and HeaderOffset are the position of the message in the source topic
+	HeaderPartition = "retry-partition"
+	HeaderOffset    = "retry-offset"
+	// HeaderRetryAt is the time of the retry (unix milliseconds)
+	HeaderRetryAt = "retry-at"
+	// HeaderError is the error of the last attempt
+	HeaderError = "retry-error"
+)
+
+// DefaultDelays are the delays of the retry tiers by default
+var DefaultDelays = []time.Duration{time.Second * 5, time.Minute, time.Minute * 10}
+
+// TopicConfig is a configuration of the retries of the source topic
+type TopicConfig struct {
+	// Delays are the delays of the retry tiers (DefaultDelays by default): the message failed
+	// by the attempt N is sent to the topic '<topic>.retry.<Delays[N-1]>'
+	Delays []time.Duration `mapstructure:"delays"`
+	// DLQTopic receives the messages failed by all retries ('<topic>.dlq' by default)
+	DLQTopic string `mapstructure:"dlq-topic"`
+}
+
+// Config is a configuration of the retry queue
+type Config struct {
+	// Topics are the source topics with the retries
+	Topics map[string]TopicConfig
+	// Producer sends the failed messages to the retry topics and the dead letter queues
+	Producer producer.Producer
+	// NumPartitions and ReplicationFactor are used by CreateTopics (1 by default)
+	NumPartitions     int
+	ReplicationFactor int
+	// Clock is a source of the time of the retries (clock.Real by default)
+	Clock clock.Clock
+}
+
+// ITopicAdmin creates the topics (e.g. kafka.AdminClient)
+type ITopicAdmin interface {
+	CreateTopics(ctx context.Context, topics []kafka.TopicSpecification, options ...kafka.CreateTopicsAdminOption) ([]kafka.TopicResult, error)
+}
+
+type tier struct {
+	topic string
+	delay time.Duration
+}
+
+type route struct {
+	source string
+	tiers  []tier
+	dlq    string
+}
+
+// A Queue is the persistent retry queue: the failed message is sent to the retry topic of the next
+// delay tier with the attempt in the headers, it's processed again after the delay,
+// the message failed by all retries is sent to the dead letter queue
+type Queue struct {
+	routes            map[string]*route
+	producer          producer.Producer
+	numPartitions     int
+	replicationFactor int
+	clock             clock.Clock
+}
+
+// New creates the retry queue
+func New(cfg Config) (*Queue, error) {
+
+	if cfg.Producer == nil {
+		return nil, errors.New("producer is nil")
+	}
+	if len(cfg.Topics) == 0 {
+		return nil, errors.New("topics is empty")
+	}
+
+	q := &Queue{
+		routes:            make(map[string]*route, len(cfg.Topics)),
+		producer:          cfg.Producer,
+		numPartitions:     cfg.NumPartitions,
+		replicationFactor: cfg.ReplicationFactor,
+		clock:             cfg.Clock,
+	}
+
+	if q.numPartitions <= 0 {
+		q.numPartitions = 1
+	}
+	if q.replicationFactor <= 0 {
+		q.replicationFactor = 1
+	}
+	if q.clock == nil {
+		q.clock = clock.Real
+	}
+
+	for source, item := range cfg.Topics {
+		r := &route{source: source, dlq: item.DLQTopic}
+		if r.dlq == "" {
+			r.dlq = source + ".dlq"
+		}
+
+		delays := item.Delays
+		if len(delays) == 0 {
+			delays = DefaultDelays
+		}
+
+		for _, delay := range delays {
+			if delay <= 0 {
+				return nil, errors.Errorf("invalid retry delay %s of topic %s", delay, source)
+			}
+			r.tiers = append(r.tiers, tier{topic: TopicName(source, delay), delay: delay})
+		}
+
+		q.routes[source] = r
+	}
+
+	return q, nil
+}
+
+// TopicName returns the name of the retry topic of the delay (e.g. 'orders.retry.5s', 'orders.retry.10m')
+func TopicName(source string, delay time.Duration) string {
+
+	var suffix string
+	switch {
+	case delay%time.Hour == 0:
+		suffix = strconv.FormatInt(int64(delay/time.Hour), 10) + "h"
+	case delay%time.Minute == 0:
+		suffix = strconv.FormatInt(int64(delay/time.Minute), 10) + "m"
+	case delay%time.Second == 0:
+		suffix = strconv.FormatInt(int64(delay/time.Second), 10) + "s"
+	default:
+		suffix = strconv.FormatInt(int64(delay/time.Millisecond), 10) + "ms"
+	}
+
+	return source + ".retry." + suffix
+}
+
+// Topics returns the source topics and the retry topics to subscribe (see consumer.Config.Topics).
+// The partition of the retry topic is paused until the time of the retry (see Wrap).
+func (q *Queue) Topics() []string {
+
+	retval := make([]string, 0, len(q.routes))
+	for source := range q.routes {
+		retval = append(retval, source)
+	}
+	sort.Strings(retval)
+
+	return append(retval, q.RetryTopics()...)
+}
+
+// RetryTopics returns the retry topics
+func (q *Queue) RetryTopics() []string {
+
+	var retval []string
+	for _, r := range q.routes {
+		for _, t := range r.tiers {
+			retval = append(retval, t.topic)
+		}
+	}
+	sort.Strings(retval)
+
+	return retval
+}
+
+// CreateTopics creates the retry topics and the dead letter queues (the existing topics are skipped)
+func (q *Queue) CreateTopics(ctx context.Context, admin ITopicAdmin) error {
+
+	var specs []kafka.TopicSpecification
+	for _, r := range q.routes {
+		topics := []string{r.dlq}
+		for _, t := range r.tiers {
+			topics = append(topics, t.topic)
+		}
+
+		for _, topic := range topics {
+			specs = append(specs, kafka.TopicSpecification{
+				Topic:             topic,
+				NumPartitions:     q.numPartitions,
+				ReplicationFactor: q.replicationFactor,
+			})
+		}
+	}
+
+	results, err := admin.CreateTopics(ctx, specs)
+	if err != nil {
+		return errors.Wrap(err, "failed to create retry topics")
+	}
+
+	for _, res := range results {
+		if code := res.Error.Code(); code != kafka.ErrNoError && code != kafka.ErrTopicAlreadyExists {
+			return errors.Wrapf(res.Error, "failed to create topic %s", res.Topic)
+		}
+	}
+
+	return nil
+}
+
+// Wrap returns OnProcess with the retries: the message of the retry topic is processed after the time
+// of the retry, the failed message is sent to the next retry topic or the dead letter queue.
+// The partition of the early message is paused until the time of the retry and consumer.ErrRetryLater
+// is returned: the message is consumed again after the resume.
+// The error is returned if the message isn't sent or the topic isn't configured.
+func (q *Queue) Wrap(fn consumer.FuncOnProcess) consumer.FuncOnProcess {
+	return func(ctx context.Context, logger *zap.Logger, msg *kafka.Message, s consumer.ISleeper, committer consumer.ICommitter) error {
+
+		attempt, r := q.parse(msg)
+		if r == nil {
+			return fn(ctx, logger, msg, s, committer)
+		}
+
+		if attempt > 0 {
+			if delay := q.delay(msg); delay > 0 {
+				// the handler isn't blocked until the retry (max.poll.interval.ms): the partition is paused
+				// and the message is consumed again after the resume. The sleep isn't bound to the context
+				// of the message: it's done after the processing.
+				if err := s.Sleep(delay, []kafka.TopicPartition{msg.TopicPartition}); err != nil {
+					return errors.Wrap(err, "failed to pause retry partition")
+				}
+				return consumer.ErrRetryLater
+			}
+		}
+
+		err := fn(ctx, logger, msg, s, committer)
+		if err == nil {
+			return nil
+		}
+
+		next, topic := attempt+1, r.dlq
+		var delay time.Duration
+		if attempt < len(r.tiers) {
+			topic, delay = r.tiers[attempt].topic, r.tiers[attempt].delay
+		}
+
+		if errProduce := q.producer.Produce(ctx, q.retryMessage(msg, r.source, topic, next, delay, err)); errProduce != nil {
+			return errors.Wrapf(errProduce, "failed to send message to %s", topic)
+		}
+
+		if topic == r.dlq {
+			logger.Warn("message sent to dlq", zap.String("topic", topic), zap.Int("attempts", attempt+1), zap.Error(err))
+		} else {
+			logger.Info("message sent to retry", zap.String("topic", topic), zap.Int("attempt", next), zap.Error(err))
+		}
+
+		return nil
+	}
+}
+
+// parse returns the attempt of the message and the route of its source topic
+func (q *Queue) parse(msg *kafka.Message) (int, *route) {
+
+	carrier := consumer.NewHeadersCarrier(msg)
+
+	source := carrier.Get(HeaderTopic)
+	if source == "" && msg.TopicPartition.Topic != nil {
+		source = *msg.TopicPartition.Topic
+	}
+
+	attempt, _ := strconv.Atoi(carrier.Get(HeaderAttempt))
+	if attempt < 0 {
+		attempt = 0
+	}
+
+	return attempt, q.routes[source]
+}
+
+// delay returns the time left until the retry
+func (q *Queue) delay(msg *kafka.Message) time.Duration {
+
+	ms, err := strconv.ParseInt(consumer.NewHeadersCarrier(msg).Get(HeaderRetryAt), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return time.Unix(0, ms*int64(time.Millisecond)).Sub(q.clock.Now())
+}
+
+// retryMessage returns the copy of the message to the retry topic (or the dead letter queue) with the retry headers
+func (q *Queue) retryMessage(msg *kafka.Message, source, topic string, attempt int, delay time.Duration, err error) *kafka.Message {
+
+	partition, offset := msg.TopicPartition.Partition, msg.TopicPartition.Offset
+
+	carrier := consumer.NewHeadersCarrier(msg)
+	if val := carrier.Get(HeaderPartition); val != "" {
+		if n, errParse := strconv.Atoi(val); errParse == nil {
+			partition = int32(n)
+		}
+	}
+	if val := carrier.Get(HeaderOffset); val != "" {
+		if n, errParse := strconv.ParseInt(val, 10, 64); errParse == nil {
+			offset = kafka.Offset(n)
+		}
+	}
+
+	retry := map[string]string{
+		HeaderAttempt:   strconv.Itoa(attempt),
+		HeaderTopic:     source,
+		HeaderPartition: strconv.Itoa(int(partition)),
+		HeaderOffset:    strconv.FormatInt(int64(offset), 10),
+		HeaderError:     err.Error(),
+	}
+	if delay > 0 {
+		retry[HeaderRetryAt] = strconv.FormatInt(q.clock.Now().Add(delay).UnixNano()/int64(time.Millisecond), 10)
+	}
+
+	headers := make([]kafka.Header, 0, len(msg.Headers)+len(retry))
+	for _, h := range msg.Headers {
+		if _, ok := retry[h.Key]; !ok && h.Key != HeaderRetryAt {
+			headers = append(headers, h)
+		}
+	}
+	for _, key := range []string{HeaderAttempt, HeaderTopic, HeaderPartition, HeaderOffset, HeaderRetryAt, HeaderError} {
+		if val, ok := retry[key]; ok {
+			headers = append(headers, kafka.Header{Key: key, Value: []byte(val)})
+		}
+	}
+
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        headers,
+		Timestamp:      msg.Timestamp,
+	}
+}
@@ -0,0 +1,259 @@
+package retrytopic
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testProducer struct {
+	msgs []*kafka.Message
+	err  error
+	mu   sync.Mutex
+}
+
+func (p *testProducer) Produce(_ context.Context, msg *kafka.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *testProducer) Close() {}
+
+func (p *testProducer) Last() *kafka.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.msgs[len(p.msgs)-1]
+}
+
+type testAdmin struct {
+	specs   []kafka.TopicSpecification
+	results []kafka.TopicResult
+}
+
+func (a *testAdmin) CreateTopics(_ context.Context, specs []kafka.TopicSpecification, _ ...kafka.CreateTopicsAdminOption) ([]kafka.TopicResult, error) {
+	a.specs = append(a.specs, specs...)
+	return a.results, nil
+}
+
+// testSleeper records the sleeps of the partitions
+type testSleeper struct {
+	delays     []time.Duration
+	partitions []kafka.TopicPartition
+	err        error
+}
+
+func (s *testSleeper) Sleep(delay time.Duration, partitions []kafka.TopicPartition) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.delays = append(s.delays, delay)
+	s.partitions = append(s.partitions, partitions...)
+	return nil
+}
+
+func (s *testSleeper) SleepUntil(context.Context, consumer.FuncSleepCondition, []kafka.TopicPartition) error {
+	return nil
+}
+
+func (s *testSleeper) SleepContext(context.Context, time.Duration, []kafka.TopicPartition) (context.CancelFunc, error) {
+	return func() {}, nil
+}
+
+func (s *testSleeper) CancelSleep([]kafka.TopicPartition) error { return nil }
+
+func headers(msg *kafka.Message) map[string]string {
+	retval := make(map[string]string, len(msg.Headers))
+	for _, h := range msg.Headers {
+		retval[h.Key] = string(h.Value)
+	}
+	return retval
+}
+
+func TestTopicName(t *testing.T) {
+
+	require.Equal(t, "a.retry.5s", TopicName("a", time.Second*5))
+	require.Equal(t, "a.retry.1m", TopicName("a", time.Minute))
+	require.Equal(t, "a.retry.90s", TopicName("a", time.Second*90))
+	require.Equal(t, "a.retry.2h", TopicName("a", time.Hour*2))
+	require.Equal(t, "a.retry.250ms", TopicName("a", time.Millisecond*250))
+}
+
+func TestNew(t *testing.T) {
+
+	_, err := New(Config{Topics: map[string]TopicConfig{"a": {}}})
+	require.EqualError(t, err, "producer is nil")
+
+	_, err = New(Config{Producer: &testProducer{}})
+	require.EqualError(t, err, "topics is empty")
+
+	_, err = New(Config{Producer: &testProducer{}, Topics: map[string]TopicConfig{"a": {Delays: []time.Duration{0}}}})
+	require.EqualError(t, err, "invalid retry delay 0s of topic a")
+
+	q, err := New(Config{
+		Producer: &testProducer{},
+		Topics: map[string]TopicConfig{
+			"a": {},
+			"b": {Delays: []time.Duration{time.Second}, DLQTopic: "dead"},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"a", "b", "a.retry.10m", "a.retry.1m", "a.retry.5s", "b.retry.1s"}, q.Topics())
+	require.Equal(t, []string{"a.retry.10m", "a.retry.1m", "a.retry.5s", "b.retry.1s"}, q.RetryTopics())
+
+	admin := &testAdmin{}
+	require.NoError(t, q.CreateTopics(context.Background(), admin))
+
+	topics := make([]string, 0, len(admin.specs))
+	for _, spec := range admin.specs {
+		require.Equal(t, 1, spec.NumPartitions)
+		require.Equal(t, 1, spec.ReplicationFactor)
+		topics = append(topics, spec.Topic)
+	}
+	require.ElementsMatch(t, []string{"a.dlq", "a.retry.5s", "a.retry.1m", "a.retry.10m", "dead", "b.retry.1s"}, topics)
+
+	// the existing topics are skipped
+	admin.results = []kafka.TopicResult{
+		{Topic: "a.dlq", Error: kafka.NewError(kafka.ErrTopicAlreadyExists, "exists", false)},
+	}
+	require.NoError(t, q.CreateTopics(context.Background(), admin))
+
+	admin.results = []kafka.TopicResult{
+		{Topic: "a.dlq", Error: kafka.NewError(kafka.ErrTopicAuthorizationFailed, "denied", false)},
+	}
+	require.EqualError(t, q.CreateTopics(context.Background(), admin), "failed to create topic a.dlq: denied")
+}
+
+func TestQueueWrap(t *testing.T) {
+
+	clk := mock.NewClock(time.Unix(1000, 0))
+	p := &testProducer{}
+
+	q, err := New(Config{
+		Producer: p,
+		Topics:   map[string]TopicConfig{"a": {Delays: []time.Duration{time.Second * 5, time.Minute}}},
+		Clock:    clk,
+	})
+	require.NoError(t, err)
+
+	var calls int
+	fn := q.Wrap(func(context.Context, *zap.Logger, *kafka.Message, consumer.ISleeper, consumer.ICommitter) error {
+		calls++
+		return errors.New("failed")
+	})
+
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	topic := "a"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 10},
+		Key:            []byte("key"),
+		Value:          []byte("value"),
+		Headers:        []kafka.Header{{Key: "trace", Value: []byte("1")}},
+	}
+
+	// the first attempt: the message is sent to the first tier
+	require.NoError(t, fn(ctx, logger, msg, nil, nil))
+	retry := p.Last()
+	require.Equal(t, "a.retry.5s", *retry.TopicPartition.Topic)
+	require.Equal(t, kafka.PartitionAny, retry.TopicPartition.Partition)
+	require.Equal(t, []byte("key"), retry.Key)
+	require.Equal(t, map[string]string{
+		"trace":         "1",
+		HeaderAttempt:   "1",
+		HeaderTopic:     "a",
+		HeaderPartition: "2",
+		HeaderOffset:    "10",
+		HeaderRetryAt:   "1005000",
+		HeaderError:     "failed",
+	}, headers(retry))
+
+	// the partition of the retry is paused until the time of the retry
+	retryTopic := *retry.TopicPartition.Topic
+	retry.TopicPartition = kafka.TopicPartition{Topic: &retryTopic, Partition: 0, Offset: 1}
+
+	sleeper := &testSleeper{}
+	require.Equal(t, consumer.ErrRetryLater, fn(ctx, logger, retry, sleeper, nil))
+	require.Equal(t, 1, calls)
+	require.Equal(t, []time.Duration{time.Second * 5}, sleeper.delays)
+	require.Equal(t, []kafka.TopicPartition{retry.TopicPartition}, sleeper.partitions)
+
+	clk.Add(time.Second * 5)
+	require.NoError(t, fn(ctx, logger, retry, sleeper, nil))
+	require.Equal(t, 2, calls)
+	require.Len(t, sleeper.delays, 1)
+
+	retry = p.Last()
+	require.Equal(t, "a.retry.1m", *retry.TopicPartition.Topic)
+	require.Equal(t, "2", headers(retry)[HeaderAttempt])
+	require.Equal(t, "10", headers(retry)[HeaderOffset])
+	require.Equal(t, "1065000", headers(retry)[HeaderRetryAt])
+
+	// the last attempt: the message is sent to the dlq
+	clk.Add(time.Minute)
+	require.NoError(t, fn(ctx, logger, retry, nil, nil))
+	dlq := p.Last()
+	require.Equal(t, "a.dlq", *dlq.TopicPartition.Topic)
+	require.Equal(t, map[string]string{
+		"trace":         "1",
+		HeaderAttempt:   "3",
+		HeaderTopic:     "a",
+		HeaderPartition: "2",
+		HeaderOffset:    "10",
+		HeaderError:     "failed",
+	}, headers(dlq))
+
+	// the error of the producer is returned
+	p.err = errors.New("unavailable")
+	require.EqualError(t, fn(ctx, logger, msg, nil, nil), "failed to send message to a.retry.5s: unavailable")
+
+	// the topic without the retries
+	other := "b"
+	require.EqualError(t, fn(ctx, logger, &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &other}}, nil, nil), "failed")
+}
+
+func TestQueueRetrySleepError(t *testing.T) {
+
+	clk := mock.NewClock(time.Unix(1000, 0))
+
+	q, err := New(Config{Producer: &testProducer{}, Topics: map[string]TopicConfig{"a": {}}, Clock: clk})
+	require.NoError(t, err)
+
+	fn := q.Wrap(func(context.Context, *zap.Logger, *kafka.Message, consumer.ISleeper, consumer.ICommitter) error {
+		return nil
+	})
+
+	topic := "a.retry.5s"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic},
+		Headers: []kafka.Header{
+			{Key: HeaderAttempt, Value: []byte("1")},
+			{Key: HeaderTopic, Value: []byte("a")},
+			{Key: HeaderRetryAt, Value: []byte("1005000")},
+		},
+	}
+
+	sleeper := &testSleeper{err: errors.New("not assigned")}
+	require.EqualError(t, fn(context.Background(), zap.NewNop(), msg, sleeper, nil), "failed to pause retry partition: not assigned")
+
+	// the time of the retry is passed
+	clk.Add(time.Second * 5)
+	require.NoError(t, fn(context.Background(), zap.NewNop(), msg, sleeper, nil))
+}
@@ -334,3 +334,9 @@ func getID(src int) (id string) {
 
 	return
 }
+
+// Ping checks the availability of the registry (e.g. the self-test of the service)
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.GetConfig(ctx, "")
+	return err
+}
@@ -0,0 +1,131 @@
+package kafka
+
+import (
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+)
+
+// The SASL mechanisms (see SecurityConfig.SASLMechanism)
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+	SASLOAuthBearer = "OAUTHBEARER"
+)
+
+// A SecurityConfig is the TLS and SASL settings of the librdkafka clients (the consumer and the producer).
+// The certificates are set by the paths of the PEM files or by the PEM contents
+// (the contents require the support by the linked librdkafka, see Features).
+type SecurityConfig struct {
+	// TLS enables TLS without the client certificate (it's enabled by any of the certificates too)
+	TLS          bool
+	CALocation   string
+	CertLocation string
+	KeyLocation  string
+	KeyPassword  string
+	CAPEM        []byte
+	CertPEM      []byte
+	KeyPEM       []byte
+	// InsecureSkipVerify disables the verification of the certificate of the broker
+	InsecureSkipVerify bool
+	// SASLMechanism enables SASL: SASLPlain, SASLScramSHA256, SASLScramSHA512 (with the username and the password)
+	// or SASLOAuthBearer (see consumer.Config.TokenProvider)
+	SASLMechanism string
+	Username      string
+	Password      string
+}
+
+// Check checks the settings
+func (s *SecurityConfig) Check() error {
+
+	if s.CALocation != "" && len(s.CAPEM) > 0 {
+		return errors.New("ca location and pem can't be used together")
+	}
+	if s.CertLocation != "" && len(s.CertPEM) > 0 {
+		return errors.New("certificate location and pem can't be used together")
+	}
+	if s.KeyLocation != "" && len(s.KeyPEM) > 0 {
+		return errors.New("key location and pem can't be used together")
+	}
+
+	hasCert := s.CertLocation != "" || len(s.CertPEM) > 0
+	hasKey := s.KeyLocation != "" || len(s.KeyPEM) > 0
+	if hasCert != hasKey {
+		return errors.New("certificate and key must be set together")
+	}
+
+	switch s.SASLMechanism {
+	case "", SASLOAuthBearer:
+	case SASLPlain, SASLScramSHA256, SASLScramSHA512:
+		if s.Username == "" {
+			return errors.New("sasl username is empty")
+		}
+	default:
+		return errors.Errorf("unknown sasl mechanism %s", s.SASLMechanism)
+	}
+
+	return nil
+}
+
+// Protocol returns the value of 'security.protocol'
+func (s *SecurityConfig) Protocol() string {
+
+	tls := s.TLS || s.InsecureSkipVerify ||
+		s.CALocation != "" || s.CertLocation != "" ||
+		len(s.CAPEM) > 0 || len(s.CertPEM) > 0
+
+	switch {
+	case s.SASLMechanism != "" && tls:
+		return "SASL_SSL"
+	case s.SASLMechanism != "":
+		return "SASL_PLAINTEXT"
+	case tls:
+		return "SSL"
+	}
+
+	return "PLAINTEXT"
+}
+
+// Apply checks the settings and sets the properties of librdkafka
+func (s *SecurityConfig) Apply(cfg *confluent.ConfigMap) error {
+
+	if err := s.Check(); err != nil {
+		return err
+	}
+
+	props := confluent.ConfigMap{
+		"security.protocol": s.Protocol(),
+	}
+
+	set := func(key, val string) {
+		if val != "" {
+			props[key] = val
+		}
+	}
+
+	set("ssl.ca.location", s.CALocation)
+	set("ssl.certificate.location", s.CertLocation)
+	set("ssl.key.location", s.KeyLocation)
+	set("ssl.key.password", s.KeyPassword)
+	set("ssl.ca.pem", string(s.CAPEM))
+	set("ssl.certificate.pem", string(s.CertPEM))
+	set("ssl.key.pem", string(s.KeyPEM))
+	if s.InsecureSkipVerify {
+		props["enable.ssl.certificate.verification"] = false
+	}
+
+	set("sasl.mechanisms", s.SASLMechanism)
+	if s.SASLMechanism != SASLOAuthBearer {
+		set("sasl.username", s.Username)
+		set("sasl.password", s.Password)
+	}
+
+	for k, v := range props {
+		// the values aren't in the error (the passwords and the keys)
+		if err := cfg.SetKey(k, v); err != nil {
+			return errors.Wrapf(err, "set config %s failed", k)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"testing"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitSecurityConfigCheck(t *testing.T) {
+
+	for _, testInfo := range []struct {
+		cfg SecurityConfig
+		err string
+	}{
+		{cfg: SecurityConfig{CALocation: "ca.pem", CAPEM: []byte("ca")}, err: "ca location and pem can't be used together"},
+		{cfg: SecurityConfig{CertLocation: "cert.pem", CertPEM: []byte("cert")}, err: "certificate location and pem can't be used together"},
+		{cfg: SecurityConfig{KeyLocation: "key.pem", KeyPEM: []byte("key")}, err: "key location and pem can't be used together"},
+		{cfg: SecurityConfig{CertLocation: "cert.pem"}, err: "certificate and key must be set together"},
+		{cfg: SecurityConfig{KeyPEM: []byte("key")}, err: "certificate and key must be set together"},
+		{cfg: SecurityConfig{SASLMechanism: SASLPlain}, err: "sasl username is empty"},
+		{cfg: SecurityConfig{SASLMechanism: "GSSAPI"}, err: "unknown sasl mechanism GSSAPI"},
+		{cfg: SecurityConfig{}},
+		{cfg: SecurityConfig{SASLMechanism: SASLOAuthBearer}},
+		{cfg: SecurityConfig{CertPEM: []byte("cert"), KeyLocation: "key.pem"}},
+	} {
+		err := testInfo.cfg.Check()
+		if testInfo.err == "" {
+			require.NoError(t, err)
+		} else {
+			require.EqualError(t, err, testInfo.err)
+		}
+	}
+}
+
+func TestUnitSecurityConfigApply(t *testing.T) {
+
+	for _, testInfo := range []struct {
+		cfg    SecurityConfig
+		expect confluent.ConfigMap
+	}{
+		{
+			cfg:    SecurityConfig{},
+			expect: confluent.ConfigMap{"security.protocol": "PLAINTEXT"},
+		},
+		{
+			cfg:    SecurityConfig{TLS: true},
+			expect: confluent.ConfigMap{"security.protocol": "SSL"},
+		},
+		{
+			cfg: SecurityConfig{
+				CALocation:         "ca.pem",
+				CertLocation:       "cert.pem",
+				KeyLocation:        "key.pem",
+				KeyPassword:        "secret",
+				InsecureSkipVerify: true,
+			},
+			expect: confluent.ConfigMap{
+				"security.protocol":                   "SSL",
+				"ssl.ca.location":                     "ca.pem",
+				"ssl.certificate.location":            "cert.pem",
+				"ssl.key.location":                    "key.pem",
+				"ssl.key.password":                    "secret",
+				"enable.ssl.certificate.verification": false,
+			},
+		},
+		{
+			cfg: SecurityConfig{
+				CAPEM:   []byte("ca"),
+				CertPEM: []byte("cert"),
+				KeyPEM:  []byte("key"),
+			},
+			expect: confluent.ConfigMap{
+				"security.protocol":   "SSL",
+				"ssl.ca.pem":          "ca",
+				"ssl.certificate.pem": "cert",
+				"ssl.key.pem":         "key",
+			},
+		},
+		{
+			cfg: SecurityConfig{SASLMechanism: SASLScramSHA512, Username: "user", Password: "password"},
+			expect: confluent.ConfigMap{
+				"security.protocol": "SASL_PLAINTEXT",
+				"sasl.mechanisms":   "SCRAM-SHA-512",
+				"sasl.username":     "user",
+				"sasl.password":     "password",
+			},
+		},
+		{
+			cfg: SecurityConfig{TLS: true, SASLMechanism: SASLOAuthBearer, Username: "user"},
+			expect: confluent.ConfigMap{
+				"security.protocol": "SASL_SSL",
+				"sasl.mechanisms":   "OAUTHBEARER",
+			},
+		},
+	} {
+		cfg := confluent.ConfigMap{}
+		require.NoError(t, testInfo.cfg.Apply(&cfg))
+		require.Equal(t, testInfo.expect, cfg)
+	}
+
+	cfg := confluent.ConfigMap{"bootstrap.servers": "b1"}
+	require.EqualError(t,
+		(&SecurityConfig{SASLMechanism: SASLPlain}).Apply(&cfg),
+		"sasl username is empty")
+	require.Equal(t, confluent.ConfigMap{"bootstrap.servers": "b1"}, cfg)
+}
@@ -0,0 +1,114 @@
+package soak
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// An Action is a synthetic failure of the schedule
+type Action string
+
+const (
+	// ActionDisconnect closes the connections of the consumer to the brokers (the consumer is restarted)
+	ActionDisconnect Action = "disconnect"
+	// ActionRebalance triggers the rebalance of the consumer group (see consumer.Consumer.Resubscribe)
+	ActionRebalance Action = "rebalance"
+	// ActionFail fails the share (Rate) of the handler calls during Duration
+	ActionFail Action = "fail"
+)
+
+// A Step is the action at the time since the start of the test
+type Step struct {
+	At       time.Duration
+	Action   Action
+	Duration time.Duration
+	Rate     float64
+}
+
+// A Schedule is a list of the steps ordered by the time
+type Schedule []Step
+
+type stepJSON struct {
+	At       string  `json:"at"`
+	Action   Action  `json:"action"`
+	Duration string  `json:"duration"`
+	Rate     float64 `json:"rate"`
+}
+
+// LoadSchedule reads the schedule from the JSON file, e.g.:
+//
+//	[
+//	  {"at": "10s", "action": "disconnect"},
+//	  {"at": "30s", "action": "rebalance"},
+//	  {"at": "1m", "action": "fail", "duration": "15s", "rate": 0.2}
+//	]
+func LoadSchedule(path string) (Schedule, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read schedule")
+	}
+
+	return ParseSchedule(data)
+}
+
+// ParseSchedule parses the JSON schedule (see LoadSchedule)
+func ParseSchedule(data []byte) (Schedule, error) {
+
+	var list []stepJSON
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, errors.Wrap(err, "failed to parse schedule")
+	}
+
+	retval := make(Schedule, 0, len(list))
+	for i, item := range list {
+		step := Step{Action: item.Action, Rate: item.Rate}
+
+		var err error
+		if step.At, err = time.ParseDuration(item.At); err != nil {
+			return nil, errors.Wrapf(err, "invalid time of step %d", i)
+		}
+
+		if item.Duration != "" {
+			if step.Duration, err = time.ParseDuration(item.Duration); err != nil {
+				return nil, errors.Wrapf(err, "invalid duration of step %d", i)
+			}
+		}
+
+		retval = append(retval, step)
+	}
+
+	if err := retval.Check(); err != nil {
+		return nil, err
+	}
+
+	return retval, nil
+}
+
+// Check checks the steps and sorts them by the time
+func (s Schedule) Check() error {
+
+	for i, step := range s {
+		switch step.Action {
+		case ActionDisconnect, ActionRebalance:
+		case ActionFail:
+			if step.Duration <= 0 || step.Rate <= 0 || step.Rate > 1 {
+				return errors.Errorf("step %d: fail requires the duration and the rate (0..1]", i)
+			}
+		default:
+			return errors.Errorf("step %d: unknown action %q", i, step.Action)
+		}
+
+		if step.At < 0 {
+			return errors.Errorf("step %d: negative time", i)
+		}
+	}
+
+	sort.SliceStable(s, func(i, j int) bool { return s[i].At < s[j].At })
+
+	return nil
+}
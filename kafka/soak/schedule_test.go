@@ -0,0 +1,52 @@
+package soak
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSchedule(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "soak")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "schedule.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[
+		{"at": "1m", "action": "fail", "duration": "15s", "rate": 0.2},
+		{"at": "10s", "action": "disconnect"},
+		{"at": "30s", "action": "rebalance"}
+	]`), 0600))
+
+	s, err := LoadSchedule(path)
+	require.NoError(t, err)
+	require.Equal(t, Schedule{
+		{At: 10 * time.Second, Action: ActionDisconnect},
+		{At: 30 * time.Second, Action: ActionRebalance},
+		{At: time.Minute, Action: ActionFail, Duration: 15 * time.Second, Rate: 0.2},
+	}, s)
+
+	_, err = LoadSchedule(filepath.Join(dir, "unknown.json"))
+	require.Error(t, err)
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+
+	for data, msg := range map[string]string{
+		`{}`:                                   "failed to parse schedule",
+		`[{"at": "x", "action": "rebalance"}]`: "invalid time of step 0",
+		`[{"at": "1s", "action": "fail", "duration": "x"}]`:  "invalid duration of step 0",
+		`[{"at": "1s", "action": "fail", "duration": "1s"}]`: "step 0: fail requires the duration and the rate (0..1]",
+		`[{"at": "1s", "action": "crash"}]`:                  `step 0: unknown action "crash"`,
+		`[{"at": "-1s", "action": "rebalance"}]`:             "step 0: negative time",
+	} {
+		_, err := ParseSchedule([]byte(data))
+		require.Error(t, err, data)
+		require.Contains(t, err.Error(), msg, data)
+	}
+}
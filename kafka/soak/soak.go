@@ -0,0 +1,486 @@
+// Package soak runs the consumer against the test topic while the synthetic failures
+// (disconnects, rebalances, handler failures) are scripted by the schedule, and checks
+// the delivery invariants: no lost messages, bounded duplicates and the ordering of the keys.
+// It's intended for the release qualification of the library and the services.
+package soak
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock"
+	"github.com/dialogs/dialog-go-lib/fault"
+	"github.com/dialogs/dialog-go-lib/idgen"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/dialogs/dialog-go-lib/kafka/producer"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// RunHeader is the header of the test run identifier (the messages of the other runs are skipped)
+const RunHeader = "x-soak-run"
+
+var (
+	// ErrLostMessages is returned if the produced messages aren't processed
+	ErrLostMessages = errors.New("messages are lost")
+	// ErrTooManyDuplicates is returned if the repeated deliveries exceed Config.MaxDuplicates
+	ErrTooManyDuplicates = errors.New("too many duplicates")
+	// ErrOrderViolated is returned if the messages of a key are processed out of order
+	ErrOrderViolated = errors.New("ordering is violated")
+)
+
+// Config is a configuration of the soak test
+type Config struct {
+	// Consumer is the configuration of the tested consumer subscribed to Topic
+	// (OnProcess is replaced by the test handler, the other settings are kept)
+	Consumer *consumer.Config
+	// Producer produces the test messages to Topic
+	Producer producer.Producer
+	Topic    string
+	// Messages is a count of the test messages (1000 by default) of Keys keys (10 by default)
+	Messages int
+	Keys     int
+	// Rate is a count of the produced messages per second (all messages are produced at once by default)
+	Rate     float64
+	Schedule Schedule
+	// Timeout is a max duration of the processing after the messages are produced (5m by default)
+	Timeout time.Duration
+	// MaxDuplicates is a max count of the repeated deliveries (e.g. the uncommitted messages after the restart)
+	MaxDuplicates int
+	// Clock is a source of the time of the schedule (clock.Real by default)
+	Clock clock.Clock
+}
+
+// A StepResult is a result of the scheduled action
+type StepResult struct {
+	Step  Step
+	Time  time.Time
+	Error string
+}
+
+// A Report is a result of the soak test
+type Report struct {
+	Run        string
+	Produced   int
+	Processed  int
+	Lost       int
+	Duplicates int
+	// Failures is a count of the injected failures of the handler
+	Failures int
+	// Restarts is a count of the restarts of the consumer (the disconnects and the failures)
+	Restarts   int
+	Steps      []StepResult
+	Violations []consumer.OrderViolation
+	Duration   time.Duration
+}
+
+// Check checks the invariants of the delivery
+func (r *Report) Check(maxDuplicates int) error {
+
+	if r.Lost > 0 {
+		return errors.Wrapf(ErrLostMessages, "%d of %d", r.Lost, r.Produced)
+	}
+
+	if r.Duplicates > maxDuplicates {
+		return errors.Wrapf(ErrTooManyDuplicates, "%d > %d", r.Duplicates, maxDuplicates)
+	}
+
+	if len(r.Violations) > 0 {
+		return errors.Wrap(ErrOrderViolated, r.Violations[0].Error())
+	}
+
+	return nil
+}
+
+// Run runs the soak test until all produced messages are processed or the timeout.
+// The report is returned with the error of the invariants.
+func Run(ctx context.Context, cfg Config, logger *zap.Logger) (*Report, error) {
+
+	r, err := newRunner(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := r.run(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	return report, report.Check(cfg.MaxDuplicates)
+}
+
+type runner struct {
+	cfg      Config
+	logger   *zap.Logger
+	id       string
+	clock    clock.Clock
+	tracker  *tracker
+	checker  *consumer.OrderingChecker
+	failure  *fault.Injector
+	failEnd  time.Time
+	failures int
+	restarts int
+	steps    []StepResult
+	current  *consumer.Consumer
+	stopped  bool
+	mu       sync.Mutex
+}
+
+func newRunner(cfg Config, logger *zap.Logger) (*runner, error) {
+
+	if cfg.Consumer == nil || cfg.Producer == nil || cfg.Topic == "" {
+		return nil, errors.New("consumer, producer and topic are required")
+	}
+
+	if err := cfg.Schedule.Check(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Messages <= 0 {
+		cfg.Messages = 1000
+	}
+	if cfg.Keys <= 0 {
+		cfg.Keys = 10
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Minute * 5
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real
+	}
+
+	id, err := idgen.Default.NewID()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &runner{
+		cfg:     cfg,
+		logger:  logger.With(zap.String("soak", id)),
+		id:      id,
+		clock:   cfg.Clock,
+		tracker: newTracker(cfg.Messages),
+	}
+
+	r.checker = consumer.NewOrderingChecker(consumer.OrderingConfig{
+		Header:          producer.SequenceHeader,
+		AllowRedelivery: true,
+		OnViolation:     func(context.Context, *zap.Logger, *consumer.OrderViolation) {},
+	})
+
+	return r, nil
+}
+
+func (r *runner) run(ctx context.Context) (*Report, error) {
+
+	start := r.clock.Now()
+
+	consumerCfg := r.cfg.Consumer.Clone()
+	consumerCfg.OnProcess = r.process
+	consumerCfg.OnProcessBatch = nil
+
+	c, err := consumer.New(consumerCfg, r.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	var consumeErr error
+	go func() {
+		defer wg.Done()
+		consumeErr = r.consume(c)
+	}()
+
+	go func() {
+		defer wg.Done()
+		r.execute(ctx, start)
+	}()
+
+	produced, err := r.produce(ctx)
+	if err == nil {
+		r.wait(ctx)
+	}
+
+	cancel()
+	r.stop()
+	wg.Wait()
+
+	if err == nil {
+		err = consumeErr
+	}
+
+	return r.report(produced, r.clock.Since(start)), err
+}
+
+// process is OnProcess of the tested consumer
+func (r *runner) process(ctx context.Context, logger *zap.Logger, msg *kafka.Message, _ consumer.ISleeper, _ consumer.ICommitter) error {
+
+	if consumer.NewHeadersCarrier(msg).Get(RunHeader) != r.id {
+		return nil
+	}
+
+	r.checker.Check(ctx, logger, msg)
+
+	r.mu.Lock()
+	injector := r.failure
+	if !r.clock.Now().Before(r.failEnd) {
+		injector = nil
+	}
+	r.mu.Unlock()
+
+	if err := injector.Inject(ctx); err != nil {
+		r.mu.Lock()
+		r.failures++
+		r.mu.Unlock()
+		return err
+	}
+
+	n, err := strconv.Atoi(string(msg.Value))
+	if err != nil {
+		logger.Warn("invalid test message", zap.Error(err))
+		return nil
+	}
+
+	r.tracker.Add(n)
+
+	return nil
+}
+
+// consume runs the consumer and restarts it after the disconnects and the failures until the stop
+func (r *runner) consume(c *consumer.Consumer) error {
+
+	for {
+		if !r.setCurrent(c) {
+			return nil
+		}
+
+		if err := c.Start(); err != nil {
+			r.logger.Warn("consumer is stopped", zap.Error(err))
+		}
+
+		r.mu.Lock()
+		stopped := r.stopped
+		if !stopped {
+			r.restarts++
+		}
+		r.mu.Unlock()
+
+		if stopped {
+			return nil
+		}
+
+		var err error
+		if c, err = c.Restart(); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *runner) setCurrent(c *consumer.Consumer) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stopped {
+		return false
+	}
+
+	r.current = c
+	return true
+}
+
+func (r *runner) stop() {
+	r.mu.Lock()
+	r.stopped = true
+	c := r.current
+	r.mu.Unlock()
+
+	if c != nil {
+		if err := c.Stop(); err != nil {
+			r.logger.Warn("failed to stop consumer", zap.Error(err))
+		}
+	}
+}
+
+// execute runs the steps of the schedule
+func (r *runner) execute(ctx context.Context, start time.Time) {
+
+	for _, step := range r.cfg.Schedule {
+		timer := r.clock.NewTimer(start.Add(step.At).Sub(r.clock.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+
+		res := StepResult{Step: step, Time: r.clock.Now()}
+		if err := r.apply(step); err != nil {
+			r.logger.Warn("failed to apply step", zap.String("action", string(step.Action)), zap.Error(err))
+			res.Error = err.Error()
+		} else {
+			r.logger.Info("step is applied", zap.String("action", string(step.Action)))
+		}
+
+		r.mu.Lock()
+		r.steps = append(r.steps, res)
+		r.mu.Unlock()
+	}
+}
+
+func (r *runner) apply(step Step) error {
+
+	r.mu.Lock()
+	c := r.current
+	r.mu.Unlock()
+
+	if c == nil && step.Action != ActionFail {
+		return errors.New("consumer is not started")
+	}
+
+	switch step.Action {
+	case ActionDisconnect:
+		// the consumer is restarted by consume
+		return c.Stop()
+
+	case ActionRebalance:
+		return c.Resubscribe()
+
+	case ActionFail:
+		r.mu.Lock()
+		r.failure = fault.NewInjector(fault.Config{Enabled: true, ErrorRate: step.Rate})
+		r.failEnd = r.clock.Now().Add(step.Duration)
+		r.mu.Unlock()
+	}
+
+	return nil
+}
+
+// produce produces the numbered messages with the sequences of the keys and returns the count of the produced ones
+func (r *runner) produce(ctx context.Context) (int, error) {
+
+	p := producer.NewSequenceProducer(r.cfg.Producer, producer.SequenceHeader)
+
+	var ticker clock.Ticker
+	if r.cfg.Rate > 0 {
+		ticker = r.clock.NewTicker(time.Duration(float64(time.Second) / r.cfg.Rate))
+		defer ticker.Stop()
+	}
+
+	for i := 0; i < r.cfg.Messages; i++ {
+		if ticker != nil && i > 0 {
+			select {
+			case <-ctx.Done():
+				return i, ctx.Err()
+			case <-ticker.C():
+			}
+		}
+
+		msg := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &r.cfg.Topic, Partition: kafka.PartitionAny},
+			Key:            []byte("key-" + strconv.Itoa(i%r.cfg.Keys)),
+			Value:          []byte(strconv.Itoa(i)),
+			Headers:        []kafka.Header{{Key: RunHeader, Value: []byte(r.id)}},
+		}
+
+		if err := p.Produce(ctx, msg); err != nil {
+			return i, errors.Wrap(err, "failed to produce test message")
+		}
+	}
+
+	return r.cfg.Messages, nil
+}
+
+// wait waits for the processing of the messages until the timeout
+func (r *runner) wait(ctx context.Context) {
+
+	ctx, cancel := clock.WithTimeout(ctx, r.clock, r.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-r.tracker.Done():
+	case <-ctx.Done():
+		r.logger.Warn("processing is not completed", zap.Error(ctx.Err()))
+	}
+}
+
+func (r *runner) report(produced int, duration time.Duration) *Report {
+
+	processed, duplicates := r.tracker.Result(produced)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return &Report{
+		Run:        r.id,
+		Produced:   produced,
+		Processed:  processed,
+		Lost:       produced - processed,
+		Duplicates: duplicates,
+		Failures:   r.failures,
+		Restarts:   r.restarts,
+		Steps:      append([]StepResult(nil), r.steps...),
+		Violations: r.checker.Violations(),
+		Duration:   duration,
+	}
+}
+
+// tracker counts the deliveries of the test messages
+type tracker struct {
+	counts []int
+	unique int
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+func newTracker(size int) *tracker {
+	return &tracker{
+		counts: make([]int, size),
+		done:   make(chan struct{}),
+	}
+}
+
+// Add counts the delivery of the message
+func (t *tracker) Add(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if n < 0 || n >= len(t.counts) {
+		return
+	}
+
+	t.counts[n]++
+	if t.counts[n] == 1 {
+		t.unique++
+		if t.unique == len(t.counts) {
+			close(t.done)
+		}
+	}
+}
+
+// Done returns the channel closed when all messages are delivered
+func (t *tracker) Done() <-chan struct{} {
+	return t.done
+}
+
+// Result returns the counts of the delivered and the repeated messages of the first messages (count)
+func (t *tracker) Result(count int) (processed, duplicates int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, n := range t.counts[:count] {
+		if n > 0 {
+			processed++
+			duplicates += n - 1
+		}
+	}
+
+	return
+}
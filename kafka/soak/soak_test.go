@@ -0,0 +1,171 @@
+package soak
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/clock/mock"
+	"github.com/dialogs/dialog-go-lib/fault"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/dialogs/dialog-go-lib/kafka/producer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testProducer struct {
+	msgs []*kafka.Message
+	mu   sync.Mutex
+}
+
+func (p *testProducer) Produce(_ context.Context, msg *kafka.Message) error {
+	p.mu.Lock()
+	p.msgs = append(p.msgs, msg)
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *testProducer) Close() {}
+
+func newTestRunner(t *testing.T, cfg Config) *runner {
+
+	cfg.Consumer = &consumer.Config{}
+	cfg.Producer = &testProducer{}
+	cfg.Topic = "soak"
+
+	r, err := newRunner(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	return r
+}
+
+func TestReportCheck(t *testing.T) {
+
+	require.NoError(t, (&Report{Produced: 10, Processed: 10, Duplicates: 2}).Check(2))
+
+	err := (&Report{Produced: 10, Processed: 9, Lost: 1}).Check(0)
+	require.True(t, errors.Is(err, ErrLostMessages))
+	require.EqualError(t, err, "1 of 10: messages are lost")
+
+	err = (&Report{Produced: 10, Processed: 10, Duplicates: 3}).Check(2)
+	require.True(t, errors.Is(err, ErrTooManyDuplicates))
+
+	err = (&Report{Violations: []consumer.OrderViolation{{Key: "k", Expected: 2, Actual: 1}}}).Check(0)
+	require.True(t, errors.Is(err, ErrOrderViolated))
+}
+
+func TestTracker(t *testing.T) {
+
+	tr := newTracker(3)
+	tr.Add(0)
+	tr.Add(0)
+	tr.Add(2)
+	// unknown message
+	tr.Add(5)
+
+	processed, duplicates := tr.Result(3)
+	require.Equal(t, 2, processed)
+	require.Equal(t, 1, duplicates)
+
+	select {
+	case <-tr.Done():
+		t.Fatal("not all messages are delivered")
+	default:
+	}
+
+	tr.Add(1)
+	<-tr.Done()
+}
+
+func TestRunnerProduceAndProcess(t *testing.T) {
+
+	r := newTestRunner(t, Config{Messages: 4, Keys: 2})
+
+	produced, err := r.produce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 4, produced)
+
+	msgs := r.cfg.Producer.(*testProducer).msgs
+	require.Len(t, msgs, 4)
+	require.Equal(t, "key-1", string(msgs[3].Key))
+	require.Equal(t, "3", string(msgs[3].Value))
+	require.Equal(t, r.id, consumer.NewHeadersCarrier(msgs[3]).Get(RunHeader))
+	require.Equal(t, "2", consumer.NewHeadersCarrier(msgs[3]).Get(producer.SequenceHeader))
+
+	ctx := context.Background()
+	for _, msg := range msgs {
+		require.NoError(t, r.process(ctx, zap.NewNop(), msg, nil, nil))
+	}
+	// the redelivery
+	require.NoError(t, r.process(ctx, zap.NewNop(), msgs[0], nil, nil))
+	// the message of the other run
+	require.NoError(t, r.process(ctx, zap.NewNop(), &kafka.Message{Value: []byte("1")}, nil, nil))
+
+	report := r.report(produced, time.Second)
+	require.Equal(t, 4, report.Processed)
+	require.Equal(t, 1, report.Duplicates)
+	require.Empty(t, report.Violations)
+	require.NoError(t, report.Check(1))
+
+	// out of order
+	require.NoError(t, r.process(ctx, zap.NewNop(), msgs[1], nil, nil))
+	msg := *msgs[0]
+	msg.Headers = []kafka.Header{{Key: RunHeader, Value: []byte(r.id)}, {Key: producer.SequenceHeader, Value: []byte(strconv.Itoa(5))}}
+	require.NoError(t, r.process(ctx, zap.NewNop(), &msg, nil, nil))
+	require.Len(t, r.report(produced, time.Second).Violations, 1)
+}
+
+func TestRunnerFailStep(t *testing.T) {
+
+	clk := mock.NewClock(time.Now())
+	r := newTestRunner(t, Config{Messages: 1, Clock: clk})
+
+	require.EqualError(t, r.apply(Step{Action: ActionRebalance}), "consumer is not started")
+	require.NoError(t, r.apply(Step{Action: ActionFail, Duration: time.Minute, Rate: 1}))
+
+	msg := &kafka.Message{Value: []byte("0"), Headers: []kafka.Header{{Key: RunHeader, Value: []byte(r.id)}}}
+	require.Equal(t, fault.ErrInjected, r.process(context.Background(), zap.NewNop(), msg, nil, nil))
+
+	// the failures are over
+	clk.Add(time.Minute)
+	require.NoError(t, r.process(context.Background(), zap.NewNop(), msg, nil, nil))
+
+	report := r.report(1, time.Minute)
+	require.Equal(t, 1, report.Failures)
+	require.Equal(t, 1, report.Processed)
+}
+
+func TestRunnerExecute(t *testing.T) {
+
+	clk := mock.NewClock(time.Now())
+	r := newTestRunner(t, Config{
+		Clock: clk,
+		Schedule: Schedule{
+			{At: time.Minute, Action: ActionFail, Duration: time.Second, Rate: 0.5},
+			{At: time.Second, Action: ActionRebalance},
+		},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.execute(context.Background(), clk.Now())
+	}()
+
+	for _, d := range []time.Duration{time.Second, time.Second * 59} {
+		require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+		clk.Add(d)
+	}
+	<-done
+
+	report := r.report(0, time.Minute)
+	require.Len(t, report.Steps, 2)
+	require.Equal(t, ActionRebalance, report.Steps[0].Step.Action)
+	require.Equal(t, "consumer is not started", report.Steps[0].Error)
+	require.Equal(t, ActionFail, report.Steps[1].Step.Action)
+	require.Empty(t, report.Steps[1].Error)
+}
@@ -0,0 +1,243 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// A Snapshot is a state of the table and the changelog offsets applied to the state
+type Snapshot struct {
+	Entries map[string][]byte `json:"entries"`
+	// Offsets are the next offsets of the changelog partitions by the topics
+	// (the messages before them are in the entries)
+	Offsets map[string]map[int32]int64 `json:"offsets"`
+}
+
+// ISnapshot is a storage of the table snapshots (a file, an object storage, etc.)
+type ISnapshot interface {
+	// Load returns nil if the snapshot doesn't exist
+	Load(ctx context.Context) (*Snapshot, error)
+	Save(ctx context.Context, s *Snapshot) error
+}
+
+// FileSnapshot is a snapshot storage in the file
+type FileSnapshot struct {
+	path string
+}
+
+// NewFileSnapshot returns the snapshot storage in the file
+func NewFileSnapshot(path string) *FileSnapshot {
+	return &FileSnapshot{path: path}
+}
+
+func (f *FileSnapshot) Load(_ context.Context) (*Snapshot, error) {
+
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to read snapshot")
+	}
+
+	s := &Snapshot{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, errors.Wrap(err, "failed to decode snapshot")
+	}
+
+	return s, nil
+}
+
+func (f *FileSnapshot) Save(_ context.Context, s *Snapshot) error {
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode snapshot")
+	}
+
+	// write and rename: the snapshot isn't corrupted by a crash
+	tmp := f.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write snapshot")
+	}
+
+	return errors.Wrap(os.Rename(tmp, f.path), "failed to write snapshot")
+}
+
+// A Bootstrap restores the table from the snapshot and then applies the changelog
+// from the offsets recorded in the snapshot: the changelog messages already in the snapshot are skipped,
+// the consumer starts from the offsets of the snapshot regardless of the group offsets (see OnAssign).
+type Bootstrap struct {
+	table    *Table
+	snapshot ISnapshot
+	offsets  map[string]map[int32]int64
+	mu       sync.Mutex
+}
+
+// NewBootstrap returns the bootstrap of the table
+func NewBootstrap(t *Table, s ISnapshot) *Bootstrap {
+	return &Bootstrap{
+		table:    t,
+		snapshot: s,
+		offsets:  make(map[string]map[int32]int64),
+	}
+}
+
+// Load restores the table from the snapshot (the table isn't changed if there is no snapshot)
+func (b *Bootstrap) Load(ctx context.Context) error {
+
+	s, err := b.snapshot.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return nil
+	}
+
+	for k, v := range s.Entries {
+		if err := b.table.store.Put(k, v); err != nil {
+			return errors.Wrap(err, "failed to restore snapshot")
+		}
+	}
+
+	b.mu.Lock()
+	for topic, partitions := range s.Offsets {
+		for p, o := range partitions {
+			b.setOffset(topic, p, o)
+		}
+	}
+	b.mu.Unlock()
+
+	return nil
+}
+
+// Save writes the table and the applied changelog offsets to the snapshot
+func (b *Bootstrap) Save(ctx context.Context) error {
+
+	// the offsets are taken before the entries: the messages applied in between are applied again after the restore
+	s := &Snapshot{
+		Entries: make(map[string][]byte),
+		Offsets: b.Offsets(),
+	}
+
+	err := b.table.store.Range(func(key string, val []byte) error {
+		s.Entries[key] = val
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to read table")
+	}
+
+	return b.snapshot.Save(ctx, s)
+}
+
+// Offsets returns the next offsets of the changelog partitions applied to the table by the topics
+func (b *Bootstrap) Offsets() map[string]map[int32]int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	offsets := make(map[string]map[int32]int64, len(b.offsets))
+	for topic, partitions := range b.offsets {
+		offsets[topic] = make(map[int32]int64, len(partitions))
+		for p, o := range partitions {
+			offsets[topic][p] = o
+		}
+	}
+
+	return offsets
+}
+
+func (b *Bootstrap) setOffset(topic string, p int32, o int64) {
+
+	partitions, ok := b.offsets[topic]
+	if !ok {
+		partitions = make(map[int32]int64)
+		b.offsets[topic] = partitions
+	}
+
+	partitions[p] = o
+}
+
+// Apply updates the table by the changelog message if the message isn't in the snapshot
+func (b *Bootstrap) Apply(msg *kafka.Message) error {
+
+	topic := stringValue(msg.TopicPartition.Topic)
+	p := msg.TopicPartition.Partition
+	o := int64(msg.TopicPartition.Offset)
+
+	b.mu.Lock()
+	next, ok := b.offsets[topic][p]
+	b.mu.Unlock()
+	if ok && o < next {
+		return nil
+	}
+
+	if err := b.table.Apply(msg); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.setOffset(topic, p, o+1)
+	b.mu.Unlock()
+
+	return nil
+}
+
+// OnAssign starts the assigned changelog partitions from the offsets applied to the table
+// (see consumer.Config.OnAssign): the group offsets can be ahead of the snapshot
+// or missing (e.g. the new group with 'auto.offset.reset=latest')
+func (b *Bootstrap) OnAssign(_ context.Context, _ *zap.Logger, partitions []kafka.TopicPartition) []kafka.TopicPartition {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	retval := make([]kafka.TopicPartition, len(partitions))
+	copy(retval, partitions)
+
+	for i := range retval {
+		tp := &retval[i]
+		if next, ok := b.offsets[stringValue(tp.Topic)][tp.Partition]; ok {
+			tp.Offset = kafka.Offset(next)
+		}
+	}
+
+	return retval
+}
+
+// Handler returns the processing function of the changelog consumer
+func (b *Bootstrap) Handler() consumer.FuncOnProcess {
+	return func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ consumer.ISleeper, _ consumer.ICommitter) error {
+		return b.Apply(msg)
+	}
+}
+
+// NewBootstrapConsumer restores the table from the snapshot and returns the consumer of the changelog topic
+// which starts from the offsets of the snapshot
+func NewBootstrapConsumer(ctx context.Context, b *Bootstrap, topic string, cfg *consumer.Config, opts ...consumer.Option) (*consumer.Consumer, error) {
+
+	if err := b.Load(ctx); err != nil {
+		return nil, err
+	}
+
+	opts = append(opts, consumer.WithConfig(func(cfg *consumer.Config) {
+		cfg.Topics = []string{topic}
+		cfg.OnProcess = b.Handler()
+		cfg.OnAssign = b.OnAssign
+	}))
+
+	return consumer.NewWithOptions(cfg, opts...)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,87 @@
+package stream
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBootstrap(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "bootstrap")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	snapshot := NewFileSnapshot(filepath.Join(dir, "snapshot.json"))
+	ctx := context.Background()
+
+	topic := "changelog"
+	changelog := func(p int32, o int64, key, val string) *kafka.Message {
+		msg := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: p, Offset: kafka.Offset(o)},
+			Key:            []byte(key),
+		}
+		if val != "" {
+			msg.Value = []byte(val)
+		}
+		return msg
+	}
+
+	// no snapshot: the changelog is applied from the beginning
+	b := NewBootstrap(NewTable(NewMemoryStore()), snapshot)
+	require.NoError(t, b.Load(ctx))
+	require.Empty(t, b.Offsets())
+
+	require.NoError(t, b.Apply(changelog(0, 0, "a", "1")))
+	require.NoError(t, b.Apply(changelog(0, 1, "b", "2")))
+	require.NoError(t, b.Apply(changelog(1, 0, "c", "3")))
+	require.NoError(t, b.Save(ctx))
+	require.Equal(t, map[string]map[int32]int64{topic: {0: 2, 1: 1}}, b.Offsets())
+
+	// restore: the changelog before the snapshot offsets is skipped
+	table := NewTable(NewMemoryStore())
+	b = NewBootstrap(table, snapshot)
+	require.NoError(t, b.Load(ctx))
+	require.Equal(t, map[string]map[int32]int64{topic: {0: 2, 1: 1}}, b.Offsets())
+
+	val, ok, err := table.Lookup([]byte("b"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("2"), val)
+
+	// replayed by the group offsets behind the snapshot
+	require.NoError(t, b.Apply(changelog(0, 1, "b", "old")))
+	// tombstone after the snapshot
+	require.NoError(t, b.Apply(changelog(0, 2, "a", "")))
+	require.NoError(t, b.Apply(changelog(2, 0, "d", "4")))
+
+	val, _, err = table.Lookup([]byte("b"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("2"), val)
+
+	_, ok, err = table.Lookup([]byte("a"))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.Equal(t, map[string]map[int32]int64{topic: {0: 3, 1: 1, 2: 1}}, b.Offsets())
+
+	// the assigned partitions start from the snapshot offsets, the others from the committed ones
+	other := "other"
+	require.Equal(t,
+		[]kafka.TopicPartition{
+			{Topic: &topic, Partition: 0, Offset: 3},
+			{Topic: &topic, Partition: 3, Offset: kafka.OffsetInvalid},
+			{Topic: &other, Partition: 0, Offset: 5},
+		},
+		b.OnAssign(ctx, zap.NewNop(), []kafka.TopicPartition{
+			{Topic: &topic, Partition: 0, Offset: 10},
+			{Topic: &topic, Partition: 3, Offset: kafka.OffsetInvalid},
+			{Topic: &other, Partition: 0, Offset: 5},
+		}))
+}
@@ -0,0 +1,50 @@
+package stream
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/dialogs/dialog-go-lib/kafka/producer"
+	"go.uber.org/zap"
+)
+
+// IProducer produces the messages and waits for the delivery reports (see producer.SyncProducer)
+type IProducer = producer.ISyncProducer
+
+// Handler returns the processing function of the consumer.
+// The function returns an error if any sink message isn't delivered,
+// so the offset of the source message is committed after the delivery only.
+func Handler(s *Stream, p IProducer) consumer.FuncOnProcess {
+	return func(ctx context.Context, logger *zap.Logger, msg *kafka.Message, _ consumer.ISleeper, _ consumer.ICommitter) error {
+
+		list, err := s.Process(ctx, msg)
+		if err != nil {
+			return err
+		}
+
+		if len(list) == 0 {
+			logger.Debug("no sink messages")
+			return nil
+		}
+
+		_, err = p.ProduceSync(ctx, list...)
+		return err
+	}
+}
+
+// NewConsumer returns the consumer of the stream sources.
+// The topics and the processing function of the configuration are replaced by the stream.
+func NewConsumer(s *Stream, p IProducer, cfg *consumer.Config, opts ...consumer.Option) (*consumer.Consumer, error) {
+
+	if err := s.Check(); err != nil {
+		return nil, err
+	}
+
+	opts = append(opts, consumer.WithConfig(func(cfg *consumer.Config) {
+		cfg.Topics = s.Sources()
+		cfg.OnProcess = Handler(s, p)
+	}))
+
+	return consumer.NewWithOptions(cfg, opts...)
+}
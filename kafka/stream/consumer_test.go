@@ -0,0 +1,67 @@
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/dialogs/dialog-go-lib/kafka/producer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testProducer struct {
+	err  error
+	msgs []*kafka.Message
+}
+
+func (p *testProducer) ProduceSync(_ context.Context, msgs ...*kafka.Message) ([]producer.Result, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	p.msgs = append(p.msgs, msgs...)
+	return make([]producer.Result, len(msgs)), nil
+}
+
+func TestHandler(t *testing.T) {
+
+	p := &testProducer{}
+	s := From("in").
+		Filter(func(_ context.Context, msg *kafka.Message) (bool, error) {
+			return string(msg.Value) != "skip", nil
+		}).
+		To("out")
+
+	fn := Handler(s, p)
+
+	require.NoError(t, fn(context.Background(), zap.NewNop(), newMessage("a"), nil, nil))
+	require.NoError(t, fn(context.Background(), zap.NewNop(), newMessage("skip"), nil, nil))
+	require.Equal(t, []string{"out:a"}, values(p.msgs))
+
+	// the offset isn't committed if the delivery is failed
+	p.err = errors.New("failed")
+	require.EqualError(t, fn(context.Background(), zap.NewNop(), newMessage("b"), nil, nil), "failed")
+}
+
+func TestNewConsumer(t *testing.T) {
+
+	cfg := &consumer.Config{
+		OnError: func(context.Context, *zap.Logger, error) {},
+		ConfigMap: &kafka.ConfigMap{
+			"group.id":          "test",
+			"bootstrap.servers": "localhost:1",
+		},
+	}
+
+	_, err := NewConsumer(From("in"), &testProducer{}, cfg)
+	require.EqualError(t, err, "stream has no sinks")
+
+	c, err := NewConsumer(From("in").To("out"), &testProducer{}, cfg)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	require.Empty(t, cfg.Topics)
+	require.Nil(t, cfg.OnProcess)
+}
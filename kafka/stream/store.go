@@ -0,0 +1,170 @@
+package stream
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// IStore is a key-value state store of the aggregations (see MemoryStore and BoltStore)
+type IStore interface {
+	Get(key string) ([]byte, bool, error)
+	Put(key string, val []byte) error
+	Delete(key string) error
+	// Range calls the function for all entries in the order of the keys
+	Range(fn func(key string, val []byte) error) error
+}
+
+// MemoryStore is a state store in the memory (the state is lost after the restart)
+type MemoryStore struct {
+	data map[string][]byte
+	mu   sync.RWMutex
+}
+
+// NewMemoryStore returns the empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		data: make(map[string][]byte),
+	}
+}
+
+func (s *MemoryStore) Get(key string) ([]byte, bool, error) {
+	s.mu.RLock()
+	val, ok := s.data[key]
+	s.mu.RUnlock()
+
+	return val, ok, nil
+}
+
+func (s *MemoryStore) Put(key string, val []byte) error {
+	s.mu.Lock()
+	s.data[key] = val
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	delete(s.data, key)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *MemoryStore) Range(fn func(key string, val []byte) error) error {
+	s.mu.RLock()
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		keys = append(keys, k)
+	}
+	s.mu.RUnlock()
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		val, ok, _ := s.Get(k)
+		if !ok {
+			continue
+		}
+		if err := fn(k, val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// _BoltBucket is the bucket of the entries of BoltStore
+var _BoltBucket = []byte("state")
+
+// BoltStore is a state store in the boltdb file (go.etcd.io/bbolt): the state is kept after the restart.
+// It must be closed to release the file.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens the store in the file (the file is created if it doesn't exist).
+// The file is locked by the store: the opening fails after the timeout if it's used by another process.
+func NewBoltStore(path string, timeout time.Duration) (*BoltStore, error) {
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: timeout})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open state store")
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(_BoltBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "failed to create state bucket")
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Get(key string) (retval []byte, ok bool, _ error) {
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// the value is valid in the transaction only
+		if val := tx.Bucket(_BoltBucket).Get([]byte(key)); val != nil {
+			retval, ok = append([]byte{}, val...), true
+		}
+		return nil
+	})
+
+	return retval, ok, errors.Wrap(err, "failed to read state store")
+}
+
+func (s *BoltStore) Put(key string, val []byte) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(_BoltBucket).Put([]byte(key), val)
+	})
+
+	return errors.Wrap(err, "failed to write state store")
+}
+
+func (s *BoltStore) Delete(key string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(_BoltBucket).Delete([]byte(key))
+	})
+
+	return errors.Wrap(err, "failed to write state store")
+}
+
+// Range calls the function after the entries are read: the function can change the store
+func (s *BoltStore) Range(fn func(key string, val []byte) error) error {
+
+	type entry struct {
+		key string
+		val []byte
+	}
+
+	var entries []entry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(_BoltBucket).ForEach(func(k, v []byte) error {
+			entries = append(entries, entry{key: string(k), val: append([]byte{}, v...)})
+			return nil
+		})
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to read state store")
+	}
+
+	for _, e := range entries {
+		if err := fn(e.key, e.val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes the file
+func (s *BoltStore) Close() error {
+	return errors.Wrap(s.db.Close(), "failed to close state store")
+}
@@ -0,0 +1,82 @@
+package stream
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestBoltStore(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state.db")
+
+	s, err := NewBoltStore(path, time.Second)
+	require.NoError(t, err)
+	testStore(t, s)
+
+	// the file is locked by the store
+	_, err = NewBoltStore(path, 10*time.Millisecond)
+	require.Error(t, err)
+	require.NoError(t, s.Close())
+
+	// the state is loaded from the file
+	s, err = NewBoltStore(path, time.Second)
+	require.NoError(t, err)
+	defer s.Close()
+
+	val, ok, err := s.Get("b")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("2"), val)
+
+	_, ok, err = s.Get("c")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// the store can be changed by the range function
+	err = s.Range(func(key string, _ []byte) error {
+		return s.Delete(key)
+	})
+	require.NoError(t, err)
+
+	_, ok, err = s.Get("a")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func testStore(t *testing.T, s IStore) {
+	t.Helper()
+
+	require.NoError(t, s.Put("b", []byte("2")))
+	require.NoError(t, s.Put("a", []byte("1")))
+	require.NoError(t, s.Put("c", []byte("3")))
+	require.NoError(t, s.Delete("c"))
+
+	val, ok, err := s.Get("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("1"), val)
+
+	_, ok, err = s.Get("c")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	var keys []string
+	require.NoError(t, s.Range(func(key string, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	}))
+	require.Equal(t, []string{"a", "b"}, keys)
+}
@@ -0,0 +1,199 @@
+package stream
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+)
+
+// FuncMap returns the new message (the message can be modified)
+type FuncMap func(ctx context.Context, msg *kafka.Message) (*kafka.Message, error)
+
+// FuncFilter reports that the message must be kept
+type FuncFilter func(ctx context.Context, msg *kafka.Message) (bool, error)
+
+// FuncFlatMap returns zero or more messages of the message
+type FuncFlatMap func(ctx context.Context, msg *kafka.Message) ([]*kafka.Message, error)
+
+// A Branch is a sub-stream of the messages matched by the predicate (see Stream.Branch)
+type Branch struct {
+	// Predicate matches the messages of the branch, nil matches all messages
+	Predicate FuncFilter
+	Stream    *Stream
+}
+
+type stage func(ctx context.Context, msg *kafka.Message) ([]*kafka.Message, error)
+
+// A Stream is a declarative pipeline of the messages processing:
+// source topics -> map/filter/flatMap -> sink topics or branches.
+//
+//	stream.From("orders").
+//		Filter(isPaid).
+//		Map(toInvoice).
+//		To("invoices")
+type Stream struct {
+	sources  []string
+	stages   []stage
+	sinks    []string
+	branches []Branch
+}
+
+// From returns the stream of the source topics
+func From(topics ...string) *Stream {
+	return &Stream{sources: topics}
+}
+
+// New returns the stream without sources (the stream of the branch)
+func New() *Stream {
+	return &Stream{}
+}
+
+// Map replaces the messages by the function results. The message is dropped if the result is nil.
+func (s *Stream) Map(fn FuncMap) *Stream {
+	s.stages = append(s.stages, func(ctx context.Context, msg *kafka.Message) ([]*kafka.Message, error) {
+		res, err := fn(ctx, msg)
+		if err != nil || res == nil {
+			return nil, err
+		}
+
+		return []*kafka.Message{res}, nil
+	})
+
+	return s
+}
+
+// Filter keeps the messages matched by the function
+func (s *Stream) Filter(fn FuncFilter) *Stream {
+	s.stages = append(s.stages, func(ctx context.Context, msg *kafka.Message) ([]*kafka.Message, error) {
+		ok, err := fn(ctx, msg)
+		if err != nil || !ok {
+			return nil, err
+		}
+
+		return []*kafka.Message{msg}, nil
+	})
+
+	return s
+}
+
+// FlatMap replaces the messages by the lists of the function results
+func (s *Stream) FlatMap(fn FuncFlatMap) *Stream {
+	s.stages = append(s.stages, stage(fn))
+	return s
+}
+
+// To sends the messages to the sink topics
+func (s *Stream) To(topics ...string) *Stream {
+	s.sinks = append(s.sinks, topics...)
+	return s
+}
+
+// Branch sends every message to the first branch matched by the predicate.
+// The message is dropped if no branch is matched.
+func (s *Stream) Branch(branches ...Branch) *Stream {
+	s.branches = append(s.branches, branches...)
+	return s
+}
+
+// Sources returns the source topics
+func (s *Stream) Sources() []string {
+	return s.sources
+}
+
+// Check validates the stream: the sources are set and every stream has a sink or branches
+func (s *Stream) Check() error {
+
+	if len(s.sources) == 0 {
+		return errors.New("stream sources are empty")
+	}
+
+	return s.checkOutputs()
+}
+
+func (s *Stream) checkOutputs() error {
+
+	if len(s.sinks) == 0 && len(s.branches) == 0 {
+		return errors.New("stream has no sinks")
+	}
+
+	for _, topic := range s.sinks {
+		if topic == "" {
+			return errors.New("stream sink topic is empty")
+		}
+	}
+
+	for i, b := range s.branches {
+		if b.Stream == nil {
+			return errors.Errorf("stream of branch %d is nil", i)
+		}
+		if err := b.Stream.checkOutputs(); err != nil {
+			return errors.Wrapf(err, "branch %d", i)
+		}
+	}
+
+	return nil
+}
+
+// Process returns the messages of the sink topics produced by the message
+func (s *Stream) Process(ctx context.Context, msg *kafka.Message) ([]*kafka.Message, error) {
+
+	list := []*kafka.Message{msg}
+	for _, fn := range s.stages {
+		next := make([]*kafka.Message, 0, len(list))
+		for _, m := range list {
+			res, err := fn(ctx, m)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, res...)
+		}
+
+		list = next
+	}
+
+	var retval []*kafka.Message
+	for _, m := range list {
+		for i := range s.sinks {
+			retval = append(retval, sinkMessage(m, s.sinks[i]))
+		}
+
+		res, err := s.branch(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		retval = append(retval, res...)
+	}
+
+	return retval, nil
+}
+
+func (s *Stream) branch(ctx context.Context, msg *kafka.Message) ([]*kafka.Message, error) {
+
+	for _, b := range s.branches {
+		if b.Predicate != nil {
+			ok, err := b.Predicate(ctx, msg)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		return b.Stream.Process(ctx, msg)
+	}
+
+	return nil, nil
+}
+
+// sinkMessage returns the copy of the message to the topic (any partition)
+func sinkMessage(msg *kafka.Message, topic string) *kafka.Message {
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        msg.Headers,
+		Timestamp:      msg.Timestamp,
+	}
+}
@@ -0,0 +1,105 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func newMessage(value string) *kafka.Message {
+	topic := "in"
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 3, Offset: 10},
+		Key:            []byte("key"),
+		Value:          []byte(value),
+	}
+}
+
+func values(list []*kafka.Message) (retval []string) {
+	for _, msg := range list {
+		retval = append(retval, *msg.TopicPartition.Topic+":"+string(msg.Value))
+	}
+	return
+}
+
+func TestStreamProcess(t *testing.T) {
+
+	isEven := func(_ context.Context, msg *kafka.Message) (bool, error) {
+		return len(msg.Value)%2 == 0, nil
+	}
+
+	s := From("in").
+		Filter(func(_ context.Context, msg *kafka.Message) (bool, error) {
+			return len(msg.Value) > 0, nil
+		}).
+		FlatMap(func(_ context.Context, msg *kafka.Message) ([]*kafka.Message, error) {
+			var retval []*kafka.Message
+			for _, part := range bytes.Split(msg.Value, []byte(",")) {
+				m := *msg
+				m.Value = part
+				retval = append(retval, &m)
+			}
+			return retval, nil
+		}).
+		Map(func(_ context.Context, msg *kafka.Message) (*kafka.Message, error) {
+			if string(msg.Value) == "skip" {
+				return nil, nil
+			}
+			msg.Value = []byte(strings.ToUpper(string(msg.Value)))
+			return msg, nil
+		}).
+		To("all").
+		Branch(
+			Branch{Predicate: isEven, Stream: New().To("even")},
+			Branch{Stream: New().To("odd", "odd-copy")})
+	require.NoError(t, s.Check())
+	require.Equal(t, []string{"in"}, s.Sources())
+
+	res, err := s.Process(context.Background(), newMessage("ab,c,skip"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"all:AB", "even:AB", "all:C", "odd:C", "odd-copy:C"}, values(res))
+
+	for _, msg := range res {
+		require.Equal(t, kafka.PartitionAny, msg.TopicPartition.Partition)
+		require.Equal(t, []byte("key"), msg.Key)
+	}
+
+	res, err = s.Process(context.Background(), newMessage(""))
+	require.NoError(t, err)
+	require.Empty(t, res)
+}
+
+func TestStreamProcessError(t *testing.T) {
+
+	errFailed := errors.New("failed")
+
+	s := From("in").
+		Map(func(context.Context, *kafka.Message) (*kafka.Message, error) { return nil, errFailed }).
+		To("out")
+	_, err := s.Process(context.Background(), newMessage("a"))
+	require.Equal(t, errFailed, err)
+
+	s = From("in").
+		Branch(Branch{
+			Predicate: func(context.Context, *kafka.Message) (bool, error) { return false, errFailed },
+			Stream:    New().To("out"),
+		})
+	_, err = s.Process(context.Background(), newMessage("a"))
+	require.Equal(t, errFailed, err)
+}
+
+func TestStreamCheck(t *testing.T) {
+
+	require.EqualError(t, New().To("out").Check(), "stream sources are empty")
+	require.EqualError(t, From("in").Check(), "stream has no sinks")
+	require.EqualError(t, From("in").To("").Check(), "stream sink topic is empty")
+	require.EqualError(t, From("in").Branch(Branch{}).Check(), "stream of branch 0 is nil")
+	require.EqualError(t,
+		From("in").Branch(Branch{Stream: New().To("out")}, Branch{Stream: New()}).Check(),
+		"branch 1: stream has no sinks")
+}
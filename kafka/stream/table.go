@@ -0,0 +1,108 @@
+package stream
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/dialogs/dialog-go-lib/metric"
+	"go.uber.org/zap"
+)
+
+// A Table is a materialized state of the compacted topic: the last value per key.
+// The messages without value (tombstones) remove the keys.
+type Table struct {
+	store IStore
+}
+
+// NewTable returns the table in the store (e.g. NewMemoryStore)
+func NewTable(store IStore) *Table {
+	return &Table{store: store}
+}
+
+// Apply updates the table by the message of the topic
+func (t *Table) Apply(msg *kafka.Message) error {
+	if msg.Value == nil {
+		return t.store.Delete(string(msg.Key))
+	}
+
+	return t.store.Put(string(msg.Key), msg.Value)
+}
+
+// Lookup returns the value of the key
+func (t *Table) Lookup(key []byte) ([]byte, bool, error) {
+	return t.store.Get(string(key))
+}
+
+// Handler returns the processing function of the consumer of the compacted topic
+func (t *Table) Handler() consumer.FuncOnProcess {
+	return func(_ context.Context, _ *zap.Logger, msg *kafka.Message, _ consumer.ISleeper, _ consumer.ICommitter) error {
+		return t.Apply(msg)
+	}
+}
+
+// NewTableConsumer returns the consumer loading the topic to the table.
+// The topic is read from the beginning if the group has no offsets
+// (a unique group per instance reloads the table after the restart).
+func NewTableConsumer(t *Table, topic string, cfg *consumer.Config, opts ...consumer.Option) (*consumer.Consumer, error) {
+
+	opts = append(opts, consumer.WithConfig(func(cfg *consumer.Config) {
+		cfg.Topics = []string{topic}
+		cfg.OnProcess = t.Handler()
+	}))
+
+	return consumer.NewWithOptions(cfg, opts...)
+}
+
+// FuncJoin returns the message enriched by the table value (ok is false if the key isn't found)
+type FuncJoin func(msg *kafka.Message, value []byte, ok bool) (*kafka.Message, error)
+
+// JoinMetrics of the join. All fields are optional.
+type JoinMetrics struct {
+	// Hit is a count of the messages with the key found in the table
+	Hit metric.ICounter
+	// Miss is a count of the messages without the key in the table
+	Miss metric.ICounter
+}
+
+// Join returns the map function (see Stream.Map) enriching the messages by the table values of the message keys.
+// The messages without the key in the table are dropped (inner join).
+func Join(t *Table, fn FuncJoin, m *JoinMetrics) FuncMap {
+	return join(t, fn, m, false)
+}
+
+// LeftJoin returns the map function (see Stream.Map) enriching the messages by the table values of the message keys.
+// The function is called for the messages without the key in the table too (left join).
+func LeftJoin(t *Table, fn FuncJoin, m *JoinMetrics) FuncMap {
+	return join(t, fn, m, true)
+}
+
+func join(t *Table, fn FuncJoin, m *JoinMetrics, left bool) FuncMap {
+
+	if m == nil {
+		m = &JoinMetrics{}
+	}
+
+	return func(_ context.Context, msg *kafka.Message) (*kafka.Message, error) {
+
+		val, ok, err := t.Lookup(msg.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			if m.Hit != nil {
+				m.Hit.Inc()
+			}
+		} else {
+			if m.Miss != nil {
+				m.Miss.Inc()
+			}
+			if !left {
+				return nil, nil
+			}
+		}
+
+		return fn(msg, val, ok)
+	}
+}
@@ -0,0 +1,88 @@
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/dialogs/dialog-go-lib/metric/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTable(t *testing.T) {
+
+	table := NewTable(NewMemoryStore())
+	fn := table.Handler()
+
+	require.NoError(t, fn(context.Background(), zap.NewNop(), &kafka.Message{Key: []byte("k1"), Value: []byte("v1")}, nil, nil))
+	require.NoError(t, fn(context.Background(), zap.NewNop(), &kafka.Message{Key: []byte("k2"), Value: []byte("v2")}, nil, nil))
+	require.NoError(t, fn(context.Background(), zap.NewNop(), &kafka.Message{Key: []byte("k1"), Value: []byte("v3")}, nil, nil))
+	// tombstone
+	require.NoError(t, fn(context.Background(), zap.NewNop(), &kafka.Message{Key: []byte("k2")}, nil, nil))
+
+	val, ok, err := table.Lookup([]byte("k1"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("v3"), val)
+
+	_, ok, err = table.Lookup([]byte("k2"))
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestJoin(t *testing.T) {
+
+	table := NewTable(NewMemoryStore())
+	require.NoError(t, table.Apply(&kafka.Message{Key: []byte("u1"), Value: []byte("alice")}))
+
+	enrich := func(msg *kafka.Message, value []byte, ok bool) (*kafka.Message, error) {
+		if !ok {
+			value = []byte("unknown")
+		}
+		msg.Value = append(append(msg.Value, ':'), value...)
+		return msg, nil
+	}
+
+	hit := mock.NewCounter()
+	miss := mock.NewCounter()
+	metrics := &JoinMetrics{Hit: hit, Miss: miss}
+
+	s := From("events").Map(Join(table, enrich, metrics)).To("out")
+	res, err := s.Process(context.Background(), &kafka.Message{Key: []byte("u1"), Value: []byte("login")})
+	require.NoError(t, err)
+	require.Equal(t, []string{"out:login:alice"}, values(res))
+
+	res, err = s.Process(context.Background(), &kafka.Message{Key: []byte("u2"), Value: []byte("login")})
+	require.NoError(t, err)
+	require.Empty(t, res)
+
+	s = From("events").Map(LeftJoin(table, enrich, metrics)).To("out")
+	res, err = s.Process(context.Background(), &kafka.Message{Key: []byte("u2"), Value: []byte("login")})
+	require.NoError(t, err)
+	require.Equal(t, []string{"out:login:unknown"}, values(res))
+
+	require.Equal(t, uint64(1), hit.Get())
+	require.Equal(t, uint64(2), miss.Get())
+
+	// without metrics
+	_, err = Join(table, enrich, nil)(context.Background(), &kafka.Message{Key: []byte("u1")})
+	require.NoError(t, err)
+}
+
+func TestNewTableConsumer(t *testing.T) {
+
+	cfg := &consumer.Config{
+		OnError: func(context.Context, *zap.Logger, error) {},
+		ConfigMap: &kafka.ConfigMap{
+			"group.id":          "test",
+			"bootstrap.servers": "localhost:1",
+		},
+	}
+
+	c, err := NewTableConsumer(NewTable(NewMemoryStore()), "users", cfg)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	require.Empty(t, cfg.Topics)
+}
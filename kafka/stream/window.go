@@ -0,0 +1,299 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/kafka/consumer"
+	"github.com/dialogs/dialog-go-lib/metric"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// HeaderWindowStart is a header of the window start (unix milliseconds) of the result message
+	HeaderWindowStart = "window-start"
+	// HeaderWindowEnd is a header of the window end (unix milliseconds) of the result message
+	HeaderWindowEnd = "window-end"
+)
+
+// A Window is a time interval [Start, End) of the aggregation
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// A WindowResult is an aggregation of the key in the closed window
+type WindowResult struct {
+	Key    string
+	Window Window
+	Value  []byte
+}
+
+// FuncAggregate returns the new aggregation of the message (the aggregation is nil for the first message of the window)
+type FuncAggregate func(agg []byte, msg *kafka.Message) ([]byte, error)
+
+// FuncEmit receives the results of the closed windows (see EmitTo)
+type FuncEmit func(ctx context.Context, res WindowResult) error
+
+// FuncWindowKey returns the aggregation key of the message
+type FuncWindowKey func(msg *kafka.Message) string
+
+// WindowConfig is a configuration of the windowed aggregation
+type WindowConfig struct {
+	// Size of the window
+	Size time.Duration
+	// Advance is a step of the hopping windows (tumbling windows by default: Advance = Size)
+	Advance time.Duration
+	// Grace is a delay of the window close for the late messages
+	Grace time.Duration
+	// Key returns the aggregation key (the message key by default)
+	Key       FuncWindowKey
+	Aggregate FuncAggregate
+	Emit      FuncEmit
+	// Late is a count of the messages dropped after the window close (optional)
+	Late metric.ICounter
+}
+
+// An Aggregator aggregates the messages by the key in the tumbling or hopping windows of the message time.
+// A window is closed (emitted and removed from the store) when the max time of the messages
+// is after the window end plus the grace period.
+type Aggregator struct {
+	cfg       WindowConfig
+	store     IStore
+	watermark time.Time
+	// windows are the keys of the open windows by the window end (unix nanoseconds)
+	windows map[int64]map[string]struct{}
+	mu      sync.Mutex
+}
+
+// NewAggregator returns the aggregator with the state store (the open windows are read from the store)
+func NewAggregator(cfg WindowConfig, store IStore) (*Aggregator, error) {
+
+	if cfg.Size <= 0 {
+		return nil, errors.New("window size must be positive")
+	}
+	if cfg.Advance <= 0 {
+		cfg.Advance = cfg.Size
+	}
+	if cfg.Advance > cfg.Size {
+		return nil, errors.New("window advance is greater than size")
+	}
+	if cfg.Aggregate == nil {
+		return nil, errors.New("aggregate function is nil")
+	}
+	if cfg.Emit == nil {
+		return nil, errors.New("emit function is nil")
+	}
+	if cfg.Key == nil {
+		cfg.Key = func(msg *kafka.Message) string { return string(msg.Key) }
+	}
+
+	a := &Aggregator{
+		cfg:     cfg,
+		store:   store,
+		windows: make(map[int64]map[string]struct{}),
+	}
+
+	err := store.Range(func(storeKey string, _ []byte) error {
+		start, key, err := parseWindowStoreKey(storeKey)
+		if err != nil {
+			return err
+		}
+
+		a.index(start.Add(cfg.Size), key)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read windows")
+	}
+
+	return a, nil
+}
+
+// Windows returns the windows of the time
+func (a *Aggregator) Windows(ts time.Time) []Window {
+
+	var retval []Window
+
+	nanos := ts.UnixNano()
+	start := nanos - nanos%int64(a.cfg.Advance)
+	for ; start > nanos-int64(a.cfg.Size); start -= int64(a.cfg.Advance) {
+		retval = append(retval, Window{
+			Start: time.Unix(0, start),
+			End:   time.Unix(0, start+int64(a.cfg.Size)),
+		})
+	}
+
+	return retval
+}
+
+// Add aggregates the message in its windows and emits the closed windows
+func (a *Aggregator) Add(ctx context.Context, msg *kafka.Message) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ts := msg.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	if ts.After(a.watermark) {
+		a.watermark = ts
+	}
+
+	key := a.cfg.Key(msg)
+	for _, w := range a.Windows(ts) {
+		if a.closed(w) {
+			if a.cfg.Late != nil {
+				a.cfg.Late.Inc()
+			}
+			continue
+		}
+
+		storeKey := windowStoreKey(w.Start, key)
+		agg, _, err := a.store.Get(storeKey)
+		if err != nil {
+			return err
+		}
+
+		if agg, err = a.cfg.Aggregate(agg, msg); err != nil {
+			return err
+		}
+
+		if err := a.store.Put(storeKey, agg); err != nil {
+			return err
+		}
+
+		a.index(w.End, key)
+	}
+
+	return a.emit(ctx, a.closed)
+}
+
+// Flush emits all windows regardless of the time (e.g. before shutdown)
+func (a *Aggregator) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.emit(ctx, func(Window) bool { return true })
+}
+
+// Handler returns the processing function of the consumer
+func (a *Aggregator) Handler() consumer.FuncOnProcess {
+	return func(ctx context.Context, _ *zap.Logger, msg *kafka.Message, _ consumer.ISleeper, _ consumer.ICommitter) error {
+		return a.Add(ctx, msg)
+	}
+}
+
+func (a *Aggregator) closed(w Window) bool {
+	return !w.End.Add(a.cfg.Grace).After(a.watermark)
+}
+
+// index adds the key of the window to the open windows
+func (a *Aggregator) index(end time.Time, key string) {
+
+	keys, ok := a.windows[end.UnixNano()]
+	if !ok {
+		keys = make(map[string]struct{})
+		a.windows[end.UnixNano()] = keys
+	}
+
+	keys[key] = struct{}{}
+}
+
+// emit emits the matched windows in the order of the window end and the key
+// (the store is read by the index of the open windows)
+func (a *Aggregator) emit(ctx context.Context, match func(Window) bool) error {
+
+	var ends []int64
+	for end := range a.windows {
+		w := Window{Start: time.Unix(0, end).Add(-a.cfg.Size), End: time.Unix(0, end)}
+		if match(w) {
+			ends = append(ends, end)
+		}
+	}
+	sort.Slice(ends, func(i, j int) bool { return ends[i] < ends[j] })
+
+	for _, end := range ends {
+		w := Window{Start: time.Unix(0, end).Add(-a.cfg.Size), End: time.Unix(0, end)}
+
+		keys := make([]string, 0, len(a.windows[end]))
+		for key := range a.windows[end] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			storeKey := windowStoreKey(w.Start, key)
+			val, ok, err := a.store.Get(storeKey)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				if err := a.cfg.Emit(ctx, WindowResult{Key: key, Window: w, Value: val}); err != nil {
+					return err
+				}
+
+				if err := a.store.Delete(storeKey); err != nil {
+					return err
+				}
+			}
+
+			delete(a.windows[end], key)
+		}
+
+		delete(a.windows, end)
+	}
+
+	return nil
+}
+
+// EmitTo returns the emit function producing the results to the topic
+// (the key of the message is the aggregation key, the window is in the headers)
+func EmitTo(p IProducer, topic string) FuncEmit {
+	return func(ctx context.Context, res WindowResult) error {
+		_, err := p.ProduceSync(ctx, &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+			Key:            []byte(res.Key),
+			Value:          res.Value,
+			Headers: []kafka.Header{
+				{Key: HeaderWindowStart, Value: []byte(strconv.FormatInt(unixMilli(res.Window.Start), 10))},
+				{Key: HeaderWindowEnd, Value: []byte(strconv.FormatInt(unixMilli(res.Window.End), 10))},
+			},
+			Timestamp: res.Window.End,
+		})
+
+		return err
+	}
+}
+
+// windowStoreKey returns the key of the store ordered by the window start
+func windowStoreKey(start time.Time, key string) string {
+	return fmt.Sprintf("%020d/%s", start.UnixNano(), key)
+}
+
+func parseWindowStoreKey(storeKey string) (time.Time, string, error) {
+
+	parts := strings.SplitN(storeKey, "/", 2)
+	if len(parts) != 2 {
+		return time.Time{}, "", errors.Errorf("invalid window key: %s", storeKey)
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, "", errors.Wrapf(err, "invalid window key: %s", storeKey)
+	}
+
+	return time.Unix(0, nanos), parts[1], nil
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,171 @@
+package stream
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/dialogs/dialog-go-lib/metric/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// count is an aggregation of the message count
+func count(agg []byte, _ *kafka.Message) ([]byte, error) {
+	n, _ := strconv.Atoi(string(agg))
+	return []byte(strconv.Itoa(n + 1)), nil
+}
+
+func newTimedMessage(key string, ts time.Time) *kafka.Message {
+	return &kafka.Message{Key: []byte(key), Timestamp: ts}
+}
+
+func TestAggregatorWindows(t *testing.T) {
+
+	base := time.Unix(100, 0)
+
+	a, err := NewAggregator(WindowConfig{Size: 10 * time.Second, Aggregate: count, Emit: func(context.Context, WindowResult) error { return nil }}, NewMemoryStore())
+	require.NoError(t, err)
+	require.Equal(t,
+		[]Window{{Start: base, End: base.Add(10 * time.Second)}},
+		a.Windows(base.Add(5*time.Second)))
+
+	a, err = NewAggregator(WindowConfig{Size: 10 * time.Second, Advance: 5 * time.Second, Aggregate: count, Emit: func(context.Context, WindowResult) error { return nil }}, NewMemoryStore())
+	require.NoError(t, err)
+	require.Equal(t,
+		[]Window{
+			{Start: base.Add(5 * time.Second), End: base.Add(15 * time.Second)},
+			{Start: base, End: base.Add(10 * time.Second)},
+		},
+		a.Windows(base.Add(7*time.Second)))
+}
+
+func TestAggregatorTumbling(t *testing.T) {
+
+	var results []WindowResult
+	late := mock.NewCounter()
+
+	a, err := NewAggregator(WindowConfig{
+		Size:      10 * time.Second,
+		Grace:     2 * time.Second,
+		Aggregate: count,
+		Emit: func(_ context.Context, res WindowResult) error {
+			results = append(results, res)
+			return nil
+		},
+		Late: late,
+	}, NewMemoryStore())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	base := time.Unix(100, 0)
+	fn := a.Handler()
+
+	require.NoError(t, fn(ctx, zap.NewNop(), newTimedMessage("a", base.Add(time.Second)), nil, nil))
+	require.NoError(t, fn(ctx, zap.NewNop(), newTimedMessage("b", base.Add(2*time.Second)), nil, nil))
+	require.NoError(t, fn(ctx, zap.NewNop(), newTimedMessage("a", base.Add(3*time.Second)), nil, nil))
+	// the next window, the first window isn't closed (grace period)
+	require.NoError(t, fn(ctx, zap.NewNop(), newTimedMessage("a", base.Add(11*time.Second)), nil, nil))
+	require.NoError(t, fn(ctx, zap.NewNop(), newTimedMessage("b", base.Add(9*time.Second)), nil, nil))
+	require.Empty(t, results)
+
+	// the first window is closed
+	require.NoError(t, fn(ctx, zap.NewNop(), newTimedMessage("a", base.Add(12*time.Second)), nil, nil))
+	first := Window{Start: base, End: base.Add(10 * time.Second)}
+	require.Equal(t, []WindowResult{
+		{Key: "a", Window: first, Value: []byte("2")},
+		{Key: "b", Window: first, Value: []byte("2")},
+	}, results)
+
+	// late message
+	require.NoError(t, fn(ctx, zap.NewNop(), newTimedMessage("a", base.Add(4*time.Second)), nil, nil))
+	require.Equal(t, uint64(1), late.Get())
+	require.Len(t, results, 2)
+
+	require.NoError(t, a.Flush(ctx))
+	require.Equal(t, WindowResult{
+		Key:    "a",
+		Window: Window{Start: base.Add(10 * time.Second), End: base.Add(20 * time.Second)},
+		Value:  []byte("2"),
+	}, results[2])
+	require.Len(t, results, 3)
+}
+
+func TestAggregatorRestore(t *testing.T) {
+
+	var results []WindowResult
+	cfg := WindowConfig{
+		Size:      10 * time.Second,
+		Aggregate: count,
+		Emit: func(_ context.Context, res WindowResult) error {
+			results = append(results, res)
+			return nil
+		},
+	}
+
+	store := NewMemoryStore()
+	a, err := NewAggregator(cfg, store)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	base := time.Unix(100, 0)
+	require.NoError(t, a.Add(ctx, newTimedMessage("a", base)))
+
+	// the open windows are read from the store after the restart
+	a, err = NewAggregator(cfg, store)
+	require.NoError(t, err)
+	require.Len(t, a.windows, 1)
+
+	require.NoError(t, a.Add(ctx, newTimedMessage("b", base.Add(10*time.Second))))
+	require.Equal(t, []WindowResult{
+		{Key: "a", Window: Window{Start: base, End: base.Add(10 * time.Second)}, Value: []byte("1")},
+	}, results)
+	require.Len(t, a.windows, 1)
+
+	// the invalid key of the store
+	require.NoError(t, store.Put("invalid", nil))
+	_, err = NewAggregator(cfg, store)
+	require.Error(t, err)
+}
+
+func TestAggregatorEmitTo(t *testing.T) {
+
+	p := &testProducer{}
+	a, err := NewAggregator(WindowConfig{
+		Size:      time.Second,
+		Aggregate: count,
+		Emit:      EmitTo(p, "out"),
+	}, NewMemoryStore())
+	require.NoError(t, err)
+
+	base := time.Unix(100, 0)
+	require.NoError(t, a.Add(context.Background(), newTimedMessage("a", base)))
+	require.NoError(t, a.Add(context.Background(), newTimedMessage("a", base.Add(time.Second))))
+
+	require.Equal(t, []string{"out:1"}, values(p.msgs))
+	require.Equal(t, []byte("a"), p.msgs[0].Key)
+	require.Equal(t, base.Add(time.Second), p.msgs[0].Timestamp)
+	require.Equal(t, []kafka.Header{
+		{Key: HeaderWindowStart, Value: []byte("100000")},
+		{Key: HeaderWindowEnd, Value: []byte("101000")},
+	}, p.msgs[0].Headers)
+}
+
+func TestNewAggregatorErrors(t *testing.T) {
+
+	emit := func(context.Context, WindowResult) error { return nil }
+
+	_, err := NewAggregator(WindowConfig{Aggregate: count, Emit: emit}, NewMemoryStore())
+	require.EqualError(t, err, "window size must be positive")
+
+	_, err = NewAggregator(WindowConfig{Size: time.Second, Advance: time.Minute, Aggregate: count, Emit: emit}, NewMemoryStore())
+	require.EqualError(t, err, "window advance is greater than size")
+
+	_, err = NewAggregator(WindowConfig{Size: time.Second, Emit: emit}, NewMemoryStore())
+	require.EqualError(t, err, "aggregate function is nil")
+
+	_, err = NewAggregator(WindowConfig{Size: time.Second, Aggregate: count}, NewMemoryStore())
+	require.EqualError(t, err, "emit function is nil")
+}
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dialogs/dialog-go-lib/trace"
+	"go.uber.org/zap"
+)
+
+const _HeaderRequestID = "X-Request-Id"
+
+type loggerKey struct{}
+
+// ContextWithLogger returns the context with the logger
+func ContextWithLogger(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger of the context or the nop logger
+func FromContext(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok && l != nil {
+		return l
+	}
+
+	return zap.NewNop()
+}
+
+// With returns the context with the logger of the context extended by the fields
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	return ContextWithLogger(ctx, FromContext(ctx).With(fields...))
+}
+
+// Middleware puts the request logger into the context of the request (see FromContext).
+// The logger has the fields: method, path, request id and trace id (if the request is traced).
+func Middleware(l *zap.Logger) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+			fields := []zap.Field{
+				zap.String("method", req.Method),
+				zap.String("path", req.URL.Path),
+			}
+
+			if id := req.Header.Get(_HeaderRequestID); id != "" {
+				fields = append(fields, zap.String("request_id", id))
+			}
+
+			ctx := req.Context()
+			if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+				fields = append(fields, zap.String("trace_id", sc.TraceID.String()))
+			}
+
+			ctx = ContextWithLogger(ctx, l.With(fields...))
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
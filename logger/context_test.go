@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dialogs/dialog-go-lib/logger/memory"
+	"github.com/dialogs/dialog-go-lib/trace"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestContext(t *testing.T) {
+
+	require.NotNil(t, FromContext(context.Background()))
+
+	l, buf, err := memory.New(nil)
+	require.NoError(t, err)
+
+	ctx := ContextWithLogger(context.Background(), l)
+	require.Equal(t, l, FromContext(ctx))
+
+	ctx = With(ctx, zap.String("key", "value"))
+	FromContext(ctx).Info("message")
+
+	out := buf.String()
+	require.Contains(t, out, `"msg":"message"`)
+	require.Contains(t, out, `"key":"value"`)
+}
+
+func TestMiddleware(t *testing.T) {
+
+	l, buf, err := memory.New(nil)
+	require.NoError(t, err)
+
+	handler := Middleware(l)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		FromContext(req.Context()).Info("handled")
+	}))
+
+	tracer := trace.NewTracer(trace.Config{Sampler: trace.NeverSample()})
+	defer tracer.Close()
+
+	handler = trace.Middleware(tracer)(handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/path", nil)
+	req.Header.Set("X-Request-Id", "id1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := buf.String()
+	require.True(t, strings.Contains(out, `"msg":"handled"`), out)
+	require.Contains(t, out, `"method":"GET"`)
+	require.Contains(t, out, `"path":"/path"`)
+	require.Contains(t, out, `"request_id":"id1"`)
+	require.Contains(t, out, `"trace_id":"`)
+}
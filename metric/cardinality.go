@@ -0,0 +1,118 @@
+package metric
+
+import (
+	"sync"
+)
+
+// OtherLabel is the label value replacing the values beyond the limit (see CardinalityGuard)
+const OtherLabel = "other"
+
+// FuncOnCapped is called once per metric when the first label value is replaced by the other label
+type FuncOnCapped func(metric, value string)
+
+// CardinalityConfig of the guard. All fields are optional.
+type CardinalityConfig struct {
+	// Limit is a max count of the label values per metric (100 by default)
+	Limit int
+	// Other is the label value replacing the values beyond the limit (OtherLabel by default)
+	Other string
+	// OnCapped reports the capping of the metric
+	OnCapped FuncOnCapped
+	// Capped is a count of the replaced label values
+	Capped ICounter
+}
+
+// A CardinalityGuard caps the count of the label values per metric (e.g. topics, tenants)
+// to prevent the explosion of the series: the values beyond the limit are replaced by the other label.
+type CardinalityGuard struct {
+	limit    int
+	other    string
+	onCapped FuncOnCapped
+	capped   ICounter
+	values   map[string]map[string]struct{}
+	reported map[string]bool
+	mu       sync.Mutex
+}
+
+// NewCardinalityGuard returns the guard
+func NewCardinalityGuard(cfg CardinalityConfig) *CardinalityGuard {
+
+	g := &CardinalityGuard{
+		limit:    cfg.Limit,
+		other:    cfg.Other,
+		onCapped: cfg.OnCapped,
+		capped:   cfg.Capped,
+		values:   make(map[string]map[string]struct{}),
+		reported: make(map[string]bool),
+	}
+
+	if g.limit <= 0 {
+		g.limit = 100
+	}
+	if g.other == "" {
+		g.other = OtherLabel
+	}
+
+	return g
+}
+
+// Label returns the value or the other label if the metric has the max count of the values
+func (g *CardinalityGuard) Label(metric, value string) string {
+
+	g.mu.Lock()
+
+	values, ok := g.values[metric]
+	if !ok {
+		values = make(map[string]struct{})
+		g.values[metric] = values
+	}
+
+	if _, ok := values[value]; ok || len(values) < g.limit {
+		values[value] = struct{}{}
+		g.mu.Unlock()
+		return value
+	}
+
+	report := !g.reported[metric]
+	g.reported[metric] = true
+	g.mu.Unlock()
+
+	if g.capped != nil {
+		g.capped.Inc()
+	}
+	if report && g.onCapped != nil {
+		g.onCapped(metric, value)
+	}
+
+	return g.other
+}
+
+// Values returns the count of the label values of the metric
+func (g *CardinalityGuard) Values(metric string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return len(g.values[metric])
+}
+
+// Counter returns the counter function with the capped label
+// (e.g. the function of the topic counter based on prometheus.CounterVec.WithLabelValues)
+func (g *CardinalityGuard) Counter(metric string, fn func(label string) ICounter) func(label string) ICounter {
+	return func(label string) ICounter {
+		return fn(g.Label(metric, label))
+	}
+}
+
+// Gauge returns the gauge function with the capped label
+func (g *CardinalityGuard) Gauge(metric string, fn func(label string) IGauge) func(label string) IGauge {
+	return func(label string) IGauge {
+		return fn(g.Label(metric, label))
+	}
+}
+
+// Observer returns the observer function with the capped label
+func (g *CardinalityGuard) Observer(metric string, fn func(label string) IObserver) func(label string) IObserver {
+	return func(label string) IObserver {
+		return fn(g.Label(metric, label))
+	}
+}
@@ -0,0 +1,50 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/dialogs/dialog-go-lib/metric/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardinalityGuard(t *testing.T) {
+
+	var reported []string
+	capped := mock.NewCounter()
+
+	g := NewCardinalityGuard(CardinalityConfig{
+		Limit:  2,
+		Capped: capped,
+		OnCapped: func(name, value string) {
+			reported = append(reported, name+":"+value)
+		},
+	})
+
+	require.Equal(t, "a", g.Label("topics", "a"))
+	require.Equal(t, "b", g.Label("topics", "b"))
+	require.Equal(t, "a", g.Label("topics", "a"))
+	require.Equal(t, OtherLabel, g.Label("topics", "c"))
+	require.Equal(t, OtherLabel, g.Label("topics", "d"))
+	// the limit is per metric
+	require.Equal(t, "c", g.Label("tenants", "c"))
+
+	require.Equal(t, 2, g.Values("topics"))
+	require.Equal(t, []string{"topics:c"}, reported)
+	require.Equal(t, uint64(2), capped.Get())
+
+	counters := make(map[string]*mock.Counter)
+	fn := g.Counter("processed", func(label string) ICounter {
+		if _, ok := counters[label]; !ok {
+			counters[label] = mock.NewCounter()
+		}
+		return counters[label]
+	})
+
+	fn("a").Inc()
+	fn("b").Inc()
+	fn("c").Inc()
+	fn("d").Inc()
+	require.Equal(t, uint64(1), counters["a"].Get())
+	require.Equal(t, uint64(2), counters[OtherLabel].Get())
+	require.Len(t, counters, 3)
+}
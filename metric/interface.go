@@ -12,3 +12,23 @@ type ICounter interface {
 	// Add adds the given value to the counter. It panics if the value is < 0.
 	Add(val float64)
 }
+
+type IGauge interface {
+	// Set sets the gauge to an arbitrary value.
+	Set(float64)
+
+	// Inc increments the gauge by 1.
+	Inc()
+
+	// Dec decrements the gauge by 1.
+	Dec()
+}
+
+// FuncTopicGauge returns the gauge of the topic (e.g. prometheus.GaugeVec.WithLabelValues)
+type FuncTopicGauge func(topic string) IGauge
+
+// FuncTopicCounter returns the counter of the topic (e.g. prometheus.CounterVec.WithLabelValues)
+type FuncTopicCounter func(topic string) ICounter
+
+// FuncTopicObserver returns the observer of the topic (e.g. prometheus.HistogramVec.WithLabelValues)
+type FuncTopicObserver func(topic string) IObserver
@@ -27,4 +27,9 @@ func TestInterface(t *testing.T) {
 			prometheus.NewHistogramVec(prometheus.HistogramOpts{}, []string{}).
 				With(prometheus.Labels{})))
 
+	// test: mock object
+	require.NotNil(t, (IGauge)(mock.NewGauge()))
+	// test: prometheus object
+	require.NotNil(t,
+		(IGauge)(prometheus.NewGauge(prometheus.GaugeOpts{})))
 }
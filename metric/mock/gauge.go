@@ -0,0 +1,40 @@
+package mock
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+type Gauge struct {
+	val uint64
+}
+
+func NewGauge() *Gauge {
+	return &Gauge{}
+}
+
+func (g *Gauge) Set(val float64) {
+	atomic.StoreUint64(&g.val, math.Float64bits(val))
+}
+
+func (g *Gauge) Inc() {
+	g.add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.add(-1)
+}
+
+func (g *Gauge) Get() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.val))
+}
+
+func (g *Gauge) add(val float64) {
+	for {
+		oldBits := atomic.LoadUint64(&g.val)
+		newBits := math.Float64bits(math.Float64frombits(oldBits) + val)
+		if atomic.CompareAndSwapUint64(&g.val, oldBits, newBits) {
+			return
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package mock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGauge(t *testing.T) {
+
+	g := NewGauge()
+
+	g.Inc()
+	require.Equal(t, float64(1), g.Get())
+
+	g.Inc()
+	require.Equal(t, float64(2), g.Get())
+
+	g.Dec()
+	require.Equal(t, float64(1), g.Get())
+
+	g.Set(10.5)
+	require.Equal(t, 10.5, g.Get())
+}
@@ -0,0 +1,48 @@
+package reporter
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// _MaxGoroutinesDump is a max size of the dump of the goroutines (the dump is sent as the extra field of the event)
+const _MaxGoroutinesDump = 32 << 10
+
+// A PanicError is an error of the recovered panic
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+	// Goroutines is a dump of all goroutines at the moment of the recovery (truncated to 32KB)
+	Goroutines []byte
+	// Details are the tags of the source of the panic (e.g. the request or the message metadata)
+	Details map[string]string
+}
+
+// NewPanicError returns the error of the recovered panic with the stack of the current goroutine
+// and the dump of all goroutines. It must be called by the deferred function of the recovery.
+func NewPanicError(val interface{}, details map[string]string) *PanicError {
+	return &PanicError{
+		Value:      val,
+		Stack:      debug.Stack(),
+		Goroutines: goroutinesDump(),
+		Details:    details,
+	}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Tags returns the details (ITaggedError implementation)
+func (e *PanicError) Tags() map[string]string {
+	return e.Details
+}
+
+func goroutinesDump() []byte {
+
+	buf := make([]byte, _MaxGoroutinesDump)
+	n := runtime.Stack(buf, true)
+
+	return buf[:n]
+}
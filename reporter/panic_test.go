@@ -0,0 +1,40 @@
+package reporter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPanicError(t *testing.T) {
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// the dump contains the other goroutines
+	go func() { <-done }()
+
+	var err *PanicError
+	func() {
+		defer func() { err = NewPanicError(recover(), map[string]string{"a": "1"}) }()
+		panic("test")
+	}()
+
+	require.EqualError(t, err, "panic: test")
+	require.Equal(t, map[string]string{"a": "1"}, err.Tags())
+	require.Contains(t, string(err.Stack), "TestNewPanicError")
+	require.True(t, bytes.Count(err.Goroutines, []byte("goroutine ")) > 1)
+}
+
+func TestGoroutinesDumpLimit(t *testing.T) {
+
+	done := make(chan struct{})
+	defer close(done)
+
+	for i := 0; i < 1000; i++ {
+		go func() { <-done }()
+	}
+
+	require.Len(t, goroutinesDump(), _MaxGoroutinesDump)
+}
@@ -0,0 +1,152 @@
+package reporter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dialogs/dialog-go-lib/service/info"
+	"go.uber.org/zap"
+)
+
+// IReporter sends errors to an error tracking system (e.g. Sentry)
+type IReporter interface {
+	Report(ctx context.Context, err error, tags map[string]string)
+}
+
+// ITaggedError is an error with the tags which are added to the report (e.g. consumer.ProgressError)
+type ITaggedError interface {
+	error
+	Tags() map[string]string
+}
+
+type tagsKey struct{}
+
+// ContextWithTags returns the context with the tags which are added to the reported errors
+func ContextWithTags(ctx context.Context, tags map[string]string) context.Context {
+
+	merged := make(map[string]string)
+	for k, v := range TagsFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+
+	return context.WithValue(ctx, tagsKey{}, merged)
+}
+
+// TagsFromContext returns the tags of the context
+func TagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(tagsKey{}).(map[string]string)
+	return tags
+}
+
+// InfoTags returns the tags of the application info
+func InfoTags(appinfo *info.Info) map[string]string {
+
+	if appinfo == nil {
+		return nil
+	}
+
+	return map[string]string{
+		"app.name":       appinfo.Name,
+		"app.version":    appinfo.Version,
+		"app.commit":     appinfo.Commit,
+		"app.go_version": appinfo.GoVersion,
+		"app.build_date": appinfo.BuildDate,
+	}
+}
+
+// OnError returns the error callback which reports the error (see consumer.Config.OnError).
+// The next callback is optional.
+func OnError(r IReporter, next func(context.Context, *zap.Logger, error)) func(context.Context, *zap.Logger, error) {
+
+	return func(ctx context.Context, logger *zap.Logger, err error) {
+		r.Report(ctx, err, nil)
+
+		if next != nil {
+			next(ctx, logger, err)
+		}
+	}
+}
+
+// Recover returns the middleware which reports the panic of the handler (see NewPanicError,
+// the details are the request info) and responds with the status 500 if the response isn't started
+func Recover(r IReporter, logger *zap.Logger) func(http.Handler) http.Handler {
+
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+			rw := &recoverWriter{ResponseWriter: w}
+			defer func() {
+				if val := recover(); val != nil {
+					if val == http.ErrAbortHandler {
+						panic(val)
+					}
+
+					err := NewPanicError(val, requestDetails(req))
+					logger.Error("recovered panic",
+						zap.Error(err),
+						zap.Any("details", err.Details),
+						zap.ByteString("stack", err.Stack))
+
+					r.Report(req.Context(), err, map[string]string{
+						"http.method": req.Method,
+						"http.path":   req.URL.Path,
+					})
+
+					if !rw.started {
+						w.WriteHeader(http.StatusInternalServerError)
+					}
+				}
+			}()
+
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
+
+// recoverWriter tracks the start of the response
+type recoverWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *recoverWriter) WriteHeader(status int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recoverWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *recoverWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.started = true
+		f.Flush()
+	}
+}
+
+func requestDetails(req *http.Request) map[string]string {
+
+	details := map[string]string{
+		"http.method":      req.Method,
+		"http.path":        req.URL.Path,
+		"http.remote_addr": req.RemoteAddr,
+	}
+
+	if val := req.UserAgent(); val != "" {
+		details["http.user_agent"] = val
+	}
+	if val := req.Header.Get("X-Request-Id"); val != "" {
+		details["http.request_id"] = val
+	}
+
+	return details
+}
@@ -0,0 +1,108 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/dialogs/dialog-go-lib/service/info"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testReport struct {
+	ctx  context.Context
+	err  error
+	tags map[string]string
+}
+
+type testReporter struct {
+	reports []testReport
+	mu      sync.Mutex
+}
+
+func (r *testReporter) Report(ctx context.Context, err error, tags map[string]string) {
+	r.mu.Lock()
+	r.reports = append(r.reports, testReport{ctx: ctx, err: err, tags: tags})
+	r.mu.Unlock()
+}
+
+func TestContextWithTags(t *testing.T) {
+
+	require.Nil(t, TagsFromContext(context.Background()))
+
+	ctx := ContextWithTags(context.Background(), map[string]string{"a": "1", "b": "2"})
+	ctx = ContextWithTags(ctx, map[string]string{"b": "3"})
+	require.Equal(t, map[string]string{"a": "1", "b": "3"}, TagsFromContext(ctx))
+
+	require.Nil(t, InfoTags(nil))
+	require.Equal(t, "name", InfoTags(&info.Info{Name: "name"})["app.name"])
+}
+
+func TestOnError(t *testing.T) {
+
+	r := &testReporter{}
+	called := false
+
+	fn := OnError(r, func(context.Context, *zap.Logger, error) { called = true })
+	fn(context.Background(), zap.NewNop(), errors.New("failed"))
+
+	require.True(t, called)
+	require.Len(t, r.reports, 1)
+	require.EqualError(t, r.reports[0].err, "failed")
+}
+
+func TestRecover(t *testing.T) {
+
+	r := &testReporter{}
+	handler := Recover(r, nil)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("test")
+	}))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/path?a=1", nil)
+	req.Header.Set("X-Request-Id", "id")
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Len(t, r.reports, 1)
+	require.EqualError(t, r.reports[0].err, "panic: test")
+	require.NotEmpty(t, r.reports[0].err.(*PanicError).Stack)
+	require.Equal(t, map[string]string{"http.method": "GET", "http.path": "/path"}, r.reports[0].tags)
+	require.Equal(t, map[string]string{
+		"http.method":      "GET",
+		"http.path":        "/path",
+		"http.remote_addr": "192.0.2.1:1234",
+		"http.request_id":  "id",
+	}, r.reports[0].err.(*PanicError).Details)
+}
+
+func TestRecoverStartedResponse(t *testing.T) {
+
+	r := &testReporter{}
+	handler := Recover(r, nil)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("test")
+	}))
+
+	w := &headerCounter{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/path", nil))
+
+	// the status isn't overwritten
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Equal(t, 1, w.count)
+	require.Len(t, r.reports, 1)
+}
+
+type headerCounter struct {
+	*httptest.ResponseRecorder
+	count int
+}
+
+func (w *headerCounter) WriteHeader(status int) {
+	w.count++
+	w.ResponseRecorder.WriteHeader(status)
+}
@@ -0,0 +1,268 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	_SentryVersion   = "7"
+	_SentryClient    = "dialog-go-lib/1.0"
+	_SentryPlatform  = "go"
+	_SentryLevel     = "error"
+	_SentryTimeout   = time.Second * 5
+	_SentryTimestamp = "2006-01-02T15:04:05"
+	_SentryQueueSize = 100
+)
+
+// A Sentry reports errors to the Sentry store endpoint.
+// The events are sent in the background, it must be closed to send the queued events.
+type Sentry struct {
+	storeURL    string
+	auth        string
+	environment string
+	release     string
+	tags        map[string]string
+	client      *http.Client
+	logger      *zap.Logger
+
+	queue  chan *sentryEvent
+	done   chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+// SentryConfig is a configuration of the Sentry reporter
+type SentryConfig struct {
+	// DSN is the Sentry project key (https://<key>@<host>/<project>)
+	DSN         string
+	Environment string
+	Release     string
+	// Tags are added to every event (see InfoTags)
+	Tags map[string]string
+	// QueueSize is a max count of the events waiting for the sending (100 by default),
+	// the events are dropped if the queue is full
+	QueueSize int
+	Client    *http.Client
+	Logger    *zap.Logger
+}
+
+// NewSentry creates the Sentry reporter
+func NewSentry(cfg SentryConfig) (*Sentry, error) {
+
+	dsn, err := url.Parse(cfg.DSN)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid sentry dsn")
+	}
+
+	project := strings.Trim(dsn.Path, "/")
+	if dsn.User == nil || dsn.User.Username() == "" || dsn.Host == "" || project == "" {
+		return nil, errors.New("invalid sentry dsn")
+	}
+
+	auth := "Sentry sentry_version=" + _SentryVersion +
+		", sentry_client=" + _SentryClient +
+		", sentry_key=" + dsn.User.Username()
+	if secret, ok := dsn.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+
+	retval := &Sentry{
+		storeURL:    dsn.Scheme + "://" + dsn.Host + "/api/" + project + "/store/",
+		auth:        auth,
+		environment: cfg.Environment,
+		release:     cfg.Release,
+		tags:        cfg.Tags,
+		client:      cfg.Client,
+		logger:      cfg.Logger,
+		done:        make(chan struct{}),
+		closed:      make(chan struct{}),
+	}
+
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = _SentryQueueSize
+	}
+	retval.queue = make(chan *sentryEvent, queueSize)
+
+	if retval.client == nil {
+		retval.client = &http.Client{Timeout: _SentryTimeout}
+	}
+	if retval.logger == nil {
+		retval.logger = zap.NewNop()
+	}
+
+	go retval.run()
+
+	return retval, nil
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Message     string            `json:"message"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Exception   []sentryException `json:"exception"`
+	Extra       map[string]string `json:"extra,omitempty"`
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Report queues the error for the sending (IReporter implementation), it doesn't wait for the response.
+// The tags are merged with the configured tags, the tags of the context and the tags of the error (see ITaggedError).
+func (s *Sentry) Report(ctx context.Context, err error, tags map[string]string) {
+
+	if err == nil {
+		return
+	}
+
+	select {
+	case <-s.done:
+		s.logger.Error("failed to report error: reporter is closed", zap.NamedError("reported", err))
+		return
+	default:
+	}
+
+	select {
+	case s.queue <- s.newEvent(ctx, err, tags):
+	default:
+		s.logger.Error("failed to report error: queue is full", zap.NamedError("reported", err))
+	}
+}
+
+// Close sends the queued events and stops the reporter
+func (s *Sentry) Close() error {
+
+	s.once.Do(func() { close(s.done) })
+	<-s.closed
+
+	return nil
+}
+
+func (s *Sentry) run() {
+
+	defer close(s.closed)
+
+	for {
+		select {
+		case event := <-s.queue:
+			s.sendEvent(event)
+		case <-s.done:
+			for {
+				select {
+				case event := <-s.queue:
+					s.sendEvent(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Sentry) sendEvent(event *sentryEvent) {
+
+	// the context of the reported error can be done after the reporting
+	if err := s.send(context.Background(), event); err != nil {
+		s.logger.Error("failed to report error", zap.String("reported", event.Message), zap.Error(err))
+	}
+}
+
+func (s *Sentry) newEvent(ctx context.Context, err error, tags map[string]string) *sentryEvent {
+
+	merged := make(map[string]string)
+	var errTags map[string]string
+	var tagged ITaggedError
+	if errors.As(err, &tagged) {
+		errTags = tagged.Tags()
+	}
+
+	for _, src := range []map[string]string{s.tags, TagsFromContext(ctx), errTags, tags} {
+		for k, v := range src {
+			merged[k] = v
+		}
+	}
+
+	event := &sentryEvent{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC().Format(_SentryTimestamp),
+		Level:       _SentryLevel,
+		Platform:    _SentryPlatform,
+		Message:     err.Error(),
+		Environment: s.environment,
+		Release:     s.release,
+		Tags:        merged,
+		Exception: []sentryException{{
+			Type:  reflect.TypeOf(errors.Cause(err)).String(),
+			Value: err.Error(),
+		}},
+	}
+
+	var p *PanicError
+	if errors.As(err, &p) {
+		event.Level = "fatal"
+		event.Extra = map[string]string{"stack": string(p.Stack)}
+		if len(p.Goroutines) > 0 {
+			event.Extra["goroutines"] = string(p.Goroutines)
+		}
+	}
+
+	return event
+}
+
+func (s *Sentry) send(ctx context.Context, event *sentryEvent) error {
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("%s: %s", resp.Status, msg)
+	}
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	return nil
+}
+
+func newEventID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
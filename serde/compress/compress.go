@@ -0,0 +1,110 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// HeaderCodec is the header of the message with the codec of the compressed payload
+const HeaderCodec = "payload-codec"
+
+// The names of the codecs
+const (
+	Gzip   = "gzip"
+	Snappy = "snappy"
+	Zstd   = "zstd"
+)
+
+// ErrUnknownCodec is returned if the codec isn't registered
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// ICodec compresses the payloads. The codecs are independent of the compression of the broker,
+// so the payloads can be exchanged with the services in other languages by the same algorithm.
+type ICodec interface {
+	// Name is the value of HeaderCodec
+	Name() string
+	Encode(src []byte) ([]byte, error)
+	Decode(src []byte) ([]byte, error)
+}
+
+var (
+	codecs   = map[string]ICodec{Gzip: gzipCodec{}}
+	codecsMu sync.RWMutex
+)
+
+// Register adds the codec (replacing the codec with the same name).
+// Gzip is registered by default, the snappy and zstd codecs must be registered by the application
+// (e.g. with github.com/golang/snappy and github.com/klauspost/compress/zstd).
+func Register(codec ICodec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	codecs[codec.Name()] = codec
+}
+
+// Get returns the codec by name
+func Get(name string) (ICodec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, errors.Wrap(ErrUnknownCodec, name)
+	}
+
+	return codec, nil
+}
+
+// NewCodec returns the codec of the functions
+func NewCodec(name string, encode, decode func([]byte) ([]byte, error)) ICodec {
+	return &funcCodec{name: name, encode: encode, decode: decode}
+}
+
+type funcCodec struct {
+	name   string
+	encode func([]byte) ([]byte, error)
+	decode func([]byte) ([]byte, error)
+}
+
+func (c *funcCodec) Name() string                      { return c.name }
+func (c *funcCodec) Encode(src []byte) ([]byte, error) { return c.encode(src) }
+func (c *funcCodec) Decode(src []byte) ([]byte, error) { return c.decode(src) }
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string { return Gzip }
+
+func (gzipCodec) Encode(src []byte) ([]byte, error) {
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+
+	if _, err := w.Write(src); err != nil {
+		return nil, errors.Wrap(err, "failed to compress")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to compress")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decode(src []byte) ([]byte, error) {
+
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decompress")
+	}
+	defer r.Close()
+
+	retval, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decompress")
+	}
+
+	return retval, nil
+}
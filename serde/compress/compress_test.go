@@ -0,0 +1,58 @@
+package compress
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzip(t *testing.T) {
+
+	codec, err := Get(Gzip)
+	require.NoError(t, err)
+	require.Equal(t, Gzip, codec.Name())
+
+	src := bytes.Repeat([]byte("payload"), 100)
+
+	enc, err := codec.Encode(src)
+	require.NoError(t, err)
+	require.True(t, len(enc) < len(src))
+
+	dec, err := codec.Decode(enc)
+	require.NoError(t, err)
+	require.Equal(t, src, dec)
+
+	_, err = codec.Decode([]byte("invalid"))
+	require.EqualError(t, err, "failed to decompress: unexpected EOF")
+}
+
+func TestRegister(t *testing.T) {
+
+	_, err := Get(Snappy)
+	require.True(t, errors.Is(err, ErrUnknownCodec))
+	require.EqualError(t, err, "snappy: unknown codec")
+
+	reverse := func(src []byte) ([]byte, error) {
+		retval := make([]byte, len(src))
+		for i := range src {
+			retval[len(src)-1-i] = src[i]
+		}
+		return retval, nil
+	}
+
+	Register(NewCodec(Snappy, reverse, reverse))
+	defer func() {
+		codecsMu.Lock()
+		delete(codecs, Snappy)
+		codecsMu.Unlock()
+	}()
+
+	codec, err := Get(Snappy)
+	require.NoError(t, err)
+
+	enc, err := codec.Encode([]byte("abc"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("cba"), enc)
+}
@@ -1,7 +1,6 @@
 package service
 
 import (
-	"net"
 	"time"
 
 	"go.uber.org/zap"
@@ -20,7 +19,7 @@ func NewGRPC(opts ...grpc.ServerOption) *GRPC {
 	return &GRPC{
 		service:      newService(),
 		svr:          grpc.NewServer(opts...),
-		closeTimeout: time.Second * 30,
+		closeTimeout: _DefaultCloseTimeout,
 	}
 }
 
@@ -48,7 +47,7 @@ func (g *GRPC) ListenAndServe(l *zap.Logger) error {
 	addr := g.GetAddr()
 
 	run := func() error {
-		listener, err := net.Listen("tcp", addr)
+		listener, err := Listen(addr)
 		if err != nil {
 			return err
 		}
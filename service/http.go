@@ -73,11 +73,16 @@ func (s *HTTP) ListenAndServe(l *zap.Logger) error {
 			return errors.New("invalid server address")
 		}
 
+		listener, err := Listen(svr.Addr)
+		if err != nil {
+			return err
+		}
+
 		if svr.TLSConfig != nil {
-			return svr.ListenAndServeTLS("", "")
+			return svr.ServeTLS(listener, "", "")
 		}
 
-		return svr.ListenAndServe()
+		return svr.Serve(listener)
 	}
 
 	stop := func() error {
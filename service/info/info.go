@@ -7,4 +7,10 @@ type Info struct {
 	Commit    string `json:"commit"`
 	GoVersion string `json:"goVersion"`
 	BuildDate string `json:"buildDate"`
+	// Libraries are the versions of the native libraries (e.g. librdkafka)
+	Libraries map[string]string `json:"libraries,omitempty"`
+	// Features are the capabilities of the libraries (e.g. kafka.FeatureSet)
+	Features map[string]bool `json:"features,omitempty"`
+	// Schemas are the schemas consumed and produced by the service (see Consumes, Produces)
+	Schemas *Schemas `json:"schemas,omitempty"`
 }
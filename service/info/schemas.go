@@ -0,0 +1,94 @@
+package info
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// A SchemaRef is a subject of the schema registry and the versions of the schema used by the service
+type SchemaRef struct {
+	Subject string `json:"subject"`
+	// Versions are the supported versions (all versions if it's empty)
+	Versions []int `json:"versions,omitempty"`
+	// Topic is the topic of the messages (optional)
+	Topic string `json:"topic,omitempty"`
+}
+
+// Schemas is a manifest of the schemas consumed and produced by the service.
+// It's published on /info (see router.AdminRouter) and to the central topic (see producer.PublishSchemas)
+// for the impact analysis of the schema changes.
+type Schemas struct {
+	consumes []SchemaRef
+	produces []SchemaRef
+	mu       sync.RWMutex
+}
+
+// SchemasManifest is a snapshot of the schemas of the service
+type SchemasManifest struct {
+	Consumes []SchemaRef `json:"consumes,omitempty"`
+	Produces []SchemaRef `json:"produces,omitempty"`
+}
+
+var schemasMu sync.Mutex
+
+// Consumes declares the schemas of the consumed messages
+func (i *Info) Consumes(refs ...SchemaRef) {
+	i.schemas().add(&i.Schemas.consumes, refs)
+}
+
+// Produces declares the schemas of the produced messages
+func (i *Info) Produces(refs ...SchemaRef) {
+	i.schemas().add(&i.Schemas.produces, refs)
+}
+
+func (i *Info) schemas() *Schemas {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+
+	if i.Schemas == nil {
+		i.Schemas = &Schemas{}
+	}
+
+	return i.Schemas
+}
+
+func (s *Schemas) add(list *[]SchemaRef, refs []SchemaRef) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ref := range refs {
+		ref.Versions = append([]int(nil), ref.Versions...)
+		sort.Ints(ref.Versions)
+		*list = append(*list, ref)
+	}
+
+	sort.SliceStable(*list, func(i, j int) bool {
+		a, b := (*list)[i], (*list)[j]
+		if a.Subject != b.Subject {
+			return a.Subject < b.Subject
+		}
+		return a.Topic < b.Topic
+	})
+}
+
+// Manifest returns the snapshot of the declared schemas
+func (s *Schemas) Manifest() SchemasManifest {
+
+	if s == nil {
+		return SchemasManifest{}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return SchemasManifest{
+		Consumes: append([]SchemaRef(nil), s.consumes...),
+		Produces: append([]SchemaRef(nil), s.produces...),
+	}
+}
+
+// MarshalJSON returns the manifest (json.Marshaler implementation)
+func (s *Schemas) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Manifest())
+}
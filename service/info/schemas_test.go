@@ -0,0 +1,47 @@
+package info
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemas(t *testing.T) {
+
+	appinfo := &Info{Name: "app"}
+
+	data, err := json.Marshal(appinfo)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "schemas")
+
+	appinfo.Consumes(
+		SchemaRef{Subject: "orders-value", Versions: []int{3, 2}, Topic: "orders"},
+		SchemaRef{Subject: "events-value"},
+	)
+	appinfo.Produces(SchemaRef{Subject: "payments-value", Versions: []int{1}})
+
+	require.Equal(t, SchemasManifest{
+		Consumes: []SchemaRef{
+			{Subject: "events-value"},
+			{Subject: "orders-value", Versions: []int{2, 3}, Topic: "orders"},
+		},
+		Produces: []SchemaRef{{Subject: "payments-value", Versions: []int{1}}},
+	}, appinfo.Schemas.Manifest())
+
+	data, err = json.Marshal(appinfo)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"name": "app", "version": "", "commit": "", "goVersion": "", "buildDate": "",
+		"schemas": {
+			"consumes": [
+				{"subject": "events-value"},
+				{"subject": "orders-value", "versions": [2, 3], "topic": "orders"}
+			],
+			"produces": [{"subject": "payments-value", "versions": [1]}]
+		}
+	}`, string(data))
+
+	var empty *Schemas
+	require.Equal(t, SchemasManifest{}, empty.Manifest())
+}
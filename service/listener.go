@@ -0,0 +1,208 @@
+package service
+
+import (
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// EnvInheritedListeners is a list of the addresses of the listeners inherited
+// from the parent process (separated by ';'). The file descriptors start from 3.
+const EnvInheritedListeners = "DIALOG_INHERITED_LISTENERS"
+
+const (
+	_InheritedFirstFD   = 3
+	_InheritedSeparator = ";"
+)
+
+type listeners struct {
+	inherited map[string]net.Listener
+	active    map[string]*listener
+	once      sync.Once
+	mu        sync.Mutex
+}
+
+var _Listeners = &listeners{
+	active: make(map[string]*listener),
+}
+
+// listener removes itself from the active listeners on close
+type listener struct {
+	net.Listener
+	addr string
+}
+
+func (l *listener) Close() error {
+	_Listeners.mu.Lock()
+	if _Listeners.active[l.addr] == l {
+		delete(_Listeners.active, l.addr)
+	}
+	_Listeners.mu.Unlock()
+
+	return l.Listener.Close()
+}
+
+// Listen returns the listener inherited from the parent process (see Restart)
+// or listens on the TCP network address
+func Listen(addr string) (net.Listener, error) {
+
+	_Listeners.once.Do(func() {
+		_Listeners.inherited = loadInheritedListeners(os.Getenv(EnvInheritedListeners), _InheritedFirstFD)
+	})
+
+	_Listeners.mu.Lock()
+	defer _Listeners.mu.Unlock()
+
+	l, ok := _Listeners.inherited[addr]
+	if ok {
+		delete(_Listeners.inherited, addr)
+	} else {
+		var err error
+		if l, err = net.Listen("tcp", addr); err != nil {
+			return nil, err
+		}
+	}
+
+	retval := &listener{Listener: l, addr: addr}
+	_Listeners.active[addr] = retval
+
+	return retval, nil
+}
+
+func loadInheritedListeners(env string, firstFD uintptr) map[string]net.Listener {
+
+	retval := make(map[string]net.Listener)
+	if env == "" {
+		return retval
+	}
+
+	for i, addr := range strings.Split(env, _InheritedSeparator) {
+		f := os.NewFile(firstFD+uintptr(i), "listener:"+addr)
+		if f == nil {
+			continue
+		}
+
+		l, err := net.FileListener(f)
+		// the listener has a copy of the file descriptor
+		_ = f.Close()
+		if err != nil {
+			continue
+		}
+
+		retval[addr] = l
+	}
+
+	return retval
+}
+
+// Restart starts a new copy of the process which inherits the active listeners.
+// The current process should close the services after that:
+// the not accepted connections are served by the new process.
+func Restart() (*os.Process, error) {
+
+	_Listeners.mu.Lock()
+	addrs := make([]string, 0, len(_Listeners.active))
+	files := make([]*os.File, 0, len(_Listeners.active))
+	for addr, l := range _Listeners.active {
+		fl, ok := l.Listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			continue
+		}
+
+		f, err := fl.File()
+		if err != nil {
+			_Listeners.mu.Unlock()
+			closeFiles(files)
+			return nil, errors.Wrapf(err, "failed to get file of listener %s", addr)
+		}
+
+		addrs = append(addrs, addr)
+		files = append(files, f)
+	}
+	_Listeners.mu.Unlock()
+	defer closeFiles(files)
+
+	path, err := os.Executable()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get executable")
+	}
+
+	env := make([]string, 0, len(os.Environ())+1)
+	for _, item := range os.Environ() {
+		if !strings.HasPrefix(item, EnvInheritedListeners+"=") {
+			env = append(env, item)
+		}
+	}
+	env = append(env, EnvInheritedListeners+"="+strings.Join(addrs, _InheritedSeparator))
+
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, "failed to start process")
+	}
+
+	return cmd.Process, nil
+}
+
+// NotifyRestart restarts the process (see Restart) on SIGUSR2 (not supported on Windows) and closes the services
+// to drain the current process. The returned function stops the notification.
+func NotifyRestart(l *zap.Logger, services ...io.Closer) (stop func()) {
+
+	if l == nil {
+		l = zap.NewNop()
+	}
+
+	sig := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	notifyRestartSignal(sig)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-sig:
+			}
+
+			p, err := Restart()
+			if err != nil {
+				l.Error("failed to restart", zap.Error(err))
+				continue
+			}
+
+			l.Info("the process is restarted, draining...", zap.Int("pid", p.Pid))
+			for _, s := range services {
+				if err := s.Close(); err != nil {
+					l.Error("failed to close service", zap.Error(err))
+				}
+			}
+			return
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sig)
+			close(done)
+		})
+	}
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
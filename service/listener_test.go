@@ -0,0 +1,62 @@
+package service
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListen(t *testing.T) {
+
+	h, p := tempAddress(t)
+	address := net.JoinHostPort(h, p)
+
+	l, err := Listen(address)
+	require.NoError(t, err)
+
+	_Listeners.mu.Lock()
+	require.Contains(t, _Listeners.active, address)
+	_Listeners.mu.Unlock()
+
+	require.NoError(t, l.Close())
+
+	_Listeners.mu.Lock()
+	require.NotContains(t, _Listeners.active, address)
+	_Listeners.mu.Unlock()
+}
+
+func TestLoadInheritedListeners(t *testing.T) {
+
+	require.Empty(t, loadInheritedListeners("", _InheritedFirstFD))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	f, err := l.(*net.TCPListener).File()
+	require.NoError(t, err)
+	defer f.Close()
+
+	inherited := loadInheritedListeners(l.Addr().String(), f.Fd())
+	require.Len(t, inherited, 1)
+
+	lInherited := inherited[l.Addr().String()]
+	require.NotNil(t, lInherited)
+	defer lInherited.Close()
+	require.Equal(t, l.Addr().String(), lInherited.Addr().String())
+
+	accepted := make(chan error, 1)
+	go func() {
+		conn, err := lInherited.Accept()
+		if err == nil {
+			_ = conn.Close()
+		}
+		accepted <- err
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	require.NoError(t, <-accepted)
+}
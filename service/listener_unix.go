@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package service
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func notifyRestartSignal(sig chan<- os.Signal) {
+	signal.Notify(sig, syscall.SIGUSR2)
+}
@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package service
+
+import (
+	"os"
+)
+
+// SIGUSR2 isn't supported on Windows: the process isn't restarted by the signal
+func notifyRestartSignal(chan<- os.Signal) {}
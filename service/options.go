@@ -0,0 +1,83 @@
+package service
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/reporter"
+)
+
+const _DefaultCloseTimeout = time.Second * 30
+
+type options struct {
+	closeTimeout time.Duration
+	server       *http.Server
+	reporter     reporter.IReporter
+}
+
+// An Option modifies the service settings (see NewHTTPWithOptions, GRPC.WithOptions)
+type Option func(o *options)
+
+// WithTimeout sets the timeout of the graceful shutdown (30s by default)
+func WithTimeout(val time.Duration) Option {
+	return func(o *options) {
+		o.closeTimeout = val
+	}
+}
+
+// WithServer sets a custom http server configuration (http service only)
+func WithServer(server *http.Server) Option {
+	return func(o *options) {
+		o.server = server
+	}
+}
+
+// WithReporter sets the reporter of the fatal errors of the service
+func WithReporter(r reporter.IReporter) Option {
+	return func(o *options) {
+		o.reporter = r
+	}
+}
+
+func newOptions(opts []Option) *options {
+
+	o := &options{
+		closeTimeout: _DefaultCloseTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// NewHTTPWithOptions creates a http service with the handler
+func NewHTTPWithOptions(handler http.Handler, opts ...Option) *HTTP {
+
+	o := newOptions(opts)
+
+	s := NewHTTP(handler, o.closeTimeout)
+	s.server = o.server
+	if o.reporter != nil {
+		s.SetReporter(o.reporter)
+	}
+
+	return s
+}
+
+// WithOptions applies the options to the grpc service
+func (g *GRPC) WithOptions(opts ...Option) *GRPC {
+
+	o := &options{closeTimeout: g.closeTimeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	g.closeTimeout = o.closeTimeout
+	if o.reporter != nil {
+		g.SetReporter(o.reporter)
+	}
+
+	return g
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testReporter struct {
+	errs []error
+}
+
+func (r *testReporter) Report(_ context.Context, err error, _ map[string]string) {
+	r.errs = append(r.errs, err)
+}
+
+func TestHTTPWithOptions(t *testing.T) {
+
+	svc := NewHTTPWithOptions(http.NotFoundHandler())
+	require.Equal(t, _DefaultCloseTimeout, svc.closeTimeout)
+	require.Nil(t, svc.server)
+	require.Nil(t, svc.reporter)
+
+	server := &http.Server{}
+	r := &testReporter{}
+
+	svc = NewHTTPWithOptions(http.NotFoundHandler(),
+		WithTimeout(time.Second),
+		WithServer(server),
+		WithReporter(r))
+	require.Equal(t, time.Second, svc.closeTimeout)
+	require.Equal(t, server, svc.server)
+	require.Equal(t, r, svc.reporter)
+}
+
+func TestGRPCWithOptions(t *testing.T) {
+
+	r := &testReporter{}
+
+	svc := NewGRPC().WithOptions(WithTimeout(time.Second), WithReporter(r))
+	require.Equal(t, time.Second, svc.closeTimeout)
+	require.Equal(t, r, svc.reporter)
+
+	svc = NewGRPC().WithOptions()
+	require.Equal(t, _DefaultCloseTimeout, svc.closeTimeout)
+}
+
+func TestServiceReporter(t *testing.T) {
+
+	r := &testReporter{}
+
+	svc := NewHTTPWithOptions(http.NotFoundHandler(), WithReporter(r))
+	require.Error(t, svc.ListenAndServeAddr(nil, "invalid address"))
+	require.Len(t, r.errs, 1)
+}
@@ -11,12 +11,24 @@ import (
 
 // AdminRouter router for administration functions
 type AdminRouter struct {
-	appinfo *info.Info
-	mux     *http.ServeMux
+	appinfo      *info.Info
+	mux          *http.ServeMux
+	auth         FuncMiddleware
+	consumers    consumers
+	diagnostics  diagnostics
+	healthChecks healthChecks
 }
 
 // NewAdminRouter create router for administration functions
 func NewAdminRouter(appinfo *info.Info) *AdminRouter {
+	return newAdminRouter(appinfo, nil)
+}
+
+func newAdminRouter(appinfo *info.Info, metrics http.Handler) *AdminRouter {
+
+	if metrics == nil {
+		metrics = promhttp.Handler()
+	}
 
 	a := &AdminRouter{
 		appinfo: appinfo,
@@ -25,7 +37,7 @@ func NewAdminRouter(appinfo *info.Info) *AdminRouter {
 
 	a.HandleFunc("/health", a.health)
 	a.HandleFunc("/info", a.info)
-	a.Handle("/metrics", promhttp.Handler())
+	a.Handle("/metrics", metrics)
 	a.HandleFunc("/debug/pprof/", pprof.Index)
 	a.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
 	a.HandleFunc("/debug/pprof/profile", pprof.Profile)
@@ -63,6 +75,11 @@ func (a *AdminRouter) health(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if failed := a.checkHealth(); len(failed) > 0 {
+		writeHealthErrors(w, failed)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
 
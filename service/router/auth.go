@@ -0,0 +1,31 @@
+package router
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// FuncMiddleware wraps the handler (e.g. authentication)
+type FuncMiddleware func(http.Handler) http.Handler
+
+// TokenAuth returns the middleware that checks the header 'Authorization: Bearer <token>'
+func TokenAuth(token string) FuncMiddleware {
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+			const prefix = "Bearer "
+
+			header := req.Header.Get("Authorization")
+			if token == "" ||
+				!strings.HasPrefix(header, prefix) ||
+				subtle.ConstantTimeCompare([]byte(header[len(prefix):]), []byte(token)) != 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IConsumerControl controls consumption of the consumer (e.g. consumer.Consumer, consumer.Group)
+type IConsumerControl interface {
+	SleepAll(delay time.Duration) error
+	ResumeAll() error
+	Commit() error
+	Resubscribe() error
+}
+
+// IConsumerDrainer drains the consumer before the shutdown (e.g. consumer.Consumer, consumer.Group)
+type IConsumerDrainer interface {
+	Drain(ctx context.Context) error
+}
+
+// IConsumerInspector returns the state of the consumer encoded to JSON (e.g. consumer.Consumer, consumer.Group)
+type IConsumerInspector interface {
+	Inspect() (interface{}, error)
+}
+
+const (
+	_ConsumersPath = "/consumers/"
+	_DashboardPath = "/debug/consumers"
+	_PeekPath      = "/messages/peek"
+)
+
+type consumers struct {
+	list map[string]IConsumerControl
+	mu   sync.RWMutex
+}
+
+// SetAuthMiddleware sets the middleware which protects the admin endpoints of the consumers.
+// The endpoints are forbidden without the middleware.
+func (a *AdminRouter) SetAuthMiddleware(auth FuncMiddleware) {
+	a.consumers.mu.Lock()
+	a.auth = auth
+	a.consumers.mu.Unlock()
+}
+
+// RegisterConsumer registers the consumer endpoints (method POST):
+//
+//	/consumers/{name}/pause?delay=1m - pause consumption
+//	/consumers/{name}/resume - resume consumption
+//	/consumers/{name}/commit - commit offsets of the processed messages
+//	/consumers/{name}/rebalance - unsubscribe and subscribe again
+//	/consumers/{name}/drain?timeout=30s - finish processing, commit and leave the group (see IConsumerDrainer)
+//
+// and the read-only endpoints (method GET):
+//
+//	/consumers/ - the names of the consumers
+//	/consumers/{name}/state - the state of the consumer (see IConsumerInspector)
+//	/debug/consumers - the dashboard of the states of the consumers
+func (a *AdminRouter) RegisterConsumer(name string, c IConsumerControl) {
+
+	a.consumers.mu.Lock()
+	defer a.consumers.mu.Unlock()
+
+	if a.consumers.list == nil {
+		a.consumers.list = make(map[string]IConsumerControl)
+		a.Handle(_ConsumersPath, http.HandlerFunc(a.consumerControl))
+		a.Handle(_DashboardPath, a.guarded(http.HandlerFunc(dashboard)))
+	}
+
+	a.consumers.list[name] = c
+}
+
+func (a *AdminRouter) consumerControl(w http.ResponseWriter, req *http.Request) {
+	a.guarded(http.HandlerFunc(a.consumerAction)).ServeHTTP(w, req)
+}
+
+// guarded returns the handler protected by the auth middleware (forbidden without the middleware)
+func (a *AdminRouter) guarded(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+		a.consumers.mu.RLock()
+		auth := a.auth
+		a.consumers.mu.RUnlock()
+
+		if auth == nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		auth(next).ServeHTTP(w, req)
+	})
+}
+
+// RegisterPeek registers the endpoint of the message peek (see consumer.PeekHandler):
+//
+//	GET /messages/peek?topic=name&partition=0&offset=1
+//
+// The endpoint is protected by the auth middleware like the consumer endpoints.
+func (a *AdminRouter) RegisterPeek(handler http.Handler) {
+	a.Handle(_PeekPath, a.guarded(handler))
+}
+
+func (a *AdminRouter) consumerAction(w http.ResponseWriter, req *http.Request) {
+
+	if req.Method == http.MethodGet {
+		a.consumerState(w, req)
+		return
+	}
+
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, _ConsumersPath), "/")
+	if len(parts) != 2 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	a.consumers.mu.RLock()
+	c, ok := a.consumers.list[parts[0]]
+	a.consumers.mu.RUnlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var err error
+	switch parts[1] {
+	case "pause":
+		var delay time.Duration
+		delay, err = time.ParseDuration(req.URL.Query().Get("delay"))
+		if err != nil || delay <= 0 {
+			http.Error(w, "invalid delay", http.StatusBadRequest)
+			return
+		}
+		err = c.SleepAll(delay)
+
+	case "resume":
+		err = c.ResumeAll()
+
+	case "commit":
+		err = c.Commit()
+
+	case "rebalance":
+		err = c.Resubscribe()
+
+	case "drain":
+		drainer, ok := c.(IConsumerDrainer)
+		if !ok {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+
+		ctx := req.Context()
+		if val := req.URL.Query().Get("timeout"); val != "" {
+			timeout, errParse := time.ParseDuration(val)
+			if errParse != nil || timeout <= 0 {
+				http.Error(w, "invalid timeout", http.StatusBadRequest)
+				return
+			}
+
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		err = drainer.Drain(ctx)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// consumerState writes the names of the consumers or the state of the consumer
+func (a *AdminRouter) consumerState(w http.ResponseWriter, req *http.Request) {
+
+	path := strings.TrimPrefix(req.URL.Path, _ConsumersPath)
+
+	a.consumers.mu.RLock()
+	names := make([]string, 0, len(a.consumers.list))
+	for name := range a.consumers.list {
+		names = append(names, name)
+	}
+	a.consumers.mu.RUnlock()
+
+	if path == "" {
+		sort.Strings(names)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(names)
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if parts[1] != "state" {
+		// the actions are available by POST
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	a.consumers.mu.RLock()
+	c, ok := a.consumers.list[parts[0]]
+	a.consumers.mu.RUnlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	inspector, ok := c.(IConsumerInspector)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	state, err := inspector.Inspect()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(state)
+}
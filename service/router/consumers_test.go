@@ -0,0 +1,206 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/service/info"
+	"github.com/stretchr/testify/require"
+)
+
+type testConsumerControl struct {
+	calls []string
+	delay time.Duration
+	err   error
+}
+
+func (c *testConsumerControl) SleepAll(delay time.Duration) error {
+	c.calls = append(c.calls, "pause")
+	c.delay = delay
+	return c.err
+}
+
+func (c *testConsumerControl) ResumeAll() error {
+	c.calls = append(c.calls, "resume")
+	return c.err
+}
+
+func (c *testConsumerControl) Commit() error {
+	c.calls = append(c.calls, "commit")
+	return c.err
+}
+
+func (c *testConsumerControl) Resubscribe() error {
+	c.calls = append(c.calls, "rebalance")
+	return c.err
+}
+
+type testConsumerDrainer struct {
+	testConsumerControl
+	deadline bool
+}
+
+func (c *testConsumerDrainer) Drain(ctx context.Context) error {
+	c.calls = append(c.calls, "drain")
+	_, c.deadline = ctx.Deadline()
+	return c.err
+}
+
+func TestAdminRouterConsumers(t *testing.T) {
+
+	const token = "secret"
+
+	ctrl := &testConsumerControl{}
+
+	adminRouter := NewAdminRouter(&info.Info{})
+	adminRouter.RegisterConsumer("orders", ctrl)
+
+	request := func(method, target, auth string) int {
+		req := httptest.NewRequest(method, target, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+
+		w := httptest.NewRecorder()
+		adminRouter.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	// without auth middleware
+	require.Equal(t, http.StatusForbidden, request(http.MethodPost, "/consumers/orders/commit", token))
+
+	adminRouter.SetAuthMiddleware(TokenAuth(token))
+
+	for _, testData := range []struct {
+		Method string
+		Target string
+		Auth   string
+		Code   int
+	}{
+		{http.MethodPost, "/consumers/orders/commit", "", http.StatusUnauthorized},
+		{http.MethodPost, "/consumers/orders/commit", "invalid", http.StatusUnauthorized},
+		{http.MethodGet, "/consumers/orders/commit", token, http.StatusMethodNotAllowed},
+		{http.MethodPost, "/consumers/unknown/commit", token, http.StatusNotFound},
+		{http.MethodPost, "/consumers/orders/unknown", token, http.StatusNotFound},
+		{http.MethodPost, "/consumers/orders/pause", token, http.StatusBadRequest},
+		{http.MethodPost, "/consumers/orders/pause?delay=1m", token, http.StatusOK},
+		{http.MethodPost, "/consumers/orders/resume", token, http.StatusOK},
+		{http.MethodPost, "/consumers/orders/commit", token, http.StatusOK},
+		{http.MethodPost, "/consumers/orders/rebalance", token, http.StatusOK},
+	} {
+		require.Equal(t, testData.Code, request(testData.Method, testData.Target, testData.Auth), testData.Method+" "+testData.Target)
+	}
+
+	require.Equal(t, []string{"pause", "resume", "commit", "rebalance"}, ctrl.calls)
+	require.Equal(t, time.Minute, ctrl.delay)
+
+	ctrl.err = errors.New("failed")
+	require.Equal(t, http.StatusInternalServerError, request(http.MethodPost, "/consumers/orders/commit", token))
+
+	// the consumer without the drain
+	require.Equal(t, http.StatusNotImplemented, request(http.MethodPost, "/consumers/orders/drain", token))
+
+	drainer := &testConsumerDrainer{}
+	adminRouter.RegisterConsumer("payments", drainer)
+
+	require.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/consumers/payments/drain?timeout=x", token))
+	require.Equal(t, http.StatusOK, request(http.MethodPost, "/consumers/payments/drain", token))
+	require.False(t, drainer.deadline)
+	require.Equal(t, http.StatusOK, request(http.MethodPost, "/consumers/payments/drain?timeout=30s", token))
+	require.True(t, drainer.deadline)
+	require.Equal(t, []string{"drain", "drain"}, drainer.calls)
+}
+
+type testConsumerInspector struct {
+	testConsumerControl
+	state interface{}
+}
+
+func (c *testConsumerInspector) Inspect() (interface{}, error) {
+	return c.state, c.err
+}
+
+func TestAdminRouterConsumerState(t *testing.T) {
+
+	const token = "secret"
+
+	adminRouter := NewAdminRouterWithOptions(&info.Info{}, WithAuth(TokenAuth(token)))
+	adminRouter.RegisterConsumer("orders", &testConsumerControl{})
+
+	inspector := &testConsumerInspector{state: map[string]int{"lag": 10}}
+	adminRouter.RegisterConsumer("payments", inspector)
+
+	request := func(target, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+
+		w := httptest.NewRecorder()
+		adminRouter.ServeHTTP(w, req)
+
+		return w
+	}
+
+	w := request("/consumers/", token)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `["orders", "payments"]`, w.Body.String())
+
+	w = request("/consumers/payments/state", token)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"lag": 10}`, w.Body.String())
+
+	require.Equal(t, http.StatusUnauthorized, request("/consumers/payments/state", "").Code)
+	require.Equal(t, http.StatusNotImplemented, request("/consumers/orders/state", token).Code)
+	require.Equal(t, http.StatusNotFound, request("/consumers/unknown/state", token).Code)
+	require.Equal(t, http.StatusNotFound, request("/consumers/payments", token).Code)
+
+	inspector.err = errors.New("failed")
+	w = request("/consumers/payments/state", token)
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, "failed\n", w.Body.String())
+
+	w = request("/debug/consumers", token)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	require.Contains(t, w.Body.String(), `fetchJSON("../consumers/")`)
+	require.Equal(t, http.StatusUnauthorized, request("/debug/consumers", "").Code)
+}
+
+func TestAdminRouterPeek(t *testing.T) {
+
+	const token = "secret"
+
+	adminRouter := NewAdminRouter(&info.Info{})
+	adminRouter.RegisterPeek(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(req.URL.Query().Get("offset")))
+	}))
+
+	request := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/messages/peek?topic=a&partition=0&offset=5", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+
+		w := httptest.NewRecorder()
+		adminRouter.ServeHTTP(w, req)
+
+		return w
+	}
+
+	// without auth middleware
+	require.Equal(t, http.StatusForbidden, request(token).Code)
+
+	adminRouter.SetAuthMiddleware(TokenAuth(token))
+	require.Equal(t, http.StatusUnauthorized, request("invalid").Code)
+
+	w := request(token)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "5", w.Body.String())
+}
@@ -0,0 +1,218 @@
+package router
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path"
+	"time"
+)
+
+// _DashboardIntervals are the refresh intervals of the dashboard, the second one is selected
+var _DashboardIntervals = []time.Duration{2 * time.Second, 5 * time.Second, 30 * time.Second}
+
+// _DashboardTemplates are the pages of the dashboard (the layout is shared with the next debug pages)
+var _DashboardTemplates = mustTemplates(NewTemplates(pagesFS{
+	"layout.html":    _DashboardLayout,
+	"consumers.html": _DashboardPage,
+}, TemplatesConfig{Layout: "layout.html"}))
+
+type dashboardData struct {
+	Title     string
+	Intervals []dashboardInterval
+}
+
+type dashboardInterval struct {
+	Name     string
+	Value    int64
+	Selected bool
+}
+
+// dashboard serves the page which polls the states of the consumers (see RegisterConsumer)
+func dashboard(w http.ResponseWriter, req *http.Request) {
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	data := dashboardData{Title: "Consumers"}
+	for i, d := range _DashboardIntervals {
+		data.Intervals = append(data.Intervals, dashboardInterval{
+			Name:     d.String(),
+			Value:    int64(d / time.Millisecond),
+			Selected: i == 1,
+		})
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := _DashboardTemplates.Render(w, http.StatusOK, "consumers.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// pagesFS is the file system of the pages defined in the code
+type pagesFS map[string]string
+
+func (p pagesFS) Open(name string) (http.File, error) {
+
+	name = path.Clean("/" + name)[1:]
+	text, ok := p[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return &pageFile{Reader: bytes.NewReader([]byte(text)), name: name, size: int64(len(text))}, nil
+}
+
+type pageFile struct {
+	*bytes.Reader
+	name string
+	size int64
+}
+
+func (f *pageFile) Close() error                             { return nil }
+func (f *pageFile) Readdir(count int) ([]os.FileInfo, error) { return nil, os.ErrInvalid }
+func (f *pageFile) Stat() (os.FileInfo, error)               { return f, nil }
+func (f *pageFile) Name() string                             { return path.Base(f.name) }
+func (f *pageFile) Size() int64                              { return f.size }
+func (f *pageFile) Mode() os.FileMode                        { return 0444 }
+func (f *pageFile) ModTime() time.Time                       { return time.Time{} }
+func (f *pageFile) IsDir() bool                              { return false }
+func (f *pageFile) Sys() interface{}                         { return nil }
+
+// the paths are relative: the admin router can be mounted with a prefix
+const _DashboardLayout = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; margin: 16px; color: #222; }
+h2 { margin: 24px 0 4px; }
+h3 { margin: 12px 0 4px; font-size: 13px; }
+table { border-collapse: collapse; margin-bottom: 8px; }
+th, td { border: 1px solid #ddd; padding: 2px 8px; text-align: left; }
+th { background: #f4f4f4; }
+.lag { text-align: right; }
+.paused { background: #fff3cd; }
+.error { color: #b00020; }
+.muted { color: #888; }
+</style>
+</head>
+<body>
+{{block "content" .}}{{end}}
+<script>
+"use strict";
+
+function el(tag, attrs, children) {
+	var node = document.createElement(tag);
+	Object.keys(attrs || {}).forEach(function (k) { node.setAttribute(k, attrs[k]); });
+	(children || []).forEach(function (c) {
+		node.appendChild(typeof c === "string" ? document.createTextNode(c) : c);
+	});
+	return node;
+}
+
+function table(headers, rows) {
+	if (!rows.length) {
+		return el("div", {"class": "muted"}, ["none"]);
+	}
+	return el("table", {}, [
+		el("tr", {}, headers.map(function (h) { return el("th", {}, [h]); }))
+	].concat(rows));
+}
+
+function row(cells, attrs) {
+	return el("tr", attrs, cells.map(function (c) { return el("td", {}, [String(c)]); }));
+}
+
+function fetchJSON(url) {
+	return fetch(url, {credentials: "same-origin"}).then(function (res) {
+		if (!res.ok) {
+			return res.text().then(function (text) { throw new Error(res.status + " " + text); });
+		}
+		return res.json();
+	});
+}
+{{block "script" .}}{{end}}
+</script>
+</body>
+</html>
+`
+
+const _DashboardPage = `{{define "content"}}
+<div class="muted">updated <span id="updated">-</span>, refresh <select id="interval">
+{{range .Intervals}}<option value="{{.Value}}"{{if .Selected}} selected{{end}}>{{.Name}}</option>{{end}}
+</select></div>
+<div id="consumers"></div>
+{{end}}
+
+{{define "script"}}
+function key(topic, partition) {
+	return topic + "/" + partition;
+}
+
+function renderWorker(s) {
+	var paused = {}, inflight = {};
+	(s.paused || []).forEach(function (p) { paused[key(p.topic, p.partition)] = true; });
+	(s.inFlight || []).forEach(function (p) { inflight[key(p.topic, p.partition)] = p.inFlight; });
+
+	var partitions = (s.partitions || []).map(function (p) {
+		var k = key(p.topic, p.partition);
+		return row([p.topic, p.partition, p.offset, p.highWatermark, p.lag < 0 ? "?" : p.lag, inflight[k] || 0, paused[k] ? "yes" : ""],
+			paused[k] ? {"class": "paused"} : {});
+	});
+	var commits = (s.commits || []).slice(0, 10).map(function (c) {
+		return row([new Date(c.time).toLocaleTimeString(), c.topic, c.partition, c.offset, c.count]);
+	});
+	var errors = (s.errors || []).slice(0, 10).map(function (e) {
+		return row([new Date(e.time).toLocaleTimeString(), e.error], {"class": "error"});
+	});
+
+	return el("div", {}, [
+		el("h3", {}, [s.consumerId + (s.sleepUntil ? " (sleeping until " + new Date(s.sleepUntil).toLocaleTimeString() + ")" : "")]),
+		table(["topic", "partition", "offset", "high watermark", "lag", "in flight", "paused"], partitions),
+		el("h3", {}, ["recent commits"]),
+		table(["time", "topic", "partition", "offset", "messages"], commits),
+		el("h3", {}, ["recent errors"]),
+		table(["time", "error"], errors)
+	]);
+}
+
+function renderConsumer(name, state, err) {
+	var children = [el("h2", {}, [name])];
+	if (err) {
+		children.push(el("div", {"class": "error"}, [err]));
+	} else {
+		(Array.isArray(state) ? state : [state]).forEach(function (s) { children.push(renderWorker(s)); });
+	}
+	return el("div", {}, children);
+}
+
+function refresh() {
+	return fetchJSON("../consumers/").then(function (names) {
+		return Promise.all(names.map(function (name) {
+			return fetchJSON("../consumers/" + encodeURIComponent(name) + "/state").then(
+				function (state) { return renderConsumer(name, state); },
+				function (err) { return renderConsumer(name, null, err.message); });
+		}));
+	}).then(function (nodes) {
+		var root = document.getElementById("consumers");
+		root.innerHTML = "";
+		nodes.forEach(function (n) { root.appendChild(n); });
+		document.getElementById("updated").textContent = new Date().toLocaleTimeString();
+	}).catch(function (err) {
+		document.getElementById("updated").textContent = "failed: " + err.message;
+	});
+}
+
+function loop() {
+	refresh().then(function () {
+		setTimeout(loop, Number(document.getElementById("interval").value));
+	});
+}
+
+loop();
+{{end}}
+`
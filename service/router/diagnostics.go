@@ -0,0 +1,231 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const _DiagnosticsPath = "/diagnostics"
+
+// FuncCheck is a quick self-test of the dependency (e.g. the broker metadata fetch or the database ping)
+type FuncCheck func(ctx context.Context) error
+
+// A CheckResult is a result of the self-test
+type CheckResult struct {
+	Name     string        `json:"name"`
+	OK       bool          `json:"ok"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// A DiagnosticsReport is a result of all self-tests
+type DiagnosticsReport struct {
+	Time   time.Time     `json:"time"`
+	OK     bool          `json:"ok"`
+	Checks []CheckResult `json:"checks"`
+}
+
+type diagnostics struct {
+	checks map[string]FuncCheck
+	mu     sync.RWMutex
+}
+
+// RegisterDiagnostic registers the self-test of the endpoint (method GET):
+//
+//	/diagnostics?timeout=5s - run all self-tests and return the report (status 503 if a test is failed)
+//
+// The endpoint is protected by the auth middleware like the consumer endpoints.
+func (a *AdminRouter) RegisterDiagnostic(name string, check FuncCheck) {
+
+	a.diagnostics.mu.Lock()
+	defer a.diagnostics.mu.Unlock()
+
+	if a.diagnostics.checks == nil {
+		a.diagnostics.checks = make(map[string]FuncCheck)
+		a.Handle(_DiagnosticsPath, a.guarded(http.HandlerFunc(a.diagnose)))
+	}
+
+	a.diagnostics.checks[name] = check
+}
+
+func (a *AdminRouter) diagnose(w http.ResponseWriter, req *http.Request) {
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	timeout := time.Second * 5
+	if val := req.URL.Query().Get("timeout"); val != "" {
+		var err error
+		timeout, err = time.ParseDuration(val)
+		if err != nil || timeout <= 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+
+	a.diagnostics.mu.RLock()
+	checks := make(map[string]FuncCheck, len(a.diagnostics.checks))
+	for name, check := range a.diagnostics.checks {
+		checks[name] = check
+	}
+	a.diagnostics.mu.RUnlock()
+
+	report := runChecks(ctx, checks)
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.OK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// runChecks runs the self-tests concurrently
+func runChecks(ctx context.Context, checks map[string]FuncCheck) *DiagnosticsReport {
+
+	report := &DiagnosticsReport{
+		Time:   time.Now(),
+		OK:     true,
+		Checks: make([]CheckResult, 0, len(checks)),
+	}
+
+	wg := sync.WaitGroup{}
+	mu := sync.Mutex{}
+
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check FuncCheck) {
+			defer wg.Done()
+
+			res := runCheck(ctx, name, check)
+
+			mu.Lock()
+			report.Checks = append(report.Checks, res)
+			report.OK = report.OK && res.OK
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	sort.Slice(report.Checks, func(i, j int) bool {
+		return report.Checks[i].Name < report.Checks[j].Name
+	})
+
+	return report
+}
+
+func runCheck(ctx context.Context, name string, check FuncCheck) (res CheckResult) {
+
+	res.Name = name
+	start := time.Now()
+
+	defer func() { res.Duration = time.Since(start) }()
+
+	// the check can ignore the context: the result is a timeout after the deadline
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- errors.New(fmt.Sprint("panic: ", r))
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "check is not completed")
+	}
+
+	res.OK = err == nil
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	return
+}
+
+// IPinger checks the connection (e.g. sql.DB)
+type IPinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingCheck returns the self-test of the connection
+func PingCheck(p IPinger) FuncCheck {
+	return p.PingContext
+}
+
+// ClockSkewCheck returns the self-test comparing the local time with the time
+// of the response of the server (the header 'Date' with the precision of a second)
+func ClockSkewCheck(url string, maxSkew time.Duration, client *http.Client) FuncCheck {
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return func(ctx context.Context) error {
+
+		req, err := http.NewRequest(http.MethodHead, url, nil)
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		remote, err := http.ParseTime(resp.Header.Get("Date"))
+		if err != nil {
+			return errors.Wrap(err, "invalid date of response")
+		}
+
+		// the middle of the request (the header precision is a second)
+		local := start.Add(time.Since(start) / 2)
+		skew := local.Sub(remote)
+		if skew < 0 {
+			skew = -skew
+		}
+
+		if skew > maxSkew+time.Second {
+			return errors.Errorf("clock skew %s exceeds %s", skew.Truncate(time.Millisecond), maxSkew)
+		}
+
+		return nil
+	}
+}
+
+// DiskSpaceCheck returns the self-test of the free space of the file system of the path (e.g. the spool directory).
+// The check fails as unsupported on the platforms other than Linux, macOS and FreeBSD.
+func DiskSpaceCheck(path string, minFree uint64) FuncCheck {
+
+	return func(context.Context) error {
+
+		free, err := freeSpace(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get file system stats of %s", path)
+		}
+
+		if free < minFree {
+			return errors.Errorf("free space of %s is %d bytes, expected at least %d", path, free, minFree)
+		}
+
+		return nil
+	}
+}
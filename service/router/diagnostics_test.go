@@ -0,0 +1,96 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dialogs/dialog-go-lib/service/info"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminRouterDiagnostics(t *testing.T) {
+
+	const token = "secret"
+
+	adminRouter := NewAdminRouter(&info.Info{})
+	adminRouter.SetAuthMiddleware(TokenAuth(token))
+
+	request := func(path string) (*httptest.ResponseRecorder, *DiagnosticsReport) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		w := httptest.NewRecorder()
+		adminRouter.ServeHTTP(w, req)
+
+		report := &DiagnosticsReport{}
+		if w.Code == http.StatusOK || w.Code == http.StatusServiceUnavailable {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), report))
+		}
+
+		return w, report
+	}
+
+	// not registered
+	w, _ := request("/diagnostics")
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	adminRouter.RegisterDiagnostic("b", func(context.Context) error { return nil })
+	adminRouter.RegisterDiagnostic("a", func(context.Context) error { return nil })
+
+	w, report := request("/diagnostics")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, report.OK)
+	require.Len(t, report.Checks, 2)
+	require.Equal(t, "a", report.Checks[0].Name)
+	require.True(t, report.Checks[0].OK)
+
+	adminRouter.RegisterDiagnostic("failed", func(context.Context) error { return errors.New("down") })
+	adminRouter.RegisterDiagnostic("panic", func(context.Context) error { panic("oops") })
+	adminRouter.RegisterDiagnostic("slow", func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	w, report = request("/diagnostics?timeout=50ms")
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.False(t, report.OK)
+	require.Len(t, report.Checks, 5)
+	require.Equal(t, "down", report.Checks[2].Error)
+	require.Equal(t, "panic: oops", report.Checks[3].Error)
+	require.Equal(t, "check is not completed: context deadline exceeded", report.Checks[4].Error)
+
+	w, _ = request("/diagnostics?timeout=invalid")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDiskSpaceCheck(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, DiskSpaceCheck(dir, 1)(context.Background()))
+	require.Error(t, DiskSpaceCheck(dir, 1<<62)(context.Background()))
+	require.Error(t, DiskSpaceCheck(dir+"/unknown", 1)(context.Background()))
+}
+
+func TestClockSkewCheck(t *testing.T) {
+
+	remote := time.Now()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Date", remote.UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	require.NoError(t, ClockSkewCheck(server.URL, time.Second, nil)(context.Background()))
+
+	remote = remote.Add(-time.Hour)
+	require.Error(t, ClockSkewCheck(server.URL, time.Second, nil)(context.Background()))
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package router
+
+import (
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+func freeSpace(string) (uint64, error) {
+	return 0, errors.Errorf("unsupported on %s", runtime.GOOS)
+}
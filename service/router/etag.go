@@ -0,0 +1,139 @@
+package router
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETag returns the strong entity tag of the content (the quoted prefix of the SHA-256 checksum)
+func ETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// WeakETag returns the weak entity tag of the content (e.g. the response is compressed by the proxy)
+func WeakETag(data []byte) string {
+	return "W/" + ETag(data)
+}
+
+// NotModified sets the 'ETag' and 'Last-Modified' headers (the empty values are skipped) and writes
+// the status 304 if the client has the actual version of the resource: 'If-None-Match' is checked
+// first, 'If-Modified-Since' is checked without 'If-None-Match'. Only GET and HEAD requests are checked.
+func NotModified(w http.ResponseWriter, req *http.Request, etag string, modified time.Time) bool {
+
+	h := w.Header()
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if !checkNotModified(req, etag, modified) {
+		return false
+	}
+
+	// the headers of the content aren't sent with 304
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+
+	return true
+}
+
+func checkNotModified(req *http.Request, etag string, modified time.Time) bool {
+
+	if match := req.Header.Get("If-None-Match"); match != "" {
+		return etag != "" && matchETag(match, etag)
+	}
+
+	since := req.Header.Get("If-Modified-Since")
+	if since == "" || modified.IsZero() {
+		return false
+	}
+
+	t, err := http.ParseTime(since)
+	if err != nil {
+		return false
+	}
+
+	// the precision of the header is a second
+	return !modified.Truncate(time.Second).After(t)
+}
+
+// matchETag checks the value of 'If-None-Match' by the weak comparison
+func matchETag(header, etag string) bool {
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, item := range strings.Split(header, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || strings.TrimPrefix(item, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Conditional returns the middleware which sets the entity tags of the successful GET responses
+// and answers 304 to the clients with the actual version (e.g. the polling of the state).
+// The responses are buffered: the streaming handlers must not be wrapped.
+// The handler can set 'ETag' itself (e.g. the version of the resource), cacheControl is
+// the value of 'Cache-Control' set if the handler doesn't set it (e.g. 'no-cache').
+func Conditional(cacheControl string) FuncMiddleware {
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			buf := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(buf, req)
+
+			h := w.Header()
+			if cacheControl != "" && h.Get("Cache-Control") == "" {
+				h.Set("Cache-Control", cacheControl)
+			}
+
+			if buf.status == http.StatusOK {
+				etag := h.Get("ETag")
+				if etag == "" {
+					etag = ETag(buf.body.Bytes())
+				}
+
+				if NotModified(w, req, etag, time.Time{}) {
+					return
+				}
+			}
+
+			w.WriteHeader(buf.status)
+			_, _ = buf.body.WriteTo(w)
+		})
+	}
+}
+
+// bufferedResponse keeps the status and the body of the response
+type bufferedResponse struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
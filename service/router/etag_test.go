@@ -0,0 +1,115 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestETag(t *testing.T) {
+
+	etag := ETag([]byte("data"))
+	require.Len(t, etag, 34)
+	require.Equal(t, etag, ETag([]byte("data")))
+	require.NotEqual(t, etag, ETag([]byte("other")))
+	require.Equal(t, "W/"+etag, WeakETag([]byte("data")))
+}
+
+func TestNotModified(t *testing.T) {
+
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 600, time.UTC)
+	etag := ETag([]byte("data"))
+
+	check := func(method string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		w.Header().Set("Content-Type", "text/plain")
+		if !NotModified(w, req, etag, modified) {
+			w.WriteHeader(http.StatusOK)
+		}
+		return w
+	}
+
+	for _, testInfo := range []struct {
+		method  string
+		headers map[string]string
+		code    int
+	}{
+		{method: http.MethodGet, code: http.StatusOK},
+		{method: http.MethodGet, headers: map[string]string{"If-None-Match": etag}, code: http.StatusNotModified},
+		{method: http.MethodHead, headers: map[string]string{"If-None-Match": `"a", W/` + etag}, code: http.StatusNotModified},
+		{method: http.MethodGet, headers: map[string]string{"If-None-Match": "*"}, code: http.StatusNotModified},
+		{method: http.MethodGet, headers: map[string]string{"If-None-Match": `"a"`}, code: http.StatusOK},
+		{method: http.MethodPost, headers: map[string]string{"If-None-Match": etag}, code: http.StatusOK},
+		{method: http.MethodGet, headers: map[string]string{"If-Modified-Since": "Thu, 02 Jan 2020 03:04:05 GMT"}, code: http.StatusNotModified},
+		{method: http.MethodGet, headers: map[string]string{"If-Modified-Since": "Thu, 02 Jan 2020 03:04:04 GMT"}, code: http.StatusOK},
+		{method: http.MethodGet, headers: map[string]string{"If-Modified-Since": "invalid"}, code: http.StatusOK},
+		// If-None-Match is checked first
+		{method: http.MethodGet, headers: map[string]string{"If-None-Match": `"a"`, "If-Modified-Since": "Thu, 02 Jan 2020 03:04:05 GMT"}, code: http.StatusOK},
+	} {
+		w := check(testInfo.method, testInfo.headers)
+		require.Equal(t, testInfo.code, w.Code, "%s %v", testInfo.method, testInfo.headers)
+		require.Equal(t, etag, w.Header().Get("ETag"))
+		require.Equal(t, "Thu, 02 Jan 2020 03:04:05 GMT", w.Header().Get("Last-Modified"))
+
+		if testInfo.code == http.StatusNotModified {
+			require.Empty(t, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestConditional(t *testing.T) {
+
+	status := http.StatusOK
+	var version string
+
+	handler := Conditional("no-cache")(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if version != "" {
+			w.Header().Set("ETag", version)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("state"))
+	}))
+
+	request := func(method, match string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		if match != "" {
+			req.Header.Set("If-None-Match", match)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "state", w.Body.String())
+	require.Equal(t, ETag([]byte("state")), w.Header().Get("ETag"))
+	require.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+
+	w = request(http.MethodGet, ETag([]byte("state")))
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Empty(t, w.Body.String())
+
+	// the tag of the handler
+	version = `"v2"`
+	require.Equal(t, http.StatusOK, request(http.MethodGet, ETag([]byte("state"))).Code)
+	require.Equal(t, http.StatusNotModified, request(http.MethodGet, `"v2"`).Code)
+
+	// the errors and the other methods aren't checked
+	status = http.StatusInternalServerError
+	require.Equal(t, http.StatusInternalServerError, request(http.MethodGet, `"v2"`).Code)
+
+	status = http.StatusOK
+	w = request(http.MethodPost, `"v2"`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "state", w.Body.String())
+}
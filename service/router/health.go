@@ -0,0 +1,64 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// IHealthChecker reports the state of the component (e.g. consumer.Consumer, consumer.Group)
+type IHealthChecker interface {
+	HealthCheck() error
+}
+
+type healthChecks struct {
+	list map[string]IHealthChecker
+	mu   sync.RWMutex
+}
+
+// RegisterHealthCheck registers the check of the endpoint /health (liveness and readiness probes):
+// the status is 503 and the body is the errors of the failed checks by the names if a check is failed
+func (a *AdminRouter) RegisterHealthCheck(name string, c IHealthChecker) {
+
+	a.healthChecks.mu.Lock()
+	defer a.healthChecks.mu.Unlock()
+
+	if a.healthChecks.list == nil {
+		a.healthChecks.list = make(map[string]IHealthChecker)
+	}
+
+	a.healthChecks.list[name] = c
+}
+
+// HealthCheck returns the self-test of the component (see RegisterDiagnostic)
+func HealthCheck(c IHealthChecker) FuncCheck {
+	return func(context.Context) error {
+		return c.HealthCheck()
+	}
+}
+
+// checkHealth returns the errors of the failed checks
+func (a *AdminRouter) checkHealth() map[string]string {
+
+	a.healthChecks.mu.RLock()
+	defer a.healthChecks.mu.RUnlock()
+
+	var failed map[string]string
+	for name, c := range a.healthChecks.list {
+		if err := c.HealthCheck(); err != nil {
+			if failed == nil {
+				failed = make(map[string]string)
+			}
+			failed[name] = err.Error()
+		}
+	}
+
+	return failed
+}
+
+func writeHealthErrors(w http.ResponseWriter, failed map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_ = json.NewEncoder(w).Encode(failed)
+}
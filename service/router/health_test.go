@@ -0,0 +1,48 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dialogs/dialog-go-lib/service/info"
+	"github.com/stretchr/testify/require"
+)
+
+type testHealthChecker struct {
+	err error
+}
+
+func (c *testHealthChecker) HealthCheck() error {
+	return c.err
+}
+
+func TestAdminRouterHealthCheck(t *testing.T) {
+
+	orders := &testHealthChecker{}
+	events := &testHealthChecker{}
+
+	adminRouter := NewAdminRouter(&info.Info{})
+	adminRouter.RegisterHealthCheck("orders", orders)
+	adminRouter.RegisterHealthCheck("events", events)
+
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		adminRouter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		return w
+	}
+
+	w := request()
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Body.String())
+
+	orders.err = errors.New("consumer is stuck")
+	w = request()
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.JSONEq(t, `{"orders":"consumer is stuck"}`, w.Body.String())
+
+	require.EqualError(t, HealthCheck(orders)(context.Background()), "consumer is stuck")
+	require.NoError(t, HealthCheck(events)(context.Background()))
+}
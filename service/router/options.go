@@ -0,0 +1,43 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/dialogs/dialog-go-lib/service/info"
+)
+
+type options struct {
+	auth    FuncMiddleware
+	metrics http.Handler
+}
+
+// An Option modifies the admin router settings (see NewAdminRouterWithOptions)
+type Option func(o *options)
+
+// WithAuth sets the middleware which protects the admin endpoints of the consumers
+func WithAuth(auth FuncMiddleware) Option {
+	return func(o *options) {
+		o.auth = auth
+	}
+}
+
+// WithMetrics sets the handler of the metrics (promhttp.Handler by default)
+func WithMetrics(handler http.Handler) Option {
+	return func(o *options) {
+		o.metrics = handler
+	}
+}
+
+// NewAdminRouterWithOptions create router for administration functions
+func NewAdminRouterWithOptions(appinfo *info.Info, opts ...Option) *AdminRouter {
+
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	a := newAdminRouter(appinfo, o.metrics)
+	a.auth = o.auth
+
+	return a
+}
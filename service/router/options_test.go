@@ -0,0 +1,31 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dialogs/dialog-go-lib/service/info"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminRouterWithOptions(t *testing.T) {
+
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	adminRouter := NewAdminRouterWithOptions(&info.Info{},
+		WithAuth(TokenAuth("secret")),
+		WithMetrics(metrics))
+	adminRouter.RegisterConsumer("orders", &testConsumerControl{})
+
+	w := httptest.NewRecorder()
+	adminRouter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	// the auth middleware is set
+	w = httptest.NewRecorder()
+	adminRouter.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/consumers/orders/commit", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,159 @@
+package router
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const _DefaultIndex = "index.html"
+
+// The pre-compressed variants of the files: the file with the suffix is served instead of the original one
+// if the client accepts the encoding (e.g. 'app.js.br' for 'app.js')
+var _StaticEncodings = []struct {
+	name   string
+	suffix string
+}{
+	{name: "br", suffix: ".br"},
+	{name: "gzip", suffix: ".gz"},
+}
+
+// StaticConfig is the settings of the static files handler (see NewStatic)
+type StaticConfig struct {
+	// Index is the file of the directories ('index.html' by default)
+	Index string
+	// SPA serves the index file for the unknown paths without an extension (the routes of the single page application)
+	SPA bool
+	// MaxAge is the max age of the cache of the files, the index file isn't cached ('no-cache')
+	MaxAge time.Duration
+}
+
+type static struct {
+	fs  http.FileSystem
+	cfg StaticConfig
+}
+
+// NewStatic returns the handler of the static files (e.g. http.FS of embed.FS with Go 1.16+,
+// http.Dir or the file system of go-bindata). The handler is mounted with http.StripPrefix.
+func NewStatic(fs http.FileSystem, cfg StaticConfig) http.Handler {
+
+	if cfg.Index == "" {
+		cfg.Index = _DefaultIndex
+	}
+
+	return &static{fs: fs, cfg: cfg}
+}
+
+func (s *static) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := path.Clean("/" + r.URL.Path)
+	if strings.HasSuffix(name, "/") {
+		name += s.cfg.Index
+	}
+
+	if !s.exists(name) {
+		dir := path.Join(name, s.cfg.Index)
+		switch {
+		case s.exists(dir):
+			name = dir
+		case s.cfg.SPA && path.Ext(name) == "":
+			name = "/" + s.cfg.Index
+		default:
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	s.serve(w, r, name)
+}
+
+func (s *static) serve(w http.ResponseWriter, r *http.Request, name string) {
+
+	h := w.Header()
+	h.Set("Vary", "Accept-Encoding")
+
+	if path.Base(name) == s.cfg.Index {
+		h.Set("Cache-Control", "no-cache")
+	} else if s.cfg.MaxAge > 0 {
+		h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(s.cfg.MaxAge.Seconds())))
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+
+	filename := name
+	for _, enc := range _StaticEncodings {
+		if acceptsEncoding(r.Header.Get("Accept-Encoding"), enc.name) && s.exists(name+enc.suffix) {
+			filename = name + enc.suffix
+			h.Set("Content-Encoding", enc.name)
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			break
+		}
+	}
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+
+	f, err := s.fs.Open(filename)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// exists checks the regular file
+func (s *static) exists(name string) bool {
+
+	f, err := s.fs.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeType == 0
+}
+
+// acceptsEncoding checks the value of the 'Accept-Encoding' header (the encoding with 'q=0' isn't accepted)
+func acceptsEncoding(header, encoding string) bool {
+
+	for _, item := range strings.Split(header, ",") {
+		parts := strings.Split(item, ";")
+		if !strings.EqualFold(strings.TrimSpace(parts[0]), encoding) {
+			continue
+		}
+
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
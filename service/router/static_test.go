@@ -0,0 +1,108 @@
+package router
+
+import (
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStaticDir(t *testing.T) string {
+
+	dir, err := ioutil.TempDir("", "static")
+	require.NoError(t, err)
+
+	for name, data := range map[string]string{
+		"index.html":         "<html>index</html>",
+		"app.js":             "console.log(1)",
+		"app.js.gz":          "gzip",
+		"app.js.br":          "brotli",
+		"style.css":          "body{}",
+		"docs/index.html":    "<html>docs</html>",
+		"images/logo.svg.gz": "logo",
+	} {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		require.NoError(t, ioutil.WriteFile(filename, []byte(data), 0644))
+	}
+
+	return dir
+}
+
+func TestStatic(t *testing.T) {
+
+	dir := newTestStaticDir(t)
+	defer os.RemoveAll(dir)
+
+	handler := NewStatic(http.Dir(dir), StaticConfig{SPA: true, MaxAge: time.Hour})
+	jsType := mime.TypeByExtension(".js")
+
+	request := func(method, target, encoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if encoding != "" {
+			req.Header.Set("Accept-Encoding", encoding)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, testInfo := range []struct {
+		target       string
+		encoding     string
+		code         int
+		body         string
+		cache        string
+		contentType  string
+		contentCoded string
+	}{
+		{target: "/", code: http.StatusOK, body: "<html>index</html>", cache: "no-cache", contentType: "text/html; charset=utf-8"},
+		{target: "/docs/", code: http.StatusOK, body: "<html>docs</html>", cache: "no-cache", contentType: "text/html; charset=utf-8"},
+		{target: "/docs", code: http.StatusOK, body: "<html>docs</html>", cache: "no-cache", contentType: "text/html; charset=utf-8"},
+		{target: "/users/1", code: http.StatusOK, body: "<html>index</html>", cache: "no-cache", contentType: "text/html; charset=utf-8"},
+		{target: "/missing.js", code: http.StatusNotFound},
+		{target: "/../index.html", code: http.StatusOK, body: "<html>index</html>", cache: "no-cache", contentType: "text/html; charset=utf-8"},
+		{target: "/style.css", encoding: "gzip", code: http.StatusOK, body: "body{}", cache: "public, max-age=3600", contentType: "text/css; charset=utf-8"},
+		{target: "/app.js", code: http.StatusOK, body: "console.log(1)", cache: "public, max-age=3600", contentType: jsType},
+		{target: "/app.js", encoding: "gzip, deflate", code: http.StatusOK, body: "gzip", cache: "public, max-age=3600", contentType: jsType, contentCoded: "gzip"},
+		{target: "/app.js", encoding: "gzip, br", code: http.StatusOK, body: "brotli", cache: "public, max-age=3600", contentType: jsType, contentCoded: "br"},
+		{target: "/app.js", encoding: "br;q=0, gzip;q=0.5", code: http.StatusOK, body: "gzip", cache: "public, max-age=3600", contentType: jsType, contentCoded: "gzip"},
+		{target: "/images/logo.svg", encoding: "gzip", code: http.StatusNotFound},
+	} {
+		w := request(http.MethodGet, testInfo.target, testInfo.encoding)
+		require.Equal(t, testInfo.code, w.Code, testInfo.target)
+		if testInfo.code != http.StatusOK {
+			continue
+		}
+
+		require.Equal(t, testInfo.body, w.Body.String(), testInfo.target)
+		require.Equal(t, testInfo.cache, w.Header().Get("Cache-Control"), testInfo.target)
+		require.Equal(t, testInfo.contentType, w.Header().Get("Content-Type"), testInfo.target)
+		require.Equal(t, testInfo.contentCoded, w.Header().Get("Content-Encoding"), testInfo.target)
+		require.Equal(t, "Accept-Encoding", w.Header().Get("Vary"), testInfo.target)
+	}
+
+	w := request(http.MethodPost, "/", "")
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+
+	// the fallback is disabled
+	w = httptest.NewRecorder()
+	NewStatic(http.Dir(dir), StaticConfig{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	require.True(t, acceptsEncoding("gzip", "gzip"))
+	require.True(t, acceptsEncoding("deflate, GZIP;q=0.8", "gzip"))
+	require.False(t, acceptsEncoding("gzip;q=0", "gzip"))
+	require.False(t, acceptsEncoding("", "gzip"))
+	require.False(t, acceptsEncoding("br", "gzip"))
+}
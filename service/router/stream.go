@@ -0,0 +1,164 @@
+package router
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const _DefaultFlushRecords = 100
+
+// StreamConfig is the settings of the streaming responses (see NewNDJSONWriter, NewCSVWriter)
+type StreamConfig struct {
+	// FlushRecords flushes the response after the count of the records (100 by default)
+	FlushRecords int
+	// FlushInterval flushes the response on the next record after the interval since the last flush
+	// (disabled by default)
+	FlushInterval time.Duration
+}
+
+// stream buffers the records and flushes them to the client
+type stream struct {
+	ctx       context.Context
+	cfg       StreamConfig
+	w         http.ResponseWriter
+	buf       *bufio.Writer
+	count     int
+	lastFlush time.Time
+}
+
+func newStream(ctx context.Context, w http.ResponseWriter, contentType string, cfg StreamConfig) *stream {
+
+	if cfg.FlushRecords <= 0 {
+		cfg.FlushRecords = _DefaultFlushRecords
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	return &stream{
+		ctx:       ctx,
+		cfg:       cfg,
+		w:         w,
+		buf:       bufio.NewWriter(w),
+		lastFlush: time.Now(),
+	}
+}
+
+// written flushes the response if it's required by the settings
+func (s *stream) written(flush func() error) error {
+
+	s.count++
+	if s.count%s.cfg.FlushRecords == 0 ||
+		(s.cfg.FlushInterval > 0 && time.Since(s.lastFlush) >= s.cfg.FlushInterval) {
+		return flush()
+	}
+
+	return nil
+}
+
+func (s *stream) flush() error {
+
+	if err := s.buf.Flush(); err != nil {
+		return err
+	}
+
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	s.lastFlush = time.Now()
+
+	return nil
+}
+
+// NDJSONWriter streams the records as the newline delimited JSON ('application/x-ndjson')
+type NDJSONWriter struct {
+	*stream
+	enc *json.Encoder
+}
+
+// NewNDJSONWriter returns the writer of the response, the writes fail after the context is done
+// (e.g. the context of the request). The headers of the response are set before the first write.
+func NewNDJSONWriter(ctx context.Context, w http.ResponseWriter, cfg StreamConfig) *NDJSONWriter {
+
+	s := newStream(ctx, w, "application/x-ndjson", cfg)
+
+	return &NDJSONWriter{
+		stream: s,
+		enc:    json.NewEncoder(s.buf),
+	}
+}
+
+// Write writes the record encoded to JSON
+func (n *NDJSONWriter) Write(v interface{}) error {
+
+	if err := n.ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := n.enc.Encode(v); err != nil {
+		return err
+	}
+
+	return n.written(n.flush)
+}
+
+// Flush sends the buffered records to the client
+func (n *NDJSONWriter) Flush() error {
+	return n.flush()
+}
+
+// CSVWriter streams the records as CSV ('text/csv')
+type CSVWriter struct {
+	*stream
+	enc *csv.Writer
+}
+
+// NewCSVWriter returns the writer of the response with the header row (skipped if it's empty),
+// the writes fail after the context is done (e.g. the context of the request).
+// The headers of the response are set before the first write.
+func NewCSVWriter(ctx context.Context, w http.ResponseWriter, header []string, cfg StreamConfig) (*CSVWriter, error) {
+
+	s := newStream(ctx, w, "text/csv; charset=utf-8", cfg)
+
+	c := &CSVWriter{
+		stream: s,
+		enc:    csv.NewWriter(s.buf),
+	}
+
+	if len(header) > 0 {
+		if err := c.enc.Write(header); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Write writes the row
+func (c *CSVWriter) Write(record []string) error {
+
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := c.enc.Write(record); err != nil {
+		return err
+	}
+
+	return c.written(c.Flush)
+}
+
+// Flush sends the buffered rows to the client
+func (c *CSVWriter) Flush() error {
+
+	c.enc.Flush()
+	if err := c.enc.Error(); err != nil {
+		return err
+	}
+
+	return c.flush()
+}
@@ -0,0 +1,82 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNDJSONWriter(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	n := NewNDJSONWriter(ctx, w, StreamConfig{FlushRecords: 2})
+	require.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	require.NoError(t, n.Write(map[string]int{"id": 1}))
+	require.Empty(t, w.Body.String())
+	require.False(t, w.Flushed)
+
+	// the response is flushed after the count of the records
+	require.NoError(t, n.Write(map[string]int{"id": 2}))
+	require.Equal(t, "{\"id\":1}\n{\"id\":2}\n", w.Body.String())
+	require.True(t, w.Flushed)
+
+	require.NoError(t, n.Write("<tail>"))
+	require.NoError(t, n.Flush())
+	require.Equal(t, "{\"id\":1}\n{\"id\":2}\n\"\\u003ctail\\u003e\"\n", w.Body.String())
+
+	cancel()
+	require.Equal(t, context.Canceled, n.Write(map[string]int{"id": 3}))
+
+	require.Error(t, NewNDJSONWriter(context.Background(), httptest.NewRecorder(), StreamConfig{}).Write(func() {}))
+}
+
+func TestCSVWriter(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := NewCSVWriter(ctx, w, []string{"id", "name"}, StreamConfig{FlushInterval: time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+
+	// the response is flushed after the interval
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, c.Write([]string{"1", "a,b"}))
+	require.Equal(t, "id,name\n1,\"a,b\"\n", w.Body.String())
+
+	require.NoError(t, c.Write([]string{"2", "c"}))
+	require.NoError(t, c.Flush())
+	require.Equal(t, "id,name\n1,\"a,b\"\n2,c\n", w.Body.String())
+
+	cancel()
+	require.Equal(t, context.Canceled, c.Write([]string{"3", "d"}))
+}
+
+func TestStreamHandler(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := NewNDJSONWriter(req.Context(), w, StreamConfig{})
+		for i := 0; i < 250; i++ {
+			require.NoError(t, n.Write(i))
+		}
+		require.NoError(t, n.Flush())
+	}))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+	require.Equal(t, []string{"chunked"}, res.TransferEncoding)
+}
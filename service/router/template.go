@@ -0,0 +1,230 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TemplatesConfig is the settings of the templates (see NewTemplates)
+type TemplatesConfig struct {
+	// Layout is the file of the base template, the pages define its blocks (e.g. '{{define "content"}}').
+	// The pages are executed without the layout if it's empty.
+	Layout string
+	// Partials is the directory of the templates shared by the pages (all '*.html' files)
+	Partials string
+	// Reload parses the templates on each render (the development mode)
+	Reload bool
+	// Funcs are added to the default functions (see TemplateFuncs) and replace them
+	Funcs template.FuncMap
+}
+
+// Templates renders the HTML pages (html/template escapes the data by the context of the page)
+type Templates struct {
+	fs    http.FileSystem
+	cfg   TemplatesConfig
+	funcs template.FuncMap
+	cache map[string]*template.Template
+	mu    sync.RWMutex
+}
+
+// NewTemplates returns the templates of the file system (e.g. http.FS of embed.FS with Go 1.16+,
+// http.Dir or the file system of go-bindata). The layout and the partials are checked on the creation.
+func NewTemplates(fs http.FileSystem, cfg TemplatesConfig) (*Templates, error) {
+
+	funcs := TemplateFuncs()
+	for name, fn := range cfg.Funcs {
+		funcs[name] = fn
+	}
+
+	t := &Templates{
+		fs:    fs,
+		cfg:   cfg,
+		funcs: funcs,
+		cache: make(map[string]*template.Template),
+	}
+
+	if _, err := t.base(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// mustTemplates panics on the error (e.g. the templates defined in the code)
+func mustTemplates(t *Templates, err error) *Templates {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// TemplateFuncs returns the default functions of the templates:
+//
+//	json - the value encoded to JSON for the scripts ('<', '>' and '&' are escaped)
+//	join - strings.Join
+//	time - the time in the format RFC3339 (empty for the zero time)
+//	duration - the duration rounded to the milliseconds
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"json": func(v interface{}) (template.JS, error) {
+			data, err := json.Marshal(v)
+			return template.JS(data), err
+		},
+		"join": strings.Join,
+		"time": func(t time.Time) string {
+			if t.IsZero() {
+				return ""
+			}
+			return t.Format(time.RFC3339)
+		},
+		"duration": func(d time.Duration) string {
+			return d.Round(time.Millisecond).String()
+		},
+	}
+}
+
+// Render renders the page with the status code. Nothing is written on the error.
+func (t *Templates) Render(w http.ResponseWriter, status int, name string, data interface{}) error {
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, name, data); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := buf.WriteTo(w)
+
+	return err
+}
+
+// Execute writes the page
+func (t *Templates) Execute(w io.Writer, name string, data interface{}) error {
+
+	tmpl, err := t.page(name)
+	if err != nil {
+		return err
+	}
+
+	entry := path.Base(name)
+	if t.cfg.Layout != "" {
+		entry = path.Base(t.cfg.Layout)
+	}
+
+	return errors.Wrap(tmpl.ExecuteTemplate(w, entry, data), "failed to execute template "+name)
+}
+
+// page returns the page with the layout and the partials
+func (t *Templates) page(name string) (*template.Template, error) {
+
+	if !t.cfg.Reload {
+		t.mu.RLock()
+		tmpl, ok := t.cache[name]
+		t.mu.RUnlock()
+
+		if ok {
+			return tmpl, nil
+		}
+	}
+
+	base, err := t.base()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.parse(base, name); err != nil {
+		return nil, err
+	}
+
+	if !t.cfg.Reload {
+		t.mu.Lock()
+		t.cache[name] = base
+		t.mu.Unlock()
+	}
+
+	return base, nil
+}
+
+// base returns the new template with the layout and the partials
+func (t *Templates) base() (*template.Template, error) {
+
+	tmpl := template.New("").Funcs(t.funcs)
+
+	if t.cfg.Layout != "" {
+		if err := t.parse(tmpl, t.cfg.Layout); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.cfg.Partials != "" {
+		list, err := t.list(t.cfg.Partials)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range list {
+			if err := t.parse(tmpl, name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return tmpl, nil
+}
+
+func (t *Templates) parse(tmpl *template.Template, name string) error {
+
+	f, err := t.fs.Open(path.Clean("/" + name))
+	if err != nil {
+		return errors.Wrap(err, "failed to open template "+name)
+	}
+	defer f.Close()
+
+	text, err := ioutil.ReadAll(f)
+	if err != nil {
+		return errors.Wrap(err, "failed to read template "+name)
+	}
+
+	if _, err := tmpl.New(path.Base(name)).Parse(string(text)); err != nil {
+		return errors.Wrap(err, "failed to parse template "+name)
+	}
+
+	return nil
+}
+
+// list returns the sorted '*.html' files of the directory
+func (t *Templates) list(dir string) ([]string, error) {
+
+	f, err := t.fs.Open(path.Clean("/" + dir))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open partials "+dir)
+	}
+	defer f.Close()
+
+	items, err := f.Readdir(-1)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read partials "+dir)
+	}
+
+	var retval []string
+	for _, item := range items {
+		if item.Mode()&os.ModeType == 0 && path.Ext(item.Name()) == ".html" {
+			retval = append(retval, path.Join(dir, item.Name()))
+		}
+	}
+	sort.Strings(retval)
+
+	return retval, nil
+}
@@ -0,0 +1,129 @@
+package router
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTemplatesDir(t *testing.T) string {
+
+	dir, err := ioutil.TempDir("", "templates")
+	require.NoError(t, err)
+
+	for name, data := range map[string]string{
+		"layout.html":          `<title>{{.Title}}</title>{{template "header" .}}{{block "content" .}}empty{{end}}`,
+		"partials/header.html": `{{define "header"}}<h1>{{.Title | upper}}</h1>{{end}}`,
+		"partials/readme.txt":  `{{.Unknown}`,
+		"index.html":           `{{define "content"}}<p>{{.Text}}</p><script>var data = {{json .Data}};</script>{{end}}`,
+		"plain.html":           `<p>{{time .Time}} {{duration .Duration}} {{join .List ","}}</p>`,
+		"broken.html":          `{{define "content"}}{{.Missing.Field}}{{end}}`,
+	} {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		require.NoError(t, ioutil.WriteFile(filename, []byte(data), 0644))
+	}
+
+	return dir
+}
+
+func TestTemplates(t *testing.T) {
+
+	dir := newTestTemplatesDir(t)
+	defer os.RemoveAll(dir)
+
+	tmpl, err := NewTemplates(http.Dir(dir), TemplatesConfig{
+		Layout:   "layout.html",
+		Partials: "partials",
+		Funcs:    template.FuncMap{"upper": strings.ToUpper},
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	require.NoError(t, tmpl.Render(w, http.StatusCreated, "index.html", map[string]interface{}{
+		"Title": "a<b",
+		"Text":  "<script>alert(1)</script>",
+		"Data":  map[string]string{"key": "</script>"},
+	}))
+
+	// the data is escaped
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	require.Equal(t,
+		`<title>a&lt;b</title><h1>A&lt;B</h1><p>&lt;script&gt;alert(1)&lt;/script&gt;</p>`+
+			`<script>var data = {"key":"\u003c/script\u003e"};</script>`,
+		w.Body.String())
+
+	// nothing is written on the error
+	w = httptest.NewRecorder()
+	require.Error(t, tmpl.Render(w, http.StatusOK, "broken.html", map[string]interface{}{}))
+	require.Empty(t, w.Body.String())
+	require.Empty(t, w.Header().Get("Content-Type"))
+
+	require.Error(t, tmpl.Render(httptest.NewRecorder(), http.StatusOK, "unknown.html", nil))
+
+	// the page without the layout
+	tmpl, err = NewTemplates(http.Dir(dir), TemplatesConfig{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Execute(&buf, "plain.html", map[string]interface{}{
+		"Time":     time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		"Duration": 1500 * time.Microsecond,
+		"List":     []string{"a", "b"},
+	}))
+	require.Equal(t, "<p>2020-01-02T03:04:05Z 2ms a,b</p>", buf.String())
+
+	// the layout is checked on the creation
+	_, err = NewTemplates(http.Dir(dir), TemplatesConfig{Layout: "missing.html"})
+	require.Error(t, err)
+}
+
+func TestTemplatesReload(t *testing.T) {
+
+	dir := newTestTemplatesDir(t)
+	defer os.RemoveAll(dir)
+
+	render := func(tmpl *Templates) string {
+		var buf bytes.Buffer
+		require.NoError(t, tmpl.Execute(&buf, "page.html", nil))
+		return buf.String()
+	}
+
+	filename := filepath.Join(dir, "page.html")
+	require.NoError(t, ioutil.WriteFile(filename, []byte("first"), 0644))
+
+	cached, err := NewTemplates(http.Dir(dir), TemplatesConfig{})
+	require.NoError(t, err)
+
+	reloaded, err := NewTemplates(http.Dir(dir), TemplatesConfig{Reload: true})
+	require.NoError(t, err)
+
+	require.Equal(t, "first", render(cached))
+	require.Equal(t, "first", render(reloaded))
+
+	require.NoError(t, ioutil.WriteFile(filename, []byte("second"), 0644))
+	require.Equal(t, "first", render(cached))
+	require.Equal(t, "second", render(reloaded))
+}
+
+func TestDashboard(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	dashboard(w, httptest.NewRequest(http.MethodGet, "/debug/consumers", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	require.Contains(t, w.Body.String(), `<title>Consumers</title>`)
+	require.Contains(t, w.Body.String(), `<option value="5000" selected>5s</option>`)
+	require.Contains(t, w.Body.String(), `fetchJSON("../consumers/")`)
+}
@@ -0,0 +1,249 @@
+package router
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	_DefaultMaxUploadPartSize = 32 << 20
+	_DefaultMaxUploadParts    = 10
+)
+
+var (
+	// ErrUploadTooLarge is returned if the file or the request exceeds the size limit (see UploadConfig)
+	ErrUploadTooLarge = errors.New("upload too large")
+	// ErrUploadTooManyParts is returned if the count of the files exceeds the limit (see UploadConfig.MaxParts)
+	ErrUploadTooManyParts = errors.New("too many upload parts")
+)
+
+// IUploadStorage saves the streams of the uploaded files by the keys (e.g. an adapter of S3).
+// The stream fails on the exceeded limits and on the timeout of the upload.
+type IUploadStorage interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+}
+
+// UploadDir saves the uploaded files to the directory
+type UploadDir string
+
+// Put writes the stream to the file (the key is a relative path, it can't leave the directory)
+func (d UploadDir) Put(_ context.Context, key string, r io.Reader) error {
+
+	name := filepath.Join(string(d), filepath.FromSlash(path.Clean("/"+key)))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return errors.Wrap(err, "failed to create directory")
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return errors.Wrap(err, "failed to create file")
+	}
+
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(name)
+		return errors.Wrap(err, "failed to write file")
+	}
+
+	return errors.Wrap(f.Close(), "failed to close file")
+}
+
+// FuncUploadProgress reports the size of the file read by the storage (see UploadedPart.Size)
+type FuncUploadProgress func(part *UploadedPart)
+
+// UploadConfig is the settings of the uploads (see Upload)
+type UploadConfig struct {
+	// MaxPartSize is the size limit of a file (32MB by default)
+	MaxPartSize int64
+	// MaxSize is the size limit of the request body (unlimited by default)
+	MaxSize int64
+	// MaxParts is the count limit of the files (10 by default)
+	MaxParts int
+	// Timeout is the time limit of the upload (unlimited by default)
+	Timeout time.Duration
+	// Key returns the key of the file in the storage (the base name of the file by default)
+	Key func(part *multipart.Part) string
+	// OnProgress is called after every read of the file
+	OnProgress FuncUploadProgress
+}
+
+// An UploadedPart is the file saved to the storage
+type UploadedPart struct {
+	Field       string `json:"field"`
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType"`
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	// SHA256 is the hex encoded checksum of the file
+	SHA256 string `json:"sha256"`
+}
+
+// Upload streams the files of the multipart request to the storage without buffering
+// (the form fields without the file names are skipped). The saved files are returned with the error.
+func Upload(req *http.Request, storage IUploadStorage, cfg UploadConfig) ([]UploadedPart, error) {
+
+	if cfg.MaxPartSize <= 0 {
+		cfg.MaxPartSize = _DefaultMaxUploadPartSize
+	}
+
+	if cfg.MaxParts <= 0 {
+		cfg.MaxParts = _DefaultMaxUploadParts
+	}
+
+	ctx := req.Context()
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	if cfg.MaxSize > 0 {
+		req.Body = &uploadReader{ctx: ctx, r: req.Body, limit: cfg.MaxSize}
+	}
+
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid multipart request")
+	}
+
+	var retval []UploadedPart
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return retval, nil
+		}
+		if err != nil {
+			return retval, uploadError(ctx, err, "failed to read part")
+		}
+
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		if len(retval) == cfg.MaxParts {
+			part.Close()
+			return retval, ErrUploadTooManyParts
+		}
+
+		item, err := uploadPart(ctx, storage, part, &cfg)
+		part.Close()
+		if err != nil {
+			return retval, err
+		}
+
+		retval = append(retval, *item)
+	}
+}
+
+func uploadPart(ctx context.Context, storage IUploadStorage, part *multipart.Part, cfg *UploadConfig) (*UploadedPart, error) {
+
+	key := path.Base(path.Clean("/" + part.FileName()))
+	if cfg.Key != nil {
+		key = cfg.Key(part)
+	}
+
+	item := &UploadedPart{
+		Field:       part.FormName(),
+		FileName:    part.FileName(),
+		ContentType: part.Header.Get("Content-Type"),
+		Key:         key,
+	}
+
+	r := &uploadReader{
+		ctx:      ctx,
+		r:        part,
+		limit:    cfg.MaxPartSize,
+		hash:     sha256.New(),
+		part:     item,
+		progress: cfg.OnProgress,
+	}
+
+	if err := storage.Put(ctx, key, r); err != nil {
+		return nil, uploadError(ctx, err, "failed to save "+key)
+	}
+
+	// the storage must read the whole file
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return nil, uploadError(ctx, err, "failed to read "+key)
+	}
+
+	item.SHA256 = hex.EncodeToString(r.hash.Sum(nil))
+
+	return item, nil
+}
+
+// uploadError returns the sentinel errors of the limits without the wrapping
+func uploadError(ctx context.Context, err error, msg string) error {
+
+	if errors.Is(err, ErrUploadTooLarge) {
+		return ErrUploadTooLarge
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	return errors.Wrap(err, msg)
+}
+
+// uploadReader limits the size of the stream, calculates the checksum and reports the progress
+type uploadReader struct {
+	ctx      context.Context
+	r        io.Reader
+	limit    int64
+	read     int64
+	hash     hash.Hash
+	part     *UploadedPart
+	progress FuncUploadProgress
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+
+	if err := u.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	// one byte more than the limit is read to detect the exceeding
+	if max := u.limit - u.read + 1; int64(len(p)) > max {
+		p = p[:max]
+	}
+
+	n, err := u.r.Read(p)
+	u.read += int64(n)
+	if u.read > u.limit {
+		return 0, ErrUploadTooLarge
+	}
+
+	if n > 0 && u.hash != nil {
+		_, _ = u.hash.Write(p[:n])
+	}
+
+	if u.part != nil {
+		u.part.Size = u.read
+		if n > 0 && u.progress != nil {
+			u.progress(u.part)
+		}
+	}
+
+	return n, err
+}
+
+func (u *uploadReader) Close() error {
+	if c, ok := u.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUploadRequest(t *testing.T, fields map[string]string, files ...[2]string) *http.Request {
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	for name, value := range fields {
+		require.NoError(t, w.WriteField(name, value))
+	}
+
+	for _, f := range files {
+		part, err := w.CreateFormFile("file", f[0])
+		require.NoError(t, err)
+		_, err = part.Write([]byte(f[1]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	return req
+}
+
+func TestUpload(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "upload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var progress []int64
+	req := newTestUploadRequest(t, map[string]string{"comment": "skipped"},
+		[2]string{"a.txt", "first file"},
+		[2]string{"../../b.txt", strings.Repeat("b", 10000)})
+
+	parts, err := Upload(req, UploadDir(dir), UploadConfig{
+		OnProgress: func(part *UploadedPart) {
+			if part.Key == "b.txt" {
+				progress = append(progress, part.Size)
+			}
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+
+	sum := sha256.Sum256([]byte("first file"))
+	require.Equal(t, UploadedPart{
+		Field:       "file",
+		FileName:    "a.txt",
+		ContentType: "application/octet-stream",
+		Key:         "a.txt",
+		Size:        10,
+		SHA256:      hex.EncodeToString(sum[:]),
+	}, parts[0])
+
+	// the file can't leave the directory
+	require.Equal(t, "b.txt", parts[1].Key)
+	require.Equal(t, int64(10000), parts[1].Size)
+	require.NotEmpty(t, progress)
+	require.Equal(t, int64(10000), progress[len(progress)-1])
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "b.txt"))
+	require.NoError(t, err)
+	require.Len(t, data, 10000)
+
+	_, err = Upload(httptest.NewRequest(http.MethodPost, "/upload", nil), UploadDir(dir), UploadConfig{})
+	require.Error(t, err)
+}
+
+func TestUploadLimits(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "upload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the file is too large: the saved files are returned
+	req := newTestUploadRequest(t, nil, [2]string{"a.txt", "small"}, [2]string{"b.txt", "large file"})
+	parts, err := Upload(req, UploadDir(dir), UploadConfig{MaxPartSize: 5})
+	require.Equal(t, ErrUploadTooLarge, err)
+	require.Len(t, parts, 1)
+	_, err = os.Stat(filepath.Join(dir, "b.txt"))
+	require.True(t, os.IsNotExist(err))
+
+	// the request is too large
+	req = newTestUploadRequest(t, nil, [2]string{"a.txt", strings.Repeat("a", 1000)})
+	_, err = Upload(req, UploadDir(dir), UploadConfig{MaxSize: 100})
+	require.Equal(t, ErrUploadTooLarge, err)
+
+	// too many files
+	req = newTestUploadRequest(t, nil, [2]string{"a.txt", "a"}, [2]string{"b.txt", "b"})
+	parts, err = Upload(req, UploadDir(dir), UploadConfig{MaxParts: 1})
+	require.Equal(t, ErrUploadTooManyParts, err)
+	require.Len(t, parts, 1)
+
+	// the timeout
+	req = newTestUploadRequest(t, nil, [2]string{"a.txt", "a"})
+	_, err = Upload(req, storageFunc(func(ctx context.Context, _ string, r io.Reader) error {
+		time.Sleep(20 * time.Millisecond)
+		_, err := ioutil.ReadAll(r)
+		return err
+	}), UploadConfig{Timeout: 10 * time.Millisecond})
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+type storageFunc func(ctx context.Context, key string, r io.Reader) error
+
+func (fn storageFunc) Put(ctx context.Context, key string, r io.Reader) error {
+	return fn(ctx, key, r)
+}
@@ -13,6 +13,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/dialogs/dialog-go-lib/reporter"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
@@ -20,6 +21,7 @@ import (
 // service base object
 type service struct {
 	addr      string
+	reporter  reporter.IReporter
 	mu        sync.RWMutex
 	ctx       context.Context
 	ctxCancel func()
@@ -50,6 +52,14 @@ func (s *service) SetAddr(val string) {
 	s.mu.Unlock()
 }
 
+// SetReporter sets the reporter of the fatal errors of the service
+func (s *service) SetReporter(r reporter.IReporter) {
+
+	s.mu.Lock()
+	s.reporter = r
+	s.mu.Unlock()
+}
+
 // GetAddr returns listener address
 func (s *service) GetAddr() (addr string) {
 
@@ -101,7 +111,18 @@ func (s *service) serve(l *zap.Logger, name, addr string, run, stop func() error
 		}
 	}()
 
-	return <-retval
+	err := <-retval
+	if err != nil && err != http.ErrServerClosed {
+		s.mu.RLock()
+		r := s.reporter
+		s.mu.RUnlock()
+
+		if r != nil {
+			r.Report(context.Background(), err, map[string]string{"service": name, "addr": addr})
+		}
+	}
+
+	return err
 }
 
 // PingConn ping tcp connection
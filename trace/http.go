@@ -0,0 +1,95 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Middleware creates the server span of the request.
+// The parent is read from the traceparent header and the consume span
+// from the tracelink header is added as a link (see Inject).
+// The span is named by the method and the route set by the handler (see SetRoute and MuxRoutes),
+// the path isn't used (it can have the identifiers).
+func Middleware(t *Tracer) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+			ctx := Extract(req.Context(), req.Header)
+
+			var links []Link
+			sc, ok := ParseTraceparent(req.Header.Get(HeaderTracelink))
+			if ok && sc.SpanID != SpanContextFromContext(ctx).SpanID {
+				links = append(links, Link{
+					SpanContext: sc,
+					Attributes:  map[string]string{"link.type": "consume"},
+				})
+			}
+
+			ctx, span := t.Start(ctx, req.Method, KindServer, links...)
+			defer span.End()
+
+			span.SetAttribute("http.method", req.Method)
+			span.SetAttribute("http.target", req.URL.RequestURI())
+			span.SetAttribute("http.host", req.Host)
+
+			rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, req.WithContext(ctx))
+
+			span.SetAttribute("http.status_code", strconv.Itoa(rw.status))
+			if rw.status >= http.StatusInternalServerError {
+				span.SetError(errorStatus(rw.status))
+			}
+		})
+	}
+}
+
+// SetRoute names the server span of the request by the route pattern (e.g. "/users/{id}")
+func SetRoute(ctx context.Context, route string) {
+
+	span := SpanFromContext(ctx)
+	if span == nil || span.kind != KindServer {
+		return
+	}
+
+	span.mu.Lock()
+	span.name = span.attributes["http.method"] + " " + route
+	span.attributes["http.route"] = route
+	span.mu.Unlock()
+}
+
+// MuxRoutes returns the handler which sets the route of the request by the pattern of the mux (see SetRoute)
+func MuxRoutes(mux *http.ServeMux) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, pattern := mux.Handler(req); pattern != "" {
+			SetRoute(req.Context(), pattern)
+		}
+
+		mux.ServeHTTP(w, req)
+	})
+}
+
+type errorStatus int
+
+func (e errorStatus) Error() string {
+	return http.StatusText(int(e))
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush sends the buffered data (http.Flusher implementation, e.g. for the streams)
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
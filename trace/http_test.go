@@ -0,0 +1,81 @@
+package trace
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+
+	exporter := &testExporter{}
+	tracer := NewTracer(Config{Exporter: exporter})
+
+	var spanCtx SpanContext
+	handler := Middleware(tracer)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		spanCtx = SpanContextFromContext(req.Context())
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+
+	parent := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{1}, Sampled: true}
+	consume := SpanContext{TraceID: TraceID{2}, SpanID: SpanID{2}, Sampled: true}
+
+	req := httptest.NewRequest(http.MethodGet, "/path?q=1", nil)
+	req.Header.Set(HeaderTraceparent, FormatTraceparent(parent))
+	req.Header.Set(HeaderTracelink, FormatTraceparent(consume))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadGateway, w.Code)
+
+	require.NoError(t, tracer.Close())
+
+	spans := exporter.get()
+	require.Len(t, spans, 1)
+
+	span := spans[0]
+	require.Equal(t, spanCtx, span.SpanContext())
+	// the path isn't used in the name without the route
+	require.Equal(t, "GET", span.name)
+	require.Equal(t, KindServer, span.kind)
+	require.Equal(t, parent.TraceID, span.sc.TraceID)
+	require.Equal(t, parent.SpanID, span.parent)
+	require.Len(t, span.links, 1)
+	require.Equal(t, consume.SpanID, span.links[0].SpanContext.SpanID)
+	require.Equal(t, "502", span.attributes["http.status_code"])
+	require.Equal(t, "/path?q=1", span.attributes["http.target"])
+	require.Error(t, span.err)
+}
+
+func TestMiddlewareRoute(t *testing.T) {
+
+	exporter := &testExporter{}
+	tracer := NewTracer(Config{Exporter: exporter})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/", func(w http.ResponseWriter, req *http.Request) {
+		// the writer supports the streams
+		_, ok := w.(http.Flusher)
+		require.True(t, ok)
+	})
+	handler := Middleware(tracer)(MuxRoutes(mux))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	// the route of another router
+	handler = Middleware(tracer)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		SetRoute(req.Context(), "/items/{id}")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/items/2", nil))
+
+	require.NoError(t, tracer.Close())
+
+	spans := exporter.get()
+	require.Len(t, spans, 2)
+	require.Equal(t, "GET /users/", spans[0].name)
+	require.Equal(t, "/users/", spans[0].attributes["http.route"])
+	require.Equal(t, "POST /items/{id}", spans[1].name)
+	require.Equal(t, "/items/{id}", spans[1].attributes["http.route"])
+}
@@ -0,0 +1,205 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	_OTLPTracesPath  = "/v1/traces"
+	_OTLPStatusError = 2
+	_ScopeName       = "github.com/dialogs/dialog-go-lib/trace"
+)
+
+// An OTLPExporter sends the spans to the collector by the protocol OTLP/HTTP (JSON encoding)
+type OTLPExporter struct {
+	url         string
+	serviceName string
+	headers     map[string]string
+	client      *http.Client
+}
+
+// NewOTLPExporter creates the exporter.
+// The endpoint is an address of the collector (e.g. http://localhost:4318).
+func NewOTLPExporter(endpoint, serviceName string, headers map[string]string, client *http.Client) *OTLPExporter {
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &OTLPExporter{
+		url:         strings.TrimRight(endpoint, "/") + _OTLPTracesPath,
+		serviceName: serviceName,
+		headers:     headers,
+		client:      client,
+	}
+}
+
+// Export sends the spans (IExporter implementation)
+func (e *OTLPExporter) Export(ctx context.Context, spans []*Span) error {
+
+	body, err := json.Marshal(newOTLPRequest(e.serviceName, spans))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode spans")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "failed to send spans")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("failed to send spans: %s: %s", resp.Status, msg)
+	}
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Links             []otlpLink      `json:"links,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpLink struct {
+	TraceID    string          `json:"traceId"`
+	SpanID     string          `json:"spanId"`
+	Attributes []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func newOTLPRequest(serviceName string, spans []*Span) *otlpRequest {
+
+	list := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		list = append(list, newOTLPSpan(s))
+	}
+
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: newOTLPAttributes(map[string]string{"service.name": serviceName}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: _ScopeName},
+				Spans: list,
+			}},
+		}},
+	}
+}
+
+func newOTLPSpan(s *Span) otlpSpan {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	retval := otlpSpan{
+		TraceID:           s.sc.TraceID.String(),
+		SpanID:            s.sc.SpanID.String(),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Attributes:        newOTLPAttributes(s.attributes),
+	}
+
+	if s.parent.IsValid() {
+		retval.ParentSpanID = s.parent.String()
+	}
+
+	for _, l := range s.links {
+		retval.Links = append(retval.Links, otlpLink{
+			TraceID:    l.SpanContext.TraceID.String(),
+			SpanID:     l.SpanContext.SpanID.String(),
+			Attributes: newOTLPAttributes(l.Attributes),
+		})
+	}
+
+	if s.err != nil {
+		retval.Status = &otlpStatus{Code: _OTLPStatusError, Message: s.err.Error()}
+	}
+
+	return retval
+}
+
+func newOTLPAttributes(in map[string]string) []otlpAttribute {
+
+	if len(in) == 0 {
+		return nil
+	}
+
+	retval := make([]otlpAttribute, 0, len(in))
+	for k, v := range in {
+		retval = append(retval, otlpAttribute{Key: k, Value: otlpValue{StringValue: v}})
+	}
+
+	sort.Slice(retval, func(i, j int) bool {
+		return retval[i].Key < retval[j].Key
+	})
+
+	return retval
+}
@@ -0,0 +1,76 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTLPExporter(t *testing.T) {
+
+	var (
+		received otlpRequest
+		header   http.Header
+		path     string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path = req.URL.Path
+		header = req.Header
+		if err := json.NewDecoder(req.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	tracer := NewTracer(Config{ServiceName: "test"})
+	defer tracer.Close()
+
+	link := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{2}, Sampled: true}
+
+	ctx, parent := tracer.Start(context.Background(), "parent", KindServer)
+	_, span := tracer.Start(ctx, "span", KindConsumer, Link{SpanContext: link})
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("failed"))
+	span.End()
+
+	exporter := NewOTLPExporter(srv.URL+"/", "test", map[string]string{"Authorization": "token"}, nil)
+	require.NoError(t, exporter.Export(context.Background(), []*Span{span}))
+
+	require.Equal(t, _OTLPTracesPath, path)
+	require.Equal(t, "token", header.Get("Authorization"))
+	require.Equal(t, "application/json", header.Get("Content-Type"))
+
+	require.Len(t, received.ResourceSpans, 1)
+	require.Equal(t,
+		[]otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: "test"}}},
+		received.ResourceSpans[0].Resource.Attributes)
+
+	require.Len(t, received.ResourceSpans[0].ScopeSpans, 1)
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 1)
+	require.Equal(t, "span", spans[0].Name)
+	require.Equal(t, KindConsumer, spans[0].Kind)
+	require.Equal(t, span.SpanContext().TraceID.String(), spans[0].TraceID)
+	require.Equal(t, span.SpanContext().SpanID.String(), spans[0].SpanID)
+	require.Equal(t, parent.SpanContext().SpanID.String(), spans[0].ParentSpanID)
+	require.Equal(t, []otlpAttribute{{Key: "key", Value: otlpValue{StringValue: "value"}}}, spans[0].Attributes)
+	require.Equal(t, []otlpLink{{TraceID: link.TraceID.String(), SpanID: link.SpanID.String()}}, spans[0].Links)
+	require.Equal(t, &otlpStatus{Code: _OTLPStatusError, Message: "failed"}, spans[0].Status)
+}
+
+func TestOTLPExporterError(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	exporter := NewOTLPExporter(srv.URL, "test", nil, nil)
+	require.Error(t, exporter.Export(context.Background(), nil))
+}
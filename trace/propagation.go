@@ -0,0 +1,114 @@
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// HeaderTraceparent is the W3C trace context header
+	HeaderTraceparent = "traceparent"
+	// HeaderTracelink is the context of the consume span of the request
+	// originated from a message processing (the format of traceparent)
+	HeaderTracelink = "tracelink"
+)
+
+const (
+	_TraceparentVersion = "00"
+	_FlagSampled        = "01"
+	_FlagNotSampled     = "00"
+)
+
+// ICarrier stores the propagated values (e.g. http.Header or the message headers)
+type ICarrier interface {
+	Get(key string) string
+	Set(key, val string)
+}
+
+// IPropagator writes the span context to the carrier and reads it from the carrier
+type IPropagator interface {
+	Inject(ctx context.Context, c ICarrier)
+	Extract(ctx context.Context, c ICarrier) context.Context
+}
+
+// TraceContext is the propagator of the W3C trace context (see Inject and Extract)
+type TraceContext struct{}
+
+func (TraceContext) Inject(ctx context.Context, c ICarrier) {
+	Inject(ctx, c)
+}
+
+func (TraceContext) Extract(ctx context.Context, c ICarrier) context.Context {
+	return Extract(ctx, c)
+}
+
+// Inject writes the context of the current span to the headers
+func Inject(ctx context.Context, h ICarrier) {
+
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		h.Set(HeaderTraceparent, FormatTraceparent(sc))
+	}
+
+	if sc := ConsumeSpanContext(ctx); sc.IsValid() {
+		h.Set(HeaderTracelink, FormatTraceparent(sc))
+	}
+}
+
+// Extract returns the context with the remote span context of the headers
+func Extract(ctx context.Context, h ICarrier) context.Context {
+
+	sc, ok := ParseTraceparent(h.Get(HeaderTraceparent))
+	if !ok {
+		return ctx
+	}
+
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// FormatTraceparent returns the value of the traceparent header
+func FormatTraceparent(sc SpanContext) string {
+
+	flags := _FlagNotSampled
+	if sc.Sampled {
+		flags = _FlagSampled
+	}
+
+	return _TraceparentVersion + "-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent parses the value of the traceparent header
+func ParseTraceparent(val string) (retval SpanContext, ok bool) {
+
+	parts := strings.Split(strings.TrimSpace(val), "-")
+	// the future versions can have additional fields
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		parts[0] == _TraceparentVersion && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+
+	if !decodeHex(parts[1], retval.TraceID[:]) ||
+		!decodeHex(parts[2], retval.SpanID[:]) ||
+		!retval.IsValid() {
+		return SpanContext{}, false
+	}
+
+	var flags [1]byte
+	if !decodeHex(parts[3], flags[:]) {
+		return SpanContext{}, false
+	}
+
+	retval.Sampled = flags[0]&1 == 1
+	retval.Remote = true
+
+	return retval, true
+}
+
+func decodeHex(src string, dst []byte) bool {
+	if len(src) != hex.EncodedLen(len(dst)) {
+		return false
+	}
+
+	_, err := hex.Decode(dst, []byte(src))
+	return err == nil
+}
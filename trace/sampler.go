@@ -0,0 +1,75 @@
+package trace
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+)
+
+// ISampler decides that the new span is recorded and exported
+type ISampler interface {
+	ShouldSample(parent SpanContext, traceID TraceID, name string) bool
+}
+
+// FuncSampler is an adapter of the function to ISampler
+type FuncSampler func(parent SpanContext, traceID TraceID, name string) bool
+
+// ShouldSample calls the function
+func (fn FuncSampler) ShouldSample(parent SpanContext, traceID TraceID, name string) bool {
+	return fn(parent, traceID, name)
+}
+
+// AlwaysSample samples every span
+func AlwaysSample() ISampler {
+	return FuncSampler(func(SpanContext, TraceID, string) bool { return true })
+}
+
+// NeverSample samples nothing
+func NeverSample() ISampler {
+	return FuncSampler(func(SpanContext, TraceID, string) bool { return false })
+}
+
+// TraceIDRatio samples the fraction of the traces (0..1).
+// The decision depends on the trace identifier only.
+func TraceIDRatio(fraction float64) ISampler {
+
+	if fraction >= 1 {
+		return AlwaysSample()
+	}
+	if fraction <= 0 {
+		return NeverSample()
+	}
+
+	bound := uint64(fraction * math.MaxInt64)
+
+	return FuncSampler(func(_ SpanContext, traceID TraceID, _ string) bool {
+		return binary.BigEndian.Uint64(traceID[8:16])>>1 < bound
+	})
+}
+
+// ParentBased follows the decision of the parent span and
+// uses the root sampler for the spans without a parent
+func ParentBased(root ISampler) ISampler {
+
+	return FuncSampler(func(parent SpanContext, traceID TraceID, name string) bool {
+		if parent.IsValid() {
+			return parent.Sampled
+		}
+
+		return root.ShouldSample(parent, traceID, name)
+	})
+}
+
+type samplingKey struct{}
+
+// ContextWithSampling returns the context forcing the sampling decision of the spans started with it
+// (e.g. the decision by the attributes of the request or the message instead of the sampler)
+func ContextWithSampling(ctx context.Context, sampled bool) context.Context {
+	return context.WithValue(ctx, samplingKey{}, sampled)
+}
+
+// SamplingFromContext returns the forced sampling decision (see ContextWithSampling)
+func SamplingFromContext(ctx context.Context) (sampled, ok bool) {
+	sampled, ok = ctx.Value(samplingKey{}).(bool)
+	return
+}
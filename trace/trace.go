@@ -0,0 +1,205 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// A TraceID is a W3C trace identifier
+type TraceID [16]byte
+
+// IsValid checks that the identifier is not zero
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// A SpanID is a W3C span identifier
+type SpanID [8]byte
+
+// IsValid checks that the identifier is not zero
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// A SpanContext identifies the span
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	// Remote is set for the span context extracted from a request
+	Remote bool
+}
+
+// IsValid checks the identifiers
+func (s SpanContext) IsValid() bool {
+	return s.TraceID.IsValid() && s.SpanID.IsValid()
+}
+
+// A SpanKind is a kind of the span (the values of OTLP)
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+	KindProducer SpanKind = 4
+	KindConsumer SpanKind = 5
+)
+
+// A Link is a reference to the span of another trace or
+// to the span which isn't a parent (e.g. a message processing)
+type Link struct {
+	SpanContext SpanContext
+	Attributes  map[string]string
+}
+
+// A Span is an operation of the trace
+type Span struct {
+	tracer     *Tracer
+	name       string
+	kind       SpanKind
+	sc         SpanContext
+	parent     SpanID
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	links      []Link
+	err        error
+	mu         sync.Mutex
+}
+
+// SpanContext returns the context of the span
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+
+	return s.sc
+}
+
+// SetName sets the name of the span (e.g. the route of the request, see SetRoute)
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetAttribute sets the attribute of the span
+func (s *Span) SetAttribute(key, val string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.attributes[key] = val
+	s.mu.Unlock()
+}
+
+// AddLink adds the link to the span
+func (s *Span) AddLink(l Link) {
+	if s == nil || !l.SpanContext.IsValid() {
+		return
+	}
+
+	s.mu.Lock()
+	s.links = append(s.links, l)
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// End completes the span. The sampled span is sent to the exporter.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if s.sc.Sampled {
+		s.tracer.enqueue(s)
+	}
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+type consumeKey struct{}
+
+// ContextWithSpan returns the context with the span
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	ctx = context.WithValue(ctx, spanKey{}, s)
+	if s.kind == KindConsumer {
+		ctx = context.WithValue(ctx, consumeKey{}, s.sc)
+	}
+
+	return ctx
+}
+
+// SpanFromContext returns the span of the context or nil
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SpanContextFromContext returns the context of the current span or
+// the remote span context (see Extract)
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.sc
+	}
+
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// ContextWithRemoteSpanContext returns the context with the span context received from another process
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// ConsumeSpanContext returns the context of the nearest consume span (see KindConsumer)
+func ConsumeSpanContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(consumeKey{}).(SpanContext)
+	return sc
+}
+
+func newTraceID() (retval TraceID) {
+	_, _ = rand.Read(retval[:])
+	return
+}
+
+func newSpanID() (retval SpanID) {
+	_, _ = rand.Read(retval[:])
+	return
+}
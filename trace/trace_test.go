@@ -0,0 +1,220 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testExporter struct {
+	spans []*Span
+	mu    sync.Mutex
+}
+
+func (e *testExporter) Export(_ context.Context, spans []*Span) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, spans...)
+	e.mu.Unlock()
+	return nil
+}
+
+func (e *testExporter) get() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Span{}, e.spans...)
+}
+
+func TestTracer(t *testing.T) {
+
+	exporter := &testExporter{}
+	tracer := NewTracer(Config{
+		ServiceName:   "test",
+		Exporter:      exporter,
+		FlushInterval: time.Hour,
+	})
+
+	ctx, root := tracer.Start(context.Background(), "root", KindConsumer)
+	require.True(t, root.SpanContext().IsValid())
+	require.True(t, root.SpanContext().Sampled)
+	require.Equal(t, root.SpanContext(), ConsumeSpanContext(ctx))
+
+	_, child := tracer.Start(ctx, "child", KindClient)
+	require.Equal(t, root.SpanContext().TraceID, child.SpanContext().TraceID)
+	require.NotEqual(t, root.SpanContext().SpanID, child.SpanContext().SpanID)
+	require.Equal(t, root.SpanContext().SpanID, child.parent)
+
+	child.SetError(errors.New("failed"))
+	child.End()
+	child.End()
+	root.End()
+
+	require.NoError(t, tracer.Close())
+
+	spans := exporter.get()
+	require.Len(t, spans, 2)
+	require.Equal(t, "child", spans[0].name)
+	require.Equal(t, "root", spans[1].name)
+}
+
+func TestTracerNotSampled(t *testing.T) {
+
+	exporter := &testExporter{}
+	tracer := NewTracer(Config{
+		Sampler:  NeverSample(),
+		Exporter: exporter,
+	})
+
+	_, span := tracer.Start(context.Background(), "root", KindInternal)
+	require.False(t, span.SpanContext().Sampled)
+	span.End()
+
+	require.NoError(t, tracer.Close())
+	require.Empty(t, exporter.get())
+}
+
+type blockingExporter struct {
+	testExporter
+	calls   int32
+	release chan struct{}
+}
+
+func (e *blockingExporter) Export(ctx context.Context, spans []*Span) error {
+	atomic.AddInt32(&e.calls, 1)
+	<-e.release
+	return e.testExporter.Export(ctx, spans)
+}
+
+func TestTracerQueueFull(t *testing.T) {
+
+	exporter := &blockingExporter{release: make(chan struct{})}
+	tracer := NewTracer(Config{
+		Exporter:      exporter,
+		BatchSize:     2,
+		MaxQueueSize:  2,
+		FlushInterval: time.Hour,
+	})
+
+	end := func() {
+		_, span := tracer.Start(context.Background(), "span", KindInternal)
+		span.End()
+	}
+
+	// the first batch is exported, the second one is queued
+	end()
+	end()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&exporter.calls) == 1 }, time.Second, time.Millisecond)
+	for i := 0; i < 5; i++ {
+		end()
+	}
+
+	require.Equal(t, uint64(3), tracer.Dropped())
+
+	close(exporter.release)
+	require.NoError(t, tracer.Close())
+	require.Len(t, exporter.get(), 4)
+}
+
+func TestTracerForcedSampling(t *testing.T) {
+
+	tracer := NewTracer(Config{Sampler: NeverSample()})
+	defer tracer.Close()
+
+	ctx := ContextWithSampling(context.Background(), true)
+	ctx, root := tracer.Start(ctx, "root", KindConsumer)
+	require.True(t, root.SpanContext().Sampled)
+
+	_, child := tracer.Start(ctx, "child", KindClient)
+	require.True(t, child.SpanContext().Sampled)
+
+	sampled, ok := SamplingFromContext(context.Background())
+	require.False(t, ok)
+	require.False(t, sampled)
+
+	// the decision overrides the parent too
+	tracer = NewTracer(Config{})
+	defer tracer.Close()
+
+	parent := ContextWithRemoteSpanContext(context.Background(), SpanContext{TraceID: TraceID{1}, SpanID: SpanID{1}, Sampled: true})
+	_, span := tracer.Start(ContextWithSampling(parent, false), "root", KindConsumer)
+	require.False(t, span.SpanContext().Sampled)
+}
+
+func TestSampler(t *testing.T) {
+
+	traceID := TraceID{1}
+	sampled := SpanContext{TraceID: traceID, SpanID: SpanID{1}, Sampled: true}
+	notSampled := SpanContext{TraceID: traceID, SpanID: SpanID{1}}
+
+	require.True(t, AlwaysSample().ShouldSample(SpanContext{}, traceID, ""))
+	require.False(t, NeverSample().ShouldSample(SpanContext{}, traceID, ""))
+
+	require.True(t, TraceIDRatio(1).ShouldSample(SpanContext{}, traceID, ""))
+	require.False(t, TraceIDRatio(0).ShouldSample(SpanContext{}, traceID, ""))
+
+	count := 0
+	sampler := TraceIDRatio(0.5)
+	for i := 0; i < 1000; i++ {
+		if sampler.ShouldSample(SpanContext{}, newTraceID(), "") {
+			count++
+		}
+	}
+	require.InDelta(t, 500, count, 100)
+
+	parentBased := ParentBased(NeverSample())
+	require.True(t, parentBased.ShouldSample(sampled, traceID, ""))
+	require.False(t, parentBased.ShouldSample(notSampled, traceID, ""))
+	require.False(t, parentBased.ShouldSample(SpanContext{}, traceID, ""))
+}
+
+func TestPropagation(t *testing.T) {
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, ok := ParseTraceparent(traceparent)
+	require.True(t, ok)
+	require.True(t, sc.Sampled)
+	require.True(t, sc.Remote)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	require.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	require.Equal(t, traceparent, FormatTraceparent(sc))
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-00",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	} {
+		_, ok := ParseTraceparent(invalid)
+		require.False(t, ok, invalid)
+	}
+
+	// the future version
+	_, ok = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-00")
+	require.True(t, ok)
+
+	h := http.Header{}
+	h.Set(HeaderTraceparent, traceparent)
+	ctx := Extract(context.Background(), h)
+	require.Equal(t, sc, SpanContextFromContext(ctx))
+
+	tracer := NewTracer(Config{})
+	defer tracer.Close()
+
+	ctx, consume := tracer.Start(ctx, "consume", KindConsumer)
+	ctx, client := tracer.Start(ctx, "client", KindClient)
+
+	h = http.Header{}
+	Inject(ctx, h)
+	require.Equal(t, FormatTraceparent(client.SpanContext()), h.Get(HeaderTraceparent))
+	require.Equal(t, FormatTraceparent(consume.SpanContext()), h.Get(HeaderTracelink))
+}
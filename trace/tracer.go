@@ -0,0 +1,211 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// IExporter sends the completed spans (e.g. OTLPExporter)
+type IExporter interface {
+	Export(ctx context.Context, spans []*Span) error
+}
+
+// Config of the tracer
+type Config struct {
+	// ServiceName is the 'service.name' attribute of the resource
+	ServiceName string
+	// Sampler is ParentBased(AlwaysSample()) by default
+	Sampler  ISampler
+	Exporter IExporter
+	// BatchSize is a max count of the spans in the export request (512 by default)
+	BatchSize int
+	// MaxQueueSize is a max count of the spans waiting for the export (2048 by default),
+	// the spans are dropped if the queue is full (see Dropped)
+	MaxQueueSize int
+	// FlushInterval is a max delay of the export (5s by default)
+	FlushInterval time.Duration
+	Logger        *zap.Logger
+}
+
+// A Tracer creates the spans and exports them in batches
+type Tracer struct {
+	serviceName   string
+	sampler       ISampler
+	exporter      IExporter
+	batchSize     int
+	maxQueueSize  int
+	flushInterval time.Duration
+	logger        *zap.Logger
+	dropped       uint64
+
+	batch  []*Span
+	flush  chan struct{}
+	done   chan struct{}
+	closed chan struct{}
+	once   sync.Once
+	mu     sync.Mutex
+}
+
+// NewTracer creates a tracer. It must be closed to export the last spans.
+func NewTracer(cfg Config) *Tracer {
+
+	t := &Tracer{
+		serviceName:   cfg.ServiceName,
+		sampler:       cfg.Sampler,
+		exporter:      cfg.Exporter,
+		batchSize:     cfg.BatchSize,
+		maxQueueSize:  cfg.MaxQueueSize,
+		flushInterval: cfg.FlushInterval,
+		logger:        cfg.Logger,
+		flush:         make(chan struct{}, 1),
+		done:          make(chan struct{}),
+		closed:        make(chan struct{}),
+	}
+
+	if t.sampler == nil {
+		t.sampler = ParentBased(AlwaysSample())
+	}
+	if t.batchSize <= 0 {
+		t.batchSize = 512
+	}
+	if t.maxQueueSize <= 0 {
+		t.maxQueueSize = 2048
+	}
+	if t.maxQueueSize < t.batchSize {
+		t.maxQueueSize = t.batchSize
+	}
+	if t.flushInterval <= 0 {
+		t.flushInterval = time.Second * 5
+	}
+	if t.logger == nil {
+		t.logger = zap.NewNop()
+	}
+
+	go t.run()
+
+	return t
+}
+
+// ServiceName returns the name of the service
+func (t *Tracer) ServiceName() string {
+	return t.serviceName
+}
+
+// Start creates the span. The parent is the span (or the remote span context) of the context.
+// The sampler isn't used if the context has the forced decision (see ContextWithSampling).
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind, links ...Link) (context.Context, *Span) {
+
+	parent := SpanContextFromContext(ctx)
+
+	s := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: make(map[string]string),
+	}
+
+	if parent.IsValid() {
+		s.sc.TraceID = parent.TraceID
+		s.parent = parent.SpanID
+	} else {
+		s.sc.TraceID = newTraceID()
+	}
+	s.sc.SpanID = newSpanID()
+	if sampled, ok := SamplingFromContext(ctx); ok {
+		s.sc.Sampled = sampled
+	} else {
+		s.sc.Sampled = t.sampler.ShouldSample(parent, s.sc.TraceID, name)
+	}
+
+	for _, l := range links {
+		s.AddLink(l)
+	}
+
+	return ContextWithSpan(ctx, s), s
+}
+
+// Close exports the completed spans and stops the tracer
+func (t *Tracer) Close() error {
+
+	t.once.Do(func() { close(t.done) })
+	<-t.closed
+
+	return nil
+}
+
+// Dropped returns the count of the spans dropped because the queue is full
+func (t *Tracer) Dropped() uint64 {
+	return atomic.LoadUint64(&t.dropped)
+}
+
+func (t *Tracer) enqueue(s *Span) {
+
+	t.mu.Lock()
+	if len(t.batch) >= t.maxQueueSize {
+		t.mu.Unlock()
+		atomic.AddUint64(&t.dropped, 1)
+		return
+	}
+	t.batch = append(t.batch, s)
+	full := len(t.batch) >= t.batchSize
+	t.mu.Unlock()
+
+	if full {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (t *Tracer) run() {
+
+	defer close(t.closed)
+
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			t.export()
+			return
+		case <-ticker.C:
+		case <-t.flush:
+		}
+
+		t.export()
+	}
+}
+
+func (t *Tracer) export() {
+
+	t.mu.Lock()
+	batch := t.batch
+	t.batch = nil
+	t.mu.Unlock()
+
+	if len(batch) == 0 || t.exporter == nil {
+		return
+	}
+
+	for len(batch) > 0 {
+		size := t.batchSize
+		if size > len(batch) {
+			size = len(batch)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), t.flushInterval)
+		if err := t.exporter.Export(ctx, batch[:size]); err != nil {
+			t.logger.Error("failed to export spans", zap.Int("count", size), zap.Error(err))
+		}
+		cancel()
+
+		batch = batch[size:]
+	}
+}